@@ -2,28 +2,62 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"authentio/internal/config"
+	"authentio/internal/constants"
 	dbpkg "authentio/internal/database"
+	"authentio/internal/grpcserver"
 	"authentio/internal/handler"
+	"authentio/internal/middleware"
 	"authentio/internal/router"
 	"authentio/internal/service"
+	"authentio/pkg/anonymizer"
+	"authentio/pkg/breaker"
+	"authentio/pkg/captcha"
+	"authentio/pkg/challengestore"
+	"authentio/pkg/disposable"
 	"authentio/pkg/email"
+	"authentio/pkg/export"
+	"authentio/pkg/health"
+	"authentio/pkg/idempotency"
+	"authentio/pkg/jwks"
 	"authentio/pkg/jwt"
+	"authentio/pkg/lock"
 	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+	"authentio/pkg/mtls"
+	"authentio/pkg/openapi"
+	"authentio/pkg/otplimit"
+	"authentio/pkg/pairing"
+	"authentio/pkg/push"
+	"authentio/pkg/quota"
+	"authentio/pkg/refreshgrace"
+	"authentio/pkg/retry"
+	"authentio/pkg/revocation"
+	"authentio/pkg/signuplimit"
+	"authentio/pkg/sms"
+	"authentio/pkg/storage"
+	"authentio/pkg/tasks"
+	"authentio/pkg/webhook"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 
-	// Swagger imports
-	_ "authentio/docs" // This imports your generated docs
+	// Generated OpenAPI docs, served by /swagger and used for request validation
+	"authentio/docs"
 )
 
 // @title Authentio API
@@ -62,14 +96,37 @@ func main() {
 
 	googleOAuthConfig := config.GoogleOAuthConfig
 
-	// Initialize email client for sending OTPs and notifications
-	emailClient := email.NewClient(
-		cfg.SMTPHost,
-		cfg.SMTPPort,
-		cfg.SMTPUsername,
-		cfg.SMTPPassword,
-		cfg.SMTPFrom,
-	)
+	// Initialize the email provider (EMAIL_PROVIDER) for sending OTPs and notifications
+	emailClient, err := email.NewSender(email.SenderConfig{
+		Provider:            cfg.EmailProvider,
+		FromEmail:           cfg.SMTPFrom,
+		FromName:            cfg.EmailFromName,
+		SMTPHost:            cfg.SMTPHost,
+		SMTPPort:            cfg.SMTPPort,
+		SMTPUsername:        cfg.SMTPUsername,
+		SMTPPassword:        cfg.SMTPPassword,
+		SMTPPoolSize:        cfg.SMTPPoolSize,
+		SMTPPoolIdleTimeout: cfg.SMTPPoolIdleTimeout,
+		DKIMDomain:          cfg.DKIMDomain,
+		DKIMSelector:        cfg.DKIMSelector,
+		DKIMPrivateKey:      cfg.DKIMPrivateKeyFile,
+		SendGridAPIKey:      cfg.SendGridAPIKey,
+		OutboxDir:           cfg.EmailOutboxDir,
+		InboxSize:           cfg.EmailInboxSize,
+		SinkAddr:            cfg.EmailSinkAddr,
+		Maildir:             cfg.EmailMaildir,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to init email provider: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Parse the embedded HTML email templates
+	emailRenderer, err := email.NewEmailRenderer("Authentio")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load email templates: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Initialize structured logger (JSON in production, console in dev)
 	if err := logger.InitLogger(cfg.Env == "production"); err != nil {
@@ -79,6 +136,10 @@ func main() {
 	defer logger.Sync() // Ensure all logs are flushed on exit
 
 	logger.Info("Starting Authentio service", "env", cfg.Env, "port", cfg.ServerPort)
+	if smtpClient, ok := emailClient.(*email.Client); ok && smtpClient.DKIM != nil {
+		record, value := smtpClient.DKIM.DNSRecord()
+		logger.Info("signing emails with DKIM - publish the public key as a TXT record", "record", record, "value", value)
+	}
 
 	// Set Gin runtime mode
 	if cfg.Env == "production" {
@@ -87,11 +148,14 @@ func main() {
 		gin.SetMode(gin.DebugMode)
 	}
 
-	// Initialize PostgreSQL connection
-	db, err := sql.Open("pgx", cfg.PostgresDSN)
+	// Initialize PostgreSQL connection; every query is timed for the metrics
+	// endpoint and logged when slower than DB_SLOW_QUERY_THRESHOLD
+	pgxConfig, err := pgx.ParseConfig(cfg.PostgresDSN)
 	if err != nil {
-		logger.Fatal("failed to open database connection", "error", err)
+		logger.Fatal("invalid POSTGRES_DSN", "error", err)
 	}
+	pgxConfig.Tracer = dbpkg.NewQueryTracer(cfg.DBSlowQueryThreshold)
+	db := stdlib.OpenDB(*pgxConfig)
 	defer func() {
 		if err := db.Close(); err != nil {
 			logger.Error("error closing database", "error", err)
@@ -117,11 +181,12 @@ func main() {
 		Password: cfg.RedisPass,
 		DB:       0, // uses default DB
 	})
-	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+	redisErr := redisClient.Ping(context.Background()).Err()
+	if err := redisErr; err != nil {
 		logger.Warn("failed to connect to Redis - some features may be unavailable", "error", err)
-		// Continue without Redis in development mode
-		if cfg.Env == "production" {
-			logger.Fatal("Redis required in production", "error", err)
+		// Continue without Redis in development mode unless REDIS_REQUIRED is set
+		if cfg.Env == "production" || cfg.RedisRequired {
+			logger.Fatal("Redis is required", "error", err)
 		}
 	} else {
 		logger.Info("Redis connection established")
@@ -132,15 +197,276 @@ func main() {
 		}
 	}()
 
-	// Test email service (non-fatal in production, but warn)
-	if err := emailClient.Send([]string{"test@example.com"}, "Authentio Email Test", "Email service is working!"); err != nil {
-		logger.Warn("Email service test failed - check SMTP settings", "error", err)
+	// Check the email provider is reachable (non-fatal, but warn): probe SMTP
+	// servers, and send a test email when EMAIL_STARTUP_TEST_TO is set
+	emailProber, _ := emailClient.(email.Prober)
+	if emailProber != nil {
+		if err := emailProber.Probe(context.Background()); err != nil {
+			logger.Warn("Email provider probe failed - check email provider settings", "error", err, "provider", cfg.EmailProvider)
+		} else {
+			logger.Info("Email provider reachable", "provider", cfg.EmailProvider)
+		}
+	}
+	if cfg.EmailStartupTestTo != "" {
+		if _, err := emailClient.Send(context.Background(), email.Mail{To: []string{cfg.EmailStartupTestTo}, Subject: "Authentio Email Test", Body: "Email service is working!"}); err != nil {
+			logger.Warn("Email service test failed - check email provider settings", "error", err, "provider", cfg.EmailProvider)
+		} else {
+			logger.Info("Email service test sent", "to", cfg.EmailStartupTestTo)
+		}
+	}
+
+	// Readiness checks served on GET /readyz: the database and, when it is
+	// required, Redis; the email provider and external services only mark
+	// the instance degraded
+	readiness := health.NewChecker(5 * time.Second)
+	readiness.Add(health.Check{Name: "database", Critical: true, SlowAfter: cfg.HealthSlowThreshold, Run: db.PingContext})
+	readiness.Add(health.Check{Name: "redis", Critical: cfg.Env == "production" || cfg.RedisRequired, SlowAfter: cfg.HealthSlowThreshold, Run: func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	}})
+	if emailProber != nil && cfg.EmailProbeInterval > 0 {
+		readiness.Add(health.Check{Name: "email", Interval: cfg.EmailProbeInterval, SlowAfter: cfg.HealthSlowThreshold, Run: emailProber.Probe})
+	}
+	if cfg.HealthExternalCheckInterval > 0 {
+		healthHTTP := &http.Client{Timeout: 5 * time.Second}
+		external := map[string]string{"geoip": cfg.IPAPIURL}
+		if cfg.GoogleClientID != "" {
+			external["google_oauth"] = "https://accounts.google.com/.well-known/openid-configuration"
+		}
+		if cfg.FederatedIssuer != "" {
+			external["federated_issuer"] = cfg.FederatedJWKSURL
+		}
+		for name, url := range external {
+			readiness.Add(health.Check{Name: name, Interval: cfg.HealthExternalCheckInterval, SlowAfter: cfg.HealthSlowThreshold, Run: health.HTTPCheck(healthHTTP, url)})
+		}
+	}
+
+	// Background workers (email queue, list refreshers) stop when bgCtx is cancelled on shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Sample the database and Redis connection pool stats for GET /metrics
+	metrics.WatchPools(bgCtx, cfg.PoolMetricsInterval, db, redisClient)
+
+	// Maintenance jobs (scans, reminders, re-encryption, key rotations) take
+	// a Redis lock so they run on one server at a time. Without Redis
+	// (development only) a single server is assumed.
+	var locks *lock.Locker
+	if redisErr == nil {
+		locks = lock.NewLocker(redisClient)
 	} else {
-		logger.Info("Email service initialized and tested successfully")
+		logger.Warn("background jobs not coordinated across servers - Redis unavailable")
+	}
+
+	// Scheduled jobs run on the elected leader; another server takes over
+	// within SCHEDULER_LEASE when it dies
+	scheduler := locks.Election("scheduler", cfg.SchedulerLease)
+	scheduler.Campaign(bgCtx)
+
+	// Record every delivery attempt in the email delivery log; SendGrid's
+	// event webhook adds deliveries, bounces and opens
+	emailLogRepo := dbpkg.NewEmailLogRepository(db)
+
+	// Template versions saved by admins replace the embedded defaults
+	emailTemplateRepo := dbpkg.NewEmailTemplateRepository(db)
+	emailRenderer.SetTemplateStore(emailTemplateRepo)
+
+	// While the email provider is failing, emails are refused at once by its
+	// circuit breaker (and retried by the queue) rather than each timing out
+	emailBreaker := breaker.New("email", cfg.CircuitBreakerFailures, cfg.CircuitBreakerOpenTimeout)
+	// Transient failures are first retried at once, before the queue's
+	// slower retries
+	emailRetries := retry.New("email", retry.Policy{
+		Attempts:  cfg.ExternalRetryAttempts,
+		BaseDelay: 500 * time.Millisecond,
+		MaxDelay:  5 * time.Second,
+		Budget:    cfg.ExternalRetryBudget,
+	})
+	var mailer email.EmailSender = email.NewTrackingSender(email.NewCircuitSender(email.NewRetrySender(emailClient, emailRetries), emailBreaker), emailLogRepo)
+	var emailWebhook *email.SendGridWebhook
+	if cfg.SendGridWebhookPublicKey != "" {
+		emailWebhook, err = email.NewSendGridWebhook(cfg.SendGridWebhookPublicKey)
+		if err != nil {
+			logger.Fatal("failed to load SendGrid webhook key", "error", err)
+		}
+	}
+
+	// Deliver emails through the Redis queue (retries + dead-letter list).
+	// Without Redis (development only) emails are sent by in-process workers,
+	// or directly when EMAIL_ASYNC_WORKERS=0.
+	var emailQueue *email.Queue
+	var emailWorkers *email.AsyncSender
+	if redisErr == nil {
+		emailQueue = email.NewQueue(redisClient, mailer, email.QueueConfig{
+			Workers:     cfg.EmailQueueWorkers,
+			MaxAttempts: cfg.EmailQueueMaxAttempts,
+			BaseBackoff: cfg.EmailQueueBaseBackoff,
+			MaxBackoff:  cfg.EmailQueueMaxBackoff,
+		})
+		mailer = emailQueue
+	} else if cfg.EmailAsyncWorkers > 0 {
+		emailWorkers = email.NewAsyncSender(mailer, cfg.EmailAsyncWorkers, cfg.EmailAsyncBacklog)
+		mailer = emailWorkers
+		logger.Warn("email queue disabled - sending emails from in-process workers without retries")
+	} else {
+		logger.Warn("email queue disabled - sending emails synchronously")
+	}
+
+	// Registration side effects (onboarding emails, admin review requests,
+	// the registration webhook) run from the Redis task queue. Without Redis
+	// (development only) they run during the request.
+	var taskQueue *tasks.Queue
+	if redisErr == nil {
+		taskQueue = tasks.NewQueue(redisClient, tasks.QueueConfig{
+			Workers:     cfg.TaskQueueWorkers,
+			MaxAttempts: cfg.TaskQueueMaxAttempts,
+			BaseBackoff: cfg.TaskQueueBaseBackoff,
+			MaxBackoff:  cfg.TaskQueueMaxBackoff,
+			DedupWindow: cfg.TaskDedupWindow,
+		})
+	} else {
+		logger.Warn("task queue disabled - running registration side effects synchronously")
+	}
+	// Revoked access tokens are checked against an in-process bloom filter
+	// kept current through Redis pub/sub; only its possible hits are looked
+	// up in Redis. REVOCATION_STRATEGY=stateless does without the list
+	var revokedTokens *revocation.List
+	switch {
+	case cfg.RevocationStrategy == constants.RevocationStateless:
+		logger.Info("access tokens aren't checked for revocation - revoked sessions end at their next refresh", "accessTokenTTL", cfg.AccessTokenTTL)
+	case redisErr == nil:
+		revokedTokens = revocation.NewList(redisClient, revocation.Config{
+			Capacity:  cfg.RevocationFilterCapacity,
+			CacheSize: cfg.RevocationCacheSize,
+			Refresh:   cfg.RevocationFilterRefresh,
+		})
+		revokedTokens.Start(bgCtx)
+	default:
+		logger.Warn("access tokens can't be revoked - Redis unavailable")
+	}
+
+	var registrationWebhook *webhook.Sender
+	if cfg.RegistrationWebhookURL != "" {
+		registrationWebhook = webhook.NewSender(cfg.RegistrationWebhookURL, cfg.RegistrationWebhookSecret)
+	}
+
+	// Disposable email domain detection: bundled list plus optional remote refresh
+	disposableChecker := disposable.NewChecker(cfg.DisposableEmailListURL)
+	disposableChecker.StartRefresh(bgCtx, cfg.DisposableEmailRefresh)
+
+	// GeoIP lookups are retried once at most, as requests wait for them, and
+	// skipped while the service is failing
+	middleware.SetGeoIPBreaker(breaker.New("geoip", cfg.CircuitBreakerFailures, cfg.CircuitBreakerOpenTimeout))
+	middleware.SetGeoIPRetries(retry.New("geoip", retry.Policy{
+		Attempts:  min(cfg.ExternalRetryAttempts, 2),
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  250 * time.Millisecond,
+		Budget:    cfg.ExternalRetryBudget,
+	}))
+
+	// Anonymizer detection (proxy, VPN, Tor and hosting IPs) for GeoIP signals
+	anonymizers, err := anonymizer.New(anonymizer.Config{
+		Provider: cfg.AnonymizerProvider,
+		IPAPIURL: cfg.IPAPIURL,
+		IPQSKey:  cfg.AnonymizerIPQSKey,
+		ListFile: cfg.AnonymizerListFile,
+		CacheTTL: cfg.AnonymizerCacheTTL,
+	})
+	if err != nil {
+		logger.Fatal("failed to initialize anonymizer detection", "error", err)
+	}
+
+	// CAPTCHA verification for registration, login and reset endpoints
+	captchaVerifier, err := captcha.NewVerifier(captcha.Config{
+		Provider: cfg.CaptchaProvider,
+		Secret:   cfg.CaptchaSecret,
+		MinScore: cfg.CaptchaMinScore,
+	})
+	if err != nil {
+		logger.Fatal("failed to initialize captcha provider", "error", err)
+	}
+
+	// Object storage for avatar uploads (disabled with STORAGE_PROVIDER=none)
+	fileStorage, err := storage.New(storage.Config{
+		Provider:  cfg.StorageProvider,
+		Endpoint:  cfg.S3Endpoint,
+		Region:    cfg.S3Region,
+		Bucket:    cfg.S3Bucket,
+		AccessKey: cfg.S3AccessKey,
+		SecretKey: cfg.S3SecretKey,
+		UseSSL:    cfg.S3UseSSL,
+		PublicURL: cfg.StoragePublicURL,
+	})
+	if err != nil {
+		logger.Fatal("failed to initialize file storage", "error", err)
+	}
+	if fileStorage == nil {
+		logger.Info("avatar uploads disabled - STORAGE_PROVIDER is none")
+	}
+
+	// Audit log entries are archived to their own, private bucket before
+	// data retention deletes them
+	var auditArchive storage.Storage
+	if cfg.AuditArchiveBucket != "" {
+		auditArchive, err = storage.New(storage.Config{
+			Provider:  cfg.StorageProvider,
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.AuditArchiveBucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			UseSSL:    cfg.S3UseSSL,
+		})
+		if err != nil {
+			logger.Fatal("failed to initialize audit log archive", "error", err)
+		}
+	}
+
+	// Push notifications for 2FA login approval (FCM and/or APNs)
+	pushDispatcher, err := push.New(push.Config{
+		FCMCredentialsFile: cfg.PushFCMCredentialsFile,
+		APNsKeyFile:        cfg.PushAPNsKeyFile,
+		APNsKeyID:          cfg.PushAPNsKeyID,
+		APNsTeamID:         cfg.PushAPNsTeamID,
+		APNsTopic:          cfg.PushAPNsTopic,
+		APNsProduction:     cfg.PushAPNsProduction,
+	})
+	if err != nil {
+		logger.Fatal("failed to initialize push notifications", "error", err)
+	}
+	if pushDispatcher == nil {
+		logger.Info("push approval 2FA disabled - no FCM or APNs credentials")
+	}
+
+	// Text messages for the security notifications users opt into
+	smsSender, err := sms.New(sms.Config{
+		Provider:         cfg.SMSProvider,
+		TwilioAccountSID: cfg.TwilioAccountSID,
+		TwilioAuthToken:  cfg.TwilioAuthToken,
+		TwilioFromNumber: cfg.TwilioFromNumber,
+	})
+	if err != nil {
+		logger.Fatal("failed to initialize SMS provider", "error", err)
+	}
+	if smsSender == nil {
+		logger.Info("SMS notifications disabled - SMS_PROVIDER is not set")
 	}
 
-	// Initialize JWT manager for token signing and verification
-	jwtManager := jwt.NewManager(cfg.JWTSecret)
+	// Security metrics alert through ALERT_WEBHOOK_URL past their thresholds
+	var alertNotifier metrics.Notifier
+	if cfg.AlertWebhookURL != "" {
+		alertNotifier = metrics.NewWebhook(cfg.AlertWebhookURL, strings.ToLower(cfg.AlertWebhookFormat), cfg.AlertPagerDutyRoutingKey, retry.New("alert_webhook", retry.Policy{
+			Attempts:  cfg.ExternalRetryAttempts,
+			BaseDelay: time.Second,
+			MaxDelay:  5 * time.Second,
+			Budget:    cfg.ExternalRetryBudget,
+		}))
+	}
+	metrics.Configure(cfg.AlertWindow, alertNotifier)
+	metrics.FailedLogins.SetThreshold(cfg.AlertFailedLoginsThreshold)
+	metrics.Lockouts.SetThreshold(cfg.AlertLockoutsThreshold)
+	metrics.RevokedTokens.SetThreshold(cfg.AlertRevokedTokensThreshold)
+	metrics.BlockedCountries.SetThreshold(cfg.AlertBlockedCountryThreshold)
+	metrics.OTPFailures.SetThreshold(cfg.AlertOTPFailuresThreshold)
 
 	// Initialize validator for request validation
 	handler.InitValidator()
@@ -149,34 +475,293 @@ func main() {
 	userRepo := dbpkg.NewUserRepository(db)
 	tokenRepo := dbpkg.NewTokenRepository(db)
 	otpRepo := dbpkg.NewOTPRepository(db)
-	twoFARepo := dbpkg.NewTwoFARepository(db)
+	secretsKeyring, _ := cfg.SecretsKeyring() // checked by cfg.Validate
+	twoFARepo := dbpkg.NewTwoFARepository(db, secretsKeyring)
+	// Phone numbers and push device tokens are encrypted with PII_ENCRYPTION
+	piiIndex, _ := cfg.PIIIndex() // checked by cfg.Validate
+	pii := dbpkg.NewPIIEncryption(secretsKeyring, piiIndex, cfg.PIIEncryption)
+	auditRepo := dbpkg.NewAuditLogRepository(db)
+	consentRepo := dbpkg.NewConsentRepository(db)
+	domainRuleRepo := dbpkg.NewEmailDomainRuleRepository(db)
+	inviteRepo := dbpkg.NewInvitationRepository(db)
+	orgRepo := dbpkg.NewOrganizationRepository(db)
+	orgInviteRepo := dbpkg.NewOrganizationInvitationRepository(db)
+	tenantRepo := dbpkg.NewTenantRepository(db)
+	// Each tenant's sender, logo, colors and footer brand its emails
+	emailRenderer.SetBrandingStore(tenantRepo)
+	oauthRepo := dbpkg.NewOAuthRepository(db)
+	profileRepo := dbpkg.NewProfileRepository(db)
+	preferencesRepo := dbpkg.NewPreferencesRepository(db, pii)
+	pushRepo := dbpkg.NewPushRepository(db, pii)
+	recoveryRepo := dbpkg.NewRecoveryRepository(db)
+	deviceRepo := dbpkg.NewDeviceRepository(db)
+	ipAllowlistRepo := dbpkg.NewIPAllowlistRepository(db)
+	ipDenylistRepo := dbpkg.NewIPDenylistRepository(db)
+
+	// Create the tenants listed in TENANTS; the default tenant always exists
+	for _, slug := range cfg.Tenants {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if slug == "" {
+			continue
+		}
+		if _, err := tenantRepo.Ensure(context.Background(), slug, slug); err != nil {
+			logger.Fatal("failed to create tenant", "tenant", slug, "error", err)
+		}
+	}
+
+	// Initialize JWT manager for token signing and verification. Each tenant
+	// signs with its own key (from the tenants table, or derived from JWT_SECRET).
+	retiredKeys, _ := cfg.RetiredJWTKeys() // checked by cfg.Validate
+	defaultKey := jwt.Key{
+		Secret:     cfg.JWTSecret,
+		KeyID:      cfg.JWTKeyID,
+		Retired:    retiredKeys,
+		Issuer:     cfg.JWTIssuer,
+		Audience:   cfg.JWTAudience,
+		AccessTTL:  cfg.AccessTokenTTL,
+		RefreshTTL: cfg.RefreshTokenTTL,
+	}
+	tenantKeys := service.NewTenantKeyStore(tenantRepo, defaultKey, cfg.KMS())
+	if err := tenantKeys.Load(context.Background()); err != nil {
+		logger.Fatal("failed to load tenant signing keys", "error", err)
+	}
+	tenantKeys.StartRefresh(bgCtx, cfg.TenantKeysRefresh)
+	jwtManager := jwt.NewManager(defaultKey, tenantKeys)
+	jwtManager.SetLeeway(cfg.JWTLeeway)
+
+	// OpenID Connect provider mode signs client tokens with an RSA key. A
+	// generated key changes on every restart, so production requires a key file.
+	var providerKey *jwt.ProviderKey
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCSigningKeyFile != "" {
+			providerKey, err = jwt.LoadProviderKey(cfg.OIDCSigningKeyFile)
+		} else if cfg.Env == "production" {
+			err = fmt.Errorf("OIDC_SIGNING_KEY_FILE is required in production")
+		} else {
+			logger.Warn("OIDC_SIGNING_KEY_FILE not set - using a temporary signing key")
+			providerKey, err = jwt.GenerateProviderKey()
+		}
+		if err != nil {
+			logger.Fatal("failed to load OIDC signing key", "error", err)
+		}
+		jwtManager.SetProviderKey(providerKey)
+		logger.Info("OpenID Connect provider enabled", "issuer", cfg.OIDCIssuer, "kid", providerKey.ID)
+	}
+
+	// Values encrypted with the secrets keyring: 2FA secrets, and personal
+	// data with PII_ENCRYPTION
+	reencryptionPasses := []service.ReencryptionPass{
+		{Name: "2FA secrets", Run: twoFARepo.ReencryptSecrets},
+		{Name: "personal data", Run: func(ctx context.Context) (int, error) {
+			return dbpkg.ReencryptPII(ctx, db, pii)
+		}},
+	}
+
+	// Keys rotated through the admin API are stored sealed with the configured
+	// master key (a keyring of its own, which never gets the generated keys)
+	// and replace JWT_SECRET, the OIDC key and SECRETS_ENCRYPTION_KEY
+	rootKeyring, _ := cfg.SecretsKeyring() // checked by cfg.Validate
+	keyManager := service.NewKeyManager(dbpkg.NewManagedKeyRepository(db), rootKeyring, secretsKeyring,
+		jwtManager, tenantKeys, defaultKey, providerKey, cfg.AccessTokenTTL+cfg.JWTLeeway, locks, reencryptionPasses...)
+	if err := keyManager.Load(context.Background()); err != nil {
+		logger.Fatal("failed to load rotated keys", "error", err)
+	}
+	keyManager.Start(bgCtx, cfg.TenantKeysRefresh)
+
+	// Encrypt values stored unencrypted, and move those wrapped by retired
+	// keys (or an older KMS key version) to the current one, at startup and
+	// every SECRETS_REWRAP_INTERVAL
+	service.StartReencryption(bgCtx, secretsKeyring, locks, cfg.SecretsRewrapInterval,
+		append(reencryptionPasses, service.ReencryptionPass{Name: "rotated keys", Run: keyManager.RewrapKeys})...)
+
+	tenantResolver, err := middleware.NewTenantResolver(cfg.TenancyMode, cfg.TenantHeader, cfg.TenantBaseDomain, tenantRepo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid tenancy config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Daily per-tenant quotas are counted in Redis; without Redis (development
+	// only) they are not enforced
+	var quotas *quota.Limiter
+	var otpLimits *otplimit.Limiter
+	var signupLimits *signuplimit.Limiter
+	var challengeTokens *challengestore.Store
+	var refreshGrace *refreshgrace.Store
+	var idempotencyKeys *idempotency.Store
+	var exports *export.Store
+	var pairings *pairing.Store
+	if redisErr == nil {
+		quotas = quota.NewLimiter(redisClient, map[string]int64{
+			quota.Requests:      cfg.TenantQuotaRequestsPerDay,
+			quota.Registrations: cfg.TenantQuotaRegistrationsPerDay,
+			quota.OTPEmails:     cfg.TenantQuotaOTPEmailsPerDay,
+		}, tenantRepo)
+		otpLimits = otplimit.NewLimiter(redisClient, cfg.OTPResendCooldown, cfg.OTPDailyLimit)
+		signupLimits = signuplimit.NewLimiter(redisClient, signuplimit.Config{
+			PerIPPerDay:    cfg.RegistrationMaxPerIPPerDay,
+			PerEmailPerDay: cfg.RegistrationMaxPerEmailBase,
+			BlockDuration:  cfg.RegistrationBlockDuration,
+		})
+		challengeTokens = challengestore.NewStore(redisClient)
+		if cfg.RefreshTokenGracePeriod > 0 {
+			refreshGrace = refreshgrace.NewStore(redisClient, cfg.RefreshTokenGracePeriod)
+		}
+		idempotencyKeys = idempotency.NewStore(redisClient, cfg.IdempotencyKeyTTL)
+		exports = export.NewStore(redisClient, cfg.ExportTTL)
+		pairings = pairing.NewStore(redisClient, cfg.PairingCodeTTL)
+	} else {
+		logger.Warn("tenant quotas, code email and registration limits, user exports and QR code pairing disabled - Redis unavailable")
+		logger.Warn("challenge tokens not tracked, only their challenges are single use - Redis unavailable")
+		logger.Warn("Idempotency-Key headers ignored - Redis unavailable")
+		logger.Warn("no refresh token grace period, concurrent refreshes fail - Redis unavailable")
+	}
+
+	// Federated sign-in verifies tokens of an external identity provider
+	var federation *jwks.Verifier
+	if cfg.FederatedIssuer != "" {
+		federation = jwks.NewVerifier(jwks.Config{
+			Issuer:   cfg.FederatedIssuer,
+			URL:      cfg.FederatedJWKSURL,
+			Audience: cfg.FederatedAudience,
+			Refresh:  cfg.FederatedJWKSRefresh,
+			Leeway:   cfg.JWTLeeway,
+		})
+		logger.Info("federated sign-in enabled", "issuer", cfg.FederatedIssuer)
+	}
+
+	// Groups of likely duplicate accounts found by the duplicate account scan
+	duplicateRepo := dbpkg.NewDuplicateAccountRepository(db)
 
 	// Initialize authentication service
-	authSrv := service.NewAuthService(userRepo, twoFARepo, otpRepo, tokenRepo, jwtManager, emailClient, googleOAuthConfig)
+	authSrv := service.NewAuthService(cfg, userRepo, twoFARepo, otpRepo, tokenRepo, auditRepo, consentRepo, domainRuleRepo, inviteRepo, orgRepo, orgInviteRepo, oauthRepo, profileRepo, preferencesRepo, pushRepo, recoveryRepo, deviceRepo, tenantRepo, ipAllowlistRepo, ipDenylistRepo, emailLogRepo, emailTemplateRepo, duplicateRepo, jwtManager, keyManager, mailer, emailRenderer, emailWebhook, disposableChecker, quotas, otpLimits, signupLimits, challengeTokens, refreshGrace, revokedTokens, locks, scheduler, taskQueue, registrationWebhook, exports, pairings, federation, fileStorage, auditArchive, pushDispatcher, smsSender, googleOAuthConfig)
+
+	// Delete one-time codes, refresh tokens and audit log entries past their
+	// retention
+	authSrv.StartCleanup(bgCtx, cfg.CleanupInterval)
+
+	// Look for likely duplicate accounts in every tenant
+	authSrv.StartDuplicateAccountScan(bgCtx, cfg.DuplicateScanInterval)
+
+	// Send new users the scheduled onboarding emails
+	authSrv.StartOnboardingEmails(bgCtx, cfg.OnboardingCheckInterval)
+
+	// Remind users with old passwords to change them
+	authSrv.StartPasswordRotationReminders(bgCtx, cfg.PasswordRotationCheckInterval)
 
 	// Initialize HTTP handlers
-	h := handler.NewHandler(*authSrv)
+	// The memory email provider's inbox is readable on the dev endpoints
+	inbox, _ := emailClient.(*email.Inbox)
+	h := handler.NewHandler(*authSrv, emailQueue, inbox, handler.SessionCookie{
+		Name:           cfg.SessionCookieName,
+		Domain:         cfg.SessionCookieDomain,
+		SameSite:       cfg.SessionSameSite(),
+		Secure:         cfg.SessionCookieSecure,
+		MaxAge:         cfg.RefreshTokenTTL,
+		AllowedOrigins: cfg.SessionAllowedOrigins,
+	}, cfg.GraphQLEnabled)
+
+	// TLS for the HTTP and gRPC listeners; with MTLS_CA_FILE clients may
+	// authenticate with a certificate, required on MTLS_REQUIRED_ROUTES
+	var tlsConfig *tls.Config
+	clientCerts := mtls.Policy{Required: cfg.MTLSRequiredRoutes, Allowed: cfg.MTLSAllowedIdentities}
+	if cfg.TLSCertFile != "" {
+		var clientCAs *x509.CertPool
+		if cfg.MTLSCAFile != "" {
+			clientCAs, err = mtls.LoadCAPool(cfg.MTLSCAFile)
+			if err != nil {
+				logger.Fatal("failed to load client CA bundle", "file", cfg.MTLSCAFile, "error", err)
+			}
+		}
+		tlsConfig, err = mtls.ServerConfig(cfg.TLSCertFile, cfg.TLSKeyFile, clientCAs)
+		if err != nil {
+			logger.Fatal("failed to load TLS certificate", "error", err)
+		}
+		if clientCAs != nil {
+			logger.Info("mutual TLS enabled", "required_routes", cfg.MTLSRequiredRoutes)
+		}
+	}
+
+	// Validate requests against the generated OpenAPI document
+	var requestValidator *openapi.Validator
+	if cfg.OpenAPIValidationEnabled {
+		requestValidator, err = openapi.New(docs.SwaggerInfo.ReadDoc())
+		if err != nil {
+			logger.Fatal("failed to load OpenAPI document", "error", err)
+		}
+	}
+
+	// Browser apps allowed to call each kind of route
+	corsOrigins := middleware.CORSOrigins{
+		API:     cfg.APICORSOrigins(),
+		Session: cfg.SessionAllowedOrigins,
+		Public:  cfg.CORSPublicOrigins,
+	}
 
 	// Setup Gin router with middleware and routes
-	r := router.SetupRouter(h, redisClient, jwtManager)
+	r := router.SetupRouter(h, redisClient, cfg.RevocationStrategy, revokedTokens, corsOrigins, jwtManager, tenantResolver, quotas, authSrv, authSrv, authSrv, authSrv, anonymizers, middleware.AnonymousIPRules{
+		BlockProxy:   cfg.AnonymizerBlockProxyRoutes,
+		BlockHosting: cfg.AnonymizerBlockHostingRoutes,
+		Allow:        cfg.AnonymizerAllowRoutes,
+	}, captchaVerifier, clientCerts, idempotencyKeys, requestValidator, cfg.MetricsToken, readiness, cfg.RequestTimeout)
 
 	// Create HTTP server instance
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.ServerPort),
-		Handler:      r,
+		Handler:      tenantResolver.Handler(r),
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: config.ServerWriteTimeout,
 		IdleTimeout:  60 * time.Second,
+		TLSConfig:    tlsConfig,
+	}
+
+	// Start email and task queue workers; they stop when bgCtx is cancelled
+	// on shutdown
+	if emailQueue != nil {
+		emailQueue.Start(bgCtx)
+	}
+	if taskQueue != nil {
+		taskQueue.Start(bgCtx, authSrv.RunTask)
 	}
 
 	// Start server in a goroutine
 	go func() {
-		logger.Info("HTTP server starting", "port", cfg.ServerPort)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("HTTP server starting", "port", cfg.ServerPort, "tls", tlsConfig != nil)
+		serve := srv.ListenAndServe
+		if tlsConfig != nil {
+			// The certificate is already loaded into TLSConfig
+			serve = func() error { return srv.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("server failed", "error", err)
 		}
 	}()
 
+	// Serve the gRPC API for internal services when GRPC_PORT is set. It
+	// shares the service layer and tenant resolution with the HTTP API.
+	var grpcSrv *grpc.Server
+	if cfg.GRPCPort != 0 {
+		if cfg.GRPCAPIKey == "" {
+			logger.Fatal("GRPC_API_KEY is required when GRPC_PORT is set")
+		}
+
+		var blacklist *middleware.TokenBlacklist
+		if revokedTokens != nil {
+			blacklist = middleware.NewTokenBlacklist(revokedTokens)
+		}
+		grpcSrv = grpcserver.New(*authSrv, blacklist, tenantResolver, cfg.GRPCAPIKey, tlsConfig, clientCerts)
+
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			logger.Fatal("failed to listen for gRPC", "port", cfg.GRPCPort, "error", err)
+		}
+		go func() {
+			logger.Info("gRPC server starting", "port", cfg.GRPCPort)
+			if err := grpcSrv.Serve(lis); err != nil {
+				logger.Fatal("gRPC server failed", "error", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal (SIGINT) to trigger graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
@@ -193,5 +778,28 @@ func main() {
 	} else {
 		logger.Info("Server stopped gracefully")
 	}
+	if grpcSrv != nil {
+		grpcSrv.GracefulStop()
+		logger.Info("gRPC server stopped")
+	}
+
+	// Stop the task and email workers after in-flight requests have queued
+	// their work
+	stopBackground()
+	if taskQueue != nil {
+		taskQueue.Wait()
+	}
+	if revokedTokens != nil {
+		revokedTokens.Wait()
+	}
+	if emailQueue != nil {
+		emailQueue.Wait()
+	}
+	if emailWorkers != nil {
+		emailWorkers.Stop()
+	}
+	if pool, ok := emailClient.(io.Closer); ok {
+		pool.Close()
+	}
 }
 
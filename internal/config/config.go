@@ -1,14 +1,27 @@
 package config
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
 	"log"
-	"strconv"
+	"net/http"
+	"strings"
 	"time"
 
+	"authentio/internal/constants"
+	"authentio/pkg/jwt"
+	"authentio/pkg/kms"
+	"authentio/pkg/otp"
+	"authentio/pkg/secretbox"
+
 	"github.com/caarlos0/env/v9"
 	"github.com/joho/godotenv"
 )
 
+// ServerWriteTimeout bounds the writing of each HTTP response by the server.
+const ServerWriteTimeout = 15 * time.Second
+
 type Config struct {
 	ServerPort int    `env:"SERVER_PORT" envDefault:"8080"`
 	Env        string `env:"APP_ENV" envDefault:"development"` // dev, staging, prod
@@ -17,15 +30,546 @@ type Config struct {
 	RedisAddr   string `env:"REDIS_ADDR" envDefault:"localhost:6379"`
 	RedisPass   string `env:"REDIS_PASS"`
 
+	// Whether startup fails when Redis is unreachable. Production always
+	// requires Redis; elsewhere the server runs without it, disabling rate
+	// limits, token revocation, tenant quotas and the email queue.
+	RedisRequired bool `env:"REDIS_REQUIRED" envDefault:"false"`
+
+	// Time each HTTP request may take: database, Redis and provider calls
+	// still running are cancelled and the request answered with 504
+	// (0 = no deadline). It must be shorter than ServerWriteTimeout.
+	RequestTimeout time.Duration `env:"REQUEST_TIMEOUT" envDefault:"10s"`
+
+	// Database queries taking longer than DB_SLOW_QUERY_THRESHOLD are logged
+	// with their repository method and tenant (0 = none)
+	DBSlowQueryThreshold time.Duration `env:"DB_SLOW_QUERY_THRESHOLD" envDefault:"200ms"`
+
+	// How often the database and Redis connection pool stats served by
+	// GET /metrics are sampled
+	PoolMetricsInterval time.Duration `env:"POOL_METRICS_INTERVAL" envDefault:"15s"`
+
+	// GET /readyz: checks passing in more than HEALTH_SLOW_THRESHOLD mark the
+	// instance degraded (0 = never); external services (GeoIP, identity
+	// providers) are checked at most once every HEALTH_EXTERNAL_CHECK_INTERVAL
+	// (0 = not checked)
+	HealthSlowThreshold         time.Duration `env:"HEALTH_SLOW_THRESHOLD" envDefault:"2s"`
+	HealthExternalCheckInterval time.Duration `env:"HEALTH_EXTERNAL_CHECK_INTERVAL" envDefault:"1m"`
+
+	// Circuit breakers of the GeoIP service, the email provider and Google:
+	// after CIRCUIT_BREAKER_FAILURES consecutive failures calls are refused
+	// for CIRCUIT_BREAKER_OPEN_TIMEOUT, then one probe call decides whether
+	// they resume (0 failures = no breakers)
+	CircuitBreakerFailures    int           `env:"CIRCUIT_BREAKER_FAILURES" envDefault:"5"`
+	CircuitBreakerOpenTimeout time.Duration `env:"CIRCUIT_BREAKER_OPEN_TIMEOUT" envDefault:"30s"`
+
+	// Calls to the email provider, the alert webhook and the GeoIP service
+	// failing for transient reasons are retried with a jittered exponential
+	// backoff, up to EXTERNAL_RETRY_ATTEMPTS attempts in all (at most 2 for
+	// GeoIP, which requests wait for; 1 = no retries). Retries are limited
+	// to EXTERNAL_RETRY_BUDGET of the calls to each (0 = no limit).
+	ExternalRetryAttempts int     `env:"EXTERNAL_RETRY_ATTEMPTS" envDefault:"3"`
+	ExternalRetryBudget   float64 `env:"EXTERNAL_RETRY_BUDGET" envDefault:"0.2"`
+
+	// Google sign-in; either all three are set or Google sign-in is disabled
+	GoogleClientID     string `env:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string `env:"GOOGLE_CLIENT_SECRET"`
+	GoogleRedirectURL  string `env:"GOOGLE_REDIRECT_URL"`
+
 	JWTSecret          string        `env:"JWT_SECRET,required"`
+	JWTIssuer          string        `env:"JWT_ISSUER"`
+	JWTAudience        string        `env:"JWT_AUDIENCE"`
 	AccessTokenTTL     time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
 	RefreshTokenTTL    time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"168h"` // 7 days
+	JWTLeeway          time.Duration `env:"JWT_LEEWAY" envDefault:"30s"`         // clock skew tolerated on exp/nbf/iat
+
+	// Session limits checked when a refresh token is used: REFRESH_TOKEN_MAX_AGE
+	// caps the time since the user signed in, REFRESH_TOKEN_IDLE_TIMEOUT the
+	// time since the session was last refreshed. 0 disables a limit; sessions
+	// then last as long as they are refreshed within REFRESH_TOKEN_TTL.
+	RefreshTokenMaxAge      time.Duration `env:"REFRESH_TOKEN_MAX_AGE" envDefault:"0"`
+	RefreshTokenIdleTimeout time.Duration `env:"REFRESH_TOKEN_IDLE_TIMEOUT" envDefault:"0"`
+	// A just-rotated refresh token is accepted once more within this period,
+	// returning the same pair, so concurrent refreshes don't sign users out
+	RefreshTokenGracePeriod time.Duration `env:"REFRESH_TOKEN_GRACE_PERIOD" envDefault:"10s"`
+
+	// REVOCATION_STRATEGY=stateful refuses revoked access tokens on every
+	// request; stateless skips the check, relying on short-lived access
+	// tokens, so revoked sessions end when their refresh token is refused
+	RevocationStrategy string `env:"REVOCATION_STRATEGY" envDefault:"stateful"`
+
+	// Cookie sessions for single-page apps: the refresh token is kept in an
+	// HttpOnly cookie renewed at /auth/session/renew. SESSION_ALLOWED_ORIGINS
+	// lists the apps' origins, allowed to call the session endpoints with
+	// credentials and to frame the renewal page; other origins are refused.
+	// SESSION_COOKIE_SAMESITE is strict, lax or none (apps on another site)
+	SessionCookieName     string   `env:"SESSION_COOKIE_NAME" envDefault:"authentio_session"`
+	SessionCookieDomain   string   `env:"SESSION_COOKIE_DOMAIN"`
+	SessionCookieSameSite string   `env:"SESSION_COOKIE_SAMESITE" envDefault:"lax"`
+	SessionCookieSecure   bool     `env:"SESSION_COOKIE_SECURE" envDefault:"true"`
+	SessionAllowedOrigins []string `env:"SESSION_ALLOWED_ORIGINS" envSeparator:","`
+
+	// CORS: CORS_ALLOWED_ORIGINS lists the browser apps allowed to call the
+	// API with credentials ("*" is refused; empty allows local development
+	// servers outside production and none in production).
+	// CORS_PUBLIC_ORIGINS applies to the public discovery routes, called
+	// without credentials
+	CORSAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS" envSeparator:","`
+	CORSPublicOrigins  []string `env:"CORS_PUBLIC_ORIGINS" envSeparator:"," envDefault:"*"`
+
+	// Revoked access tokens are checked against an in-process bloom filter
+	// sized for REVOCATION_FILTER_CAPACITY tokens and rebuilt every
+	// REVOCATION_FILTER_REFRESH; the Redis answers for its possible hits are
+	// cached for the REVOCATION_CACHE_SIZE most recently used tokens
+	RevocationFilterCapacity int           `env:"REVOCATION_FILTER_CAPACITY" envDefault:"100000"`
+	RevocationFilterRefresh  time.Duration `env:"REVOCATION_FILTER_REFRESH" envDefault:"10m"`
+	RevocationCacheSize      int           `env:"REVOCATION_CACHE_SIZE" envDefault:"10000"`
+
+	// Signing key rotation: JWT_KEY_ID names JWT_SECRET in the "kid" header of
+	// tokens. JWT_RETIRED_KEYS lists the secrets it replaced as kid:secret
+	// (a bare secret for tokens issued without a kid); they keep verifying
+	// until ACCESS_TOKEN_TTL after JWT_KEYS_ROTATED_AT, or until removed when
+	// it is unset. JWT_PREVIOUS_SECRETS lists secrets that signed tokens
+	// under the same kid as JWT_SECRET (or without a kid when JWT_KEY_ID is
+	// unset): they are tried in turn, for deployments that rotate the secret
+	// without naming keys.
+	JWTKeyID           string    `env:"JWT_KEY_ID"`
+	JWTRetiredKeys     []string  `env:"JWT_RETIRED_KEYS" envSeparator:","`
+	JWTPreviousSecrets []string  `env:"JWT_PREVIOUS_SECRETS" envSeparator:","`
+	JWTKeysRotatedAt   time.Time `env:"JWT_KEYS_ROTATED_AT"`
+
+	// Encryption at rest of stored credentials such as 2FA secrets:
+	// SECRETS_ENCRYPTION_KEY is a base64 256-bit master key named by
+	// SECRETS_KEY_ID. SECRETS_RETIRED_KEYS lists the keys it replaced as
+	// id:key; values they wrapped are re-wrapped with the current key.
+	SecretsEncryptionKey string   `env:"SECRETS_ENCRYPTION_KEY"`
+	SecretsKeyID         string   `env:"SECRETS_KEY_ID" envDefault:"1"`
+	SecretsRetiredKeys   []string `env:"SECRETS_RETIRED_KEYS" envSeparator:","`
+
+	// When PII_ENCRYPTION is set, phone numbers and push device tokens are
+	// also encrypted with the secrets keys. PII_INDEX_KEY (base64, at least
+	// 256 bits, never rotated) hashes them so they can still be looked up.
+	PIIEncryption bool   `env:"PII_ENCRYPTION" envDefault:"false"`
+	PIIIndexKey   string `env:"PII_INDEX_KEY"`
+
+	// Key management service: KMS_PROVIDER is aws, gcp or vault and
+	// KMS_KEY_ID the key at the service. Key settings (JWT_SECRET,
+	// JWT_RETIRED_KEYS, SECRETS_ENCRYPTION_KEY, SECRETS_RETIRED_KEYS,
+	// PII_INDEX_KEY) can then hold their value wrapped by it, as
+	// "kms:<ciphertext>". With SECRETS_USE_KMS the service key itself wraps
+	// the data keys of encrypted values, SECRETS_ENCRYPTION_KEY becoming a
+	// retired key. Unwrapped keys are cached for KMS_CACHE_TTL.
+	KMSProvider       string        `env:"KMS_PROVIDER"`
+	KMSKeyID          string        `env:"KMS_KEY_ID"`
+	KMSRegion         string        `env:"KMS_REGION"` // aws; defaults to AWS_REGION
+	VaultAddr         string        `env:"VAULT_ADDR"`
+	VaultToken        string        `env:"VAULT_TOKEN"`
+	VaultTransitMount string        `env:"VAULT_TRANSIT_MOUNT" envDefault:"transit"`
+	KMSCacheTTL       time.Duration `env:"KMS_CACHE_TTL" envDefault:"5m"`
+	SecretsUseKMS     bool          `env:"SECRETS_USE_KMS" envDefault:"false"`
+
+	// How often values wrapped by retired master keys (or by an older version
+	// of the KMS key) are re-wrapped with the current one; 0 only does it at
+	// startup.
+	SecretsRewrapInterval time.Duration `env:"SECRETS_REWRAP_INTERVAL" envDefault:"24h"`
+
+	// kms is the client of KMS_PROVIDER, connected by LoadConfig
+	kms *kms.Keys
 
 	SMTPHost     string `env:"SMTP_HOST" envDefault:"smtp.gmail.com"`
 	SMTPPort     int    `env:"SMTP_PORT" envDefault:"587"`
 	SMTPUsername string `env:"SMTP_USERNAME" envDefault:""`
 	SMTPPassword string `env:"SMTP_PASSWORD" envDefault:""`
-	SMTPFrom     string `env:"SMTP_FROM" envDefault:"noreply@example.com"` 
+	SMTPFrom     string `env:"SMTP_FROM" envDefault:"noreply@example.com"` // sender address for every provider
+
+	// SMTP sessions kept open between emails, instead of connecting and
+	// authenticating for each one: at most SMTP_POOL_SIZE emails are sent at
+	// once and idle sessions are closed after SMTP_POOL_IDLE_TIMEOUT
+	// (0 = a new connection per email)
+	SMTPPoolSize        int           `env:"SMTP_POOL_SIZE" envDefault:"4"`
+	SMTPPoolIdleTimeout time.Duration `env:"SMTP_POOL_IDLE_TIMEOUT" envDefault:"30s"`
+
+	// DKIM signing of the emails sent by the SMTP provider, with the RSA
+	// private key (PEM) in DKIM_PRIVATE_KEY_FILE, whose public key is
+	// published at DKIM_SELECTOR._domainkey.DKIM_DOMAIN (default: the
+	// SMTP_FROM domain). Unsigned without a key file; other providers sign
+	// with their own keys.
+	DKIMPrivateKeyFile string `env:"DKIM_PRIVATE_KEY_FILE"`
+	DKIMSelector       string `env:"DKIM_SELECTOR"`
+	DKIMDomain         string `env:"DKIM_DOMAIN"`
+
+	// Email delivery provider: smtp, sendgrid, or one of the dry-run
+	// providers (outside production) rendering emails without sending them:
+	// log writes them to the application log, file to files in
+	// EMAIL_OUTBOX_DIR, and memory keeps the last EMAIL_INBOX_SIZE for
+	// GET /api/v1/dev/emails. The development sinks deliver them locally:
+	// mailhog to the MailHog or Mailpit SMTP port at EMAIL_SINK_ADDR, maildir
+	// to the maildir EMAIL_MAILDIR.
+	EmailProvider  string `env:"EMAIL_PROVIDER" envDefault:"smtp"`
+	EmailFromName  string `env:"EMAIL_FROM_NAME" envDefault:"Authentio"`
+	SendGridAPIKey string `env:"SENDGRID_API_KEY"`
+	EmailOutboxDir string `env:"EMAIL_OUTBOX_DIR" envDefault:"tmp/emails"`
+	EmailInboxSize int    `env:"EMAIL_INBOX_SIZE" envDefault:"100"`
+	EmailSinkAddr  string `env:"EMAIL_SINK_ADDR" envDefault:"localhost:1025"`
+	EmailMaildir   string `env:"EMAIL_MAILDIR" envDefault:"tmp/maildir"`
+
+	// EMAIL_STARTUP_TEST_TO receives a test email at every startup (empty =
+	// none). SMTP providers are instead probed without sending (connection,
+	// TLS, authentication and NOOP) for GET /readyz, at most once every
+	// EMAIL_PROBE_INTERVAL (0 = no probe).
+	EmailStartupTestTo string        `env:"EMAIL_STARTUP_TEST_TO"`
+	EmailProbeInterval time.Duration `env:"EMAIL_PROBE_INTERVAL" envDefault:"1m"`
+
+	// Verification key (base64) of SendGrid's signed event webhook, which
+	// reports deliveries, bounces and opens to POST /api/v1/webhooks/email/sendgrid
+	// for the email delivery log. The webhook is refused without it.
+	SendGridWebhookPublicKey string `env:"SENDGRID_WEBHOOK_PUBLIC_KEY"`
+
+	// Redis email queue: worker count and retry policy (exponential backoff
+	// starting at EMAIL_QUEUE_BASE_BACKOFF, capped at EMAIL_QUEUE_MAX_BACKOFF)
+	EmailQueueWorkers     int           `env:"EMAIL_QUEUE_WORKERS" envDefault:"2"`
+	EmailQueueMaxAttempts int           `env:"EMAIL_QUEUE_MAX_ATTEMPTS" envDefault:"5"`
+	EmailQueueBaseBackoff time.Duration `env:"EMAIL_QUEUE_BASE_BACKOFF" envDefault:"30s"`
+	EmailQueueMaxBackoff  time.Duration `env:"EMAIL_QUEUE_MAX_BACKOFF" envDefault:"1h"`
+
+	// Redis task queue running registration side effects (onboarding emails,
+	// admin review requests, the registration webhook) after Register has
+	// returned: worker count, retry policy and how long a task is kept from
+	// being queued twice.
+	TaskQueueWorkers     int           `env:"TASK_QUEUE_WORKERS" envDefault:"4"`
+	TaskQueueMaxAttempts int           `env:"TASK_QUEUE_MAX_ATTEMPTS" envDefault:"8"`
+	TaskQueueBaseBackoff time.Duration `env:"TASK_QUEUE_BASE_BACKOFF" envDefault:"10s"`
+	TaskQueueMaxBackoff  time.Duration `env:"TASK_QUEUE_MAX_BACKOFF" envDefault:"30m"`
+	TaskDedupWindow      time.Duration `env:"TASK_DEDUP_WINDOW" envDefault:"24h"`
+
+	// user.registered events are posted to REGISTRATION_WEBHOOK_URL (e.g. an
+	// analytics pipeline or CRM), signed with REGISTRATION_WEBHOOK_SECRET.
+	RegistrationWebhookURL    string `env:"REGISTRATION_WEBHOOK_URL"`
+	RegistrationWebhookSecret string `env:"REGISTRATION_WEBHOOK_SECRET"`
+
+	// Without Redis, emails are sent by EMAIL_ASYNC_WORKERS in-process
+	// workers, up to EMAIL_ASYNC_BACKLOG waiting for them, without retries
+	// (0 workers = sent synchronously by the request)
+	EmailAsyncWorkers int `env:"EMAIL_ASYNC_WORKERS" envDefault:"4"`
+	EmailAsyncBacklog int `env:"EMAIL_ASYNC_BACKLOG" envDefault:"1000"`
+
+	// Disposable email domains: block, flag or off. The bundled list can be
+	// extended with a remote one-domain-per-line list refreshed periodically.
+	DisposableEmailMode    string        `env:"DISPOSABLE_EMAIL_MODE" envDefault:"block"`
+	DisposableEmailListURL string        `env:"DISPOSABLE_EMAIL_LIST_URL"`
+	DisposableEmailRefresh time.Duration `env:"DISPOSABLE_EMAIL_REFRESH" envDefault:"24h"`
+
+	// Registration email domain allowlist / blocklist (comma-separated). When an
+	// allowlist is set, only those domains and their subdomains may sign up.
+	// Rules added through the admin API are applied on top of these.
+	RegistrationAllowedDomains []string `env:"REGISTRATION_ALLOWED_DOMAINS" envSeparator:","`
+	RegistrationBlockedDomains []string `env:"REGISTRATION_BLOCKED_DOMAINS" envSeparator:","`
+
+	// Invitations: when INVITE_ONLY_REGISTRATION is set, signing up requires a
+	// pending invitation for the registering email. Admins can always invite;
+	// USERS_CAN_INVITE lets every user do so.
+	InviteOnlyRegistration bool          `env:"INVITE_ONLY_REGISTRATION" envDefault:"false"`
+	UsersCanInvite         bool          `env:"USERS_CAN_INVITE" envDefault:"false"`
+	InvitationTTL          time.Duration `env:"INVITATION_TTL" envDefault:"168h"`
+
+	// When REQUIRE_REGISTRATION_APPROVAL is set, new accounts (other than
+	// invited ones) stay pending until an admin approves them.
+	RequireRegistrationApproval bool `env:"REQUIRE_REGISTRATION_APPROVAL" envDefault:"false"`
+
+	// Passwords older than PASSWORD_MAX_AGE are reported as expired in login
+	// responses, so clients can ask for a new one (0 = passwords never expire).
+	PasswordMaxAge time.Duration `env:"PASSWORD_MAX_AGE" envDefault:"0"`
+	// Users logging in less than PASSWORD_EXPIRY_REMINDER before their
+	// password expires are emailed a reminder, once per password (0 = never).
+	PasswordExpiryReminder time.Duration `env:"PASSWORD_EXPIRY_REMINDER" envDefault:"168h"`
+
+	// Every PASSWORD_ROTATION_CHECK_INTERVAL, users whose password is older
+	// than PASSWORD_ROTATION_REMINDER_AGE, or was last set before
+	// PASSWORD_HASH_UPGRADED_AT (RFC 3339, when the hashing algorithm or its
+	// cost was strengthened), are emailed a reminder to change it (0 or unset
+	// = no reminders). A user isn't reminded again within
+	// PASSWORD_ROTATION_REMINDER_SUPPRESSION.
+	PasswordRotationReminderAge         time.Duration `env:"PASSWORD_ROTATION_REMINDER_AGE" envDefault:"0"`
+	PasswordHashUpgradedAt              time.Time     `env:"PASSWORD_HASH_UPGRADED_AT"`
+	PasswordRotationCheckInterval       time.Duration `env:"PASSWORD_ROTATION_CHECK_INTERVAL" envDefault:"24h"`
+	PasswordRotationReminderSuppression time.Duration `env:"PASSWORD_ROTATION_REMINDER_SUPPRESSION" envDefault:"720h"`
+
+	// ONBOARDING_EMAILS is the sequence of emails new users get, as
+	// step:delay entries counted from registration: welcome, tips and
+	// security (see constants.Onboarding*). Steps without a delay are sent at
+	// registration (or approval); the others by a job running every
+	// ONBOARDING_CHECK_INTERVAL. Empty sends none.
+	OnboardingEmails        []string      `env:"ONBOARDING_EMAILS" envSeparator:"," envDefault:"welcome:0,tips:72h"`
+	OnboardingCheckInterval time.Duration `env:"ONBOARDING_CHECK_INTERVAL" envDefault:"1h"`
+
+	// How often every tenant is scanned for likely duplicate accounts (same
+	// normalized email, phone or provider subject); 0 disables the scan.
+	DuplicateScanInterval time.Duration `env:"DUPLICATE_SCAN_INTERVAL" envDefault:"24h"`
+
+	// How often the data retention job deletes the rows kept past their
+	// retention; 0 keeps them.
+	CleanupInterval time.Duration `env:"CLEANUP_INTERVAL" envDefault:"1h"`
+
+	// Data retention: how long one-time codes and refresh tokens are kept
+	// once expired (or revoked), and login events (sign-ins) and the other
+	// audit log entries once recorded; 0 keeps events forever. Rows are
+	// deleted RETENTION_BATCH_SIZE at a time, RETENTION_BATCH_PAUSE apart,
+	// so no delete locks its table for long.
+	OTPRetention          time.Duration `env:"OTP_RETENTION" envDefault:"0"`
+	RefreshTokenRetention time.Duration `env:"REFRESH_TOKEN_RETENTION" envDefault:"0"`
+	LoginEventRetention   time.Duration `env:"LOGIN_EVENT_RETENTION" envDefault:"0"`
+	AuditLogRetention     time.Duration `env:"AUDIT_LOG_RETENTION" envDefault:"0"`
+	RetentionBatchSize    int           `env:"RETENTION_BATCH_SIZE" envDefault:"1000"`
+	RetentionBatchPause   time.Duration `env:"RETENTION_BATCH_PAUSE" envDefault:"100ms"`
+
+	// Scheduled jobs (cleanup, duplicate account scan, onboarding emails,
+	// password rotation reminders) run on one elected server. It holds the
+	// leadership for SCHEDULER_LEASE at a time and extends it every third of
+	// that; when it dies another server takes over within the lease.
+	SchedulerLease time.Duration `env:"SCHEDULER_LEASE" envDefault:"30s"`
+
+	// How long responses to requests sent with an Idempotency-Key header
+	// (registration, password reset, code emails) are replayed to retries.
+	IdempotencyKeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+
+	// User exports requested by admins are deleted EXPORT_TTL after the
+	// request; the signed links to download them are valid for EXPORT_LINK_TTL.
+	ExportTTL     time.Duration `env:"EXPORT_TTL" envDefault:"24h"`
+	ExportLinkTTL time.Duration `env:"EXPORT_LINK_TTL" envDefault:"15m"`
+
+	// How long the sudo scope obtained by re-authenticating (POST
+	// /user/reauthenticate) lasts; admin operations require it (1m-1h).
+	SudoTTL time.Duration `env:"SUDO_TTL" envDefault:"10m"`
+
+	// Multi-tenancy: TENANCY_MODE is off, header, subdomain or path. Each
+	// tenant has its own user pool; TENANTS lists the tenant slugs created at
+	// startup in addition to the built-in "default" tenant.
+	TenancyMode      string   `env:"TENANCY_MODE" envDefault:"off"`
+	TenantHeader     string   `env:"TENANT_HEADER" envDefault:"X-Tenant-ID"`
+	TenantBaseDomain string   `env:"TENANT_BASE_DOMAIN"`
+	Tenants          []string `env:"TENANTS" envSeparator:","`
+
+	// Per-tenant signing keys, issuers and token TTLs are read from the
+	// tenants table at startup and reloaded every TENANT_KEYS_REFRESH.
+	TenantKeysRefresh time.Duration `env:"TENANT_KEYS_REFRESH" envDefault:"5m"`
+
+	// Default daily quotas per tenant (0 = unlimited), enforced in Redis.
+	// Individual tenants can override them in the tenant_quotas table.
+	TenantQuotaRequestsPerDay      int64 `env:"TENANT_QUOTA_REQUESTS_PER_DAY" envDefault:"0"`
+	TenantQuotaRegistrationsPerDay int64 `env:"TENANT_QUOTA_REGISTRATIONS_PER_DAY" envDefault:"0"`
+	TenantQuotaOTPEmailsPerDay     int64 `env:"TENANT_QUOTA_OTP_EMAILS_PER_DAY" envDefault:"0"`
+
+	// Anti-abuse limits on /auth/register, enforced in Redis apart from the
+	// rate limiter: signups per IP address per day and per email address
+	// without its +tag per day (0 disables either). Going over one blocks the
+	// address for REGISTRATION_BLOCK_DURATION.
+	RegistrationMaxPerIPPerDay  int64         `env:"REGISTRATION_MAX_PER_IP_PER_DAY" envDefault:"20"`
+	RegistrationMaxPerEmailBase int64         `env:"REGISTRATION_MAX_PER_EMAIL_BASE" envDefault:"3"`
+	RegistrationBlockDuration   time.Duration `env:"REGISTRATION_BLOCK_DURATION" envDefault:"1h"`
+
+	// Per-recipient limits on 2FA and password reset code emails, enforced in
+	// Redis: the minimum delay between two codes and the daily maximum
+	// (0 disables either).
+	OTPResendCooldown time.Duration `env:"OTP_RESEND_COOLDOWN" envDefault:"60s"`
+	OTPDailyLimit     int64         `env:"OTP_DAILY_LIMIT" envDefault:"10"`
+
+	// Code policy by OTP type: length (4-12), charset (numeric or
+	// alphanumeric) and how long a code stays valid (1m-24h).
+	OTP2FALength            int           `env:"OTP_2FA_LENGTH" envDefault:"6"`
+	OTP2FACharset           string        `env:"OTP_2FA_CHARSET" envDefault:"numeric"`
+	OTP2FATTL               time.Duration `env:"OTP_2FA_TTL" envDefault:"10m"`
+	OTPPasswordResetLength  int           `env:"OTP_PASSWORD_RESET_LENGTH" envDefault:"6"`
+	OTPPasswordResetCharset string        `env:"OTP_PASSWORD_RESET_CHARSET" envDefault:"numeric"`
+	OTPPasswordResetTTL     time.Duration `env:"OTP_PASSWORD_RESET_TTL" envDefault:"10m"`
+
+	// Push approval 2FA. FCM is enabled by a Firebase service account key,
+	// APNs by a .p8 token signing key with its key ID, team ID and the app's
+	// bundle ID. PUSH_CHALLENGE_TTL is how long a login waits for approval.
+	PushFCMCredentialsFile string        `env:"PUSH_FCM_CREDENTIALS_FILE"`
+	PushAPNsKeyFile        string        `env:"PUSH_APNS_KEY_FILE"`
+	PushAPNsKeyID          string        `env:"PUSH_APNS_KEY_ID"`
+	PushAPNsTeamID         string        `env:"PUSH_APNS_TEAM_ID"`
+	PushAPNsTopic          string        `env:"PUSH_APNS_TOPIC"`
+	PushAPNsProduction     bool          `env:"PUSH_APNS_PRODUCTION" envDefault:"false"`
+	PushChallengeTTL       time.Duration `env:"PUSH_CHALLENGE_TTL" envDefault:"2m"`
+
+	// QR code pairing: how long a desktop's pairing code can be scanned, and
+	// how long each poll of the desktop waits for the app's answer (under
+	// REQUEST_TIMEOUT)
+	PairingCodeTTL  time.Duration `env:"PAIRING_CODE_TTL" envDefault:"2m"`
+	PairingPollWait time.Duration `env:"PAIRING_POLL_WAIT" envDefault:"8s"`
+
+	// Text messages for security notifications users opt into: twilio, log
+	// (development only; writes messages to the application log) or empty to
+	// disable them
+	SMSProvider      string `env:"SMS_PROVIDER"`
+	TwilioAccountSID string `env:"TWILIO_ACCOUNT_SID"`
+	TwilioAuthToken  string `env:"TWILIO_AUTH_TOKEN"`
+	TwilioFromNumber string `env:"TWILIO_FROM_NUMBER"`
+
+	// How long a user who lost their second factor waits, after proving
+	// access to their email, before 2FA is turned off (1h-30 days). The
+	// account owner is alerted meanwhile and can cancel the recovery.
+	TwoFARecoveryDelay time.Duration `env:"TWO_FA_RECOVERY_DELAY" envDefault:"24h"`
+
+	// Adaptive MFA thresholds on a login's risk score (0-100; 0 turns one
+	// off): push approval is skipped below MFA_RISK_SKIP_BELOW on a known
+	// device, users without push 2FA get an email code from
+	// MFA_RISK_REQUIRE_AT, logins from MFA_RISK_QUARANTINE_AT wait until the
+	// user approves them with an emailed link (valid LOGIN_QUARANTINE_TTL,
+	// 5m-24h), and logins from MFA_RISK_BLOCK_AT are refused. Tenants can
+	// override each threshold.
+	MFARiskSkipBelow    int           `env:"MFA_RISK_SKIP_BELOW" envDefault:"0"`
+	MFARiskRequireAt    int           `env:"MFA_RISK_REQUIRE_AT" envDefault:"0"`
+	MFARiskQuarantineAt int           `env:"MFA_RISK_QUARANTINE_AT" envDefault:"0"`
+	MFARiskBlockAt      int           `env:"MFA_RISK_BLOCK_AT" envDefault:"0"`
+	LoginQuarantineTTL  time.Duration `env:"LOGIN_QUARANTINE_TTL" envDefault:"30m"`
+
+	// How long an address stays allowed after a login from outside an
+	// account's IP allowlist passed step-up verification (5m-720h).
+	IPAllowlistStepUpTTL time.Duration `env:"IP_ALLOWLIST_STEP_UP_TTL" envDefault:"12h"`
+
+	// Anonymizer detection flags requests from proxies, VPNs and Tor
+	// ("is_proxy") and from hosting providers ("is_hosting") through
+	// ANONYMIZER_PROVIDER: off, ipapi (IPAPI_URL, shared with GeoIP), ipqs
+	// (IPQualityScore) or list (a local file of CIDR blocks). Lookups are
+	// cached for ANONYMIZER_CACHE_TTL. Flagged logins score higher risk, and
+	// requests to routes under the ANONYMIZER_BLOCK_*_ROUTES path prefixes
+	// ("*" for all) are refused unless a longer ANONYMIZER_ALLOW_ROUTES
+	// prefix matches.
+	AnonymizerProvider           string        `env:"ANONYMIZER_PROVIDER" envDefault:"off"`
+	IPAPIURL                     string        `env:"IPAPI_URL" envDefault:"http://ip-api.com/json/"`
+	AnonymizerIPQSKey            string        `env:"ANONYMIZER_IPQS_KEY"`
+	AnonymizerListFile           string        `env:"ANONYMIZER_LIST_FILE"`
+	AnonymizerCacheTTL           time.Duration `env:"ANONYMIZER_CACHE_TTL" envDefault:"6h"`
+	AnonymizerBlockProxyRoutes   []string      `env:"ANONYMIZER_BLOCK_PROXY_ROUTES" envSeparator:","`
+	AnonymizerBlockHostingRoutes []string      `env:"ANONYMIZER_BLOCK_HOSTING_ROUTES" envSeparator:","`
+	AnonymizerAllowRoutes        []string      `env:"ANONYMIZER_ALLOW_ROUTES" envSeparator:","`
+
+	// Geographic sign-in rules of tenants that haven't set their own through
+	// the admin API. Countries are ISO 3166-1 alpha-2 codes resolved by
+	// GeoIP: BLOCKED_COUNTRIES are refused, ALLOWED_COUNTRIES (when set) are
+	// the only ones not refused, and sign-ins from SUSPICIOUS_COUNTRIES get
+	// GEO_SUSPICIOUS_ACTION (log, step_up or block). Sign-ins implying travel
+	// faster than GEO_MAX_TRAVEL_SPEED_KMH since the user's previous one get
+	// GEO_VELOCITY_ACTION; 0 turns the check off.
+	BlockedCountries    []string `env:"BLOCKED_COUNTRIES" envSeparator:","`
+	AllowedCountries    []string `env:"ALLOWED_COUNTRIES" envSeparator:","`
+	SuspiciousCountries []string `env:"SUSPICIOUS_COUNTRIES" envSeparator:","`
+	GeoSuspiciousAction string   `env:"GEO_SUSPICIOUS_ACTION" envDefault:"log"`
+	GeoMaxTravelSpeed   int      `env:"GEO_MAX_TRAVEL_SPEED_KMH" envDefault:"0"`
+	GeoVelocityAction   string   `env:"GEO_VELOCITY_ACTION" envDefault:"step_up"`
+
+	// CAPTCHA gate on registration, login, password reset and 2FA email
+	// recovery: off, recaptcha, hcaptcha or turnstile, verified with the
+	// site's CAPTCHA_SECRET. reCAPTCHA v3 tokens scoring below
+	// CAPTCHA_MIN_SCORE (0.0-1.0) are rejected.
+	CaptchaProvider string  `env:"CAPTCHA_PROVIDER" envDefault:"off"`
+	CaptchaSecret   string  `env:"CAPTCHA_SECRET"`
+	CaptchaMinScore float64 `env:"CAPTCHA_MIN_SCORE" envDefault:"0.5"`
+
+	// OpenID Connect provider mode, enabled by setting OIDC_ISSUER (the public
+	// base URL of this server). Tokens for registered clients are signed with
+	// the RSA key in OIDC_SIGNING_KEY_FILE; without one a temporary key is
+	// generated (development only). The authorization endpoint sends users to
+	// OIDC_CONSENT_URL (default FRONTEND_URL/oauth/consent) to sign in and
+	// approve the request.
+	OIDCIssuer         string        `env:"OIDC_ISSUER"`
+	OIDCSigningKeyFile string        `env:"OIDC_SIGNING_KEY_FILE"`
+	OIDCConsentURL     string        `env:"OIDC_CONSENT_URL"`
+	OIDCCodeTTL        time.Duration `env:"OIDC_CODE_TTL" envDefault:"5m"`
+
+	// Federated sign-in, enabled by setting FEDERATED_ISSUER: tokens issued by
+	// that external identity provider for FEDERATED_AUDIENCE are verified
+	// with the keys published at FEDERATED_JWKS_URL, fetched again every
+	// FEDERATED_JWKS_REFRESH, and exchanged for Authentio tokens.
+	FederatedIssuer      string        `env:"FEDERATED_ISSUER"`
+	FederatedJWKSURL     string        `env:"FEDERATED_JWKS_URL"`
+	FederatedAudience    string        `env:"FEDERATED_AUDIENCE"`
+	FederatedJWKSRefresh time.Duration `env:"FEDERATED_JWKS_REFRESH" envDefault:"1h"`
+
+	// TLS for the HTTP and gRPC listeners, served when TLS_CERT_FILE and
+	// TLS_KEY_FILE are set. With MTLS_CA_FILE, clients may present a
+	// certificate signed by one of its CAs: MTLS_REQUIRED_ROUTES lists the
+	// HTTP path and gRPC method prefixes ("*" for all) that require one, and
+	// MTLS_ALLOWED_IDENTITIES the common names, DNS or URI SANs accepted
+	// there (empty accepts any certificate the CAs signed).
+	TLSCertFile           string   `env:"TLS_CERT_FILE"`
+	TLSKeyFile            string   `env:"TLS_KEY_FILE"`
+	MTLSCAFile            string   `env:"MTLS_CA_FILE"`
+	MTLSRequiredRoutes    []string `env:"MTLS_REQUIRED_ROUTES" envSeparator:","`
+	MTLSAllowedIdentities []string `env:"MTLS_ALLOWED_IDENTITIES" envSeparator:","`
+
+	// gRPC API for internal services, served on GRPC_PORT when it is set.
+	// Callers authenticate with the shared GRPC_API_KEY.
+	GRPCPort   int    `env:"GRPC_PORT" envDefault:"0"`
+	GRPCAPIKey string `env:"GRPC_API_KEY"`
+
+	// Security metrics (failed logins, lockouts, revoked token use,
+	// blocked-country requests, OTP failures) served on GET /metrics in the
+	// Prometheus format to scrapers sending METRICS_TOKEN as a bearer token;
+	// the endpoint is off without one.
+	MetricsToken string `env:"METRICS_TOKEN"`
+
+	// Security alerts: when one of those events happens ALERT_*_THRESHOLD
+	// times within ALERT_WINDOW on an instance (0 disables that alert), an
+	// alert is posted to ALERT_WEBHOOK_URL, at most once per window.
+	// ALERT_WEBHOOK_FORMAT is slack or pagerduty (Events API v2, routed with
+	// ALERT_PAGERDUTY_ROUTING_KEY).
+	AlertWebhookURL              string        `env:"ALERT_WEBHOOK_URL"`
+	AlertWebhookFormat           string        `env:"ALERT_WEBHOOK_FORMAT" envDefault:"slack"`
+	AlertPagerDutyRoutingKey     string        `env:"ALERT_PAGERDUTY_ROUTING_KEY"`
+	AlertWindow                  time.Duration `env:"ALERT_WINDOW" envDefault:"5m"`
+	AlertFailedLoginsThreshold   int64         `env:"ALERT_FAILED_LOGINS_THRESHOLD" envDefault:"0"`
+	AlertLockoutsThreshold       int64         `env:"ALERT_LOCKOUTS_THRESHOLD" envDefault:"0"`
+	AlertRevokedTokensThreshold  int64         `env:"ALERT_REVOKED_TOKENS_THRESHOLD" envDefault:"0"`
+	AlertBlockedCountryThreshold int64         `env:"ALERT_BLOCKED_COUNTRY_THRESHOLD" envDefault:"0"`
+	AlertOTPFailuresThreshold    int64         `env:"ALERT_OTP_FAILURES_THRESHOLD" envDefault:"0"`
+
+	// Optional GraphQL endpoint (POST /api/v1/graphql) alongside the REST API
+	GraphQLEnabled bool `env:"GRAPHQL_ENABLED" envDefault:"false"`
+
+	// Refuse requests to documented endpoints that don't match the generated
+	// OpenAPI document (docs/swagger.json): unknown fields, wrong types, enums
+	OpenAPIValidationEnabled bool `env:"OPENAPI_VALIDATION_ENABLED" envDefault:"true"`
+
+	// Object storage for user uploads (avatars). STORAGE_PROVIDER is none or
+	// s3; the s3 provider also works with MinIO and other S3-compatible
+	// servers. Uploaded files are served from STORAGE_PUBLIC_URL (e.g. a CDN),
+	// defaulting to the bucket URL.
+	StorageProvider  string `env:"STORAGE_PROVIDER" envDefault:"none"`
+	S3Endpoint       string `env:"S3_ENDPOINT"`
+	S3Region         string `env:"S3_REGION"`
+	S3Bucket         string `env:"S3_BUCKET"`
+	S3AccessKey      string `env:"S3_ACCESS_KEY"`
+	S3SecretKey      string `env:"S3_SECRET_KEY"`
+	S3UseSSL         bool   `env:"S3_USE_SSL" envDefault:"true"`
+	StoragePublicURL string `env:"STORAGE_PUBLIC_URL"`
+
+	// Audit log archival: with AUDIT_ARCHIVE_BUCKET set, audit log entries
+	// are archived as gzipped NDJSON to that bucket of the S3 server above
+	// (under AUDIT_ARCHIVE_PREFIX) before data retention deletes them, and
+	// admins can export date ranges on demand. The bucket must not be
+	// public, unlike the avatar bucket.
+	AuditArchiveBucket string `env:"AUDIT_ARCHIVE_BUCKET"`
+	AuditArchivePrefix string `env:"AUDIT_ARCHIVE_PREFIX" envDefault:"audit-logs/"`
+
+	// Avatar uploads: maximum upload size and the edge length, in pixels, of
+	// the square image they are resized to
+	AvatarMaxBytes int64 `env:"AVATAR_MAX_BYTES" envDefault:"5242880"` // 5 MiB
+	AvatarSize     int   `env:"AVATAR_SIZE" envDefault:"256"`
+
+	// Whether access tokens carry the user_metadata / app_metadata claims.
+	// Tokens are re-issued on refresh, so changes show up after the next one.
+	TokenIncludeUserMetadata bool `env:"TOKEN_INCLUDE_USER_METADATA" envDefault:"false"`
+	TokenIncludeAppMetadata  bool `env:"TOKEN_INCLUDE_APP_METADATA" envDefault:"false"`
+
+	// Base URL of the frontend, used to build links in emails
+	FrontendURL string `env:"FRONTEND_URL" envDefault:"http://localhost:3000"`
+
+	// Current legal document versions. Bumping a version makes every user who
+	// accepted an older one get consent_required=true until they re-accept.
+	TOSVersion           string `env:"TOS_VERSION" envDefault:"1.0"`
+	PrivacyPolicyVersion string `env:"PRIVACY_POLICY_VERSION" envDefault:"1.0"`
 }
 
 // This loads the config from environment variables and optionally .env file
@@ -40,17 +584,258 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	// This code is performing custom validation on the server port configuration
-	if cfg.ServerPort <= 0 || cfg.ServerPort > 65535 {
-		return nil,  ErrInvalidPort(cfg.ServerPort)
+	// Keys wrapped by the KMS are unwrapped first, so they are validated
+	// like the others
+	if err := cfg.connectKMS(context.Background()); err != nil {
+		return nil, err
+	}
+
+	// Report every misconfiguration at once rather than failing later at runtime
+	warnings, err := cfg.Validate()
+	for _, warning := range warnings {
+		log.Println("config warning:", warning)
+	}
+	if err != nil {
+		return nil, err
 	}
 
+	GoogleOAuthConfig.ClientID = cfg.GoogleClientID
+	GoogleOAuthConfig.ClientSecret = cfg.GoogleClientSecret
+	GoogleOAuthConfig.RedirectURL = cfg.GoogleRedirectURL
+
 	return cfg, nil
 }
 
-// An example of custom error
-type ErrInvalidPort int
+// connectKMS connects to KMS_PROVIDER, if set, and replaces the key settings
+// wrapped by it with their value.
+func (cfg *Config) connectKMS(ctx context.Context) error {
+	if cfg.KMSProvider == "" {
+		return nil // references left are reported by Validate
+	}
+	keys, err := kms.New(ctx, kms.Config{
+		Provider:   strings.ToLower(cfg.KMSProvider),
+		KeyID:      cfg.KMSKeyID,
+		AWSRegion:  cfg.KMSRegion,
+		VaultAddr:  cfg.VaultAddr,
+		VaultToken: cfg.VaultToken,
+		VaultMount: cfg.VaultTransitMount,
+		CacheTTL:   cfg.KMSCacheTTL,
+	})
+	if err != nil {
+		return fmt.Errorf("KMS_PROVIDER: %w", err)
+	}
+	cfg.kms = keys
+
+	for _, setting := range []struct {
+		name  string
+		value *string
+	}{
+		{"JWT_SECRET", &cfg.JWTSecret},
+		{"SECRETS_ENCRYPTION_KEY", &cfg.SecretsEncryptionKey},
+		{"PII_INDEX_KEY", &cfg.PIIIndexKey},
+	} {
+		if *setting.value, err = keys.Resolve(*setting.value); err != nil {
+			return fmt.Errorf("%s: %w", setting.name, err)
+		}
+	}
+	for i, secret := range cfg.JWTPreviousSecrets {
+		if cfg.JWTPreviousSecrets[i], err = keys.Resolve(strings.TrimSpace(secret)); err != nil {
+			return fmt.Errorf("JWT_PREVIOUS_SECRETS entry %d: %w", i+1, err)
+		}
+	}
+	// id:kms:<ciphertext> entries
+	for name, entries := range map[string][]string{
+		"JWT_RETIRED_KEYS":     cfg.JWTRetiredKeys,
+		"SECRETS_RETIRED_KEYS": cfg.SecretsRetiredKeys,
+	} {
+		for i, entry := range entries {
+			id, value, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok || !kms.IsReference(value) {
+				continue
+			}
+			if value, err = keys.Resolve(value); err != nil {
+				return fmt.Errorf("%s key %q: %w", name, id, err)
+			}
+			entries[i] = id + ":" + value
+		}
+	}
+	return nil
+}
+
+// KMS returns the client of KMS_PROVIDER, or nil when it is unset.
+func (cfg *Config) KMS() *kms.Keys {
+	return cfg.kms
+}
+
+// RetiredJWTKeys parses JWT_PREVIOUS_SECRETS and JWT_RETIRED_KEYS.
+func (cfg *Config) RetiredJWTKeys() ([]jwt.RetiredKey, error) {
+	keys := make([]jwt.RetiredKey, 0, len(cfg.JWTPreviousSecrets)+len(cfg.JWTRetiredKeys))
+	for i, secret := range cfg.JWTPreviousSecrets {
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			return nil, fmt.Errorf("JWT_PREVIOUS_SECRETS entry %d is empty", i+1)
+		}
+		keys = append(keys, jwt.RetiredKey{ID: cfg.JWTKeyID, Secret: secret, RetiredAt: cfg.JWTKeysRotatedAt})
+	}
+	for _, entry := range cfg.JWTRetiredKeys {
+		entry = strings.TrimSpace(entry)
+		key := jwt.RetiredKey{Secret: entry, RetiredAt: cfg.JWTKeysRotatedAt}
+		if id, secret, ok := strings.Cut(entry, ":"); ok {
+			key.ID, key.Secret = id, secret
+		}
+		if key.Secret == "" {
+			return nil, fmt.Errorf("JWT_RETIRED_KEYS entry %q has no secret", entry)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SecretsKeyring builds the keyring of SECRETS_ENCRYPTION_KEY and
+// SECRETS_RETIRED_KEYS, or of the KMS key with SECRETS_USE_KMS. It returns
+// nil when no key is set, in which case secrets are stored unencrypted.
+func (cfg *Config) SecretsKeyring() (*secretbox.Keyring, error) {
+	if cfg.SecretsEncryptionKey == "" && !cfg.SecretsUseKMS {
+		if len(cfg.SecretsRetiredKeys) > 0 {
+			return nil, fmt.Errorf("SECRETS_ENCRYPTION_KEY is required when SECRETS_RETIRED_KEYS is set")
+		}
+		return nil, nil
+	}
+
+	retired := make([]secretbox.Key, 0, len(cfg.SecretsRetiredKeys)+1)
+	for _, entry := range cfg.SecretsRetiredKeys {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, fmt.Errorf("SECRETS_RETIRED_KEYS entries must be id:key")
+		}
+		key, err := secretbox.ParseKey(id, encoded)
+		if err != nil {
+			return nil, fmt.Errorf("SECRETS_RETIRED_KEYS: %w", err)
+		}
+		retired = append(retired, key)
+	}
+	var current secretbox.Key
+	if cfg.SecretsEncryptionKey != "" {
+		var err error
+		if current, err = secretbox.ParseKey(cfg.SecretsKeyID, cfg.SecretsEncryptionKey); err != nil {
+			return nil, fmt.Errorf("SECRETS_ENCRYPTION_KEY: %w", err)
+		}
+	}
+
+	if cfg.SecretsUseKMS {
+		if cfg.kms == nil {
+			return nil, fmt.Errorf("KMS_PROVIDER is required when SECRETS_USE_KMS is set")
+		}
+		// The local key, if any, only opens values sealed before the move
+		if cfg.SecretsEncryptionKey != "" {
+			retired = append(retired, current)
+		}
+		keyring, err := secretbox.NewWrappedKeyring(cfg.kms, retired...)
+		if err != nil {
+			return nil, fmt.Errorf("SECRETS_USE_KMS: %w", err)
+		}
+		return keyring, nil
+	}
+
+	keyring, err := secretbox.NewKeyring(current, retired...)
+	if err != nil {
+		return nil, fmt.Errorf("SECRETS_RETIRED_KEYS: %w", err)
+	}
+	return keyring, nil
+}
+
+// PIIIndex builds the blind index of PII_INDEX_KEY, or returns nil when it
+// is unset.
+func (cfg *Config) PIIIndex() (*secretbox.Index, error) {
+	if cfg.PIIIndexKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.PIIIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("PII_INDEX_KEY is not valid base64")
+	}
+	index, err := secretbox.NewIndex(key)
+	if err != nil {
+		return nil, fmt.Errorf("PII_INDEX_KEY: %w", err)
+	}
+	return index, nil
+}
+
+// OnboardingStep is an email of the onboarding sequence, sent Delay after
+// registration.
+type OnboardingStep struct {
+	Name  string
+	Delay time.Duration
+}
+
+// devCORSOrigins are the usual origins of local development servers
+// (Create React App and Next.js, Angular, Vite, webpack), allowed to call
+// the API outside production when CORS_ALLOWED_ORIGINS is empty.
+var devCORSOrigins = []string{
+	"http://localhost:3000",
+	"http://localhost:4200",
+	"http://localhost:5173",
+	"http://localhost:8080",
+}
+
+// APICORSOrigins returns the origins allowed to call the API with
+// credentials: CORS_ALLOWED_ORIGINS, or local development servers outside
+// production when it is empty.
+func (cfg *Config) APICORSOrigins() []string {
+	if len(cfg.CORSAllowedOrigins) == 0 && cfg.Env != "production" {
+		return devCORSOrigins
+	}
+	return cfg.CORSAllowedOrigins
+}
 
-func (e ErrInvalidPort) Error() string {
-	return "invalid server port: " + strconv.Itoa(int(e))
+// SessionSameSite returns the SameSite attribute of SESSION_COOKIE_SAMESITE.
+func (cfg *Config) SessionSameSite() http.SameSite {
+	switch cfg.SessionCookieSameSite {
+	case constants.SameSiteStrict:
+		return http.SameSiteStrictMode
+	case constants.SameSiteNone:
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+// OnboardingSequence parses ONBOARDING_EMAILS.
+func (cfg *Config) OnboardingSequence() ([]OnboardingStep, error) {
+	steps := make([]OnboardingStep, 0, len(cfg.OnboardingEmails))
+	seen := make(map[string]bool, len(cfg.OnboardingEmails))
+	for _, entry := range cfg.OnboardingEmails {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, delay, _ := strings.Cut(entry, ":")
+		step := OnboardingStep{Name: strings.TrimSpace(name)}
+		switch step.Name {
+		case constants.OnboardingWelcome, constants.OnboardingTips, constants.OnboardingSecurity:
+		default:
+			return nil, fmt.Errorf("ONBOARDING_EMAILS step %q must be welcome, tips or security", step.Name)
+		}
+		if seen[step.Name] {
+			return nil, fmt.Errorf("ONBOARDING_EMAILS lists %q twice", step.Name)
+		}
+		seen[step.Name] = true
+		if delay = strings.TrimSpace(delay); delay != "" {
+			d, err := time.ParseDuration(delay)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("ONBOARDING_EMAILS step %q has an invalid delay %q", step.Name, delay)
+			}
+			step.Delay = d
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// OTPPolicy returns the code policy for an OTP type. Types without their own
+// settings use the 2FA policy.
+func (cfg *Config) OTPPolicy(kind constants.Type) otp.Policy {
+	if kind == constants.TypePasswordReset {
+		return otp.Policy{Length: cfg.OTPPasswordResetLength, Charset: cfg.OTPPasswordResetCharset, TTL: cfg.OTPPasswordResetTTL}
+	}
+	return otp.Policy{Length: cfg.OTP2FALength, Charset: cfg.OTP2FACharset, TTL: cfg.OTP2FATTL}
 }
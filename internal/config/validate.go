@@ -0,0 +1,966 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/anonymizer"
+	"authentio/pkg/captcha"
+	"authentio/pkg/email"
+	"authentio/pkg/kms"
+	"authentio/pkg/metrics"
+	"authentio/pkg/origin"
+	"authentio/pkg/otp"
+	"authentio/pkg/sms"
+	"authentio/pkg/storage"
+)
+
+// minJWTSecretLength is the shortest JWT_SECRET accepted in production
+// (256 bits for HS256).
+const minJWTSecretLength = 32
+
+// maxStatelessAccessTokenTTL is the longest ACCESS_TOKEN_TTL accepted in
+// production with REVOCATION_STRATEGY=stateless, since revoked sessions keep
+// their access token until it expires.
+const maxStatelessAccessTokenTTL = 15 * time.Minute
+
+// ValidationError lists every misconfiguration found by Validate, so they
+// can all be fixed at once instead of one restart at a time.
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements the error interface with a one-problem-per-line report.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problems):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// configCheck collects problems (which make the configuration unusable) and
+// warnings (which only matter outside development) while validating.
+type configCheck struct {
+	production bool
+	problems   []string
+	warnings   []string
+}
+
+// fail records a problem.
+func (c *configCheck) fail(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+// strict records a problem in production and a warning elsewhere.
+func (c *configCheck) strict(format string, args ...interface{}) {
+	if c.production {
+		c.fail(format, args...)
+		return
+	}
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the whole configuration. It returns warnings for settings
+// that are tolerated outside production, and a *ValidationError listing
+// every problem when the configuration can't be used.
+func (cfg *Config) Validate() (warnings []string, err error) {
+	c := &configCheck{production: cfg.Env == "production"}
+
+	cfg.validateServer(c)
+	cfg.validateTLS(c)
+	cfg.validateTokens(c)
+	cfg.validateSessionCookie(c)
+	cfg.validateCORS(c)
+	cfg.validateKMS(c)
+	cfg.validateSecretsKeys(c)
+	cfg.validateEmail(c)
+	cfg.validateRegistration(c)
+	cfg.validateOTP(c)
+	cfg.validateGoogleOAuth(c)
+	cfg.validateOIDC(c)
+	cfg.validateFederation(c)
+	cfg.validateStorage(c)
+	cfg.validateAnonymizer(c)
+	cfg.validateGeoPolicy(c)
+	cfg.validateCaptcha(c)
+	cfg.validateAlerts(c)
+
+	if len(c.problems) > 0 {
+		return c.warnings, &ValidationError{Problems: c.problems}
+	}
+	return c.warnings, nil
+}
+
+// =============================================================================
+// Checks by Area
+// =============================================================================
+
+// validateServer checks listeners, Redis and URLs.
+func (cfg *Config) validateServer(c *configCheck) {
+	if cfg.ServerPort <= 0 || cfg.ServerPort > 65535 {
+		c.fail("SERVER_PORT must be between 1 and 65535, got %d", cfg.ServerPort)
+	}
+
+	if cfg.GRPCPort < 0 || cfg.GRPCPort > 65535 {
+		c.fail("GRPC_PORT must be between 0 (disabled) and 65535, got %d", cfg.GRPCPort)
+	} else if cfg.GRPCPort != 0 {
+		if cfg.GRPCPort == cfg.ServerPort {
+			c.fail("GRPC_PORT and SERVER_PORT must differ (both %d)", cfg.GRPCPort)
+		}
+		if cfg.GRPCAPIKey == "" {
+			c.fail("GRPC_API_KEY is required when GRPC_PORT is set")
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.RedisAddr); err != nil {
+		c.fail("REDIS_ADDR must be host:port, got %q", cfg.RedisAddr)
+	}
+
+	checkURL(c, "FRONTEND_URL", cfg.FrontendURL)
+
+	if cfg.RequestTimeout < 0 || cfg.RequestTimeout >= ServerWriteTimeout {
+		c.fail("REQUEST_TIMEOUT must be between 0 (no deadline) and the %s server write timeout, got %s", ServerWriteTimeout, cfg.RequestTimeout)
+	}
+	if cfg.DBSlowQueryThreshold < 0 {
+		c.fail("DB_SLOW_QUERY_THRESHOLD must be 0 (not logged) or positive, got %s", cfg.DBSlowQueryThreshold)
+	}
+	if cfg.PoolMetricsInterval <= 0 {
+		c.fail("POOL_METRICS_INTERVAL must be positive, got %s", cfg.PoolMetricsInterval)
+	}
+	if cfg.OTPRetention < 0 || cfg.RefreshTokenRetention < 0 || cfg.LoginEventRetention < 0 || cfg.AuditLogRetention < 0 {
+		c.fail("OTP_RETENTION, REFRESH_TOKEN_RETENTION, LOGIN_EVENT_RETENTION and AUDIT_LOG_RETENTION must not be negative")
+	}
+	if cfg.RetentionBatchSize < 1 {
+		c.fail("RETENTION_BATCH_SIZE must be at least 1, got %d", cfg.RetentionBatchSize)
+	}
+	if cfg.RetentionBatchPause < 0 {
+		c.fail("RETENTION_BATCH_PAUSE must not be negative, got %s", cfg.RetentionBatchPause)
+	}
+	if cfg.SchedulerLease < 3*time.Second {
+		c.fail("SCHEDULER_LEASE must be at least 3s, got %s", cfg.SchedulerLease)
+	}
+
+	if cfg.HealthSlowThreshold < 0 {
+		c.fail("HEALTH_SLOW_THRESHOLD must be 0 (never slow) or positive, got %s", cfg.HealthSlowThreshold)
+	}
+	if cfg.HealthExternalCheckInterval < 0 {
+		c.fail("HEALTH_EXTERNAL_CHECK_INTERVAL must be 0 (not checked) or positive, got %s", cfg.HealthExternalCheckInterval)
+	}
+	if cfg.CircuitBreakerFailures < 0 {
+		c.fail("CIRCUIT_BREAKER_FAILURES must be 0 (no breakers) or positive, got %d", cfg.CircuitBreakerFailures)
+	}
+	if cfg.CircuitBreakerFailures > 0 && cfg.CircuitBreakerOpenTimeout <= 0 {
+		c.fail("CIRCUIT_BREAKER_OPEN_TIMEOUT must be positive, got %s", cfg.CircuitBreakerOpenTimeout)
+	}
+	if cfg.ExternalRetryAttempts < 1 {
+		c.fail("EXTERNAL_RETRY_ATTEMPTS must be at least 1, got %d", cfg.ExternalRetryAttempts)
+	}
+	if cfg.ExternalRetryBudget < 0 || cfg.ExternalRetryBudget > 1 {
+		c.fail("EXTERNAL_RETRY_BUDGET must be between 0 (no limit) and 1, got %g", cfg.ExternalRetryBudget)
+	}
+}
+
+// validateTLS checks the listener certificate and client certificate
+// (mTLS) settings.
+func (cfg *Config) validateTLS(c *configCheck) {
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		c.fail("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for _, file := range []struct{ name, path string }{
+		{"TLS_CERT_FILE", cfg.TLSCertFile},
+		{"TLS_KEY_FILE", cfg.TLSKeyFile},
+		{"MTLS_CA_FILE", cfg.MTLSCAFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			c.fail("%s is not readable: %v", file.name, err)
+		}
+	}
+
+	if cfg.MTLSCAFile == "" {
+		if len(cfg.MTLSRequiredRoutes) > 0 || len(cfg.MTLSAllowedIdentities) > 0 {
+			c.fail("MTLS_REQUIRED_ROUTES and MTLS_ALLOWED_IDENTITIES need MTLS_CA_FILE")
+		}
+		return
+	}
+	// Client certificates are only requested during our own TLS handshake
+	if cfg.TLSCertFile == "" {
+		c.fail("MTLS_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE: client certificates are verified during the TLS handshake")
+	}
+	for _, prefix := range cfg.MTLSRequiredRoutes {
+		if prefix = strings.TrimSpace(prefix); prefix != "*" && !strings.HasPrefix(prefix, "/") {
+			c.fail("MTLS_REQUIRED_ROUTES entries must be path or gRPC method prefixes starting with / or *, got %q", prefix)
+		}
+	}
+	if len(cfg.MTLSRequiredRoutes) == 0 {
+		c.warnings = append(c.warnings, "MTLS_REQUIRED_ROUTES is empty: client certificates are verified when presented but never required")
+	}
+}
+
+// validateTokens checks JWT signing and token lifetimes.
+func (cfg *Config) validateTokens(c *configCheck) {
+	if len(cfg.JWTSecret) < minJWTSecretLength {
+		c.strict("JWT_SECRET must be at least %d characters, got %d", minJWTSecretLength, len(cfg.JWTSecret))
+	}
+	if cfg.AccessTokenTTL <= 0 {
+		c.fail("ACCESS_TOKEN_TTL must be positive, got %s", cfg.AccessTokenTTL)
+	}
+	if cfg.RefreshTokenTTL <= cfg.AccessTokenTTL {
+		c.fail("REFRESH_TOKEN_TTL (%s) must be longer than ACCESS_TOKEN_TTL (%s)", cfg.RefreshTokenTTL, cfg.AccessTokenTTL)
+	}
+	if cfg.RefreshTokenMaxAge < 0 || (cfg.RefreshTokenMaxAge > 0 && cfg.RefreshTokenMaxAge <= cfg.AccessTokenTTL) {
+		c.fail("REFRESH_TOKEN_MAX_AGE must be 0 (no limit) or longer than ACCESS_TOKEN_TTL (%s), got %s", cfg.AccessTokenTTL, cfg.RefreshTokenMaxAge)
+	}
+	if cfg.RefreshTokenIdleTimeout < 0 || (cfg.RefreshTokenIdleTimeout > 0 && cfg.RefreshTokenIdleTimeout <= cfg.AccessTokenTTL) {
+		c.fail("REFRESH_TOKEN_IDLE_TIMEOUT must be 0 (no limit) or longer than ACCESS_TOKEN_TTL (%s), got %s", cfg.AccessTokenTTL, cfg.RefreshTokenIdleTimeout)
+	} else if cfg.RefreshTokenIdleTimeout >= cfg.RefreshTokenTTL {
+		c.warnings = append(c.warnings, "REFRESH_TOKEN_IDLE_TIMEOUT is not shorter than REFRESH_TOKEN_TTL: idle refresh tokens expire first")
+	}
+	if cfg.RefreshTokenGracePeriod < 0 || cfg.RefreshTokenGracePeriod > 2*time.Minute {
+		c.fail("REFRESH_TOKEN_GRACE_PERIOD must be between 0 (off) and 2m, got %s", cfg.RefreshTokenGracePeriod)
+	}
+	switch cfg.RevocationStrategy {
+	case constants.RevocationStateful:
+	case constants.RevocationStateless:
+		if cfg.AccessTokenTTL > maxStatelessAccessTokenTTL {
+			c.strict("ACCESS_TOKEN_TTL must be at most %s with REVOCATION_STRATEGY=stateless, got %s", maxStatelessAccessTokenTTL, cfg.AccessTokenTTL)
+		}
+	default:
+		c.fail("REVOCATION_STRATEGY must be stateful or stateless, got %q", cfg.RevocationStrategy)
+	}
+	if cfg.RevocationFilterCapacity < 1000 {
+		c.fail("REVOCATION_FILTER_CAPACITY must be at least 1000, got %d", cfg.RevocationFilterCapacity)
+	}
+	if cfg.RevocationFilterRefresh < time.Minute {
+		c.fail("REVOCATION_FILTER_REFRESH must be at least 1m, got %s", cfg.RevocationFilterRefresh)
+	}
+	if cfg.RevocationCacheSize < 0 {
+		c.fail("REVOCATION_CACHE_SIZE must not be negative, got %d", cfg.RevocationCacheSize)
+	}
+	if cfg.TenantKeysRefresh <= 0 {
+		c.fail("TENANT_KEYS_REFRESH must be positive, got %s", cfg.TenantKeysRefresh)
+	}
+	cfg.validateKeyRotation(c)
+	if cfg.JWTLeeway < 0 || cfg.JWTLeeway > 5*time.Minute {
+		c.fail("JWT_LEEWAY must be between 0 and 5m, got %s", cfg.JWTLeeway)
+	}
+	if cfg.IdempotencyKeyTTL < time.Minute || cfg.IdempotencyKeyTTL > 7*24*time.Hour {
+		c.fail("IDEMPOTENCY_KEY_TTL must be between 1m and 168h, got %s", cfg.IdempotencyKeyTTL)
+	}
+	if cfg.ExportTTL < time.Hour || cfg.ExportTTL > 7*24*time.Hour {
+		c.fail("EXPORT_TTL must be between 1h and 168h, got %s", cfg.ExportTTL)
+	}
+	if cfg.ExportLinkTTL < time.Minute || cfg.ExportLinkTTL > 24*time.Hour {
+		c.fail("EXPORT_LINK_TTL must be between 1m and 24h, got %s", cfg.ExportLinkTTL)
+	}
+	if cfg.SudoTTL < time.Minute || cfg.SudoTTL > time.Hour {
+		c.fail("SUDO_TTL must be between 1m and 1h, got %s", cfg.SudoTTL)
+	}
+}
+
+// validateCORS checks the origins of the CORS policies.
+func (cfg *Config) validateCORS(c *configCheck) {
+	for _, pattern := range cfg.CORSAllowedOrigins {
+		if pattern == origin.Any {
+			c.fail("CORS_ALLOWED_ORIGINS must list origins: \"*\" would let any site call the API with credentials")
+			continue
+		}
+		checkOriginPattern(c, "CORS_ALLOWED_ORIGINS", pattern)
+	}
+	if len(cfg.CORSAllowedOrigins) == 0 && c.production {
+		c.warnings = append(c.warnings, "CORS_ALLOWED_ORIGINS is empty: browser apps on other origins can't call the API")
+	}
+	for _, pattern := range cfg.CORSPublicOrigins {
+		checkOriginPattern(c, "CORS_PUBLIC_ORIGINS", pattern)
+	}
+}
+
+// checkOriginPattern checks that value is "*", an origin or a wildcard
+// subdomain pattern such as https://*.example.com.
+func checkOriginPattern(c *configCheck, name, value string) {
+	if _, err := origin.Parse(value); err != nil {
+		c.fail("%s: %v", name, err)
+	}
+}
+
+// validateSessionCookie checks the cookie sessions of single-page apps.
+func (cfg *Config) validateSessionCookie(c *configCheck) {
+	if !validCookieName(cfg.SessionCookieName) {
+		c.fail("SESSION_COOKIE_NAME must be a non-empty cookie name, got %q", cfg.SessionCookieName)
+	}
+	switch cfg.SessionCookieSameSite {
+	case constants.SameSiteStrict, constants.SameSiteLax:
+	case constants.SameSiteNone:
+		if !cfg.SessionCookieSecure {
+			c.fail("SESSION_COOKIE_SECURE must be true with SESSION_COOKIE_SAMESITE=none")
+		}
+	default:
+		c.fail("SESSION_COOKIE_SAMESITE must be strict, lax or none, got %q", cfg.SessionCookieSameSite)
+	}
+	if !cfg.SessionCookieSecure {
+		c.strict("SESSION_COOKIE_SECURE is false: session cookies are sent over plain HTTP")
+	}
+	for _, allowed := range cfg.SessionAllowedOrigins {
+		checkOrigin(c, "SESSION_ALLOWED_ORIGINS", allowed)
+	}
+}
+
+// checkOrigin checks that value is an origin (scheme://host[:port]).
+func checkOrigin(c *configCheck, name, value string) {
+	if u := checkURL(c, name, value); u != nil && (u.Path != "" || u.RawQuery != "" || u.User != nil) {
+		c.fail("%s must list origins (scheme://host[:port]), got %q", name, value)
+	}
+}
+
+// validCookieName reports whether name is a valid cookie name (an RFC 7230
+// token).
+func validCookieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateKeyRotation checks the JWT key ring.
+func (cfg *Config) validateKeyRotation(c *configCheck) {
+	retired, err := cfg.RetiredJWTKeys()
+	if err != nil {
+		c.fail("%v", err)
+		return
+	}
+	if len(retired) == 0 {
+		return
+	}
+	if cfg.JWTKeyID == "" && len(cfg.JWTRetiredKeys) > 0 {
+		c.fail("JWT_KEY_ID is required when JWT_RETIRED_KEYS is set, so tokens name the key that signed them")
+	}
+
+	previous := retired[:len(cfg.JWTPreviousSecrets)]
+	for i, key := range previous {
+		if key.Secret == cfg.JWTSecret {
+			c.fail("JWT_PREVIOUS_SECRETS entry %d is JWT_SECRET itself", i+1)
+		}
+		if len(key.Secret) < minJWTSecretLength {
+			c.strict("JWT_PREVIOUS_SECRETS entry %d must be at least %d characters", i+1, minJWTSecretLength)
+		}
+	}
+
+	seen := map[string]bool{cfg.JWTKeyID: true}
+	for _, key := range retired[len(previous):] {
+		if seen[key.ID] {
+			c.fail("JWT_RETIRED_KEYS key id %q is used more than once (including JWT_KEY_ID)", key.ID)
+		}
+		seen[key.ID] = true
+		if len(key.Secret) < minJWTSecretLength {
+			c.strict("JWT_RETIRED_KEYS secret of key %q must be at least %d characters", key.ID, minJWTSecretLength)
+		}
+	}
+	if cfg.JWTKeysRotatedAt.IsZero() {
+		c.warnings = append(c.warnings, "JWT_KEYS_ROTATED_AT is not set: previous and retired signing keys are accepted until removed from JWT_PREVIOUS_SECRETS and JWT_RETIRED_KEYS")
+	} else if cfg.JWTKeysRotatedAt.After(time.Now()) {
+		c.fail("JWT_KEYS_ROTATED_AT must not be in the future, got %s", cfg.JWTKeysRotatedAt.Format(time.RFC3339))
+	}
+}
+
+// validateKMS checks the key management service settings. Connecting to it
+// and unwrapping the settings it wraps is done by LoadConfig.
+func (cfg *Config) validateKMS(c *configCheck) {
+	if cfg.KMSProvider == "" {
+		for _, setting := range []struct{ name, value string }{
+			{"JWT_SECRET", cfg.JWTSecret},
+			{"SECRETS_ENCRYPTION_KEY", cfg.SecretsEncryptionKey},
+			{"PII_INDEX_KEY", cfg.PIIIndexKey},
+		} {
+			if kms.IsReference(setting.value) {
+				c.fail("%s is wrapped by a KMS (kms:...) but KMS_PROVIDER is not set", setting.name)
+			}
+		}
+		for _, secret := range cfg.JWTPreviousSecrets {
+			if kms.IsReference(strings.TrimSpace(secret)) {
+				c.fail("JWT_PREVIOUS_SECRETS is wrapped by a KMS (kms:...) but KMS_PROVIDER is not set")
+				break
+			}
+		}
+	}
+	if cfg.KMSCacheTTL <= 0 {
+		c.fail("KMS_CACHE_TTL must be positive, got %s", cfg.KMSCacheTTL)
+	}
+	if cfg.SecretsRewrapInterval < 0 {
+		c.fail("SECRETS_REWRAP_INTERVAL must not be negative, got %s", cfg.SecretsRewrapInterval)
+	}
+}
+
+// validateSecretsKeys checks the keys encrypting stored credentials and PII.
+func (cfg *Config) validateSecretsKeys(c *configCheck) {
+	keyring, err := cfg.SecretsKeyring()
+	if err != nil {
+		c.fail("%v", err)
+		return
+	}
+	if keyring == nil {
+		c.strict("SECRETS_ENCRYPTION_KEY (or SECRETS_USE_KMS) is not set: 2FA secrets are stored unencrypted")
+	}
+
+	index, err := cfg.PIIIndex()
+	if err != nil {
+		c.fail("%v", err)
+	}
+	if cfg.PIIEncryption {
+		if keyring == nil {
+			c.fail("SECRETS_ENCRYPTION_KEY or SECRETS_USE_KMS is required when PII_ENCRYPTION is set")
+		}
+		if index == nil && err == nil {
+			c.fail("PII_INDEX_KEY is required when PII_ENCRYPTION is set")
+		}
+	}
+}
+
+// validateEmail checks the email provider and delivery queue.
+func (cfg *Config) validateEmail(c *configCheck) {
+	if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+		c.fail("SMTP_FROM must be an email address, got %q", cfg.SMTPFrom)
+	}
+
+	switch strings.ToLower(cfg.EmailProvider) {
+	case email.ProviderSMTP, "":
+		if cfg.SMTPHost == "" {
+			c.fail("SMTP_HOST is required when EMAIL_PROVIDER=smtp")
+		}
+		if cfg.SMTPPort <= 0 || cfg.SMTPPort > 65535 {
+			c.fail("SMTP_PORT must be between 1 and 65535, got %d", cfg.SMTPPort)
+		}
+		if (cfg.SMTPUsername == "") != (cfg.SMTPPassword == "") {
+			c.fail("SMTP_USERNAME and SMTP_PASSWORD must be set together")
+		}
+		if cfg.SMTPPoolSize < 0 {
+			c.fail("SMTP_POOL_SIZE must be 0 (no pool) or positive, got %d", cfg.SMTPPoolSize)
+		}
+		if cfg.SMTPPoolSize > 0 && cfg.SMTPPoolIdleTimeout <= 0 {
+			c.fail("SMTP_POOL_IDLE_TIMEOUT must be positive, got %s", cfg.SMTPPoolIdleTimeout)
+		}
+		if cfg.DKIMPrivateKeyFile != "" {
+			if cfg.DKIMSelector == "" {
+				c.fail("DKIM_SELECTOR is required when DKIM_PRIVATE_KEY_FILE is set")
+			}
+			if _, err := email.LoadDKIMSigner(cfg.DKIMDomain, cfg.DKIMSelector, cfg.DKIMPrivateKeyFile); err != nil {
+				c.fail("DKIM_PRIVATE_KEY_FILE is invalid: %v", err)
+			}
+		}
+	case email.ProviderSendGrid:
+		if cfg.SendGridAPIKey == "" {
+			c.fail("SENDGRID_API_KEY is required when EMAIL_PROVIDER=sendgrid")
+		}
+	case email.ProviderLog:
+		c.strict("EMAIL_PROVIDER=log writes emails to the log instead of sending them")
+	case email.ProviderFile:
+		c.strict("EMAIL_PROVIDER=file writes emails to %s instead of sending them", cfg.EmailOutboxDir)
+		if cfg.EmailOutboxDir == "" {
+			c.fail("EMAIL_OUTBOX_DIR is required when EMAIL_PROVIDER=file")
+		}
+	case email.ProviderMemory:
+		c.strict("EMAIL_PROVIDER=memory keeps emails in memory, readable at /api/v1/dev/emails, instead of sending them")
+		if cfg.EmailInboxSize <= 0 {
+			c.fail("EMAIL_INBOX_SIZE must be positive, got %d", cfg.EmailInboxSize)
+		}
+	case email.ProviderMailHog:
+		c.strict("EMAIL_PROVIDER=mailhog delivers emails to the mail catcher at %s instead of sending them", cfg.EmailSinkAddr)
+		if _, _, err := net.SplitHostPort(cfg.EmailSinkAddr); err != nil {
+			c.fail("EMAIL_SINK_ADDR must be host:port, got %q", cfg.EmailSinkAddr)
+		}
+	case email.ProviderMaildir:
+		c.strict("EMAIL_PROVIDER=maildir delivers emails to %s instead of sending them", cfg.EmailMaildir)
+		if cfg.EmailMaildir == "" {
+			c.fail("EMAIL_MAILDIR is required when EMAIL_PROVIDER=maildir")
+		}
+	default:
+		c.fail("EMAIL_PROVIDER must be smtp, sendgrid, log, file, memory, mailhog or maildir, got %q", cfg.EmailProvider)
+	}
+	if cfg.EmailStartupTestTo != "" {
+		if _, err := mail.ParseAddress(cfg.EmailStartupTestTo); err != nil {
+			c.fail("EMAIL_STARTUP_TEST_TO must be an email address, got %q", cfg.EmailStartupTestTo)
+		}
+	}
+	if cfg.EmailProbeInterval < 0 {
+		c.fail("EMAIL_PROBE_INTERVAL must be 0 (no probe) or positive, got %s", cfg.EmailProbeInterval)
+	}
+	if cfg.SendGridWebhookPublicKey != "" {
+		if _, err := email.NewSendGridWebhook(cfg.SendGridWebhookPublicKey); err != nil {
+			c.fail("SENDGRID_WEBHOOK_PUBLIC_KEY is invalid: %v", err)
+		}
+	}
+
+	if cfg.EmailQueueWorkers < 1 {
+		c.fail("EMAIL_QUEUE_WORKERS must be at least 1, got %d", cfg.EmailQueueWorkers)
+	}
+	if cfg.EmailQueueMaxAttempts < 1 {
+		c.fail("EMAIL_QUEUE_MAX_ATTEMPTS must be at least 1, got %d", cfg.EmailQueueMaxAttempts)
+	}
+	if cfg.EmailQueueBaseBackoff <= 0 || cfg.EmailQueueMaxBackoff < cfg.EmailQueueBaseBackoff {
+		c.fail("EMAIL_QUEUE_BASE_BACKOFF (%s) must be positive and at most EMAIL_QUEUE_MAX_BACKOFF (%s)", cfg.EmailQueueBaseBackoff, cfg.EmailQueueMaxBackoff)
+	}
+	if cfg.TaskQueueWorkers < 1 {
+		c.fail("TASK_QUEUE_WORKERS must be at least 1, got %d", cfg.TaskQueueWorkers)
+	}
+	if cfg.TaskQueueMaxAttempts < 1 {
+		c.fail("TASK_QUEUE_MAX_ATTEMPTS must be at least 1, got %d", cfg.TaskQueueMaxAttempts)
+	}
+	if cfg.TaskQueueBaseBackoff <= 0 || cfg.TaskQueueMaxBackoff < cfg.TaskQueueBaseBackoff {
+		c.fail("TASK_QUEUE_BASE_BACKOFF (%s) must be positive and at most TASK_QUEUE_MAX_BACKOFF (%s)", cfg.TaskQueueBaseBackoff, cfg.TaskQueueMaxBackoff)
+	}
+	if cfg.TaskDedupWindow <= 0 {
+		c.fail("TASK_DEDUP_WINDOW must be positive, got %s", cfg.TaskDedupWindow)
+	}
+	if cfg.RegistrationWebhookURL != "" {
+		checkURL(c, "REGISTRATION_WEBHOOK_URL", cfg.RegistrationWebhookURL)
+	}
+	if cfg.EmailAsyncWorkers < 0 {
+		c.fail("EMAIL_ASYNC_WORKERS must be 0 (synchronous) or positive, got %d", cfg.EmailAsyncWorkers)
+	}
+	if cfg.EmailAsyncWorkers > 0 && cfg.EmailAsyncBacklog < 1 {
+		c.fail("EMAIL_ASYNC_BACKLOG must be at least 1, got %d", cfg.EmailAsyncBacklog)
+	}
+}
+
+// validateRegistration checks registration and password policies, tenancy and
+// legal versions.
+func (cfg *Config) validateRegistration(c *configCheck) {
+	switch cfg.DisposableEmailMode {
+	case constants.DisposableEmailBlock, constants.DisposableEmailFlag, constants.DisposableEmailOff:
+	default:
+		c.fail("DISPOSABLE_EMAIL_MODE must be block, flag or off, got %q", cfg.DisposableEmailMode)
+	}
+	if cfg.DisposableEmailListURL != "" {
+		checkURL(c, "DISPOSABLE_EMAIL_LIST_URL", cfg.DisposableEmailListURL)
+	}
+
+	if cfg.InvitationTTL <= 0 {
+		c.fail("INVITATION_TTL must be positive, got %s", cfg.InvitationTTL)
+	}
+	if cfg.PasswordMaxAge < 0 {
+		c.fail("PASSWORD_MAX_AGE must be 0 (never expires) or positive, got %s", cfg.PasswordMaxAge)
+	}
+	if cfg.PasswordExpiryReminder < 0 {
+		c.fail("PASSWORD_EXPIRY_REMINDER must be 0 (no reminders) or positive, got %s", cfg.PasswordExpiryReminder)
+	}
+	if cfg.DuplicateScanInterval < 0 {
+		c.fail("DUPLICATE_SCAN_INTERVAL must be 0 (no scan) or positive, got %s", cfg.DuplicateScanInterval)
+	}
+	if _, err := cfg.OnboardingSequence(); err != nil {
+		c.fail("%v", err)
+	}
+	if cfg.OnboardingCheckInterval < 0 {
+		c.fail("ONBOARDING_CHECK_INTERVAL must be 0 (only the emails sent at registration) or positive, got %s", cfg.OnboardingCheckInterval)
+	}
+	if cfg.PasswordRotationReminderAge < 0 {
+		c.fail("PASSWORD_ROTATION_REMINDER_AGE must be 0 (no reminders) or positive, got %s", cfg.PasswordRotationReminderAge)
+	}
+	if cfg.PasswordHashUpgradedAt.After(time.Now()) {
+		c.fail("PASSWORD_HASH_UPGRADED_AT must not be in the future, got %s", cfg.PasswordHashUpgradedAt.Format(time.RFC3339))
+	}
+	if cfg.PasswordRotationCheckInterval < 0 {
+		c.fail("PASSWORD_ROTATION_CHECK_INTERVAL must be 0 (no reminders) or positive, got %s", cfg.PasswordRotationCheckInterval)
+	}
+	if cfg.PasswordRotationReminderSuppression < 0 {
+		c.fail("PASSWORD_ROTATION_REMINDER_SUPPRESSION must be 0 or positive, got %s", cfg.PasswordRotationReminderSuppression)
+	}
+
+	switch cfg.TenancyMode {
+	case constants.TenancyOff, constants.TenancyPath:
+	case constants.TenancyHeader:
+		if cfg.TenantHeader == "" {
+			c.fail("TENANT_HEADER is required when TENANCY_MODE=header")
+		}
+	case constants.TenancySubdomain:
+		if cfg.TenantBaseDomain == "" {
+			c.fail("TENANT_BASE_DOMAIN is required when TENANCY_MODE=subdomain")
+		}
+	default:
+		c.fail("TENANCY_MODE must be off, header, subdomain or path, got %q", cfg.TenancyMode)
+	}
+
+	quotas := []struct {
+		name  string
+		limit int64
+	}{
+		{"TENANT_QUOTA_REQUESTS_PER_DAY", cfg.TenantQuotaRequestsPerDay},
+		{"TENANT_QUOTA_REGISTRATIONS_PER_DAY", cfg.TenantQuotaRegistrationsPerDay},
+		{"TENANT_QUOTA_OTP_EMAILS_PER_DAY", cfg.TenantQuotaOTPEmailsPerDay},
+	}
+	for _, q := range quotas {
+		if q.limit < 0 {
+			c.fail("%s must be 0 (unlimited) or positive, got %d", q.name, q.limit)
+		}
+	}
+
+	if cfg.RegistrationMaxPerIPPerDay < 0 {
+		c.fail("REGISTRATION_MAX_PER_IP_PER_DAY must be 0 (unlimited) or positive, got %d", cfg.RegistrationMaxPerIPPerDay)
+	}
+	if cfg.RegistrationMaxPerEmailBase < 0 {
+		c.fail("REGISTRATION_MAX_PER_EMAIL_BASE must be 0 (unlimited) or positive, got %d", cfg.RegistrationMaxPerEmailBase)
+	}
+	if cfg.RegistrationBlockDuration < 0 || cfg.RegistrationBlockDuration > 7*24*time.Hour {
+		c.fail("REGISTRATION_BLOCK_DURATION must be between 0 and 168h, got %s", cfg.RegistrationBlockDuration)
+	}
+
+	if cfg.TOSVersion == "" || cfg.PrivacyPolicyVersion == "" {
+		c.fail("TOS_VERSION and PRIVACY_POLICY_VERSION must not be empty")
+	}
+}
+
+// validateOTP checks the code policies, the limits on code emails and the
+// push approval settings.
+func (cfg *Config) validateOTP(c *configCheck) {
+	policies := []struct {
+		prefix string
+		policy otp.Policy
+	}{
+		{"OTP_2FA", cfg.OTPPolicy(constants.Type2FA)},
+		{"OTP_PASSWORD_RESET", cfg.OTPPolicy(constants.TypePasswordReset)},
+	}
+	for _, p := range policies {
+		if err := p.policy.Validate(); err != nil {
+			c.fail("%s_*: %v", p.prefix, err)
+		}
+	}
+
+	if cfg.PushFCMCredentialsFile != "" {
+		if _, err := os.Stat(cfg.PushFCMCredentialsFile); err != nil {
+			c.fail("PUSH_FCM_CREDENTIALS_FILE is not readable: %v", err)
+		}
+	}
+	if cfg.PushAPNsKeyFile != "" {
+		if _, err := os.Stat(cfg.PushAPNsKeyFile); err != nil {
+			c.fail("PUSH_APNS_KEY_FILE is not readable: %v", err)
+		}
+		if cfg.PushAPNsKeyID == "" || cfg.PushAPNsTeamID == "" || cfg.PushAPNsTopic == "" {
+			c.fail("PUSH_APNS_KEY_ID, PUSH_APNS_TEAM_ID and PUSH_APNS_TOPIC are required when PUSH_APNS_KEY_FILE is set")
+		}
+	}
+	switch strings.ToLower(cfg.SMSProvider) {
+	case "":
+	case sms.ProviderTwilio:
+		if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" {
+			c.fail("TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN are required when SMS_PROVIDER=twilio")
+		}
+		if !sms.ValidNumber(cfg.TwilioFromNumber) {
+			c.fail("TWILIO_FROM_NUMBER must be a phone number in E.164 format, got %q", cfg.TwilioFromNumber)
+		}
+	case sms.ProviderLog:
+		c.strict("SMS_PROVIDER=log writes text messages to the log instead of sending them")
+	default:
+		c.fail("SMS_PROVIDER must be twilio, log or empty, got %q", cfg.SMSProvider)
+	}
+	if cfg.PushChallengeTTL < 10*time.Second || cfg.PushChallengeTTL > 15*time.Minute {
+		c.fail("PUSH_CHALLENGE_TTL must be between 10s and 15m, got %s", cfg.PushChallengeTTL)
+	}
+	if cfg.PairingCodeTTL < 30*time.Second || cfg.PairingCodeTTL > 10*time.Minute {
+		c.fail("PAIRING_CODE_TTL must be between 30s and 10m, got %s", cfg.PairingCodeTTL)
+	}
+	pollLimit := ServerWriteTimeout
+	if cfg.RequestTimeout > 0 {
+		pollLimit = cfg.RequestTimeout
+	}
+	if cfg.PairingPollWait < 0 || cfg.PairingPollWait >= pollLimit {
+		c.fail("PAIRING_POLL_WAIT must be between 0 (no waiting) and REQUEST_TIMEOUT (%s), got %s", pollLimit, cfg.PairingPollWait)
+	}
+	if cfg.TwoFARecoveryDelay < time.Hour || cfg.TwoFARecoveryDelay > 30*24*time.Hour {
+		c.fail("TWO_FA_RECOVERY_DELAY must be between 1h and 720h, got %s", cfg.TwoFARecoveryDelay)
+	}
+
+	thresholds := []struct {
+		name  string
+		value int
+	}{
+		{"MFA_RISK_SKIP_BELOW", cfg.MFARiskSkipBelow},
+		{"MFA_RISK_REQUIRE_AT", cfg.MFARiskRequireAt},
+		{"MFA_RISK_QUARANTINE_AT", cfg.MFARiskQuarantineAt},
+		{"MFA_RISK_BLOCK_AT", cfg.MFARiskBlockAt},
+	}
+	for _, t := range thresholds {
+		if t.value < 0 || t.value > 100 {
+			c.fail("%s must be between 0 (off) and 100, got %d", t.name, t.value)
+		}
+	}
+	if cfg.MFARiskSkipBelow > 0 && cfg.MFARiskRequireAt > 0 && cfg.MFARiskSkipBelow > cfg.MFARiskRequireAt {
+		c.fail("MFA_RISK_SKIP_BELOW (%d) must not exceed MFA_RISK_REQUIRE_AT (%d)", cfg.MFARiskSkipBelow, cfg.MFARiskRequireAt)
+	}
+	// The thresholds that are set must escalate: require < quarantine < block
+	for i := 1; i < len(thresholds); i++ {
+		for j := i + 1; j < len(thresholds); j++ {
+			lower, higher := thresholds[i], thresholds[j]
+			if lower.value > 0 && higher.value > 0 && higher.value <= lower.value {
+				c.fail("%s (%d) must be above %s (%d)", higher.name, higher.value, lower.name, lower.value)
+			}
+		}
+	}
+	if cfg.LoginQuarantineTTL < 5*time.Minute || cfg.LoginQuarantineTTL > 24*time.Hour {
+		c.fail("LOGIN_QUARANTINE_TTL must be between 5m and 24h, got %s", cfg.LoginQuarantineTTL)
+	}
+	if cfg.IPAllowlistStepUpTTL < 5*time.Minute || cfg.IPAllowlistStepUpTTL > 30*24*time.Hour {
+		c.fail("IP_ALLOWLIST_STEP_UP_TTL must be between 5m and 720h, got %s", cfg.IPAllowlistStepUpTTL)
+	}
+
+	if cfg.OTPResendCooldown < 0 {
+		c.fail("OTP_RESEND_COOLDOWN must not be negative, got %s", cfg.OTPResendCooldown)
+	}
+	if cfg.OTPDailyLimit < 0 {
+		c.fail("OTP_DAILY_LIMIT must be 0 (unlimited) or positive, got %d", cfg.OTPDailyLimit)
+	}
+}
+
+// validateAnonymizer checks the anonymizer detection provider and the route
+// policies that use it.
+func (cfg *Config) validateAnonymizer(c *configCheck) {
+	switch strings.ToLower(cfg.AnonymizerProvider) {
+	case anonymizer.ProviderOff, "":
+	case anonymizer.ProviderIPAPI:
+		checkURL(c, "IPAPI_URL", cfg.IPAPIURL)
+	case anonymizer.ProviderIPQS:
+		if cfg.AnonymizerIPQSKey == "" {
+			c.fail("ANONYMIZER_IPQS_KEY is required when ANONYMIZER_PROVIDER=ipqs")
+		}
+	case anonymizer.ProviderList:
+		if cfg.AnonymizerListFile == "" {
+			c.fail("ANONYMIZER_LIST_FILE is required when ANONYMIZER_PROVIDER=list")
+		} else if _, err := os.Stat(cfg.AnonymizerListFile); err != nil {
+			c.fail("ANONYMIZER_LIST_FILE is not readable: %v", err)
+		}
+	default:
+		c.fail("ANONYMIZER_PROVIDER must be off, ipapi, ipqs or list, got %q", cfg.AnonymizerProvider)
+	}
+	if cfg.AnonymizerCacheTTL < time.Minute {
+		c.fail("ANONYMIZER_CACHE_TTL must be at least 1m, got %s", cfg.AnonymizerCacheTTL)
+	}
+
+	for _, routes := range []struct {
+		name     string
+		prefixes []string
+	}{
+		{"ANONYMIZER_BLOCK_PROXY_ROUTES", cfg.AnonymizerBlockProxyRoutes},
+		{"ANONYMIZER_BLOCK_HOSTING_ROUTES", cfg.AnonymizerBlockHostingRoutes},
+		{"ANONYMIZER_ALLOW_ROUTES", cfg.AnonymizerAllowRoutes},
+	} {
+		for _, prefix := range routes.prefixes {
+			if prefix = strings.TrimSpace(prefix); prefix != "*" && !strings.HasPrefix(prefix, "/") {
+				c.fail("%s entries must be path prefixes starting with / or *, got %q", routes.name, prefix)
+			}
+		}
+	}
+	blocking := len(cfg.AnonymizerBlockProxyRoutes) > 0 || len(cfg.AnonymizerBlockHostingRoutes) > 0
+	if blocking && (cfg.AnonymizerProvider == "" || strings.EqualFold(cfg.AnonymizerProvider, anonymizer.ProviderOff)) {
+		c.strict("ANONYMIZER_BLOCK_*_ROUTES have no effect while ANONYMIZER_PROVIDER is off")
+	}
+}
+
+// validateGeoPolicy checks the default geographic sign-in rules.
+func (cfg *Config) validateGeoPolicy(c *configCheck) {
+	for _, list := range []struct {
+		name      string
+		countries []string
+	}{
+		{"BLOCKED_COUNTRIES", cfg.BlockedCountries},
+		{"ALLOWED_COUNTRIES", cfg.AllowedCountries},
+		{"SUSPICIOUS_COUNTRIES", cfg.SuspiciousCountries},
+	} {
+		for _, country := range list.countries {
+			if country = strings.TrimSpace(country); len(country) != 2 {
+				c.fail("%s entries must be ISO 3166-1 alpha-2 country codes, got %q", list.name, country)
+			}
+		}
+	}
+	for _, action := range []struct {
+		name  string
+		value string
+	}{
+		{"GEO_SUSPICIOUS_ACTION", cfg.GeoSuspiciousAction},
+		{"GEO_VELOCITY_ACTION", cfg.GeoVelocityAction},
+	} {
+		switch action.value {
+		case constants.GeoActionLog, constants.GeoActionStepUp, constants.GeoActionBlock:
+		default:
+			c.fail("%s must be log, step_up or block, got %q", action.name, action.value)
+		}
+	}
+	if cfg.GeoMaxTravelSpeed != 0 && (cfg.GeoMaxTravelSpeed < 100 || cfg.GeoMaxTravelSpeed > 20000) {
+		c.fail("GEO_MAX_TRAVEL_SPEED_KMH must be 0 (off) or between 100 and 20000, got %d", cfg.GeoMaxTravelSpeed)
+	}
+}
+
+// validateCaptcha checks the CAPTCHA provider.
+func (cfg *Config) validateCaptcha(c *configCheck) {
+	switch strings.ToLower(cfg.CaptchaProvider) {
+	case captcha.ProviderOff, "":
+	case captcha.ProviderReCAPTCHA, captcha.ProviderHCaptcha, captcha.ProviderTurnstile:
+		if cfg.CaptchaSecret == "" {
+			c.fail("CAPTCHA_SECRET is required when CAPTCHA_PROVIDER=%s", cfg.CaptchaProvider)
+		}
+	default:
+		c.fail("CAPTCHA_PROVIDER must be off, recaptcha, hcaptcha or turnstile, got %q", cfg.CaptchaProvider)
+	}
+	if cfg.CaptchaMinScore < 0 || cfg.CaptchaMinScore > 1 {
+		c.fail("CAPTCHA_MIN_SCORE must be between 0.0 and 1.0, got %g", cfg.CaptchaMinScore)
+	}
+}
+
+// validateAlerts checks the security alert webhook and thresholds.
+func (cfg *Config) validateAlerts(c *configCheck) {
+	thresholds := []struct {
+		name  string
+		value int64
+	}{
+		{"ALERT_FAILED_LOGINS_THRESHOLD", cfg.AlertFailedLoginsThreshold},
+		{"ALERT_LOCKOUTS_THRESHOLD", cfg.AlertLockoutsThreshold},
+		{"ALERT_REVOKED_TOKENS_THRESHOLD", cfg.AlertRevokedTokensThreshold},
+		{"ALERT_BLOCKED_COUNTRY_THRESHOLD", cfg.AlertBlockedCountryThreshold},
+		{"ALERT_OTP_FAILURES_THRESHOLD", cfg.AlertOTPFailuresThreshold},
+	}
+	set := false
+	for _, threshold := range thresholds {
+		if threshold.value < 0 {
+			c.fail("%s must be 0 (disabled) or more, got %d", threshold.name, threshold.value)
+		}
+		set = set || threshold.value > 0
+	}
+
+	if cfg.AlertWindow < time.Minute || cfg.AlertWindow > 24*time.Hour {
+		c.fail("ALERT_WINDOW must be between 1m and 24h, got %s", cfg.AlertWindow)
+	}
+
+	if cfg.AlertWebhookURL == "" {
+		if set {
+			c.fail("ALERT_*_THRESHOLD settings need ALERT_WEBHOOK_URL")
+		}
+		return
+	}
+	checkURL(c, "ALERT_WEBHOOK_URL", cfg.AlertWebhookURL)
+	switch strings.ToLower(cfg.AlertWebhookFormat) {
+	case metrics.FormatSlack:
+	case metrics.FormatPagerDuty:
+		if cfg.AlertPagerDutyRoutingKey == "" {
+			c.fail("ALERT_PAGERDUTY_ROUTING_KEY is required when ALERT_WEBHOOK_FORMAT=pagerduty")
+		}
+	default:
+		c.fail("ALERT_WEBHOOK_FORMAT must be slack or pagerduty, got %q", cfg.AlertWebhookFormat)
+	}
+}
+
+// validateGoogleOAuth checks that Google sign-in is either fully configured
+// or not configured at all.
+func (cfg *Config) validateGoogleOAuth(c *configCheck) {
+	set := 0
+	for _, v := range []string{cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL} {
+		if v != "" {
+			set++
+		}
+	}
+	switch set {
+	case 0:
+		c.warnings = append(c.warnings, "GOOGLE_CLIENT_ID is not set: Google sign-in is disabled")
+	case 3:
+		checkURL(c, "GOOGLE_REDIRECT_URL", cfg.GoogleRedirectURL)
+	default:
+		c.fail("GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET and GOOGLE_REDIRECT_URL must be set together")
+	}
+}
+
+// validateFederation checks federated sign-in when it is enabled.
+func (cfg *Config) validateFederation(c *configCheck) {
+	if cfg.FederatedIssuer == "" {
+		return
+	}
+
+	if cfg.FederatedJWKSURL == "" {
+		c.fail("FEDERATED_JWKS_URL is required when FEDERATED_ISSUER is set")
+	} else if u := checkURL(c, "FEDERATED_JWKS_URL", cfg.FederatedJWKSURL); u != nil && u.Scheme != "https" {
+		c.strict("FEDERATED_JWKS_URL must use https, got %q", cfg.FederatedJWKSURL)
+	}
+	// Without an audience, tokens the provider issued to any other
+	// application would be accepted
+	if cfg.FederatedAudience == "" {
+		c.fail("FEDERATED_AUDIENCE is required when FEDERATED_ISSUER is set")
+	}
+	if cfg.FederatedJWKSRefresh < time.Minute {
+		c.fail("FEDERATED_JWKS_REFRESH must be at least 1m, got %s", cfg.FederatedJWKSRefresh)
+	}
+}
+
+// validateOIDC checks OpenID Connect provider mode when it is enabled.
+func (cfg *Config) validateOIDC(c *configCheck) {
+	if cfg.OIDCIssuer == "" {
+		return
+	}
+
+	if u := checkURL(c, "OIDC_ISSUER", cfg.OIDCIssuer); u != nil && u.Scheme != "https" {
+		c.strict("OIDC_ISSUER must use https, got %q", cfg.OIDCIssuer)
+	}
+	if cfg.OIDCSigningKeyFile == "" {
+		c.strict("OIDC_SIGNING_KEY_FILE is required when OIDC_ISSUER is set (a temporary key changes on every restart)")
+	} else if _, err := os.Stat(cfg.OIDCSigningKeyFile); err != nil {
+		c.fail("OIDC_SIGNING_KEY_FILE is not readable: %v", err)
+	}
+	if cfg.OIDCConsentURL != "" {
+		checkURL(c, "OIDC_CONSENT_URL", cfg.OIDCConsentURL)
+	}
+	if cfg.OIDCCodeTTL <= 0 {
+		c.fail("OIDC_CODE_TTL must be positive, got %s", cfg.OIDCCodeTTL)
+	}
+}
+
+// validateStorage checks object storage and avatar upload limits.
+func (cfg *Config) validateStorage(c *configCheck) {
+	switch strings.ToLower(cfg.StorageProvider) {
+	case storage.ProviderNone, "":
+	case storage.ProviderS3:
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			c.fail("S3_ENDPOINT and S3_BUCKET are required when STORAGE_PROVIDER=s3")
+		}
+		if cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			c.fail("S3_ACCESS_KEY and S3_SECRET_KEY are required when STORAGE_PROVIDER=s3")
+		}
+		if !cfg.S3UseSSL {
+			c.strict("S3_USE_SSL is off: uploads are sent to object storage unencrypted")
+		}
+		if cfg.StoragePublicURL != "" {
+			checkURL(c, "STORAGE_PUBLIC_URL", cfg.StoragePublicURL)
+		}
+	default:
+		c.fail("STORAGE_PROVIDER must be none or s3, got %q", cfg.StorageProvider)
+	}
+
+	if cfg.AuditArchiveBucket != "" {
+		if !strings.EqualFold(cfg.StorageProvider, storage.ProviderS3) {
+			c.fail("AUDIT_ARCHIVE_BUCKET requires STORAGE_PROVIDER=s3")
+		}
+		if cfg.AuditArchiveBucket == cfg.S3Bucket {
+			c.fail("AUDIT_ARCHIVE_BUCKET must not be S3_BUCKET, whose avatars are public")
+		}
+	}
+
+	if cfg.AvatarMaxBytes <= 0 {
+		c.fail("AVATAR_MAX_BYTES must be positive, got %d", cfg.AvatarMaxBytes)
+	}
+	if cfg.AvatarSize < 32 || cfg.AvatarSize > 2048 {
+		c.fail("AVATAR_SIZE must be between 32 and 2048 pixels, got %d", cfg.AvatarSize)
+	}
+}
+
+// checkURL records a problem unless value is an absolute http(s) URL, and
+// returns the parsed URL when it is.
+func checkURL(c *configCheck, name, value string) *url.URL {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.fail("%s must be an absolute http(s) URL, got %q", name, value)
+		return nil
+	}
+	return u
+}
@@ -0,0 +1,8 @@
+package constants
+
+// Registration approval states (users.approval_status).
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
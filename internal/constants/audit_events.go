@@ -0,0 +1,63 @@
+package constants
+
+// AuditEvent identifies the kind of entry written to the audit_logs table.
+type AuditEvent string
+
+const (
+	AuditUserRegistered AuditEvent = "user_registered"
+	AuditLoginSuccess   AuditEvent = "login_success"
+	AuditLoginFailed    AuditEvent = "login_failed"
+	AuditLoginBlocked   AuditEvent = "login_blocked"
+	AuditSudoGranted    AuditEvent = "sudo_granted"
+	AuditPasswordReset  AuditEvent = "password_reset"
+	AuditUserAnonymized AuditEvent = "user_anonymized"
+	AuditInviteCreated  AuditEvent = "invitation_created"
+	AuditInviteRevoked  AuditEvent = "invitation_revoked"
+	AuditUserApproved   AuditEvent = "user_approved"
+	AuditUserRejected   AuditEvent = "user_rejected"
+	AuditMetadataEdited AuditEvent = "user_metadata_edited"
+	AuditDeviceRevoked  AuditEvent = "device_revoked"
+	AuditUsersExported  AuditEvent = "users_exported"
+	AuditLogsExported   AuditEvent = "audit_logs_exported"
+
+	AuditDuplicatesDismissed AuditEvent = "duplicate_accounts_dismissed"
+
+	AuditKeyRotated AuditEvent = "key_rotated"
+
+	AuditEmailTemplateChanged AuditEvent = "email_template_changed"
+	AuditEmailBrandingChanged AuditEvent = "email_branding_changed"
+	AuditEmailTestSent        AuditEvent = "email_test_sent"
+
+	AuditIPAllowlistChanged AuditEvent = "ip_allowlist_changed"
+	AuditIPDenylistChanged  AuditEvent = "ip_denylist_changed"
+	AuditGeoPolicyChanged   AuditEvent = "geo_policy_changed"
+
+	AuditPushApproved AuditEvent = "push_login_approved"
+	AuditPushDenied   AuditEvent = "push_login_denied"
+
+	AuditPairingApproved AuditEvent = "pairing_approved"
+	AuditPairingDenied   AuditEvent = "pairing_denied"
+
+	AuditLoginQuarantined   AuditEvent = "login_quarantined"
+	AuditQuarantineApproved AuditEvent = "login_quarantine_approved"
+	AuditQuarantineExpired  AuditEvent = "login_quarantine_expired"
+
+	AuditRecoveryCodesGenerated AuditEvent = "recovery_codes_generated"
+	AuditRecoveryCodeUsed       AuditEvent = "recovery_code_used"
+	AuditRecoveryStarted        AuditEvent = "two_factor_recovery_started"
+	AuditRecoveryCancelled      AuditEvent = "two_factor_recovery_cancelled"
+	AuditRecoveryCompleted      AuditEvent = "two_factor_recovery_completed"
+
+	AuditOrgCreated       AuditEvent = "organization_created"
+	AuditOrgLeft          AuditEvent = "organization_left"
+	AuditOrgJoined        AuditEvent = "organization_joined"
+	AuditOrgRoleChanged   AuditEvent = "organization_role_changed"
+	AuditOrgMemberRemoved AuditEvent = "organization_member_removed"
+	AuditOrgInviteCreated AuditEvent = "organization_invitation_created"
+	AuditOrgInviteRevoked AuditEvent = "organization_invitation_revoked"
+
+	AuditOAuthClientCreated AuditEvent = "oauth_client_created"
+	AuditOAuthClientDeleted AuditEvent = "oauth_client_deleted"
+	AuditOAuthAuthorized    AuditEvent = "oauth_authorized"
+	AuditOAuthGrantRevoked  AuditEvent = "oauth_grant_revoked"
+)
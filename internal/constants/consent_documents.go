@@ -0,0 +1,7 @@
+package constants
+
+// Legal documents a user can consent to, stored in user_consents.document.
+const (
+	ConsentTermsOfService = "tos"
+	ConsentPrivacyPolicy  = "privacy"
+)
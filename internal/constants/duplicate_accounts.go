@@ -0,0 +1,15 @@
+package constants
+
+// Reasons accounts are reported as likely duplicates
+// (duplicate_account_groups.reason).
+const (
+	DuplicateByEmail    = "email"    // same address once plus tags are removed (and dots, at Gmail)
+	DuplicateByPhone    = "phone"    // same phone number in the notification preferences
+	DuplicateByProvider = "provider" // same identity provider subject
+)
+
+// Duplicate account group states (duplicate_account_groups.status).
+const (
+	DuplicatesOpen      = "open"
+	DuplicatesDismissed = "dismissed"
+)
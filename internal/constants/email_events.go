@@ -0,0 +1,37 @@
+package constants
+
+// EmailEvent names what triggered an email in the delivery log
+// (email_log.event).
+type EmailEvent string
+
+const (
+	EmailWelcome                EmailEvent = "welcome"
+	EmailOnboardingTips         EmailEvent = "onboarding_tips"
+	EmailOnboardingSecurity     EmailEvent = "onboarding_security"
+	EmailTwoFactorCode          EmailEvent = "two_factor_code"
+	EmailPasswordReset          EmailEvent = "password_reset"
+	EmailPasswordChanged        EmailEvent = "password_changed"
+	EmailLoginAlert             EmailEvent = "login_alert"
+	EmailLoginApproval          EmailEvent = "login_approval"
+	EmailTwoFactorRecovery      EmailEvent = "two_factor_recovery"
+	EmailTwoFactorDisabled      EmailEvent = "two_factor_disabled"
+	EmailNewLocationLogin       EmailEvent = "new_location_login"
+	EmailPasswordExpiring       EmailEvent = "password_expiry_reminder"
+	EmailPasswordRotation       EmailEvent = "password_rotation_reminder"
+	EmailInvitation             EmailEvent = "invitation"
+	EmailOrganizationInvitation EmailEvent = "organization_invitation"
+	EmailRegistrationPending    EmailEvent = "registration_pending" // sent to admins
+	EmailRegistrationApproved   EmailEvent = "registration_approved"
+	EmailRegistrationRejected   EmailEvent = "registration_rejected"
+	EmailTest                   EmailEvent = "email_test" // sent by an admin
+)
+
+// Email delivery states (email_log.status).
+const (
+	EmailQueued    = "queued"    // waiting for the first attempt
+	EmailSent      = "sent"      // accepted by the provider
+	EmailDelivered = "delivered" // accepted by the recipient's server
+	EmailFailed    = "failed"    // the last attempt failed; the queue may retry
+	EmailBounced   = "bounced"   // rejected by the recipient's server
+	EmailDropped   = "dropped"   // not sent by the provider (e.g. suppressed address)
+)
@@ -0,0 +1,14 @@
+package constants
+
+// How registrations from disposable email domains are handled (DISPOSABLE_EMAIL_MODE).
+const (
+	DisposableEmailBlock = "block" // reject with the disposable_email error code
+	DisposableEmailFlag  = "flag"  // allow, but mark the registration in logs and the audit trail
+	DisposableEmailOff   = "off"   // no check
+)
+
+// Registration email domain rule types (email_domain_rules.rule).
+const (
+	DomainRuleAllow = "allow"
+	DomainRuleBlock = "block"
+)
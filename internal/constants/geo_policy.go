@@ -0,0 +1,16 @@
+package constants
+
+// Geo policy actions: what happens to a sign-in from a suspicious country, or
+// one implying impossible travel since the user's previous sign-in.
+const (
+	GeoActionLog    = "log"     // allowed, flagged in the audit log and server logs
+	GeoActionStepUp = "step_up" // needs a second factor
+	GeoActionBlock  = "block"   // refused
+)
+
+// Country codes GeoIP resolves for clients it can't place. Country rules
+// don't apply to them.
+const (
+	CountryLocal   = "LOCAL"   // loopback address
+	CountryUnknown = "UNKNOWN" // lookup failed
+)
@@ -0,0 +1,9 @@
+package constants
+
+// Invitation states, derived from an invitation's timestamps.
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
@@ -0,0 +1,8 @@
+package constants
+
+// IP allowlist modes stored in users.ip_allowlist_mode: what happens to a
+// login from an address that isn't on the account's allowlist.
+const (
+	IPAllowlistBlock  = "block"  // refused
+	IPAllowlistVerify = "verify" // needs a second factor, then the address is allowed for a while
+)
@@ -0,0 +1,22 @@
+package constants
+
+// Purposes of keys rotated through the admin API (managed_keys.purpose).
+const (
+	KeyPurposeJWT     = "jwt"     // HMAC secret of first-party tokens (JWT_SECRET)
+	KeyPurposeOIDC    = "oidc"    // RSA key of OpenID Connect provider tokens
+	KeyPurposeSecrets = "secrets" // master key of encrypted columns (SECRETS_ENCRYPTION_KEY)
+)
+
+// Managed key states (managed_keys.status).
+const (
+	KeyActive     = "active"      // signs or seals
+	KeyVerifyOnly = "verify_only" // only verifies or opens what it signed or sealed
+	KeyRetired    = "retired"     // phased out
+)
+
+// Key rotation states (key_rotations.status).
+const (
+	RotationRunning   = "running"
+	RotationCompleted = "completed"
+	RotationFailed    = "failed"
+)
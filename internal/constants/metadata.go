@@ -0,0 +1,11 @@
+package constants
+
+// Custom user metadata kinds, named after their users table columns.
+const (
+	MetadataUser = "user_metadata" // editable by the user
+	MetadataApp  = "app_metadata"  // editable through the admin API only
+)
+
+// MaxMetadataBytes caps the JSON-encoded size of each metadata object, which
+// may be embedded in every access token.
+const MaxMetadataBytes = 8 << 10
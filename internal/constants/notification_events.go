@@ -0,0 +1,34 @@
+package constants
+
+// NotificationEvent is a security or lifecycle event users are notified of.
+// The notification dispatcher picks its channels (email, SMS, push) from the
+// event and the user's preferences.
+type NotificationEvent string
+
+const (
+	// Account lifecycle: always emailed
+	NotificationWelcome              NotificationEvent = "welcome"
+	NotificationOnboardingTips       NotificationEvent = "onboarding_tips"
+	NotificationOnboardingSecurity   NotificationEvent = "onboarding_security"
+	NotificationRegistrationApproved NotificationEvent = "registration_approved"
+	NotificationRegistrationRejected NotificationEvent = "registration_rejected"
+
+	// Critical security alerts: always emailed; text messages and push
+	// notifications as chosen by the user
+	NotificationPasswordChanged   NotificationEvent = "password_changed"
+	NotificationTwoFactorDisabled NotificationEvent = "two_factor_disabled"
+
+	// Optional security alerts and reminders: each class can be turned off
+	NotificationNewLocation      NotificationEvent = "new_location_login"
+	NotificationPasswordExpiring NotificationEvent = "password_expiry_reminder"
+	NotificationPasswordRotation NotificationEvent = "password_rotation_reminder"
+
+	// Login notifications: channels chosen by the user
+	NotificationLoginAlert NotificationEvent = "login_alert"
+
+	// Two-factor recovery: sent on every channel the user can be reached on,
+	// whatever their preferences
+	NotificationRecoveryCodeUsed  NotificationEvent = "recovery_code_used"
+	NotificationRecoveryStarted   NotificationEvent = "recovery_started"
+	NotificationRecoveryCompleted NotificationEvent = "recovery_completed"
+)
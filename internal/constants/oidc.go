@@ -0,0 +1,18 @@
+package constants
+
+// Scopes supported by the OpenID Connect provider.
+const (
+	ScopeOpenID        = "openid"         // required; issues an ID token
+	ScopeProfile       = "profile"        // name, given_name, family_name, locale claims
+	ScopeEmail         = "email"          // email claim
+	ScopeOfflineAccess = "offline_access" // issues a refresh token
+)
+
+// OIDCScopes lists every supported scope.
+var OIDCScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopeOfflineAccess}
+
+// Grant types accepted by the token endpoint.
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+)
@@ -0,0 +1,8 @@
+package constants
+
+// Onboarding emails, the steps of ONBOARDING_EMAILS.
+const (
+	OnboardingWelcome  = "welcome"  // welcome and getting started
+	OnboardingTips     = "tips"     // features worth knowing about
+	OnboardingSecurity = "security" // set up two-factor authentication; skipped once it is on
+)
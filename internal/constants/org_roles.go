@@ -0,0 +1,23 @@
+package constants
+
+// Organization role values stored in organization_members.role and carried in
+// the "org_role" JWT claim of organization-scoped tokens.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// orgRoleRanks orders organization roles from least to most privileged.
+var orgRoleRanks = map[string]int{
+	OrgRoleMember: 1,
+	OrgRoleAdmin:  2,
+	OrgRoleOwner:  3,
+}
+
+// OrgRoleAtLeast reports whether role grants at least the privileges of min.
+// Unknown roles grant nothing.
+func OrgRoleAtLeast(role, min string) bool {
+	rank, ok := orgRoleRanks[role]
+	return ok && rank >= orgRoleRanks[min]
+}
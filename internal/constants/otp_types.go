@@ -6,4 +6,4 @@ const (
     Type2FA           Type = "2fa"
     TypePasswordReset Type = "password_reset"
     TypeEmailVerify   Type = "email_verify"
-)
\ No newline at end of file
+)
@@ -0,0 +1,7 @@
+package constants
+
+// How access tokens are revoked (REVOCATION_STRATEGY).
+const (
+	RevocationStateful  = "stateful"  // revoked tokens are refused on every request (revocation list in Redis)
+	RevocationStateless = "stateless" // no per-request check; revoked sessions end at their next refresh
+)
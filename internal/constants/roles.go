@@ -0,0 +1,7 @@
+package constants
+
+// Role values stored in users.role and carried in the "role" JWT claim.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
@@ -0,0 +1,8 @@
+package constants
+
+// SameSite attribute of the session cookie (SESSION_COOKIE_SAMESITE).
+const (
+	SameSiteStrict = "strict" // sent only by pages of the same site
+	SameSiteLax    = "lax"    // also sent on top-level navigations from other sites
+	SameSiteNone   = "none"   // sent by every site, e.g. to apps on another site; requires Secure
+)
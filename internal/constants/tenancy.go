@@ -0,0 +1,16 @@
+package constants
+
+// How the tenant of a request is resolved (TENANCY_MODE).
+const (
+	TenancyOff       = "off"       // single tenant; every request uses the default tenant
+	TenancyHeader    = "header"    // tenant slug from a request header (TENANT_HEADER)
+	TenancySubdomain = "subdomain" // tenant slug from the first label below TENANT_BASE_DOMAIN
+	TenancyPath      = "path"      // tenant slug from a /t/{tenant} path prefix
+)
+
+// The default tenant owns every record created before tenancy was enabled and
+// serves requests that don't name a tenant.
+const (
+	DefaultTenantID   int64 = 1
+	DefaultTenantSlug       = "default"
+)
@@ -0,0 +1,30 @@
+package constants
+
+// 2FA methods stored in two_fa_configs.method.
+const (
+	TwoFAMethodEmail = "email"
+	TwoFAMethodPush  = "push"
+)
+
+// ChallengeQuarantine is the method of a risky login held until the user
+// approves it with an emailed link, besides the 2FA methods.
+const ChallengeQuarantine = "quarantine"
+
+// Push approval challenge states.
+const (
+	PushChallengePending    = "pending"
+	PushChallengeApproved   = "approved"
+	PushChallengeDenied     = "denied"
+	PushChallengeCompleted  = "completed"  // approved and exchanged for tokens
+	PushChallengeRecovering = "recovering" // the user started email recovery from it
+)
+
+// Two-factor recovery request states.
+const (
+	RecoveryPending   = "pending"
+	RecoveryCancelled = "cancelled"
+	RecoveryCompleted = "completed"
+)
+
+// RecoveryCodeCount is the number of recovery codes issued at a time.
+const RecoveryCodeCount = 10
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/pagination"
+)
+
+type auditLogRepository struct {
+	db *sql.DB
+}
+
+// NewAuditLogRepository creates a new PostgreSQL audit log repository
+func NewAuditLogRepository(db *sql.DB) repository.AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// Create appends a new audit log entry
+func (r *auditLogRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	var metadata sql.NullString
+	if len(entry.Metadata) > 0 {
+		raw, err := json.Marshal(entry.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = sql.NullString{String: string(raw), Valid: true}
+	}
+
+	// The device is looked up among the subject's registered devices, so an
+	// unknown fingerprint (or none) leaves device_id NULL
+	query := `
+		INSERT INTO audit_logs (user_id, actor_id, event, ip_address, user_agent, metadata, device_id)
+		VALUES ($1, $2, $3, $4, $5, $6,
+		        (SELECT id FROM user_devices WHERE user_id = $1 AND fingerprint_hash = $7))
+		RETURNING id, created_at, device_id`
+
+	return r.db.QueryRowContext(ctx, query,
+		entry.UserID,
+		entry.ActorID,
+		entry.Event,
+		nullString(entry.IPAddress),
+		nullString(entry.UserAgent),
+		metadata,
+		entry.DeviceFingerprintHash,
+	).Scan(&entry.ID, &entry.CreatedAt, &entry.DeviceID)
+}
+
+// ListByUser returns up to limit of a user's entries for the given events
+// (all events when empty), newest first, starting after the after cursor
+// when set
+func (r *auditLogRepository) ListByUser(ctx context.Context, userID int64, events []string, after *pagination.Cursor, limit int) ([]*models.AuditLog, error) {
+	args := []interface{}{userID, limit}
+	eventCondition := "TRUE"
+	if len(events) > 0 {
+		placeholders := make([]string, len(events))
+		for i, event := range events {
+			args = append(args, event)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		eventCondition = "event IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+	cursorCondition, args := afterCursor(after, args)
+
+	query := `
+		SELECT id, user_id, actor_id, event, COALESCE(ip_address, ''), COALESCE(user_agent, ''), metadata, created_at, device_id
+		FROM audit_logs
+		WHERE user_id = $1 AND ` + eventCondition + ` AND ` + cursorCondition + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditLog
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// scanAuditLog scans an entry selected with id, user_id, actor_id, event,
+// ip_address, user_agent, metadata, created_at and device_id
+func scanAuditLog(rows *sql.Rows) (*models.AuditLog, error) {
+	entry := &models.AuditLog{}
+	var userID, actorID, deviceID sql.NullInt64
+	var metadata []byte
+	if err := rows.Scan(&entry.ID, &userID, &actorID, &entry.Event, &entry.IPAddress, &entry.UserAgent, &metadata, &entry.CreatedAt, &deviceID); err != nil {
+		return nil, err
+	}
+	if deviceID.Valid {
+		entry.DeviceID = &deviceID.Int64
+	}
+	if userID.Valid {
+		entry.UserID = &userID.Int64
+	}
+	if actorID.Valid {
+		entry.ActorID = &actorID.Int64
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &entry.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	return entry, nil
+}
+
+// DailyLogins counts the successful and failed sign-ins to the tenant's
+// accounts per UTC day since since, oldest first. Failed sign-ins with an
+// unknown email have no user, so no tenant, and are not counted.
+func (r *auditLogRepository) DailyLogins(ctx context.Context, since time.Time) ([]models.DailyLoginStats, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT TO_CHAR(a.created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day,
+		       COUNT(*) FILTER (WHERE a.event = $2),
+		       COUNT(*) FILTER (WHERE a.event = $3),
+		       COUNT(DISTINCT a.user_id) FILTER (WHERE a.event = $2)
+		FROM audit_logs a
+		JOIN users u ON u.id = a.user_id
+		WHERE u.tenant_id = $1 AND a.event IN ($2, $3) AND a.created_at >= $4
+		GROUP BY day
+		ORDER BY day`,
+		tenantID(ctx), constants.AuditLoginSuccess, constants.AuditLoginFailed, since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []models.DailyLoginStats
+	for rows.Next() {
+		var day models.DailyLoginStats
+		if err := rows.Scan(&day.Date, &day.Successful, &day.Failed, &day.ActiveUsers); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// CountLoginCountry adds a successful or failed sign-in from country to the
+// tenant's rollup of the current UTC day
+func (r *auditLogRepository) CountLoginCountry(ctx context.Context, country string, successful bool) error {
+	counts := [2]int64{0, 1}
+	if successful {
+		counts = [2]int64{1, 0}
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO login_country_stats (tenant_id, day, country, successful, failed)
+		VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date, $2, $3, $4)
+		ON CONFLICT (tenant_id, day, country) DO UPDATE SET
+			successful = login_country_stats.successful + EXCLUDED.successful,
+			failed = login_country_stats.failed + EXCLUDED.failed`,
+		tenantID(ctx), country, counts[0], counts[1],
+	)
+	return err
+}
+
+// DailyLoginsByCountry returns the tenant's sign-ins per UTC day and country
+// since since, oldest first
+func (r *auditLogRepository) DailyLoginsByCountry(ctx context.Context, since time.Time) ([]models.DailyCountryLogins, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT TO_CHAR(day, 'YYYY-MM-DD'), country, successful, failed
+		FROM login_country_stats
+		WHERE tenant_id = $1 AND day >= ($2::timestamptz AT TIME ZONE 'UTC')::date
+		ORDER BY day, country`,
+		tenantID(ctx), since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logins []models.DailyCountryLogins
+	for rows.Next() {
+		var day models.DailyCountryLogins
+		if err := rows.Scan(&day.Date, &day.Country, &day.Successful, &day.Failed); err != nil {
+			return nil, err
+		}
+		logins = append(logins, day)
+	}
+
+	return logins, rows.Err()
+}
+
+// nullString maps an empty string to SQL NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// ListForArchive returns up to limit entries matching filter with an ID
+// above afterID, in ID order
+func (r *auditLogRepository) ListForArchive(ctx context.Context, filter models.AuditLogFilter, afterID int64, limit int) ([]*models.AuditLog, error) {
+	condition, args := auditFilterCondition(filter, []interface{}{afterID, limit})
+
+	query := `
+		SELECT id, user_id, actor_id, event, COALESCE(ip_address, ''), COALESCE(user_agent, ''), metadata, created_at, device_id
+		FROM audit_logs
+		WHERE id > $1 AND ` + condition + `
+		ORDER BY id
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditLog
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// Purge deletes up to limit entries matching filter and returns how many it
+// deleted
+func (r *auditLogRepository) Purge(ctx context.Context, filter models.AuditLogFilter, limit int) (int64, error) {
+	condition, args := auditFilterCondition(filter, []interface{}{limit})
+
+	query := `
+		DELETE FROM audit_logs
+		WHERE id IN (SELECT id FROM audit_logs WHERE ` + condition + ` LIMIT $1)`
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// auditFilterCondition returns the SQL condition selecting the entries of
+// filter, with its arguments appended to args
+func auditFilterCondition(filter models.AuditLogFilter, args []interface{}) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	if len(filter.Events) > 0 {
+		placeholders := make([]string, len(filter.Events))
+		for i, event := range filter.Events {
+			args = append(args, event)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		operator := "IN"
+		if filter.OtherEvents {
+			operator = "NOT IN"
+		}
+		conditions = append(conditions, "event "+operator+" ("+strings.Join(placeholders, ", ")+")")
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.Before.IsZero() {
+		args = append(args, filter.Before)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if filter.ThroughID != 0 {
+		args = append(args, filter.ThroughID)
+		conditions = append(conditions, fmt.Sprintf("id <= $%d", len(args)))
+	}
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type consentRepository struct {
+	db *sql.DB
+}
+
+// NewConsentRepository creates a new PostgreSQL consent repository
+func NewConsentRepository(db *sql.DB) repository.ConsentRepository {
+	return &consentRepository{db: db}
+}
+
+// Record stores a new consent acceptance
+func (r *consentRepository) Record(ctx context.Context, consent *models.UserConsent) error {
+	query := `
+		INSERT INTO user_consents (user_id, document, version, ip_address)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, accepted_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		consent.UserID,
+		consent.Document,
+		consent.Version,
+		nullString(consent.IPAddress),
+	).Scan(&consent.ID, &consent.AcceptedAt)
+}
+
+// LatestByUser returns the most recent acceptance per document, keyed by document
+func (r *consentRepository) LatestByUser(ctx context.Context, userID int64) (map[string]*models.UserConsent, error) {
+	query := `
+		SELECT DISTINCT ON (document) id, user_id, document, version, accepted_at
+		FROM user_consents
+		WHERE user_id = $1
+		ORDER BY document, accepted_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := make(map[string]*models.UserConsent)
+	for rows.Next() {
+		consent := &models.UserConsent{}
+		if err := rows.Scan(
+			&consent.ID,
+			&consent.UserID,
+			&consent.Document,
+			&consent.Version,
+			&consent.AcceptedAt,
+		); err != nil {
+			return nil, err
+		}
+		consents[consent.Document] = consent
+	}
+
+	return consents, rows.Err()
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type deviceRepository struct {
+	db *sql.DB
+}
+
+// NewDeviceRepository creates a new PostgreSQL device registry repository
+func NewDeviceRepository(db *sql.DB) repository.DeviceRepository {
+	return &deviceRepository{db: db}
+}
+
+// TouchDevice inserts the device, or updates the last seen time, address,
+// user agent and platform of the user's device with the same fingerprint.
+// The stored ID, name and first seen time are read back into device.
+func (r *deviceRepository) TouchDevice(ctx context.Context, device *models.Device) error {
+	query := `
+		INSERT INTO user_devices (user_id, fingerprint_hash, platform, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, fingerprint_hash) DO UPDATE
+		SET platform = EXCLUDED.platform, ip_address = EXCLUDED.ip_address,
+		    user_agent = EXCLUDED.user_agent, last_seen_at = NOW()
+		RETURNING id, name, first_seen_at, last_seen_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		device.UserID,
+		device.FingerprintHash,
+		device.Platform,
+		nullString(device.IPAddress),
+		nullString(device.UserAgent),
+	).Scan(&device.ID, &device.Name, &device.FirstSeenAt, &device.LastSeenAt)
+}
+
+// ListDevices returns the user's devices, most recently seen first.
+func (r *deviceRepository) ListDevices(ctx context.Context, userID int64) ([]models.Device, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT d.id, d.user_id, d.fingerprint_hash, d.name, d.platform,
+		       COALESCE(d.ip_address, ''), COALESCE(d.user_agent, ''), d.first_seen_at, d.last_seen_at
+		FROM user_devices d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1 AND u.tenant_id = $2
+		ORDER BY d.last_seen_at DESC, d.id DESC`,
+		userID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		var d models.Device
+		err := rows.Scan(&d.ID, &d.UserID, &d.FingerprintHash, &d.Name, &d.Platform,
+			&d.IPAddress, &d.UserAgent, &d.FirstSeenAt, &d.LastSeenAt)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+
+	return devices, rows.Err()
+}
+
+// RenameDevice sets the name of one of the user's devices. It returns
+// sql.ErrNoRows if the device doesn't exist.
+func (r *deviceRepository) RenameDevice(ctx context.Context, userID, deviceID int64, name string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE user_devices SET name = $1 WHERE id = $2 AND user_id = $3`, name, deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteDevice deletes the refresh tokens issued to one of the user's devices
+// and then the device, in a single transaction. It returns sql.ErrNoRows if
+// the device doesn't exist.
+func (r *deviceRepository) DeleteDevice(ctx context.Context, userID, deviceID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE device_id = $1 AND user_id = $2`, deviceID, userID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_devices WHERE id = $1 AND user_id = $2`, deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,261 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/pagination"
+)
+
+type duplicateAccountRepository struct {
+	db *sql.DB
+}
+
+// NewDuplicateAccountRepository creates a new PostgreSQL duplicate account repository
+func NewDuplicateAccountRepository(db *sql.DB) repository.DuplicateAccountRepository {
+	return &duplicateAccountRepository{db: db}
+}
+
+// Candidates finds the tenant's groups of accounts sharing a normalized
+// email, a phone number or an identity provider subject. Emails are
+// normalized by lowercasing them and dropping the +tag of the local part, and
+// its dots at Gmail. Deleted and anonymized accounts are left out.
+func (r *duplicateAccountRepository) Candidates(ctx context.Context) ([]models.DuplicateCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH accounts AS (
+			SELECT id, LOWER(REGEXP_REPLACE(SPLIT_PART(email, '@', 1), '\+.*$', '')) AS local_part,
+			       LOWER(SPLIT_PART(email, '@', 2)) AS domain, provider, provider_id
+			FROM users
+			WHERE tenant_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL
+		), keys AS (
+			SELECT '`+constants.DuplicateByEmail+`' AS reason, id,
+			       CASE WHEN domain IN ('gmail.com', 'googlemail.com') THEN REPLACE(local_part, '.', '') || '@gmail.com'
+			            ELSE local_part || '@' || domain END AS match_key
+			FROM accounts
+			UNION ALL
+			SELECT '`+constants.DuplicateByPhone+`', a.id, COALESCE(p.phone_number_hash, p.phone_number)
+			FROM accounts a JOIN user_preferences p ON p.user_id = a.id
+			WHERE p.phone_number IS NOT NULL
+			UNION ALL
+			SELECT '`+constants.DuplicateByProvider+`', id, provider || ':' || provider_id
+			FROM accounts
+			WHERE provider_id IS NOT NULL
+		)
+		SELECT reason, match_key, id FROM (
+			SELECT reason, match_key, id, COUNT(*) OVER (PARTITION BY reason, match_key) AS matches FROM keys
+		) counted
+		WHERE matches > 1
+		ORDER BY reason, match_key, id`, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.DuplicateCandidate
+	for rows.Next() {
+		var reason, matchKey string
+		var userID int64
+		if err := rows.Scan(&reason, &matchKey, &userID); err != nil {
+			return nil, err
+		}
+		if n := len(candidates); n > 0 && candidates[n-1].Reason == reason && candidates[n-1].MatchKey == matchKey {
+			candidates[n-1].UserIDs = append(candidates[n-1].UserIDs, userID)
+			continue
+		}
+		candidates = append(candidates, models.DuplicateCandidate{Reason: reason, MatchKey: matchKey, UserIDs: []int64{userID}})
+	}
+
+	return candidates, rows.Err()
+}
+
+// Sync replaces the tenant's groups with candidates. Groups keep their
+// status unless an account joins them, which reopens them.
+func (r *duplicateAccountRepository) Sync(ctx context.Context, candidates []models.DuplicateCandidate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Current groups and their members, by reason and match key
+	type group struct {
+		id      int64
+		members map[int64]bool
+	}
+	groups := make(map[string]*group)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT g.id, g.reason, g.match_key, m.user_id
+		FROM duplicate_account_groups g
+		LEFT JOIN duplicate_account_members m ON m.group_id = g.id
+		WHERE g.tenant_id = $1
+		FOR UPDATE OF g`, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var reason, matchKey string
+		var userID sql.NullInt64
+		if err := rows.Scan(&id, &reason, &matchKey, &userID); err != nil {
+			rows.Close()
+			return err
+		}
+		key := reason + ":" + matchKey
+		if groups[key] == nil {
+			groups[key] = &group{id: id, members: make(map[int64]bool)}
+		}
+		if userID.Valid {
+			groups[key].members[userID.Int64] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		key := candidate.Reason + ":" + candidate.MatchKey
+		existing := groups[key]
+		delete(groups, key)
+
+		if existing == nil {
+			var id int64
+			if err := tx.QueryRowContext(ctx, `
+				INSERT INTO duplicate_account_groups (tenant_id, reason, match_key)
+				VALUES ($1, $2, $3)
+				RETURNING id`,
+				tenantID(ctx), candidate.Reason, candidate.MatchKey,
+			).Scan(&id); err != nil {
+				return err
+			}
+			if err := addDuplicateMembers(ctx, tx, id, candidate.UserIDs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		joined := false
+		for _, userID := range candidate.UserIDs {
+			if !existing.members[userID] {
+				joined = true
+			}
+		}
+		if !joined && len(existing.members) == len(candidate.UserIDs) {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM duplicate_account_members WHERE group_id = $1`, existing.id); err != nil {
+			return err
+		}
+		if err := addDuplicateMembers(ctx, tx, existing.id, candidate.UserIDs); err != nil {
+			return err
+		}
+		update := `UPDATE duplicate_account_groups SET updated_at = NOW() WHERE id = $1`
+		if joined {
+			update = `
+				UPDATE duplicate_account_groups
+				SET status = '` + constants.DuplicatesOpen + `', dismissed_by = NULL, dismissed_at = NULL, updated_at = NOW()
+				WHERE id = $1`
+		}
+		if _, err := tx.ExecContext(ctx, update, existing.id); err != nil {
+			return err
+		}
+	}
+
+	// Groups the scan no longer finds
+	for _, stale := range groups {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM duplicate_account_groups WHERE id = $1`, stale.id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// addDuplicateMembers adds userIDs to a group
+func addDuplicateMembers(ctx context.Context, tx *sql.Tx, groupID int64, userIDs []int64) error {
+	for _, userID := range userIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO duplicate_account_members (group_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			groupID, userID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns up to limit of the tenant's groups with the given status (any
+// when empty), newest first, starting after the after cursor when set
+func (r *duplicateAccountRepository) List(ctx context.Context, status string, after *pagination.Cursor, limit int) ([]*models.DuplicateAccountGroup, error) {
+	args := []interface{}{tenantID(ctx), limit}
+	conditions := []string{"tenant_id = $1"}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	cursorCondition, args := afterCursor(after, args)
+	conditions = append(conditions, cursorCondition)
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH page AS (
+			SELECT id, reason, match_key, status, dismissed_by, dismissed_at, created_at, updated_at
+			FROM duplicate_account_groups
+			WHERE `+strings.Join(conditions, " AND ")+`
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)
+		SELECT p.id, p.reason, p.match_key, p.status, p.dismissed_by, p.dismissed_at, p.created_at, p.updated_at,
+		       u.id, u.email, u.first_name, u.last_name, COALESCE(u.provider, ''), u.created_at
+		FROM page p
+		JOIN duplicate_account_members m ON m.group_id = p.id
+		JOIN users u ON u.id = m.user_id
+		ORDER BY p.created_at DESC, p.id DESC, u.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*models.DuplicateAccountGroup
+	for rows.Next() {
+		group := &models.DuplicateAccountGroup{}
+		var user models.DuplicateAccountUser
+		if err := rows.Scan(&group.ID, &group.Reason, &group.MatchKey, &group.Status, &group.DismissedBy, &group.DismissedAt,
+			&group.CreatedAt, &group.UpdatedAt,
+			&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.Provider, &user.CreatedAt); err != nil {
+			return nil, err
+		}
+		if n := len(groups); n > 0 && groups[n-1].ID == group.ID {
+			groups[n-1].Users = append(groups[n-1].Users, user)
+			continue
+		}
+		group.Users = []models.DuplicateAccountUser{user}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// Dismiss marks a group as not duplicates; it returns false when the group
+// doesn't exist
+func (r *duplicateAccountRepository) Dismiss(ctx context.Context, id, dismissedBy int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE duplicate_account_groups
+		SET status = $3, dismissed_by = $4, dismissed_at = NOW()
+		WHERE id = $1 AND tenant_id = $2`,
+		id, tenantID(ctx), constants.DuplicatesDismissed, dismissedBy,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type emailDomainRuleRepository struct {
+	db *sql.DB
+}
+
+// NewEmailDomainRuleRepository creates a new PostgreSQL email domain rule repository
+func NewEmailDomainRuleRepository(db *sql.DB) repository.EmailDomainRuleRepository {
+	return &emailDomainRuleRepository{db: db}
+}
+
+// List returns all rules ordered by domain
+func (r *emailDomainRuleRepository) List(ctx context.Context) ([]*models.EmailDomainRule, error) {
+	query := `
+		SELECT id, domain, rule, created_by, created_at
+		FROM email_domain_rules
+		WHERE tenant_id = $1
+		ORDER BY domain`
+
+	rows, err := r.db.QueryContext(ctx, query, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.EmailDomainRule
+	for rows.Next() {
+		rule := &models.EmailDomainRule{}
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&rule.ID, &rule.Domain, &rule.Rule, &createdBy, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		if createdBy.Valid {
+			rule.CreatedBy = &createdBy.Int64
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// Create inserts a rule, replacing the rule type if the domain already has one
+func (r *emailDomainRuleRepository) Create(ctx context.Context, rule *models.EmailDomainRule) error {
+	query := `
+		INSERT INTO email_domain_rules (domain, rule, created_by, tenant_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, domain) DO UPDATE SET rule = EXCLUDED.rule, created_by = EXCLUDED.created_by, created_at = NOW()
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query, rule.Domain, rule.Rule, rule.CreatedBy, tenantID(ctx)).
+		Scan(&rule.ID, &rule.CreatedAt)
+}
+
+// Delete removes a rule by ID, returning sql.ErrNoRows if it doesn't exist
+func (r *emailDomainRuleRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM email_domain_rules WHERE id = $1 AND tenant_id = $2`, id, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
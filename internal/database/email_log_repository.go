@@ -0,0 +1,169 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/email"
+	"authentio/pkg/pagination"
+)
+
+type emailLogRepository struct {
+	db *sql.DB
+}
+
+// NewEmailLogRepository creates a new PostgreSQL email delivery log repository
+func NewEmailLogRepository(db *sql.DB) repository.EmailLogRepository {
+	return &emailLogRepository{db: db}
+}
+
+// Create records a queued email, one entry per recipient, linking each to
+// the tenant's account with that address
+func (r *emailLogRepository) Create(ctx context.Context, messageID, event, subject string, recipients []string) error {
+	for _, recipient := range recipients {
+		_, err := r.db.ExecContext(ctx, `
+			INSERT INTO email_log (tenant_id, message_id, user_id, event, recipient, subject)
+			VALUES ($1, $2,
+			        (SELECT id FROM users WHERE tenant_id = $1 AND email = $4 AND deleted_at IS NULL),
+			        $3, $4, $5)
+			ON CONFLICT (message_id, recipient) DO NOTHING`,
+			tenantID(ctx), messageID, event, recipient, subject,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordAttempt records a send attempt of every recipient of an email: sent
+// when sendErr is nil, failed otherwise. Workers send without a tenant, so
+// the unguessable message ID alone selects the entries. Events the provider
+// already reported aren't overwritten.
+func (r *emailLogRepository) RecordAttempt(ctx context.Context, messageID, providerMessageID string, sendErr error) error {
+	if sendErr != nil {
+		_, err := r.db.ExecContext(ctx, `
+			UPDATE email_log
+			SET status = $2, error = $3, attempts = attempts + 1, updated_at = NOW()
+			WHERE message_id = $1 AND status IN ($4, $2)`,
+			messageID, constants.EmailFailed, sendErr.Error(), constants.EmailQueued,
+		)
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_log
+		SET status = CASE WHEN status IN ($2, $3) THEN $4 ELSE status END,
+		    error = NULL, attempts = attempts + 1,
+		    provider_message_id = NULLIF($5, ''), sent_at = NOW(), updated_at = NOW()
+		WHERE message_id = $1`,
+		messageID, constants.EmailQueued, constants.EmailFailed, constants.EmailSent, providerMessageID,
+	)
+	return err
+}
+
+// MarkFailed marks an email that couldn't be queued as failed
+func (r *emailLogRepository) MarkFailed(ctx context.Context, messageID, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE email_log
+		SET status = $2, error = $3, updated_at = NOW()
+		WHERE message_id = $1 AND status IN ($4, $2)`,
+		messageID, constants.EmailFailed, reason, constants.EmailQueued,
+	)
+	return err
+}
+
+// RecordEvent applies a delivery or engagement event reported by the
+// provider for one recipient; unknown emails are ignored. An open implies
+// the email was delivered.
+func (r *emailLogRepository) RecordEvent(ctx context.Context, event email.DeliveryEvent) error {
+	var query string
+	args := []interface{}{event.MessageID, event.Recipient, event.At}
+	switch event.Type {
+	case email.EventDelivered:
+		args = append(args, constants.EmailDelivered, constants.EmailQueued, constants.EmailSent, constants.EmailFailed)
+		query = `
+			UPDATE email_log
+			SET status = CASE WHEN status IN ($5, $6, $7) THEN $4 ELSE status END,
+			    delivered_at = COALESCE(delivered_at, $3), error = NULL, updated_at = NOW()
+			WHERE message_id = $1 AND LOWER(recipient) = LOWER($2)`
+	case email.EventBounced, email.EventDropped:
+		status := constants.EmailBounced
+		if event.Type == email.EventDropped {
+			status = constants.EmailDropped
+		}
+		args = append(args, status, event.Reason)
+		query = `
+			UPDATE email_log
+			SET status = $4, error = NULLIF($5, ''), updated_at = NOW()
+			WHERE message_id = $1 AND LOWER(recipient) = LOWER($2)`
+	case email.EventOpened:
+		args = append(args, constants.EmailDelivered, constants.EmailQueued, constants.EmailSent, constants.EmailFailed)
+		query = `
+			UPDATE email_log
+			SET status = CASE WHEN status IN ($5, $6, $7) THEN $4 ELSE status END,
+			    delivered_at = COALESCE(delivered_at, $3),
+			    opened_at = COALESCE(opened_at, $3), open_count = open_count + 1, updated_at = NOW()
+			WHERE message_id = $1 AND LOWER(recipient) = LOWER($2)`
+	default:
+		return nil
+	}
+
+	_, err := r.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+// List returns up to limit of the tenant's records matching filter, newest
+// first, starting after the after cursor when set
+func (r *emailLogRepository) List(ctx context.Context, filter models.EmailLogFilter, after *pagination.Cursor, limit int) ([]*models.EmailLog, error) {
+	args := []interface{}{tenantID(ctx), limit}
+	conditions := []string{"tenant_id = $1"}
+	for _, f := range []struct {
+		column string
+		value  interface{}
+		set    bool
+	}{
+		{"user_id", filter.UserID, filter.UserID != 0},
+		{"message_id", filter.MessageID, filter.MessageID != ""},
+		{"LOWER(recipient)", strings.ToLower(filter.Recipient), filter.Recipient != ""},
+		{"event", filter.Event, filter.Event != ""},
+		{"status", filter.Status, filter.Status != ""},
+	} {
+		if f.set {
+			args = append(args, f.value)
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", f.column, len(args)))
+		}
+	}
+	cursorCondition, args := afterCursor(after, args)
+	conditions = append(conditions, cursorCondition)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, message_id, user_id, event, recipient, subject, status, attempts, COALESCE(error, ''),
+		       COALESCE(provider_message_id, ''), sent_at, delivered_at, opened_at, open_count, created_at, updated_at
+		FROM email_log
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*models.EmailLog
+	for rows.Next() {
+		entry := &models.EmailLog{}
+		if err := rows.Scan(&entry.ID, &entry.MessageID, &entry.UserID, &entry.Event, &entry.Recipient, &entry.Subject,
+			&entry.Status, &entry.Attempts, &entry.Error, &entry.ProviderMessageID,
+			&entry.SentAt, &entry.DeliveredAt, &entry.OpenedAt, &entry.OpenCount, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
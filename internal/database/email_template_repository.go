@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/email"
+)
+
+type emailTemplateRepository struct {
+	db *sql.DB
+}
+
+// NewEmailTemplateRepository creates a new PostgreSQL email template repository
+func NewEmailTemplateRepository(db *sql.DB) repository.EmailTemplateRepository {
+	return &emailTemplateRepository{db: db}
+}
+
+const emailTemplateColumns = `id, name, version, subject, content, active, created_by, created_at`
+
+// ActiveTemplate returns the tenant's active version of a template, or nil
+// when it uses the default
+func (r *emailTemplateRepository) ActiveTemplate(ctx context.Context, name string) (*email.StoredTemplate, error) {
+	stored := &email.StoredTemplate{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, version, subject, content FROM email_templates
+		WHERE tenant_id = $1 AND name = $2 AND active`,
+		tenantID(ctx), name,
+	).Scan(&stored.ID, &stored.Version, &stored.Subject, &stored.Content)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ListActive returns the tenant's active template versions
+func (r *emailTemplateRepository) ListActive(ctx context.Context) ([]*models.EmailTemplate, error) {
+	return r.query(ctx, `
+		SELECT `+emailTemplateColumns+` FROM email_templates
+		WHERE tenant_id = $1 AND active
+		ORDER BY name`, tenantID(ctx))
+}
+
+// Versions returns the tenant's versions of a template, newest first
+func (r *emailTemplateRepository) Versions(ctx context.Context, name string) ([]*models.EmailTemplate, error) {
+	return r.query(ctx, `
+		SELECT `+emailTemplateColumns+` FROM email_templates
+		WHERE tenant_id = $1 AND name = $2
+		ORDER BY version DESC`, tenantID(ctx), name)
+}
+
+// Create saves the next version of a template and makes it active
+func (r *emailTemplateRepository) Create(ctx context.Context, tmpl *models.EmailTemplate) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE email_templates SET active = FALSE WHERE tenant_id = $1 AND name = $2 AND active`,
+		tenantID(ctx), tmpl.Name,
+	); err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO email_templates (tenant_id, name, version, subject, content, active, created_by)
+		SELECT $1, $2, COALESCE(MAX(version), 0) + 1, $3, $4, TRUE, $5
+		FROM email_templates WHERE tenant_id = $1 AND name = $2
+		RETURNING id, version, active, created_at`,
+		tenantID(ctx), tmpl.Name, tmpl.Subject, tmpl.Content, tmpl.CreatedBy,
+	).Scan(&tmpl.ID, &tmpl.Version, &tmpl.Active, &tmpl.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Activate makes a saved version active; it returns nil when the version
+// doesn't exist
+func (r *emailTemplateRepository) Activate(ctx context.Context, name string, version int) (*models.EmailTemplate, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE email_templates SET active = FALSE WHERE tenant_id = $1 AND name = $2 AND active AND version <> $3`,
+		tenantID(ctx), name, version,
+	); err != nil {
+		return nil, err
+	}
+
+	tmpl := &models.EmailTemplate{}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE email_templates SET active = TRUE
+		WHERE tenant_id = $1 AND name = $2 AND version = $3
+		RETURNING `+emailTemplateColumns,
+		tenantID(ctx), name, version,
+	).Scan(&tmpl.ID, &tmpl.Name, &tmpl.Version, &tmpl.Subject, &tmpl.Content, &tmpl.Active, &tmpl.CreatedBy, &tmpl.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return tmpl, tx.Commit()
+}
+
+// Deactivate makes a template use the default again
+func (r *emailTemplateRepository) Deactivate(ctx context.Context, name string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE email_templates SET active = FALSE WHERE tenant_id = $1 AND name = $2 AND active`,
+		tenantID(ctx), name,
+	)
+	return err
+}
+
+// query runs a query selecting emailTemplateColumns
+func (r *emailTemplateRepository) query(ctx context.Context, query string, args ...interface{}) ([]*models.EmailTemplate, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*models.EmailTemplate
+	for rows.Next() {
+		tmpl := &models.EmailTemplate{}
+		if err := rows.Scan(&tmpl.ID, &tmpl.Name, &tmpl.Version, &tmpl.Subject, &tmpl.Content, &tmpl.Active, &tmpl.CreatedBy, &tmpl.CreatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return templates, rows.Err()
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type invitationRepository struct {
+	db *sql.DB
+}
+
+// NewInvitationRepository creates a new PostgreSQL invitation repository
+func NewInvitationRepository(db *sql.DB) repository.InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+const invitationColumns = `id, email, token_hash, invited_by, accepted_by, expires_at, accepted_at, revoked_at, created_at`
+
+// Create stores a new invitation
+func (r *invitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	query := `
+		INSERT INTO invitations (email, token_hash, invited_by, expires_at, tenant_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		invitation.Email,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+		tenantID(ctx),
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+}
+
+// FindByID returns an invitation by ID, or nil if it doesn't exist
+func (r *invitationRepository) FindByID(ctx context.Context, id int64) (*models.Invitation, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+invitationColumns+` FROM invitations WHERE id = $1 AND tenant_id = $2`, id, tenantID(ctx))
+	return scanInvitation(row)
+}
+
+// FindByTokenHash returns the invitation with the given token hash, or nil
+func (r *invitationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+invitationColumns+` FROM invitations WHERE token_hash = $1 AND tenant_id = $2`, tokenHash, tenantID(ctx))
+	return scanInvitation(row)
+}
+
+// List returns invitations newest first; invitedBy limits them to one inviter when non-nil
+func (r *invitationRepository) List(ctx context.Context, invitedBy *int64) ([]*models.Invitation, error) {
+	query := `
+		SELECT ` + invitationColumns + `
+		FROM invitations
+		WHERE tenant_id = $2 AND ($1::BIGINT IS NULL OR invited_by = $1)
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, invitedBy, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*models.Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// Revoke marks a pending invitation as revoked
+func (r *invitationRepository) Revoke(ctx context.Context, id int64) error {
+	query := `
+		UPDATE invitations SET revoked_at = NOW()
+		WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL AND accepted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id, tenantID(ctx))
+	return err
+}
+
+// MarkAccepted records the user created from a pending invitation. It
+// returns sql.ErrNoRows if the invitation is no longer pending.
+func (r *invitationRepository) MarkAccepted(ctx context.Context, id, userID int64) error {
+	query := `
+		UPDATE invitations SET accepted_by = $2, accepted_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND accepted_at IS NULL AND expires_at > NOW()`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanInvitation scans one invitation row, returning nil, nil for sql.ErrNoRows.
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	var invitedBy, acceptedBy sql.NullInt64
+	var acceptedAt, revokedAt sql.NullTime
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.Email,
+		&invitation.TokenHash,
+		&invitedBy,
+		&acceptedBy,
+		&invitation.ExpiresAt,
+		&acceptedAt,
+		&revokedAt,
+		&invitation.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if invitedBy.Valid {
+		invitation.InvitedBy = &invitedBy.Int64
+	}
+	if acceptedBy.Valid {
+		invitation.AcceptedBy = &acceptedBy.Int64
+	}
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = &acceptedAt.Time
+	}
+	if revokedAt.Valid {
+		invitation.RevokedAt = &revokedAt.Time
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type ipAllowlistRepository struct {
+	db *sql.DB
+}
+
+// NewIPAllowlistRepository creates a new PostgreSQL IP allowlist repository
+func NewIPAllowlistRepository(db *sql.DB) repository.IPAllowlistRepository {
+	return &ipAllowlistRepository{db: db}
+}
+
+// ListEntries returns the user's unexpired entries, oldest first.
+func (r *ipAllowlistRepository) ListEntries(ctx context.Context, userID int64) ([]models.IPAllowlistEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT a.id, a.user_id, a.cidr::text, a.label, a.created_by, a.expires_at, a.created_at
+		FROM user_ip_allowlist a
+		JOIN users u ON u.id = a.user_id
+		WHERE a.user_id = $1 AND u.tenant_id = $2 AND (a.expires_at IS NULL OR a.expires_at > NOW())
+		ORDER BY a.created_at, a.id`,
+		userID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.IPAllowlistEntry
+	for rows.Next() {
+		var e models.IPAllowlistEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.CIDR, &e.Label, &e.CreatedBy, &e.ExpiresAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// SaveEntry inserts the entry, or replaces the label, expiry and author of
+// the user's entry for the same block. The stored ID and creation time are
+// read back into entry.
+func (r *ipAllowlistRepository) SaveEntry(ctx context.Context, entry *models.IPAllowlistEntry) error {
+	query := `
+		INSERT INTO user_ip_allowlist (user_id, cidr, label, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, cidr) DO UPDATE
+		SET label = EXCLUDED.label, created_by = EXCLUDED.created_by, expires_at = EXCLUDED.expires_at
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		entry.UserID,
+		entry.CIDR,
+		entry.Label,
+		entry.CreatedBy,
+		entry.ExpiresAt,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// DeleteEntry removes one of the user's entries. It returns sql.ErrNoRows if
+// the entry doesn't exist.
+func (r *ipAllowlistRepository) DeleteEntry(ctx context.Context, userID, entryID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_ip_allowlist WHERE id = $1 AND user_id = $2`, entryID, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetMode returns the user's allowlist mode.
+func (r *ipAllowlistRepository) GetMode(ctx context.Context, userID int64) (string, error) {
+	var mode string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT ip_allowlist_mode FROM users WHERE id = $1 AND tenant_id = $2`, userID, tenantID(ctx),
+	).Scan(&mode)
+	return mode, err
+}
+
+// SetMode saves the user's allowlist mode. It returns sql.ErrNoRows if the
+// user doesn't exist.
+func (r *ipAllowlistRepository) SetMode(ctx context.Context, userID int64, mode string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET ip_allowlist_mode = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3`, mode, userID, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
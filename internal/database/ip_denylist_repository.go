@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type ipDenylistRepository struct {
+	db *sql.DB
+}
+
+// NewIPDenylistRepository creates a new PostgreSQL IP denylist repository
+func NewIPDenylistRepository(db *sql.DB) repository.IPDenylistRepository {
+	return &ipDenylistRepository{db: db}
+}
+
+// List returns the unexpired entries ordered by block. The denylist applies
+// to every tenant, so it isn't scoped.
+func (r *ipDenylistRepository) List(ctx context.Context) ([]models.IPDenylistEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, cidr::text, reason, created_by, expires_at, created_at
+		FROM ip_denylist
+		WHERE expires_at IS NULL OR expires_at > NOW()
+		ORDER BY cidr`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.IPDenylistEntry
+	for rows.Next() {
+		var e models.IPDenylistEntry
+		if err := rows.Scan(&e.ID, &e.CIDR, &e.Reason, &e.CreatedBy, &e.ExpiresAt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// Save inserts the entry, or replaces the reason, expiry and author of the
+// entry for the same block. The stored ID and creation time are read back
+// into entry.
+func (r *ipDenylistRepository) Save(ctx context.Context, entry *models.IPDenylistEntry) error {
+	query := `
+		INSERT INTO ip_denylist (cidr, reason, created_by, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (cidr) DO UPDATE
+		SET reason = EXCLUDED.reason, created_by = EXCLUDED.created_by, expires_at = EXCLUDED.expires_at, created_at = NOW()
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		entry.CIDR,
+		entry.Reason,
+		entry.CreatedBy,
+		entry.ExpiresAt,
+	).Scan(&entry.ID, &entry.CreatedAt)
+}
+
+// Delete removes an entry by ID. It returns sql.ErrNoRows if the entry
+// doesn't exist.
+func (r *ipDenylistRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM ip_denylist WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type managedKeyRepository struct {
+	db *sql.DB
+}
+
+// NewManagedKeyRepository creates a new PostgreSQL managed key repository
+func NewManagedKeyRepository(db *sql.DB) repository.ManagedKeyRepository {
+	return &managedKeyRepository{db: db}
+}
+
+// ListKeys returns the keys that aren't retired, oldest first
+func (r *managedKeyRepository) ListKeys(ctx context.Context) ([]*models.ManagedKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, purpose, key_id, material, status, created_by, created_at, retired_at
+		FROM managed_keys
+		WHERE status <> $1
+		ORDER BY created_at, id`,
+		constants.KeyRetired,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*models.ManagedKey
+	for rows.Next() {
+		key := &models.ManagedKey{}
+		if err := rows.Scan(&key.ID, &key.Purpose, &key.KeyID, &key.Material, &key.Status, &key.CreatedBy, &key.CreatedAt, &key.RetiredAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Rotate makes key the active key of its purpose and the previous one
+// verify-only, recording rotation, in one transaction
+func (r *managedKeyRepository) Rotate(ctx context.Context, key *models.ManagedKey, rotation *models.KeyRotation) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The partial unique index allows one running rotation per purpose
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO key_rotations (purpose, key_id, previous_key_id, status, reencrypted, requested_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (purpose) WHERE status = 'running' DO NOTHING
+		RETURNING id, started_at`,
+		rotation.Purpose, rotation.KeyID, rotation.PreviousKeyID, rotation.Status, rotation.Reencrypted, rotation.RequestedBy,
+	).Scan(&rotation.ID, &rotation.StartedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE managed_keys SET status = $2, retired_at = NOW()
+		WHERE purpose = $1 AND status = $3`,
+		key.Purpose, constants.KeyVerifyOnly, constants.KeyActive,
+	); err != nil {
+		return false, err
+	}
+
+	key.Status = constants.KeyActive
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO managed_keys (purpose, key_id, material, status, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`,
+		key.Purpose, key.KeyID, key.Material, key.Status, key.CreatedBy,
+	).Scan(&key.ID, &key.CreatedAt); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// RetireKeys retires the verify-only keys of purpose that stopped signing
+// before the given time
+func (r *managedKeyRepository) RetireKeys(ctx context.Context, purpose string, before time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE managed_keys SET status = $3
+		WHERE purpose = $1 AND status = $4 AND retired_at < $2`,
+		purpose, before, constants.KeyRetired, constants.KeyVerifyOnly,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// UpdateMaterial replaces the sealed material of a key unless it changed
+// since it was read
+func (r *managedKeyRepository) UpdateMaterial(ctx context.Context, id int64, old, material string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE managed_keys SET material = $3 WHERE id = $1 AND material = $2`,
+		id, old, material,
+	)
+	return err
+}
+
+// UpdateRotation saves the progress, status and error of a rotation; the
+// completion time is set when it is no longer running
+func (r *managedKeyRepository) UpdateRotation(ctx context.Context, rotation *models.KeyRotation) error {
+	return r.db.QueryRowContext(ctx, `
+		UPDATE key_rotations
+		SET status = $2, reencrypted = $3, error = NULLIF($4, ''),
+			completed_at = CASE WHEN $2 = 'running' THEN NULL ELSE NOW() END
+		WHERE id = $1
+		RETURNING completed_at`,
+		rotation.ID, rotation.Status, rotation.Reencrypted, rotation.Error,
+	).Scan(&rotation.CompletedAt)
+}
+
+// ListRotations returns the latest rotations, newest first
+func (r *managedKeyRepository) ListRotations(ctx context.Context, limit int) ([]*models.KeyRotation, error) {
+	return r.queryRotations(ctx, `
+		SELECT id, purpose, key_id, previous_key_id, status, reencrypted, COALESCE(error, ''), requested_by, started_at, completed_at
+		FROM key_rotations
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1`, limit)
+}
+
+// RunningRotations returns the rotations whose job hasn't finished, e.g.
+// because the server stopped during it
+func (r *managedKeyRepository) RunningRotations(ctx context.Context) ([]*models.KeyRotation, error) {
+	return r.queryRotations(ctx, `
+		SELECT id, purpose, key_id, previous_key_id, status, reencrypted, COALESCE(error, ''), requested_by, started_at, completed_at
+		FROM key_rotations
+		WHERE status = $1
+		ORDER BY started_at`, constants.RotationRunning)
+}
+
+// queryRotations runs a query selecting key_rotations rows
+func (r *managedKeyRepository) queryRotations(ctx context.Context, query string, args ...interface{}) ([]*models.KeyRotation, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rotations []*models.KeyRotation
+	for rows.Next() {
+		rotation := &models.KeyRotation{}
+		if err := rows.Scan(&rotation.ID, &rotation.Purpose, &rotation.KeyID, &rotation.PreviousKeyID, &rotation.Status,
+			&rotation.Reencrypted, &rotation.Error, &rotation.RequestedBy, &rotation.StartedAt, &rotation.CompletedAt); err != nil {
+			return nil, err
+		}
+		rotations = append(rotations, rotation)
+	}
+	return rotations, rows.Err()
+}
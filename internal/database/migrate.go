@@ -2,27 +2,46 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
-// RunMigrations executes the migration files to setup the database schema
+// RunMigrations executes the migration files to setup the database schema.
+// Every *.up.sql file in the migrations directory is applied in lexical order
+// (001_, 002_, ...). A failing file is reported but does not stop later files,
+// so additive migrations written with IF NOT EXISTS still apply on databases
+// where the initial schema already exists.
 func RunMigrations(db *sql.DB) error {
-	// Read the migration file
-	migrationPath := filepath.Join("migrations", "001_init_schema.up.sql")
-	migrationSQL, err := os.ReadFile(migrationPath)
+	// Collect the migration files
+	files, err := filepath.Glob(filepath.Join("migrations", "*.up.sql"))
 	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+		return fmt.Errorf("failed to list migration files: %w", err)
 	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migration files found")
+	}
+	sort.Strings(files)
 
-	// Execute the migration
-	_, err = db.Exec(string(migrationSQL))
-	if err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+	var errs []error
+	for _, file := range files {
+		migrationSQL, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read migration file %s: %w", file, err))
+			continue
+		}
+
+		// Execute the migration
+		if _, err := db.Exec(string(migrationSQL)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to execute migration %s: %w", filepath.Base(file), err))
+			continue
+		}
+
+		log.Printf("Applied migration %s", filepath.Base(file))
 	}
 
-	log.Println("Database schema created successfully")
-	return nil
-}
\ No newline at end of file
+	return errors.Join(errs...)
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type oauthRepository struct {
+	db *sql.DB
+}
+
+// NewOAuthRepository creates a new PostgreSQL OAuth client and authorization code repository
+func NewOAuthRepository(db *sql.DB) repository.OAuthRepository {
+	return &oauthRepository{db: db}
+}
+
+const oauthClientColumns = `id, client_id, secret_hash, name, redirect_uris, scopes, created_by, created_at`
+
+// CreateClient stores a new OAuth client
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	redirectURIs, err := json.Marshal(client.RedirectURIs)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO oauth_clients (client_id, secret_hash, name, redirect_uris, scopes, created_by, tenant_id)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		client.ClientID,
+		client.SecretHash,
+		client.Name,
+		redirectURIs,
+		strings.Join(client.Scopes, " "),
+		client.CreatedBy,
+		tenantID(ctx),
+	).Scan(&client.ID, &client.CreatedAt)
+}
+
+// FindClientByClientID returns the client with the given client_id, or nil
+func (r *oauthRepository) FindClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+oauthClientColumns+` FROM oauth_clients WHERE client_id = $1 AND tenant_id = $2`, clientID, tenantID(ctx))
+	return scanOAuthClient(row)
+}
+
+// ListClients returns every client, newest first
+func (r *oauthRepository) ListClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+oauthClientColumns+` FROM oauth_clients WHERE tenant_id = $1 ORDER BY created_at DESC`, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []*models.OAuthClient
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, rows.Err()
+}
+
+// DeleteClient removes a client with its codes and refresh tokens. It
+// returns sql.ErrNoRows if the client doesn't exist.
+func (r *oauthRepository) DeleteClient(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_clients WHERE id = $1 AND tenant_id = $2`, id, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CreateCode stores a new authorization code
+func (r *oauthRepository) CreateCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	query := `
+		INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		code.CodeHash,
+		code.ClientID,
+		code.UserID,
+		code.RedirectURI,
+		code.Scope,
+		code.Nonce,
+		code.CodeChallenge,
+		code.ExpiresAt,
+	).Scan(&code.ID, &code.CreatedAt)
+}
+
+// ConsumeCode marks an unused, unexpired code as used and returns it, or
+// nil if there is no such code
+func (r *oauthRepository) ConsumeCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	query := `
+		UPDATE oauth_authorization_codes SET used_at = NOW()
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, expires_at, used_at, created_at`
+
+	code := &models.OAuthAuthorizationCode{}
+	var usedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, query, codeHash).Scan(
+		&code.ID,
+		&code.CodeHash,
+		&code.ClientID,
+		&code.UserID,
+		&code.RedirectURI,
+		&code.Scope,
+		&code.Nonce,
+		&code.CodeChallenge,
+		&code.ExpiresAt,
+		&usedAt,
+		&code.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		code.UsedAt = &usedAt.Time
+	}
+
+	return code, nil
+}
+
+// scanOAuthClient scans one client row, returning nil, nil for sql.ErrNoRows.
+func scanOAuthClient(row rowScanner) (*models.OAuthClient, error) {
+	client := &models.OAuthClient{}
+	var secretHash sql.NullString
+	var createdBy sql.NullInt64
+	var redirectURIs []byte
+	var scopes string
+
+	err := row.Scan(
+		&client.ID,
+		&client.ClientID,
+		&secretHash,
+		&client.Name,
+		&redirectURIs,
+		&scopes,
+		&createdBy,
+		&client.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(redirectURIs, &client.RedirectURIs); err != nil {
+		return nil, err
+	}
+	client.Scopes = strings.Fields(scopes)
+	client.SecretHash = secretHash.String
+	if createdBy.Valid {
+		client.CreatedBy = &createdBy.Int64
+	}
+
+	return client, nil
+}
+
+const oauthGrantColumns = `g.id, g.user_id, g.client_id, c.client_id, c.name, g.scope, g.created_at, g.updated_at`
+
+// SaveGrant creates or replaces the scopes userID approved for a client
+func (r *oauthRepository) SaveGrant(ctx context.Context, grant *models.OAuthGrant) error {
+	query := `
+		INSERT INTO oauth_grants (user_id, client_id, scope)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scope = EXCLUDED.scope, updated_at = NOW()
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		grant.UserID,
+		grant.OAuthClientID,
+		strings.Join(grant.Scopes, " "),
+	).Scan(&grant.ID, &grant.CreatedAt, &grant.UpdatedAt)
+}
+
+// FindGrant returns userID's grant for a client (oauth_clients.id), or nil
+func (r *oauthRepository) FindGrant(ctx context.Context, userID, clientID int64) (*models.OAuthGrant, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+oauthGrantColumns+`
+		FROM oauth_grants g JOIN oauth_clients c ON c.id = g.client_id
+		WHERE g.user_id = $1 AND g.client_id = $2 AND c.tenant_id = $3`, userID, clientID, tenantID(ctx))
+	return scanOAuthGrant(row)
+}
+
+// ListGrants returns userID's grants with client details, most recent first
+func (r *oauthRepository) ListGrants(ctx context.Context, userID int64) ([]*models.OAuthGrant, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+oauthGrantColumns+`
+		FROM oauth_grants g JOIN oauth_clients c ON c.id = g.client_id
+		WHERE g.user_id = $1 AND c.tenant_id = $2
+		ORDER BY g.updated_at DESC`, userID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []*models.OAuthGrant
+	for rows.Next() {
+		grant, err := scanOAuthGrant(rows)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+// RevokeGrant deletes userID's grant for a client along with the refresh
+// tokens and unused codes issued to it. It returns sql.ErrNoRows if there
+// is no such grant.
+func (r *oauthRepository) RevokeGrant(ctx context.Context, userID, clientID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM oauth_grants WHERE user_id = $1 AND client_id = $2`, userID, clientID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM refresh_tokens WHERE user_id = $1 AND oauth_client_id = $2`, userID, clientID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM oauth_authorization_codes WHERE user_id = $1 AND client_id = $2 AND used_at IS NULL`, userID, clientID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// scanOAuthGrant scans one grant row, returning nil, nil for sql.ErrNoRows.
+func scanOAuthGrant(row rowScanner) (*models.OAuthGrant, error) {
+	grant := &models.OAuthGrant{}
+	var scope string
+
+	err := row.Scan(
+		&grant.ID,
+		&grant.UserID,
+		&grant.OAuthClientID,
+		&grant.ClientID,
+		&grant.ClientName,
+		&scope,
+		&grant.CreatedAt,
+		&grant.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	grant.Scopes = strings.Fields(scope)
+	return grant, nil
+}
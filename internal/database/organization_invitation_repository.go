@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type organizationInvitationRepository struct {
+	db *sql.DB
+}
+
+// NewOrganizationInvitationRepository creates a new PostgreSQL organization invitation repository
+func NewOrganizationInvitationRepository(db *sql.DB) repository.OrganizationInvitationRepository {
+	return &organizationInvitationRepository{db: db}
+}
+
+const organizationInvitationColumns = `id, organization_id, email, role, token_hash, invited_by, accepted_by, expires_at, accepted_at, revoked_at, created_at`
+
+// inTenantOrganization limits organization invitations to organizations of the
+// tenant bound to $2, so invite tokens can't be redeemed from another tenant.
+const inTenantOrganization = `organization_id IN (SELECT id FROM organizations WHERE tenant_id = $2)`
+
+// Create stores a new organization invitation
+func (r *organizationInvitationRepository) Create(ctx context.Context, invitation *models.OrganizationInvitation) error {
+	query := `
+		INSERT INTO organization_invitations (organization_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		invitation.OrganizationID,
+		invitation.Email,
+		invitation.Role,
+		invitation.TokenHash,
+		invitation.InvitedBy,
+		invitation.ExpiresAt,
+	).Scan(&invitation.ID, &invitation.CreatedAt)
+}
+
+// FindByID returns an invitation by ID, or nil if it doesn't exist
+func (r *organizationInvitationRepository) FindByID(ctx context.Context, id int64) (*models.OrganizationInvitation, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+organizationInvitationColumns+` FROM organization_invitations WHERE id = $1 AND `+inTenantOrganization,
+		id, tenantID(ctx))
+	return scanOrganizationInvitation(row)
+}
+
+// FindByTokenHash returns the invitation with the given token hash, or nil
+func (r *organizationInvitationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*models.OrganizationInvitation, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+organizationInvitationColumns+` FROM organization_invitations WHERE token_hash = $1 AND `+inTenantOrganization,
+		tokenHash, tenantID(ctx))
+	return scanOrganizationInvitation(row)
+}
+
+// ListByOrganization returns an organization's invitations, newest first
+func (r *organizationInvitationRepository) ListByOrganization(ctx context.Context, orgID int64) ([]*models.OrganizationInvitation, error) {
+	query := `
+		SELECT ` + organizationInvitationColumns + `
+		FROM organization_invitations
+		WHERE organization_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*models.OrganizationInvitation
+	for rows.Next() {
+		invitation, err := scanOrganizationInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+// Revoke marks a pending invitation as revoked
+func (r *organizationInvitationRepository) Revoke(ctx context.Context, id int64) error {
+	query := `
+		UPDATE organization_invitations SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND accepted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// MarkAccepted records the user who joined with a pending invitation. It
+// returns sql.ErrNoRows if the invitation is no longer pending.
+func (r *organizationInvitationRepository) MarkAccepted(ctx context.Context, id, userID int64) error {
+	query := `
+		UPDATE organization_invitations SET accepted_by = $2, accepted_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND accepted_at IS NULL AND expires_at > NOW()`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// scanOrganizationInvitation scans one invitation row, returning nil, nil for sql.ErrNoRows.
+func scanOrganizationInvitation(row rowScanner) (*models.OrganizationInvitation, error) {
+	invitation := &models.OrganizationInvitation{}
+	var invitedBy, acceptedBy sql.NullInt64
+	var acceptedAt, revokedAt sql.NullTime
+
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.OrganizationID,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.TokenHash,
+		&invitedBy,
+		&acceptedBy,
+		&invitation.ExpiresAt,
+		&acceptedAt,
+		&revokedAt,
+		&invitation.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if invitedBy.Valid {
+		invitation.InvitedBy = &invitedBy.Int64
+	}
+	if acceptedBy.Valid {
+		invitation.AcceptedBy = &acceptedBy.Int64
+	}
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = &acceptedAt.Time
+	}
+	if revokedAt.Valid {
+		invitation.RevokedAt = &revokedAt.Time
+	}
+
+	return invitation, nil
+}
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type organizationRepository struct {
+	db *sql.DB
+}
+
+// NewOrganizationRepository creates a new PostgreSQL organization repository
+func NewOrganizationRepository(db *sql.DB) repository.OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+const organizationColumns = `o.id, o.name, o.slug, o.created_by, o.created_at, o.updated_at`
+
+const organizationMemberColumns = `m.organization_id, m.user_id, u.first_name, u.last_name, u.email, m.role, m.created_at`
+
+// Create stores a new organization and adds ownerID as its owner in a single transaction
+func (r *organizationRepository) Create(ctx context.Context, org *models.Organization, ownerID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO organizations (name, slug, created_by, tenant_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`,
+		org.Name, org.Slug, org.CreatedBy, tenantID(ctx),
+	).Scan(&org.ID, &org.CreatedAt, &org.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`,
+		org.ID, ownerID, constants.OrgRoleOwner,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindByID returns an organization by ID, or nil if it doesn't exist
+func (r *organizationRepository) FindByID(ctx context.Context, id int64) (*models.Organization, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+organizationColumns+` FROM organizations o WHERE o.id = $1 AND o.tenant_id = $2`, id, tenantID(ctx))
+	return scanOrganization(row)
+}
+
+// FindBySlug returns an organization by slug, or nil if it doesn't exist
+func (r *organizationRepository) FindBySlug(ctx context.Context, slug string) (*models.Organization, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+organizationColumns+` FROM organizations o WHERE o.slug = $1 AND o.tenant_id = $2`, slug, tenantID(ctx))
+	return scanOrganization(row)
+}
+
+// ListByUser returns the organizations userID belongs to, with Role set, by name
+func (r *organizationRepository) ListByUser(ctx context.Context, userID int64) ([]*models.Organization, error) {
+	query := `
+		SELECT ` + organizationColumns + `, m.role
+		FROM organizations o
+		JOIN organization_members m ON m.organization_id = o.id
+		WHERE m.user_id = $1 AND o.tenant_id = $2
+		ORDER BY o.name`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []*models.Organization
+	for rows.Next() {
+		org := &models.Organization{}
+		var createdBy sql.NullInt64
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &createdBy, &org.CreatedAt, &org.UpdatedAt, &org.Role); err != nil {
+			return nil, err
+		}
+		if createdBy.Valid {
+			org.CreatedBy = &createdBy.Int64
+		}
+		orgs = append(orgs, org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// FindMember returns userID's membership in orgID, or nil if not a member
+func (r *organizationRepository) FindMember(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	query := `
+		SELECT ` + organizationMemberColumns + `
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1 AND m.user_id = $2`
+
+	member, err := scanOrganizationMember(r.db.QueryRowContext(ctx, query, orgID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return member, err
+}
+
+// ListMembers returns the members of orgID in the order they joined
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID int64) ([]*models.OrganizationMember, error) {
+	query := `
+		SELECT ` + organizationMemberColumns + `
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.organization_id = $1
+		ORDER BY m.created_at, m.user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*models.OrganizationMember
+	for rows.Next() {
+		member, err := scanOrganizationMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// AddMember adds userID to orgID with role; existing members are left unchanged
+func (r *organizationRepository) AddMember(ctx context.Context, orgID, userID int64, role string) error {
+	query := `
+		INSERT INTO organization_members (organization_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO NOTHING`
+	_, err := r.db.ExecContext(ctx, query, orgID, userID, role)
+	return err
+}
+
+// UpdateMemberRole changes userID's role in orgID
+func (r *organizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error {
+	query := `UPDATE organization_members SET role = $3 WHERE organization_id = $1 AND user_id = $2`
+	_, err := r.db.ExecContext(ctx, query, orgID, userID, role)
+	return err
+}
+
+// RemoveMember removes userID from orgID
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	query := `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`
+	_, err := r.db.ExecContext(ctx, query, orgID, userID)
+	return err
+}
+
+// CountMembersWithRole returns how many members of orgID have role
+func (r *organizationRepository) CountMembersWithRole(ctx context.Context, orgID int64, role string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = $2`, orgID, role,
+	).Scan(&count)
+	return count, err
+}
+
+// scanOrganization scans one organization row, returning nil, nil for sql.ErrNoRows.
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	org := &models.Organization{}
+	var createdBy sql.NullInt64
+
+	err := row.Scan(&org.ID, &org.Name, &org.Slug, &createdBy, &org.CreatedAt, &org.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if createdBy.Valid {
+		org.CreatedBy = &createdBy.Int64
+	}
+	return org, nil
+}
+
+// scanOrganizationMember scans one membership row joined with its user.
+func scanOrganizationMember(row rowScanner) (*models.OrganizationMember, error) {
+	member := &models.OrganizationMember{}
+	err := row.Scan(
+		&member.OrganizationID,
+		&member.UserID,
+		&member.FirstName,
+		&member.LastName,
+		&member.Email,
+		&member.Role,
+		&member.JoinedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
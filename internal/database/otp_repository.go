@@ -3,9 +3,11 @@ package database
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 	"authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/pkg/otp"
 )
 
 type otpRepository struct {
@@ -16,50 +18,112 @@ func NewOTPRepository(db *sql.DB) repository.OTPRepository {
 	return &otpRepository{db: db}
 }
 
+// CreateOTP stores a code and expires the email's earlier unused codes of the
+// same type in one transaction, so only the latest code is accepted. The
+// caller sets ExpiredAt from the type's policy.
 func (r *otpRepository) CreateOTP(ctx context.Context, otp *models.OTP) error {
-	// Set expiration to 10 minutes
-	expiredAt := time.Now().Add(10 * time.Minute)
-	otp.ExpiredAt = &expiredAt
+	if otp.ExpiredAt == nil {
+		return fmt.Errorf("otp expiry not set")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, `
+		UPDATE otps
+		SET expires_at = $1
+		WHERE email = $2 AND type = $3 AND tenant_id = $4
+		AND used = FALSE AND expires_at > $1`,
+		now,
+		otp.Email,
+		otp.Type,
+		tenantID(ctx),
+	)
+	if err != nil {
+		return err
+	}
 
 	query := `
-		INSERT INTO otps (user_id, email, code, type, expires_at) 
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO otps (user_id, email, code, type, expires_at, tenant_id) 
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at`
-	
-	err := r.db.QueryRowContext(ctx, query,
+
+	err = tx.QueryRowContext(ctx, query,
 		otp.UserID,
 		otp.Email,
 		otp.Code,
 		otp.Type,
 		otp.ExpiredAt,
+		tenantID(ctx),
 	).Scan(&otp.ID, &otp.CreatedAt)
-	
-	return err
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
+// VerifyOTP checks code against the email's unused, unexpired codes of
+// otpType and marks the matching one as used. Codes are compared in Go in
+// constant time rather than in the WHERE clause, so timing doesn't reveal
+// partial matches.
 func (r *otpRepository) VerifyOTP(ctx context.Context, email, code, otpType string) (bool, error) {
 	query := `
-		UPDATE otps 
-		SET used = TRUE 
-		WHERE email = $1 AND code = $2 AND type = $3 
-		AND used = FALSE AND expires_at > $4
-		RETURNING id`
-	
-	var id int64
-	err := r.db.QueryRowContext(ctx, query, email, code, otpType, time.Now()).Scan(&id)
-	
-	if err == sql.ErrNoRows {
+		SELECT id, code FROM otps
+		WHERE email = $1 AND type = $2 AND tenant_id = $4
+		AND used = FALSE AND expires_at > $3`
+
+	rows, err := r.db.QueryContext(ctx, query, email, otpType, time.Now(), tenantID(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var matchID int64
+	for rows.Next() {
+		var id int64
+		var issued string
+		if err := rows.Scan(&id, &issued); err != nil {
+			return false, err
+		}
+		// Keep comparing after a match so the loop's duration doesn't depend on it
+		if otp.Equal(issued, code) && matchID == 0 {
+			matchID = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if matchID == 0 {
 		return false, nil // Code not found or expired
 	}
+
+	// The used = FALSE guard makes a concurrent verification of the same code fail
+	result, err := r.db.ExecContext(ctx, `UPDATE otps SET used = TRUE WHERE id = $1 AND used = FALSE`, matchID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
 	if err != nil {
 		return false, err
 	}
-	
-	return true, nil
+
+	return n == 1, nil
 }
 
-func (r *otpRepository) CleanupExpiredOTPs(ctx context.Context) error {
-	query := `DELETE FROM otps WHERE expires_at < $1`
-	_, err := r.db.ExecContext(ctx, query, time.Now())
-	return err
+// PurgeOTPs deletes up to limit codes that expired before before, used or
+// not, and returns how many it deleted
+func (r *otpRepository) PurgeOTPs(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM otps
+		WHERE id IN (SELECT id FROM otps WHERE expires_at < $1 LIMIT $2)`
+	result, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
\ No newline at end of file
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"fmt"
+
+	"authentio/pkg/pagination"
+)
+
+// afterCursor returns the condition selecting the rows of a list ordered by
+// (created_at, id) descending that come after cursor, adding its parameters
+// to args. The first page (nil cursor) selects every row.
+func afterCursor(after *pagination.Cursor, args []interface{}) (string, []interface{}) {
+	if after == nil {
+		return "TRUE", args
+	}
+	args = append(args, after.CreatedAt, after.ID)
+	return fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)), args
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"authentio/pkg/secretbox"
+)
+
+// PIIEncryption encrypts personal data columns (phone numbers, push device
+// tokens) in the repositories. Encrypted columns are paired with a hash
+// column holding a blind index of the value, used for lookups. A nil
+// *PIIEncryption stores values unencrypted.
+type PIIEncryption struct {
+	keys    *secretbox.Keyring
+	index   *secretbox.Index
+	enabled bool
+}
+
+// NewPIIEncryption creates the encryption of personal data columns. Values
+// are encrypted with keys when enabled; values encrypted earlier are still
+// decrypted with keys when it isn't.
+func NewPIIEncryption(keys *secretbox.Keyring, index *secretbox.Index, enabled bool) *PIIEncryption {
+	return &PIIEncryption{keys: keys, index: index, enabled: enabled && keys != nil && index != nil}
+}
+
+// reencryptBatchSize is how many values re-encryption reads and updates at a
+// time.
+const reencryptBatchSize = 500
+
+// piiColumn is an encrypted column of a table with a user_id column.
+type piiColumn struct {
+	table  string
+	column string
+	hash   string // column holding the blind index
+	key    string // primary key column
+}
+
+var (
+	piiPhoneNumber = piiColumn{table: "user_preferences", column: "phone_number", hash: "phone_number_hash", key: "user_id"}
+	piiPushToken   = piiColumn{table: "push_devices", column: "token", hash: "token_hash", key: "id"}
+)
+
+// piiColumns lists every encrypted column.
+var piiColumns = []piiColumn{piiPhoneNumber, piiPushToken}
+
+// seal returns the value to store in col for the user's row: value itself
+// when encryption is off
+func (p *PIIEncryption) seal(col piiColumn, userID int64, value string) (string, error) {
+	if p == nil || !p.enabled || value == "" {
+		return value, nil
+	}
+	return p.keys.SealString(value, col.context(userID))
+}
+
+// open decrypts a value read from col for the user's row. Unencrypted values
+// are returned as is.
+func (p *PIIEncryption) open(col piiColumn, userID int64, stored string) (string, error) {
+	if !secretbox.IsSealedString(stored) {
+		return stored, nil
+	}
+	if p == nil || p.keys == nil {
+		return "", fmt.Errorf("%s.%s is encrypted but no key is configured: %w", col.table, col.column, secretbox.ErrUnknownKey)
+	}
+	return p.keys.OpenString(stored, col.context(userID))
+}
+
+// hash returns the blind index of value for col, or NULL when encryption is
+// off or value is empty
+func (p *PIIEncryption) hash(col piiColumn, value string) sql.NullString {
+	if p == nil || !p.enabled || value == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: p.index.Hash(col.table+"."+col.column, value), Valid: true}
+}
+
+// context binds an encrypted value to its user, so a value copied to
+// another user's row doesn't decrypt
+func (col piiColumn) context(userID int64) []byte {
+	return []byte(col.table + "." + col.column + ":" + strconv.FormatInt(userID, 10))
+}
+
+// ReencryptPII encrypts the personal data stored unencrypted and re-wraps
+// the values sealed under a retired master key with the current one. It
+// does nothing when encryption is off, and returns how many values were
+// updated; values wrapped by keys missing from the keyring are skipped and
+// reported in the error.
+func ReencryptPII(ctx context.Context, db *sql.DB, pii *PIIEncryption) (int, error) {
+	if pii == nil || !pii.enabled {
+		return 0, nil
+	}
+
+	updated, unknown := 0, 0
+	for _, col := range piiColumns {
+		n, skipped, err := pii.reencryptColumn(ctx, db, col)
+		updated += n
+		unknown += skipped
+		if err != nil {
+			return updated, fmt.Errorf("%s.%s: %w", col.table, col.column, err)
+		}
+	}
+
+	if unknown > 0 {
+		return updated, fmt.Errorf("%d values are wrapped by master keys missing from the keyring: %w", unknown, secretbox.ErrUnknownKey)
+	}
+	return updated, nil
+}
+
+// reencryptColumn encrypts or re-wraps the values of col not sealed under
+// the current key, in batches. It returns how many were updated and skipped.
+func (p *PIIEncryption) reencryptColumn(ctx context.Context, db *sql.DB, col piiColumn) (int, int, error) {
+	prefix := secretbox.FieldPrefix(p.keys.CurrentKeyID())
+	updated, unknown := 0, 0
+	var after int64
+	for {
+		values, err := p.pendingValues(ctx, db, col, prefix, after)
+		if err != nil || len(values) == 0 {
+			return updated, unknown, err
+		}
+		after = values[len(values)-1].key
+
+		for _, v := range values {
+			var sealed string
+			hash := sql.NullString{}
+			if secretbox.IsSealedString(v.stored) {
+				sealed, err = p.keys.RewrapString(v.stored)
+				if errors.Is(err, secretbox.ErrUnknownKey) {
+					unknown++
+					continue
+				}
+			} else {
+				sealed, err = p.seal(col, v.userID, v.stored)
+				hash = p.hash(col, v.stored)
+			}
+			if err != nil {
+				return updated, unknown, fmt.Errorf("user %d: %w", v.userID, err)
+			}
+
+			// Skipped if the value changed since it was read; re-wrapping keeps
+			// the hash
+			result, err := db.ExecContext(ctx, fmt.Sprintf(`
+				UPDATE %[1]s SET %[2]s = $3, %[3]s = COALESCE($4, %[3]s)
+				WHERE %[4]s = $1 AND %[2]s = $2`, col.table, col.column, col.hash, col.key),
+				v.key, v.stored, sealed, hash)
+			if err != nil {
+				return updated, unknown, err
+			}
+			if n, err := result.RowsAffected(); err == nil {
+				updated += int(n)
+			}
+		}
+
+		if len(values) < reencryptBatchSize {
+			return updated, unknown, nil
+		}
+	}
+}
+
+// pendingValue is a value of an encrypted column to re-encrypt.
+type pendingValue struct {
+	key    int64 // primary key of the row
+	userID int64
+	stored string
+}
+
+// pendingValues returns the next batch of values of col after the given row
+// that aren't sealed under the key of prefix.
+func (p *PIIEncryption) pendingValues(ctx context.Context, db *sql.DB, col piiColumn, prefix string, after int64) ([]pendingValue, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %[3]s, user_id, %[2]s FROM %[1]s
+		WHERE %[2]s <> '' AND LEFT(%[2]s, $2) <> $1 AND %[3]s > $3
+		ORDER BY %[3]s
+		LIMIT $4`, col.table, col.column, col.key),
+		prefix, len(prefix), after, reencryptBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []pendingValue
+	for rows.Next() {
+		var v pendingValue
+		if err := rows.Scan(&v.key, &v.userID, &v.stored); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type preferencesRepository struct {
+	db  *sql.DB
+	pii *PIIEncryption
+}
+
+// NewPreferencesRepository creates a new PostgreSQL notification preferences
+// repository. Phone numbers are encrypted with pii.
+func NewPreferencesRepository(db *sql.DB, pii *PIIEncryption) repository.PreferencesRepository {
+	return &preferencesRepository{db: db, pii: pii}
+}
+
+// FindByUserID returns the user's saved preferences, or nil when there is no row.
+func (r *preferencesRepository) FindByUserID(ctx context.Context, userID int64) (*models.NotificationPreferences, error) {
+	query := `
+		SELECT p.user_id, p.security_alert_emails, p.marketing_emails, p.login_notifications,
+		       COALESCE(p.phone_number, ''), p.security_alert_sms, p.security_alert_push,
+		       p.login_notification_sms, p.login_notification_push,
+		       p.new_location_alerts, p.password_expiry_reminders, p.updated_at
+		FROM user_preferences p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.user_id = $1 AND u.tenant_id = $2`
+
+	prefs := &models.NotificationPreferences{}
+	err := r.db.QueryRowContext(ctx, query, userID, tenantID(ctx)).Scan(
+		&prefs.UserID,
+		&prefs.SecurityAlertEmails,
+		&prefs.MarketingEmails,
+		&prefs.LoginNotifications,
+		&prefs.PhoneNumber,
+		&prefs.SecurityAlertSMS,
+		&prefs.SecurityAlertPush,
+		&prefs.LoginNotificationSMS,
+		&prefs.LoginNotificationPush,
+		&prefs.NewLocationAlerts,
+		&prefs.PasswordExpiryReminders,
+		&prefs.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prefs.PhoneNumber, err = r.pii.open(piiPhoneNumber, prefs.UserID, prefs.PhoneNumber); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+// Save upserts the user's preferences. It returns sql.ErrNoRows when the user
+// does not exist in the current tenant.
+func (r *preferencesRepository) Save(ctx context.Context, prefs *models.NotificationPreferences) error {
+	phoneNumber, err := r.pii.seal(piiPhoneNumber, prefs.UserID, prefs.PhoneNumber)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_preferences (user_id, security_alert_emails, marketing_emails, login_notifications,
+		                              phone_number, security_alert_sms, security_alert_push,
+		                              login_notification_sms, login_notification_push,
+		                              new_location_alerts, password_expiry_reminders, phone_number_hash)
+		SELECT id, $2, $3, $4, NULLIF($6, ''), $7, $8, $9, $10, $11, $12, $13 FROM users
+		WHERE id = $1 AND tenant_id = $5 AND deleted_at IS NULL
+		ON CONFLICT (user_id) DO UPDATE
+		SET security_alert_emails = EXCLUDED.security_alert_emails,
+		    marketing_emails = EXCLUDED.marketing_emails,
+		    login_notifications = EXCLUDED.login_notifications,
+		    phone_number = EXCLUDED.phone_number,
+		    phone_number_hash = EXCLUDED.phone_number_hash,
+		    security_alert_sms = EXCLUDED.security_alert_sms,
+		    security_alert_push = EXCLUDED.security_alert_push,
+		    login_notification_sms = EXCLUDED.login_notification_sms,
+		    login_notification_push = EXCLUDED.login_notification_push,
+		    new_location_alerts = EXCLUDED.new_location_alerts,
+		    password_expiry_reminders = EXCLUDED.password_expiry_reminders,
+		    updated_at = NOW()
+		RETURNING updated_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		prefs.UserID,
+		prefs.SecurityAlertEmails,
+		prefs.MarketingEmails,
+		prefs.LoginNotifications,
+		tenantID(ctx),
+		phoneNumber,
+		prefs.SecurityAlertSMS,
+		prefs.SecurityAlertPush,
+		prefs.LoginNotificationSMS,
+		prefs.LoginNotificationPush,
+		prefs.NewLocationAlerts,
+		prefs.PasswordExpiryReminders,
+		r.pii.hash(piiPhoneNumber, prefs.PhoneNumber),
+	).Scan(&prefs.UpdatedAt)
+}
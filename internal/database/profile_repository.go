@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type profileRepository struct {
+	db *sql.DB
+}
+
+// NewProfileRepository creates a new PostgreSQL user profile repository
+func NewProfileRepository(db *sql.DB) repository.ProfileRepository {
+	return &profileRepository{db: db}
+}
+
+// FindByUserID returns a user's profile. Names and the avatar URL live on
+// users, so the profile row is optional.
+func (r *profileRepository) FindByUserID(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	query := `
+		SELECT p.id, u.id, u.first_name, u.last_name, COALESCE(p.display_name, ''), p.bio, u.avatar_url,
+		       COALESCE(p.created_at, u.created_at), COALESCE(p.updated_at, u.updated_at)
+		FROM users u
+		LEFT JOIN user_profiles p ON p.user_id = u.id
+		WHERE u.id = $1 AND u.tenant_id = $2 AND u.deleted_at IS NULL`
+
+	profile := &models.UserProfile{}
+	var id sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, userID, tenantID(ctx)).Scan(
+		&id,
+		&profile.UserID,
+		&profile.FirstName,
+		&profile.LastName,
+		&profile.DisplayName,
+		&profile.Bio,
+		&profile.AvatarURL,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile.ID = id.Int64
+
+	return profile, nil
+}
+
+// Save updates the user's names and avatar URL and upserts the profile row in
+// a single transaction.
+func (r *profileRepository) Save(ctx context.Context, profile *models.UserProfile) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET first_name = $1, last_name = $2, avatar_url = $3, updated_at = NOW()
+		WHERE id = $4 AND tenant_id = $5 AND deleted_at IS NULL`,
+		profile.FirstName,
+		profile.LastName,
+		profile.AvatarURL,
+		profile.UserID,
+		tenantID(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO user_profiles (user_id, display_name, bio)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET display_name = EXCLUDED.display_name, bio = EXCLUDED.bio, updated_at = NOW()
+		RETURNING id, created_at, updated_at`,
+		profile.UserID,
+		profile.DisplayName,
+		profile.Bio,
+	).Scan(&profile.ID, &profile.CreatedAt, &profile.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
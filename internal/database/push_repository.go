@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type pushRepository struct {
+	db  *sql.DB
+	pii *PIIEncryption
+}
+
+// NewPushRepository creates a new PostgreSQL push approval repository. Device
+// tokens are encrypted with pii.
+func NewPushRepository(db *sql.DB, pii *PIIEncryption) repository.PushRepository {
+	return &pushRepository{db: db, pii: pii}
+}
+
+// pushChallengeColumns lists the push_challenges columns in scanPushChallenge order
+const pushChallengeColumns = `c.id, c.challenge_id, c.user_id, c.method, c.login_method, c.status,
+	COALESCE(c.ip_address, ''), COALESCE(c.user_agent, ''), c.expires_at, c.decided_at, c.created_at,
+	COALESCE(c.approval_token_hash, '')`
+
+// SaveDevice inserts a device, or updates the name and platform of the user's
+// device with the same token.
+func (r *pushRepository) SaveDevice(ctx context.Context, device *models.PushDevice) error {
+	token, err := r.pii.seal(piiPushToken, device.UserID, device.Token)
+	if err != nil {
+		return err
+	}
+	// Encrypted tokens are matched by their hash
+	hash := r.pii.hash(piiPushToken, device.Token)
+	conflict := "(user_id, token)"
+	if hash.Valid {
+		conflict = "(user_id, token_hash)"
+	}
+
+	query := `
+		INSERT INTO push_devices (user_id, platform, token, name, token_hash)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ` + conflict + ` DO UPDATE
+		SET platform = EXCLUDED.platform, name = EXCLUDED.name
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		device.UserID,
+		device.Platform,
+		token,
+		device.Name,
+		hash,
+	).Scan(&device.ID, &device.CreatedAt)
+}
+
+// ListDevices returns the user's devices, oldest first.
+func (r *pushRepository) ListDevices(ctx context.Context, userID int64) ([]models.PushDevice, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT d.id, d.user_id, d.platform, d.token, d.name, d.created_at
+		FROM push_devices d
+		JOIN users u ON u.id = d.user_id
+		WHERE d.user_id = $1 AND u.tenant_id = $2
+		ORDER BY d.created_at, d.id`,
+		userID, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []models.PushDevice
+	for rows.Next() {
+		var d models.PushDevice
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.Name, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		token, err := r.pii.open(piiPushToken, d.UserID, d.Token)
+		if err != nil {
+			return nil, err
+		}
+		d.Token = token
+		devices = append(devices, d)
+	}
+
+	return devices, rows.Err()
+}
+
+// DeleteDevice removes one of the user's devices. It returns sql.ErrNoRows if
+// the device doesn't exist.
+func (r *pushRepository) DeleteDevice(ctx context.Context, userID, deviceID int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM push_devices WHERE id = $1 AND user_id = $2`, deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteDeviceToken removes the user's device registered with token.
+func (r *pushRepository) DeleteDeviceToken(ctx context.Context, userID int64, token string) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM push_devices WHERE user_id = $1 AND (token = $2 OR token_hash = $3)`,
+		userID, token, r.pii.hash(piiPushToken, token))
+	return err
+}
+
+// CreateChallenge stores a pending challenge.
+func (r *pushRepository) CreateChallenge(ctx context.Context, challenge *models.PushChallenge) error {
+	query := `
+		INSERT INTO push_challenges (challenge_id, user_id, method, login_method, status, ip_address, user_agent, expires_at, approval_token_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		challenge.ChallengeID,
+		challenge.UserID,
+		challenge.Method,
+		challenge.LoginMethod,
+		challenge.Status,
+		nullString(challenge.IPAddress),
+		nullString(challenge.UserAgent),
+		challenge.ExpiresAt,
+		nullString(challenge.ApprovalTokenHash),
+	).Scan(&challenge.ID, &challenge.CreatedAt)
+}
+
+// FindChallenge returns the challenge with the given public identifier in the
+// current tenant, or nil.
+func (r *pushRepository) FindChallenge(ctx context.Context, challengeID string) (*models.PushChallenge, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+pushChallengeColumns+`
+		FROM push_challenges c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.challenge_id = $1 AND u.tenant_id = $2`,
+		challengeID, tenantID(ctx))
+
+	challenge, err := scanPushChallenge(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return challenge, err
+}
+
+// FindChallengeByApprovalToken returns the quarantined login whose approval
+// link carries the token with the given hash, in the current tenant, or nil.
+func (r *pushRepository) FindChallengeByApprovalToken(ctx context.Context, tokenHash string) (*models.PushChallenge, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+pushChallengeColumns+`
+		FROM push_challenges c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.approval_token_hash = $1 AND u.tenant_id = $2`,
+		tokenHash, tenantID(ctx))
+
+	challenge, err := scanPushChallenge(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return challenge, err
+}
+
+// ListPendingChallenges returns the user's unexpired pending push challenges,
+// newest first. Email challenges are answered with a code instead.
+func (r *pushRepository) ListPendingChallenges(ctx context.Context, userID int64) ([]models.PushChallenge, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+pushChallengeColumns+`
+		FROM push_challenges c
+		WHERE c.user_id = $1 AND c.method = $2 AND c.status = $3 AND c.expires_at > NOW()
+		ORDER BY c.created_at DESC`,
+		userID, constants.TwoFAMethodPush, constants.PushChallengePending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var challenges []models.PushChallenge
+	for rows.Next() {
+		challenge, err := scanPushChallenge(rows)
+		if err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, *challenge)
+	}
+
+	return challenges, rows.Err()
+}
+
+// DecideChallenge sets the status of the user's unexpired pending challenge.
+// It returns sql.ErrNoRows if there is none.
+func (r *pushRepository) DecideChallenge(ctx context.Context, userID int64, challengeID, status string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE push_challenges
+		SET status = $1, decided_at = NOW()
+		WHERE challenge_id = $2 AND user_id = $3 AND status = $4 AND expires_at > NOW()`,
+		status, challengeID, userID, constants.PushChallengePending)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CompleteChallenge moves an approved challenge to completed. The status
+// guard makes sure a challenge is exchanged for tokens only once.
+func (r *pushRepository) CompleteChallenge(ctx context.Context, challengeID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE push_challenges SET status = $1
+		WHERE challenge_id = $2 AND status = $3`,
+		constants.PushChallengeCompleted, challengeID, constants.PushChallengeApproved)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// scanPushChallenge scans a row selected with pushChallengeColumns.
+func scanPushChallenge(row rowScanner) (*models.PushChallenge, error) {
+	c := &models.PushChallenge{}
+	err := row.Scan(
+		&c.ID,
+		&c.ChallengeID,
+		&c.UserID,
+		&c.Method,
+		&c.LoginMethod,
+		&c.Status,
+		&c.IPAddress,
+		&c.UserAgent,
+		&c.ExpiresAt,
+		&c.DecidedAt,
+		&c.CreatedAt,
+		&c.ApprovalTokenHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
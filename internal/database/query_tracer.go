@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"time"
+
+	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// packagePrefix starts the function names of this package in stack traces.
+const packagePrefix = "authentio/internal/database."
+
+// QueryTracer times every query made through the connection pool: durations
+// go to the metrics endpoint, named after the repository method making the
+// query, and queries slower than the threshold are logged with their tenant,
+// to point at missing indexes.
+type QueryTracer struct {
+	slowAfter time.Duration
+}
+
+// NewQueryTracer creates a tracer logging queries slower than slowAfter
+// (0 = none). Set it as the Tracer of the pgx connection config.
+func NewQueryTracer(slowAfter time.Duration) *QueryTracer {
+	return &QueryTracer{slowAfter: slowAfter}
+}
+
+type queryStartKey struct{}
+
+// queryStart is what TraceQueryStart passes to TraceQueryEnd.
+type queryStart struct {
+	name string
+	sql  string
+	at   time.Time
+}
+
+// TraceQueryStart implements pgx.QueryTracer.
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryStartKey{}, queryStart{name: queryName(), sql: data.SQL, at: time.Now()})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	took := time.Since(start.at)
+	metrics.ObserveQuery(start.name, took)
+
+	if t.slowAfter <= 0 || took < t.slowAfter {
+		return
+	}
+	tenant := tenantID(ctx)
+	metrics.SlowQuery(start.name, tenant)
+	logger.Warn("slow database query",
+		"query", start.name,
+		"tenant", tenant,
+		"duration", took,
+		"sql", strings.Join(strings.Fields(start.sql), " "),
+		"error", data.Err,
+	)
+}
+
+// queryName names the query being started after the outermost function of
+// this package on the stack, e.g. "userRepository.FindByEmail" rather than
+// the findUser helper it calls.
+func queryName() string {
+	var pcs [48]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+
+	var name string
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, packagePrefix) {
+			name = frame.Function
+		} else if name != "" {
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	if name == "" {
+		return "unknown"
+	}
+
+	name = strings.TrimPrefix(name, packagePrefix)
+	name = strings.NewReplacer("(*", "", ")", "", "[...]", "").Replace(name)
+	// closures are named after the function defining them
+	for {
+		i := strings.LastIndex(name, ".func")
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return name
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+)
+
+type recoveryRepository struct {
+	db *sql.DB
+}
+
+// NewRecoveryRepository creates a new PostgreSQL two-factor recovery repository
+func NewRecoveryRepository(db *sql.DB) repository.RecoveryRepository {
+	return &recoveryRepository{db: db}
+}
+
+// recoveryColumns lists the two_factor_recoveries columns in scanRecovery order
+const recoveryColumns = `r.id, r.user_id, r.token_hash, r.cancel_token_hash, r.status,
+	COALESCE(r.ip_address, ''), COALESCE(r.user_agent, ''), r.available_at, r.completed_at, r.created_at`
+
+// ReplaceCodes deletes the user's recovery codes, used or not, and stores the
+// new ones in a single transaction.
+func (r *recoveryRepository) ReplaceCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseCode marks the user's unused code with the given hash as used. The
+// used_at guard makes sure each code works only once.
+func (r *recoveryRepository) UseCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// CountUnusedCodes returns how many of the user's recovery codes are unused.
+func (r *recoveryRepository) CountUnusedCodes(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM recovery_codes c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.user_id = $1 AND c.used_at IS NULL AND u.tenant_id = $2`,
+		userID, tenantID(ctx)).Scan(&count)
+	return count, err
+}
+
+// CreateRecovery cancels the user's pending recoveries and inserts the new
+// one, so only the latest request can complete.
+func (r *recoveryRepository) CreateRecovery(ctx context.Context, recovery *models.TwoFactorRecovery) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE two_factor_recoveries SET status = $1
+		WHERE user_id = $2 AND status = $3`,
+		constants.RecoveryCancelled, recovery.UserID, constants.RecoveryPending)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO two_factor_recoveries (user_id, token_hash, cancel_token_hash, status, ip_address, user_agent, available_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`
+
+	err = tx.QueryRowContext(ctx, query,
+		recovery.UserID,
+		recovery.TokenHash,
+		recovery.CancelTokenHash,
+		recovery.Status,
+		nullString(recovery.IPAddress),
+		nullString(recovery.UserAgent),
+		recovery.AvailableAt,
+	).Scan(&recovery.ID, &recovery.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindRecoveryByToken returns the recovery with the given token hash in the
+// current tenant, or nil.
+func (r *recoveryRepository) FindRecoveryByToken(ctx context.Context, tokenHash string) (*models.TwoFactorRecovery, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+recoveryColumns+`
+		FROM two_factor_recoveries r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.token_hash = $1 AND u.tenant_id = $2`,
+		tokenHash, tenantID(ctx))
+
+	recovery, err := scanRecovery(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return recovery, err
+}
+
+// CancelRecovery cancels the pending recovery with the given cancel token
+// hash. It returns sql.ErrNoRows if there is none.
+func (r *recoveryRepository) CancelRecovery(ctx context.Context, cancelTokenHash string) (*models.TwoFactorRecovery, error) {
+	row := r.db.QueryRowContext(ctx, `
+		UPDATE two_factor_recoveries r SET status = $1
+		FROM users u
+		WHERE u.id = r.user_id AND r.cancel_token_hash = $2 AND r.status = $3 AND u.tenant_id = $4
+		RETURNING `+recoveryColumns,
+		constants.RecoveryCancelled, cancelTokenHash, constants.RecoveryPending, tenantID(ctx))
+
+	return scanRecovery(row)
+}
+
+// CompleteRecovery moves a pending recovery to completed. The status guard
+// makes sure a recovery is used only once.
+func (r *recoveryRepository) CompleteRecovery(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE two_factor_recoveries SET status = $1, completed_at = NOW()
+		WHERE id = $2 AND status = $3`,
+		constants.RecoveryCompleted, id, constants.RecoveryPending)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// scanRecovery scans a row selected with recoveryColumns.
+func scanRecovery(row rowScanner) (*models.TwoFactorRecovery, error) {
+	rec := &models.TwoFactorRecovery{}
+	err := row.Scan(
+		&rec.ID,
+		&rec.UserID,
+		&rec.TokenHash,
+		&rec.CancelTokenHash,
+		&rec.Status,
+		&rec.IPAddress,
+		&rec.UserAgent,
+		&rec.AvailableAt,
+		&rec.CompletedAt,
+		&rec.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
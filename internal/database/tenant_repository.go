@@ -0,0 +1,281 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/requestctx"
+	"authentio/pkg/email"
+)
+
+type tenantRepository struct {
+	db *sql.DB
+}
+
+// NewTenantRepository creates a new PostgreSQL tenant repository
+func NewTenantRepository(db *sql.DB) repository.TenantRepository {
+	return &tenantRepository{db: db}
+}
+
+// FindBySlug returns the tenant with the given slug, or nil if it doesn't exist
+func (r *tenantRepository) FindBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, slug, name, created_at FROM tenants WHERE slug = $1`, slug,
+	).Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// Ensure creates the tenant with the given slug unless it already exists
+func (r *tenantRepository) Ensure(ctx context.Context, slug, name string) (*models.Tenant, error) {
+	query := `
+		INSERT INTO tenants (slug, name)
+		VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+		RETURNING id, slug, name, created_at`
+
+	tenant := &models.Tenant{}
+	err := r.db.QueryRowContext(ctx, query, slug, name).
+		Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// List returns every tenant, including its token signing settings
+func (r *tenantRepository) List(ctx context.Context) ([]*models.Tenant, error) {
+	query := `
+		SELECT id, slug, name, created_at, COALESCE(jwt_secret, ''), COALESCE(jwt_issuer, ''),
+		       COALESCE(access_token_ttl_seconds, 0), COALESCE(refresh_token_ttl_seconds, 0)
+		FROM tenants
+		ORDER BY id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []*models.Tenant
+	for rows.Next() {
+		tenant := &models.Tenant{}
+		var accessTTL, refreshTTL int64
+		if err := rows.Scan(
+			&tenant.ID,
+			&tenant.Slug,
+			&tenant.Name,
+			&tenant.CreatedAt,
+			&tenant.JWTSecret,
+			&tenant.JWTIssuer,
+			&accessTTL,
+			&refreshTTL,
+		); err != nil {
+			return nil, err
+		}
+		tenant.AccessTokenTTL = time.Duration(accessTTL) * time.Second
+		tenant.RefreshTokenTTL = time.Duration(refreshTTL) * time.Second
+		tenants = append(tenants, tenant)
+	}
+
+	return tenants, rows.Err()
+}
+
+// QuotaLimits returns a tenant's daily quota overrides by metric
+func (r *tenantRepository) QuotaLimits(ctx context.Context, tenantID int64) (map[string]int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT metric, daily_limit FROM tenant_quotas WHERE tenant_id = $1`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	limits := make(map[string]int64)
+	for rows.Next() {
+		var metric string
+		var limit int64
+		if err := rows.Scan(&metric, &limit); err != nil {
+			return nil, err
+		}
+		limits[metric] = limit
+	}
+
+	return limits, rows.Err()
+}
+
+// RiskPolicy returns a tenant's login risk threshold overrides, or nil if it
+// doesn't exist
+func (r *tenantRepository) RiskPolicy(ctx context.Context, tenantID int64) (*models.TenantRiskPolicy, error) {
+	policy := &models.TenantRiskPolicy{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT mfa_risk_skip_below, mfa_risk_require_at, mfa_risk_quarantine_at, mfa_risk_block_at
+		FROM tenants WHERE id = $1`, tenantID,
+	).Scan(&policy.SkipBelow, &policy.RequireAt, &policy.QuarantineAt, &policy.BlockAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// GeoPolicy returns a tenant's geographic sign-in rules, or nil if it has
+// none
+func (r *tenantRepository) GeoPolicy(ctx context.Context, tenantID int64) (*models.GeoPolicy, error) {
+	policy := &models.GeoPolicy{Source: "tenant"}
+	var blocked, allowed, suspicious []byte
+	var updatedBy sql.NullInt64
+	var updatedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT blocked_countries, allowed_countries, suspicious_countries, suspicious_action,
+		       max_travel_speed_kmh, velocity_action, updated_by, updated_at
+		FROM geo_policies WHERE tenant_id = $1`, tenantID,
+	).Scan(&blocked, &allowed, &suspicious, &policy.SuspiciousAction,
+		&policy.MaxTravelSpeed, &policy.VelocityAction, &updatedBy, &updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range []struct {
+		raw []byte
+		to  *[]string
+	}{{blocked, &policy.BlockedCountries}, {allowed, &policy.AllowedCountries}, {suspicious, &policy.SuspiciousCountries}} {
+		if err := json.Unmarshal(list.raw, list.to); err != nil {
+			return nil, err
+		}
+	}
+	if updatedBy.Valid {
+		policy.UpdatedBy = &updatedBy.Int64
+	}
+	if updatedAt.Valid {
+		policy.UpdatedAt = &updatedAt.Time
+	}
+	return policy, nil
+}
+
+// SaveGeoPolicy replaces a tenant's geographic sign-in rules
+func (r *tenantRepository) SaveGeoPolicy(ctx context.Context, tenantID int64, policy *models.GeoPolicy) error {
+	lists := make([][]byte, 0, 3)
+	for _, countries := range [][]string{policy.BlockedCountries, policy.AllowedCountries, policy.SuspiciousCountries} {
+		if countries == nil {
+			countries = []string{}
+		}
+		raw, err := json.Marshal(countries)
+		if err != nil {
+			return err
+		}
+		lists = append(lists, raw)
+	}
+
+	var updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO geo_policies (tenant_id, blocked_countries, allowed_countries, suspicious_countries,
+		                          suspicious_action, max_travel_speed_kmh, velocity_action, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			blocked_countries = EXCLUDED.blocked_countries,
+			allowed_countries = EXCLUDED.allowed_countries,
+			suspicious_countries = EXCLUDED.suspicious_countries,
+			suspicious_action = EXCLUDED.suspicious_action,
+			max_travel_speed_kmh = EXCLUDED.max_travel_speed_kmh,
+			velocity_action = EXCLUDED.velocity_action,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`,
+		tenantID, string(lists[0]), string(lists[1]), string(lists[2]),
+		policy.SuspiciousAction, policy.MaxTravelSpeed, policy.VelocityAction, policy.UpdatedBy,
+	).Scan(&updatedAt)
+	if err != nil {
+		return err
+	}
+	policy.UpdatedAt = &updatedAt
+	return nil
+}
+
+// DeleteGeoPolicy removes a tenant's geographic sign-in rules
+func (r *tenantRepository) DeleteGeoPolicy(ctx context.Context, tenantID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM geo_policies WHERE tenant_id = $1`, tenantID)
+	return err
+}
+
+// Branding returns a tenant's email branding, or nil if the tenant doesn't
+// exist
+func (r *tenantRepository) Branding(ctx context.Context, tenantID int64) (*models.TenantBranding, error) {
+	branding := &models.TenantBranding{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COALESCE(email_from_name, ''), COALESCE(email_from_address, ''), COALESCE(email_logo_url, ''),
+		       COALESCE(email_primary_color, ''), COALESCE(email_background_color, ''), COALESCE(email_footer_text, '')
+		FROM tenants WHERE id = $1`, tenantID,
+	).Scan(
+		&branding.FromName,
+		&branding.FromEmail,
+		&branding.LogoURL,
+		&branding.PrimaryColor,
+		&branding.BackgroundColor,
+		&branding.FooterText,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return branding, nil
+}
+
+// SaveBranding replaces a tenant's email branding; empty fields are cleared
+func (r *tenantRepository) SaveBranding(ctx context.Context, tenantID int64, branding *models.TenantBranding) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE tenants SET
+			email_from_name = NULLIF($2, ''),
+			email_from_address = NULLIF($3, ''),
+			email_logo_url = NULLIF($4, ''),
+			email_primary_color = NULLIF($5, ''),
+			email_background_color = NULLIF($6, ''),
+			email_footer_text = NULLIF($7, '')
+		WHERE id = $1`,
+		tenantID, branding.FromName, branding.FromEmail, branding.LogoURL,
+		branding.PrimaryColor, branding.BackgroundColor, branding.FooterText,
+	)
+	return err
+}
+
+// EmailBranding returns the email branding of the tenant in ctx
+func (r *tenantRepository) EmailBranding(ctx context.Context) (*email.Branding, error) {
+	branding, err := r.Branding(ctx, tenantID(ctx))
+	if err != nil || branding == nil {
+		return nil, err
+	}
+	return &email.Branding{
+		FromName:        branding.FromName,
+		FromEmail:       branding.FromEmail,
+		LogoURL:         branding.LogoURL,
+		PrimaryColor:    branding.PrimaryColor,
+		BackgroundColor: branding.BackgroundColor,
+		FooterText:      branding.FooterText,
+	}, nil
+}
+
+// tenantID returns the tenant queries in ctx are scoped to. Background jobs
+// and single-tenant deployments don't set one and use the default tenant.
+func tenantID(ctx context.Context) int64 {
+	if id := requestctx.TenantFrom(ctx); id != 0 {
+		return id
+	}
+	return constants.DefaultTenantID
+}
@@ -8,6 +8,7 @@ import (
 
 	"authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/pkg/pagination"
 )
 
 type tokenRepository struct {
@@ -22,15 +23,22 @@ func NewTokenRepository(db *sql.DB) repository.TokenRepository {
 // SaveRefreshToken stores a new refresh token
 func (r *tokenRepository) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
 	query := `
-		INSERT INTO refresh_tokens (user_id, token, expires_at, created_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (user_id, token, expires_at, organization_id, oauth_client_id, scope, created_at, device_id, auth_time, access_token_id, access_expires_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, NULLIF($10, ''), $11)
 		RETURNING id`
 
 	err := r.db.QueryRowContext(ctx, query,
 		token.UserID,
 		token.Token,
 		token.ExpiredAt,
+		token.OrganizationID,
+		token.OAuthClientID,
+		token.Scope,
 		time.Now(),
+		token.DeviceID,
+		token.AuthTime,
+		token.AccessTokenID,
+		token.AccessExpiresAt,
 	).Scan(&token.ID)
 
 	if err != nil {
@@ -43,17 +51,26 @@ func (r *tokenRepository) SaveRefreshToken(ctx context.Context, token *models.Re
 // GetRefreshToken retrieves a refresh token by its token string
 func (r *tokenRepository) GetRefreshToken(ctx context.Context, tokenStr string) (*models.RefreshToken, error) {
 	query := `
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, expires_at, organization_id, oauth_client_id, COALESCE(scope, ''), created_at, device_id, auth_time,
+			COALESCE(access_token_id, ''), access_expires_at
 		FROM refresh_tokens
 		WHERE token = $1 AND expires_at > $2`
 
 	token := &models.RefreshToken{}
+	var organizationID, oauthClientID, deviceID sql.NullInt64
 	err := r.db.QueryRowContext(ctx, query, tokenStr, time.Now()).Scan(
 		&token.ID,
 		&token.UserID,
 		&token.Token,
 		&token.ExpiredAt,
+		&organizationID,
+		&oauthClientID,
+		&token.Scope,
 		&token.CreatedAt,
+		&deviceID,
+		&token.AuthTime,
+		&token.AccessTokenID,
+		&token.AccessExpiresAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -62,6 +79,15 @@ func (r *tokenRepository) GetRefreshToken(ctx context.Context, tokenStr string)
 	if err != nil {
 		return nil, err
 	}
+	if organizationID.Valid {
+		token.OrganizationID = &organizationID.Int64
+	}
+	if oauthClientID.Valid {
+		token.OAuthClientID = &oauthClientID.Int64
+	}
+	if deviceID.Valid {
+		token.DeviceID = &deviceID.Int64
+	}
 
 	return token, nil
 }
@@ -86,6 +112,81 @@ func (r *tokenRepository) DeleteRefreshToken(ctx context.Context, token string)
 	return nil
 }
 
+// sessionCondition selects a user's ($1) unexpired ($2) first-party refresh
+// tokens, i.e. their signed-in sessions
+const sessionCondition = `user_id = $1 AND oauth_client_id IS NULL AND revoked IS NOT TRUE AND expires_at > $2`
+
+// ListUserRefreshTokens returns up to limit of a user's unexpired
+// first-party refresh tokens (one per signed-in session), newest first,
+// starting after the after cursor when set
+func (r *tokenRepository) ListUserRefreshTokens(ctx context.Context, userID int64, after *pagination.Cursor, limit int) ([]*models.RefreshToken, error) {
+	cursorCondition, args := afterCursor(after, []interface{}{userID, time.Now(), limit})
+	query := `
+		SELECT id, user_id, expires_at, organization_id, created_at, device_id
+		FROM refresh_tokens
+		WHERE ` + sessionCondition + ` AND ` + cursorCondition + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.RefreshToken
+	for rows.Next() {
+		token := &models.RefreshToken{}
+		var organizationID, deviceID sql.NullInt64
+		if err := rows.Scan(&token.ID, &token.UserID, &token.ExpiredAt, &organizationID, &token.CreatedAt, &deviceID); err != nil {
+			return nil, err
+		}
+		if organizationID.Valid {
+			token.OrganizationID = &organizationID.Int64
+		}
+		if deviceID.Valid {
+			token.DeviceID = &deviceID.Int64
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+// CountUserRefreshTokens counts a user's signed-in sessions
+func (r *tokenRepository) CountUserRefreshTokens(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM refresh_tokens WHERE `+sessionCondition, userID, time.Now()).Scan(&count)
+	return count, err
+}
+
+// ListDeviceAccessTokens returns the last access token (ID and expiry) of
+// each session started on one of a user's devices, leaving out sessions
+// without one
+func (r *tokenRepository) ListDeviceAccessTokens(ctx context.Context, userID, deviceID int64) ([]*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, access_token_id, access_expires_at
+		FROM refresh_tokens
+		WHERE user_id = $1 AND device_id = $2 AND access_token_id IS NOT NULL AND access_expires_at > $3`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, deviceID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []*models.RefreshToken
+	for rows.Next() {
+		token := &models.RefreshToken{}
+		if err := rows.Scan(&token.ID, &token.UserID, &token.AccessTokenID, &token.AccessExpiresAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
 // DeleteUserRefreshTokens removes all refresh tokens for a specific user
 func (r *tokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID int64) error {
 	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
@@ -93,9 +194,18 @@ func (r *tokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID in
 	return err
 }
 
-// CleanupExpiredTokens removes all expired refresh tokens
-func (r *tokenRepository) CleanupExpiredTokens(ctx context.Context) error {
-	query := `DELETE FROM refresh_tokens WHERE expires_at <= $1`
-	_, err := r.db.ExecContext(ctx, query, time.Now())
-	return err
+// PurgeRefreshTokens deletes up to limit refresh tokens that expired, or
+// were revoked, before before, and returns how many it deleted
+func (r *tokenRepository) PurgeRefreshTokens(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM refresh_tokens
+		WHERE id IN (
+			SELECT id FROM refresh_tokens
+			WHERE expires_at < $1 OR (revoked AND updated_at < $1)
+			LIMIT $2)`
+	result, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
\ No newline at end of file
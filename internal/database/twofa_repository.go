@@ -3,17 +3,24 @@ package database
 import (
 	_ "authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/pkg/secretbox"
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strconv"
 )
 
 type twoFARepository struct {
-	db *sql.DB
+	db   *sql.DB
+	keys *secretbox.Keyring // nil stores secrets unencrypted
 }
 
-func NewTwoFARepository(db *sql.DB) repository.TwoFARepository {
-	return &twoFARepository{db: db}
+// NewTwoFARepository creates a new PostgreSQL 2FA repository. Secrets are
+// encrypted with keys; when nil they are stored as is.
+func NewTwoFARepository(db *sql.DB, keys *secretbox.Keyring) repository.TwoFARepository {
+	return &twoFARepository{db: db, keys: keys}
 }
 
 func (r *twoFARepository) EnableEmail2FA(ctx context.Context, userID int64) error {
@@ -28,6 +35,19 @@ func (r *twoFARepository) EnableEmail2FA(ctx context.Context, userID int64) erro
 	return err
 }
 
+// EnablePush2FA switches the user to push approval 2FA. Like email OTP it
+// needs no secret; approvals go to the user's registered devices.
+func (r *twoFARepository) EnablePush2FA(ctx context.Context, userID int64) error {
+	query := `
+		INSERT INTO two_fa_configs (user_id, method, secret, enabled)
+		VALUES ($1, 'push', '', TRUE)
+		ON CONFLICT (user_id)
+		DO UPDATE SET method = 'push', enabled = TRUE, updated_at = CURRENT_TIMESTAMP`
+
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
 func (r *twoFARepository) Disable2FA(ctx context.Context, userID int64) error {
 	query := `UPDATE two_fa_configs SET enabled = FALSE WHERE user_id = $1`
 	_, err := r.db.ExecContext(ctx, query, userID)
@@ -49,9 +69,202 @@ func (r *twoFARepository) Is2FAEnabled(ctx context.Context, userID int64) (bool,
 	return enabled, nil
 }
 
+// Save2FASecret stores the user's 2FA secret (e.g. a TOTP key), encrypted
+// when a keyring is set. It doesn't change the method nor enable 2FA.
+func (r *twoFARepository) Save2FASecret(ctx context.Context, userID int64, secret string) error {
+	stored, keyID, dataKey, err := r.sealSecret(userID, secret)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO two_fa_configs (user_id, method, secret, secret_key_id, secret_data_key, enabled)
+		VALUES ($1, '', $2, $3, $4, FALSE)
+		ON CONFLICT (user_id)
+		DO UPDATE SET secret = $2, secret_key_id = $3, secret_data_key = $4, updated_at = CURRENT_TIMESTAMP`
+
+	_, err = r.db.ExecContext(ctx, query, userID, stored, keyID, dataKey)
+	return err
+}
+
+// Get2FASecret returns the user's decrypted 2FA secret, or "" when none is
+// stored
 func (r *twoFARepository) Get2FASecret(ctx context.Context, userID int64) (string, error) {
-	// Not used for email OTP
-	return "", errors.New("not applicable for email OTP")
+	query := `SELECT COALESCE(secret, ''), secret_key_id, secret_data_key FROM two_fa_configs WHERE user_id = $1`
+
+	var stored string
+	var keyID, dataKey sql.NullString
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&stored, &keyID, &dataKey)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !keyID.Valid {
+		return stored, nil
+	}
+	if r.keys == nil {
+		return "", fmt.Errorf("2FA secret of user %d is encrypted but no key is configured: %w", userID, secretbox.ErrUnknownKey)
+	}
+
+	sealed, err := decodeSealed(stored, keyID.String, dataKey.String)
+	if err != nil {
+		return "", err
+	}
+	secret, err := r.keys.Open(sealed, secretContext(userID))
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// ReencryptSecrets encrypts the secrets stored unencrypted and re-wraps the
+// data keys of those sealed under a retired master key with the current one.
+// It returns how many secrets were updated; secrets wrapped by keys missing
+// from the keyring are skipped and reported in the error. Secrets are read
+// and updated in batches.
+func (r *twoFARepository) ReencryptSecrets(ctx context.Context) (int, error) {
+	if r.keys == nil {
+		return 0, nil
+	}
+
+	current := r.keys.CurrentKeyID()
+	updated, unknown := 0, 0
+	var after int64
+	for {
+		last, n, batchUpdated, batchUnknown, err := r.reencryptBatch(ctx, current, after)
+		updated += batchUpdated
+		unknown += batchUnknown
+		if err != nil {
+			return updated, err
+		}
+		if n < reencryptBatchSize {
+			break
+		}
+		after = last
+	}
+
+	if unknown > 0 {
+		return updated, fmt.Errorf("%d 2FA secrets are wrapped by master keys missing from the keyring: %w", unknown, secretbox.ErrUnknownKey)
+	}
+	return updated, nil
+}
+
+// reencryptBatch re-encrypts the next batch of secrets of users after the
+// given ID not sealed under the current key. It returns the last user ID of
+// the batch, its size, and how many were updated and skipped.
+func (r *twoFARepository) reencryptBatch(ctx context.Context, current string, after int64) (int64, int, int, int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT user_id, secret, secret_key_id, secret_data_key FROM two_fa_configs
+		WHERE secret <> '' AND secret_key_id IS DISTINCT FROM $1 AND user_id > $2
+		ORDER BY user_id
+		LIMIT $3`, current, after, reencryptBatchSize)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	type pending struct {
+		userID  int64
+		stored  string
+		keyID   sql.NullString
+		dataKey sql.NullString
+	}
+	var secrets []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.userID, &p.stored, &p.keyID, &p.dataKey); err != nil {
+			rows.Close()
+			return 0, 0, 0, 0, err
+		}
+		secrets = append(secrets, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if len(secrets) == 0 {
+		return after, 0, 0, 0, nil
+	}
+	last := secrets[len(secrets)-1].userID
+
+	updated, unknown := 0, 0
+	for _, p := range secrets {
+		var sealed *secretbox.Sealed
+		if p.keyID.Valid {
+			old, err := decodeSealed(p.stored, p.keyID.String, p.dataKey.String)
+			if err != nil {
+				return last, len(secrets), updated, unknown, err
+			}
+			sealed, err = r.keys.Rewrap(old)
+			if errors.Is(err, secretbox.ErrUnknownKey) {
+				unknown++
+				continue
+			}
+			if err != nil {
+				return last, len(secrets), updated, unknown, fmt.Errorf("user %d: %w", p.userID, err)
+			}
+		} else {
+			sealed, err = r.keys.Seal([]byte(p.stored), secretContext(p.userID))
+			if err != nil {
+				return last, len(secrets), updated, unknown, err
+			}
+		}
+
+		// Skipped if the secret changed since it was read
+		result, err := r.db.ExecContext(ctx, `
+			UPDATE two_fa_configs SET secret = $2, secret_key_id = $3, secret_data_key = $4
+			WHERE user_id = $1 AND secret = $5 AND secret_key_id IS NOT DISTINCT FROM $6`,
+			p.userID, encodeBytes(sealed.Ciphertext), sealed.KeyID, encodeBytes(sealed.DataKey), p.stored, p.keyID,
+		)
+		if err != nil {
+			return last, len(secrets), updated, unknown, err
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			updated++
+		}
+	}
+	return last, len(secrets), updated, unknown, nil
+}
+
+// sealSecret returns the stored form of a user's secret: the ciphertext with
+// the master key ID and wrapped data key, or the secret itself without a
+// keyring
+func (r *twoFARepository) sealSecret(userID int64, secret string) (string, sql.NullString, sql.NullString, error) {
+	if r.keys == nil || secret == "" {
+		return secret, sql.NullString{}, sql.NullString{}, nil
+	}
+	sealed, err := r.keys.Seal([]byte(secret), secretContext(userID))
+	if err != nil {
+		return "", sql.NullString{}, sql.NullString{}, err
+	}
+	return encodeBytes(sealed.Ciphertext),
+		sql.NullString{String: sealed.KeyID, Valid: true},
+		sql.NullString{String: encodeBytes(sealed.DataKey), Valid: true},
+		nil
+}
+
+// secretContext binds a secret to its user, so a ciphertext copied to
+// another row doesn't decrypt
+func secretContext(userID int64) []byte {
+	return []byte("two_fa_configs:" + strconv.FormatInt(userID, 10))
+}
+
+// decodeSealed rebuilds a sealed secret from its columns
+func decodeSealed(ciphertext, keyID, dataKey string) (*secretbox.Sealed, error) {
+	sealed := &secretbox.Sealed{KeyID: keyID}
+	var err error
+	if sealed.Ciphertext, err = base64.StdEncoding.DecodeString(ciphertext); err != nil {
+		return nil, secretbox.ErrDecrypt
+	}
+	if sealed.DataKey, err = base64.StdEncoding.DecodeString(dataKey); err != nil {
+		return nil, secretbox.ErrDecrypt
+	}
+	return sealed, nil
+}
+
+// encodeBytes encodes a ciphertext or wrapped key for a TEXT column
+func encodeBytes(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
 }
 
 func (r *twoFARepository) VerifyOTP(ctx context.Context, userID int64,email, code, otpType string) (bool, error) {
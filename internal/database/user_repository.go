@@ -5,8 +5,16 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
 	"authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/pkg/pagination"
 )
 
 type userRepository struct {
@@ -18,24 +26,32 @@ func NewUserRepository(db *sql.DB) repository.UserRepository {
 	return &userRepository{db: db}
 }
 
+// userColumns lists the columns read by findUser, in scan order
+const userColumns = `id, first_name, last_name, email, COALESCE(username, ''), COALESCE(password, ''), password_changed_at, is_active, role,
+	COALESCE(provider, 'email'), COALESCE(locale, ''), avatar_url, user_metadata, app_metadata, approval_status, tenant_id, token_version, created_at, updated_at`
+
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findUser(ctx, `email = $1`, email)
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	return r.findUser(ctx, `id = $1`, id)
+}
+
+// FindByUsername finds a user by username, case-insensitively
+func (r *userRepository) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findUser(ctx, `LOWER(username) = LOWER($1)`, username)
+}
+
+// findUser returns the tenant's live user matching condition, which compares
+// a column with $1
+func (r *userRepository) findUser(ctx context.Context, condition string, value interface{}) (*models.User, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password, is_active, created_at, updated_at 
+		SELECT ` + userColumns + `
 		FROM users 
-		WHERE email = $1 AND deleted_at IS NULL`
-	
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID,
-		&user.FirstName,
-		&user.LastName,
-		&user.Email,
-		&user.Password,
-		&user.IsActive,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+		WHERE ` + condition + ` AND tenant_id = $2 AND deleted_at IS NULL`
 	
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, value, tenantID(ctx)))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -46,38 +62,222 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models
 	return user, nil
 }
 
-func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+// List returns up to limit of the tenant's live users matching filter,
+// newest first, starting after the after cursor when set
+func (r *userRepository) List(ctx context.Context, filter models.UserFilter, after *pagination.Cursor, limit int) ([]*models.User, error) {
+	filterCondition, args := userFilterCondition(filter, []interface{}{tenantID(ctx), limit})
+	cursorCondition, args := afterCursor(after, args)
 	query := `
-		SELECT id, first_name, last_name, email, password, is_active, created_at, updated_at 
-		FROM users 
-		WHERE id = $1 AND deleted_at IS NULL`
-	
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND ` + filterCondition + ` AND ` + cursorCondition + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// Stats counts the tenant's live users in total, locked out (registration
+// pending or rejected, which refuses sign-in), with 2FA enabled and per
+// sign-up provider. Anonymized accounts are left out.
+func (r *userRepository) Stats(ctx context.Context) (*models.UserStats, error) {
+	stats := &models.UserStats{Providers: make(map[string]int64)}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE approval_status IN ($2, $3)),
+		       COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM two_fa_configs t WHERE t.user_id = users.id AND t.enabled))
+		FROM users
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL`,
+		tenantID(ctx), constants.ApprovalPending, constants.ApprovalRejected,
+	).Scan(&stats.Total, &stats.Locked, &stats.TwoFactorEnabled)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(provider, 'email'), COUNT(*)
+		FROM users
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL
+		GROUP BY 1`,
+		tenantID(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var provider string
+		var count int64
+		if err := rows.Scan(&provider, &count); err != nil {
+			return nil, err
+		}
+		stats.Providers[provider] = count
+	}
+
+	return stats, rows.Err()
+}
+
+// DailySignups counts the tenant's registrations per UTC day since since,
+// oldest first
+func (r *userRepository) DailySignups(ctx context.Context, since time.Time) ([]models.DailyCount, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT TO_CHAR(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, COUNT(*)
+		FROM users
+		WHERE tenant_id = $1 AND created_at >= $2
+		GROUP BY day
+		ORDER BY day`,
+		tenantID(ctx), since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []models.DailyCount
+	for rows.Next() {
+		var day models.DailyCount
+		if err := rows.Scan(&day.Date, &day.Count); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// Search returns up to limit of the tenant's live users matching query, which
+// must be lowercase. A user matches when search_text (see migration 028)
+// contains query, has a word similar to it (pg_trgm word similarity, for
+// typos) or contains all its words. Exact email or username matches come
+// first, then users ranked by full-text rank plus word similarity.
+func (r *userRepository) Search(ctx context.Context, query string, limit int) ([]*models.User, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND (
+			search_text LIKE $3 OR
+			$2 <% search_text OR
+			to_tsvector('simple', search_text) @@ plainto_tsquery('simple', $2)
+		)
+		ORDER BY (LOWER(email) = $2 OR COALESCE(LOWER(username), '') = $2) DESC,
+			ts_rank(to_tsvector('simple', search_text), plainto_tsquery('simple', $2)) + word_similarity($2, search_text) DESC,
+			created_at DESC, id DESC
+		LIMIT $4`,
+		tenantID(ctx), query, pattern, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// userFilterCondition returns the condition selecting the users matching
+// filter, adding its parameters to args
+func userFilterCondition(filter models.UserFilter, args []interface{}) (string, []interface{}) {
+	conditions := []string{"TRUE"}
+	if filter.Provider != "" {
+		args = append(args, filter.Provider)
+		conditions = append(conditions, fmt.Sprintf("COALESCE(provider, 'email') = $%d", len(args)))
+	}
+	if filter.CreatedFrom != nil {
+		args = append(args, *filter.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.CreatedTo != nil {
+		args = append(args, *filter.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if filter.Active != nil {
+		args = append(args, *filter.Active)
+		conditions = append(conditions, fmt.Sprintf("is_active = $%d", len(args)))
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// scanUser reads a row of userColumns
+func scanUser(row interface{ Scan(...interface{}) error }) (*models.User, error) {
 	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	var userMetadata, appMetadata []byte
+	err := row.Scan(
 		&user.ID,
 		&user.FirstName,
 		&user.LastName,
 		&user.Email,
+		&user.Username,
 		&user.Password,
+		&user.PasswordChangedAt,
 		&user.IsActive,
+		&user.Role,
+		&user.Provider,
+		&user.Locale,
+		&user.AvatarURL,
+		&userMetadata,
+		&appMetadata,
+		&user.ApprovalStatus,
+		&user.TenantID,
+		&user.TokenVersion,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
-	
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
 	if err != nil {
 		return nil, err
 	}
-	
+	if err := unmarshalMetadata(user, userMetadata, appMetadata); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	if user.Role == "" {
+		user.Role = constants.RoleUser
+	}
+	if user.Provider == "" {
+		user.Provider = "email"
+	}
+	if user.ApprovalStatus == "" {
+		user.ApprovalStatus = constants.ApprovalApproved
+	}
+	user.TenantID = tenantID(ctx)
+	if user.Password != "" && user.PasswordChangedAt == nil {
+		changedAt := user.CreatedAt
+		user.PasswordChangedAt = &changedAt
+	}
+
 	query := `
-		INSERT INTO users (first_name, last_name, email, password, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO users (first_name, last_name, email, password, is_active, role, provider, invited_by, approval_status, tenant_id, created_at, updated_at, username, password_changed_at, provider_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, NULLIF($15, ''))
 		RETURNING id`
 	
 	err := r.db.QueryRowContext(ctx, query,
@@ -86,8 +286,16 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		user.Email,
 		user.Password,
 		user.IsActive,
+		user.Role,
+		user.Provider,
+		user.InvitedBy,
+		user.ApprovalStatus,
+		user.TenantID,
 		user.CreatedAt,
 		user.UpdatedAt,
+		user.Username,
+		user.PasswordChangedAt,
+		user.ProviderID,
 	).Scan(&user.ID)
 	
 	return err
@@ -96,23 +304,317 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	query := `
 		UPDATE users 
-		SET first_name = $1, last_name = $2, email = $3, is_active = $4, updated_at = $5
-		WHERE id = $6`
+		SET first_name = $1, last_name = $2, email = $3, is_active = $4, locale = NULLIF($5, ''), updated_at = $6
+		WHERE id = $7 AND tenant_id = $8`
 	
 	_, err := r.db.ExecContext(ctx, query,
 		user.FirstName,
 		user.LastName,
 		user.Email,
 		user.IsActive,
+		user.Locale,
 		user.UpdatedAt,
 		user.ID,
+		tenantID(ctx),
 	)
 	
 	return err
 }
 
 func (r *userRepository) Delete(ctx context.Context, id int64) error {
-	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
+	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1 AND tenant_id = $2`
+	_, err := r.db.ExecContext(ctx, query, id, tenantID(ctx))
 	return err
-}
\ No newline at end of file
+}
+
+// SetUsername saves a user's (lowercase) username
+func (r *userRepository) SetUsername(ctx context.Context, id int64, username string) error {
+	query := `UPDATE users SET username = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, username, id, tenantID(ctx))
+	return err
+}
+
+// SetPassword saves a user's new password hash and when it was changed
+func (r *userRepository) SetPassword(ctx context.Context, id int64, hash string) error {
+	query := `UPDATE users SET password = $1, password_changed_at = NOW(), updated_at = NOW() WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, hash, id, tenantID(ctx))
+	return err
+}
+
+// SetAvatarURL saves the URL of a user's profile picture; nil removes it
+func (r *userRepository) SetAvatarURL(ctx context.Context, id int64, avatarURL *string) error {
+	query := `UPDATE users SET avatar_url = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, avatarURL, id, tenantID(ctx))
+	return err
+}
+
+// SetMetadata replaces a user's user_metadata or app_metadata (see
+// constants.Metadata*)
+func (r *userRepository) SetMetadata(ctx context.Context, id int64, kind string, metadata map[string]interface{}) error {
+	if kind != constants.MetadataUser && kind != constants.MetadataApp {
+		return fmt.Errorf("unknown metadata kind %q", kind)
+	}
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	query := `UPDATE users SET ` + kind + ` = $1, updated_at = NOW() WHERE id = $2 AND tenant_id = $3 AND deleted_at IS NULL`
+	_, err = r.db.ExecContext(ctx, query, raw, id, tenantID(ctx))
+	return err
+}
+
+// TokenVersion returns the token version of a live user
+func (r *userRepository) TokenVersion(ctx context.Context, id int64) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT token_version FROM users WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL`,
+		id, tenantID(ctx)).Scan(&version)
+	return version, err
+}
+
+// BumpTokenVersion increments a user's token version and returns the new one
+func (r *userRepository) BumpTokenVersion(ctx context.Context, id int64) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE users SET token_version = token_version + 1, updated_at = NOW() WHERE id = $1 AND tenant_id = $2 AND deleted_at IS NULL RETURNING token_version`,
+		id, tenantID(ctx)).Scan(&version)
+	return version, err
+}
+
+// FindByApprovalStatus returns users in the given approval state, oldest first
+func (r *userRepository) FindByApprovalStatus(ctx context.Context, status string) ([]*models.User, error) {
+	query := `
+		SELECT id, first_name, last_name, email, is_active, role, approval_status, created_at, updated_at
+		FROM users
+		WHERE approval_status = $1 AND tenant_id = $2 AND deleted_at IS NULL
+		ORDER BY created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, status, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user := &models.User{}
+		if err := rows.Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.Email,
+			&user.IsActive,
+			&user.Role,
+			&user.ApprovalStatus,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// FindEmailsByRole returns the email addresses of active users with the given role
+func (r *userRepository) FindEmailsByRole(ctx context.Context, role string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT email FROM users WHERE role = $1 AND tenant_id = $2 AND is_active AND deleted_at IS NULL ORDER BY id`,
+		role, tenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
+
+// FindStalePasswords returns up to limit of the tenant's active, approved
+// users with an ID above afterID whose password was last set before
+// changedBefore and who weren't emailed event since remindedSince, by ID.
+// Anonymized accounts and accounts without a password are left out.
+func (r *userRepository) FindStalePasswords(ctx context.Context, changedBefore time.Time, event string, remindedSince time.Time, afterID int64, limit int) ([]*models.User, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL AND is_active
+		  AND approval_status = $2 AND COALESCE(password, '') <> ''
+		  AND COALESCE(password_changed_at, created_at) < $3 AND id > $6
+		  AND NOT EXISTS (SELECT 1 FROM email_log e WHERE e.user_id = u.id AND e.event = $4 AND e.created_at >= $5)
+		ORDER BY id
+		LIMIT $7`,
+		tenantID(ctx), constants.ApprovalApproved, changedBefore, event, remindedSince, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// FindNotEmailed returns up to limit of the tenant's active, approved users
+// with an ID above afterID, registered between registeredAfter and
+// registeredBefore, who were never emailed event, by ID. Anonymized accounts
+// are left out.
+func (r *userRepository) FindNotEmailed(ctx context.Context, registeredAfter, registeredBefore time.Time, event string, afterID int64, limit int) ([]*models.User, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+userColumns+`
+		FROM users u
+		WHERE tenant_id = $1 AND deleted_at IS NULL AND anonymized_at IS NULL AND is_active
+		  AND approval_status = $2 AND created_at > $3 AND created_at <= $4 AND id > $6
+		  AND NOT EXISTS (SELECT 1 FROM email_log e WHERE e.user_id = u.id AND e.event = $5)
+		ORDER BY id
+		LIMIT $7`,
+		tenantID(ctx), constants.ApprovalApproved, registeredAfter, registeredBefore, event, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// SetApprovalStatus moves a pending user to status, recording the reviewer.
+// It returns sql.ErrNoRows if the user is not pending.
+func (r *userRepository) SetApprovalStatus(ctx context.Context, id int64, status string, reviewerID int64) error {
+	query := `
+		UPDATE users
+		SET approval_status = $2, reviewed_by = $3, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND tenant_id = $4 AND approval_status = 'pending' AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id, status, reviewerID, tenantID(ctx))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Anonymize scrubs a user's PII in a single transaction while keeping the row
+// (and therefore every foreign key pointing at it) in place. Credentials,
+// sessions, OTPs, 2FA secrets and the profile are deleted outright; audit log
+// entries are kept for statistics but lose their IP address, user agent and
+// email.
+func (r *userRepository) Anonymize(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the row and remember the original email for scrubbing related records
+	var email string
+	err = tx.QueryRowContext(ctx,
+		`SELECT email FROM users WHERE id = $1 AND tenant_id = $2 AND anonymized_at IS NULL FOR UPDATE`, id, tenantID(ctx),
+	).Scan(&email)
+	if err == sql.ErrNoRows {
+		return errors.New("user not found or already anonymized")
+	}
+	if err != nil {
+		return err
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{
+			// Replace identifying fields with placeholders; the email stays unique per id
+			query: `
+				UPDATE users
+				SET first_name = 'Deleted', last_name = 'User',
+				    email = 'deleted-' || id || '@anonymized.invalid',
+				    username = NULL, password = NULL, password_changed_at = NULL, provider_id = NULL, avatar_url = NULL,
+				    user_metadata = '{}', app_metadata = '{}',
+				    is_active = FALSE, anonymized_at = NOW(),
+				    deleted_at = COALESCE(deleted_at, NOW()), updated_at = NOW()
+				WHERE id = $1`,
+			args: []interface{}{id},
+		},
+		{query: `DELETE FROM refresh_tokens WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM otps WHERE user_id = $1 OR (email = $2 AND tenant_id = $3)`, args: []interface{}{id, email, tenantID(ctx)}},
+		{query: `DELETE FROM two_fa_configs WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM user_profiles WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM user_preferences WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM push_devices WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM push_challenges WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM recovery_codes WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM two_factor_recoveries WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM user_devices WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `DELETE FROM user_ip_allowlist WHERE user_id = $1`, args: []interface{}{id}},
+		{query: `UPDATE user_consents SET ip_address = NULL WHERE user_id = $1`, args: []interface{}{id}},
+		{
+			// Delivery records stay for statistics, without the address
+			query: `
+				UPDATE email_log
+				SET recipient = 'deleted-' || id || '@anonymized.invalid', subject = ''
+				WHERE user_id = $1 OR (LOWER(recipient) = LOWER($2) AND tenant_id = $3)`,
+			args: []interface{}{id, email, tenantID(ctx)},
+		},
+		{
+			query: `
+				UPDATE audit_logs
+				SET ip_address = NULL, user_agent = NULL, metadata = metadata - 'email' - 'location'
+				WHERE user_id = $1 OR metadata->>'email' = $2`,
+			args: []interface{}{id, email},
+		},
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// unmarshalMetadata decodes the user_metadata and app_metadata columns
+func unmarshalMetadata(user *models.User, userMetadata, appMetadata []byte) error {
+	if len(userMetadata) > 0 {
+		if err := json.Unmarshal(userMetadata, &user.UserMetadata); err != nil {
+			return err
+		}
+	}
+	if len(appMetadata) > 0 {
+		if err := json.Unmarshal(appMetadata, &user.AppMetadata); err != nil {
+			return err
+		}
+	}
+	return nil
+}
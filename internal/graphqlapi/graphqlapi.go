@@ -0,0 +1,73 @@
+// Package graphqlapi implements the optional GraphQL endpoint (schema.graphql)
+// on top of the same AuthService as the REST handlers. Resolvers authenticate
+// the caller themselves from the access token stored in the context.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+
+	"authentio/internal/requestctx"
+	"authentio/internal/service"
+	"authentio/pkg/i18n"
+	"authentio/pkg/logger"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type accessTokenKey struct{}
+
+// NewSchema parses the GraphQL schema and binds it to authService.
+func NewSchema(authService service.AuthService) *graphql.Schema {
+	return graphql.MustParseSchema(schemaSDL, &rootResolver{authService: authService})
+}
+
+// WithAccessToken returns a copy of ctx carrying the caller's bearer token.
+func WithAccessToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessTokenKey{}, token)
+}
+
+// =============================================================================
+// Errors
+// =============================================================================
+
+// errUnauthenticated is returned by every resolver when the request carries
+// no valid access token.
+var errUnauthenticated = &resolverError{code: "unauthenticated", message: "authentication required"}
+
+// resolverError is a GraphQL error with a machine-readable code in its
+// extensions, mirroring the "code" field of REST error responses.
+type resolverError struct {
+	code    string
+	message string
+}
+
+// Error implements the error interface.
+func (e *resolverError) Error() string {
+	return e.message
+}
+
+// Extensions adds the error code to the GraphQL error.
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// toResolverError converts service errors to localized resolver errors.
+// Unexpected errors are logged and hidden from the client.
+func toResolverError(ctx context.Context, err error) error {
+	var svcErr *service.ServiceError
+	if !errors.As(err, &svcErr) {
+		logger.Error("graphql resolver failed", "error", err)
+		return &resolverError{code: "internal", message: "internal server error"}
+	}
+
+	msg, ok := i18n.Lookup(requestctx.LocaleFrom(ctx), "error."+svcErr.Code)
+	if !ok {
+		msg = svcErr.Message
+	}
+	return &resolverError{code: svcErr.Code, message: msg}
+}
@@ -0,0 +1,256 @@
+package graphqlapi
+
+import (
+	"context"
+	"strconv"
+
+	"authentio/internal/requestctx"
+	"authentio/internal/service"
+	"authentio/pkg/pagination"
+	"authentio/pkg/response"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// validate checks resolver arguments with the same rules as REST requests.
+var validate = validator.New()
+
+// =============================================================================
+// Root Resolver
+// =============================================================================
+
+// rootResolver resolves the Query and Mutation fields.
+type rootResolver struct {
+	authService service.AuthService
+}
+
+// currentUserID authenticates the caller from the access token in ctx.
+func (r *rootResolver) currentUserID(ctx context.Context) (int64, error) {
+	token, _ := ctx.Value(accessTokenKey{}).(string)
+	if token == "" {
+		return 0, errUnauthenticated
+	}
+
+	info, err := r.authService.VerifyAccessToken(ctx, token)
+	if err != nil {
+		return 0, errUnauthenticated
+	}
+	// Like AuthRequired, refuse accounts used from outside their IP allowlist
+	if !r.authService.AllowsIP(ctx, info.UserID, requestctx.ClientInfoFrom(ctx).IP) {
+		return 0, toResolverError(ctx, service.ErrIPNotAllowed)
+	}
+	return info.UserID, nil
+}
+
+// user loads the profile of userID.
+func (r *rootResolver) user(ctx context.Context, userID int64) (*userResolver, error) {
+	profile, err := r.authService.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+
+	twoFactor, err := r.authService.Is2FAEnabled(ctx, userID)
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+
+	return &userResolver{user: profile, twoFactorEnabled: twoFactor}, nil
+}
+
+// =============================================================================
+// Queries
+// =============================================================================
+
+// Me resolves the authenticated user's profile.
+func (r *rootResolver) Me(ctx context.Context) (*userResolver, error) {
+	userID, err := r.currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.user(ctx, userID)
+}
+
+// Sessions resolves the authenticated user's signed-in sessions.
+func (r *rootResolver) Sessions(ctx context.Context) ([]*sessionResolver, error) {
+	userID, err := r.currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.authService.ListSessions(ctx, userID, pagination.Request{Limit: pagination.MaxLimit})
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	sessions := page.Data
+
+	resolvers := make([]*sessionResolver, len(sessions))
+	for i := range sessions {
+		resolvers[i] = &sessionResolver{session: sessions[i]}
+	}
+	return resolvers, nil
+}
+
+// LoginHistory resolves the authenticated user's recent sign-ins.
+func (r *rootResolver) LoginHistory(ctx context.Context, args struct{ Limit int32 }) ([]*loginEventResolver, error) {
+	userID, err := r.currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.authService.LoginHistory(ctx, userID, pagination.Request{Limit: int(args.Limit)})
+	if err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	events := page.Data
+
+	resolvers := make([]*loginEventResolver, len(events))
+	for i := range events {
+		resolvers[i] = &loginEventResolver{event: events[i]}
+	}
+	return resolvers, nil
+}
+
+// =============================================================================
+// Mutations
+// =============================================================================
+
+// updateProfileInput mirrors the UpdateProfileInput GraphQL input type.
+type updateProfileInput struct {
+	FirstName *string
+	LastName  *string
+	Email     *string
+}
+
+// UpdateProfile updates the authenticated user's name and/or email.
+func (r *rootResolver) UpdateProfile(ctx context.Context, args struct{ Input updateProfileInput }) (*userResolver, error) {
+	userID, err := r.currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName, email := deref(args.Input.FirstName), deref(args.Input.LastName), deref(args.Input.Email)
+	if email != "" {
+		if err := validate.Var(email, "email"); err != nil {
+			return nil, &resolverError{code: "invalid_email", message: "email must be a valid email address"}
+		}
+	}
+
+	if err := r.authService.UpdateProfile(ctx, userID, firstName, lastName, email); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return r.user(ctx, userID)
+}
+
+// EnableTwoFactor turns email-based 2FA on for the authenticated user.
+func (r *rootResolver) EnableTwoFactor(ctx context.Context) (*userResolver, error) {
+	userID, err := r.currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.authService.EnableEmail2FA(ctx, userID); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return r.user(ctx, userID)
+}
+
+// DisableTwoFactor turns 2FA off for the authenticated user.
+func (r *rootResolver) DisableTwoFactor(ctx context.Context) (*userResolver, error) {
+	userID, err := r.currentUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.authService.Disable2FA(ctx, userID); err != nil {
+		return nil, toResolverError(ctx, err)
+	}
+	return r.user(ctx, userID)
+}
+
+// =============================================================================
+// Type Resolvers
+// =============================================================================
+
+// userResolver resolves the User type.
+type userResolver struct {
+	user             *response.UserResponse
+	twoFactorEnabled bool
+}
+
+func (u *userResolver) ID() graphql.ID          { return int64ID(u.user.ID) }
+func (u *userResolver) Email() string           { return u.user.Email }
+func (u *userResolver) Username() *string       { return optional(u.user.Username) }
+func (u *userResolver) FirstName() string       { return u.user.FirstName }
+func (u *userResolver) LastName() string        { return u.user.LastName }
+func (u *userResolver) IsActive() bool          { return u.user.IsActive }
+func (u *userResolver) Locale() *string         { return optional(u.user.Locale) }
+func (u *userResolver) AvatarURL() *string      { return u.user.AvatarURL }
+func (u *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: u.user.CreatedAt} }
+func (u *userResolver) TwoFactorEnabled() bool  { return u.twoFactorEnabled }
+func (u *userResolver) ConsentRequired() bool   { return u.user.ConsentRequired }
+
+// sessionResolver resolves the Session type.
+type sessionResolver struct {
+	session response.Session
+}
+
+func (s *sessionResolver) ID() graphql.ID          { return int64ID(s.session.ID) }
+func (s *sessionResolver) CreatedAt() graphql.Time { return graphql.Time{Time: s.session.CreatedAt} }
+
+func (s *sessionResolver) OrganizationID() *graphql.ID { return optionalID(s.session.OrganizationID) }
+func (s *sessionResolver) DeviceID() *graphql.ID       { return optionalID(s.session.DeviceID) }
+
+func (s *sessionResolver) ExpiresAt() *graphql.Time {
+	if s.session.ExpiresAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *s.session.ExpiresAt}
+}
+
+// loginEventResolver resolves the LoginEvent type.
+type loginEventResolver struct {
+	event response.LoginEvent
+}
+
+func (e *loginEventResolver) Success() bool           { return e.event.Success }
+func (e *loginEventResolver) Method() *string         { return optional(e.event.Method) }
+func (e *loginEventResolver) Reason() *string         { return optional(e.event.Reason) }
+func (e *loginEventResolver) IPAddress() *string      { return optional(e.event.IPAddress) }
+func (e *loginEventResolver) UserAgent() *string      { return optional(e.event.UserAgent) }
+func (e *loginEventResolver) DeviceID() *graphql.ID   { return optionalID(e.event.DeviceID) }
+func (e *loginEventResolver) CreatedAt() graphql.Time { return graphql.Time{Time: e.event.CreatedAt} }
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+// optionalID formats an optional database ID, nil staying null.
+func optionalID(id *int64) *graphql.ID {
+	if id == nil {
+		return nil
+	}
+	gid := int64ID(*id)
+	return &gid
+}
+
+// int64ID formats a database ID as a GraphQL ID.
+func int64ID(id int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(id, 10))
+}
+
+// optional maps an empty string to null.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// deref returns the value of s, or "" when it is nil.
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
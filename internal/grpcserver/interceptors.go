@@ -0,0 +1,155 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"authentio/internal/middleware"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+	"authentio/pkg/mtls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// tenantMetadataKey names the tenant slug of a call in its metadata.
+const tenantMetadataKey = "x-tenant"
+
+// =============================================================================
+// Unary Interceptors
+// =============================================================================
+
+// recoverPanics turns a panicking handler into an INTERNAL error instead of
+// crashing the server.
+func recoverPanics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("grpc handler panicked", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// logCalls logs each call with its status code and latency.
+func logCalls(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	logger.Info("grpc call",
+		"method", info.FullMethod,
+		"code", status.Code(err).String(),
+		"latency", time.Since(start),
+	)
+	return resp, err
+}
+
+// requireClientCert rejects calls to the methods policy covers unless they
+// were made with a verified and allowed client certificate.
+func requireClientCert(policy mtls.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !policy.Requires(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		identity, verified := peerCertificate(ctx)
+		if !verified {
+			return nil, status.Error(codes.Unauthenticated, "a client certificate is required")
+		}
+		if !policy.Allows(identity) {
+			logger.Warn("grpc call with a client certificate not allowed", "method", info.FullMethod, "peer", peerIP(ctx), "certificate", identity.Name(), "fingerprint", identity.Fingerprint)
+			return nil, status.Error(codes.PermissionDenied, "this client certificate is not allowed")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// requireAPIKey rejects calls that don't carry "authorization: Bearer <apiKey>"
+// metadata.
+func requireAPIKey(apiKey string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key, ok := strings.CutPrefix(firstMetadata(ctx, "authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			logger.Warn("grpc call with invalid API key", "method", info.FullMethod, "peer", peerIP(ctx))
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// resolveTenant scopes the call's context to the tenant named by the
+// x-tenant metadata, or the default tenant. Unknown tenants get NOT_FOUND.
+// The caller's IP and verified client certificate are added to the context.
+func resolveTenant(tenants *middleware.TenantResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		slug := firstMetadata(ctx, tenantMetadataKey)
+
+		tenantID, err := tenants.TenantID(ctx, slug)
+		if err != nil {
+			logger.Error("tenant lookup failed", "tenant", slug, "error", err)
+			return nil, status.Error(codes.Internal, "internal server error")
+		}
+		if tenantID == 0 {
+			return nil, status.Error(codes.NotFound, "unknown tenant")
+		}
+
+		ctx = requestctx.WithTenant(ctx, tenantID)
+		client := requestctx.ClientInfo{IP: peerIP(ctx)}
+		if identity, ok := peerCertificate(ctx); ok {
+			client.ClientCertificate = identity.Name()
+		}
+		ctx = requestctx.WithClientInfo(ctx, client)
+		return handler(ctx, req)
+	}
+}
+
+// =============================================================================
+// Metadata Helpers
+// =============================================================================
+
+// firstMetadata returns the first value of an incoming metadata key, or "".
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerIP returns the caller's IP address, or "" when unknown.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// peerCertificate returns the identity of the client certificate verified
+// during the caller's TLS handshake, or false without one.
+func peerCertificate(ctx context.Context) (mtls.Identity, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return mtls.Identity{}, false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return mtls.Identity{}, false
+	}
+	return mtls.VerifiedIdentity(&info.State)
+}
@@ -0,0 +1,173 @@
+// Package grpcserver serves the Authentio gRPC API (proto/authentio/v1) used
+// by internal services. It is a thin transport over the same AuthService as
+// the HTTP handlers.
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+
+	"authentio/internal/middleware"
+	"authentio/internal/service"
+	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+	"authentio/pkg/mtls"
+	"authentio/pkg/response"
+	authentiov1 "authentio/proto/authentio/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// =============================================================================
+// Server Structure and Constructor
+// =============================================================================
+
+// Server implements authentiov1.AuthServiceServer on top of AuthService.
+type Server struct {
+	authentiov1.UnimplementedAuthServiceServer
+
+	authService service.AuthService
+	blacklist   *middleware.TokenBlacklist // revoked access tokens; nil skips the check
+}
+
+// New creates a gRPC server exposing the Authentio API. Every call must
+// present apiKey and is scoped to the tenant named in its metadata. With a
+// TLS configuration, methods the client certificate policy covers also
+// require a verified client certificate (mTLS).
+//
+// Parameters:
+//   - authService: The service layer shared with the HTTP handlers
+//   - blacklist: Revoked access token store (nil when Redis is unavailable)
+//   - tenants: Resolver used to look up the tenant slug of each call
+//   - apiKey: Shared key internal services authenticate with (GRPC_API_KEY)
+//   - tlsConfig: Server TLS configuration (nil serves plaintext)
+//   - clientCerts: Methods requiring a client certificate and the identities accepted
+//
+// Returns:
+//   - *grpc.Server: Server with the API registered, ready to Serve
+func New(authService service.AuthService, blacklist *middleware.TokenBlacklist, tenants *middleware.TenantResolver, apiKey string, tlsConfig *tls.Config, clientCerts mtls.Policy) *grpc.Server {
+	opts := []grpc.ServerOption{grpc.ChainUnaryInterceptor(
+		recoverPanics,
+		logCalls,
+		requireClientCert(clientCerts),
+		requireAPIKey(apiKey),
+		resolveTenant(tenants),
+	)}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	srv := grpc.NewServer(opts...)
+
+	authentiov1.RegisterAuthServiceServer(srv, &Server{
+		authService: authService,
+		blacklist:   blacklist,
+	})
+
+	return srv
+}
+
+// =============================================================================
+// RPC Methods
+// =============================================================================
+
+// VerifyToken validates an access token and returns its claims.
+func (s *Server) VerifyToken(ctx context.Context, req *authentiov1.VerifyTokenRequest) (*authentiov1.VerifyTokenResponse, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access_token is required")
+	}
+
+	if s.blacklist != nil {
+		revoked, err := s.blacklist.IsBlacklisted(ctx, req.GetAccessToken())
+		if err != nil {
+			logger.Error("blacklist check failed", "error", err) // allow on Redis error, like the HTTP API
+		} else if revoked {
+			metrics.RevokedTokens.Inc()
+			return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+		}
+	}
+
+	info, err := s.authService.VerifyAccessToken(ctx, req.GetAccessToken())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &authentiov1.VerifyTokenResponse{
+		UserId:    info.UserID,
+		Email:     info.Email,
+		Role:      info.Role,
+		OrgId:     info.OrgID,
+		OrgRole:   info.OrgRole,
+		TenantId:  info.TenantID,
+		ExpiresAt: timestamppb.New(info.ExpiresAt),
+	}, nil
+}
+
+// GetUser looks a user up by ID or email.
+func (s *Server) GetUser(ctx context.Context, req *authentiov1.GetUserRequest) (*authentiov1.GetUserResponse, error) {
+	if req.GetUserId() == 0 && req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id or email is required")
+	}
+
+	user, err := s.authService.LookupUser(ctx, req.GetUserId(), req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &authentiov1.GetUserResponse{User: toProtoUser(user)}, nil
+}
+
+// GetTwoFactorStatus reports whether a user has 2FA enabled.
+func (s *Server) GetTwoFactorStatus(ctx context.Context, req *authentiov1.GetTwoFactorStatusRequest) (*authentiov1.GetTwoFactorStatusResponse, error) {
+	if req.GetUserId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	enabled, err := s.authService.TwoFactorStatus(ctx, req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &authentiov1.GetTwoFactorStatusResponse{Enabled: enabled}, nil
+}
+
+// =============================================================================
+// Conversion Helpers
+// =============================================================================
+
+// toProtoUser converts a user response to its protobuf message.
+func toProtoUser(user *response.UserResponse) *authentiov1.User {
+	return &authentiov1.User{
+		Id:             user.ID,
+		Email:          user.Email,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		IsActive:       user.IsActive,
+		Locale:         user.Locale,
+		ApprovalStatus: user.ApprovalStatus,
+		CreatedAt:      timestamppb.New(user.CreatedAt),
+	}
+}
+
+// toStatus maps service errors to gRPC status errors carrying the service
+// error message. Unexpected errors are logged and reported as INTERNAL.
+func toStatus(err error) error {
+	var serviceErr *service.ServiceError
+	if !errors.As(err, &serviceErr) {
+		logger.Error("grpc call failed", "error", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	code := codes.Internal
+	switch {
+	case errors.Is(err, service.ErrInvalidAccessToken), errors.Is(err, service.ErrTokenOutdated):
+		code = codes.Unauthenticated
+	case errors.Is(err, service.ErrUserNotFound):
+		code = codes.NotFound
+	}
+	return status.Error(code, serviceErr.Message)
+}
@@ -0,0 +1,1538 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/service"
+	"authentio/pkg/email"
+	"authentio/pkg/export"
+	"authentio/pkg/pagination"
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// AdminHandler Structure and Constructor
+// =============================================================================
+
+// AdminHandler handles administrative HTTP requests. All routes are expected
+// to be mounted behind AuthRequired and RequireRole("admin").
+type AdminHandler struct {
+	authService service.AuthService
+	emailQueue  *email.Queue
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(authService service.AuthService, emailQueue *email.Queue) *AdminHandler {
+	return &AdminHandler{
+		authService: authService,
+		emailQueue:  emailQueue,
+	}
+}
+
+// =============================================================================
+// User Administration Endpoints
+// =============================================================================
+
+// AnonymizeUser godoc
+// @Summary Anonymize a user
+// @Description Honor an erasure request by scrubbing a user's personal data while keeping aggregate records
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "User anonymized successfully"
+// @Failure 400 {object} map[string]string "Invalid user ID or user not found"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/users/{id}/anonymize [post]
+func (h *AdminHandler) AnonymizeUser(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.authService.AnonymizeUser(c.Request.Context(), targetID, c.GetInt64("userID")); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondMessage(c, "user_anonymized")
+}
+
+// EditUserMetadata godoc
+// @Summary Edit a user's metadata
+// @Description Merge keys into a user's user_metadata and/or app_metadata. Keys set to null are removed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body models.AdminMetadataRequest true "Metadata changes"
+// @Success 200 {object} response.Envelope{data=response.UserResponse} "Updated user"
+// @Failure 400 {object} map[string]string "Invalid input or metadata too large"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/metadata [patch]
+func (h *AdminHandler) EditUserMetadata(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req models.AdminMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	user, err := h.authService.AdminUpdateMetadata(c.Request.Context(), targetID, c.GetInt64("userID"), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrMetadataTooLarge):
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, user)
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description List the tenant's users, newest first, one page at a time. Pass meta.next_cursor back as cursor to get the following page; it is omitted on the last one.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "meta.next_cursor of the previous page"
+// @Success 200 {object} response.Envelope{data=[]response.UserResponse,meta=response.PageMeta} "Page of users"
+// @Failure 400 {object} map[string]string "Invalid limit or cursor"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	page, err := pagination.NewRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	users, err := h.authService.ListUsers(c.Request.Context(), page)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.Page(c, users)
+}
+
+// SearchUsers godoc
+// @Summary Search users
+// @Description Search the tenant's users by email, first and last name, and username. Matches may be partial or misspelled; exact email and username matches come first, then the best-ranked matches.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search text (2-100 characters)" minlength(2) maxlength(100)
+// @Param limit query int false "Maximum number of results (1-100)" default(20) minimum(1) maximum(100)
+// @Success 200 {object} response.Envelope{data=[]response.UserResponse} "Matching users, best first"
+// @Failure 400 {object} map[string]string "Missing or invalid search text"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/users/search [get]
+func (h *AdminHandler) SearchUsers(c *gin.Context) {
+	var req struct {
+		Query string `form:"q" validate:"required,min=2,max=100"`
+		Limit int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	users, err := h.authService.SearchUsers(c.Request.Context(), req.Query, req.Limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, users)
+}
+
+// GetStats godoc
+// @Summary Get user and sign-in statistics
+// @Description Aggregate the tenant's users (total, locked out, 2FA adoption, per sign-up provider) and the sign-ins of the last days UTC days, today included: daily sign-ups, daily active users, successful and failed logins and the failure rate. Failed sign-ins with an unknown email are not counted.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Number of days, today included (1-90)" default(30) minimum(1) maximum(90)
+// @Success 200 {object} response.Envelope{data=response.AdminStats} "Statistics"
+// @Failure 400 {object} map[string]string "Invalid number of days"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/stats [get]
+func (h *AdminHandler) GetStats(c *gin.Context) {
+	var req struct {
+		Days int `form:"days" validate:"omitempty,min=1,max=90"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 30
+	}
+
+	stats, err := h.authService.AdminStats(c.Request.Context(), req.Days)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, stats)
+}
+
+// GetCountryStats godoc
+// @Summary Get sign-in statistics per country
+// @Description Count the tenant's successful and failed sign-ins per country of the client (resolved by GeoIP) over the last days UTC days, today included, most attempts first, with one entry per day for each country. Unlike the sign-ins of /admin/stats, failed sign-ins with an unknown email are counted, so credential stuffing campaigns show up as spikes from a few countries.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Number of days, today included (1-90)" default(30) minimum(1) maximum(90)
+// @Success 200 {object} response.Envelope{data=response.CountryStats} "Statistics per country"
+// @Failure 400 {object} map[string]string "Invalid number of days"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/stats/countries [get]
+func (h *AdminHandler) GetCountryStats(c *gin.Context) {
+	var req struct {
+		Days int `form:"days" validate:"omitempty,min=1,max=90"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 30
+	}
+
+	stats, err := h.authService.LoginCountryStats(c.Request.Context(), req.Days)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, stats)
+}
+
+// GetUserAuditLog godoc
+// @Summary Get a user's audit log
+// @Description List the audit log entries about a user (sign-ins, password and 2FA changes, admin actions...), newest first, one page at a time. Pass meta.next_cursor back as cursor to get the following page; it is omitted on the last one.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "meta.next_cursor of the previous page"
+// @Success 200 {object} response.Envelope{data=[]models.AuditLog,meta=response.PageMeta} "Page of audit log entries"
+// @Failure 400 {object} map[string]string "Invalid user ID, limit or cursor"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/audit-logs [get]
+func (h *AdminHandler) GetUserAuditLog(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	page, err := pagination.NewRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.authService.UserAuditLog(c.Request.Context(), targetID, page)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.Page(c, entries)
+}
+
+// ListPendingUsers godoc
+// @Summary List registrations awaiting approval
+// @Description Users who registered while REQUIRE_REGISTRATION_APPROVAL was enabled and have not been reviewed yet, oldest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]response.UserResponse} "Pending users"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/users/pending [get]
+func (h *AdminHandler) ListPendingUsers(c *gin.Context) {
+	users, err := h.authService.ListPendingUsers(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, users)
+}
+
+// ApproveUser godoc
+// @Summary Approve a pending registration
+// @Description Let a pending user log in. The user is emailed about the decision.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "User approved"
+// @Failure 400 {object} map[string]string "Invalid user ID or user is not awaiting approval"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/approve [post]
+func (h *AdminHandler) ApproveUser(c *gin.Context) {
+	h.reviewUser(c, h.authService.ApproveUser, "user_approved")
+}
+
+// RejectUser godoc
+// @Summary Reject a pending registration
+// @Description Refuse a pending registration; the account stays blocked from logging in. The user is emailed about the decision.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "User rejected"
+// @Failure 400 {object} map[string]string "Invalid user ID or user is not awaiting approval"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/reject [post]
+func (h *AdminHandler) RejectUser(c *gin.Context) {
+	h.reviewUser(c, h.authService.RejectUser, "user_rejected")
+}
+
+// reviewUser applies an approval decision to the user in the :id parameter
+// and confirms it with the messageKey message.
+func (h *AdminHandler) reviewUser(c *gin.Context, decide func(ctx context.Context, userID, actorID int64) error, messageKey string) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := decide(c.Request.Context(), targetID, c.GetInt64("userID")); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	respondMessage(c, messageKey)
+}
+
+// =============================================================================
+// Registration Domain Rule Endpoints
+// =============================================================================
+
+// ListEmailDomainRules godoc
+// @Summary List registration domain rules
+// @Description Allow/block rules for registration email domains, from the environment (source "env") and the admin API (source "admin")
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.EmailDomainRule} "Domain rules"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/email-domains [get]
+func (h *AdminHandler) ListEmailDomainRules(c *gin.Context) {
+	rules, err := h.authService.ListEmailDomainRules(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, rules)
+}
+
+// AddEmailDomainRule godoc
+// @Summary Add a registration domain rule
+// @Description Allow or block registrations from an email domain (and its subdomains). Once any allow rule exists, only allowed domains can sign up.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.EmailDomainRuleRequest true "Domain and rule (allow or block)"
+// @Success 201 {object} response.Envelope{data=models.EmailDomainRule} "Rule added"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/email-domains [post]
+func (h *AdminHandler) AddEmailDomainRule(c *gin.Context) {
+	var req models.EmailDomainRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	rule, err := h.authService.AddEmailDomainRule(c.Request.Context(), req, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, rule)
+}
+
+// DeleteEmailDomainRule godoc
+// @Summary Delete a registration domain rule
+// @Description Remove a rule added through the admin API. Environment rules can't be deleted.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Rule ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Rule deleted"
+// @Failure 400 {object} map[string]string "Invalid rule ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Rule not found"
+// @Router /admin/email-domains/{id} [delete]
+func (h *AdminHandler) DeleteEmailDomainRule(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+		return
+	}
+
+	if err := h.authService.DeleteEmailDomainRule(c.Request.Context(), id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDomainRuleNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	respondMessage(c, "rule_deleted")
+}
+
+// =============================================================================
+// Email Queue Administration Endpoints
+// =============================================================================
+
+// ListFailedEmails godoc
+// @Summary List dead-lettered emails
+// @Description Queue statistics and emails that exhausted their delivery attempts, most recent first. Bodies are omitted.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum number of jobs to return" default(50)
+// @Success 200 {object} response.Envelope{data=map[string]interface{}} "Queue stats and dead-lettered jobs"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 503 {object} map[string]string "Email queue unavailable"
+// @Router /admin/emails/failed [get]
+func (h *AdminHandler) ListFailedEmails(c *gin.Context) {
+	if !h.requireEmailQueue(c) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+
+	stats, err := h.emailQueue.Stats(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	jobs, err := h.emailQueue.DeadLetters(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, gin.H{"stats": stats, "jobs": jobs})
+}
+
+// RetryFailedEmail godoc
+// @Summary Retry a dead-lettered email
+// @Description Move a dead-lettered email back to the delivery queue with a fresh attempt budget
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Job ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Email requeued"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Job not found"
+// @Failure 503 {object} map[string]string "Email queue unavailable"
+// @Router /admin/emails/failed/{id}/retry [post]
+func (h *AdminHandler) RetryFailedEmail(c *gin.Context) {
+	if !h.requireEmailQueue(c) {
+		return
+	}
+
+	if err := h.emailQueue.RetryDeadLetter(c.Request.Context(), c.Param("id")); err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	respondMessage(c, "email_requeued")
+}
+
+// DeleteFailedEmail godoc
+// @Summary Discard a dead-lettered email
+// @Description Permanently remove an email from the dead-letter list
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Job ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Email discarded"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Job not found"
+// @Failure 503 {object} map[string]string "Email queue unavailable"
+// @Router /admin/emails/failed/{id} [delete]
+func (h *AdminHandler) DeleteFailedEmail(c *gin.Context) {
+	if !h.requireEmailQueue(c) {
+		return
+	}
+
+	if err := h.emailQueue.DeleteDeadLetter(c.Request.Context(), c.Param("id")); err != nil {
+		respondJobError(c, err)
+		return
+	}
+
+	respondMessage(c, "email_discarded")
+}
+
+// SendTestEmail godoc
+// @Summary Send a test email
+// @Description Send a test email to an address through the configured email provider, to check delivery. Without the delivery queue the provider's answer is known at once; with it the email is only queued (queued is true) and its outcome shows in the email delivery log under message_id.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.EmailTestRequest true "Recipient"
+// @Success 200 {object} response.Envelope{data=models.EmailTestResult} "Email sent or queued"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 502 {object} map[string]string "The email provider refused the email"
+// @Router /admin/email/test [post]
+func (h *AdminHandler) SendTestEmail(c *gin.Context) {
+	var req models.EmailTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	result, err := h.authService.SendTestEmail(c.Request.Context(), c.GetInt64("userID"), req)
+	if err != nil {
+		respondError(c, emailTestErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, result)
+}
+
+// =============================================================================
+// Email Delivery Log Endpoints
+// =============================================================================
+
+// ListEmailLog godoc
+// @Summary List the email delivery log
+// @Description Delivery records of the emails sent to the tenant's users, one per recipient, newest first: the event that triggered the email, its status (queued, sent, delivered, failed, bounced or dropped), attempts, and the delivery and open times reported by the provider. Pass meta.next_cursor back as cursor to get the following page.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "Only emails sent to this user"
+// @Param message_id query string false "Only the recipients of this email"
+// @Param recipient query string false "Only emails sent to this address"
+// @Param event query string false "Only emails triggered by this event (e.g. password_reset)"
+// @Param status query string false "Only emails with this status" Enums(queued, sent, delivered, failed, bounced, dropped)
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "meta.next_cursor of the previous page"
+// @Success 200 {object} response.Envelope{data=[]models.EmailLog,meta=response.PageMeta} "Page of delivery records"
+// @Failure 400 {object} map[string]string "Invalid filter, limit or cursor"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/emails [get]
+func (h *AdminHandler) ListEmailLog(c *gin.Context) {
+	var query struct {
+		UserID    int64  `form:"user_id"`
+		MessageID string `form:"message_id"`
+		Recipient string `form:"recipient"`
+		Event     string `form:"event"`
+		Status    string `form:"status"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid filter"})
+		return
+	}
+	page, err := pagination.NewRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.authService.EmailLog(c.Request.Context(), models.EmailLogFilter{
+		UserID:    query.UserID,
+		MessageID: query.MessageID,
+		Recipient: query.Recipient,
+		Event:     query.Event,
+		Status:    query.Status,
+	}, page)
+	if err != nil {
+		respondError(c, emailLogErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.Page(c, entries)
+}
+
+// SendGridEvents godoc
+// @Summary Receive SendGrid delivery events
+// @Description Endpoint of SendGrid's signed event webhook. Deliveries, bounces, drops and opens of the emails Authentio sent are recorded in the email delivery log; other events are ignored. Requests are authenticated by their signature, checked with SENDGRID_WEBHOOK_PUBLIC_KEY.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-Twilio-Email-Event-Webhook-Signature header string true "Signature of the payload"
+// @Param X-Twilio-Email-Event-Webhook-Timestamp header string true "Timestamp of the signature"
+// @Param events body []map[string]interface{} true "SendGrid events"
+// @Success 200 {object} response.Envelope{data=map[string]int} "Number of events recorded"
+// @Failure 401 {object} map[string]string "Invalid signature"
+// @Failure 503 {object} map[string]string "Webhook not configured"
+// @Router /webhooks/email/sendgrid [post]
+func (h *AdminHandler) SendGridEvents(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	recorded, err := h.authService.RecordSendGridEvents(c.Request.Context(), payload,
+		c.GetHeader(email.SendGridSignatureHeader), c.GetHeader(email.SendGridTimestampHeader))
+	if err != nil {
+		respondError(c, emailLogErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, gin.H{"recorded": recorded})
+}
+
+// =============================================================================
+// Email Template Endpoints
+// =============================================================================
+
+// ListEmailTemplates godoc
+// @Summary List email templates
+// @Description Every email template with the saved version in use; version 0 means the embedded default is used.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.EmailTemplateSummary} "Email templates"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/email-templates [get]
+func (h *AdminHandler) ListEmailTemplates(c *gin.Context) {
+	templates, err := h.authService.EmailTemplates(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, templates)
+}
+
+// GetEmailTemplate godoc
+// @Summary Get an email template
+// @Description The source of the embedded default of an email template and its saved versions, newest first. The active version, if any, is the one in use.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Template name, e.g. welcome"
+// @Success 200 {object} response.Envelope{data=models.EmailTemplateDetails} "Email template"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Unknown template"
+// @Router /admin/email-templates/{name} [get]
+func (h *AdminHandler) GetEmailTemplate(c *gin.Context) {
+	details, err := h.authService.EmailTemplate(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		respondError(c, emailTemplateErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, details)
+}
+
+// SaveEmailTemplate godoc
+// @Summary Save an email template
+// @Description Save a new version of an email template and put it in use at once. Subject and content use Go html/template syntax with the fields of the template (see the default), {{appName}}, {{year}}, the tenant's {{brand}} and the shared partials; the content is placed in the common layout. The version must render with sample data.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Template name, e.g. welcome"
+// @Param request body models.EmailTemplateRequest true "Subject and content"
+// @Success 200 {object} response.Envelope{data=models.EmailTemplate} "Saved version"
+// @Failure 400 {object} map[string]string "Invalid input or template that can't be rendered"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Unknown template"
+// @Router /admin/email-templates/{name} [put]
+func (h *AdminHandler) SaveEmailTemplate(c *gin.Context) {
+	var req models.EmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	tmpl, err := h.authService.SaveEmailTemplate(c.Request.Context(), c.Param("name"), req, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, emailTemplateErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, tmpl)
+}
+
+// ActivateEmailTemplate godoc
+// @Summary Activate an email template version
+// @Description Put a saved version of an email template back in use, e.g. to roll back an edit
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Template name, e.g. welcome"
+// @Param version path int true "Version"
+// @Success 200 {object} response.Envelope{data=models.EmailTemplate} "Active version"
+// @Failure 400 {object} map[string]string "Invalid version"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Unknown template or version"
+// @Router /admin/email-templates/{name}/versions/{version}/activate [post]
+func (h *AdminHandler) ActivateEmailTemplate(c *gin.Context) {
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	tmpl, err := h.authService.ActivateEmailTemplate(c.Request.Context(), c.Param("name"), version, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, emailTemplateErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, tmpl)
+}
+
+// ResetEmailTemplate godoc
+// @Summary Reset an email template
+// @Description Put the embedded default of an email template back in use. Saved versions are kept and can be activated again.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Template name, e.g. welcome"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Template reset"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Unknown template"
+// @Router /admin/email-templates/{name} [delete]
+func (h *AdminHandler) ResetEmailTemplate(c *gin.Context) {
+	if err := h.authService.ResetEmailTemplate(c.Request.Context(), c.Param("name"), c.GetInt64("userID")); err != nil {
+		respondError(c, emailTemplateErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "template_reset")
+}
+
+// GetEmailBranding godoc
+// @Summary Get the email branding
+// @Description Get the sender, logo, colors and footer of the tenant's emails. Empty fields use the deployment defaults.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.TenantBranding} "Email branding"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/email-branding [get]
+func (h *AdminHandler) GetEmailBranding(c *gin.Context) {
+	branding, err := h.authService.EmailBranding(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, branding)
+}
+
+// UpdateEmailBranding godoc
+// @Summary Update the email branding
+// @Description Replace the sender, logo, colors and footer of the tenant's emails. Empty or omitted fields use the deployment defaults. The sender name replaces {{appName}} in templates; the logo must be an https URL and colors hex CSS colors.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TenantBranding true "Email branding"
+// @Success 200 {object} response.Envelope{data=models.TenantBranding} "Saved branding"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/email-branding [put]
+func (h *AdminHandler) UpdateEmailBranding(c *gin.Context) {
+	var req models.TenantBranding
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	branding, err := h.authService.UpdateEmailBranding(c.Request.Context(), req, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, emailBrandingErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, branding)
+}
+
+// GetGeoPolicy godoc
+// @Summary Get the geo policy
+// @Description Get the geographic sign-in rules of the tenant: blocked, allowed and suspicious countries and the maximum travel speed between two sign-ins of a user, with what happens to sign-ins that break them. source is "config" while the tenant uses the deployment's defaults.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.GeoPolicy} "Geo policy"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/geo-policy [get]
+func (h *AdminHandler) GetGeoPolicy(c *gin.Context) {
+	policy, err := h.authService.GeoPolicy(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, policy)
+}
+
+// UpdateGeoPolicy godoc
+// @Summary Update the geo policy
+// @Description Replace the geographic sign-in rules of the tenant. Blocked countries, and countries outside allowed_countries when it isn't empty, are refused on every request. Sign-ins from suspicious countries get suspicious_action (log, step_up or block; default log), and sign-ins implying travel faster than max_travel_speed_kmh since the user's previous one get velocity_action (default step_up). Changes apply within 30 seconds on every server.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateGeoPolicyRequest true "Geo policy"
+// @Success 200 {object} response.Envelope{data=models.GeoPolicy} "Saved policy"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 409 {object} map[string]string "The policy would refuse the caller's own country"
+// @Router /admin/geo-policy [put]
+func (h *AdminHandler) UpdateGeoPolicy(c *gin.Context) {
+	var req models.UpdateGeoPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	policy, err := h.authService.UpdateGeoPolicy(c.Request.Context(), c.GetInt64("userID"), req)
+	if err != nil {
+		respondError(c, geoPolicyErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, policy)
+}
+
+// ResetGeoPolicy godoc
+// @Summary Reset the geo policy
+// @Description Remove the tenant's geographic sign-in rules, so the deployment's defaults (BLOCKED_COUNTRIES, ALLOWED_COUNTRIES, SUSPICIOUS_COUNTRIES and GEO_* settings) apply, and return them.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.GeoPolicy} "Default policy"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 409 {object} map[string]string "The defaults would refuse the caller's own country"
+// @Router /admin/geo-policy [delete]
+func (h *AdminHandler) ResetGeoPolicy(c *gin.Context) {
+	policy, err := h.authService.ResetGeoPolicy(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, geoPolicyErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, policy)
+}
+
+// PreviewEmailTemplate godoc
+// @Summary Preview an email template
+// @Description Render an email template with sample data: the version in use, or a draft when subject or content are sent (omitted fields use the version in use). Nothing is saved.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Template name, e.g. welcome"
+// @Param request body models.EmailTemplatePreviewRequest false "Draft subject and content"
+// @Success 200 {object} response.Envelope{data=email.Message} "Rendered subject and HTML"
+// @Failure 400 {object} map[string]string "Invalid input or template that can't be rendered"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Unknown template"
+// @Router /admin/email-templates/{name}/preview [post]
+func (h *AdminHandler) PreviewEmailTemplate(c *gin.Context) {
+	var req models.EmailTemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	msg, err := h.authService.PreviewEmailTemplate(c.Request.Context(), c.Param("name"), req)
+	if err != nil {
+		respondError(c, emailTemplateErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, msg)
+}
+
+// =============================================================================
+// Tenant Quota Endpoints
+// =============================================================================
+
+// GetQuotaUsage godoc
+// @Summary Get tenant quota usage
+// @Description Daily usage and limits of the current tenant's quotas (requests, registrations, OTP emails). A limit of 0 means unlimited. Usage is kept for a week.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param date query string false "UTC day (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} response.Envelope{data=map[string]interface{}} "Date and usage per metric"
+// @Failure 400 {object} map[string]string "Invalid date"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 503 {object} map[string]string "Quota tracking unavailable"
+// @Router /admin/quotas [get]
+func (h *AdminHandler) GetQuotaUsage(c *gin.Context) {
+	day := time.Now().UTC()
+	if date := c.Query("date"); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+
+	usage, err := h.authService.QuotaUsage(c.Request.Context(), day)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrQuotasUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, gin.H{"date": day.Format("2006-01-02"), "usage": usage})
+}
+
+// =============================================================================
+// IP Allowlist Endpoints
+// =============================================================================
+
+// AdminGetIPAllowlist godoc
+// @Summary Get a user's IP allowlist
+// @Description The addresses and CIDR blocks a user's account may be used from, and the mode applied to logins from anywhere else
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} response.Envelope{data=models.IPAllowlist} "Allowlist"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/ip-allowlist [get]
+func (h *AdminHandler) AdminGetIPAllowlist(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	list, err := h.authService.GetIPAllowlist(c.Request.Context(), targetID)
+	if err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, list)
+}
+
+// AdminAddIPAllowlistEntry godoc
+// @Summary Add an entry to a user's IP allowlist
+// @Description Allow an address or CIDR block for a user's account. Once it has an entry, the account can't be used from other addresses.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body models.AddIPAllowlistEntryRequest true "Address or CIDR block"
+// @Success 201 {object} response.Envelope{data=models.IPAllowlistEntry} "Entry added"
+// @Failure 400 {object} map[string]string "Invalid user ID, address or CIDR block"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/ip-allowlist [post]
+func (h *AdminHandler) AdminAddIPAllowlistEntry(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req models.AddIPAllowlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	entry, err := h.authService.AddIPAllowlistEntry(c.Request.Context(), targetID, c.GetInt64("userID"), req)
+	if err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, entry)
+}
+
+// AdminRemoveIPAllowlistEntry godoc
+// @Summary Remove an entry from a user's IP allowlist
+// @Description Removing the last permanent entry lets the account be used from anywhere
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param entryId path int true "Entry ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Entry removed"
+// @Failure 400 {object} map[string]string "Invalid user or entry ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User or entry not found"
+// @Router /admin/users/{id}/ip-allowlist/{entryId} [delete]
+func (h *AdminHandler) AdminRemoveIPAllowlistEntry(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	entryID, err := strconv.ParseInt(c.Param("entryId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry id"})
+		return
+	}
+
+	if err := h.authService.RemoveIPAllowlistEntry(c.Request.Context(), targetID, c.GetInt64("userID"), entryID); err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "allowlist_entry_removed")
+}
+
+// AdminSetIPAllowlistMode godoc
+// @Summary Set a user's IP allowlist mode
+// @Description Choose what happens to the user's logins from outside their allowlist: "block" refuses them, "verify" requires a second factor
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body models.IPAllowlistModeRequest true "Mode"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Mode updated"
+// @Failure 400 {object} map[string]string "Invalid user ID or mode"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/ip-allowlist/mode [put]
+func (h *AdminHandler) AdminSetIPAllowlistMode(c *gin.Context) {
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req models.IPAllowlistModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.SetIPAllowlistMode(c.Request.Context(), targetID, c.GetInt64("userID"), req.Mode); err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "allowlist_mode_updated")
+}
+
+// =============================================================================
+// IP Denylist Endpoints
+// =============================================================================
+
+// ListIPDenylist godoc
+// @Summary List the IP denylist
+// @Description Addresses and CIDR blocks whose requests are refused on every route, in every tenant. Expired entries are left out. Only admins of the default tenant can manage the denylist.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.IPDenylistEntry} "Entries"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role or default tenant required"
+// @Router /admin/ip-denylist [get]
+func (h *AdminHandler) ListIPDenylist(c *gin.Context) {
+	entries, err := h.authService.ListIPDenylist(c.Request.Context())
+	if err != nil {
+		respondError(c, ipDenylistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, entries)
+}
+
+// AddIPDenylistEntry godoc
+// @Summary Deny an IP address or CIDR block
+// @Description Refuse every request from an address or CIDR block with 403, for ttl_seconds or until removed. Denying a block again replaces its reason and expiry. Other server instances apply the change within 30 seconds.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AddIPDenylistEntryRequest true "Address or CIDR block"
+// @Success 201 {object} response.Envelope{data=models.IPDenylistEntry} "Entry added"
+// @Failure 400 {object} map[string]string "Invalid address, CIDR block or TTL"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role or default tenant required"
+// @Failure 409 {object} map[string]string "The block contains the caller's address"
+// @Router /admin/ip-denylist [post]
+func (h *AdminHandler) AddIPDenylistEntry(c *gin.Context) {
+	var req models.AddIPDenylistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	entry, err := h.authService.AddIPDenylistEntry(c.Request.Context(), c.GetInt64("userID"), req)
+	if err != nil {
+		respondError(c, ipDenylistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, entry)
+}
+
+// RemoveIPDenylistEntry godoc
+// @Summary Remove an IP denylist entry
+// @Description Stop refusing requests from a denied block
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Entry ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Entry removed"
+// @Failure 400 {object} map[string]string "Invalid entry ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role or default tenant required"
+// @Failure 404 {object} map[string]string "Entry not found"
+// @Router /admin/ip-denylist/{id} [delete]
+func (h *AdminHandler) RemoveIPDenylistEntry(c *gin.Context) {
+	entryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry id"})
+		return
+	}
+
+	if err := h.authService.RemoveIPDenylistEntry(c.Request.Context(), c.GetInt64("userID"), entryID); err != nil {
+		respondError(c, ipDenylistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "denylist_entry_removed")
+}
+
+// =============================================================================
+// User Export Endpoints
+// =============================================================================
+
+// StartUserExport godoc
+// @Summary Export users
+// @Description Start exporting the tenant's users matching a filter (sign-up provider, creation range, active status) as CSV or JSON. The file is generated in the background: poll the returned export until its status is ready, then download it from its signed download_url. Exports are deleted after EXPORT_TTL.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UserExportRequest true "Format and filter"
+// @Success 202 {object} response.Envelope{data=response.UserExport} "Export started"
+// @Failure 400 {object} map[string]string "Invalid format or filter"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 503 {object} map[string]string "Exports unavailable (no Redis)"
+// @Router /admin/users/exports [post]
+func (h *AdminHandler) StartUserExport(c *gin.Context) {
+	var req models.UserExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	userExport, err := h.authService.StartUserExport(c.Request.Context(), req, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, exportErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusAccepted, userExport)
+}
+
+// GetUserExport godoc
+// @Summary Get a user export
+// @Description Get the status of a user export. Once it is ready, download_url is a signed link to the file, valid until download_expires_at (EXPORT_LINK_TTL); get the export again for a fresh link.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Export ID"
+// @Success 200 {object} response.Envelope{data=response.UserExport} "Export"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Export not found or expired"
+// @Failure 503 {object} map[string]string "Exports unavailable (no Redis)"
+// @Router /admin/users/exports/{id} [get]
+func (h *AdminHandler) GetUserExport(c *gin.Context) {
+	userExport, err := h.authService.UserExport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, exportErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, userExport)
+}
+
+// DownloadUserExport godoc
+// @Summary Download a user export
+// @Description Download the file of a ready user export with the signed link from its download_url. No access token is needed: the link itself grants access until it expires.
+// @Tags admin
+// @Produce text/csv
+// @Produce json
+// @Param id path string true "Export ID"
+// @Param token query string true "Signature of the download link"
+// @Success 200 {file} file "CSV or JSON file"
+// @Failure 403 {object} map[string]string "Invalid or expired download link"
+// @Failure 404 {object} map[string]string "Export not found or expired"
+// @Failure 409 {object} map[string]string "Export not ready yet"
+// @Failure 503 {object} map[string]string "Exports unavailable (no Redis)"
+// @Router /exports/{id}/download [get]
+func (h *AdminHandler) DownloadUserExport(c *gin.Context) {
+	job, file, err := h.authService.DownloadUserExport(c.Request.Context(), c.Param("id"), c.Query("token"))
+	if err != nil {
+		respondError(c, exportErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	filename := fmt.Sprintf("users-%s.%s", job.CreatedAt.Format("20060102-150405"), job.Format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, export.ContentType(job.Format), file)
+}
+
+// =============================================================================
+// Duplicate Account Endpoints
+// =============================================================================
+
+// ListDuplicateAccounts godoc
+// @Summary List likely duplicate accounts
+// @Description Groups of the tenant's accounts that likely belong to the same person, newest first: accounts whose emails match once lowercased and stripped of +tags (and of dots, at Gmail), that share a phone number, or that share an identity provider subject. Groups are refreshed by a scan every DUPLICATE_SCAN_INTERVAL. Clean up with the delete and anonymize endpoints, or dismiss groups that aren't duplicates. Pass meta.next_cursor back as cursor to get the following page.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Only groups with this status" Enums(open, dismissed)
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "meta.next_cursor of the previous page"
+// @Success 200 {object} response.Envelope{data=[]models.DuplicateAccountGroup,meta=response.PageMeta} "Page of duplicate groups"
+// @Failure 400 {object} map[string]string "Invalid status, limit or cursor"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/duplicate-accounts [get]
+func (h *AdminHandler) ListDuplicateAccounts(c *gin.Context) {
+	page, err := pagination.NewRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	groups, err := h.authService.DuplicateAccounts(c.Request.Context(), c.Query("status"), page)
+	if err != nil {
+		respondError(c, duplicateAccountErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.Page(c, groups)
+}
+
+// ScanDuplicateAccounts godoc
+// @Summary Scan for duplicate accounts
+// @Description Look for likely duplicate accounts now instead of waiting for the next scheduled scan. Dismissed groups stay dismissed unless another account joined them; groups whose accounts no longer match are removed.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.DuplicateScanResult} "Groups and accounts found"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/duplicate-accounts/scan [post]
+func (h *AdminHandler) ScanDuplicateAccounts(c *gin.Context) {
+	result, err := h.authService.ScanDuplicateAccounts(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, result)
+}
+
+// DismissDuplicateAccounts godoc
+// @Summary Dismiss a duplicate account group
+// @Description Mark a group as not duplicates. It leaves the open report until a scan finds another account matching it.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Group ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Group dismissed"
+// @Failure 400 {object} map[string]string "Invalid group ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Group not found"
+// @Router /admin/duplicate-accounts/{id}/dismiss [post]
+func (h *AdminHandler) DismissDuplicateAccounts(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	if err := h.authService.DismissDuplicateAccounts(c.Request.Context(), id, c.GetInt64("userID")); err != nil {
+		respondError(c, duplicateAccountErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "duplicates_dismissed")
+}
+
+// =============================================================================
+// Key Rotation Endpoints
+// =============================================================================
+
+// KeyRotationStatus godoc
+// @Summary Get key rotation status
+// @Description The keys in use for each purpose (jwt, oidc, secrets), whether configured or generated by a rotation, with their state: active keys sign or encrypt, verify-only keys only verify the tokens they signed or decrypt the values they encrypted. Also the 20 latest rotations with the progress of their re-encryption job.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.KeyRotationStatus} "Keys and rotations"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required, or not the default tenant"
+// @Router /admin/keys [get]
+func (h *AdminHandler) KeyRotationStatus(c *gin.Context) {
+	status, err := h.authService.KeyRotationStatus(c.Request.Context())
+	if err != nil {
+		respondError(c, keyRotationErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, status)
+}
+
+// RotateKey godoc
+// @Summary Rotate a key
+// @Description Generate a new key and make it the active one; the previous key becomes verify-only. jwt rotates the secret signing API tokens (tokens it signed keep verifying until they expire), oidc the RSA key of OpenID Connect tokens (kept in the JWKS as long), and secrets the master key of encrypted columns, after which a background job re-encrypts the stored values in batches; follow its progress with GET /admin/keys. Generated keys are stored encrypted with the configured master key, which is required.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param purpose path string true "Key to rotate" Enums(jwt, oidc, secrets)
+// @Success 202 {object} response.Envelope{data=models.KeyRotation} "Rotation started"
+// @Failure 400 {object} map[string]string "Unknown key"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required, or not the default tenant"
+// @Failure 409 {object} map[string]string "A rotation of this key is already running"
+// @Failure 503 {object} map[string]string "No master key, or the key's feature is disabled"
+// @Router /admin/keys/{purpose}/rotate [post]
+func (h *AdminHandler) RotateKey(c *gin.Context) {
+	rotation, err := h.authService.RotateKey(c.Request.Context(), c.Param("purpose"), c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, keyRotationErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusAccepted, rotation)
+}
+
+// =============================================================================
+// Scheduler Endpoints
+// =============================================================================
+
+// SchedulerStatus godoc
+// @Summary Get scheduler status
+// @Description The server elected to run the scheduled jobs (cleanup, duplicate account scan, onboarding emails, password rotation reminders) and when its lease expires unless it extends it, the server answering, and the scheduled jobs with their interval. leader is empty while a new leader is being elected after the previous one died. Without Redis, servers aren't coordinated (coordinated is false) and each runs the jobs.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.SchedulerStatus} "Scheduler status"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required, or not the default tenant"
+// @Router /admin/scheduler [get]
+func (h *AdminHandler) SchedulerStatus(c *gin.Context) {
+	status, err := h.authService.SchedulerStatus(c.Request.Context())
+	if err != nil {
+		respondError(c, schedulerErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, status)
+}
+
+// =============================================================================
+// Audit Log Archive Endpoints
+// =============================================================================
+
+// ArchiveAuditLogs godoc
+// @Summary Archive audit logs
+// @Description Start exporting the audit log entries (sign-ins included) recorded from from up to, excluding, to to the archive bucket (AUDIT_ARCHIVE_BUCKET) as gzipped NDJSON files, one entry per line, in the background. The entries stay in the database. When the export completes, its manifest, listing the files with their number of entries, size and SHA-256, is written to the manifest key. Audit logs are shared by every tenant.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AuditArchiveRequest true "Date range"
+// @Success 202 {object} response.Envelope{data=models.AuditArchive} "Export started"
+// @Failure 400 {object} map[string]string "Invalid range"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required, or not the default tenant"
+// @Failure 503 {object} map[string]string "Archival not configured"
+// @Router /admin/audit-archives [post]
+func (h *AdminHandler) ArchiveAuditLogs(c *gin.Context) {
+	var req models.AuditArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	archive, err := h.authService.ArchiveAuditLogs(c.Request.Context(), req, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, auditArchiveErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusAccepted, archive)
+}
+
+// requireEmailQueue writes a 503 and returns false when the server runs
+// without the Redis email queue.
+func (h *AdminHandler) requireEmailQueue(c *gin.Context) bool {
+	if h.emailQueue == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "email queue unavailable"})
+		return false
+	}
+	return true
+}
+
+// respondJobError maps dead-letter errors to HTTP responses.
+func respondJobError(c *gin.Context, err error) {
+	if errors.Is(err, email.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	respondError(c, http.StatusInternalServerError, err)
+}
@@ -31,29 +31,30 @@ func NewAuthHandler(authService service.AuthService) *AuthHandler {
 
 // Refresh godoc
 // @Summary Refresh access token
-// @Description Get a new access token using a valid refresh token
+// @Description Get a new access token using a valid refresh token. The token is rotated; the one just rotated is accepted once more within a short grace period, returning the same pair
 // @Tags authentication
 // @Accept json
 // @Produce json
 // @Param request body RefreshTokenRequest true "Refresh token request"
-// @Success 200 {object} response.LoginResponse "New tokens generated successfully"
-// @Failure 400 {object} map[string]string "Invalid or expired refresh token"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "New tokens generated successfully"
+// @Failure 400 {object} map[string]string "Invalid or expired refresh token, or session ended (session_max_age, session_idle_timeout)"
+// @Failure 403 {object} map[string]string "Account awaiting or refused registration approval"
 // @Router /auth/refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	var req struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	result, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, loginErrorStatus(err, http.StatusBadRequest), err)
 		return
 	}
-	c.JSON(http.StatusOK, result)
+	response.JSON(c, http.StatusOK, result)
 }
 
 // =============================================================================
@@ -67,22 +68,26 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body ForgotPasswordRequest true "Password reset request"
-// @Success 200 {object} map[string]string "Password reset email sent successfully"
-// @Failure 400 {object} map[string]string "Invalid email format"
+// @Param X-Captcha-Token header string false "Solved CAPTCHA, required when CAPTCHA_PROVIDER is set"
+// @Param Idempotency-Key header string false "Client-generated key; retries with the same key replay the first response"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Password reset email sent successfully"
+// @Failure 400 {object} map[string]string "Invalid email format or missing CAPTCHA"
+// @Failure 403 {object} map[string]string "CAPTCHA rejected"
+// @Failure 429 {object} map[string]interface{} "Code requested too soon or too often for this email (Retry-After set), or tenant's daily OTP email quota used up"
 // @Router /auth/forgot-password [post]
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req struct {
 		Email string `json:"email" binding:"required,email"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 	if err := h.authService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, quotaErrorStatus(err, http.StatusBadRequest), err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
+	respondMessage(c, "password_reset_email_sent")
 }
 
 // ResetPassword godoc
@@ -92,7 +97,8 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body ResetPasswordRequest true "Password reset confirmation"
-// @Success 200 {object} map[string]string "Password reset successful"
+// @Param Idempotency-Key header string false "Client-generated key; retries with the same key replay the first response"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Password reset successful"
 // @Failure 400 {object} map[string]string "Invalid code, email, or password requirements not met"
 // @Router /auth/reset-password [post]
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
@@ -102,44 +108,385 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 		NewPassword string `json:"new_password" binding:"required,min=8"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 	if err := h.authService.ResetPassword(c.Request.Context(), req.Email, req.Code, req.NewPassword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Password reset successful"})
+	respondMessage(c, "password_reset_successful")
 }
 
 // =============================================================================
 // Two-Factor Authentication Endpoints
 // =============================================================================
 
-// Verify2FA godoc
-// @Summary Verify two-factor authentication code
-// @Description Verify the 2FA code sent to user's email during login process
+// CompletePushLogin godoc
+// @Summary Complete a login approved on a device
+// @Description Poll a push approval challenge with the challenge token returned by login. Returns 202 while the user hasn't answered, and the tokens once the login is approved (only once).
 // @Tags authentication
 // @Accept json
 // @Produce json
-// @Param request body Verify2FARequest true "2FA verification request"
-// @Success 200 {object} map[string]string "2FA verification successful"
-// @Failure 400 {object} map[string]string "Invalid or expired 2FA code"
-// @Router /auth/2fa/verify [post]
-func (h *AuthHandler) Verify2FA(c *gin.Context) {
-	var req struct {
-		Email string `json:"email" binding:"required,email"`
-		Code  string `json:"code" binding:"required"`
+// @Param request body models.PushLoginRequest true "Challenge token to poll"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Login approved"
+// @Success 202 {object} map[string]string "Waiting for approval"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Invalid, expired or already used challenge token"
+// @Failure 403 {object} map[string]string "Login denied on the device"
+// @Failure 404 {object} map[string]string "Unknown or already completed challenge"
+// @Failure 410 {object} map[string]string "Challenge expired"
+// @Router /auth/2fa/push [post]
+func (h *AuthHandler) CompletePushLogin(c *gin.Context) {
+	var req models.PushLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	resp, err := h.authService.CompletePushLogin(c.Request.Context(), req.ChallengeToken)
+	if err != nil {
+		respondError(c, pushErrorStatus(err, loginErrorStatus(err, http.StatusInternalServerError)), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// StartPairing godoc
+// @Summary Start a QR code pairing
+// @Description Start logging a desktop client in with the signed-in mobile app. Show code as a QR code for the app to scan, then poll POST /auth/pairing/poll with code and poll_token (keep it secret) until the app approves or denies the pairing, or it expires.
+// @Tags authentication
+// @Produce json
+// @Success 201 {object} response.Envelope{data=response.PairingSession} "Pairing started"
+// @Failure 503 {object} map[string]string "Pairing not available"
+// @Router /auth/pairing [post]
+func (h *AuthHandler) StartPairing(c *gin.Context) {
+	session, err := h.authService.StartPairing(c.Request.Context())
+	if err != nil {
+		respondError(c, pairingErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+	response.JSON(c, http.StatusCreated, session)
+}
+
+// PollPairing godoc
+// @Summary Poll a QR code pairing
+// @Description Wait up to PAIRING_POLL_WAIT for the app to answer the pairing (long polling). Returns 202 while it hasn't, so poll again at once, and the tokens once the pairing is approved (only once).
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.PairingPollRequest true "Pairing code and poll token"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Pairing approved"
+// @Success 202 {object} map[string]string "Waiting for approval"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Invalid poll token"
+// @Failure 403 {object} map[string]string "Pairing denied in the app, or the account can't sign in from the desktop (IP allowlist, geo policy, deactivated or unapproved account)"
+// @Failure 410 {object} map[string]string "Pairing code expired or already used"
+// @Failure 503 {object} map[string]string "Pairing not available"
+// @Router /auth/pairing/poll [post]
+func (h *AuthHandler) PollPairing(c *gin.Context) {
+	var req models.PairingPollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	resp, err := h.authService.PollPairing(c.Request.Context(), req.Code, req.PollToken)
+	if err != nil {
+		respondError(c, pairingErrorStatus(err, loginErrorStatus(err, http.StatusInternalServerError)), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// CompleteEmailLogin godoc
+// @Summary Complete a login with an emailed code
+// @Description Complete a risky login that login challenged with a code sent by email (two_factor.method "email"), with its challenge token. Returns the tokens once (the challenge token can't be reused).
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.EmailLoginRequest true "Challenge token and code"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Login successful"
+// @Failure 400 {object} map[string]string "Invalid input data or code"
+// @Failure 401 {object} map[string]string "Invalid, expired or already used challenge token"
+// @Failure 404 {object} map[string]string "Unknown or already completed challenge"
+// @Failure 410 {object} map[string]string "Challenge expired"
+// @Router /auth/2fa/email [post]
+func (h *AuthHandler) CompleteEmailLogin(c *gin.Context) {
+	var req models.EmailLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	resp, err := h.authService.CompleteEmailLogin(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, recoveryErrorStatus(err, loginErrorStatus(err, http.StatusInternalServerError)), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// ResendLoginCode godoc
+// @Summary Resend the code of an email challenge
+// @Description Email a new code for a login challenged with a code sent by email, given its challenge token. Earlier codes stop working. Subject to the code email cooldown and daily cap.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.ChallengeTokenRequest true "Challenge token"
+// @Param Idempotency-Key header string false "Client-generated key; retries with the same key replay the first response"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Code sent"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Invalid, expired or already used challenge token"
+// @Failure 404 {object} map[string]string "Unknown or already completed challenge"
+// @Failure 410 {object} map[string]string "Challenge expired"
+// @Failure 429 {object} map[string]string "Code requested too recently or too often"
+// @Router /auth/2fa/email/resend [post]
+func (h *AuthHandler) ResendLoginCode(c *gin.Context) {
+	var req models.ChallengeTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.ResendLoginCode(c.Request.Context(), req.ChallengeToken); err != nil {
+		respondError(c, quotaErrorStatus(err, pushErrorStatus(err, http.StatusInternalServerError)), err)
+		return
+	}
+	respondMessage(c, "code_resent")
+}
+
+// CompleteQuarantinedLogin godoc
+// @Summary Complete a quarantined login
+// @Description Poll a risky login that login put on hold (two_factor.method "quarantine"). Returns 202 until the user approves it with the link emailed to them, then the tokens (only once).
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.QuarantinePollRequest true "Challenge to poll"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Login approved"
+// @Success 202 {object} map[string]string "Waiting for approval"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 404 {object} map[string]string "Unknown or already completed challenge"
+// @Failure 410 {object} map[string]string "Approval link expired"
+// @Router /auth/login/quarantine [post]
+func (h *AuthHandler) CompleteQuarantinedLogin(c *gin.Context) {
+	var req models.QuarantinePollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	resp, err := h.authService.CompleteQuarantinedLogin(c.Request.Context(), req.ChallengeID)
+	if err != nil {
+		respondError(c, pushErrorStatus(err, loginErrorStatus(err, http.StatusInternalServerError)), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// ApproveQuarantinedLogin godoc
+// @Summary Approve a quarantined login
+// @Description Approve a risky login on hold with the token from the link emailed to the user. The login client then receives its tokens.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.QuarantineApprovalRequest true "Token from the approval link"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Login approved"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 404 {object} map[string]string "Unknown or already used link"
+// @Failure 410 {object} map[string]string "Approval link expired"
+// @Router /auth/login/approve [post]
+func (h *AuthHandler) ApproveQuarantinedLogin(c *gin.Context) {
+	var req models.QuarantineApprovalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.ApproveQuarantinedLogin(c.Request.Context(), req.Token); err != nil {
+		respondError(c, pushErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+	respondMessage(c, "login_approved")
+}
+
+// LoginWithRecoveryCode godoc
+// @Summary Complete a login with a recovery code
+// @Description Complete a login waiting for push approval, given its challenge token, with one of the user's recovery codes, for when no device is at hand. The code can't be used again and the user is alerted by email and on their devices.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RecoveryCodeLoginRequest true "Challenge token and recovery code"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Login successful"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Invalid or already used recovery code or challenge token"
+// @Failure 404 {object} map[string]string "Unknown or already answered challenge"
+// @Failure 410 {object} map[string]string "Challenge expired"
+// @Router /auth/2fa/recover/code [post]
+func (h *AuthHandler) LoginWithRecoveryCode(c *gin.Context) {
+	var req models.RecoveryCodeLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	resp, err := h.authService.LoginWithRecoveryCode(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, recoveryErrorStatus(err, loginErrorStatus(err, http.StatusInternalServerError)), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// StartEmailRecovery godoc
+// @Summary Start two-factor recovery by email
+// @Description For a login waiting for push approval that the user can't approve: emails them a code to start recovery. The challenge no longer accepts approvals; call again to resend the code.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RecoveryEmailRequest true "Challenge to recover from"
+// @Param X-Captcha-Token header string false "Solved CAPTCHA, required when CAPTCHA_PROVIDER is set"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Code sent"
+// @Failure 400 {object} map[string]string "Invalid input data or missing CAPTCHA"
+// @Failure 403 {object} map[string]string "CAPTCHA rejected"
+// @Failure 404 {object} map[string]string "Unknown or already answered challenge"
+// @Failure 410 {object} map[string]string "Challenge expired"
+// @Failure 429 {object} map[string]string "A code was sent recently or too many codes today"
+// @Router /auth/2fa/recover/email [post]
+func (h *AuthHandler) StartEmailRecovery(c *gin.Context) {
+	var req models.RecoveryEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.StartEmailRecovery(c.Request.Context(), req.ChallengeID); err != nil {
+		respondError(c, recoveryErrorStatus(err, quotaErrorStatus(err, http.StatusInternalServerError)), err)
+		return
 	}
+	respondMessage(c, "recovery_code_sent")
+}
+
+// VerifyEmailRecovery godoc
+// @Summary Verify the two-factor recovery email code
+// @Description Checks the emailed code and opens a recovery request. The returned token turns 2FA off and logs in once available_at has passed; meanwhile the account owner is alerted and can cancel it.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RecoveryEmailVerifyRequest true "Challenge and emailed code"
+// @Success 202 {object} response.Envelope{data=models.RecoveryStarted} "Recovery requested"
+// @Failure 400 {object} map[string]string "Invalid input data or code"
+// @Failure 404 {object} map[string]string "No email recovery started from this challenge"
+// @Router /auth/2fa/recover/email/verify [post]
+func (h *AuthHandler) VerifyEmailRecovery(c *gin.Context) {
+	var req models.RecoveryEmailVerifyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
-	if err := h.authService.Verify2FA(c.Request.Context(), req.Email, req.Code); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	started, err := h.authService.VerifyEmailRecovery(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, recoveryErrorStatus(err, http.StatusInternalServerError), err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "2FA verification successful"})
+	response.JSON(c, http.StatusAccepted, started)
+}
+
+// CompleteRecovery godoc
+// @Summary Complete two-factor recovery
+// @Description Turns 2FA off and logs in with a recovery token whose cool-down period has passed. Before that it returns 202 with Retry-After.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RecoveryTokenRequest true "Recovery token"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "2FA turned off, login successful"
+// @Success 202 {object} map[string]interface{} "Recovery not available yet"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 404 {object} map[string]string "Unknown, cancelled or completed recovery"
+// @Router /auth/2fa/recover/complete [post]
+func (h *AuthHandler) CompleteRecovery(c *gin.Context) {
+	var req models.RecoveryTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	resp, err := h.authService.CompleteRecovery(c.Request.Context(), req.Token)
+	if err != nil {
+		respondError(c, recoveryErrorStatus(err, loginErrorStatus(err, http.StatusInternalServerError)), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// CancelRecovery godoc
+// @Summary Cancel two-factor recovery
+// @Description Stops a pending recovery request with the cancel token from the security alert email.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body models.RecoveryTokenRequest true "Cancel token"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Recovery cancelled"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 404 {object} map[string]string "Unknown or no longer pending recovery"
+// @Router /auth/2fa/recover/cancel [post]
+func (h *AuthHandler) CancelRecovery(c *gin.Context) {
+	var req models.RecoveryTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.CancelRecovery(c.Request.Context(), req.Token); err != nil {
+		respondError(c, recoveryErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+	respondMessage(c, "recovery_cancelled")
 }
 
 // =============================================================================
@@ -153,18 +500,22 @@ func (h *AuthHandler) Verify2FA(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body models.RegisterRequest true "User registration data"
-// @Success 201 {object} response.RegisterResponse "User registered successfully"
-// @Failure 400 {object} map[string]string "Invalid input data or validation failed"
+// @Param X-Captcha-Token header string false "Solved CAPTCHA, required when CAPTCHA_PROVIDER is set"
+// @Param Idempotency-Key header string false "Client-generated key; retries with the same key replay the first response"
+// @Success 201 {object} response.Envelope{data=response.RegisterResponse} "User registered successfully"
+// @Failure 400 {object} map[string]string "Invalid input data, validation failed or missing CAPTCHA"
+// @Failure 403 {object} map[string]string "CAPTCHA rejected"
 // @Failure 409 {object} map[string]string "Email already exists"
+// @Failure 429 {object} map[string]string "Tenant's daily registration quota used up, or too many signups from this IP address or for this email address (registration_ip_limit, registration_email_flood, registration_blocked; see Retry-After)"
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 	if err := Validate.Struct(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err)})
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
 		return
 	}
 
@@ -172,40 +523,73 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	resp, err := h.authService.Register(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, quotaErrorStatus(err, http.StatusBadRequest), err)
 		return
 	}
-	c.JSON(http.StatusCreated, resp)
+	response.JSON(c, http.StatusCreated, resp)
 }
 
 // Login godoc
 // @Summary User login
-// @Description Authenticate user with email and password, returns JWT tokens
+// @Description Authenticate user with email (or username) and password, returns JWT tokens. Logins needing a second factor (push approval, an email code or, for the riskiest logins, approval by emailed link) return a two_factor challenge instead of tokens.
 // @Tags authentication
 // @Accept json
 // @Produce json
 // @Param request body models.LoginRequest true "User login credentials"
-// @Success 200 {object} response.LoginResponse "Login successful with JWT tokens"
-// @Failure 400 {object} map[string]string "Invalid input data"
-// @Failure 401 {object} map[string]string "Invalid email or password"
+// @Param X-Captcha-Token header string false "Solved CAPTCHA, required when CAPTCHA_PROVIDER is set"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Login successful with JWT tokens, or a second factor challenge"
+// @Failure 400 {object} map[string]string "Invalid input data or missing CAPTCHA"
+// @Failure 401 {object} map[string]string "Invalid email/username or password"
+// @Failure 403 {object} map[string]string "Account awaiting or refused registration approval, login blocked as too risky, or CAPTCHA rejected"
+// @Failure 429 {object} map[string]string "Too many code emails for an email challenge"
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 	if err := Validate.Struct(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err)})
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
 		return
 	}
 
 	resp, err := h.authService.Login(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, quotaErrorStatus(err, loginErrorStatus(err, http.StatusUnauthorized)), err)
 		return
 	}
-	c.JSON(http.StatusOK, resp)
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// UsernameAvailable godoc
+// @Summary Check username availability
+// @Description Report whether a username can be claimed. Unavailable usernames carry a reason: invalid, reserved or taken.
+// @Tags authentication
+// @Produce json
+// @Param username query string true "Username to check (case-insensitive)"
+// @Success 200 {object} response.Envelope{data=response.UsernameAvailability} "Availability"
+// @Failure 400 {object} map[string]string "Missing username"
+// @Router /auth/username-available [get]
+func (h *AuthHandler) UsernameAvailable(c *gin.Context) {
+	var req struct {
+		Username string `form:"username" validate:"required,max=64"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	result, err := h.authService.CheckUsername(c.Request.Context(), req.Username)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	response.JSON(c, http.StatusOK, result)
 }
 
 // =============================================================================
@@ -231,25 +615,26 @@ func (h *AuthHandler) GoogleRedirect(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body GoogleLoginRequest true "Google ID token"
-// @Success 200 {object} response.LoginResponse "Google authentication successful"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Google authentication successful"
 // @Failure 400 {object} map[string]string "Invalid ID token format"
 // @Failure 401 {object} map[string]string "Invalid Google token"
+// @Failure 403 {object} map[string]string "Account awaiting or refused registration approval"
+// @Failure 429 {object} map[string]string "Tenant's daily registration quota used up"
+// @Failure 503 {object} map[string]string "Google unreachable"
 // @Router /auth/google/login [post]
 func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	var req struct {
-		IDToken string `json:"id_token" binding:"required"`
-	}
+	var req GoogleLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
 		return
 	}
 
-	resp, err := h.authService.GoogleAuth(c.Request.Context(), req.IDToken, config.GoogleOAuthConfig.ClientID)
+	resp, err := h.authService.GoogleAuth(c.Request.Context(), req.IDToken, config.GoogleOAuthConfig.ClientID, req.InviteToken, req.OrgInviteToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, googleErrorStatus(err, quotaErrorStatus(err, loginErrorStatus(err, http.StatusUnauthorized))), err)
 		return
 	}
-	c.JSON(http.StatusOK, resp)
+	response.JSON(c, http.StatusOK, resp)
 }
 
 // GoogleCallback godoc
@@ -258,9 +643,12 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 // @Tags authentication
 // @Produce json
 // @Param code query string true "Authorization code from Google"
-// @Success 200 {object} response.LoginResponse "OAuth authentication successful"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "OAuth authentication successful"
 // @Failure 400 {object} map[string]string "Missing authorization code"
 // @Failure 401 {object} map[string]string "Failed to exchange code for tokens"
+// @Failure 403 {object} map[string]string "Account awaiting or refused registration approval"
+// @Failure 429 {object} map[string]string "Tenant's daily registration quota used up"
+// @Failure 503 {object} map[string]string "Google unreachable"
 // @Router /auth/google/callback [get]
 func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	code := c.Query("code")
@@ -272,8 +660,36 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	// Exchange code for tokens + verify ID token
 	resp, err := h.authService.GoogleCallback(c.Request.Context(), code, config.GoogleOAuthConfig)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		respondError(c, googleErrorStatus(err, quotaErrorStatus(err, loginErrorStatus(err, http.StatusUnauthorized))), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// FederatedLogin godoc
+// @Summary Sign in with an external identity provider token
+// @Description Exchange a JWT issued by the configured external identity provider (FEDERATED_ISSUER) for Authentio tokens. The token is verified against the provider's JWKS and matched to an existing account by its verified email.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body FederatedLoginRequest true "Identity provider token"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Authentication successful or second factor required"
+// @Failure 400 {object} map[string]string "Invalid request format"
+// @Failure 401 {object} map[string]string "Invalid or expired identity provider token"
+// @Failure 403 {object} map[string]string "No matching account, or account awaiting or refused approval"
+// @Failure 404 {object} map[string]string "Federated sign-in not enabled"
+// @Router /auth/federated/login [post]
+func (h *AuthHandler) FederatedLogin(c *gin.Context) {
+	var req FederatedLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "details": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.FederatedLogin(c.Request.Context(), req.Token)
+	if err != nil {
+		respondError(c, federationErrorStatus(err, loginErrorStatus(err, http.StatusUnauthorized)), err)
 		return
 	}
-	c.JSON(http.StatusOK, resp)
+	response.JSON(c, http.StatusOK, resp)
 }
\ No newline at end of file
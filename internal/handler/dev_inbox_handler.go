@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+
+	"authentio/pkg/email"
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// DevInboxHandler Structure and Constructor
+// =============================================================================
+
+// DevInboxHandler serves the emails kept by the memory email provider, so
+// staging environments and end-to-end tests can read the codes and links
+// they contain
+type DevInboxHandler struct {
+	inbox *email.Inbox
+}
+
+// NewDevInboxHandler creates a new DevInboxHandler instance
+func NewDevInboxHandler(inbox *email.Inbox) *DevInboxHandler {
+	return &DevInboxHandler{inbox: inbox}
+}
+
+// =============================================================================
+// Dev Email Inbox Endpoints
+// =============================================================================
+
+// ListDevEmails godoc
+// @Summary List emails kept by the dev inbox
+// @Description Emails rendered since startup (or the last clear) by the memory email provider instead of being sent, newest first, with their HTML. Available outside production when EMAIL_PROVIDER=memory; the endpoint is unauthenticated.
+// @Tags dev
+// @Produce json
+// @Param to query string false "Only emails sent to this address"
+// @Param limit query int false "Maximum number of emails to return (1-100)" default(20) minimum(1) maximum(100)
+// @Success 200 {object} response.Envelope{data=[]email.InboxMessage} "Emails, newest first"
+// @Failure 400 {object} map[string]string "Invalid parameters"
+// @Router /dev/emails [get]
+func (h *DevInboxHandler) ListDevEmails(c *gin.Context) {
+	var req struct {
+		To    string `form:"to" validate:"omitempty,email"`
+		Limit int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	response.JSON(c, http.StatusOK, h.inbox.Messages(req.To, req.Limit))
+}
+
+// ClearDevEmails godoc
+// @Summary Clear the dev inbox
+// @Description Forget every email kept by the memory email provider, e.g. between end-to-end tests. Available outside production when EMAIL_PROVIDER=memory; the endpoint is unauthenticated.
+// @Tags dev
+// @Produce json
+// @Success 200 {object} response.Envelope{data=map[string]int} "Number of emails removed"
+// @Router /dev/emails [delete]
+func (h *DevInboxHandler) ClearDevEmails(c *gin.Context) {
+	response.JSON(c, http.StatusOK, gin.H{"removed": h.inbox.Clear()})
+}
@@ -0,0 +1,370 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"authentio/internal/service"
+	"authentio/pkg/i18n"
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError writes err as a JSON error response. Service errors are
+// translated into the request locale and carry their stable "code" so clients
+// can branch on it; errors that can be retried later also set the Retry-After
+// header and "retry_after" (seconds), and errors with details about the
+// request carry them in "detail". Server errors caused by the request
+// deadline (REQUEST_TIMEOUT) become 504 request_timeout. Any other error is
+// returned as-is.
+func respondError(c *gin.Context, status int, err error) {
+	if status >= http.StatusInternalServerError && deadlineExceeded(c, err) {
+		status, err = http.StatusGatewayTimeout, service.ErrRequestTimeout
+	}
+
+	var retryErr *service.RetryAfterError
+	if errors.As(err, &retryErr) {
+		seconds := int(math.Ceil(retryErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(status, gin.H{
+			"error":       translatedMessage(c, retryErr.ServiceError),
+			"code":        retryErr.Code,
+			"retry_after": seconds,
+		})
+		return
+	}
+
+	var detailErr *service.DetailError
+	if errors.As(err, &detailErr) {
+		c.JSON(status, gin.H{
+			"error":  translatedMessage(c, detailErr.ServiceError),
+			"code":   detailErr.Code,
+			"detail": detailErr.Detail,
+		})
+		return
+	}
+
+	var svcErr *service.ServiceError
+	if errors.As(err, &svcErr) {
+		c.JSON(status, gin.H{"error": translatedMessage(c, svcErr), "code": svcErr.Code})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// deadlineExceeded reports whether err, or the request, ran out of time.
+func deadlineExceeded(c *gin.Context, err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded)
+}
+
+// translatedMessage returns err's message in the request locale, falling back
+// to its English message.
+func translatedMessage(c *gin.Context, err *service.ServiceError) string {
+	if msg, ok := i18n.Lookup(locale(c), "error."+err.Code); ok {
+		return msg
+	}
+	return err.Message
+}
+
+// loginErrorStatus returns the status for a failed login or token refresh:
+// 403 for accounts awaiting or refused registration approval, for logins
+// blocked as too risky or by the geo policy and for addresses outside the
+// account's IP allowlist, otherwise fallback.
+func loginErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrAccountPending) || errors.Is(err, service.ErrAccountRejected) || errors.Is(err, service.ErrLoginBlocked) || errors.Is(err, service.ErrGeoBlocked) || errors.Is(err, service.ErrIPNotAllowed) {
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
+// federationErrorStatus maps federated sign-in errors to 404 (not enabled)
+// or 403 (no matching account), otherwise fallback.
+func federationErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrFederationDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrNoFederatedAccount):
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
+// googleErrorStatus returns 503 while Google can't be reached, otherwise
+// fallback.
+func googleErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrGoogleUnavailable) {
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// quotaErrorStatus returns 429 when the tenant has used up a daily quota,
+// the recipient of a code email hit its cooldown or daily cap, or a
+// registration hit the anti-abuse limits, otherwise fallback.
+func quotaErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrQuotaExceeded), errors.Is(err, service.ErrOTPCooldown), errors.Is(err, service.ErrOTPDailyLimit):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrRegistrationIPLimit), errors.Is(err, service.ErrRegistrationFlood), errors.Is(err, service.ErrRegistrationBlocked):
+		return http.StatusTooManyRequests
+	}
+	return fallback
+}
+
+// avatarErrorStatus maps avatar upload errors to 400, 404 (uploads disabled)
+// or 413, otherwise fallback.
+func avatarErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidImage):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAvatarsDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrImageTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return fallback
+}
+
+// pushErrorStatus maps push approval and login quarantine errors to their
+// status, otherwise fallback. A login still waiting for approval is 202
+// Accepted.
+func pushErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrChallengePending), errors.Is(err, service.ErrLoginQuarantined):
+		return http.StatusAccepted
+	case errors.Is(err, service.ErrNoPushDevice):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidChallengeToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrLoginDenied):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPushUnavailable), errors.Is(err, service.ErrPushDeviceNotFound), errors.Is(err, service.ErrChallengeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrLastPushDevice):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrChallengeExpired):
+		return http.StatusGone
+	}
+	return fallback
+}
+
+// recoveryErrorStatus maps two-factor recovery errors to their status,
+// otherwise falls back to pushErrorStatus. A recovery still in its cool-down
+// period is 202 Accepted, like a login waiting for approval.
+func recoveryErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrRecoveryPending):
+		return http.StatusAccepted
+	case errors.Is(err, service.ErrInvalidRecoveryCode):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrInvalidOTP):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrRecoveryNotFound):
+		return http.StatusNotFound
+	}
+	return pushErrorStatus(err, fallback)
+}
+
+// ipAllowlistErrorStatus maps IP allowlist management errors to their status,
+// otherwise fallback. A change that would lock the caller out is 409 Conflict.
+func ipAllowlistErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCIDR):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrUserNotFound), errors.Is(err, service.ErrIPEntryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrIPAllowlistLockout):
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+// ipDenylistErrorStatus maps IP denylist errors to their status, otherwise
+// fallback. Denying the caller's own address is 409 Conflict.
+func ipDenylistErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCIDR):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrDenylistForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrDenylistEntryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrIPDenylistLockout):
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+// exportErrorStatus maps user export errors to their status, otherwise
+// fallback. Downloading an export still being generated is 409 Conflict.
+func exportErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidExportFilter):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidDownloadLink):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrExportNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrExportNotReady):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrExportsDisabled):
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// pairingErrorStatus maps QR code pairing errors to their status, otherwise
+// fallback. A pairing waiting for the app's answer is 202 Accepted, like a
+// login waiting for approval.
+func pairingErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrPairingPending):
+		return http.StatusAccepted
+	case errors.Is(err, service.ErrInvalidPollToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrPairingDenied), errors.Is(err, service.ErrUserNotFound):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrPairingNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPairingExpired):
+		return http.StatusGone
+	case errors.Is(err, service.ErrPairingUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// sessionErrorStatus maps cookie session errors to their status, otherwise
+// fallback. Sessions that can't be renewed anymore are 401, so apps send
+// the user to sign in again.
+func sessionErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrSessionCookieMissing), errors.Is(err, service.ErrInvalidRefreshToken),
+		errors.Is(err, service.ErrSessionMaxAge), errors.Is(err, service.ErrSessionIdle), errors.Is(err, service.ErrUserNotFound):
+		return http.StatusUnauthorized
+	}
+	return loginErrorStatus(err, fallback)
+}
+
+// emailLogErrorStatus maps email delivery log errors to their status,
+// otherwise fallback. Webhook requests with a bad signature are 401.
+func emailLogErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidEmailStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidEmailWebhook):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrEmailWebhookDisabled):
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// emailTemplateErrorStatus maps email template errors to their status,
+// otherwise fallback.
+func emailTemplateErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidEmailTemplate):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrEmailTemplateNotFound), errors.Is(err, service.ErrNoSuchTemplateVersion):
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+// emailTestErrorStatus maps test email errors to their status, otherwise
+// fallback.
+func emailTestErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrEmailTestFailed) {
+		return http.StatusBadGateway
+	}
+	return fallback
+}
+
+// emailBrandingErrorStatus maps email branding errors to their status,
+// otherwise fallback.
+func emailBrandingErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrInvalidEmailBranding) {
+		return http.StatusBadRequest
+	}
+	return fallback
+}
+
+// geoPolicyErrorStatus maps geo policy errors to their status, otherwise
+// fallback. A policy refusing the caller's own country is 409 Conflict.
+func geoPolicyErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrGeoPolicyLockout) {
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+// duplicateAccountErrorStatus maps duplicate account errors to their status,
+// otherwise fallback.
+func duplicateAccountErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidDuplicateState):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrDuplicatesNotFound):
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+// keyRotationErrorStatus maps key rotation errors to their status, otherwise
+// fallback.
+func keyRotationErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidKeyPurpose):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrKeysForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrKeyRotationRunning):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrKeyRotationDisabled):
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// schedulerErrorStatus maps scheduler status errors to their status,
+// otherwise fallback.
+func schedulerErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrSchedulerForbidden) {
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
+// auditArchiveErrorStatus maps audit log archival errors to their status,
+// otherwise fallback.
+func auditArchiveErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidArchiveRange):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAuditArchiveForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrAuditArchiveDisabled):
+		return http.StatusServiceUnavailable
+	}
+	return fallback
+}
+
+// respondMessage writes the confirmation message "message.<key>" of the
+// catalogs in the request locale.
+func respondMessage(c *gin.Context, key string) {
+	response.Message(c, http.StatusOK, i18n.T(locale(c), "message."+key))
+}
+
+// locale returns the locale negotiated by the Locale middleware, or the
+// user's saved preference on authenticated routes.
+func locale(c *gin.Context) string {
+	if l := c.GetString("locale"); l != "" {
+		return l
+	}
+	return i18n.DefaultLocale
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"authentio/internal/graphqlapi"
+	"authentio/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// =============================================================================
+// GraphQLHandler Structure and Constructor
+// =============================================================================
+
+// GraphQLHandler serves the optional GraphQL endpoint
+type GraphQLHandler struct {
+	schema *graphql.Schema
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler instance
+func NewGraphQLHandler(authService service.AuthService) *GraphQLHandler {
+	return &GraphQLHandler{
+		schema: graphqlapi.NewSchema(authService),
+	}
+}
+
+// =============================================================================
+// GraphQL Endpoint
+// =============================================================================
+
+// graphQLRequest is the body of a GraphQL-over-HTTP request.
+type graphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQL godoc
+// @Summary GraphQL endpoint
+// @Description Execute a GraphQL query or mutation: profile, sessions and login history, profile updates and 2FA management. Resolvers authenticate the caller from the bearer token; errors are returned in the GraphQL "errors" array with the error code in extensions.code. Available when GRAPHQL_ENABLED is set.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body graphQLRequest true "GraphQL query, operation name and variables"
+// @Success 200 {object} map[string]interface{} "GraphQL response (data and/or errors)"
+// @Failure 400 {object} map[string]string "Missing query"
+// @Router /graphql [post]
+func (h *GraphQLHandler) GraphQL(c *gin.Context) {
+	var req graphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		ctx = graphqlapi.WithAccessToken(ctx, token)
+	}
+
+	c.JSON(http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
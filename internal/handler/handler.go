@@ -1,15 +1,18 @@
 package handler
 
-import "authentio/internal/service"
+import (
+	"authentio/internal/service"
+	"authentio/pkg/email"
+)
 
 // =============================================================================
 // Main Handler Aggregator
 // =============================================================================
 
 // Handler aggregates all sub-handlers into a single struct.
-// 
+//
 // Embedding the concrete handlers automatically promotes their methods,
-// allowing the router to call methods like `h.GoogleCallback`, `h.Login`, 
+// allowing the router to call methods like `h.GoogleCallback`, `h.Login`,
 // `h.GetProfile`, etc. directly on the main Handler instance.
 //
 // This pattern provides:
@@ -18,9 +21,16 @@ import "authentio/internal/service"
 // - Method promotion without explicit delegation
 // - Maintainable and testable structure
 type Handler struct {
-	*AuthHandler   // Handles authentication endpoints (login, register, OAuth)
-	*TwoFAHandler  // Handles two-factor authentication endpoints
-	*UserHandler   // Handles user profile management endpoints
+	*AuthHandler         // Handles authentication endpoints (login, register, OAuth)
+	*SessionHandler      // Handles the cookie sessions of single-page apps
+	*TwoFAHandler        // Handles two-factor authentication endpoints
+	*UserHandler         // Handles user profile management endpoints
+	*AdminHandler        // Handles administrative endpoints (admin role only)
+	*InvitationHandler   // Handles registration invitation endpoints
+	*OrganizationHandler // Handles organization and membership endpoints
+	*OAuthHandler        // Handles OpenID Connect provider endpoints and OAuth clients
+	*GraphQLHandler      // Handles the GraphQL endpoint; nil when GraphQL is disabled
+	*DevInboxHandler     // Handles the dev email inbox endpoints; nil unless EMAIL_PROVIDER=memory
 }
 
 // =============================================================================
@@ -33,13 +43,29 @@ type Handler struct {
 //
 // Parameters:
 //   - authService: The core service containing business logic for all handlers
+//   - emailQueue: Email delivery queue for dead-letter administration (nil when Redis is unavailable)
+//   - inbox: Emails kept by the memory email provider (nil with the other providers)
+//   - session: Cookie keeping the refresh token of single-page apps
+//   - graphqlEnabled: Whether to serve the GraphQL endpoint (GRAPHQL_ENABLED)
 //
 // Returns:
 //   - *Handler: Fully initialized handler aggregator ready for router setup
-func NewHandler(authService service.AuthService) *Handler {
-	return &Handler{
-		AuthHandler:  NewAuthHandler(authService),
-		TwoFAHandler: NewTwoFAHandler(authService),
-		UserHandler:  NewUserHandler(authService),
+func NewHandler(authService service.AuthService, emailQueue *email.Queue, inbox *email.Inbox, session SessionCookie, graphqlEnabled bool) *Handler {
+	h := &Handler{
+		AuthHandler:         NewAuthHandler(authService),
+		SessionHandler:      NewSessionHandler(authService, session),
+		TwoFAHandler:        NewTwoFAHandler(authService),
+		UserHandler:         NewUserHandler(authService),
+		AdminHandler:        NewAdminHandler(authService, emailQueue),
+		InvitationHandler:   NewInvitationHandler(authService),
+		OrganizationHandler: NewOrganizationHandler(authService),
+		OAuthHandler:        NewOAuthHandler(authService),
+	}
+	if graphqlEnabled {
+		h.GraphQLHandler = NewGraphQLHandler(authService)
 	}
-}
\ No newline at end of file
+	if inbox != nil {
+		h.DevInboxHandler = NewDevInboxHandler(inbox)
+	}
+	return h
+}
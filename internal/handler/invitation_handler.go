@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"authentio/internal/models"
+	"authentio/internal/service"
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// InvitationHandler Structure and Constructor
+// =============================================================================
+
+// InvitationHandler handles registration invitation HTTP requests
+type InvitationHandler struct {
+	authService service.AuthService
+}
+
+// NewInvitationHandler creates a new InvitationHandler instance
+func NewInvitationHandler(authService service.AuthService) *InvitationHandler {
+	return &InvitationHandler{
+		authService: authService,
+	}
+}
+
+// =============================================================================
+// Invitation Endpoints
+// =============================================================================
+
+// CreateInvitation godoc
+// @Summary Invite someone to register
+// @Description Email a registration invite link. Admins can always invite; other users only when USERS_CAN_INVITE is enabled.
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.InvitationRequest true "Invitee email"
+// @Success 201 {object} response.Envelope{data=models.Invitation} "Invitation sent"
+// @Failure 400 {object} map[string]string "Invalid input or email already registered"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Not allowed to send invitations"
+// @Router /invitations [post]
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req models.InvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	invitation, err := h.authService.CreateInvitation(c.Request.Context(), c.GetInt64("userID"), c.GetString("role"), req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrInviteNotPermitted) {
+			status = http.StatusForbidden
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, invitation)
+}
+
+// ListInvitations godoc
+// @Summary List invitations
+// @Description Invitations sent by the authenticated user (all invitations for admins), newest first
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.Invitation} "Invitations"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Router /invitations [get]
+func (h *InvitationHandler) ListInvitations(c *gin.Context) {
+	invitations, err := h.authService.ListInvitations(c.Request.Context(), c.GetInt64("userID"), c.GetString("role"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, invitations)
+}
+
+// RevokeInvitation godoc
+// @Summary Revoke an invitation
+// @Description Cancel a pending invitation so its link can no longer be used
+// @Tags invitations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Invitation ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Invitation revoked"
+// @Failure 400 {object} map[string]string "Invitation is no longer pending"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Invitation not found"
+// @Router /invitations/{id} [delete]
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation id"})
+		return
+	}
+
+	if err := h.authService.RevokeInvitation(c.Request.Context(), id, c.GetInt64("userID"), c.GetString("role")); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrInvitationNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	respondMessage(c, "invitation_revoked")
+}
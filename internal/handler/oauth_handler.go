@@ -0,0 +1,407 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"authentio/internal/models"
+	"authentio/internal/service"
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// OAuthHandler Structure and Constructor
+// =============================================================================
+
+// OAuthHandler handles the OpenID Connect provider endpoints (discovery,
+// authorization, token, userinfo) and OAuth client administration
+type OAuthHandler struct {
+	authService service.AuthService
+}
+
+// NewOAuthHandler creates a new OAuthHandler instance
+func NewOAuthHandler(authService service.AuthService) *OAuthHandler {
+	return &OAuthHandler{
+		authService: authService,
+	}
+}
+
+// =============================================================================
+// Discovery Endpoints
+// =============================================================================
+
+// OIDCDiscovery serves the OpenID Provider Metadata at
+// /.well-known/openid-configuration.
+func (h *OAuthHandler) OIDCDiscovery(c *gin.Context) {
+	doc, err := h.authService.OIDCDiscovery()
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	c.JSON(http.StatusOK, doc)
+}
+
+// JWKS serves the provider's public signing keys at /.well-known/jwks.json.
+func (h *OAuthHandler) JWKS(c *gin.Context) {
+	keys, err := h.authService.JWKS()
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// =============================================================================
+// Authorization Endpoints
+// =============================================================================
+
+// Authorize godoc
+// @Summary OpenID Connect authorization endpoint
+// @Description Start the authorization code flow. Valid requests are redirected to the consent page (OIDC_CONSENT_URL) with the same parameters; other errors are reported to the client's redirect URI.
+// @Tags oauth
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string true "Space-separated scopes, including openid"
+// @Param state query string false "Opaque value returned to the client"
+// @Param nonce query string false "Value copied into the ID token"
+// @Param code_challenge query string false "PKCE challenge (required for public clients)"
+// @Param code_challenge_method query string false "Must be S256"
+// @Success 302 "Redirect to the consent page or the client's redirect URI"
+// @Failure 400 {object} map[string]string "Unknown client or unregistered redirect URI"
+// @Failure 404 {object} map[string]string "Provider mode is disabled"
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	redirectTo, err := h.authService.StartAuthorization(c.Request.Context(), req)
+	if err != nil {
+		respondError(c, oidcErrorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectTo)
+}
+
+// AuthorizationConsent godoc
+// @Summary Describe an authorization request for the consent screen
+// @Description Called by the consent page with the authorization request parameters. Returns the client and the requested scopes with descriptions in the user's language. consent_required is false when the user already approved every scope, in which case the page can submit the approval without asking.
+// @Tags oauth
+// @Produce json
+// @Security BearerAuth
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param response_type query string true "Must be code"
+// @Param scope query string true "Space-separated scopes, including openid"
+// @Param code_challenge query string false "PKCE challenge"
+// @Param code_challenge_method query string false "Must be S256"
+// @Success 200 {object} response.Envelope{data=models.OAuthConsentPrompt} "Consent details"
+// @Failure 400 {object} map[string]string "Invalid authorization request"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Provider mode is disabled"
+// @Router /oauth/authorize/consent [get]
+func (h *OAuthHandler) AuthorizationConsent(c *gin.Context) {
+	var req models.AuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	prompt, err := h.authService.AuthorizationConsent(c.Request.Context(), c.GetInt64("userID"), req)
+	if err != nil {
+		var oauthErr *service.OAuthError
+		if errors.As(err, &oauthErr) {
+			respondOAuthError(c, err)
+			return
+		}
+		respondError(c, oidcErrorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, prompt)
+}
+
+// AuthorizeDecision godoc
+// @Summary Approve or deny an authorization request
+// @Description Called by the consent page on behalf of the signed-in user with the authorization request parameters. Returns the client redirect URI carrying an authorization code, or an access_denied error when the user declined.
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AuthorizeDecision true "Authorization request and decision"
+// @Success 200 {object} response.Envelope{data=map[string]string} "URI to redirect the browser to"
+// @Failure 400 {object} map[string]string "Unknown client or unregistered redirect URI"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Account awaiting or refused registration approval"
+// @Failure 404 {object} map[string]string "Provider mode is disabled"
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) AuthorizeDecision(c *gin.Context) {
+	var req models.AuthorizeDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	redirectTo, err := h.authService.Authorize(c.Request.Context(), c.GetInt64("userID"), req)
+	if err != nil {
+		respondError(c, oidcErrorStatus(err, loginErrorStatus(err, http.StatusBadRequest)), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, gin.H{"redirect_to": redirectTo})
+}
+
+// =============================================================================
+// Token Endpoints
+// =============================================================================
+
+// Token godoc
+// @Summary OpenID Connect token endpoint
+// @Description Exchange an authorization code (grant_type=authorization_code) or refresh token (grant_type=refresh_token) for tokens. Confidential clients authenticate with HTTP Basic or client_id/client_secret form fields; public clients send client_id and a PKCE code_verifier.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code or refresh_token"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used in the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param client_id formData string false "Client ID (when not using HTTP Basic)"
+// @Param client_secret formData string false "Client secret (when not using HTTP Basic)"
+// @Success 200 {object} response.OAuthTokenResponse "Tokens issued"
+// @Failure 400 {object} map[string]string "OAuth error (invalid_grant, invalid_request, ...)"
+// @Failure 401 {object} map[string]string "Client authentication failed"
+// @Failure 404 {object} map[string]string "Provider mode is disabled"
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	var req models.TokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	// client_secret_basic: credentials are form-encoded inside the header
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, _ = url.QueryUnescape(id)
+		req.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	tokens, err := h.authService.ExchangeToken(c.Request.Context(), req)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// UserInfo godoc
+// @Summary OpenID Connect userinfo endpoint
+// @Description Claims about the user an OAuth access token was issued for, limited to its scopes
+// @Tags oauth
+// @Produce json
+// @Param Authorization header string true "Bearer access token issued by the token endpoint"
+// @Success 200 {object} map[string]interface{} "User claims"
+// @Failure 401 {object} map[string]string "Invalid or expired access token"
+// @Failure 404 {object} map[string]string "Provider mode is disabled"
+// @Router /oauth/userinfo [get]
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" || token == c.GetHeader("Authorization") {
+		c.Header("WWW-Authenticate", `Bearer`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token", "error_description": "bearer token required"})
+		return
+	}
+
+	info, err := h.authService.UserInfo(c.Request.Context(), token)
+	if err != nil {
+		respondOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// =============================================================================
+// Client Administration Endpoints
+// =============================================================================
+
+// CreateOAuthClient godoc
+// @Summary Register an OAuth client
+// @Description Register an application with the OpenID Connect provider. The client secret is only returned in this response. Public clients get no secret and must use PKCE.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.OAuthClientRequest true "Client details"
+// @Success 201 {object} response.Envelope{data=models.OAuthClient} "Client registered"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/oauth/clients [post]
+func (h *OAuthHandler) CreateOAuthClient(c *gin.Context) {
+	var req models.OAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	client, err := h.authService.CreateOAuthClient(c.Request.Context(), c.GetInt64("userID"), req)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, client)
+}
+
+// ListOAuthClients godoc
+// @Summary List OAuth clients
+// @Description Applications registered with the OpenID Connect provider, newest first. Secrets are never returned.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.OAuthClient} "Clients"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Router /admin/oauth/clients [get]
+func (h *OAuthHandler) ListOAuthClients(c *gin.Context) {
+	clients, err := h.authService.ListOAuthClients(c.Request.Context())
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, clients)
+}
+
+// DeleteOAuthClient godoc
+// @Summary Delete an OAuth client
+// @Description Remove a client; its authorization codes and refresh tokens stop working immediately
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Client record ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Client deleted"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Forbidden - Admin role required"
+// @Failure 404 {object} map[string]string "Client not found"
+// @Router /admin/oauth/clients/{id} [delete]
+func (h *OAuthHandler) DeleteOAuthClient(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client id"})
+		return
+	}
+
+	if err := h.authService.DeleteOAuthClient(c.Request.Context(), id, c.GetInt64("userID")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrOAuthClientNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	respondMessage(c, "client_deleted")
+}
+
+// =============================================================================
+// Granted Consent Endpoints
+// =============================================================================
+
+// ListOAuthGrants godoc
+// @Summary List authorized applications
+// @Description Applications the authenticated user has approved through the OpenID Connect provider, with the scopes granted to each
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.OAuthGrant} "Grants"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Router /user/consents [get]
+func (h *OAuthHandler) ListOAuthGrants(c *gin.Context) {
+	grants, err := h.authService.ListOAuthGrants(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, grants)
+}
+
+// RevokeOAuthGrant godoc
+// @Summary Revoke an application's access
+// @Description Withdraw the authenticated user's approval for a client. Its refresh tokens stop working immediately and the next sign-in asks for consent again.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param clientId path string true "Client ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Access revoked"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "No grant for this client"
+// @Router /user/consents/{clientId} [delete]
+func (h *OAuthHandler) RevokeOAuthGrant(c *gin.Context) {
+	if err := h.authService.RevokeOAuthGrant(c.Request.Context(), c.GetInt64("userID"), c.Param("clientId")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrOAuthGrantNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	respondMessage(c, "access_revoked")
+}
+
+// =============================================================================
+// Error Helpers
+// =============================================================================
+
+// oidcErrorStatus returns 404 when provider mode is disabled, otherwise fallback.
+func oidcErrorStatus(err error, fallback int) int {
+	if errors.Is(err, service.ErrOIDCDisabled) {
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+// respondOAuthError writes a token or userinfo endpoint error in the OAuth 2.0
+// format ({"error": ..., "error_description": ...}).
+func respondOAuthError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		if errors.Is(err, service.ErrOIDCDisabled) {
+			respondError(c, http.StatusNotFound, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case "invalid_client":
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="authentio"`)
+	case "invalid_token":
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	}
+	c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
@@ -0,0 +1,454 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"authentio/internal/models"
+	"authentio/internal/service"
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// OrganizationHandler Structure and Constructor
+// =============================================================================
+
+// OrganizationHandler handles organization and membership HTTP requests
+type OrganizationHandler struct {
+	authService service.AuthService
+}
+
+// NewOrganizationHandler creates a new OrganizationHandler instance
+func NewOrganizationHandler(authService service.AuthService) *OrganizationHandler {
+	return &OrganizationHandler{
+		authService: authService,
+	}
+}
+
+// =============================================================================
+// Organization Endpoints
+// =============================================================================
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create an organization owned by the authenticated user. The slug is derived from the name when omitted.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.OrganizationRequest true "Organization name and optional slug"
+// @Success 201 {object} response.Envelope{data=models.Organization} "Organization created"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 409 {object} map[string]string "Slug already taken"
+// @Router /orgs [post]
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req models.OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	org, err := h.authService.CreateOrganization(c.Request.Context(), c.GetInt64("userID"), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrOrgSlugTaken) {
+			status = http.StatusConflict
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, org)
+}
+
+// ListOrganizations godoc
+// @Summary List my organizations
+// @Description Organizations the authenticated user belongs to, with the user's role in each
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.Organization} "Organizations"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Router /orgs [get]
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.authService.ListUserOrganizations(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, orgs)
+}
+
+// GetOrganization godoc
+// @Summary Get an organization
+// @Description Organization details, with the authenticated user's role in it
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} response.Envelope{data=models.Organization} "Organization"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Organization not found or not a member"
+// @Router /orgs/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	org, err := h.authService.GetOrganization(c.Request.Context(), orgID, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, org)
+}
+
+// ListOrganizationMembers godoc
+// @Summary List organization members
+// @Description Members of an organization the authenticated user belongs to, in the order they joined
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} response.Envelope{data=[]models.OrganizationMember} "Members"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Organization not found or not a member"
+// @Router /orgs/{id}/members [get]
+func (h *OrganizationHandler) ListOrganizationMembers(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.authService.ListOrganizationMembers(c.Request.Context(), orgID, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, members)
+}
+
+// LeaveOrganization godoc
+// @Summary Leave an organization
+// @Description Remove the authenticated user from an organization. The last owner can't leave.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Left organization"
+// @Failure 400 {object} map[string]string "Last owner can't leave"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Organization not found or not a member"
+// @Router /orgs/{id}/members/me [delete]
+func (h *OrganizationHandler) LeaveOrganization(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.LeaveOrganization(c.Request.Context(), orgID, c.GetInt64("userID")); err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	respondMessage(c, "organization_left")
+}
+
+// SwitchOrganization godoc
+// @Summary Get organization-scoped tokens
+// @Description Issue a new token pair scoped to an organization. The access token carries org_id and org_role claims; refreshing keeps the scope while the user remains a member.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} response.Envelope{data=response.LoginResponse} "Organization-scoped tokens"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Organization not found or not a member"
+// @Router /orgs/{id}/switch [post]
+func (h *OrganizationHandler) SwitchOrganization(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.authService.SwitchOrganization(c.Request.Context(), orgID, c.GetInt64("userID"), c.GetTime("authTime"))
+	if err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, resp)
+}
+
+// =============================================================================
+// Member Management Endpoints
+// =============================================================================
+
+// UpdateOrganizationMemberRole godoc
+// @Summary Change a member's role
+// @Description Promote or demote a member (owner, admin or member). Owners can change any role; admins can only change members and grant at most admin. The last owner can't be demoted.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param userId path int true "Member user ID"
+// @Param request body models.OrganizationMemberRoleRequest true "New role"
+// @Success 200 {object} response.Envelope{data=models.OrganizationMember} "Updated membership"
+// @Failure 400 {object} map[string]string "Invalid input or last owner"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Not allowed to change this member's role"
+// @Failure 404 {object} map[string]string "Organization or member not found"
+// @Router /orgs/{id}/members/{userId} [put]
+func (h *OrganizationHandler) UpdateOrganizationMemberRole(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+	targetID, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.OrganizationMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	member, err := h.authService.UpdateOrganizationMemberRole(c.Request.Context(), orgID, targetID, c.GetInt64("userID"), req.Role)
+	if err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, member)
+}
+
+// RemoveOrganizationMember godoc
+// @Summary Remove a member
+// @Description Remove a member from the organization. Owners can remove anyone; admins can only remove members. The last owner can't be removed.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param userId path int true "Member user ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Member removed"
+// @Failure 400 {object} map[string]string "Last owner can't be removed"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Not allowed to remove this member"
+// @Failure 404 {object} map[string]string "Organization or member not found"
+// @Router /orgs/{id}/members/{userId} [delete]
+func (h *OrganizationHandler) RemoveOrganizationMember(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+	targetID, ok := memberIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.RemoveOrganizationMember(c.Request.Context(), orgID, targetID, c.GetInt64("userID")); err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	respondMessage(c, "member_removed")
+}
+
+// =============================================================================
+// Organization Invitation Endpoints
+// =============================================================================
+
+// CreateOrganizationInvitation godoc
+// @Summary Invite someone to an organization
+// @Description Email an invite link for joining the organization. Existing users accept it with POST /orgs/invitations/accept; new users pass it as org_invite_token when registering. Owners and admins only; admins can't invite owners.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param request body models.OrganizationInvitationRequest true "Invitee email and role (default member)"
+// @Success 201 {object} response.Envelope{data=models.OrganizationInvitation} "Invitation sent"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Not allowed to manage the organization"
+// @Failure 404 {object} map[string]string "Organization not found or not a member"
+// @Failure 409 {object} map[string]string "Already a member"
+// @Router /orgs/{id}/invitations [post]
+func (h *OrganizationHandler) CreateOrganizationInvitation(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.OrganizationInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	invitation, err := h.authService.CreateOrganizationInvitation(c.Request.Context(), orgID, c.GetInt64("userID"), req)
+	if err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, invitation)
+}
+
+// ListOrganizationInvitations godoc
+// @Summary List organization invitations
+// @Description Invitations sent for the organization, newest first. Owners and admins only.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Success 200 {object} response.Envelope{data=[]models.OrganizationInvitation} "Invitations"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Not allowed to manage the organization"
+// @Failure 404 {object} map[string]string "Organization not found or not a member"
+// @Router /orgs/{id}/invitations [get]
+func (h *OrganizationHandler) ListOrganizationInvitations(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+
+	invitations, err := h.authService.ListOrganizationInvitations(c.Request.Context(), orgID, c.GetInt64("userID"))
+	if err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, invitations)
+}
+
+// RevokeOrganizationInvitation godoc
+// @Summary Revoke an organization invitation
+// @Description Cancel a pending invitation so its link can no longer be used. Owners and admins only.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Organization ID"
+// @Param invitationId path int true "Invitation ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Invitation revoked"
+// @Failure 400 {object} map[string]string "Invitation is no longer pending"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} map[string]string "Not allowed to manage the organization"
+// @Failure 404 {object} map[string]string "Organization or invitation not found"
+// @Router /orgs/{id}/invitations/{invitationId} [delete]
+func (h *OrganizationHandler) RevokeOrganizationInvitation(c *gin.Context) {
+	orgID, ok := orgIDParam(c)
+	if !ok {
+		return
+	}
+	invitationID, err := strconv.ParseInt(c.Param("invitationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation id"})
+		return
+	}
+
+	if err := h.authService.RevokeOrganizationInvitation(c.Request.Context(), orgID, invitationID, c.GetInt64("userID")); err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	respondMessage(c, "invitation_revoked")
+}
+
+// AcceptOrganizationInvitation godoc
+// @Summary Accept an organization invitation
+// @Description Join the organization an invite token was issued for. The invitation must be addressed to the authenticated user's email.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AcceptOrganizationInvitationRequest true "Invite token"
+// @Success 200 {object} response.Envelope{data=models.Organization} "Joined organization"
+// @Failure 400 {object} map[string]string "Invalid, expired or already used invitation"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 409 {object} map[string]string "Already a member"
+// @Router /orgs/invitations/accept [post]
+func (h *OrganizationHandler) AcceptOrganizationInvitation(c *gin.Context) {
+	var req models.AcceptOrganizationInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	org, err := h.authService.AcceptOrganizationInvitation(c.Request.Context(), c.GetInt64("userID"), req.Token)
+	if err != nil {
+		respondError(c, orgErrorStatus(err), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, org)
+}
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+// orgIDParam parses the :id path parameter, writing a 400 response when it
+// isn't a valid ID.
+func orgIDParam(c *gin.Context) (int64, bool) {
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization id"})
+		return 0, false
+	}
+	return orgID, true
+}
+
+// memberIDParam parses the :userId path parameter, writing a 400 response when
+// it isn't a valid ID.
+func memberIDParam(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// orgErrorStatus maps organization service errors to HTTP statuses.
+func orgErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrOrgNotFound), errors.Is(err, service.ErrOrgMemberNotFound), errors.Is(err, service.ErrInvitationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrOrgPermission):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrAlreadyOrgMember):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrLastOrgOwner), errors.Is(err, service.ErrInvalidInvite):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -40,13 +40,6 @@ type ResetPasswordRequest struct {
 // TWO-FACTOR AUTHENTICATION REQUEST DTOs
 // =============================================================================
 
-// Verify2FARequest represents a two-factor authentication verification request
-// Used in: POST /auth/2fa/verify
-type Verify2FARequest struct {
-    Email string `json:"email" binding:"required,email"`  // User's email address
-    Code  string `json:"code" binding:"required"`         // 2FA verification code
-}
-
 // SendOTPRequest represents a request to send OTP for two-factor authentication
 // Used in: POST /2fa/sendOtp
 type SendOTPRequest struct {
@@ -67,7 +60,16 @@ type VerifyOTPRequest struct {
 // GoogleLoginRequest represents a Google OAuth2 authentication request
 // Used in: POST /auth/google/login
 type GoogleLoginRequest struct {
-    IDToken string `json:"id_token" binding:"required"`  // Google ID token from frontend OAuth flow
+    IDToken     string `json:"id_token" binding:"required"`  // Google ID token from frontend OAuth flow
+    InviteToken string `json:"invite_token,omitempty"`       // Required for new users when INVITE_ONLY_REGISTRATION is enabled
+    OrgInviteToken string `json:"org_invite_token,omitempty"` // Joins a new user to the inviting organization
+}
+
+// FederatedLoginRequest represents a sign-in with a token issued by the
+// external identity provider
+// Used in: POST /auth/federated/login
+type FederatedLoginRequest struct {
+    Token string `json:"token" binding:"required"` // JWT issued by FEDERATED_ISSUER (ID or access token)
 }
 
 
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"authentio/internal/middleware"
+	"authentio/internal/service"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// SessionHandler Structure and Constructor
+// =============================================================================
+
+// sessionPath is the path of the session endpoints, to which the session
+// cookie is scoped.
+const sessionPath = "/auth/session"
+
+// SessionCookie configures the cookie keeping the refresh token of
+// single-page apps (SESSION_COOKIE_*).
+type SessionCookie struct {
+	Name           string
+	Domain         string
+	SameSite       http.SameSite
+	Secure         bool
+	MaxAge         time.Duration // REFRESH_TOKEN_TTL
+	AllowedOrigins []string      // origins the renewal page posts tokens to
+}
+
+// SessionHandler handles the cookie sessions of single-page apps: the
+// refresh token is kept in an HttpOnly cookie, so scripts only ever see
+// access tokens.
+type SessionHandler struct {
+	authService service.AuthService
+	cookie      SessionCookie
+}
+
+// NewSessionHandler creates a new SessionHandler instance
+func NewSessionHandler(authService service.AuthService, cookie SessionCookie) *SessionHandler {
+	return &SessionHandler{
+		authService: authService,
+		cookie:      cookie,
+	}
+}
+
+// =============================================================================
+// Cookie Session Endpoints
+// =============================================================================
+
+// StartSession godoc
+// @Summary Start a cookie session
+// @Description Move a session into an HttpOnly cookie for a single-page app: the refresh token is rotated, the new one set as the session cookie and only an access token returned. Renew it with POST /auth/session/renew, and don't keep the refresh token sent.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token of the session"
+// @Success 200 {object} response.Envelope{data=response.SessionToken} "Session cookie set"
+// @Failure 400 {object} map[string]string "Invalid or expired refresh token, or session ended"
+// @Failure 403 {object} map[string]string "Origin not allowed, or account awaiting or refused registration approval"
+// @Router /auth/session [post]
+func (h *SessionHandler) StartSession(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondError(c, loginErrorStatus(err, http.StatusBadRequest), err)
+		return
+	}
+	h.setCookie(c, result.RefreshToken)
+	response.JSON(c, http.StatusOK, sessionToken(result))
+}
+
+// RenewSession godoc
+// @Summary Renew a cookie session
+// @Description Issue a fresh access token from the session cookie, rotating it. Called with credentials (fetch credentials: "include") by the apps of SESSION_ALLOWED_ORIGINS.
+// @Tags authentication
+// @Produce json
+// @Success 200 {object} response.Envelope{data=response.SessionToken} "New access token"
+// @Failure 401 {object} map[string]string "No session cookie, or session expired or ended (cookie cleared)"
+// @Failure 403 {object} map[string]string "Origin not allowed, or account awaiting or refused registration approval"
+// @Router /auth/session/renew [post]
+func (h *SessionHandler) RenewSession(c *gin.Context) {
+	result, err := h.renew(c)
+	if err != nil {
+		respondError(c, sessionErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, sessionToken(result))
+}
+
+// renewFrame is the page served to hidden iframes renewing a session. It
+// posts its message to the parent page, only if the parent has origin.
+var renewFrame = template.Must(template.New("renew").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Session renewal</title></head>
+<body><script>window.parent.postMessage({{.Message}}, {{.Origin}});</script></body></html>
+`))
+
+// RenewSessionFrame godoc
+// @Summary Renew a cookie session in an iframe
+// @Description Page for a hidden iframe of an app: renews the session like POST /auth/session/renew and posts {type: "authentio:session", access_token, expires_in} or {type: "authentio:session", error} to the parent window, whose origin must be one of SESSION_ALLOWED_ORIGINS. Check event.origin before trusting the message.
+// @Tags authentication
+// @Produce html
+// @Param origin query string true "Origin of the parent page"
+// @Success 200 {string} string "Renewal page"
+// @Failure 403 {object} map[string]string "Origin not allowed"
+// @Router /auth/session/renew [get]
+func (h *SessionHandler) RenewSessionFrame(c *gin.Context) {
+	origin := c.Query("origin")
+	if !middleware.SessionOriginAllowed(origin, h.cookie.AllowedOrigins) {
+		respondError(c, http.StatusForbidden, service.ErrOriginNotAllowed)
+		return
+	}
+
+	message := gin.H{"type": "authentio:session"}
+	result, err := h.renew(c)
+	var svcErr *service.ServiceError
+	switch {
+	case err == nil:
+		message["access_token"] = result.AccessToken
+		message["expires_in"] = result.ExpiresIn
+	case errors.As(err, &svcErr):
+		message["error"] = svcErr.Code
+	default:
+		logger.Error("failed to renew session", "error", err)
+		message["error"] = "internal_error"
+	}
+
+	var page bytes.Buffer
+	if err := renewFrame.Execute(&page, gin.H{"Message": message, "Origin": origin}); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// EndSession godoc
+// @Summary End a cookie session
+// @Description Sign out of a cookie session: its refresh token and last access token are revoked and the cookie cleared.
+// @Tags authentication
+// @Produce json
+// @Success 200 {object} response.Envelope{data=map[string]string} "Session ended"
+// @Failure 403 {object} map[string]string "Origin not allowed"
+// @Router /auth/session [delete]
+func (h *SessionHandler) EndSession(c *gin.Context) {
+	if token, err := c.Cookie(h.cookie.Name); err == nil && token != "" {
+		if err := h.authService.Logout(c.Request.Context(), token); err != nil {
+			respondError(c, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	h.clearCookie(c)
+	respondMessage(c, "session_ended")
+}
+
+// =============================================================================
+// Session Cookie Helpers
+// =============================================================================
+
+// renew rotates the refresh token of the session cookie. The cookie is
+// cleared when the session can't be renewed anymore.
+func (h *SessionHandler) renew(c *gin.Context) (*response.LoginResponse, error) {
+	token, err := c.Cookie(h.cookie.Name)
+	if err != nil || token == "" {
+		return nil, service.ErrSessionCookieMissing
+	}
+
+	result, err := h.authService.RefreshToken(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrSessionMaxAge) || errors.Is(err, service.ErrSessionIdle) {
+			h.clearCookie(c)
+		}
+		return nil, err
+	}
+	h.setCookie(c, result.RefreshToken)
+	return result, nil
+}
+
+func (h *SessionHandler) setCookie(c *gin.Context, token string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     h.cookie.Name,
+		Value:    token,
+		Path:     cookiePath(c),
+		Domain:   h.cookie.Domain,
+		MaxAge:   int(h.cookie.MaxAge.Seconds()),
+		Secure:   h.cookie.Secure,
+		HttpOnly: true,
+		SameSite: h.cookie.SameSite,
+	})
+}
+
+func (h *SessionHandler) clearCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     h.cookie.Name,
+		Path:     cookiePath(c),
+		Domain:   h.cookie.Domain,
+		MaxAge:   -1,
+		Secure:   h.cookie.Secure,
+		HttpOnly: true,
+		SameSite: h.cookie.SameSite,
+	})
+}
+
+// cookiePath returns the path of the session endpoints as the browser sees
+// it, including a /t/{tenant} prefix removed before routing.
+func cookiePath(c *gin.Context) string {
+	path, _, _ := strings.Cut(c.Request.RequestURI, "?")
+	if i := strings.Index(path, sessionPath); i >= 0 {
+		return path[:i+len(sessionPath)]
+	}
+	return "/"
+}
+
+// sessionToken keeps the access token of a refresh, leaving out the refresh
+// token.
+func sessionToken(result *response.LoginResponse) *response.SessionToken {
+	return &response.SessionToken{
+		User:        result.User,
+		AccessToken: result.AccessToken,
+		ExpiresIn:   result.ExpiresIn,
+	}
+}
@@ -2,8 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+
 	// _"authentio/internal/handler"
+	"authentio/internal/models"
 	"authentio/internal/service"
+	"authentio/pkg/response"
 	"github.com/gin-gonic/gin"
 )
 
@@ -34,7 +38,7 @@ func NewTwoFAHandler(authService service.AuthService) *TwoFAHandler {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} map[string]string "2FA enabled successfully"
+// @Success 200 {object} response.Envelope{data=map[string]string} "2FA enabled successfully"
 // @Failure 400 {object} map[string]string "Failed to enable 2FA"
 // @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
 // @Failure 500 {object} map[string]string "Internal server error"
@@ -48,21 +52,21 @@ func (h *TwoFAHandler) EnableEmail2FA(c *gin.Context) {
 	}
 
 	if err := h.authService.EnableEmail2FA(c.Request.Context(), userID.(int64)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "2FA enabled successfully"})
+	respondMessage(c, "two_factor_enabled")
 }
 
 // Disable2FA godoc
 // @Summary Disable 2FA
-// @Description Disable two-factor authentication for the authenticated user
+// @Description Disable two-factor authentication for the authenticated user. The user is always alerted by email.
 // @Tags 2fa
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} map[string]string "2FA disabled successfully"
+// @Success 200 {object} response.Envelope{data=map[string]string} "2FA disabled successfully"
 // @Failure 400 {object} map[string]string "Failed to disable 2FA"
 // @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
 // @Failure 500 {object} map[string]string "Internal server error"
@@ -76,11 +80,11 @@ func (h *TwoFAHandler) Disable2FA(c *gin.Context) {
 	}
 
 	if err := h.authService.Disable2FA(c.Request.Context(), userID.(int64)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "2FA disabled successfully"})
+	respondMessage(c, "two_factor_disabled")
 }
 
 // =============================================================================
@@ -94,8 +98,10 @@ func (h *TwoFAHandler) Disable2FA(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body SendOTPRequest true "Email address to send OTP"
-// @Success 200 {object} map[string]string "OTP sent successfully"
+// @Param Idempotency-Key header string false "Client-generated key; retries with the same key replay the first response"
+// @Success 200 {object} response.Envelope{data=map[string]string} "OTP sent successfully"
 // @Failure 400 {object} map[string]string "Invalid email format or user not found"
+// @Failure 429 {object} map[string]interface{} "Code requested too soon or too often for this email (Retry-After set), or tenant's daily OTP email quota used up"
 // @Failure 500 {object} map[string]string "Failed to send OTP email"
 // @Router /2fa/sendOtp [post]
 func (h *TwoFAHandler) SendOTP(c *gin.Context) {
@@ -106,16 +112,16 @@ func (h *TwoFAHandler) SendOTP(c *gin.Context) {
 	// var req SendOTPRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.authService.Send2FAOTP(c.Request.Context(), req.Email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, quotaErrorStatus(err, http.StatusInternalServerError), err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "OTP sent successfully"})
+	respondMessage(c, "otp_sent")
 }
 
 // VerifyOTP godoc
@@ -125,7 +131,7 @@ func (h *TwoFAHandler) SendOTP(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body VerifyOTPRequest true "OTP verification data"
-// @Success 200 {object} map[string]string "OTP verified successfully"
+// @Success 200 {object} response.Envelope{data=map[string]string} "OTP verified successfully"
 // @Failure 400 {object} map[string]string "Invalid OTP code, expired code, or invalid email"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /2fa/verifyOtp [post]
@@ -138,14 +144,267 @@ func (h *TwoFAHandler) VerifyOTP(c *gin.Context) {
 	// var req VerifyOTPRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.authService.Verify2FA(c.Request.Context(), req.Email, req.Code); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	respondMessage(c, "otp_verified")
+}
+// =============================================================================
+// Push Approval Endpoints (Protected - Called by the mobile app)
+// =============================================================================
+
+// EnablePush2FA godoc
+// @Summary Enable push approval 2FA
+// @Description Require logins to be approved on one of the user's registered devices. At least one device must be registered.
+// @Tags 2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=map[string]string} "Push approval enabled"
+// @Failure 400 {object} map[string]string "No device registered"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Push notifications are not configured"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/enablePush [post]
+func (h *TwoFAHandler) EnablePush2FA(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authService.EnablePush2FA(c.Request.Context(), userID.(int64)); err != nil {
+		respondError(c, pushErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "push_enabled")
+}
+
+// RegisterPushDevice godoc
+// @Summary Register a device for push approval
+// @Description Register the device the mobile app runs on with its FCM or APNs token. Registering a known token updates its name.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.RegisterDeviceRequest true "Device"
+// @Success 201 {object} response.Envelope{data=models.PushDevice} "Registered device"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Push notifications are not configured for the platform"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/devices [post]
+func (h *TwoFAHandler) RegisterPushDevice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	device, err := h.authService.RegisterPushDevice(c.Request.Context(), userID.(int64), req)
+	if err != nil {
+		respondError(c, pushErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, device)
+}
+
+// ListPushDevices godoc
+// @Summary List push approval devices
+// @Description List the devices registered for push approval by the authenticated user
+// @Tags 2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.PushDevice} "Devices"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/devices [get]
+func (h *TwoFAHandler) ListPushDevices(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	devices, err := h.authService.ListPushDevices(c.Request.Context(), userID.(int64))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "OTP verified successfully"})
-}
\ No newline at end of file
+	response.JSON(c, http.StatusOK, devices)
+}
+
+// RemovePushDevice godoc
+// @Summary Remove a push approval device
+// @Description Unregister a device. The last device can't be removed while push approval is enabled.
+// @Tags 2fa
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Device ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Device removed"
+// @Failure 400 {object} map[string]string "Invalid device ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Device not found"
+// @Failure 409 {object} map[string]string "Last device while push approval is enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/devices/{id} [delete]
+func (h *TwoFAHandler) RemovePushDevice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	deviceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid device id"})
+		return
+	}
+
+	if err := h.authService.RemovePushDevice(c.Request.Context(), userID.(int64), deviceID); err != nil {
+		respondError(c, pushErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "device_removed")
+}
+
+// ListPushChallenges godoc
+// @Summary List pending login approvals
+// @Description List the logins waiting for the authenticated user's approval, newest first
+// @Tags 2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=[]models.PushChallenge} "Pending logins"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/push/challenges [get]
+func (h *TwoFAHandler) ListPushChallenges(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	challenges, err := h.authService.ListPushChallenges(c.Request.Context(), userID.(int64))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, challenges)
+}
+
+// DecidePushChallenge godoc
+// @Summary Approve or deny a login
+// @Description Approve or deny a pending login of the authenticated user
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param challengeId path string true "Challenge ID"
+// @Param request body models.PushDecisionRequest true "Decision"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Decision recorded"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "No pending login with this ID"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/push/challenges/{challengeId} [post]
+func (h *TwoFAHandler) DecidePushChallenge(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.PushDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.DecidePushChallenge(c.Request.Context(), userID.(int64), c.Param("challengeId"), *req.Approve); err != nil {
+		respondError(c, pushErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	if *req.Approve {
+		respondMessage(c, "login_approved")
+		return
+	}
+	respondMessage(c, "login_denied")
+}
+
+// GenerateRecoveryCodes godoc
+// @Summary Generate recovery codes
+// @Description Issue a new set of single-use recovery codes, replacing any earlier ones. Each code completes one login when no push approval device is at hand. The codes are shown only in this response.
+// @Tags 2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.RecoveryCodes} "New recovery codes"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/recovery-codes [post]
+func (h *TwoFAHandler) GenerateRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	codes, err := h.authService.GenerateRecoveryCodes(c.Request.Context(), userID.(int64))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, codes)
+}
+
+// GetRecoveryCodes godoc
+// @Summary Count recovery codes
+// @Description Return how many unused recovery codes the authenticated user has left
+// @Tags 2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.RecoveryCodes} "Remaining recovery codes"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/recovery-codes [get]
+func (h *TwoFAHandler) GetRecoveryCodes(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	codes, err := h.authService.RecoveryCodesRemaining(c.Request.Context(), userID.(int64))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, codes)
+}
@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
+	"authentio/internal/models"
 	"authentio/internal/service"
+	"authentio/pkg/i18n"
+	"authentio/pkg/pagination"
+	"authentio/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,6 +34,95 @@ func NewUserHandler(authService service.AuthService) *UserHandler {
 // Profile Management Endpoints
 // =============================================================================
 
+// GetSecurityOverview godoc
+// @Summary Get security overview
+// @Description Summarize the authenticated user's account security in one call: 2FA methods, recovery codes remaining, push devices, active sessions, last login (time, IP address and device), password age and sign-in methods
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=response.SecurityOverview} "Security overview"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/security [get]
+func (h *UserHandler) GetSecurityOverview(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	overview, err := h.authService.SecurityOverview(c.Request.Context(), userID.(int64))
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			respondError(c, http.StatusNotFound, err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, overview)
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description List the authenticated user's signed-in sessions, newest first, one page at a time. Pass meta.next_cursor back as cursor to get the following page; it is omitted on the last one.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "meta.next_cursor of the previous page"
+// @Success 200 {object} response.Envelope{data=[]response.Session,meta=response.PageMeta} "Page of sessions"
+// @Failure 400 {object} map[string]string "Invalid limit or cursor"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/sessions [get]
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	page, err := pagination.NewRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), c.GetInt64("userID"), page)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.Page(c, sessions)
+}
+
+// GetLoginHistory godoc
+// @Summary Get login history
+// @Description List the successful and failed sign-ins on the authenticated user's account, newest first, one page at a time. Pass meta.next_cursor back as cursor to get the following page; it is omitted on the last one.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "meta.next_cursor of the previous page"
+// @Success 200 {object} response.Envelope{data=[]response.LoginEvent,meta=response.PageMeta} "Page of sign-ins"
+// @Failure 400 {object} map[string]string "Invalid limit or cursor"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/login-history [get]
+func (h *UserHandler) GetLoginHistory(c *gin.Context) {
+	page, err := pagination.NewRequest(c.Query("limit"), c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	history, err := h.authService.LoginHistory(c.Request.Context(), c.GetInt64("userID"), page)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.Page(c, history)
+}
+
 // GetProfile godoc
 // @Summary Get user profile
 // @Description Retrieve the authenticated user's profile information
@@ -35,7 +130,9 @@ func NewUserHandler(authService service.AuthService) *UserHandler {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} map[string]interface{} "User profile retrieved successfully"
+// @Param If-None-Match header string false "ETag of the profile the client already has"
+// @Success 200 {object} response.Envelope{data=map[string]interface{}} "User profile retrieved successfully"
+// @Success 304 "Profile unchanged since the ETag in If-None-Match"
 // @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
 // @Failure 404 {object} map[string]string "User not found"
 // @Failure 500 {object} map[string]string "Internal server error"
@@ -50,11 +147,11 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 
 	profile, err := h.authService.GetUserProfile(c.Request.Context(), userID.(int64))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, profile)
+	response.JSON(c, http.StatusOK, profile)
 }
 
 // UpdateProfile godoc
@@ -65,7 +162,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param request body UpdateProfileRequest true "Profile update data"
-// @Success 200 {object} map[string]string "Profile updated successfully"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Profile updated successfully"
 // @Failure 400 {object} map[string]string "Invalid input data"
 // @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
 // @Failure 409 {object} map[string]string "Email already exists"
@@ -87,14 +184,840 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	 var req UpdateProfileRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
 	if err := h.authService.UpdateProfile(c.Request.Context(), userID.(int64), req.FirstName, req.LastName, req.Email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondMessage(c, "profile_updated")
+}
+
+// GetProfileDetails godoc
+// @Summary Get profile details
+// @Description Retrieve the authenticated user's profile: names, display name, bio and avatar
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param If-None-Match header string false "ETag of the profile the client already has"
+// @Success 200 {object} response.Envelope{data=models.UserProfile} "User profile"
+// @Success 304 "Profile unchanged since the ETag in If-None-Match"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/profile [get]
+func (h *UserHandler) GetProfileDetails(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profile, err := h.authService.GetProfile(c.Request.Context(), userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, profile)
+}
+
+// SaveProfile godoc
+// @Summary Update profile details
+// @Description Update the authenticated user's names, display name, bio or avatar URL. Omitted fields are left unchanged; an empty avatar_url removes the picture.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateProfileRequest true "Profile fields to change"
+// @Success 200 {object} response.Envelope{data=models.UserProfile} "Updated profile"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/profile [put]
+func (h *UserHandler) SaveProfile(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	profile, err := h.authService.SaveProfile(c.Request.Context(), userID.(int64), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, profile)
+}
+
+// GetPreferences godoc
+// @Summary Get notification preferences
+// @Description Retrieve which optional notifications the authenticated user receives and on which channels. Users who never saved preferences get the defaults: security alert emails on, everything else off.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.NotificationPreferences} "Notification preferences"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/preferences [get]
+func (h *UserHandler) GetPreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs, err := h.authService.GetPreferences(c.Request.Context(), userID.(int64))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, prefs)
+}
+
+// UpdatePreferences godoc
+// @Summary Update notification preferences
+// @Description Turn optional notifications on or off per channel: email, text message (to phone_number, in E.164 format) and push devices. Omitted fields are left unchanged; an empty phone_number removes it and turns text messages off. Verification codes, password resets and invitations are always sent.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdatePreferencesRequest true "Preferences to change"
+// @Success 200 {object} response.Envelope{data=models.NotificationPreferences} "Updated preferences"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/preferences [put]
+func (h *UserHandler) UpdatePreferences(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	prefs, err := h.authService.UpdatePreferences(c.Request.Context(), userID.(int64), req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrUserNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrInvalidPhoneNumber), errors.Is(err, service.ErrPhoneNumberRequired):
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, prefs)
+}
+
+// SetUsername godoc
+// @Summary Choose or change username
+// @Description Set the authenticated user's username, which can be used instead of the email to log in. It appears in access tokens issued from the next login or refresh.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UsernameRequest true "New username"
+// @Success 200 {object} response.Envelope{data=response.UserResponse} "Updated user"
+// @Failure 400 {object} map[string]string "Invalid username"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 409 {object} map[string]string "Username not available"
+// @Router /user/username [put]
+func (h *UserHandler) SetUsername(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.UsernameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	user, err := h.authService.SetUsername(c.Request.Context(), userID.(int64), req.Username)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrUsernameTaken):
+			status = http.StatusConflict
+		case errors.Is(err, service.ErrInvalidUsername):
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, user)
+}
+
+// Reauthenticate godoc
+// @Summary Re-authenticate for sudo mode
+// @Description Confirm the authenticated user's password to get an access token carrying the sudo scope for SUDO_TTL. Admin operations that change anything require it and otherwise return 403 with code sudo_required. Refreshing tokens drops the scope. Users with 2FA also confirm a code: called without one, a code is emailed to them and 403 returned with code two_factor_code_required, so call again with the password and the code.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ReauthenticateRequest true "Current password, and the emailed code for users with 2FA"
+// @Success 200 {object} response.Envelope{data=response.SudoToken} "Sudo access token"
+// @Failure 400 {object} map[string]string "Invalid input data, or the account has no password"
+// @Failure 401 {object} map[string]string "Unauthorized, or wrong password or code"
+// @Failure 403 {object} map[string]string "Code emailed, send it with the password (two_factor_code_required), or account awaiting or refused approval"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 429 {object} map[string]string "A code was emailed recently, or too many codes today"
+// @Router /user/reauthenticate [post]
+func (h *UserHandler) Reauthenticate(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	token, err := h.authService.Reauthenticate(c.Request.Context(), userID.(int64), req.Password, req.Code)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidOTP):
+			status = http.StatusUnauthorized
+		case errors.Is(err, service.ErrTwoFactorCodeRequired):
+			status = http.StatusForbidden
+		case errors.Is(err, service.ErrNoPassword):
+			status = http.StatusBadRequest
+		case errors.Is(err, service.ErrUserNotFound):
+			status = http.StatusNotFound
+		}
+		respondError(c, quotaErrorStatus(err, loginErrorStatus(err, status)), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, token)
+}
+
+// UpdateUserMetadata godoc
+// @Summary Update own user metadata
+// @Description Merge keys into the authenticated user's user_metadata. Keys set to null are removed; app_metadata can only be changed by admins.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MetadataUpdate true "Keys to set or remove"
+// @Success 200 {object} response.Envelope{data=map[string]interface{}} "Updated user_metadata"
+// @Failure 400 {object} map[string]string "Invalid JSON or metadata too large"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/metadata [patch]
+func (h *UserHandler) UpdateUserMetadata(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var update models.MetadataUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	metadata, err := h.authService.UpdateUserMetadata(c.Request.Context(), userID.(int64), update)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrMetadataTooLarge) {
+			status = http.StatusBadRequest
+		}
+		respondError(c, status, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, gin.H{"user_metadata": metadata})
+}
+
+// UploadAvatar godoc
+// @Summary Upload profile picture
+// @Description Upload a JPEG, PNG, GIF or WebP image as the authenticated user's avatar. It is cropped to a square, resized and stored; the response carries its public URL in avatar_url.
+// @Tags user
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param avatar formData file true "Image file (AVATAR_MAX_BYTES, default 5 MiB)"
+// @Success 200 {object} response.Envelope{data=response.UserResponse} "Avatar uploaded"
+// @Failure 400 {object} map[string]string "Missing file or not a supported image"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Avatar uploads are not enabled"
+// @Failure 413 {object} map[string]string "Image too large"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/avatar [post]
+func (h *UserHandler) UploadAvatar(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.authService.AvatarUploadLimit())
+	fileHeader, err := c.FormFile("avatar")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(c, http.StatusRequestEntityTooLarge, service.ErrImageTooLarge)
+			return
+		}
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+
+	profile, err := h.authService.UploadAvatar(c.Request.Context(), userID.(int64), file)
+	if err != nil {
+		respondError(c, avatarErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, profile)
+}
+
+// RemoveAvatar godoc
+// @Summary Remove profile picture
+// @Description Clear the authenticated user's avatar and delete the stored image
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=map[string]string} "Avatar removed"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/avatar [delete]
+func (h *UserHandler) RemoveAvatar(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authService.RemoveAvatar(c.Request.Context(), userID.(int64)); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
-}
\ No newline at end of file
+	respondMessage(c, "avatar_removed")
+}
+
+// =============================================================================
+// Account Erasure Endpoints
+// =============================================================================
+
+// DeleteAccount godoc
+// @Summary Delete (anonymize) own account
+// @Description Permanently scrub the authenticated user's personal data (right to be forgotten). Sessions are revoked and the account can no longer be used.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=map[string]string} "Account deleted successfully"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/account [delete]
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authService.AnonymizeUser(c.Request.Context(), userID.(int64), userID.(int64)); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondMessage(c, "account_deleted")
+}
+
+// =============================================================================
+// Legal Consent Endpoints
+// =============================================================================
+
+// GetConsent godoc
+// @Summary Get legal consent status
+// @Description Return the Terms of Service / Privacy Policy versions the user accepted and whether re-acceptance of a newer version is required
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=response.ConsentStatus} "Consent status"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/consent [get]
+func (h *UserHandler) GetConsent(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	status, err := h.authService.GetConsentStatus(c.Request.Context(), userID.(int64))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, status)
+}
+
+// AcceptConsent godoc
+// @Summary Accept current legal documents
+// @Description Record acceptance of the current Terms of Service and Privacy Policy versions
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ConsentRequest true "Accepted document versions"
+// @Success 200 {object} response.Envelope{data=response.ConsentStatus} "Consent recorded"
+// @Failure 400 {object} map[string]string "Invalid input or outdated document versions"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Router /user/consent [post]
+func (h *UserHandler) AcceptConsent(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.ConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	status, err := h.authService.AcceptConsent(c.Request.Context(), userID.(int64), req)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, status)
+}
+
+// UpdateLocale godoc
+// @Summary Set preferred language
+// @Description Save the language used for validation, error and confirmation messages. It overrides Accept-Language once a new access token is issued (login or refresh).
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.LocaleRequest true "Preferred locale (en, fr, es)"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Locale updated"
+// @Failure 400 {object} map[string]string "Invalid input or unsupported locale"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Router /user/locale [put]
+func (h *UserHandler) UpdateLocale(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.LocaleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.UpdateLocale(c.Request.Context(), userID.(int64), req.Locale); err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Confirmed in the language just chosen
+	response.JSON(c, http.StatusOK, gin.H{"message": i18n.T(req.Locale, "message.locale_updated"), "locale": req.Locale})
+}
+
+// =============================================================================
+// Device Registry Endpoints
+// =============================================================================
+
+// ListDevices godoc
+// @Summary List devices
+// @Description List the devices the authenticated user signed in from, most recently seen first. Devices are recognized by the X-Device-Fingerprint header; the one making the request has "current": true.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param X-Device-Fingerprint header string false "Identifier the client generated for this device"
+// @Success 200 {object} response.Envelope{data=[]models.Device} "Devices"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/devices [get]
+func (h *UserHandler) ListDevices(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	devices, err := h.authService.ListDevices(c.Request.Context(), userID.(int64))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, devices)
+}
+
+// RenameDevice godoc
+// @Summary Rename a device
+// @Description Give one of the authenticated user's devices a name; an empty name clears it
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Device ID"
+// @Param request body models.RenameDeviceRequest true "New name"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Device renamed"
+// @Failure 400 {object} map[string]string "Invalid device ID or name"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Device not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/devices/{id} [patch]
+func (h *UserHandler) RenameDevice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	deviceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid device id"})
+		return
+	}
+
+	var req models.RenameDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.RenameDevice(c.Request.Context(), userID.(int64), deviceID, req.Name); err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			respondError(c, http.StatusNotFound, err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondMessage(c, "device_renamed")
+}
+
+// RevokeDevice godoc
+// @Summary Revoke a device
+// @Description Sign out every session of one of the authenticated user's devices, revoking their refresh and last access tokens, and forget it. The device is registered again if it signs in later.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Device ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Device revoked"
+// @Failure 400 {object} map[string]string "Invalid device ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Device not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/devices/{id} [delete]
+func (h *UserHandler) RevokeDevice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	deviceID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid device id"})
+		return
+	}
+
+	if err := h.authService.RevokeDevice(c.Request.Context(), userID.(int64), deviceID); err != nil {
+		if errors.Is(err, service.ErrDeviceNotFound) {
+			respondError(c, http.StatusNotFound, err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	respondMessage(c, "device_revoked")
+}
+
+// GetIPAllowlist godoc
+// @Summary Get the IP allowlist
+// @Description The addresses and CIDR blocks the authenticated user's account may be used from, and the mode applied to logins from anywhere else ("block" refuses them, "verify" requires a second factor). Without a permanent entry the account may be used from anywhere; entries with expires_at were added after a verified login.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} response.Envelope{data=models.IPAllowlist} "Allowlist"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/ip-allowlist [get]
+func (h *UserHandler) GetIPAllowlist(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	list, err := h.authService.GetIPAllowlist(c.Request.Context(), userID.(int64))
+	if err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusOK, list)
+}
+
+// AddIPAllowlistEntry godoc
+// @Summary Add an IP allowlist entry
+// @Description Allow an address or CIDR block for the authenticated user's account. Once it has an entry, the account can't be used from other addresses. Refused with 409 if the current address would be locked out.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AddIPAllowlistEntryRequest true "Address or CIDR block"
+// @Success 201 {object} response.Envelope{data=models.IPAllowlistEntry} "Entry added"
+// @Failure 400 {object} map[string]string "Invalid address or CIDR block"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 409 {object} map[string]string "The current address would be refused"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/ip-allowlist [post]
+func (h *UserHandler) AddIPAllowlistEntry(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.AddIPAllowlistEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	entry, err := h.authService.AddIPAllowlistEntry(c.Request.Context(), userID.(int64), userID.(int64), req)
+	if err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	response.JSON(c, http.StatusCreated, entry)
+}
+
+// RemoveIPAllowlistEntry godoc
+// @Summary Remove an IP allowlist entry
+// @Description Remove one of the authenticated user's allowlist entries. Removing the last permanent entry lets the account be used from anywhere. Refused with 409 if the current address would be locked out.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Entry ID"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Entry removed"
+// @Failure 400 {object} map[string]string "Invalid entry ID"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Entry not found"
+// @Failure 409 {object} map[string]string "The current address would be refused"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/ip-allowlist/{id} [delete]
+func (h *UserHandler) RemoveIPAllowlistEntry(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	entryID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid entry id"})
+		return
+	}
+
+	if err := h.authService.RemoveIPAllowlistEntry(c.Request.Context(), userID.(int64), userID.(int64), entryID); err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "allowlist_entry_removed")
+}
+
+// SetIPAllowlistMode godoc
+// @Summary Set the IP allowlist mode
+// @Description Choose what happens to logins from outside the authenticated user's allowlist: "block" refuses them, "verify" requires a second factor and then allows the address for IP_ALLOWLIST_STEP_UP_TTL.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.IPAllowlistModeRequest true "Mode"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Mode updated"
+// @Failure 400 {object} map[string]string "Invalid mode"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 409 {object} map[string]string "The current address would be refused"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/ip-allowlist/mode [put]
+func (h *UserHandler) SetIPAllowlistMode(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.IPAllowlistModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.SetIPAllowlistMode(c.Request.Context(), userID.(int64), userID.(int64), req.Mode); err != nil {
+		respondError(c, ipAllowlistErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	respondMessage(c, "allowlist_mode_updated")
+}
+
+// =============================================================================
+// QR Code Pairing Endpoints
+// =============================================================================
+
+// GetPairing godoc
+// @Summary Get a pairing code's desktop
+// @Description Describe the desktop client behind a QR pairing code the app scanned (IP address, user agent, country), to show before the user approves it.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Pairing code"
+// @Success 200 {object} response.Envelope{data=response.PairingDetails} "Pending pairing"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Pairing code not found, expired or already answered"
+// @Failure 503 {object} map[string]string "Pairing not available"
+// @Router /user/pairing/{code} [get]
+func (h *UserHandler) GetPairing(c *gin.Context) {
+	details, err := h.authService.GetPairing(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		respondError(c, pairingErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+	response.JSON(c, http.StatusOK, details)
+}
+
+// DecidePairing godoc
+// @Summary Approve or deny a pairing
+// @Description Approve or deny a QR pairing code the app scanned. An approved desktop is logged in as the authenticated user when it next polls.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Pairing code"
+// @Param request body models.PairingDecisionRequest true "Decision"
+// @Success 200 {object} response.Envelope{data=map[string]string} "Decision recorded"
+// @Failure 400 {object} map[string]string "Invalid input data"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} map[string]string "Pairing code not found, expired or already answered"
+// @Failure 503 {object} map[string]string "Pairing not available"
+// @Router /user/pairing/{code} [post]
+func (h *UserHandler) DecidePairing(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.PairingDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err := Validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"validation_error": FormatValidationError(err, locale(c))})
+		return
+	}
+
+	if err := h.authService.DecidePairing(c.Request.Context(), userID.(int64), c.Param("code"), *req.Approve); err != nil {
+		respondError(c, pairingErrorStatus(err, http.StatusInternalServerError), err)
+		return
+	}
+
+	if *req.Approve {
+		respondMessage(c, "login_approved")
+		return
+	}
+	respondMessage(c, "login_denied")
+}
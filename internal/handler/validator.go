@@ -4,6 +4,9 @@ import (
 	"regexp"
 	"strings"
 
+	"authentio/internal/service"
+	"authentio/pkg/i18n"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -86,27 +89,34 @@ func InitValidator() {
 		return hasSpecial
 	})
 
+	// URL-safe identifiers: lowercase letters and digits separated by single hyphens
+	Validate.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		re := regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+		return re.MatchString(fl.Field().String())
+	})
+
+	// Usernames: 3-30 letters, digits, underscores or dots starting with a letter
+	Validate.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return service.IsValidUsername(fl.Field().String())
+	})
+
+	// Accept only locales with a message catalog
+	Validate.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+		return i18n.IsSupported(fl.Field().String())
+	})
 }
 
-func FormatValidationError(err error) map[string]string {
+// FormatValidationError maps each failed field to a message in the given
+// locale. Messages come from the "validation.<tag>" i18n keys, falling back
+// to a generic "validation.invalid" message for tags without one.
+func FormatValidationError(err error, locale string) map[string]string {
 	errs := make(map[string]string)
 	for _, e := range err.(validator.ValidationErrors) {
-		// Here is a more user-friendly error messages
-		switch e.Tag() {
-		case "required":
-			errs[strings.ToLower(e.Field())] = "This field is required"
-		case "email":
-			errs[strings.ToLower(e.Field())] = "Invalid email format"
-		case "min":
-			errs[strings.ToLower(e.Field())] = "Value is too short"
-		case "password":
-			errs[strings.ToLower(e.Field())] = "Password must contain uppercase, lowercase, number, and special character"
-		case "alphaSpace":
-			errs[strings.ToLower(e.Field())] = "Only letters and spaces are allowed"
-		default:
-			errs[strings.ToLower(e.Field())] = "Invalid value"
+		msg, ok := i18n.Lookup(locale, "validation."+e.Tag())
+		if !ok {
+			msg = i18n.T(locale, "validation.invalid")
 		}
+		errs[strings.ToLower(e.Field())] = msg
 	}
 	return errs
 }
-
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// Anonymizer Route Policies
+// =============================================================================
+
+// AnonymousIPRules lists the request path prefixes ("*" for every path)
+// where requests flagged by anonymizer detection are refused.
+type AnonymousIPRules struct {
+	BlockProxy   []string // refuse proxies, VPNs and Tor exit nodes
+	BlockHosting []string // refuse hosting providers and datacenters
+	Allow        []string // exceptions to the block rules
+}
+
+// AnonymousIPPolicy creates a Gin middleware that refuses requests with 403
+// when the "is_proxy" or "is_hosting" signal set by GeoIPMiddleware is
+// blocked on the request path. The longest matching prefix decides; an allow
+// rule wins over a block rule of the same length, so e.g. "*" can be blocked
+// while "/api/v1/oauth/token" stays open to servers.
+// It must be registered after GeoIPMiddleware.
+//
+// Parameters:
+//   - rules: Per-route block and allow rules
+//
+// Returns:
+//   - gin.HandlerFunc: Anonymous IP policy middleware function
+func AnonymousIPPolicy(rules AnonymousIPRules) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		allowed := longestPrefix(rules.Allow, path)
+
+		var blocked string
+		switch {
+		case c.GetBool("is_proxy") && longestPrefix(rules.BlockProxy, path) > allowed:
+			blocked = "proxy"
+		case c.GetBool("is_hosting") && longestPrefix(rules.BlockHosting, path) > allowed:
+			blocked = "hosting"
+		}
+		if blocked == "" {
+			c.Next()
+			return
+		}
+
+		logger.Logger.Warn("request from anonymizing network blocked",
+			zap.String("ip", c.ClientIP()),
+			zap.String("path", path),
+			zap.String("signal", blocked),
+		)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": errorMessage(c, "anonymous_ip_blocked"),
+			"code":  "anonymous_ip_blocked",
+		})
+		c.Abort()
+	}
+}
+
+// longestPrefix returns the length of the longest of prefixes that path
+// starts with, 0 for "*", and -1 when none matches.
+func longestPrefix(prefixes []string, path string) int {
+	longest := -1
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSpace(prefix)
+		switch {
+		case prefix == "*":
+			if longest < 0 {
+				longest = 0
+			}
+		case prefix != "" && strings.HasPrefix(path, prefix) && len(prefix) > longest:
+			longest = len(prefix)
+		}
+	}
+	return longest
+}
@@ -1,14 +1,22 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"authentio/internal/constants"
+	"authentio/pkg/anonymizer"
+	"authentio/pkg/breaker"
+	"authentio/pkg/i18n"
 	"authentio/pkg/jwt"
 	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+	"authentio/pkg/retry"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -40,21 +48,35 @@ type IPAPIResponse struct {
 // Configuration and Environment Variables
 // =============================================================================
 
-// GeoIP configuration loaded from environment variables
+// GeoIP configuration loaded from environment variables. The country rules
+// (BLOCKED_COUNTRIES...) are the tenants' geo policies, applied through
+// GeoRules.
 var (
 	// IPAPI_URL: External GeoIP service endpoint (default: ip-api.com)
 	ipapiURL = getEnv("IPAPI_URL", "http://ip-api.com/json/")
-	
-	// BLOCKED_COUNTRIES: Comma-separated list of country codes to block entirely
-	blockedCountries = loadCountries("BLOCKED_COUNTRIES")
-	
-	// SUSPICIOUS_COUNTRIES: Comma-separated list of country codes to monitor
-	suspiciousCountries = loadCountries("SUSPICIOUS_COUNTRIES")
-	
-	// ALLOWED_COUNTRIES: Comma-separated list of country codes to allow (optional)
-	allowedCountries = loadCountries("ALLOWED_COUNTRIES")
+
+	// geoIPBreaker stops GeoIP lookups while the service is failing (nil
+	// when circuit breakers are disabled)
+	geoIPBreaker *breaker.Breaker
+
+	// geoIPRetries retries lookups failing for transient reasons (nil =
+	// never)
+	geoIPRetries *retry.Retrier
 )
 
+// SetGeoIPBreaker guards the GeoIP lookups of AuthRequired and
+// GeoIPMiddleware with b: while it is open, locations are unknown instead
+// of every request waiting for the lookup timeout. Call it before serving.
+func SetGeoIPBreaker(b *breaker.Breaker) {
+	geoIPBreaker = b
+}
+
+// SetGeoIPRetries retries the GeoIP lookups failing because the service was
+// unreachable or overloaded with r. Call it before serving.
+func SetGeoIPRetries(r *retry.Retrier) {
+	geoIPRetries = r
+}
+
 // getEnv retrieves environment variable with fallback to default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -63,43 +85,48 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// loadCountries parses comma-separated country codes from environment variable
-// into a map for efficient lookup
-func loadCountries(envVar string) map[string]bool {
-	countries := make(map[string]bool)
-	envValue := os.Getenv(envVar)
-	if envValue == "" {
-		return countries
-	}
-	
-	for _, country := range strings.Split(envValue, ",") {
-		country = strings.TrimSpace(country)
-		if country != "" {
-			countries[country] = true
-		}
-	}
-	return countries
-}
-
 // =============================================================================
 // Authentication Middleware
 // =============================================================================
 
+// IPAllowlist decides whether an account may be used from an IP address.
+type IPAllowlist interface {
+	AllowsIP(ctx context.Context, userID int64, ip string) bool
+}
+
+// GeoRules applies the tenant's geographic rules to requests from a country:
+// it returns constants.GeoActionBlock for refused countries,
+// constants.GeoActionLog for suspicious ones and "" otherwise.
+type GeoRules interface {
+	CountryAction(ctx context.Context, country string) string
+}
+
+// TokenVersions decides whether an access token was issued after its user's
+// privileges last changed ("ver" claim).
+type TokenVersions interface {
+	TokenVersionCurrent(ctx context.Context, userID int64, version int) bool
+}
+
 // AuthRequired creates a Gin middleware that validates JWT tokens and enforces
 // geographical access restrictions. This is the main authentication guard for protected routes.
 //
 // Features:
 // - JWT token validation
+// - Refusal of tokens issued before the user's privileges changed
+// - Per-account IP allowlists
 // - GeoIP-based access control
 // - Request context enrichment with user and location data
 // - Security monitoring for suspicious locations
 //
 // Parameters:
 //   - jwtManager: JWT manager instance for token verification
+//   - allowlist: Per-account IP allowlists (nil disables them)
+//   - versions: Current token versions of users (nil disables the check)
+//   - geo: Geographic rules of tenants (nil disables them)
 //
 // Returns:
 //   - gin.HandlerFunc: Authentication middleware function
-func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
+func AuthRequired(jwtManager *jwt.Manager, allowlist IPAllowlist, versions TokenVersions, geo GeoRules) gin.HandlerFunc {
 	httpClient := &http.Client{Timeout: 3 * time.Second} // GeoIP API client with timeout
 	
 	return func(c *gin.Context) {
@@ -107,7 +134,7 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			logger.Debug("missing authorization header")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage(c, "authorization_required"), "code": "authorization_required"})
 			c.Abort()
 			return
 		}
@@ -116,18 +143,26 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			logger.Debug("invalid authorization header format")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization format"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage(c, "invalid_authorization_format"), "code": "invalid_authorization_format"})
 			c.Abort()
 			return
 		}
 
 		token := parts[1]
 		
-		// Verify JWT token signature and expiration
-		claims, err := jwtManager.VerifyToken(token)
+		// Verify JWT token signature and expiration with the request tenant's key
+		claims, err := jwtManager.VerifyTenantToken(token, requestTenant(c))
+		if jwt.IsForeignToken(err) {
+			// Most likely minted by another environment (e.g. staging)
+			logger.Warn("token issuer or audience mismatch",
+				zap.String("ip", c.ClientIP()),
+				zap.Int64("requestTenant", requestTenant(c)),
+				zap.Error(err),
+			)
+		}
 		if err != nil {
 			logger.Debug("invalid token", zap.Error(err))
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage(c, "invalid_token"), "code": "invalid_token"})
 			c.Abort()
 			return
 		}
@@ -136,7 +171,7 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		userID, ok := claims["user_id"].(float64)
 		if !ok {
 			logger.Debug("missing user_id in token")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage(c, "invalid_token_claims"), "code": "invalid_token_claims"})
 			c.Abort()
 			return
 		}
@@ -145,19 +180,79 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		firstName, _ := claims["first_name"].(string)
 		lastName, _ := claims["last_name"].(string)
 		fullName, _ := claims["name"].(string)
+		role, _ := claims["role"].(string)
+		orgID, _ := claims["org_id"].(float64) // absent for personal tokens
+		orgRole, _ := claims["org_role"].(string)
+
+		// Sudo tokens carry when their elevated scope lapses
+		var sudoUntil int64
+		if scope, _ := claims["scope"].(string); scope == jwt.ScopeSudo {
+			if exp, ok := claims["sudo_exp"].(float64); ok {
+				sudoUntil = int64(exp)
+			}
+		}
+
+		// Tokens are only valid for the tenant that issued them. Tokens without
+		// the claim predate tenancy and belong to the default tenant.
+		tokenTenant := constants.DefaultTenantID
+		if id, ok := claims["tenant_id"].(float64); ok {
+			tokenTenant = int64(id)
+		}
+		if tokenTenant != requestTenant(c) {
+			logger.Warn("token used for another tenant",
+				zap.Int64("userID", int64(userID)),
+				zap.Int64("tokenTenant", tokenTenant),
+				zap.Int64("requestTenant", requestTenant(c)),
+			)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage(c, "invalid_token"), "code": "invalid_token"})
+			c.Abort()
+			return
+		}
+
+		// A saved language preference overrides Accept-Language negotiation,
+		// from the refusals below on
+		if locale, _ := claims["locale"].(string); i18n.IsSupported(locale) {
+			setLocale(c, locale)
+		}
+
+		// Tokens issued before the user's privileges changed (2FA state,
+		// organization role...) must be refreshed to carry the new ones
+		if versions != nil && !versions.TokenVersionCurrent(c.Request.Context(), int64(userID), jwt.ClaimedVersion(claims)) {
+			logger.Debug("outdated token", zap.Int64("userID", int64(userID)))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage(c, "token_outdated"), "code": "token_outdated"})
+			c.Abort()
+			return
+		}
+
+		// Accounts restricted to an IP allowlist can only be used from those addresses
+		if allowlist != nil && !allowlist.AllowsIP(c.Request.Context(), int64(userID), c.ClientIP()) {
+			logger.Warn("access from IP address not on the account's allowlist",
+				zap.Int64("userID", int64(userID)),
+				zap.String("ip", c.ClientIP()),
+			)
+			c.JSON(http.StatusForbidden, gin.H{"error": errorMessage(c, "ip_not_allowed"), "code": "ip_not_allowed"})
+			c.Abort()
+			return
+		}
 
 		// Perform GeoIP lookup for geographical restrictions
-		countryCode, countryName := getGeoIPInfo(c, httpClient)
+		location := getGeoIPInfo(c, httpClient)
+		countryCode, countryName := location.CountryCode, location.Country
+		geoAction := ""
+		if geo != nil {
+			geoAction = geo.CountryAction(c.Request.Context(), countryCode)
+		}
 		
 		// Check if country is blocked
-		if isCountryBlocked(countryCode) {
+		if geoAction == constants.GeoActionBlock {
+			metrics.BlockedCountries.Inc()
 			logger.Warn("blocked access from restricted country",
 				zap.Int64("userID", int64(userID)),
 				zap.String("email", email),
 				zap.String("ip", c.ClientIP()),
 				zap.String("country", countryCode),
 			)
-			c.JSON(http.StatusForbidden, gin.H{"error": "access denied from your region"})
+			c.JSON(http.StatusForbidden, gin.H{"error": errorMessage(c, "region_blocked"), "code": "region_blocked"})
 			c.Abort()
 			return
 		}
@@ -169,6 +264,13 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		c.Set("firstName", firstName)
 		c.Set("lastName", lastName)
 		c.Set("fullName", fullName)
+		c.Set("role", role)
+		c.Set("orgID", int64(orgID))
+		c.Set("orgRole", orgRole)
+		c.Set("sudoUntil", sudoUntil)
+		if authTime, ok := claims["auth_time"].(float64); ok {
+			c.Set("authTime", time.Unix(int64(authTime), 0))
+		}
 		c.Set("country", countryCode)
 		c.Set("countryName", countryName)
 		c.Set("clientIP", c.ClientIP())
@@ -181,7 +283,7 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		)
 
 		// Log warning for suspicious countries (monitoring purposes)
-		if isSuspiciousCountry(countryCode) {
+		if geoAction == constants.GeoActionLog {
 			logger.Warn("login from suspicious country",
 				zap.Int64("userID", int64(userID)),
 				zap.String("email", email),
@@ -199,16 +301,6 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 // GeoIP Utility Functions
 // =============================================================================
 
-// isCountryBlocked checks if a country code is in the blocked countries list
-func isCountryBlocked(countryCode string) bool {
-	return blockedCountries[countryCode]
-}
-
-// isSuspiciousCountry checks if a country code is in the suspicious countries list
-func isSuspiciousCountry(countryCode string) bool {
-	return suspiciousCountries[countryCode]
-}
-
 // getGeoIPInfo performs IP geolocation lookup using external GeoIP service
 //
 // Parameters:
@@ -216,32 +308,56 @@ func isSuspiciousCountry(countryCode string) bool {
 //   - client: HTTP client for making GeoIP API requests
 //
 // Returns:
-//   - string: Country code (e.g., "US", "GB", "LOCAL", "UNKNOWN")
-//   - string: Country name or description
-func getGeoIPInfo(c *gin.Context, client *http.Client) (string, string) {
+//   - IPAPIResponse: Location of the client. Its Status is "success" when
+//     the lookup succeeded; otherwise (also while the GeoIP circuit breaker
+//     is open) only CountryCode ("LOCAL" or "UNKNOWN") and Country are set
+func getGeoIPInfo(c *gin.Context, client *http.Client) IPAPIResponse {
 	clientIP := c.ClientIP()
+	unknown := IPAPIResponse{CountryCode: constants.CountryUnknown, Country: "Unknown"}
 	
 	// Handle local development addresses
 	if clientIP == "::1" || clientIP == "127.0.0.1" || clientIP == "localhost" {
-		return "LOCAL", "Localhost"
+		return IPAPIResponse{CountryCode: constants.CountryLocal, Country: "Localhost"}
 	}
 
 	// Handle empty IP addresses
 	if clientIP == "" {
-		return "UNKNOWN", "Unknown"
+		return unknown
+	}
+
+	// Skip the lookup while the GeoIP service is failing
+	if err := geoIPBreaker.Allow(); err != nil {
+		return unknown
 	}
 
 	// Construct GeoIP API URL
 	url := ipapiURL + clientIP
 	
-	// Make HTTP request to GeoIP service
-	resp, err := client.Get(url)
+	// Make HTTP request to GeoIP service, retrying transient failures
+	var resp *http.Response
+	err := geoIPRetries.Do(c.Request.Context(), func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		attempt, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		if attempt.StatusCode >= http.StatusInternalServerError || attempt.StatusCode == http.StatusTooManyRequests {
+			attempt.Body.Close()
+			return fmt.Errorf("ipapi returned %s", attempt.Status)
+		}
+		resp = attempt
+		return nil
+	}, nil)
+	geoIPBreaker.Record(err != nil)
 	if err != nil {
 		logger.Debug("ipapi request failed", 
 			zap.String("ip", clientIP), 
 			zap.Error(err),
 		)
-		return "UNKNOWN", "Unknown"
+		return unknown
 	}
 	defer resp.Body.Close()
 
@@ -252,15 +368,15 @@ func getGeoIPInfo(c *gin.Context, client *http.Client) (string, string) {
 			zap.String("ip", clientIP), 
 			zap.Error(err),
 		)
-		return "UNKNOWN", "Unknown"
+		return unknown
 	}
 
-	// Return country information if request was successful
+	// Return location information if request was successful
 	if result.Status == "success" {
-		return result.CountryCode, result.Country
+		return result
 	}
 
-	return "UNKNOWN", "Unknown"
+	return unknown
 }
 
 // =============================================================================
@@ -270,19 +386,38 @@ func getGeoIPInfo(c *gin.Context, client *http.Client) (string, string) {
 // GeoIPMiddleware creates a Gin middleware that performs GeoIP lookup
 // and adds location information to the request context.
 // This can be used independently of authentication for public routes.
+// It sets "country" and "countryName", and "latitude", "longitude" and
+// "located" when the lookup succeeded.
+//
+// With an anonymizer detector it also sets "is_proxy" (proxy, VPN or Tor)
+// and "is_hosting" (hosting provider or datacenter).
+//
+// Parameters:
+//   - anonymizers: Anonymizer detector (nil disables the signals)
 //
 // Returns:
 //   - gin.HandlerFunc: GeoIP middleware function
-func GeoIPMiddleware() gin.HandlerFunc {
+func GeoIPMiddleware(anonymizers *anonymizer.Detector) gin.HandlerFunc {
 	httpClient := &http.Client{Timeout: 3 * time.Second}
 	
 	return func(c *gin.Context) {
-		countryCode, countryName := getGeoIPInfo(c, httpClient)
+		location := getGeoIPInfo(c, httpClient)
 		
 		// Add location information to context for subsequent handlers
-		c.Set("country", countryCode)
-		c.Set("countryName", countryName)
+		c.Set("country", location.CountryCode)
+		c.Set("countryName", location.Country)
+		if location.Status == "success" {
+			c.Set("latitude", location.Lat)
+			c.Set("longitude", location.Lon)
+			c.Set("located", true)
+		}
 		c.Set("clientIP", c.ClientIP())
+
+		if anonymizers != nil {
+			signals := anonymizers.Lookup(c.Request.Context(), c.ClientIP())
+			c.Set("is_proxy", signals.Proxy)
+			c.Set("is_hosting", signals.Hosting)
+		}
 		
 		c.Next()
 	}
@@ -6,28 +6,30 @@ import (
 	"strings"
 	"time"
 
+	"authentio/pkg/jwt"
 	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+	"authentio/pkg/revocation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
+// TokenBlacklist revokes access tokens and refuses revoked ones, by token ID
+// ("jti" claim). Checks are answered by the revocation list's in-process
+// filter, going to Redis only for possible hits. A nil list (no Redis)
+// revokes nothing.
 type TokenBlacklist struct {
-	redis     *redis.Client
-	keyPrefix string
+	revoked *revocation.List
 }
 
-func NewTokenBlacklist(redis *redis.Client) *TokenBlacklist {
-	return &TokenBlacklist{
-		redis:     redis,
-		keyPrefix: "blacklist:",
-	}
+func NewTokenBlacklist(revoked *revocation.List) *TokenBlacklist {
+	return &TokenBlacklist{revoked: revoked}
 }
 
 // BlacklistMiddleware checks if a token is blacklisted
-func BlacklistMiddleware(redis *redis.Client) gin.HandlerFunc {
-	blacklist := NewTokenBlacklist(redis)
+func BlacklistMiddleware(revoked *revocation.List) gin.HandlerFunc {
+	blacklist := NewTokenBlacklist(revoked)
 	return blacklist.Handle
 }
 
@@ -53,11 +55,12 @@ func (bl *TokenBlacklist) Handle(c *gin.Context) {
 	}
 
 	if isBlacklisted {
+		metrics.RevokedTokens.Inc()
 		logger.Logger.Warn("blacklisted token used",
 			zap.String("ip", c.ClientIP()),
 			zap.String("path", c.Request.URL.Path),
 		)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage(c, "token_revoked"), "code": "token_revoked"})
 		c.Abort()
 		return
 	}
@@ -65,24 +68,40 @@ func (bl *TokenBlacklist) Handle(c *gin.Context) {
 	c.Next()
 }
 
-// Blacklist adds a token to the blacklist with an expiration
+// Blacklist revokes a token for expiration, the time it has left before it
+// expires. Tokens issued before they had IDs are revoked by their whole
+// value, as they used to be.
 func (bl *TokenBlacklist) Blacklist(ctx context.Context, token string, expiration time.Duration) error {
-	key := bl.keyPrefix + token
-	return bl.redis.Set(ctx, key, "1", expiration).Err()
+	id, ok := jwt.UnverifiedTokenID(token)
+	if bl.revoked == nil || !ok {
+		return nil
+	}
+	if id == "" {
+		return bl.revoked.RevokeLegacy(ctx, token, expiration)
+	}
+	return bl.revoked.Revoke(ctx, id, expiration)
 }
 
 // IsBlacklisted checks if a token is in the blacklist
 func (bl *TokenBlacklist) IsBlacklisted(ctx context.Context, token string) (bool, error) {
-	key := bl.keyPrefix + token
-	exists, err := bl.redis.Exists(ctx, key).Result()
-	if err != nil {
-		return false, err
+	id, ok := jwt.UnverifiedTokenID(token)
+	if bl.revoked == nil || !ok {
+		return false, nil
 	}
-	return exists > 0, nil
+	if id == "" {
+		return bl.revoked.IsLegacyRevoked(ctx, token)
+	}
+	return bl.revoked.IsRevoked(ctx, id)
 }
 
 // RemoveFromBlacklist removes a token from the blacklist
 func (bl *TokenBlacklist) RemoveFromBlacklist(ctx context.Context, token string) error {
-	key := bl.keyPrefix + token
-	return bl.redis.Del(ctx, key).Err()
-}
\ No newline at end of file
+	id, ok := jwt.UnverifiedTokenID(token)
+	if bl.revoked == nil || !ok {
+		return nil
+	}
+	if id == "" {
+		return bl.revoked.RestoreLegacy(ctx, token)
+	}
+	return bl.revoked.Restore(ctx, id)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"authentio/pkg/captcha"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CaptchaTokenHeader carries the token the client got by solving the
+// CAPTCHA widget of the configured provider.
+const CaptchaTokenHeader = "X-Captcha-Token"
+
+// CaptchaRequired creates a Gin middleware that gates an endpoint behind a
+// solved CAPTCHA: requests without a token get 400 ("captcha_required"),
+// rejected tokens 403 ("captcha_failed"), and 503 when the provider can't be
+// reached. A nil verifier (CAPTCHA_PROVIDER=off) lets every request through.
+//
+// Parameters:
+//   - verifier: CAPTCHA provider selected by CAPTCHA_PROVIDER
+//
+// Returns:
+//   - gin.HandlerFunc: CAPTCHA middleware function
+func CaptchaRequired(verifier captcha.CaptchaVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil {
+			c.Next()
+			return
+		}
+
+		token := strings.TrimSpace(c.GetHeader(CaptchaTokenHeader))
+		err := verifier.Verify(c.Request.Context(), token, c.ClientIP())
+		switch {
+		case err == nil:
+			c.Next()
+			return
+		case errors.Is(err, captcha.ErrMissingToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage(c, "captcha_required"), "code": "captcha_required"})
+		case errors.Is(err, captcha.ErrFailed):
+			logger.Logger.Info("captcha verification failed",
+				zap.String("ip", c.ClientIP()),
+				zap.String("path", c.Request.URL.Path),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusForbidden, gin.H{"error": errorMessage(c, "captcha_failed"), "code": "captcha_failed"})
+		default:
+			logger.Logger.Error("captcha verification unavailable", zap.Error(err))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": errorMessage(c, "captcha_unavailable"), "code": "captcha_unavailable"})
+		}
+		c.Abort()
+	}
+}
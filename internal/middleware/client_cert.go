@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+
+	"authentio/pkg/logger"
+	"authentio/pkg/mtls"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// Client Certificate Policy
+// =============================================================================
+
+// ClientCertificate creates a Gin middleware enforcing mutual TLS on the
+// routes policy requires it for. Requests without a verified client
+// certificate get 401 there, and certificates outside the policy's allowed
+// identities 403. On every route, a verified certificate's identity is
+// stored as "clientCert" (and by RequestContext in the request context).
+//
+// Parameters:
+//   - policy: Routes requiring a certificate and the identities accepted
+//
+// Returns:
+//   - gin.HandlerFunc: Client certificate middleware function
+func ClientCertificate(policy mtls.Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, verified := mtls.VerifiedIdentity(c.Request.TLS)
+		if verified {
+			c.Set("clientCert", identity.Name())
+		}
+		allowed := verified && policy.Allows(identity)
+
+		if !allowed && policy.Requires(c.Request.URL.Path) {
+			if !verified {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": errorMessage(c, "client_certificate_required"),
+					"code":  "client_certificate_required",
+				})
+				c.Abort()
+				return
+			}
+
+			logger.Logger.Warn("client certificate not allowed",
+				zap.String("ip", c.ClientIP()),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("certificate", identity.Name()),
+				zap.String("fingerprint", identity.Fingerprint),
+			)
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": errorMessage(c, "client_certificate_not_allowed"),
+				"code":  "client_certificate_not_allowed",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
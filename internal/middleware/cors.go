@@ -1,150 +1,192 @@
 package middleware
 
 import (
-	"os"
+	"net/http"
 	"strings"
 
+	"authentio/pkg/logger"
+	"authentio/pkg/origin"
+
 	"github.com/gin-gonic/gin"
 )
 
+// =============================================================================
+// CORS Policies
+// =============================================================================
+
+// CORSPolicy decides which cross-origin requests browsers may make to a
+// group of routes.
+type CORSPolicy struct {
+	// Origins allowed to call the routes: origins, wildcard patterns such as
+	// https://*.example.com, or "*" for every origin (ignored with Credentials)
+	Origins []string
+	// Credentials lets browsers send cookies and client certificates. Allowed
+	// origins are then echoed back, never answered with "*"
+	Credentials bool
+	// Methods allowed in cross-origin requests; empty allows every method
+	Methods []string
+}
+
+// CORSRoute applies a policy to the routes under a path prefix.
+type CORSRoute struct {
+	Prefix string
+	Policy CORSPolicy
+}
+
+// CORSOrigins are the configured origins of each kind of route.
+type CORSOrigins struct {
+	API     []string // credentialed API routes (CORS_ALLOWED_ORIGINS)
+	Session []string // cookie sessions of single-page apps (SESSION_ALLOWED_ORIGINS)
+	Public  []string // public discovery routes (CORS_PUBLIC_ORIGINS)
+}
+
+// corsAllowedHeaders are the request headers cross-origin requests may send.
+var corsAllowedHeaders = strings.Join([]string{
+	"Content-Type",
+	"Content-Length",
+	"Accept-Encoding",
+	"X-CSRF-Token",
+	"Authorization",
+	"accept",
+	"origin",
+	"Cache-Control",
+	"If-None-Match",
+	"X-Requested-With",
+	"X-API-Key",             // Custom API key header
+	"X-Client-Version",      // Client version header
+	"X-Request-ID",          // Request tracing
+	DeviceFingerprintHeader, // Device registry
+	CaptchaTokenHeader,      // Solved CAPTCHA on gated endpoints
+	IdempotencyKeyHeader,    // Safe retries of mutating requests
+}, ", ")
+
+// corsAllMethods are the methods allowed by policies listing none.
+var corsAllMethods = []string{
+	"POST",
+	"OPTIONS",
+	"GET",
+	"PUT",
+	"DELETE",
+	"PATCH", // Partial updates
+	"HEAD",  // Header-only requests
+}
+
+// corsExposedHeaders are the response headers scripts may read.
+var corsExposedHeaders = strings.Join([]string{
+	"Content-Length",
+	"Content-Type",
+	"X-Request-ID",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+	"Idempotent-Replayed",
+	"ETag",
+	"Content-Disposition",
+}, ", ")
+
 // =============================================================================
 // CORS Middleware
 // =============================================================================
 
-// CORSMiddleware creates a Gin middleware that handles Cross-Origin Resource Sharing (CORS).
-// This middleware enables secure cross-origin requests for web applications.
+// CORS creates a Gin middleware that handles Cross-Origin Resource Sharing
+// with a policy per group of routes: each request follows the route with the
+// longest prefix of its path. Requests matching no route, or from an origin
+// their policy doesn't allow, get no CORS headers, so browsers refuse to
+// expose the response; their preflight requests get 403. Policies with
+// Credentials only allow the origins they list: "*" is ignored there.
 //
-// CORS is a security feature that allows restricted resources on a web page to be requested
-// from another domain outside the domain from which the first resource was served.
+// It runs before routing, so preflight (OPTIONS) requests, which match no
+// route handler, are answered here.
 //
-// Features:
-// - Configurable allowed origins (environment variable support)
-// - Preflight request handling
-// - Secure headers for credentialed requests
-// - Flexible HTTP methods and headers
+// Parameters:
+//   - routes: Policies by path prefix
 //
 // Returns:
 //   - gin.HandlerFunc: CORS middleware function
-func CORSMiddleware() gin.HandlerFunc {
-	// Get allowed origins from environment variable or default to all
-	allowedOrigins := getCORSAllowedOrigins()
+func CORS(routes []CORSRoute) gin.HandlerFunc {
+	compiled := make([]corsRoute, len(routes))
+	for i, route := range routes {
+		compiled[i] = corsRoute{CORSRoute: route}
+		for _, o := range route.Policy.Origins {
+			if o == origin.Any && route.Policy.Credentials {
+				// Any site could make requests carrying the user's cookies
+				logger.Warn("ignoring \"*\" in a credentialed CORS policy", "prefix", route.Prefix)
+				continue
+			}
+			pattern, err := origin.Parse(o)
+			if err != nil {
+				logger.Warn("ignoring invalid CORS origin", "origin", o, "error", err)
+				continue
+			}
+			compiled[i].patterns = append(compiled[i].patterns, pattern)
+			compiled[i].any = compiled[i].any || o == origin.Any
+		}
+	}
 
 	return func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
-		
-		// Set Access-Control-Allow-Origin header
-		// In production, you should specify exact domains instead of "*" for security
-		if isOriginAllowed(origin, allowedOrigins) {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-		} else {
-			// Fallback to wildcard if no specific origins configured
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		requestOrigin := c.Request.Header.Get("Origin")
+		if requestOrigin == "" {
+			c.Next()
+			return
+		}
+
+		route := corsRouteFor(compiled, c.Request.URL.Path)
+		allowed := route != nil && origin.MatchesAny(route.patterns, requestOrigin)
+		if allowed {
+			if route.Policy.Credentials || !route.any {
+				c.Header("Access-Control-Allow-Origin", requestOrigin)
+				c.Header("Vary", "Origin")
+			} else {
+				c.Header("Access-Control-Allow-Origin", origin.Any)
+			}
+			if route.Policy.Credentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+			c.Header("Access-Control-Expose-Headers", corsExposedHeaders)
 		}
 
-		// Allow credentials (cookies, authorization headers) in cross-origin requests
-		// Important: Cannot use wildcard "*" origin when credentials are allowed
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		
-		// Define which headers are allowed in actual requests
-		c.Writer.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{
-			"Content-Type",
-			"Content-Length",
-			"Accept-Encoding",
-			"X-CSRF-Token",
-			"Authorization",
-			"accept",
-			"origin",
-			"Cache-Control",
-			"X-Requested-With",
-			"X-API-Key",           // Custom API key header
-			"X-Client-Version",    // Client version header
-			"X-Request-ID",        // Request tracing
-		}, ", "))
-
-		// Define which HTTP methods are allowed for cross-origin requests
-		c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join([]string{
-			"POST",
-			"OPTIONS",
-			"GET",
-			"PUT",
-			"DELETE",
-			"PATCH",              // Added for partial updates
-			"HEAD",               // Added for header-only requests
-		}, ", "))
-
-		// Define which response headers can be exposed to the client
-		c.Writer.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{
-			"Content-Length",
-			"Content-Type",
-			"X-Request-ID",
-			"X-RateLimit-Limit",
-			"X-RateLimit-Remaining",
-			"X-RateLimit-Reset",
-		}, ", "))
-
-		// Handle preflight requests (OPTIONS)
-		// Preflight requests are sent by browsers before actual requests to check CORS permissions
-		if c.Request.Method == "OPTIONS" {
-			// Set cache duration for preflight responses (in seconds)
-			// Browsers will cache the preflight response for this duration
-			c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-			
-			// Return 204 No Content for successful preflight requests
-			c.AbortWithStatus(204)
+		// Handle preflight requests, sent by browsers before the actual
+		// request to check its CORS permissions
+		if c.Request.Method == http.MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != "" {
+			if !allowed {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			methods := route.Policy.Methods
+			if len(methods) == 0 {
+				methods = corsAllMethods
+			}
+			c.Header("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			c.Header("Access-Control-Allow-Headers", corsAllowedHeaders)
+			c.Header("Access-Control-Max-Age", "86400") // browsers cache it for 24 hours
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 
-		// Continue to next middleware/handler for actual requests
 		c.Next()
 	}
 }
 
-// =============================================================================
-// CORS Configuration Helpers
-// =============================================================================
-
-// getCORSAllowedOrigins retrieves and parses allowed origins from environment variable
-// Format: "https://app.example.com,https://admin.example.com,http://localhost:3000"
-//
-// Returns:
-//   - []string: List of allowed origins, empty slice means all origins allowed
-func getCORSAllowedOrigins() []string {
-	originsEnv := os.Getenv("CORS_ALLOWED_ORIGINS")
-	if originsEnv == "" {
-		return []string{} // Empty means allow all origins
-	}
-
-	origins := strings.Split(originsEnv, ",")
-	allowedOrigins := make([]string, 0, len(origins))
-	
-	for _, origin := range origins {
-		trimmedOrigin := strings.TrimSpace(origin)
-		if trimmedOrigin != "" {
-			allowedOrigins = append(allowedOrigins, trimmedOrigin)
-		}
-	}
-
-	return allowedOrigins
+// corsRoute is a CORSRoute with its origins parsed.
+type corsRoute struct {
+	CORSRoute
+	patterns []origin.Pattern
+	any      bool // "*" is one of the origins
 }
 
-// isOriginAllowed checks if a request origin is in the list of allowed origins
-//
-// Parameters:
-//   - origin: The Origin header value from the request
-//   - allowedOrigins: List of allowed origins from configuration
-//
-// Returns:
-//   - bool: True if origin is allowed, false otherwise
-func isOriginAllowed(origin string, allowedOrigins []string) bool {
-	if len(allowedOrigins) == 0 {
-		return true // Allow all origins if none specified
-	}
-
-	for _, allowedOrigin := range allowedOrigins {
-		if origin == allowedOrigin {
-			return true
+// corsRouteFor returns the route with the longest prefix of path, or nil.
+// Prefixes match whole path segments: /health doesn't cover /healthz.
+func corsRouteFor(routes []corsRoute, path string) *corsRoute {
+	var best *corsRoute
+	for i := range routes {
+		route := &routes[i]
+		prefix := strings.TrimSuffix(route.Prefix, "/")
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if best == nil || len(route.Prefix) > len(best.Prefix) {
+			best = route
 		}
 	}
-
-	return false
-}
\ No newline at end of file
+	return best
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Request Deadline Middleware
+// =============================================================================
+
+// RequestDeadline creates a Gin middleware giving each request timeout to
+// complete (REQUEST_TIMEOUT): database, Redis and provider calls made with the
+// request context are cancelled once it passes, so a stuck dependency can't
+// hold connections past the server's write timeout. Handlers answer server
+// errors caused by the deadline with 504 request_timeout; a handler that
+// returns without answering gets the same response. A timeout of 0 disables
+// it.
+//
+// Parameters:
+//   - timeout: Time each request may take (0 = no deadline)
+//
+// Returns:
+//   - gin.HandlerFunc: Request deadline middleware function
+func RequestDeadline(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+		logger.Warn("request deadline exceeded", "method", c.Request.Method, "path", c.FullPath(), "timeout", timeout)
+		if !c.Writer.Written() {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": errorMessage(c, "request_timeout"), "code": "request_timeout"})
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"authentio/pkg/idempotency"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// IdempotencyKeyHeader carries the client-generated key identifying one
+// logical request across retries.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the header; longer keys are refused.
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers stored and replayed along with
+// the body.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// =============================================================================
+// Idempotency Middleware
+// =============================================================================
+
+// Idempotent creates a Gin middleware that runs a request sent with an
+// Idempotency-Key header only once. The first successful (2xx) response is
+// stored, scoped to the tenant, route and user (or client IP when signed
+// out), and replayed to retries with the same key with an
+// "Idempotent-Replayed: true" header. Failed requests aren't stored, so they
+// can be retried. A retry arriving while the first request is running gets
+// 409, and reusing a key for a different request body 422. Requests without
+// the header, and every request when store is nil (no Redis), run normally.
+//
+// Parameters:
+//   - store: Redis-backed response store (nil disables idempotency keys)
+//
+// Returns:
+//   - gin.HandlerFunc: Idempotency middleware function
+func Idempotent(store *idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if store == nil || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage(c, "invalid_idempotency_key", maxIdempotencyKeyLength), "code": "invalid_idempotency_key"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage(c, "invalid_request_body"), "code": "invalid_request_body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := hashBody(body)
+
+		ctx := c.Request.Context()
+		scoped := idempotencyScope(c, key)
+		stored, err := store.Begin(ctx, scoped)
+		switch {
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.Header("Retry-After", "1")
+			c.JSON(http.StatusConflict, gin.H{
+				"error": errorMessage(c, "idempotency_request_in_progress"),
+				"code":  "idempotency_request_in_progress",
+			})
+			c.Abort()
+			return
+		case err != nil:
+			// Fail open: a Redis outage shouldn't take the endpoint down
+			logger.Logger.Warn("idempotency key lookup failed", zap.String("path", c.FullPath()), zap.Error(err))
+			c.Next()
+			return
+		case stored != nil:
+			replayResponse(c, stored, requestHash)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Record the outcome even if the client has gone away meanwhile
+		ctx = context.WithoutCancel(ctx)
+
+		status := recorder.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			if err := store.Release(ctx, scoped); err != nil {
+				logger.Logger.Warn("failed to release idempotency key", zap.Error(err))
+			}
+			return
+		}
+
+		resp := idempotency.Response{
+			RequestHash: requestHash,
+			Status:      status,
+			Header:      make(map[string]string),
+			Body:        recorder.body.Bytes(),
+		}
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				resp.Header[name] = value
+			}
+		}
+		if err := store.Complete(ctx, scoped, resp); err != nil {
+			logger.Logger.Warn("failed to store idempotent response", zap.Error(err))
+		}
+	}
+}
+
+// replayResponse writes a stored response, or 422 when it was produced by a
+// request with another body.
+func replayResponse(c *gin.Context, stored *idempotency.Response, requestHash string) {
+	if stored.RequestHash != requestHash {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": errorMessage(c, "idempotency_key_reused"),
+			"code":  "idempotency_key_reused",
+		})
+		c.Abort()
+		return
+	}
+
+	for name, value := range stored.Header {
+		c.Header(name, value)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Status(stored.Status)
+	c.Writer.Write(stored.Body)
+	c.Abort()
+}
+
+// idempotencyScope scopes key to the tenant, the route and the signed-in
+// user, or the client IP for public endpoints, so clients can't replay each
+// other's responses.
+func idempotencyScope(c *gin.Context, key string) string {
+	subject := "ip:" + c.ClientIP()
+	if userID := c.GetInt64("userID"); userID != 0 {
+		subject = fmt.Sprintf("user:%d", userID)
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%s %s|%s|%s", requestTenant(c), c.Request.Method, c.FullPath(), subject, key)))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashBody fingerprints a request body.
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder copies the response body while it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
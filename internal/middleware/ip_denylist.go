@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// IPDenylist decides whether requests from an IP address are refused.
+type IPDenylist interface {
+	DeniesIP(ctx context.Context, ip string) bool
+}
+
+// IPDenylistMiddleware creates a Gin middleware that refuses requests from
+// addresses on the global IP denylist with 403 before any other processing.
+// It should be registered early so denied clients don't use rate limits,
+// quotas or database lookups.
+//
+// Parameters:
+//   - denylist: Global IP denylist (nil disables the check)
+//
+// Returns:
+//   - gin.HandlerFunc: IP denylist middleware function
+func IPDenylistMiddleware(denylist IPDenylist) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if denylist == nil || !denylist.DeniesIP(c.Request.Context(), c.ClientIP()) {
+			c.Next()
+			return
+		}
+
+		logger.Logger.Warn("request from denied IP address",
+			zap.String("ip", c.ClientIP()),
+			zap.String("path", c.Request.URL.Path),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": errorMessage(c, "ip_denied"), "code": "ip_denied"})
+		c.Abort()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"authentio/internal/requestctx"
+	"authentio/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale creates a Gin middleware that negotiates the response language from
+// the Accept-Language header and stores it both in the Gin context ("locale")
+// and in the request's context.Context. On authenticated routes AuthRequired
+// replaces it with the user's saved preference when one is set.
+//
+// Returns:
+//   - gin.HandlerFunc: Locale negotiation middleware function
+func Locale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		setLocale(c, i18n.Match(c.GetHeader("Accept-Language")))
+
+		c.Next()
+	}
+}
+
+// errorMessage returns the catalog message of error code ("error.<code>") in
+// the request locale, formatted with args if any. Middleware running before
+// Locale negotiates it from Accept-Language.
+func errorMessage(c *gin.Context, code string, args ...interface{}) string {
+	locale := c.GetString("locale")
+	if locale == "" {
+		locale = i18n.Match(c.GetHeader("Accept-Language"))
+	}
+	return i18n.T(locale, "error."+code, args...)
+}
+
+// setLocale records locale for handlers (c.GetString("locale")) and services
+// (requestctx.LocaleFrom).
+func setLocale(c *gin.Context, locale string) {
+	c.Set("locale", locale)
+	c.Request = c.Request.WithContext(requestctx.WithLocale(c.Request.Context(), locale))
+}
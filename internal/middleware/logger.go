@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"authentio/pkg/logger"
+	"authentio/pkg/response"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -75,7 +76,7 @@ func RequestLogger() gin.HandlerFunc {
 		fields = append(fields, zap.Int("response_size", c.Writer.Size()))
 
 		// Add request ID if present
-		if requestID := c.GetHeader("X-Request-ID"); requestID != "" {
+		if requestID := c.GetString(response.RequestIDKey); requestID != "" {
 			fields = append(fields, zap.String("request_id", requestID))
 		}
 
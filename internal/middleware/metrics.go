@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsToken creates a Gin middleware admitting only scrapers that send
+// token as a bearer token, so security metrics aren't public.
+//
+// Parameters:
+//   - token: Shared scrape token (METRICS_TOKEN)
+//
+// Returns:
+//   - gin.HandlerFunc: Metrics authentication middleware function
+func MetricsToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sent, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid metrics token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -75,7 +75,8 @@ func (rl *InMemoryRateLimiter) Handle(c *gin.Context) {
 			zap.String("path", c.Request.URL.Path),
 		)
 		c.JSON(http.StatusTooManyRequests, gin.H{
-			"error": "rate limit exceeded",
+			"error": errorMessage(c, "rate_limit_exceeded"),
+			"code": "rate_limit_exceeded",
 			"retry_after": rl.window.Seconds(),
 		})
 		c.Abort()
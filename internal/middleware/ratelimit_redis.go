@@ -1,12 +1,13 @@
 package middleware
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"authentio/pkg/logger"
+	"authentio/pkg/quota"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -77,7 +78,7 @@ func RateLimiterMiddlewareRedis(redis *redis.Client) gin.HandlerFunc {
 // 5. Returns appropriate headers and responses
 func (rl *RedisRateLimiter) Handle(c *gin.Context) {
 	key := rl.getKey(c)
-	ctx := context.Background()
+	ctx := c.Request.Context()
 
 	// Use Redis pipeline for atomic operations to prevent race conditions
 	pipe := rl.redis.Pipeline()
@@ -149,7 +150,8 @@ func (rl *RedisRateLimiter) Handle(c *gin.Context) {
 			zap.String("window", rl.window.String()),
 		)
 		c.JSON(http.StatusTooManyRequests, gin.H{
-			"error": "rate limit exceeded",
+			"error": errorMessage(c, "rate_limit_exceeded"),
+			"code": "rate_limit_exceeded",
 			"retry_after": rl.window.Seconds(),
 			"limit": rl.limit,
 			"window_seconds": rl.window.Seconds(),
@@ -179,4 +181,38 @@ func (rl *RedisRateLimiter) Handle(c *gin.Context) {
 //   - string: Unique Redis key for rate limiting
 func (rl *RedisRateLimiter) getKey(c *gin.Context) string {
 	return rl.keyPrefix + c.ClientIP() + ":" + c.Request.URL.Path
-}
\ No newline at end of file
+}
+
+// =============================================================================
+// Tenant Quotas
+// =============================================================================
+
+// TenantQuota creates a Gin middleware that counts each request against the
+// daily request quota of the tenant resolved by the tenant middleware and
+// rejects requests once it is used up. A nil limiter disables the check.
+//
+// Parameters:
+//   - quotas: Per-tenant daily quota limiter
+//
+// Returns:
+//   - gin.HandlerFunc: Tenant request quota middleware function
+func TenantQuota(quotas *quota.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if quotas == nil {
+			c.Next()
+			return
+		}
+
+		if err := quotas.Consume(c.Request.Context(), requestTenant(c), quota.Requests); errors.Is(err, quota.ErrExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":  errorMessage(c, "tenant_quota_exceeded"),
+				"code":   "tenant_quota_exceeded",
+				"metric": quota.Requests,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"strings"
+
+	"authentio/internal/requestctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceFingerprintHeader carries an opaque identifier the client generates
+// once per device and sends with every request, so logins from the same
+// device are recognized.
+const DeviceFingerprintHeader = "X-Device-Fingerprint"
+
+// maxFingerprintLength bounds the fingerprint header; longer values are ignored.
+const maxFingerprintLength = 256
+
+// RequestContext creates a Gin middleware that copies client details (IP, user
+// agent, device fingerprint, the location and anonymizer signals resolved
+// by GeoIPMiddleware and the client certificate accepted by ClientCertificate) into
+// the request's context.Context so the service layer can use them, e.g. for
+// audit logging.
+// It must be registered after GeoIPMiddleware.
+//
+// Returns:
+//   - gin.HandlerFunc: Request context middleware function
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := requestctx.ClientInfo{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Country:   c.GetString("country"),
+			Latitude:  c.GetFloat64("latitude"),
+			Longitude: c.GetFloat64("longitude"),
+			Located:   c.GetBool("located"),
+			IsProxy:   c.GetBool("is_proxy"),
+			IsHosting: c.GetBool("is_hosting"),
+
+			ClientCertificate: c.GetString("clientCert"),
+		}
+		if fingerprint := strings.TrimSpace(c.GetHeader(DeviceFingerprintHeader)); len(fingerprint) <= maxFingerprintLength {
+			info.DeviceFingerprint = fingerprint
+		}
+		c.Request = c.Request.WithContext(requestctx.WithClientInfo(c.Request.Context(), info))
+
+		c.Next()
+	}
+}
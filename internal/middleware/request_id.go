@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"authentio/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID of a request, sent by clients or proxies
+// that trace requests, and echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied IDs; longer ones are replaced.
+const maxRequestIDLength = 128
+
+// =============================================================================
+// Request ID Middleware
+// =============================================================================
+
+// RequestID creates a Gin middleware that gives every request an ID: the
+// X-Request-ID header when the client or a proxy sent one, otherwise a new
+// random ID. The ID is returned in the X-Request-ID response header and the
+// request_id of response envelopes, and logged with the request, so a
+// client reporting a problem can point at the matching log lines.
+//
+// Returns:
+//   - gin.HandlerFunc: Request ID middleware function
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(response.RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// validRequestID reports whether a client-supplied ID is safe to log and
+// echo: non-empty, bounded, printable ASCII without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit ID in hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
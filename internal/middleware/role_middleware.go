@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireRole creates a Gin middleware that only lets requests through when the
+// authenticated user's role (set by AuthRequired from the "role" claim) is one
+// of the given roles. It must be registered after AuthRequired.
+//
+// Parameters:
+//   - roles: Roles allowed to access the route group
+//
+// Returns:
+//   - gin.HandlerFunc: Role authorization middleware function
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		allowed[role] = true
+	}
+
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if !allowed[role] {
+			logger.Warn("insufficient role for route",
+				zap.Int64("userID", c.GetInt64("userID")),
+				zap.String("role", role),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.JSON(http.StatusForbidden, gin.H{"error": errorMessage(c, "insufficient_permissions"), "code": "insufficient_permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ReauthenticatePath is where clients exchange their password for a sudo
+// token, returned as a hint when one is required.
+const ReauthenticatePath = "/api/v1/user/reauthenticate"
+
+// RequireSudo creates a Gin middleware that refuses state-changing requests
+// (anything but GET, HEAD and OPTIONS) unless the access token carries the
+// "sudo" scope obtained by re-authenticating recently. Stale sessions get 403
+// with code "sudo_required" and the path to re-authenticate at. It must be
+// registered after AuthRequired, usually right after RequireRole.
+//
+// Returns:
+//   - gin.HandlerFunc: Sudo scope middleware function
+func RequireSudo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		if time.Now().Unix() < c.GetInt64("sudoUntil") {
+			c.Next()
+			return
+		}
+
+		logger.Warn("admin operation without recent re-authentication",
+			zap.Int64("userID", c.GetInt64("userID")),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+		)
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  errorMessage(c, "sudo_required"),
+			"code":   "sudo_required",
+			"reauth": ReauthenticatePath,
+		})
+		c.Abort()
+	}
+}
+
+// RequireOrgRole creates a Gin middleware that only lets requests through when
+// the access token is scoped to an organization (POST /orgs/{id}/switch) and
+// the user's role there is at least minRole, so RequireOrgRole("admin") also
+// admits owners. It must be registered after AuthRequired; handlers read the
+// organization from c.GetInt64("orgID").
+//
+// The role comes from the token's "org_role" claim, so role changes apply once
+// the client refreshes its tokens.
+//
+// Parameters:
+//   - minRole: Least privileged organization role allowed (owner, admin or member)
+//
+// Returns:
+//   - gin.HandlerFunc: Organization role authorization middleware function
+func RequireOrgRole(minRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetInt64("orgID") == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": errorMessage(c, "org_token_required"), "code": "org_token_required"})
+			c.Abort()
+			return
+		}
+
+		orgRole := c.GetString("orgRole")
+		if !constants.OrgRoleAtLeast(orgRole, minRole) {
+			logger.Warn("insufficient organization role for route",
+				zap.Int64("userID", c.GetInt64("userID")),
+				zap.Int64("orgID", c.GetInt64("orgID")),
+				zap.String("orgRole", orgRole),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.JSON(http.StatusForbidden, gin.H{"error": errorMessage(c, "insufficient_permissions"), "code": "insufficient_permissions"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// Session Origins
+// =============================================================================
+
+// SessionOrigins creates a Gin middleware guarding the cookie session
+// endpoints of single-page apps. Since the session cookie is sent with every
+// request to them, cross-origin requests are only accepted from the apps'
+// origins, whose CORS headers come from the session routes' CORS policy;
+// requests from other origins get 403. The listed origins may also frame the
+// endpoints, for silent renewal in a hidden iframe. Responses are never
+// cached.
+//
+// Parameters:
+//   - allowed: Origins of the apps (SESSION_ALLOWED_ORIGINS)
+//
+// Returns:
+//   - gin.HandlerFunc: Session origin middleware function
+func SessionOrigins(allowed []string) gin.HandlerFunc {
+	frameAncestors := strings.Join(append([]string{"'self'"}, allowed...), " ")
+
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		if origin != "" && !sameOrigin(origin, requestScheme(c), c.Request.Host) {
+			if !SessionOriginAllowed(origin, allowed) {
+				logger.Logger.Warn("session request from an origin not allowed",
+					zap.String("ip", c.ClientIP()),
+					zap.String("origin", origin),
+					zap.String("path", c.Request.URL.Path),
+				)
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": errorMessage(c, "origin_not_allowed"),
+					"code":  "origin_not_allowed",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Header("Content-Security-Policy", "frame-ancestors "+frameAncestors)
+		c.Header("Cache-Control", "no-store")
+		c.Next()
+	}
+}
+
+// SessionOriginAllowed reports whether origin is one of the apps' origins.
+func SessionOriginAllowed(origin string, allowed []string) bool {
+	for _, o := range allowed {
+		if strings.EqualFold(origin, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin reports whether origin is scheme://host, where the request was
+// sent. Browsers send Origin on same-origin POST requests too.
+func sameOrigin(origin, scheme, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Scheme, scheme) && strings.EqualFold(u.Host, host)
+}
+
+// requestScheme returns the scheme the client used: https over TLS, else the
+// X-Forwarded-Proto of a proxy gin trusts (one whose X-Forwarded-For gave
+// ClientIP), else http.
+func requestScheme(c *gin.Context) string {
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	if c.ClientIP() != c.RemoteIP() {
+		if proto := strings.TrimSpace(strings.Split(c.GetHeader("X-Forwarded-Proto"), ",")[0]); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+	return "http"
+}
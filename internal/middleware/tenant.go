@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"authentio/internal/constants"
+	"authentio/internal/repository"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// tenantPathPrefix starts request paths that name a tenant in path mode,
+// e.g. /t/acme/api/v1/auth/login.
+const tenantPathPrefix = "/t/"
+
+type tenantSlugKey struct{}
+
+// TenantResolver resolves the tenant of each request according to
+// TENANCY_MODE and scopes the request's context.Context to it, so repositories
+// only see that tenant's users, invitations, domain rules and organizations.
+// Requests that don't name a tenant use the default tenant.
+type TenantResolver struct {
+	mode       string
+	header     string
+	baseDomain string
+	tenants    repository.TenantRepository
+
+	// Tenant IDs by slug; tenants are never deleted, so entries stay valid
+	mu    sync.RWMutex
+	cache map[string]int64
+}
+
+// NewTenantResolver creates a resolver for the given tenancy mode. Subdomain
+// mode needs the base domain tenants are served under (TENANT_BASE_DOMAIN).
+//
+// Parameters:
+//   - mode: Tenancy mode (constants.Tenancy*)
+//   - header: Request header carrying the tenant slug in header mode
+//   - baseDomain: Parent domain of tenant subdomains in subdomain mode
+//   - tenants: Tenant repository used to look up slugs
+//
+// Returns:
+//   - *TenantResolver: Configured resolver
+//   - error: When the mode is unknown or its settings are incomplete
+func NewTenantResolver(mode, header, baseDomain string, tenants repository.TenantRepository) (*TenantResolver, error) {
+	switch mode {
+	case constants.TenancyOff, constants.TenancyHeader, constants.TenancyPath:
+	case constants.TenancySubdomain:
+		if baseDomain == "" {
+			return nil, fmt.Errorf("TENANT_BASE_DOMAIN is required when TENANCY_MODE=%s", mode)
+		}
+	default:
+		return nil, fmt.Errorf("invalid TENANCY_MODE %q", mode)
+	}
+
+	return &TenantResolver{
+		mode:       mode,
+		header:     header,
+		baseDomain: strings.ToLower(strings.TrimPrefix(baseDomain, ".")),
+		tenants:    tenants,
+		cache:      map[string]int64{constants.DefaultTenantSlug: constants.DefaultTenantID},
+	}, nil
+}
+
+// Handler strips the /t/{tenant} prefix from request paths in path mode before
+// the router sees them, remembering the slug for Middleware. In other modes
+// it returns next unchanged.
+//
+// Parameters:
+//   - next: Handler serving the un-prefixed paths (the Gin engine)
+//
+// Returns:
+//   - http.Handler: Path-rewriting handler
+func (t *TenantResolver) Handler(next http.Handler) http.Handler {
+	if t.mode != constants.TenancyPath {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, tenantPathPrefix); ok {
+			slug, path, _ := strings.Cut(rest, "/")
+			r2 := r.Clone(context.WithValue(r.Context(), tenantSlugKey{}, slug))
+			r2.URL.Path = "/" + path
+			r2.URL.RawPath = ""
+			r = r2
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware creates a Gin middleware that resolves the request's tenant,
+// stores its ID in the Gin context ("tenantID") and in the request's
+// context.Context. Unknown tenants get 404.
+//
+// Returns:
+//   - gin.HandlerFunc: Tenant resolution middleware function
+func (t *TenantResolver) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID := constants.DefaultTenantID
+
+		if slug := t.slug(c.Request); slug != "" {
+			id, err := t.lookup(c.Request.Context(), slug)
+			if err != nil {
+				logger.Error("tenant lookup failed", zap.String("tenant", slug), zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": errorMessage(c, "internal_error"), "code": "internal_error"})
+				c.Abort()
+				return
+			}
+			if id == 0 {
+				logger.Debug("unknown tenant", zap.String("tenant", slug), zap.String("path", c.Request.URL.Path))
+				c.JSON(http.StatusNotFound, gin.H{"error": errorMessage(c, "unknown_tenant"), "code": "unknown_tenant"})
+				c.Abort()
+				return
+			}
+			tenantID = id
+		}
+
+		c.Set("tenantID", tenantID)
+		c.Request = c.Request.WithContext(requestctx.WithTenant(c.Request.Context(), tenantID))
+
+		c.Next()
+	}
+}
+
+// TenantID returns the ID of the tenant with the given slug for callers
+// outside the HTTP stack (the gRPC API). An empty slug names the default
+// tenant; unknown slugs return 0.
+func (t *TenantResolver) TenantID(ctx context.Context, slug string) (int64, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if slug == "" {
+		return constants.DefaultTenantID, nil
+	}
+	return t.lookup(ctx, slug)
+}
+
+// requestTenant returns the tenant ID resolved for c by the tenant middleware,
+// or the default tenant when it didn't run.
+func requestTenant(c *gin.Context) int64 {
+	if id := c.GetInt64("tenantID"); id != 0 {
+		return id
+	}
+	return constants.DefaultTenantID
+}
+
+// slug returns the tenant slug named by r, or "" for the default tenant.
+func (t *TenantResolver) slug(r *http.Request) string {
+	switch t.mode {
+	case constants.TenancyHeader:
+		return strings.ToLower(strings.TrimSpace(r.Header.Get(t.header)))
+	case constants.TenancySubdomain:
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		sub, ok := strings.CutSuffix(strings.ToLower(host), "."+t.baseDomain)
+		if !ok {
+			return ""
+		}
+		return sub
+	case constants.TenancyPath:
+		slug, _ := r.Context().Value(tenantSlugKey{}).(string)
+		return strings.ToLower(slug)
+	}
+	return ""
+}
+
+// lookup returns the ID of the tenant with the given slug, or 0 if there is none.
+func (t *TenantResolver) lookup(ctx context.Context, slug string) (int64, error) {
+	t.mu.RLock()
+	id, ok := t.cache[slug]
+	t.mu.RUnlock()
+	if ok {
+		return id, nil
+	}
+
+	tenant, err := t.tenants.FindBySlug(ctx, slug)
+	if err != nil || tenant == nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	t.cache[slug] = tenant.ID
+	t.mu.Unlock()
+	return tenant.ID, nil
+}
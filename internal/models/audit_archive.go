@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// AuditLogFilter selects the audit log entries of all tenants recorded in
+// [From, Before) for Events or, with OtherEvents, for every other event, up
+// to entry ThroughID. Zero fields don't restrict the selection.
+type AuditLogFilter struct {
+	Events      []string
+	OtherEvents bool
+	From        time.Time
+	Before      time.Time
+	ThroughID   int64
+}
+
+// AuditArchiveRequest is the payload for exporting the audit log entries
+// recorded in [From, To) to the archive.
+type AuditArchiveRequest struct {
+	From time.Time `json:"from" validate:"required" example:"2026-01-01T00:00:00Z"`
+	To   time.Time `json:"to" validate:"required" example:"2026-02-01T00:00:00Z"`
+}
+
+// AuditArchive is the manifest of an archival run: the gzipped NDJSON files
+// it wrote, one audit log entry per line. Runs of the data retention job
+// (Reason "retention") delete the entries they archived; on-demand exports
+// (Reason "export") keep them.
+type AuditArchive struct {
+	ID          string             `json:"id" example:"20260116T120000Z-3f2a9c1d"`
+	Reason      string             `json:"reason" example:"export"`   // retention or export
+	Data        string             `json:"data" example:"audit_logs"` // login_events, audit_logs or all
+	Status      string             `json:"status" example:"complete"` // running, complete or failed
+	From        *time.Time         `json:"from,omitempty"`            // entries recorded at or after
+	To          time.Time          `json:"to"`                        // entries recorded before
+	Manifest    string             `json:"manifest" example:"audit-logs/20260116T120000Z-3f2a9c1d/manifest.json"`
+	Rows        int                `json:"rows"`
+	Files       []AuditArchiveFile `json:"files"`
+	Error       string             `json:"error,omitempty"`
+	RequestedBy *int64             `json:"requested_by,omitempty"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CompletedAt *time.Time         `json:"completed_at,omitempty"`
+}
+
+// AuditArchiveFile is one gzipped NDJSON file of an archive, holding the
+// entries FirstID to LastID selected by the run.
+type AuditArchiveFile struct {
+	Key     string `json:"key" example:"audit-logs/20260116T120000Z-3f2a9c1d/000001.ndjson.gz"`
+	Rows    int    `json:"rows"`
+	Bytes   int    `json:"bytes"`
+	SHA256  string `json:"sha256"`
+	FirstID int64  `json:"first_id"`
+	LastID  int64  `json:"last_id"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AuditLog is a single security-relevant event such as a login or password reset.
+// IPAddress and UserAgent are personal data and are cleared when the subject is
+// anonymized; the rest of the record is kept for aggregate statistics.
+type AuditLog struct {
+	ID        int64                  `json:"id" db:"id"`
+	UserID    *int64                 `json:"user_id,omitempty" db:"user_id"`
+	ActorID   *int64                 `json:"actor_id,omitempty" db:"actor_id"`
+	Event     string                 `json:"event" db:"event"`
+	IPAddress string                 `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent string                 `json:"user_agent,omitempty" db:"user_agent"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty" db:"metadata"`
+	CreatedAt time.Time              `json:"created_at" db:"created_at"`
+
+	// DeviceID is the subject's registered device the event came from.
+	// DeviceFingerprintHash identifies it when the entry is written.
+	DeviceID              *int64 `json:"device_id,omitempty" db:"device_id"`
+	DeviceFingerprintHash string `json:"-" db:"-"`
+}
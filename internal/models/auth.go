@@ -5,11 +5,34 @@ type RegisterRequest struct {
 	LastName  string `json:"last_name" db:"last_name" validate:"required,alphaSpace,min=2,max=50"`
 	Email     string `json:"email" db:"email" validate:"required,email,max=50"`
 	Password  string `json:"password" db:"password" validate:"required,password"`
+
+	// Username optionally lets the user log in without their email
+	Username string `json:"username,omitempty" validate:"omitempty,username"`
+
+	// AcceptTerms must be true: registering implies accepting the current
+	// Terms of Service and Privacy Policy versions.
+	AcceptTerms bool `json:"accept_terms" validate:"required"`
+
+	// InviteToken is required when INVITE_ONLY_REGISTRATION is enabled
+	InviteToken string `json:"invite_token,omitempty" validate:"max=128"`
+
+	// OrgInviteToken joins the new account to the inviting organization. It
+	// also satisfies INVITE_ONLY_REGISTRATION.
+	OrgInviteToken string `json:"org_invite_token,omitempty" validate:"max=128"`
 }
 
+// LoginRequest identifies the user by email or by username.
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email,max=100"`
+	Email    string `json:"email,omitempty" validate:"required_without=Username,omitempty,email,max=100"`
+	Username string `json:"username,omitempty" validate:"required_without=Email,omitempty,max=30"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ReauthenticateRequest confirms the signed-in user's password, and for
+// users with 2FA the code emailed to them, to obtain a sudo token.
+type ReauthenticateRequest struct {
 	Password string `json:"password" validate:"required"`
+	Code     string `json:"code,omitempty" validate:"omitempty,max=32"`
 }
 
 
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// UserConsent records a user's acceptance of a specific version of a legal
+// document (Terms of Service or Privacy Policy).
+type UserConsent struct {
+	ID         int64     `json:"-" db:"id"`
+	UserID     int64     `json:"-" db:"user_id"`
+	Document   string    `json:"document" db:"document"`
+	Version    string    `json:"version" db:"version"`
+	IPAddress  string    `json:"-" db:"ip_address"`
+	AcceptedAt time.Time `json:"accepted_at" db:"accepted_at"`
+}
+
+// ConsentRequest is the payload for accepting the current legal documents.
+type ConsentRequest struct {
+	TOSVersion           string `json:"tos_version" validate:"required,max=50"`
+	PrivacyPolicyVersion string `json:"privacy_policy_version" validate:"required,max=50"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Device is a device a user signed in from, recognized by the fingerprint
+// its client sends.
+type Device struct {
+	ID              int64     `json:"id" db:"id"`
+	UserID          int64     `json:"-" db:"user_id"`
+	FingerprintHash string    `json:"-" db:"fingerprint_hash"`
+	Name            string    `json:"name" db:"name"`
+	Platform        string    `json:"platform" db:"platform"`
+	IPAddress       string    `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent       string    `json:"user_agent,omitempty" db:"user_agent"`
+	FirstSeenAt     time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt      time.Time `json:"last_seen_at" db:"last_seen_at"`
+
+	// Current is true for the device making the request.
+	Current bool `json:"current"`
+}
+
+// RenameDeviceRequest is the payload for naming a device.
+type RenameDeviceRequest struct {
+	Name string `json:"name" validate:"max=100"`
+}
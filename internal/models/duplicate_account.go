@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// DuplicateAccountGroup is a set of accounts that likely belong to the same
+// person, found by the duplicate account scan.
+type DuplicateAccountGroup struct {
+	ID          int64                  `json:"id"`
+	Reason      string                 `json:"reason" example:"email"`                 // email, phone or provider
+	MatchKey    string                 `json:"match_key" example:"jane.doe@gmail.com"` // what the accounts share
+	Status      string                 `json:"status" example:"open"`                  // open or dismissed
+	Users       []DuplicateAccountUser `json:"users"`
+	DismissedBy *int64                 `json:"dismissed_by,omitempty"`
+	DismissedAt *time.Time             `json:"dismissed_at,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"` // first detected
+	UpdatedAt   time.Time              `json:"updated_at"` // members last changed
+}
+
+// DuplicateAccountUser is an account of a duplicate group.
+type DuplicateAccountUser struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Provider  string    `json:"provider"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DuplicateCandidate is a group of accounts found by a scan.
+type DuplicateCandidate struct {
+	Reason   string
+	MatchKey string
+	UserIDs  []int64
+}
+
+// DuplicateScanResult summarizes a duplicate account scan.
+type DuplicateScanResult struct {
+	Groups   int `json:"groups"`   // groups of likely duplicates found
+	Accounts int `json:"accounts"` // accounts in at least one group
+}
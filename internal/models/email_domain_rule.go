@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// EmailDomainRule allows or blocks registrations from an email domain.
+// Rules from the environment have ID 0 and Source "env"; they can't be deleted
+// through the API.
+type EmailDomainRule struct {
+	ID        int64     `json:"id" db:"id"`
+	Domain    string    `json:"domain" db:"domain"`
+	Rule      string    `json:"rule" db:"rule"`
+	Source    string    `json:"source" db:"-"`
+	CreatedBy *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time `json:"created_at,omitempty" db:"created_at"`
+}
+
+// EmailDomainRuleRequest is the payload for adding a domain rule.
+type EmailDomainRuleRequest struct {
+	Domain string `json:"domain" validate:"required,fqdn,max=255"`
+	Rule   string `json:"rule" validate:"required,oneof=allow block"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// EmailLog is the delivery record of an email to one recipient. UserID is
+// the account the recipient address belongs to, when there is one.
+type EmailLog struct {
+	ID                int64      `json:"id"`
+	MessageID         string     `json:"message_id"` // shared by the recipients of one email
+	UserID            *int64     `json:"user_id,omitempty"`
+	Event             string     `json:"event"`
+	Recipient         string     `json:"recipient"`
+	Subject           string     `json:"subject"`
+	Status            string     `json:"status"`
+	Attempts          int        `json:"attempts"`
+	Error             string     `json:"error,omitempty"`
+	ProviderMessageID string     `json:"provider_message_id,omitempty"`
+	SentAt            *time.Time `json:"sent_at,omitempty"`
+	DeliveredAt       *time.Time `json:"delivered_at,omitempty"`
+	OpenedAt          *time.Time `json:"opened_at,omitempty"` // first open
+	OpenCount         int        `json:"open_count"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// EmailTestRequest is the body of POST /admin/email/test.
+type EmailTestRequest struct {
+	To string `json:"to" validate:"required,email" example:"admin@example.com"`
+}
+
+// EmailTestResult identifies a test email in the delivery log. Queued is set
+// when the email went to the delivery queue or workers rather than straight
+// to the provider.
+type EmailTestResult struct {
+	MessageID string `json:"message_id"`
+	Queued    bool   `json:"queued"`
+}
+
+// EmailLogFilter selects delivery records. Zero fields match every record.
+type EmailLogFilter struct {
+	UserID    int64
+	MessageID string
+	Recipient string
+	Event     string
+	Status    string
+}
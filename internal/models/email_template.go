@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// EmailTemplate is a saved version of an email template, replacing the
+// embedded default while it is active.
+type EmailTemplate struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Subject   string    `json:"subject"`
+	Content   string    `json:"content"`
+	Active    bool      `json:"active"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EmailTemplateSummary describes an email template in listings. Version is
+// the active saved version, 0 when the embedded default is used.
+type EmailTemplateSummary struct {
+	Name      string     `json:"name"`
+	Version   int        `json:"version"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// EmailTemplateDetails is an email template with its embedded default and
+// its saved versions, newest first.
+type EmailTemplateDetails struct {
+	Name           string           `json:"name"`
+	DefaultSubject string           `json:"default_subject"`
+	DefaultContent string           `json:"default_content"`
+	Versions       []*EmailTemplate `json:"versions"`
+}
+
+// EmailTemplateRequest is the payload for saving an email template version.
+// Both fields use html/template syntax.
+type EmailTemplateRequest struct {
+	Subject string `json:"subject" validate:"required,max=255"`
+	Content string `json:"content" validate:"required,max=100000"`
+}
+
+// EmailTemplatePreviewRequest renders a draft of an email template. Omitted
+// fields use the version in use.
+type EmailTemplatePreviewRequest struct {
+	Subject string `json:"subject" validate:"max=255"`
+	Content string `json:"content" validate:"max=100000"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// GeoPolicy holds the geographic sign-in rules of a tenant. Countries are
+// ISO 3166-1 alpha-2 codes resolved by GeoIP.
+type GeoPolicy struct {
+	// BlockedCountries are refused on every request, sign-in included.
+	BlockedCountries []string `json:"blocked_countries"`
+	// AllowedCountries, when not empty, are the only countries not refused.
+	AllowedCountries []string `json:"allowed_countries"`
+	// SuspiciousCountries get SuspiciousAction when signing in, and are
+	// logged on other requests.
+	SuspiciousCountries []string `json:"suspicious_countries"`
+	SuspiciousAction    string   `json:"suspicious_action"`
+	// MaxTravelSpeed (km/h) flags sign-ins too far from the user's previous
+	// one for the time elapsed, with VelocityAction. 0 turns it off.
+	MaxTravelSpeed int    `json:"max_travel_speed_kmh"`
+	VelocityAction string `json:"velocity_action"`
+
+	// Source is "tenant" for a policy saved by the tenant's admins, "config"
+	// for the deployment's defaults.
+	Source    string     `json:"source"`
+	UpdatedBy *int64     `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateGeoPolicyRequest replaces a tenant's geographic sign-in rules.
+// Omitted actions default to log for suspicious countries and step_up for
+// impossible travel.
+type UpdateGeoPolicyRequest struct {
+	BlockedCountries    []string `json:"blocked_countries" validate:"max=250,dive,len=2,alpha"`
+	AllowedCountries    []string `json:"allowed_countries" validate:"max=250,dive,len=2,alpha"`
+	SuspiciousCountries []string `json:"suspicious_countries" validate:"max=250,dive,len=2,alpha"`
+	SuspiciousAction    string   `json:"suspicious_action" validate:"omitempty,oneof=log step_up block"`
+	MaxTravelSpeed      int      `json:"max_travel_speed_kmh" validate:"omitempty,min=100,max=20000"`
+	VelocityAction      string   `json:"velocity_action" validate:"omitempty,oneof=log step_up block"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"authentio/internal/constants"
+)
+
+// Invitation is an invite token sent to an email address. The token itself is
+// only ever emailed; TokenHash is what gets stored.
+type Invitation struct {
+	ID         int64      `json:"id" db:"id"`
+	Email      string     `json:"email" db:"email"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	InvitedBy  *int64     `json:"invited_by,omitempty" db:"invited_by"`
+	AcceptedBy *int64     `json:"accepted_by,omitempty" db:"accepted_by"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+
+	// Status is computed from the timestamps (see constants.Invitation*)
+	Status string `json:"status" db:"-"`
+}
+
+// CurrentStatus derives the invitation state at now.
+func (i *Invitation) CurrentStatus(now time.Time) string {
+	return invitationStatus(i.AcceptedAt, i.RevokedAt, i.ExpiresAt, now)
+}
+
+// invitationStatus derives an invitation's state from its timestamps.
+func invitationStatus(acceptedAt, revokedAt *time.Time, expiresAt, now time.Time) string {
+	switch {
+	case acceptedAt != nil:
+		return constants.InvitationAccepted
+	case revokedAt != nil:
+		return constants.InvitationRevoked
+	case now.After(expiresAt):
+		return constants.InvitationExpired
+	default:
+		return constants.InvitationPending
+	}
+}
+
+// InvitationRequest is the payload for inviting someone by email.
+type InvitationRequest struct {
+	Email string `json:"email" validate:"required,email,max=50"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// IPAllowlistEntry is an address block an account may be used from.
+type IPAllowlistEntry struct {
+	ID        int64      `json:"id" db:"id"`
+	UserID    int64      `json:"-" db:"user_id"`
+	CIDR      string     `json:"cidr" db:"cidr"`
+	Label     string     `json:"label" db:"label"`
+	CreatedBy *int64     `json:"created_by,omitempty" db:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"` // set for addresses allowed after step-up verification
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IPAllowlist is an account's address restriction. It applies once the
+// account has a permanent entry.
+type IPAllowlist struct {
+	Mode    string             `json:"mode"`
+	Entries []IPAllowlistEntry `json:"entries"`
+}
+
+// AddIPAllowlistEntryRequest allows an address or CIDR block.
+type AddIPAllowlistEntryRequest struct {
+	CIDR  string `json:"cidr" validate:"required,cidr|ip"`
+	Label string `json:"label" validate:"max=100"`
+}
+
+// IPAllowlistModeRequest sets what happens to logins from other addresses.
+type IPAllowlistModeRequest struct {
+	Mode string `json:"mode" validate:"required,oneof=block verify"`
+}
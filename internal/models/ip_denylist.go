@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IPDenylistEntry is an address block refused on every route.
+type IPDenylistEntry struct {
+	ID        int64      `json:"id" db:"id"`
+	CIDR      string     `json:"cidr" db:"cidr"`
+	Reason    string     `json:"reason" db:"reason"`
+	CreatedBy *int64     `json:"created_by,omitempty" db:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"` // nil blocks until removed
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AddIPDenylistEntryRequest denies an address or CIDR block, for TTLSeconds
+// or until removed when it is 0.
+type AddIPDenylistEntryRequest struct {
+	CIDR       string `json:"cidr" validate:"required,cidr|ip"`
+	Reason     string `json:"reason" validate:"max=255"`
+	TTLSeconds int    `json:"ttl_seconds" validate:"omitempty,min=60,max=31536000"`
+}
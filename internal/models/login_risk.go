@@ -0,0 +1,58 @@
+package models
+
+// RiskPolicy maps the risk score of a login (0-100) to what it takes to sign
+// in. A zero threshold is off.
+type RiskPolicy struct {
+	SkipBelow    int // known-device logins scoring below this skip push approval
+	RequireAt    int // logins scoring at least this need a second factor
+	QuarantineAt int // logins scoring at least this wait for approval by email link
+	BlockAt      int // logins scoring at least this are refused
+}
+
+// TenantRiskPolicy holds a tenant's risk threshold overrides; nil fields use
+// the deployment's policy.
+type TenantRiskPolicy struct {
+	SkipBelow    *int `db:"mfa_risk_skip_below"`
+	RequireAt    *int `db:"mfa_risk_require_at"`
+	QuarantineAt *int `db:"mfa_risk_quarantine_at"`
+	BlockAt      *int `db:"mfa_risk_block_at"`
+}
+
+// Apply returns defaults with the tenant's overrides applied.
+func (p *TenantRiskPolicy) Apply(defaults RiskPolicy) RiskPolicy {
+	if p == nil {
+		return defaults
+	}
+	if p.SkipBelow != nil {
+		defaults.SkipBelow = *p.SkipBelow
+	}
+	if p.RequireAt != nil {
+		defaults.RequireAt = *p.RequireAt
+	}
+	if p.QuarantineAt != nil {
+		defaults.QuarantineAt = *p.QuarantineAt
+	}
+	if p.BlockAt != nil {
+		defaults.BlockAt = *p.BlockAt
+	}
+	return defaults
+}
+
+// QuarantineApprovalRequest approves a quarantined login with the token from
+// the emailed link.
+type QuarantineApprovalRequest struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+// EmailLoginRequest completes a login challenged for a code sent by email,
+// with the challenge token returned by login.
+type EmailLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,max=2048"`
+	Code           string `json:"code" validate:"required,max=32"`
+}
+
+// ChallengeTokenRequest carries the challenge token of a login waiting for a
+// second factor, e.g. to resend its code.
+type ChallengeTokenRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,max=2048"`
+}
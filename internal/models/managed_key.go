@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// ManagedKey is a key generated by a rotation.
+type ManagedKey struct {
+	ID        int64      `json:"id"`
+	Purpose   string     `json:"purpose" example:"jwt"`             // jwt, oidc or secrets
+	KeyID     string     `json:"key_id" example:"20261016T120000Z"` // "kid" header or encryption key ID
+	Material  string     `json:"-"`                                 // sealed key material
+	Status    string     `json:"status" example:"active"`           // active, verify_only or retired
+	CreatedBy *int64     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"` // when it stopped signing or sealing
+}
+
+// KeyRotation is a rotation of the key of one purpose, with the progress of
+// the re-encryption of the values the previous key protects.
+type KeyRotation struct {
+	ID            int64      `json:"id"`
+	Purpose       string     `json:"purpose" example:"secrets"`
+	KeyID         string     `json:"key_id" example:"20261016T120000Z"` // new key
+	PreviousKeyID string     `json:"previous_key_id" example:"1"`       // key made verify-only
+	Status        string     `json:"status" example:"running"`          // running, completed or failed
+	Reencrypted   int        `json:"reencrypted"`                       // values moved to the new key so far
+	Error         string     `json:"error,omitempty"`
+	RequestedBy   *int64     `json:"requested_by,omitempty"`
+	StartedAt     time.Time  `json:"started_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+// KeyRotationStatus lists the current and phased-in keys of each purpose and
+// the recent rotations.
+type KeyRotationStatus struct {
+	Keys      []KeyStatus    `json:"keys"`
+	Rotations []*KeyRotation `json:"rotations"` // newest first
+}
+
+// KeyStatus is a key in use, whether configured or generated by a rotation.
+type KeyStatus struct {
+	Purpose   string     `json:"purpose" example:"jwt"`
+	KeyID     string     `json:"key_id" example:"20261016T120000Z"`
+	Status    string     `json:"status" example:"active"`   // active or verify_only
+	Source    string     `json:"source" example:"rotation"` // config or rotation
+	CreatedAt *time.Time `json:"created_at,omitempty"`      // rotation keys only
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// NotificationPreferences records which optional notifications a user
+// receives and on which channels. Critical mail such as verification codes
+// and password resets ignores them.
+type NotificationPreferences struct {
+	UserID int64 `json:"user_id" db:"user_id"`
+
+	// SecurityAlertEmails covers optional security alerts such as logins
+	// from a new location. Critical alerts (password changed, two-factor
+	// authentication turned off) are always emailed.
+	SecurityAlertEmails bool `json:"security_alert_emails" db:"security_alert_emails"`
+
+	// MarketingEmails is the opt-in for product news. Authentio itself sends
+	// none; applications sending their own campaigns should check it.
+	MarketingEmails bool `json:"marketing_emails" db:"marketing_emails"`
+
+	// LoginNotifications sends an email after every successful login.
+	LoginNotifications bool `json:"login_notifications" db:"login_notifications"`
+
+	// PhoneNumber (E.164) receives the text messages turned on below.
+	PhoneNumber string `json:"phone_number,omitempty" db:"phone_number"`
+
+	// Security alerts and login notifications by text message and on the
+	// user's push devices, in addition to the emails above.
+	SecurityAlertSMS      bool `json:"security_alert_sms" db:"security_alert_sms"`
+	SecurityAlertPush     bool `json:"security_alert_push" db:"security_alert_push"`
+	LoginNotificationSMS  bool `json:"login_notification_sms" db:"login_notification_sms"`
+	LoginNotificationPush bool `json:"login_notification_push" db:"login_notification_push"`
+
+	// Classes of optional notifications, on unless turned off: alerts for
+	// logins from an IP address none of the recent logins came from, and
+	// reminders before the password expires (PASSWORD_MAX_AGE).
+	NewLocationAlerts       bool `json:"new_location_alerts" db:"new_location_alerts"`
+	PasswordExpiryReminders bool `json:"password_expiry_reminders" db:"password_expiry_reminders"`
+
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultNotificationPreferences returns the preferences of a user who never
+// saved any: security alert emails and the optional notification classes
+// on, everything else off.
+func DefaultNotificationPreferences(userID int64) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID:                  userID,
+		SecurityAlertEmails:     true,
+		NewLocationAlerts:       true,
+		PasswordExpiryReminders: true,
+	}
+}
+
+// UpdatePreferencesRequest changes notification preferences. Omitted fields
+// are left unchanged; an empty phone number removes it.
+type UpdatePreferencesRequest struct {
+	SecurityAlertEmails   *bool   `json:"security_alert_emails"`
+	MarketingEmails       *bool   `json:"marketing_emails"`
+	LoginNotifications    *bool   `json:"login_notifications"`
+	PhoneNumber           *string `json:"phone_number" example:"+14155552671"`
+	SecurityAlertSMS      *bool   `json:"security_alert_sms"`
+	SecurityAlertPush     *bool   `json:"security_alert_push"`
+	LoginNotificationSMS  *bool   `json:"login_notification_sms"`
+	LoginNotificationPush *bool   `json:"login_notification_push"`
+
+	NewLocationAlerts       *bool `json:"new_location_alerts"`
+	PasswordExpiryReminders *bool `json:"password_expiry_reminders"`
+}
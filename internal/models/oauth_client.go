@@ -0,0 +1,122 @@
+package models
+
+import "time"
+
+// OAuthClient is an application registered with the OpenID Connect provider.
+// The client secret is only returned when the client is created; SecretHash
+// is what gets stored. Public clients have no secret and must use PKCE.
+type OAuthClient struct {
+	ID           int64     `json:"id" db:"id"`
+	ClientID     string    `json:"client_id" db:"client_id"`
+	SecretHash   string    `json:"-" db:"secret_hash"`
+	Name         string    `json:"name" db:"name"`
+	RedirectURIs []string  `json:"redirect_uris" db:"redirect_uris"`
+	Scopes       []string  `json:"scopes" db:"scopes"`
+	CreatedBy    *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+
+	// ClientSecret is only set in the response that creates the client
+	ClientSecret string `json:"client_secret,omitempty" db:"-"`
+}
+
+// Public reports whether the client has no secret.
+func (c *OAuthClient) Public() bool {
+	return c.SecretHash == ""
+}
+
+// AllowsRedirect reports whether uri exactly matches a registered redirect URI.
+func (c *OAuthClient) AllowsRedirect(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthAuthorizationCode is a single-use code issued by the authorization
+// endpoint. The code itself is only ever sent to the client; CodeHash is what
+// gets stored.
+type OAuthAuthorizationCode struct {
+	ID            int64      `db:"id"`
+	CodeHash      string     `db:"code_hash"`
+	ClientID      int64      `db:"client_id"` // oauth_clients.id
+	UserID        int64      `db:"user_id"`
+	RedirectURI   string     `db:"redirect_uri"`
+	Scope         string     `db:"scope"`
+	Nonce         string     `db:"nonce"`
+	CodeChallenge string     `db:"code_challenge"`
+	ExpiresAt     time.Time  `db:"expires_at"`
+	UsedAt        *time.Time `db:"used_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+// OAuthGrant records the scopes a user approved for an OAuth client.
+type OAuthGrant struct {
+	ID            int64     `json:"-" db:"id"`
+	UserID        int64     `json:"-" db:"user_id"`
+	OAuthClientID int64     `json:"-" db:"client_id"` // oauth_clients.id
+	ClientID      string    `json:"client_id" db:"-"` // public identifier of the client
+	ClientName    string    `json:"client_name" db:"-"`
+	Scopes        []string  `json:"scopes" db:"scope"`
+	CreatedAt     time.Time `json:"granted_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// OAuthConsentPrompt describes an authorization request for the consent
+// screen.
+type OAuthConsentPrompt struct {
+	ClientID   string             `json:"client_id"`
+	ClientName string             `json:"client_name"`
+	Scopes     []OAuthScopeDetail `json:"scopes"`
+
+	// ConsentRequired is false when the user already approved every requested
+	// scope; the consent page can then approve without asking.
+	ConsentRequired bool `json:"consent_required"`
+}
+
+// OAuthScopeDetail is a requested scope with a description for the user.
+type OAuthScopeDetail struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+	Granted     bool   `json:"granted"` // already approved earlier
+}
+
+// OAuthClientRequest is the payload for registering an OAuth client. Scopes
+// defaults to every supported scope.
+type OAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=255"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" validate:"omitempty,dive,oneof=openid profile email offline_access"`
+	Public       bool     `json:"public"` // no secret; the client must use PKCE
+}
+
+// AuthorizeRequest holds the parameters of an OpenID Connect authorization
+// request (authorization code flow).
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type" form:"response_type"`
+	ClientID            string `json:"client_id" form:"client_id"`
+	RedirectURI         string `json:"redirect_uri" form:"redirect_uri"`
+	Scope               string `json:"scope" form:"scope"`
+	State               string `json:"state" form:"state"`
+	Nonce               string `json:"nonce" form:"nonce"`
+	CodeChallenge       string `json:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method" form:"code_challenge_method"`
+}
+
+// AuthorizeDecision is the signed-in user's answer to an authorization request.
+type AuthorizeDecision struct {
+	AuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// TokenRequest holds the form parameters of a token endpoint request.
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Organization groups users so access can be granted per organization.
+type Organization struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	CreatedBy *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Role is the requesting user's role in the organization, when known
+	Role string `json:"role,omitempty" db:"-"`
+}
+
+// OrganizationMember is a user's membership in an organization, with the
+// user's public profile fields.
+type OrganizationMember struct {
+	OrganizationID int64     `json:"organization_id" db:"organization_id"`
+	UserID         int64     `json:"user_id" db:"user_id"`
+	FirstName      string    `json:"first_name" db:"first_name"`
+	LastName       string    `json:"last_name" db:"last_name"`
+	Email          string    `json:"email" db:"email"`
+	Role           string    `json:"role" db:"role"`
+	JoinedAt       time.Time `json:"joined_at" db:"created_at"`
+}
+
+// OrganizationInvitation invites an email address to join an organization.
+// Like Invitation, only the token hash is stored.
+type OrganizationInvitation struct {
+	ID             int64      `json:"id" db:"id"`
+	OrganizationID int64      `json:"organization_id" db:"organization_id"`
+	Email          string     `json:"email" db:"email"`
+	Role           string     `json:"role" db:"role"`
+	TokenHash      string     `json:"-" db:"token_hash"`
+	InvitedBy      *int64     `json:"invited_by,omitempty" db:"invited_by"`
+	AcceptedBy     *int64     `json:"accepted_by,omitempty" db:"accepted_by"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+
+	// Status is computed from the timestamps (see constants.Invitation*)
+	Status string `json:"status" db:"-"`
+}
+
+// CurrentStatus derives the invitation state at now.
+func (i *OrganizationInvitation) CurrentStatus(now time.Time) string {
+	return invitationStatus(i.AcceptedAt, i.RevokedAt, i.ExpiresAt, now)
+}
+
+// OrganizationRequest is the payload for creating an organization. Slug is
+// derived from Name when omitted.
+type OrganizationRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+	Slug string `json:"slug,omitempty" validate:"omitempty,min=2,max=63,slug"`
+}
+
+// OrganizationInvitationRequest is the payload for inviting someone to an
+// organization. Role defaults to member.
+type OrganizationInvitationRequest struct {
+	Email string `json:"email" validate:"required,email,max=50"`
+	Role  string `json:"role,omitempty" validate:"omitempty,oneof=owner admin member"`
+}
+
+// OrganizationMemberRoleRequest is the payload for changing a member's role.
+type OrganizationMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// AcceptOrganizationInvitationRequest is the payload for joining an
+// organization with an invite token.
+type AcceptOrganizationInvitationRequest struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// PairingPollRequest polls a QR code pairing with the poll token returned
+// when the desktop started it.
+type PairingPollRequest struct {
+	Code      string `json:"code" validate:"required,len=32,hexadecimal"`
+	PollToken string `json:"poll_token" validate:"required,len=64,hexadecimal"`
+}
+
+// PairingDecisionRequest approves or denies a QR code pairing.
+type PairingDecisionRequest struct {
+	Approve *bool `json:"approve" validate:"required"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// PushDevice is a mobile device that receives login approval requests.
+type PushDevice struct {
+	ID        int64     `json:"id" db:"id"`
+	UserID    int64     `json:"-" db:"user_id"`
+	Platform  string    `json:"platform" db:"platform"`
+	Token     string    `json:"-" db:"token"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// PushChallenge is a login waiting for approval on one of the user's devices,
+// or, with Method "email", for a code sent by email, or, with Method
+// "quarantine", for approval with an emailed link.
+type PushChallenge struct {
+	ID          int64      `json:"-" db:"id"`
+	ChallengeID string     `json:"id" db:"challenge_id"`
+	UserID      int64      `json:"-" db:"user_id"`
+	Method      string     `json:"-" db:"method"`
+	LoginMethod string     `json:"login_method" db:"login_method"`
+	Status      string     `json:"status" db:"status"`
+	IPAddress   string     `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent   string     `json:"user_agent,omitempty" db:"user_agent"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+
+	// ApprovalTokenHash is the hash of a quarantined login's approval link token.
+	ApprovalTokenHash string `json:"-" db:"approval_token_hash"`
+}
+
+// RegisterDeviceRequest registers the calling app's device for push approvals.
+type RegisterDeviceRequest struct {
+	Platform string `json:"platform" validate:"required,oneof=fcm apns"`
+	Token    string `json:"token" validate:"required,max=4096"`
+	Name     string `json:"name" validate:"omitempty,max=100"`
+}
+
+// PushDecisionRequest approves or denies a login.
+type PushDecisionRequest struct {
+	Approve *bool `json:"approve" validate:"required"`
+}
+
+// PushLoginRequest polls a push approval challenge with the challenge token
+// returned by login.
+type PushLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,max=2048"`
+}
+
+// QuarantinePollRequest polls a quarantined login.
+type QuarantinePollRequest struct {
+	ChallengeID string `json:"challenge_id" validate:"required,max=64"`
+}
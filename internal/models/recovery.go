@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// TwoFactorRecovery is a request to turn off 2FA for a user who lost their
+// second factor. It can be completed once AvailableAt has passed, unless the
+// account owner cancels it first.
+type TwoFactorRecovery struct {
+	ID              int64      `json:"-" db:"id"`
+	UserID          int64      `json:"-" db:"user_id"`
+	TokenHash       string     `json:"-" db:"token_hash"`
+	CancelTokenHash string     `json:"-" db:"cancel_token_hash"`
+	Status          string     `json:"status" db:"status"`
+	IPAddress       string     `json:"ip_address,omitempty" db:"ip_address"`
+	UserAgent       string     `json:"user_agent,omitempty" db:"user_agent"`
+	AvailableAt     time.Time  `json:"available_at" db:"available_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RecoveryCodeLoginRequest completes a push approval login with a recovery
+// code, given the challenge token returned by login.
+type RecoveryCodeLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,max=2048"`
+	Code           string `json:"code" validate:"required,max=32"`
+}
+
+// RecoveryEmailRequest asks for an email code to start recovery from a
+// login waiting for push approval.
+type RecoveryEmailRequest struct {
+	ChallengeID string `json:"challenge_id" validate:"required,max=64"`
+}
+
+// RecoveryEmailVerifyRequest starts recovery with the emailed code.
+type RecoveryEmailVerifyRequest struct {
+	ChallengeID string `json:"challenge_id" validate:"required,max=64"`
+	Code        string `json:"code" validate:"required,max=32"`
+}
+
+// RecoveryTokenRequest completes or cancels a recovery request.
+type RecoveryTokenRequest struct {
+	Token string `json:"token" validate:"required,max=128"`
+}
+
+// RecoveryStarted is returned when email recovery starts. Token completes the
+// recovery once AvailableAt has passed.
+type RecoveryStarted struct {
+	Token       string    `json:"recovery_token"`
+	AvailableAt time.Time `json:"available_at"`
+}
+
+// RecoveryCodes is the set of recovery codes issued to a user. The codes are
+// only shown when they are generated.
+type RecoveryCodes struct {
+	Codes []string `json:"codes,omitempty"`
+
+	// Remaining is the number of codes not used yet.
+	Remaining int `json:"remaining"`
+}
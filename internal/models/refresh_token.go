@@ -1,7 +1,7 @@
 package models
 
 import (
-	_"time"
+	"time"
 )
 
 type RefreshToken struct {
@@ -9,4 +9,25 @@ type RefreshToken struct {
 	UserID    int64     `db:"user_id" json:"user_id"`
 	Token     string    `db:"token" json:"token"`
 	Revoked   bool      `db:"revoked" json:"revoked"`
+
+	// OrganizationID is the organization the token is scoped to, nil for personal tokens
+	OrganizationID *int64 `db:"organization_id" json:"organization_id,omitempty"`
+
+	// OAuthClientID and Scope are set for refresh tokens issued to an OAuth
+	// client by the OpenID Connect provider; nil for first-party tokens
+	OAuthClientID *int64 `db:"oauth_client_id" json:"oauth_client_id,omitempty"`
+	Scope         string `db:"scope" json:"scope,omitempty"`
+
+	// DeviceID is the registered device the session was started on, if known
+	DeviceID *int64 `db:"device_id" json:"device_id,omitempty"`
+
+	// AuthTime is when the user last authenticated in this session, kept
+	// when the token is rotated; nil for tokens issued before it was recorded
+	AuthTime *time.Time `db:"auth_time" json:"-"`
+
+	// AccessTokenID and AccessExpiresAt identify the last access token
+	// issued with the token, revoked when the session ends; empty for
+	// tokens issued before they were recorded
+	AccessTokenID   string     `db:"access_token_id" json:"-"`
+	AccessExpiresAt *time.Time `db:"access_expires_at" json:"-"`
 }
\ No newline at end of file
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SchedulerStatus tells which server runs the scheduled jobs. Leader is
+// empty between the death of a leader and the election of the next one.
+// Without Redis, servers aren't coordinated (Coordinated is false) and each
+// runs the jobs.
+type SchedulerStatus struct {
+	Leader         string         `json:"leader,omitempty" example:"authentio-7d9f-1"` // host name and process ID
+	LeaseExpiresAt *time.Time     `json:"lease_expires_at,omitempty"`                  // unless the leader extends it
+	Server         string         `json:"server,omitempty" example:"authentio-5c2a-1"` // server answering
+	IsLeader       bool           `json:"is_leader"`
+	Coordinated    bool           `json:"coordinated"`
+	Jobs           []ScheduledJob `json:"jobs"`
+}
+
+// ScheduledJob is a job the leader runs every Interval.
+type ScheduledJob struct {
+	Name     string `json:"name" example:"duplicate-account-scan"`
+	Interval string `json:"interval" example:"24h0m0s"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// UserStats are aggregate counts over a tenant's live users.
+type UserStats struct {
+	Total            int64
+	Locked           int64 // refused sign-in: registration pending or rejected
+	TwoFactorEnabled int64
+	Providers        map[string]int64 // users per sign-up provider (email, google, ...)
+}
+
+// DailyCount is a count for one UTC day (YYYY-MM-DD).
+type DailyCount struct {
+	Date  string
+	Count int64
+}
+
+// DailyLoginStats are the sign-ins of one UTC day (YYYY-MM-DD).
+type DailyLoginStats struct {
+	Date        string
+	Successful  int64
+	Failed      int64
+	ActiveUsers int64 // distinct users with a successful sign-in
+}
+
+// DailyCountryLogins are the sign-ins from one country (GeoIP country code,
+// LOCAL or UNKNOWN) on one UTC day (YYYY-MM-DD).
+type DailyCountryLogins struct {
+	Date       string
+	Country    string
+	Successful int64
+	Failed     int64
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Tenant is an isolated application served by this deployment, with its own
+// users, invitations, email domain rules and organizations.
+type Tenant struct {
+	ID        int64     `json:"id" db:"id"`
+	Slug      string    `json:"slug" db:"slug"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// Token signing settings; empty or zero values fall back to the
+	// deployment defaults. JWTSecret is either the key itself or a reference
+	// to it ("env:NAME" or "file:/path"), e.g. a secret injected from a KMS,
+	// or the key wrapped by the configured KMS ("kms:<ciphertext>").
+	JWTSecret       string        `json:"-" db:"jwt_secret"`
+	JWTIssuer       string        `json:"-" db:"jwt_issuer"`
+	AccessTokenTTL  time.Duration `json:"-" db:"access_token_ttl_seconds"`
+	RefreshTokenTTL time.Duration `json:"-" db:"refresh_token_ttl_seconds"`
+}
+
+// TenantBranding is the sender and look of a tenant's emails. Empty fields
+// use the deployment defaults: SMTP_FROM, EMAIL_FROM_NAME and the built-in
+// logo, colors and footer.
+type TenantBranding struct {
+	FromName        string `json:"from_name" validate:"max=100"`
+	FromEmail       string `json:"from_email" validate:"omitempty,email,max=255"`
+	LogoURL         string `json:"logo_url" validate:"omitempty,url,startswith=https://,max=2048"`
+	PrimaryColor    string `json:"primary_color" validate:"omitempty,hexcolor" example:"#2563eb"`
+	BackgroundColor string `json:"background_color" validate:"omitempty,hexcolor" example:"#ffffff"`
+	FooterText      string `json:"footer_text" validate:"max=500"`
+}
@@ -1,11 +1,68 @@
 package models
 
+import "time"
+
 type User struct {
 	BaseModel
 	FirstName string `json:"first_name" db:"first_name"`
       LastName  string `json:"last_name" db:"last_name"`
 	Email    string `json:"email" db:"email"`
+	Username string `json:"username,omitempty" db:"username"` // optional login name, lowercase
 	Password string `json:"-" db:"password"`
+	// PasswordChangedAt is when the password was last set; nil without one.
+	PasswordChangedAt *time.Time `json:"-" db:"password_changed_at"`
 	Provider string `json:"provider" db:"provider"`
+	// ProviderID is the account's subject at Provider (e.g. Google's "sub"),
+	// empty for email signups.
+	ProviderID string `json:"-" db:"provider_id"`
 	IsActive bool   `json:"is_active" db:"is_active"`
-}
\ No newline at end of file
+	Role     string `json:"role" db:"role"`
+	Locale   string `json:"locale,omitempty" db:"locale"` // preferred language; empty means Accept-Language decides
+
+	// AvatarURL is the public URL of the user's profile picture, uploaded
+	// through POST /user/avatar; nil when the user has none.
+	AvatarURL *string `json:"avatar_url,omitempty" db:"avatar_url"`
+
+	// UserMetadata holds attributes the user can edit; AppMetadata holds
+	// attributes only admins can change. Both are free-form JSON objects.
+	UserMetadata map[string]interface{} `json:"user_metadata,omitempty" db:"user_metadata"`
+	AppMetadata  map[string]interface{} `json:"app_metadata,omitempty" db:"app_metadata"`
+
+	// InvitedBy is the user whose invitation this account registered with.
+	InvitedBy *int64 `json:"invited_by,omitempty" db:"invited_by"`
+
+	// ApprovalStatus is pending until an admin reviews the registration when
+	// REQUIRE_REGISTRATION_APPROVAL is enabled (see constants.Approval*).
+	ApprovalStatus string `json:"approval_status" db:"approval_status"`
+
+	// TenantID is the tenant whose user pool the account belongs to.
+	TenantID int64 `json:"-" db:"tenant_id"`
+
+	// AnonymizedAt is set once the user's PII has been scrubbed for an erasure request.
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty" db:"anonymized_at"`
+
+	// TokenVersion is incremented when the user's privileges change; access
+	// tokens carrying an older version ("ver" claim) are refused.
+	TokenVersion int `json:"-" db:"token_version"`
+}
+
+// LocaleRequest is the payload for saving the user's preferred language.
+type LocaleRequest struct {
+	Locale string `json:"locale" validate:"required,locale"`
+}
+
+// MetadataUpdate merges keys into user or app metadata. Keys set to null are
+// removed; other keys are replaced as a whole.
+type MetadataUpdate map[string]interface{}
+
+// AdminMetadataRequest is the payload for an admin editing a user's metadata.
+// Either object may be omitted.
+type AdminMetadataRequest struct {
+	UserMetadata MetadataUpdate `json:"user_metadata"`
+	AppMetadata  MetadataUpdate `json:"app_metadata"`
+}
+
+// UsernameRequest is the payload for choosing or changing a username.
+type UsernameRequest struct {
+	Username string `json:"username" validate:"required,username"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// UserFilter selects users by sign-up provider, creation time and active
+// status. Zero fields match every user.
+type UserFilter struct {
+	Provider    string     `json:"provider,omitempty" validate:"omitempty,oneof=email google"`
+	CreatedFrom *time.Time `json:"created_from,omitempty"` // created at or after
+	CreatedTo   *time.Time `json:"created_to,omitempty"`   // created before
+	Active      *bool      `json:"active,omitempty"`
+}
+
+// UserExportRequest is the payload for exporting the users matching a filter.
+type UserExportRequest struct {
+	Format string `json:"format" validate:"required,oneof=csv json"`
+	UserFilter
+}
+
+// UserExportRecord is one user in an export file.
+type UserExportRecord struct {
+	ID             int64     `json:"id"`
+	Email          string    `json:"email"`
+	Username       string    `json:"username,omitempty"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Provider       string    `json:"provider"`
+	Role           string    `json:"role"`
+	IsActive       bool      `json:"is_active"`
+	ApprovalStatus string    `json:"approval_status"`
+	Locale         string    `json:"locale,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -1,7 +1,5 @@
 package models
 
-
-
 // UserProfile stores non-critical, mutable user details, separating them
 // from the core authentication fields in the User model.
 type UserProfile struct {
@@ -24,11 +22,13 @@ type UserProfile struct {
 	Bio *string `json:"bio" db:"bio"`
 }
 
-// UpdateProfileRequest defines the input structure for updating a user's profile.
+// UpdateProfileRequest defines the input structure for updating a user's
+// profile. Empty fields are left unchanged; an empty avatar_url removes the
+// profile picture.
 type UpdateProfileRequest struct {
-	FirstName   string `json:"first_name" validate:"omitempty"`
-	LastName    string `json:"last_name" validate:"omitempty"`
-	DisplayName string `json:"display_name" validate:"omitempty"`
-	Bio         string `json:"bio" validate:"omitempty,max=500"`
+	FirstName   string  `json:"first_name" validate:"omitempty,max=100"`
+	LastName    string  `json:"last_name" validate:"omitempty,max=100"`
+	DisplayName string  `json:"display_name" validate:"omitempty,max=100"`
+	Bio         string  `json:"bio" validate:"omitempty,max=500"`
 	AvatarURL   *string `json:"avatar_url" validate:"omitempty,url"`
-}
\ No newline at end of file
+}
@@ -10,6 +10,9 @@ type TwoFARepository interface {
 
 // EnableEmail2FA enables email-based 2FA for a user
 	EnableEmail2FA(ctx context.Context, userID int64) error
+
+	// EnablePush2FA enables push approval 2FA for a user
+	EnablePush2FA(ctx context.Context, userID int64) error
 	
 	// Disable2FA disables 2FA for a user
 	Disable2FA(ctx context.Context, userID int64) error
@@ -20,6 +23,18 @@ type TwoFARepository interface {
 	// Get2FAMethod returns the 2FA method (e.g., "email", "sms", "totp")
 	Get2FAMethod(ctx context.Context, userID int64) (string, error)
 
+	// Save2FASecret stores the user's 2FA secret (e.g. a TOTP key), encrypted
+	// at rest; it doesn't change the method nor enable 2FA
+	Save2FASecret(ctx context.Context, userID int64, secret string) error
+
+	// Get2FASecret returns the user's decrypted 2FA secret, or "" when none
+	// is stored
+	Get2FASecret(ctx context.Context, userID int64) (string, error)
+
+	// ReencryptSecrets encrypts unencrypted secrets and re-wraps those sealed
+	// under a retired master key; it returns how many were updated
+	ReencryptSecrets(ctx context.Context) (int, error)
+
 	// VerifyOTP verifies an OTP code for 2FA
 	VerifyOTP(ctx context.Context, userID int64, email, code, otpType string) (bool, error)
 }
\ No newline at end of file
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"authentio/pkg/pagination"
+	"context"
+	"time"
+)
+
+// AuditLogRepository defines the interface for audit log persistence
+type AuditLogRepository interface {
+	// Create appends a new audit log entry
+	Create(ctx context.Context, entry *models.AuditLog) error
+
+	// ListByUser returns up to limit of a user's entries for the given events
+	// (all events when empty), newest first, starting after the after cursor
+	// when set
+	ListByUser(ctx context.Context, userID int64, events []string, after *pagination.Cursor, limit int) ([]*models.AuditLog, error)
+
+	// DailyLogins counts the successful and failed sign-ins to the tenant's
+	// accounts per UTC day since since, oldest first; days without any are
+	// omitted
+	DailyLogins(ctx context.Context, since time.Time) ([]models.DailyLoginStats, error)
+
+	// CountLoginCountry adds a successful or failed sign-in from country to
+	// the tenant's rollup of the current UTC day
+	CountLoginCountry(ctx context.Context, country string, successful bool) error
+
+	// DailyLoginsByCountry returns the tenant's sign-ins per UTC day and
+	// country since since, oldest first; days without any are omitted
+	DailyLoginsByCountry(ctx context.Context, since time.Time) ([]models.DailyCountryLogins, error)
+
+	// ListForArchive returns up to limit entries matching filter with an ID
+	// above afterID, in ID order
+	ListForArchive(ctx context.Context, filter models.AuditLogFilter, afterID int64, limit int) ([]*models.AuditLog, error)
+
+	// Purge deletes up to limit entries matching filter and returns how many
+	// it deleted
+	Purge(ctx context.Context, filter models.AuditLogFilter, limit int) (int64, error)
+}
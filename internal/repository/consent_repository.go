@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// ConsentRepository defines the interface for legal consent persistence
+type ConsentRepository interface {
+	// Record stores a new consent acceptance
+	Record(ctx context.Context, consent *models.UserConsent) error
+
+	// LatestByUser returns the most recent acceptance per document, keyed by document
+	LatestByUser(ctx context.Context, userID int64) (map[string]*models.UserConsent, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// DeviceRepository defines the interface for the registry of devices users sign in from
+type DeviceRepository interface {
+	// TouchDevice records that a user's device was seen, registering it the first time
+	TouchDevice(ctx context.Context, device *models.Device) error
+
+	// ListDevices returns a user's devices, most recently seen first
+	ListDevices(ctx context.Context, userID int64) ([]models.Device, error)
+
+	// RenameDevice sets the name of a user's device; sql.ErrNoRows if it doesn't exist
+	RenameDevice(ctx context.Context, userID, deviceID int64, name string) error
+
+	// DeleteDevice removes a user's device and signs out its sessions; sql.ErrNoRows
+	// if it doesn't exist
+	DeleteDevice(ctx context.Context, userID, deviceID int64) error
+}
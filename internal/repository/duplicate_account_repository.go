@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"authentio/pkg/pagination"
+	"context"
+)
+
+// DuplicateAccountRepository defines the interface for likely duplicate
+// accounts
+type DuplicateAccountRepository interface {
+	// Candidates finds the tenant's groups of accounts sharing a normalized
+	// email, a phone number or an identity provider subject
+	Candidates(ctx context.Context) ([]models.DuplicateCandidate, error)
+
+	// Sync replaces the tenant's groups with candidates. Groups keep their
+	// status unless an account joins them, which reopens them.
+	Sync(ctx context.Context, candidates []models.DuplicateCandidate) error
+
+	// List returns up to limit of the tenant's groups with the given status
+	// (any when empty), newest first, starting after the after cursor when
+	// set
+	List(ctx context.Context, status string, after *pagination.Cursor, limit int) ([]*models.DuplicateAccountGroup, error)
+
+	// Dismiss marks a group as not duplicates; it returns false when the
+	// group doesn't exist
+	Dismiss(ctx context.Context, id, dismissedBy int64) (bool, error)
+}
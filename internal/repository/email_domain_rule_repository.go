@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// EmailDomainRuleRepository defines the interface for registration domain rule persistence
+type EmailDomainRuleRepository interface {
+	// List returns all rules ordered by domain
+	List(ctx context.Context) ([]*models.EmailDomainRule, error)
+
+	// Create inserts a rule, replacing the rule type if the domain already has one
+	Create(ctx context.Context, rule *models.EmailDomainRule) error
+
+	// Delete removes a rule by ID, returning sql.ErrNoRows if it doesn't exist
+	Delete(ctx context.Context, id int64) error
+}
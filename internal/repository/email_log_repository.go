@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"authentio/pkg/email"
+	"authentio/pkg/pagination"
+	"context"
+)
+
+// EmailLogRepository defines the interface for the email delivery log
+type EmailLogRepository interface {
+	// Create records a queued email, one entry per recipient, linking each
+	// to the tenant's account with that address
+	Create(ctx context.Context, messageID, event, subject string, recipients []string) error
+
+	// RecordAttempt records a send attempt of every recipient of an email:
+	// sent when sendErr is nil, failed otherwise. It implements
+	// email.DeliveryTracker.
+	RecordAttempt(ctx context.Context, messageID, providerMessageID string, sendErr error) error
+
+	// MarkFailed marks an email that couldn't be queued as failed
+	MarkFailed(ctx context.Context, messageID, reason string) error
+
+	// RecordEvent applies a delivery or engagement event reported by the
+	// provider for one recipient; unknown emails are ignored
+	RecordEvent(ctx context.Context, event email.DeliveryEvent) error
+
+	// List returns up to limit of the tenant's records matching filter,
+	// newest first, starting after the after cursor when set
+	List(ctx context.Context, filter models.EmailLogFilter, after *pagination.Cursor, limit int) ([]*models.EmailLog, error)
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"authentio/pkg/email"
+	"context"
+)
+
+// EmailTemplateRepository defines the interface for saved email template versions
+type EmailTemplateRepository interface {
+	// ActiveTemplate returns the tenant's active version of a template, or
+	// nil when it uses the default. It implements email.TemplateStore.
+	ActiveTemplate(ctx context.Context, name string) (*email.StoredTemplate, error)
+
+	// ListActive returns the tenant's active template versions
+	ListActive(ctx context.Context) ([]*models.EmailTemplate, error)
+
+	// Versions returns the tenant's versions of a template, newest first
+	Versions(ctx context.Context, name string) ([]*models.EmailTemplate, error)
+
+	// Create saves the next version of a template and makes it active
+	Create(ctx context.Context, tmpl *models.EmailTemplate) error
+
+	// Activate makes a saved version active; it returns nil when the
+	// version doesn't exist
+	Activate(ctx context.Context, name string, version int) (*models.EmailTemplate, error)
+
+	// Deactivate makes a template use the default again
+	Deactivate(ctx context.Context, name string) error
+}
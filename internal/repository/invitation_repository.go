@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// InvitationRepository defines the interface for registration invitation persistence
+type InvitationRepository interface {
+	// Create stores a new invitation
+	Create(ctx context.Context, invitation *models.Invitation) error
+
+	// FindByID returns an invitation by ID, or nil if it doesn't exist
+	FindByID(ctx context.Context, id int64) (*models.Invitation, error)
+
+	// FindByTokenHash returns the invitation with the given token hash, or nil
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.Invitation, error)
+
+	// List returns invitations newest first; invitedBy limits them to one inviter when non-nil
+	List(ctx context.Context, invitedBy *int64) ([]*models.Invitation, error)
+
+	// Revoke marks a pending invitation as revoked
+	Revoke(ctx context.Context, id int64) error
+
+	// MarkAccepted records the user created from a pending invitation. It
+	// returns sql.ErrNoRows if the invitation is no longer pending.
+	MarkAccepted(ctx context.Context, id, userID int64) error
+}
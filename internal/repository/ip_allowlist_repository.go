@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// IPAllowlistRepository defines the interface for per-account IP allowlists
+type IPAllowlistRepository interface {
+	// ListEntries returns a user's unexpired entries, oldest first
+	ListEntries(ctx context.Context, userID int64) ([]models.IPAllowlistEntry, error)
+
+	// SaveEntry adds an entry, replacing the label and expiry of the user's entry for the same block
+	SaveEntry(ctx context.Context, entry *models.IPAllowlistEntry) error
+
+	// DeleteEntry removes one of a user's entries; sql.ErrNoRows if it doesn't exist
+	DeleteEntry(ctx context.Context, userID, entryID int64) error
+
+	// GetMode returns a user's allowlist mode (see constants.IPAllowlist*)
+	GetMode(ctx context.Context, userID int64) (string, error)
+
+	// SetMode saves a user's allowlist mode
+	SetMode(ctx context.Context, userID int64, mode string) error
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// IPDenylistRepository defines the interface for the global IP denylist
+type IPDenylistRepository interface {
+	// List returns the unexpired entries ordered by block
+	List(ctx context.Context) ([]models.IPDenylistEntry, error)
+
+	// Save adds an entry, replacing the reason, expiry and author of the entry for the same block
+	Save(ctx context.Context, entry *models.IPDenylistEntry) error
+
+	// Delete removes an entry by ID; sql.ErrNoRows if it doesn't exist
+	Delete(ctx context.Context, id int64) error
+}
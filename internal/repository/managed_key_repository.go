@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+	"time"
+)
+
+// ManagedKeyRepository defines the interface for keys generated by rotations
+// and the rotations themselves
+type ManagedKeyRepository interface {
+	// ListKeys returns the keys that aren't retired, oldest first
+	ListKeys(ctx context.Context) ([]*models.ManagedKey, error)
+
+	// Rotate makes key the active key of its purpose, the previous one
+	// verify-only, and records rotation, setting their IDs. It returns false,
+	// changing nothing, when a rotation of the purpose is already running.
+	Rotate(ctx context.Context, key *models.ManagedKey, rotation *models.KeyRotation) (bool, error)
+
+	// RetireKeys retires the verify-only keys of purpose that stopped
+	// signing before the given time, and returns how many were retired
+	RetireKeys(ctx context.Context, purpose string, before time.Time) (int, error)
+
+	// UpdateMaterial replaces the sealed material of a key, unless it
+	// changed since it was read
+	UpdateMaterial(ctx context.Context, id int64, old, material string) error
+
+	// UpdateRotation saves the progress, status and error of a rotation
+	UpdateRotation(ctx context.Context, rotation *models.KeyRotation) error
+
+	// ListRotations returns the latest rotations, newest first
+	ListRotations(ctx context.Context, limit int) ([]*models.KeyRotation, error)
+
+	// RunningRotations returns the rotations whose job hasn't finished
+	RunningRotations(ctx context.Context) ([]*models.KeyRotation, error)
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// OAuthRepository defines the interface for OpenID Connect provider persistence:
+// registered clients, authorization codes and users' grants
+type OAuthRepository interface {
+	// CreateClient stores a new OAuth client
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+
+	// FindClientByClientID returns the client with the given client_id, or nil
+	FindClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+
+	// ListClients returns every client, newest first
+	ListClients(ctx context.Context) ([]*models.OAuthClient, error)
+
+	// DeleteClient removes a client with its codes and refresh tokens. It
+	// returns sql.ErrNoRows if the client doesn't exist.
+	DeleteClient(ctx context.Context, id int64) error
+
+	// CreateCode stores a new authorization code
+	CreateCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+
+	// ConsumeCode marks an unused, unexpired code as used and returns it, or
+	// nil if there is no such code
+	ConsumeCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error)
+
+	// SaveGrant creates or replaces the scopes userID approved for a client
+	SaveGrant(ctx context.Context, grant *models.OAuthGrant) error
+
+	// FindGrant returns userID's grant for a client (oauth_clients.id), or nil
+	FindGrant(ctx context.Context, userID, clientID int64) (*models.OAuthGrant, error)
+
+	// ListGrants returns userID's grants with client details, most recent first
+	ListGrants(ctx context.Context, userID int64) ([]*models.OAuthGrant, error)
+
+	// RevokeGrant deletes userID's grant for a client along with the refresh
+	// tokens and unused codes issued to it. It returns sql.ErrNoRows if there
+	// is no such grant.
+	RevokeGrant(ctx context.Context, userID, clientID int64) error
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// OrganizationInvitationRepository defines the interface for organization invitation persistence
+type OrganizationInvitationRepository interface {
+	// Create stores a new organization invitation
+	Create(ctx context.Context, invitation *models.OrganizationInvitation) error
+
+	// FindByID returns an invitation by ID, or nil if it doesn't exist
+	FindByID(ctx context.Context, id int64) (*models.OrganizationInvitation, error)
+
+	// FindByTokenHash returns the invitation with the given token hash, or nil
+	FindByTokenHash(ctx context.Context, tokenHash string) (*models.OrganizationInvitation, error)
+
+	// ListByOrganization returns an organization's invitations, newest first
+	ListByOrganization(ctx context.Context, orgID int64) ([]*models.OrganizationInvitation, error)
+
+	// Revoke marks a pending invitation as revoked
+	Revoke(ctx context.Context, id int64) error
+
+	// MarkAccepted records the user who joined with a pending invitation. It
+	// returns sql.ErrNoRows if the invitation is no longer pending.
+	MarkAccepted(ctx context.Context, id, userID int64) error
+}
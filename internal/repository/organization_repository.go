@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// OrganizationRepository defines the interface for organization and membership persistence
+type OrganizationRepository interface {
+	// Create stores a new organization and adds ownerID as its owner
+	Create(ctx context.Context, org *models.Organization, ownerID int64) error
+
+	// FindByID returns an organization by ID, or nil if it doesn't exist
+	FindByID(ctx context.Context, id int64) (*models.Organization, error)
+
+	// FindBySlug returns an organization by slug, or nil if it doesn't exist
+	FindBySlug(ctx context.Context, slug string) (*models.Organization, error)
+
+	// ListByUser returns the organizations userID belongs to, with Role set, by name
+	ListByUser(ctx context.Context, userID int64) ([]*models.Organization, error)
+
+	// FindMember returns userID's membership in orgID, or nil if not a member
+	FindMember(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error)
+
+	// ListMembers returns the members of orgID in the order they joined
+	ListMembers(ctx context.Context, orgID int64) ([]*models.OrganizationMember, error)
+
+	// AddMember adds userID to orgID with role; existing members are left unchanged
+	AddMember(ctx context.Context, orgID, userID int64, role string) error
+
+	// UpdateMemberRole changes userID's role in orgID
+	UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error
+
+	// RemoveMember removes userID from orgID
+	RemoveMember(ctx context.Context, orgID, userID int64) error
+
+	// CountMembersWithRole returns how many members of orgID have role
+	CountMembersWithRole(ctx context.Context, orgID int64, role string) (int, error)
+}
@@ -2,16 +2,19 @@ package repository
 
 import (
 	"context"
+	"time"
 	"authentio/internal/models"
 )
 
 type OTPRepository interface {
-	// CreateOTP creates a new OTP code
+	// CreateOTP creates a new OTP code, expiring earlier unused codes of the
+	// same type for the email
 	CreateOTP(ctx context.Context, otp *models.OTP) error
 	
 	// VerifyOTP verifies an OTP code and marks it as used
 	VerifyOTP(ctx context.Context, email, code, otpType string) (bool, error)
 	
-	// CleanupExpiredOTPs removes expired OTP codes
-	CleanupExpiredOTPs(ctx context.Context) error
+	// PurgeOTPs deletes up to limit codes that expired before before, used
+	// or not, and returns how many it deleted
+	PurgeOTPs(ctx context.Context, before time.Time, limit int) (int64, error)
 }
\ No newline at end of file
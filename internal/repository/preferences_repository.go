@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// PreferencesRepository defines the interface for notification preference persistence
+type PreferencesRepository interface {
+	// FindByUserID returns a user's saved preferences, or nil when they never
+	// saved any
+	FindByUserID(ctx context.Context, userID int64) (*models.NotificationPreferences, error)
+
+	// Save creates or replaces a user's preferences
+	Save(ctx context.Context, prefs *models.NotificationPreferences) error
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// ProfileRepository defines the interface for user profile persistence
+type ProfileRepository interface {
+	// FindByUserID returns a user's profile; users who never saved one get an
+	// empty profile with their names and avatar
+	FindByUserID(ctx context.Context, userID int64) (*models.UserProfile, error)
+
+	// Save stores a profile, updating the names and avatar URL kept on the user
+	Save(ctx context.Context, profile *models.UserProfile) error
+}
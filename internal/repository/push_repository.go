@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// PushRepository defines the interface for push approval devices and challenges
+type PushRepository interface {
+	// SaveDevice registers a device, renaming it if the user already registered its token
+	SaveDevice(ctx context.Context, device *models.PushDevice) error
+
+	// ListDevices returns a user's devices, oldest first
+	ListDevices(ctx context.Context, userID int64) ([]models.PushDevice, error)
+
+	// DeleteDevice removes one of a user's devices; sql.ErrNoRows if it doesn't exist
+	DeleteDevice(ctx context.Context, userID, deviceID int64) error
+
+	// DeleteDeviceToken removes a device whose token the platform no longer accepts
+	DeleteDeviceToken(ctx context.Context, userID int64, token string) error
+
+	// CreateChallenge stores a new pending challenge
+	CreateChallenge(ctx context.Context, challenge *models.PushChallenge) error
+
+	// FindChallenge returns a challenge by its public identifier, or nil
+	FindChallenge(ctx context.Context, challengeID string) (*models.PushChallenge, error)
+
+	// FindChallengeByApprovalToken returns a quarantined login by the hash of its approval token, or nil
+	FindChallengeByApprovalToken(ctx context.Context, tokenHash string) (*models.PushChallenge, error)
+
+	// ListPendingChallenges returns a user's unexpired pending push challenges, newest first
+	ListPendingChallenges(ctx context.Context, userID int64) ([]models.PushChallenge, error)
+
+	// DecideChallenge approves or denies a user's unexpired pending challenge;
+	// sql.ErrNoRows if there is none
+	DecideChallenge(ctx context.Context, userID int64, challengeID, status string) error
+
+	// CompleteChallenge marks an approved challenge as exchanged for tokens,
+	// returning false if it was not approved or already completed
+	CompleteChallenge(ctx context.Context, challengeID string) (bool, error)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"context"
+)
+
+// RecoveryRepository defines the interface for recovery codes and two-factor
+// recovery requests
+type RecoveryRepository interface {
+	// ReplaceCodes swaps a user's recovery codes for the given hashes
+	ReplaceCodes(ctx context.Context, userID int64, codeHashes []string) error
+
+	// UseCode marks a user's unused code as used, returning false if there is none
+	UseCode(ctx context.Context, userID int64, codeHash string) (bool, error)
+
+	// CountUnusedCodes returns how many recovery codes a user has left
+	CountUnusedCodes(ctx context.Context, userID int64) (int, error)
+
+	// CreateRecovery stores a pending recovery, cancelling the user's earlier pending ones
+	CreateRecovery(ctx context.Context, recovery *models.TwoFactorRecovery) error
+
+	// FindRecoveryByToken returns the recovery with the given token hash, or nil
+	FindRecoveryByToken(ctx context.Context, tokenHash string) (*models.TwoFactorRecovery, error)
+
+	// CancelRecovery cancels the pending recovery with the given cancel token hash
+	// and returns it; sql.ErrNoRows if there is none
+	CancelRecovery(ctx context.Context, cancelTokenHash string) (*models.TwoFactorRecovery, error)
+
+	// CompleteRecovery marks a pending recovery as completed, returning false if
+	// it was cancelled or already completed
+	CompleteRecovery(ctx context.Context, id int64) (bool, error)
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"authentio/internal/models"
+	"authentio/pkg/email"
+	"context"
+)
+
+// TenantRepository defines the interface for tenant persistence.
+//
+// Other repositories scope their queries to the tenant stored in the context
+// (requestctx.WithTenant); contexts without one use the default tenant.
+type TenantRepository interface {
+	// FindBySlug returns the tenant with the given slug, or nil if it doesn't exist
+	FindBySlug(ctx context.Context, slug string) (*models.Tenant, error)
+
+	// Ensure creates the tenant with the given slug unless it already exists
+	Ensure(ctx context.Context, slug, name string) (*models.Tenant, error)
+
+	// List returns every tenant, including its token signing settings
+	List(ctx context.Context) ([]*models.Tenant, error)
+
+	// QuotaLimits returns a tenant's daily quota overrides by metric
+	QuotaLimits(ctx context.Context, tenantID int64) (map[string]int64, error)
+
+	// RiskPolicy returns a tenant's login risk threshold overrides, or nil if it doesn't exist
+	RiskPolicy(ctx context.Context, tenantID int64) (*models.TenantRiskPolicy, error)
+
+	// GeoPolicy returns a tenant's geographic sign-in rules, or nil if it
+	// has none
+	GeoPolicy(ctx context.Context, tenantID int64) (*models.GeoPolicy, error)
+
+	// SaveGeoPolicy replaces a tenant's geographic sign-in rules
+	SaveGeoPolicy(ctx context.Context, tenantID int64, policy *models.GeoPolicy) error
+
+	// DeleteGeoPolicy removes a tenant's geographic sign-in rules, so the
+	// deployment's apply
+	DeleteGeoPolicy(ctx context.Context, tenantID int64) error
+
+	// Branding returns a tenant's email branding, or nil if the tenant
+	// doesn't exist
+	Branding(ctx context.Context, tenantID int64) (*models.TenantBranding, error)
+
+	// SaveBranding replaces a tenant's email branding; empty fields are
+	// cleared
+	SaveBranding(ctx context.Context, tenantID int64, branding *models.TenantBranding) error
+
+	// EmailBranding returns the email branding of the tenant in ctx. It
+	// implements email.BrandingStore.
+	EmailBranding(ctx context.Context) (*email.Branding, error)
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"authentio/internal/models"
+	"authentio/pkg/pagination"
 	"context"
+	"time"
 )
 
 // TokenRepository defines the interface for token-related database operations
@@ -16,9 +18,23 @@ type TokenRepository interface {
 	// DeleteRefreshToken removes a refresh token (used during logout or token rotation)
 	DeleteRefreshToken(ctx context.Context, token string) error
 
+	// ListUserRefreshTokens returns up to limit of a user's unexpired
+	// first-party refresh tokens (one per signed-in session), newest first,
+	// starting after the after cursor when set
+	ListUserRefreshTokens(ctx context.Context, userID int64, after *pagination.Cursor, limit int) ([]*models.RefreshToken, error)
+
+	// CountUserRefreshTokens counts a user's signed-in sessions
+	CountUserRefreshTokens(ctx context.Context, userID int64) (int, error)
+
+	// ListDeviceAccessTokens returns the last access token (ID and expiry) of
+	// each session started on one of a user's devices, leaving out sessions
+	// without one
+	ListDeviceAccessTokens(ctx context.Context, userID, deviceID int64) ([]*models.RefreshToken, error)
+
 	// DeleteUserRefreshTokens removes all refresh tokens for a specific user
 	DeleteUserRefreshTokens(ctx context.Context, userID int64) error
 
-	// CleanupExpiredTokens removes all expired refresh tokens
-	CleanupExpiredTokens(ctx context.Context) error
+	// PurgeRefreshTokens deletes up to limit refresh tokens that expired, or
+	// were revoked, before before, and returns how many it deleted
+	PurgeRefreshTokens(ctx context.Context, before time.Time, limit int) (int64, error)
 }
\ No newline at end of file
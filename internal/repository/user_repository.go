@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
 	"authentio/internal/models"
+	"authentio/pkg/pagination"
 )
 
 type UserRepository interface {
@@ -11,6 +13,9 @@ type UserRepository interface {
 	
 	// FindByID finds a user by ID
 	FindByID(ctx context.Context, id int64) (*models.User, error)
+
+	// FindByUsername finds a user by username, case-insensitively
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
 	
 	// Create inserts a new user into the database
 	Create(ctx context.Context, user *models.User) error
@@ -20,4 +25,63 @@ type UserRepository interface {
 	
 	// Delete soft deletes a user
 	Delete(ctx context.Context, id int64) error
+
+	// SetUsername saves a user's (lowercase) username
+	SetUsername(ctx context.Context, id int64, username string) error
+
+	// SetPassword saves a user's new password hash and records when it changed
+	SetPassword(ctx context.Context, id int64, hash string) error
+
+	// SetAvatarURL saves the URL of a user's profile picture; nil removes it
+	SetAvatarURL(ctx context.Context, id int64, avatarURL *string) error
+
+	// SetMetadata replaces a user's user_metadata or app_metadata (see constants.Metadata*)
+	SetMetadata(ctx context.Context, id int64, kind string, metadata map[string]interface{}) error
+
+	// TokenVersion returns the version access tokens of a live user must
+	// carry (sql.ErrNoRows for unknown or deleted users)
+	TokenVersion(ctx context.Context, id int64) (int, error)
+
+	// BumpTokenVersion increments a user's token version, invalidating the
+	// access tokens issued before, and returns the new one
+	BumpTokenVersion(ctx context.Context, id int64) (int, error)
+
+	// List returns up to limit of the tenant's users matching filter, newest
+	// first, starting after the after cursor when set
+	List(ctx context.Context, filter models.UserFilter, after *pagination.Cursor, limit int) ([]*models.User, error)
+
+	// Search returns up to limit of the tenant's users whose email, name or
+	// username match query (lowercase), best matches first. Misspelled words
+	// still match similar ones.
+	Search(ctx context.Context, query string, limit int) ([]*models.User, error)
+
+	// Stats counts the tenant's live users in total, locked out, with 2FA
+	// enabled and per sign-up provider
+	Stats(ctx context.Context) (*models.UserStats, error)
+
+	// DailySignups counts the tenant's registrations per UTC day since since,
+	// oldest first; days without any are omitted
+	DailySignups(ctx context.Context, since time.Time) ([]models.DailyCount, error)
+
+	// FindByApprovalStatus returns users in the given approval state, oldest first
+	FindByApprovalStatus(ctx context.Context, status string) ([]*models.User, error)
+
+	// FindEmailsByRole returns the email addresses of active users with the given role
+	FindEmailsByRole(ctx context.Context, role string) ([]string, error)
+
+	// FindStalePasswords returns up to limit of the tenant's active, approved
+	// users with an ID above afterID whose password was last set before
+	// changedBefore and who weren't emailed event since remindedSince, by ID
+	FindStalePasswords(ctx context.Context, changedBefore time.Time, event string, remindedSince time.Time, afterID int64, limit int) ([]*models.User, error)
+
+	// FindNotEmailed returns up to limit of the tenant's active, approved users
+	// with an ID above afterID, registered between registeredAfter and
+	// registeredBefore, who were never emailed event, by ID
+	FindNotEmailed(ctx context.Context, registeredAfter, registeredBefore time.Time, event string, afterID int64, limit int) ([]*models.User, error)
+
+	// SetApprovalStatus moves a pending user to status, recording the reviewer
+	SetApprovalStatus(ctx context.Context, id int64, status string, reviewerID int64) error
+
+	// Anonymize irreversibly scrubs a user's personal data for an erasure request
+	Anonymize(ctx context.Context, id int64) error
 }
\ No newline at end of file
@@ -0,0 +1,71 @@
+// Package requestctx carries per-request client details (IP, user agent, country,
+// device fingerprint), the negotiated locale and the resolved tenant from the
+// HTTP layer down to services and repositories through context.Context, so
+// they can use them without depending on gin.
+package requestctx
+
+import "context"
+
+type clientInfoKey struct{}
+
+type localeKey struct{}
+
+type tenantKey struct{}
+
+// ClientInfo describes the client that issued the current request.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Country   string
+
+	// Latitude and Longitude locate the IP address (GeoIP) when Located is
+	// set.
+	Latitude  float64
+	Longitude float64
+	Located   bool
+
+	// DeviceFingerprint is the opaque device identifier the client sent, if any.
+	DeviceFingerprint string
+
+	// IsProxy and IsHosting are set when anonymizer detection found the IP
+	// to be a proxy, VPN or Tor exit node, or to belong to a hosting provider.
+	IsProxy   bool
+	IsHosting bool
+
+	// ClientCertificate names the holder of the TLS client certificate the
+	// request was made with (mTLS), when one was verified.
+	ClientCertificate string
+}
+
+// WithClientInfo returns a copy of ctx carrying the given client information.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFrom returns the client information stored in ctx, or the zero value.
+func ClientInfoFrom(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// WithLocale returns a copy of ctx carrying the locale for user-facing messages.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFrom returns the locale stored in ctx, or "" when none was set.
+func LocaleFrom(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// WithTenant returns a copy of ctx scoped to the tenant with the given ID.
+func WithTenant(ctx context.Context, tenantID int64) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom returns the tenant ID stored in ctx, or 0 when none was set.
+func TenantFrom(ctx context.Context) int64 {
+	tenantID, _ := ctx.Value(tenantKey{}).(int64)
+	return tenantID
+}
@@ -3,11 +3,22 @@ package router
 import (
 	"net/http"
 	"os"
+	"time"
 
+	"authentio/internal/constants"
 	"authentio/internal/handler"
 	"authentio/internal/middleware"
+	"authentio/pkg/anonymizer"
+	"authentio/pkg/captcha"
+	"authentio/pkg/health"
+	"authentio/pkg/idempotency"
 	"authentio/pkg/jwt"
 	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+	"authentio/pkg/mtls"
+	"authentio/pkg/openapi"
+	"authentio/pkg/quota"
+	"authentio/pkg/revocation"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
@@ -40,12 +51,30 @@ import (
 //
 // Parameters:
 //   - h: Handler instance containing all route handlers
-//   - redis: Redis client for rate limiting and token blacklisting
+//   - redis: Redis client for rate limiting
+//   - revocationStrategy: REVOCATION_STRATEGY; stateless leaves out the revoked token check
+//   - revokedTokens: Revoked access tokens (nil revokes nothing)
+//   - corsOrigins: Origins allowed by the CORS policies of API, cookie session and public routes
 //   - jwtManager: JWT manager for token validation and generation
+//   - tenants: Tenant resolver scoping each request to one tenant's data
+//   - quotas: Per-tenant daily quota limiter (nil disables quotas)
+//   - ipAllowlist: Per-account IP allowlists checked on authenticated routes
+//   - tokenVersions: Token versions refusing access tokens issued before privilege changes
+//   - geoRules: Tenants' geographic rules refusing or flagging countries on authenticated routes
+//   - ipDenylist: Global IP denylist checked on every route
+//   - anonymizers: Proxy/VPN/Tor and hosting IP detection (nil disables it)
+//   - anonymousIPRules: Routes refusing requests flagged by anonymizer detection
+//   - captchaVerifier: CAPTCHA provider gating abuse-prone endpoints (nil disables it)
+//   - clientCerts: Routes requiring a TLS client certificate (mTLS) and the identities accepted
+//   - idempotencyKeys: Stored responses replayed to retried requests (nil disables Idempotency-Key)
+//   - requestValidator: OpenAPI document requests are validated against (nil disables validation)
+//   - metricsToken: Bearer token scrapers send to GET /metrics (empty disables the endpoint)
+//   - readiness: Dependency checks served on GET /readyz
+//   - requestTimeout: Time each request may take (0 = no deadline)
 //
 // Returns:
 //   - *gin.Engine: Fully configured Gin router ready to serve HTTP requests
-func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manager) *gin.Engine {
+func SetupRouter(h *handler.Handler, redis *redis.Client, revocationStrategy string, revokedTokens *revocation.List, corsOrigins middleware.CORSOrigins, jwtManager *jwt.Manager, tenants *middleware.TenantResolver, quotas *quota.Limiter, ipAllowlist middleware.IPAllowlist, tokenVersions middleware.TokenVersions, geoRules middleware.GeoRules, ipDenylist middleware.IPDenylist, anonymizers *anonymizer.Detector, anonymousIPRules middleware.AnonymousIPRules, captchaVerifier captcha.CaptchaVerifier, clientCerts mtls.Policy, idempotencyKeys *idempotency.Store, requestValidator *openapi.Validator, metricsToken string, readiness *health.Checker, requestTimeout time.Duration) *gin.Engine {
 	// Initialize the Gin engine with default middleware
 	r := gin.New()
 
@@ -56,15 +85,60 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 	// Recovery middleware recovers from any panics and returns a 500 error
 	r.Use(gin.Recovery())
 
+	// Request ID middleware tags each request with X-Request-ID (kept from
+	// the client or generated), echoed in responses and request logs
+	r.Use(middleware.RequestID())
+
 	// Custom structured request logger for consistent request logging
 	r.Use(middleware.RequestLogger())
 
-	// CORS middleware handles Cross-Origin Resource Sharing headers
-	r.Use(middleware.CORSMiddleware())
+	// Request deadline middleware cancels the calls of requests taking longer
+	// than REQUEST_TIMEOUT, answered with 504
+	r.Use(middleware.RequestDeadline(requestTimeout))
+
+	// IP denylist middleware refuses requests from addresses blocked by admins
+	// before they reach rate limiting, tenant resolution or any handler
+	r.Use(middleware.IPDenylistMiddleware(ipDenylist))
+
+	// Client certificate middleware requires a verified TLS client
+	// certificate on the routes listed in MTLS_REQUIRED_ROUTES (e.g. admin)
+	r.Use(middleware.ClientCertificate(clientCerts))
+
+	// CORS middleware handles Cross-Origin Resource Sharing headers with a
+	// policy per group of routes: credentialed API routes only allow the
+	// configured origins, while public discovery routes (OIDC metadata and
+	// keys, token and userinfo endpoints, health) allow any origin without
+	// credentials
+	public := middleware.CORSPolicy{Origins: corsOrigins.Public, Methods: []string{"GET", "HEAD", "POST", "OPTIONS"}}
+	r.Use(middleware.CORS([]middleware.CORSRoute{
+		{Prefix: "/api/v1", Policy: middleware.CORSPolicy{Origins: corsOrigins.API, Credentials: true}},
+		{Prefix: "/api/v1/auth/session", Policy: middleware.CORSPolicy{Origins: corsOrigins.Session, Credentials: true}},
+		{Prefix: "/api/v1/oauth/token", Policy: public},
+		{Prefix: "/api/v1/oauth/userinfo", Policy: public},
+		{Prefix: "/.well-known", Policy: public},
+		{Prefix: "/health", Policy: public},
+	}))
 
 	// GeoIP middleware extracts geographical information from client IP addresses
+	// and flags proxies, VPNs, Tor and hosting providers (ANONYMIZER_PROVIDER)
 	// Used for security monitoring and regional access control
-	r.Use(middleware.GeoIPMiddleware())
+	r.Use(middleware.GeoIPMiddleware(anonymizers))
+
+	// Anonymous IP policy refuses flagged requests on the routes configured
+	// with ANONYMIZER_BLOCK_PROXY_ROUTES / ANONYMIZER_BLOCK_HOSTING_ROUTES
+	r.Use(middleware.AnonymousIPPolicy(anonymousIPRules))
+
+	// Request context middleware makes client IP, user agent and country
+	// available to the service layer (e.g. for audit logging)
+	r.Use(middleware.RequestContext())
+
+	// Locale middleware negotiates the language for validation and error
+	// messages from the Accept-Language header (en, fr, es; English fallback)
+	r.Use(middleware.Locale())
+
+	// Tenant middleware resolves the tenant (TENANCY_MODE: header, subdomain
+	// or path) so repositories only see that tenant's user pool
+	r.Use(tenants.Middleware())
 
 	// Environment-specific rate limiting
 	// In production: Use Redis-based distributed rate limiting for scalability
@@ -76,8 +150,16 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 	}
 
 	// Token blacklist middleware checks if JWT tokens have been invalidated
-	// Prevents use of logged-out or revoked tokens
-	r.Use(middleware.BlacklistMiddleware(redis))
+	// Prevents use of logged-out or revoked tokens; an in-process filter
+	// answers most checks without a Redis round trip. Left out with
+	// REVOCATION_STRATEGY=stateless, where revoked sessions end at refresh
+	if revocationStrategy == constants.RevocationStateful {
+		r.Use(middleware.BlacklistMiddleware(revokedTokens))
+	}
+
+	// OpenAPI validation refuses requests that don't match the documented
+	// parameters and bodies (OPENAPI_VALIDATION_ENABLED), before any binding
+	r.Use(middleware.ValidateRequest(requestValidator))
 
 	// =========================================================================
 	// Public Routes - No Authentication Required
@@ -89,19 +171,45 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// Readiness endpoint for orchestrators: 503 while the database (or a
+	// required Redis) is unreachable
+	r.GET("/readyz", gin.WrapH(readiness))
+
+	// Security metrics in the Prometheus format, for scrapers holding
+	// METRICS_TOKEN
+	if metricsToken != "" {
+		r.GET("/metrics", middleware.MetricsToken(metricsToken), gin.WrapH(metrics.Handler()))
+	}
+
 	// Swagger documentation endpoint
 	// Serves auto-generated API documentation at /swagger/index.html
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// OpenID Connect discovery document and signing keys (provider mode),
+	// served relative to the issuer (OIDC_ISSUER)
+	r.GET("/.well-known/openid-configuration", h.OIDCDiscovery)
+	r.GET("/.well-known/jwks.json", h.JWKS)
+
 	// =========================================================================
 	// API v1 Routes - Main Application Endpoints
 	// =========================================================================
 	api := r.Group("/api/v1")
+
+	// Count every API request against the tenant's daily request quota
+	api.Use(middleware.TenantQuota(quotas))
 	{
 		// =====================================================================
 		// Authentication Routes - Public access
 		// =====================================================================
 		auth := api.Group("/auth")
+
+		// Endpoints bots abuse (signups, credential stuffing, email floods)
+		// require a solved CAPTCHA when CAPTCHA_PROVIDER is set
+		captchaGate := middleware.CaptchaRequired(captchaVerifier)
+
+		// Retries of requests sending emails or creating accounts replay the
+		// first response when they carry an Idempotency-Key header
+		idempotent := middleware.Idempotent(idempotencyKeys)
 		{
 			// Google OAuth2 authentication endpoints
 			// Frontend sends ID token directly (mobile/app flow)
@@ -114,34 +222,117 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 			// OAuth callback endpoint - Google redirects here with authorization code
 			auth.GET("/google/callback", h.GoogleCallback)
 
+			// Sign in with a token from the external identity provider
+			// (FEDERATED_ISSUER), verified against its JWKS
+			auth.POST("/federated/login", h.FederatedLogin)
+
 			// Basic email/password authentication
 			// User registration with email verification
-			auth.POST("/register", h.Register)
+			auth.POST("/register", idempotent, captchaGate, h.Register)
 
-			// User login with credentials, returns JWT tokens
-			auth.POST("/login", h.Login)
+			// User login with credentials (email or username), returns JWT tokens
+			auth.POST("/login", captchaGate, h.Login)
+
+			// Check whether a username can still be claimed
+			auth.GET("/username-available", h.UsernameAvailable)
 
 			// Refresh access token using valid refresh token
 			auth.POST("/refresh", h.Refresh)
 
+			// Cookie sessions of single-page apps: the refresh token stays in
+			// an HttpOnly cookie, renewed by fetch or from a hidden iframe
+			session := auth.Group("/session", middleware.SessionOrigins(corsOrigins.Session))
+			{
+				session.POST("", h.StartSession)
+				session.DELETE("", h.EndSession)
+				session.POST("/renew", h.RenewSession)
+				session.GET("/renew", h.RenewSessionFrame)
+			}
+
 			// Password reset flow
 			// Step 1: Request password reset (sends email with reset code)
-			auth.POST("/forgot-password", h.ForgotPassword)
+			auth.POST("/forgot-password", idempotent, captchaGate, h.ForgotPassword)
 
 			// Step 2: Verify reset code and set new password
-			auth.POST("/reset-password", h.ResetPassword)
+			auth.POST("/reset-password", idempotent, h.ResetPassword)
+
+			// Poll a push approval challenge; returns tokens once approved
+			auth.POST("/2fa/push", h.CompletePushLogin)
+
+			// Complete a risky login challenged with a code sent by email
+			auth.POST("/2fa/email", h.CompleteEmailLogin)
+			auth.POST("/2fa/email/resend", idempotent, h.ResendLoginCode)
+
+			// QR code pairing: a desktop shows a code the signed-in app approves, then long-polls for its tokens
+			auth.POST("/pairing", h.StartPairing)
+			auth.POST("/pairing/poll", h.PollPairing)
+
+			// Quarantined logins: the client polls until the user approves the
+			// login with the link emailed to them
+			auth.POST("/login/quarantine", h.CompleteQuarantinedLogin)
+			auth.POST("/login/approve", h.ApproveQuarantinedLogin)
+
+			// Recovery for users who can't approve a push login: a recovery code
+			// completes it at once; email recovery turns 2FA off after a delay
+			// during which the owner is alerted and can cancel
+			auth.POST("/2fa/recover/code", h.LoginWithRecoveryCode)
+			auth.POST("/2fa/recover/email", captchaGate, h.StartEmailRecovery)
+			auth.POST("/2fa/recover/email/verify", h.VerifyEmailRecovery)
+			auth.POST("/2fa/recover/complete", h.CompleteRecovery)
+			auth.POST("/2fa/recover/cancel", h.CancelRecovery)
+		}
+
+		// =====================================================================
+		// OpenID Connect Provider - Authorization code flow for registered clients
+		// =====================================================================
+		oauth := api.Group("/oauth")
+		{
+			// Browser entry point; redirects to the consent page
+			oauth.GET("/authorize", h.Authorize)
+
+			// Consent page describes the request, then submits the signed-in user's decision
+			oauth.GET("/authorize/consent", middleware.AuthRequired(jwtManager, ipAllowlist, tokenVersions, geoRules), h.AuthorizationConsent)
+			oauth.POST("/authorize", middleware.AuthRequired(jwtManager, ipAllowlist, tokenVersions, geoRules), h.AuthorizeDecision)
+
+			// Clients redeem codes and refresh tokens, then fetch user claims
+			oauth.POST("/token", h.Token)
+			oauth.GET("/userinfo", h.UserInfo)
+		}
 
-			// Public 2FA verification endpoint
-			// Used during login flow after credentials are verified
-			auth.POST("/2fa/verify", h.Verify2FA)
+		// =====================================================================
+		// GraphQL - Optional; resolvers authenticate with the JWT themselves
+		// =====================================================================
+		if h.GraphQLHandler != nil {
+			api.POST("/graphql", h.GraphQL)
 		}
 
+		// =====================================================================
+		// Dev Email Inbox - Only with EMAIL_PROVIDER=memory, refused in production
+		// =====================================================================
+		if h.DevInboxHandler != nil {
+			dev := api.Group("/dev")
+			{
+				dev.GET("/emails", h.ListDevEmails)
+				dev.DELETE("/emails", h.ClearDevEmails)
+			}
+		}
+
+		// =====================================================================
+		// Export Downloads - Public; the signed link grants access
+		// =====================================================================
+		api.GET("/exports/:id/download", h.DownloadUserExport)
+
+		// =====================================================================
+		// Provider Webhooks - Public; requests are signed by the provider
+		// =====================================================================
+		api.POST("/webhooks/email/sendgrid", h.SendGridEvents)
+
 		// =====================================================================
 		// Two-Factor Authentication Management - Protected routes
 		// Requires valid JWT token
 		// =====================================================================
 		twoFA := api.Group("/2fa")
-		twoFA.Use(middleware.AuthRequired(jwtManager)) // JWT authentication required
+		twoFA.Use(middleware.AuthRequired(jwtManager, ipAllowlist, tokenVersions, geoRules)) // JWT authentication required
 		{
 			// Enable email-based 2FA for the authenticated user
 			twoFA.POST("/enableOtp", h.EnableEmail2FA)
@@ -151,7 +342,19 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 
 			// Send a new 2FA OTP code to the user's email
 			// Used when user needs a new code or previous code expired
-			twoFA.POST("/sendOtp", h.SendOTP)
+			twoFA.POST("/sendOtp", idempotent, h.SendOTP)
+
+			// Push approval: devices of the mobile app, and the logins it approves or denies
+			twoFA.POST("/enablePush", h.EnablePush2FA)
+			twoFA.GET("/devices", h.ListPushDevices)
+			twoFA.POST("/devices", h.RegisterPushDevice)
+			twoFA.DELETE("/devices/:id", h.RemovePushDevice)
+			twoFA.GET("/push/challenges", h.ListPushChallenges)
+			twoFA.POST("/push/challenges/:challengeId", h.DecidePushChallenge)
+
+			// Single-use recovery codes for logins without a device at hand
+			twoFA.GET("/recovery-codes", h.GetRecoveryCodes)
+			twoFA.POST("/recovery-codes", h.GenerateRecoveryCodes)
 		}
 
 		// =====================================================================
@@ -159,15 +362,225 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 		// Requires valid JWT token
 		// =====================================================================
 		user := api.Group("/user")
-		user.Use(middleware.AuthRequired(jwtManager)) // JWT authentication required
+		user.Use(middleware.AuthRequired(jwtManager, ipAllowlist, tokenVersions, geoRules)) // JWT authentication required
 		{
 			// Retrieve the authenticated user's profile information
-			// Returns user details without sensitive data like password
-			user.GET("/getProfile", h.GetProfile)
+			// Returns user details without sensitive data like password;
+			// ETag / If-None-Match answer 304 to clients polling an unchanged profile
+			user.GET("/getProfile", middleware.ConditionalGET(), h.GetProfile)
 
 			// Update the authenticated user's profile information
 			// Supports partial updates of firstName, lastName, and email
 			user.PUT("/updateProfile", h.UpdateProfile)
+
+			// Profile details: names, display name, bio and avatar URL
+			user.GET("/profile", middleware.ConditionalGET(), h.GetProfileDetails)
+			user.PUT("/profile", h.SaveProfile)
+
+			// Consolidated security posture: 2FA, sessions, last login, password age, providers
+			user.GET("/security", h.GetSecurityOverview)
+
+			// Signed-in sessions and sign-in attempts, paginated with ?limit=&cursor=
+			user.GET("/sessions", h.ListSessions)
+			user.GET("/login-history", h.GetLoginHistory)
+
+			// Devices the user signed in from (X-Device-Fingerprint); revoking one signs out its sessions
+			user.GET("/devices", h.ListDevices)
+			user.PATCH("/devices/:id", h.RenameDevice)
+			user.DELETE("/devices/:id", h.RevokeDevice)
+
+			// Addresses the account may be used from; "verify" mode steps up logins from elsewhere
+			user.GET("/ip-allowlist", h.GetIPAllowlist)
+			user.POST("/ip-allowlist", h.AddIPAllowlistEntry)
+			user.PUT("/ip-allowlist/mode", h.SetIPAllowlistMode)
+			user.DELETE("/ip-allowlist/:id", h.RemoveIPAllowlistEntry)
+
+			// Which optional emails (security alerts, marketing, login notifications) the user receives
+			user.GET("/preferences", h.GetPreferences)
+			user.PUT("/preferences", h.UpdatePreferences)
+
+			// Choose or change the username used to log in
+			user.PUT("/username", h.SetUsername)
+
+			// Confirm the password to get a short-lived sudo token for admin operations
+			user.POST("/reauthenticate", h.Reauthenticate)
+
+			// Merge keys into the user's own user_metadata
+			user.PATCH("/metadata", h.UpdateUserMetadata)
+
+			// Upload (multipart "avatar" field) or remove the profile picture
+			user.POST("/avatar", h.UploadAvatar)
+			user.DELETE("/avatar", h.RemoveAvatar)
+
+			// Permanently anonymize the authenticated user's account (right to be forgotten)
+			user.DELETE("/account", h.DeleteAccount)
+
+			// Terms of Service / Privacy Policy acceptance status and re-acceptance
+			user.GET("/consent", h.GetConsent)
+			user.POST("/consent", h.AcceptConsent)
+
+			// Applications authorized through the OpenID Connect provider
+			user.GET("/consents", h.ListOAuthGrants)
+			user.DELETE("/consents/:clientId", h.RevokeOAuthGrant)
+
+			// Approve or deny the QR pairing code of a desktop client scanned by the app
+			user.GET("/pairing/:code", h.GetPairing)
+			user.POST("/pairing/:code", h.DecidePairing)
+
+			// Save the preferred language for validation, error and confirmation messages
+			user.PUT("/locale", h.UpdateLocale)
+		}
+
+		// =====================================================================
+		// Registration Invitations - Protected routes
+		// Requires valid JWT token
+		// =====================================================================
+		invitations := api.Group("/invitations")
+		invitations.Use(middleware.AuthRequired(jwtManager, ipAllowlist, tokenVersions, geoRules)) // JWT authentication required
+		{
+			// Email an invite link (admins, or any user when USERS_CAN_INVITE is set)
+			invitations.POST("", h.CreateInvitation)
+
+			// List invitations sent by the caller (all invitations for admins)
+			invitations.GET("", h.ListInvitations)
+
+			// Revoke a pending invitation
+			invitations.DELETE("/:id", h.RevokeInvitation)
+		}
+
+		// =====================================================================
+		// Organizations - Protected routes
+		// Requires valid JWT token
+		// =====================================================================
+		orgs := api.Group("/orgs")
+		orgs.Use(middleware.AuthRequired(jwtManager, ipAllowlist, tokenVersions, geoRules)) // JWT authentication required
+		{
+			// Create an organization (the caller becomes its owner) and list the caller's
+			orgs.POST("", h.CreateOrganization)
+			orgs.GET("", h.ListOrganizations)
+
+			// Organization details and members (members only)
+			orgs.GET("/:id", h.GetOrganization)
+			orgs.GET("/:id/members", h.ListOrganizationMembers)
+
+			// Leave an organization
+			orgs.DELETE("/:id/members/me", h.LeaveOrganization)
+
+			// Promote, demote or remove members (owners and admins)
+			orgs.PUT("/:id/members/:userId", h.UpdateOrganizationMemberRole)
+			orgs.DELETE("/:id/members/:userId", h.RemoveOrganizationMember)
+
+			// Issue tokens scoped to an organization (org_id claim)
+			orgs.POST("/:id/switch", h.SwitchOrganization)
+
+			// Invite members by email (owners and admins), and join with an invite token
+			orgs.POST("/:id/invitations", h.CreateOrganizationInvitation)
+			orgs.GET("/:id/invitations", h.ListOrganizationInvitations)
+			orgs.DELETE("/:id/invitations/:invitationId", h.RevokeOrganizationInvitation)
+			orgs.POST("/invitations/accept", h.AcceptOrganizationInvitation)
+		}
+
+		// =====================================================================
+		// Administration - Protected routes
+		// Requires valid JWT token with the admin role; changes also need a
+		// sudo token from POST /user/reauthenticate
+		// =====================================================================
+		admin := api.Group("/admin")
+		admin.Use(middleware.AuthRequired(jwtManager, ipAllowlist, tokenVersions, geoRules), middleware.RequireRole(constants.RoleAdmin), middleware.RequireSudo())
+		{
+			// Browse the tenant's users and a user's audit log, paginated with ?limit=&cursor=
+			admin.GET("/users", h.ListUsers)
+			admin.GET("/users/:id/audit-logs", h.GetUserAuditLog)
+
+			// Ranked, typo-tolerant search by email, name and username (?q=)
+			admin.GET("/users/search", h.SearchUsers)
+
+			// Daily sign-ups and sign-ins, 2FA adoption and provider breakdown (?days=)
+			admin.GET("/stats", h.GetStats)
+			admin.GET("/stats/countries", h.GetCountryStats)
+
+			// Export users matching a filter as CSV or JSON, generated in the background
+			admin.POST("/users/exports", h.StartUserExport)
+			admin.GET("/users/exports/:id", h.GetUserExport)
+
+			// Likely duplicate accounts found by the periodic scan (?status=open|dismissed)
+			admin.GET("/duplicate-accounts", h.ListDuplicateAccounts)
+			admin.POST("/duplicate-accounts/scan", h.ScanDuplicateAccounts)
+			admin.POST("/duplicate-accounts/:id/dismiss", h.DismissDuplicateAccounts)
+
+			// Signing and encryption keys: status, and rotation (jwt, oidc or secrets)
+			admin.GET("/keys", h.KeyRotationStatus)
+			admin.POST("/keys/:purpose/rotate", h.RotateKey)
+
+			// Server elected to run the scheduled jobs
+			admin.GET("/scheduler", h.SchedulerStatus)
+
+			// Export the audit log entries of a date range to the archive bucket
+			admin.POST("/audit-archives", h.ArchiveAuditLogs)
+
+			// Scrub a user's PII for an erasure request
+			admin.POST("/users/:id/anonymize", h.AnonymizeUser)
+
+			// Edit a user's user_metadata / app_metadata
+			admin.PATCH("/users/:id/metadata", h.EditUserMetadata)
+
+			// Manage a user's IP allowlist (no lockout check; admins may be elsewhere)
+			admin.GET("/users/:id/ip-allowlist", h.AdminGetIPAllowlist)
+			admin.POST("/users/:id/ip-allowlist", h.AdminAddIPAllowlistEntry)
+			admin.PUT("/users/:id/ip-allowlist/mode", h.AdminSetIPAllowlistMode)
+			admin.DELETE("/users/:id/ip-allowlist/:entryId", h.AdminRemoveIPAllowlistEntry)
+
+			// Review registrations awaiting approval
+			admin.GET("/users/pending", h.ListPendingUsers)
+			admin.POST("/users/:id/approve", h.ApproveUser)
+			admin.POST("/users/:id/reject", h.RejectUser)
+
+			// Registration email domain allowlist / blocklist
+			admin.GET("/email-domains", h.ListEmailDomainRules)
+			admin.POST("/email-domains", h.AddEmailDomainRule)
+			admin.DELETE("/email-domains/:id", h.DeleteEmailDomainRule)
+
+			// Email templates: saved versions replace the embedded defaults;
+			// activate rolls back to a version, delete restores the default
+			admin.GET("/email-templates", h.ListEmailTemplates)
+			admin.GET("/email-templates/:name", h.GetEmailTemplate)
+			admin.PUT("/email-templates/:name", h.SaveEmailTemplate)
+			admin.DELETE("/email-templates/:name", h.ResetEmailTemplate)
+			admin.POST("/email-templates/:name/versions/:version/activate", h.ActivateEmailTemplate)
+			admin.POST("/email-templates/:name/preview", h.PreviewEmailTemplate)
+
+			// Sender, logo, colors and footer of the tenant's emails
+			admin.GET("/email-branding", h.GetEmailBranding)
+			admin.PUT("/email-branding", h.UpdateEmailBranding)
+
+			// Geographic sign-in rules: blocked, allowed and suspicious
+			// countries, impossible travel
+			admin.GET("/geo-policy", h.GetGeoPolicy)
+			admin.PUT("/geo-policy", h.UpdateGeoPolicy)
+			admin.DELETE("/geo-policy", h.ResetGeoPolicy)
+
+			// Email delivery log: status of every email sent, filterable by user,
+			// message, recipient, triggering event and status
+			admin.GET("/emails", h.ListEmailLog)
+
+			// Inspect, retry or discard emails that exhausted their delivery attempts
+			admin.GET("/emails/failed", h.ListFailedEmails)
+			admin.POST("/emails/failed/:id/retry", h.RetryFailedEmail)
+			admin.DELETE("/emails/failed/:id", h.DeleteFailedEmail)
+			admin.POST("/email/test", h.SendTestEmail)
+
+			// Tenant quota usage (requests, registrations, OTP emails per day)
+			admin.GET("/quotas", h.GetQuotaUsage)
+
+			// Global IP denylist (default tenant only): refuse abusive sources on every route
+			admin.GET("/ip-denylist", h.ListIPDenylist)
+			admin.POST("/ip-denylist", h.AddIPDenylistEntry)
+			admin.DELETE("/ip-denylist/:id", h.RemoveIPDenylistEntry)
+
+			// Applications registered with the OpenID Connect provider
+			admin.GET("/oauth/clients", h.ListOAuthClients)
+			admin.POST("/oauth/clients", h.CreateOAuthClient)
+			admin.DELETE("/oauth/clients/:id", h.DeleteOAuthClient)
 		}
 	}
 
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/pagination"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Sessions and Login History
+// ============================================================================
+
+// ListSessions returns a page of the user's signed-in sessions (unexpired
+// first-party refresh tokens), newest first.
+func (s *AuthService) ListSessions(ctx context.Context, userID int64, page pagination.Request) (pagination.Page[response.Session], error) {
+	tokens, err := s.tokenRepo.ListUserRefreshTokens(ctx, userID, page.After, page.Fetch())
+	if err != nil {
+		return pagination.Page[response.Session]{}, err
+	}
+
+	return pagination.NewPage(tokens, page, refreshTokenCursor, func(token *models.RefreshToken) response.Session {
+		return response.Session{
+			ID:             token.ID,
+			OrganizationID: token.OrganizationID,
+			CreatedAt:      token.CreatedAt,
+			ExpiresAt:      token.ExpiredAt,
+			DeviceID:       token.DeviceID,
+		}
+	}), nil
+}
+
+// LoginHistory returns a page of the user's successful and failed sign-ins,
+// newest first.
+func (s *AuthService) LoginHistory(ctx context.Context, userID int64, page pagination.Request) (pagination.Page[response.LoginEvent], error) {
+	entries, err := s.auditRepo.ListByUser(ctx, userID, []string{
+		string(constants.AuditLoginSuccess),
+		string(constants.AuditLoginFailed),
+	}, page.After, page.Fetch())
+	if err != nil {
+		return pagination.Page[response.LoginEvent]{}, err
+	}
+
+	return pagination.NewPage(entries, page, auditLogCursor, newLoginEvent), nil
+}
+
+// newLoginEvent converts a login_success or login_failed audit entry.
+func newLoginEvent(entry *models.AuditLog) response.LoginEvent {
+	event := response.LoginEvent{
+		Success:   entry.Event == string(constants.AuditLoginSuccess),
+		IPAddress: entry.IPAddress,
+		UserAgent: entry.UserAgent,
+		DeviceID:  entry.DeviceID,
+		CreatedAt: entry.CreatedAt,
+	}
+	event.Method, _ = entry.Metadata["method"].(string)
+	event.Reason, _ = entry.Metadata["reason"].(string)
+	return event
+}
+
+// ============================================================================
+// User and Audit Log Listings (admin)
+// ============================================================================
+
+// ListUsers returns a page of the tenant's users, newest first.
+func (s *AuthService) ListUsers(ctx context.Context, page pagination.Request) (pagination.Page[response.UserResponse], error) {
+	users, err := s.userRepo.List(ctx, models.UserFilter{}, page.After, page.Fetch())
+	if err != nil {
+		return pagination.Page[response.UserResponse]{}, err
+	}
+
+	return pagination.NewPage(users, page, func(user *models.User) pagination.Cursor {
+		return pagination.Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
+	}, newUserResponse), nil
+}
+
+// SearchUsers returns up to limit of the tenant's users whose email, name or
+// username match query, best matches first. Typos are tolerated.
+func (s *AuthService) SearchUsers(ctx context.Context, query string, limit int) ([]response.UserResponse, error) {
+	results := []response.UserResponse{}
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return results, nil
+	}
+
+	users, err := s.userRepo.Search(ctx, query, pagination.Request{Limit: limit}.Size())
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		results = append(results, newUserResponse(user))
+	}
+	return results, nil
+}
+
+// UserAuditLog returns a page of the audit log entries about one of the
+// tenant's users, newest first.
+func (s *AuthService) UserAuditLog(ctx context.Context, userID int64, page pagination.Request) (pagination.Page[*models.AuditLog], error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return pagination.Page[*models.AuditLog]{}, err
+	}
+	if user == nil {
+		return pagination.Page[*models.AuditLog]{}, ErrUserNotFound
+	}
+
+	entries, err := s.auditRepo.ListByUser(ctx, userID, nil, page.After, page.Fetch())
+	if err != nil {
+		return pagination.Page[*models.AuditLog]{}, err
+	}
+
+	return pagination.NewPage(entries, page, auditLogCursor, func(entry *models.AuditLog) *models.AuditLog {
+		return entry
+	}), nil
+}
+
+// refreshTokenCursor is the position of a session in session listings.
+func refreshTokenCursor(token *models.RefreshToken) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: token.CreatedAt, ID: token.ID}
+}
+
+// auditLogCursor is the position of an entry in audit log listings.
+func auditLogCursor(entry *models.AuditLog) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: entry.CreatedAt, ID: entry.ID}
+}
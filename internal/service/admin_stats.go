@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Admin Statistics
+// ============================================================================
+
+// statsDateLayout is the format of the UTC days statistics are grouped by
+const statsDateLayout = "2006-01-02"
+
+// AdminStats aggregates the tenant's users and the sign-ins of the last days
+// UTC days, today included. Days without activity are reported as zeros.
+func (s *AuthService) AdminStats(ctx context.Context, days int) (*response.AdminStats, error) {
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -days)
+
+	users, err := s.userRepo.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	signups, err := s.userRepo.DailySignups(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	logins, err := s.auditRepo.DailyLogins(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &response.AdminStats{
+		From: from,
+		To:   to,
+		Days: days,
+		Users: response.UserStats{
+			Total:            users.Total,
+			Locked:           users.Locked,
+			TwoFactorEnabled: users.TwoFactorEnabled,
+			Providers:        users.Providers,
+		},
+		Daily: make([]response.DailyStat, days),
+	}
+	if users.Total > 0 {
+		stats.Users.TwoFactorAdoption = float64(users.TwoFactorEnabled) / float64(users.Total)
+	}
+
+	index := make(map[string]*response.DailyStat, days)
+	for i := range stats.Daily {
+		day := &stats.Daily[i]
+		day.Date = from.AddDate(0, 0, i).Format(statsDateLayout)
+		index[day.Date] = day
+	}
+	for _, signup := range signups {
+		if day, ok := index[signup.Date]; ok {
+			day.Signups = signup.Count
+		}
+	}
+	for _, login := range logins {
+		day, ok := index[login.Date]
+		if !ok {
+			continue
+		}
+		day.SuccessfulLogins = login.Successful
+		day.FailedLogins = login.Failed
+		day.ActiveUsers = login.ActiveUsers
+		stats.Logins.Successful += login.Successful
+		stats.Logins.Failed += login.Failed
+	}
+	if attempts := stats.Logins.Successful + stats.Logins.Failed; attempts > 0 {
+		stats.Logins.FailureRate = float64(stats.Logins.Failed) / float64(attempts)
+	}
+
+	return stats, nil
+}
+
+// countLoginCountry adds sign-in events to the daily rollup of their
+// client's country. Failed sign-ins with an unknown email are counted too:
+// they make up most credential stuffing attempts.
+func (s *AuthService) countLoginCountry(ctx context.Context, event constants.AuditEvent, country string) {
+	if event != constants.AuditLoginSuccess && event != constants.AuditLoginFailed {
+		return
+	}
+	if country == "" {
+		country = "UNKNOWN"
+	}
+	if err := s.auditRepo.CountLoginCountry(ctx, country, event == constants.AuditLoginSuccess); err != nil {
+		logger.Error("failed to count sign-in country", "error", err, "country", country)
+	}
+}
+
+// LoginCountryStats returns the tenant's sign-ins per country of the client
+// over the last days UTC days, today included, most attempts first. Each
+// country has an entry per day, zero when there was no activity, so spikes
+// stand out.
+func (s *AuthService) LoginCountryStats(ctx context.Context, days int) (*response.CountryStats, error) {
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	from := to.AddDate(0, 0, -days)
+
+	logins, err := s.auditRepo.DailyLoginsByCountry(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	dayIndex := make(map[string]int, days)
+	for i := 0; i < days; i++ {
+		dayIndex[from.AddDate(0, 0, i).Format(statsDateLayout)] = i
+	}
+
+	stats := &response.CountryStats{From: from, To: to, Days: days, Countries: []response.CountryStat{}}
+	countries := make(map[string]*response.CountryStat)
+	for _, login := range logins {
+		i, ok := dayIndex[login.Date]
+		if !ok {
+			continue
+		}
+		country, ok := countries[login.Country]
+		if !ok {
+			country = &response.CountryStat{Country: login.Country, Daily: make([]response.CountryDailyStat, days)}
+			for date, j := range dayIndex {
+				country.Daily[j].Date = date
+			}
+			countries[login.Country] = country
+		}
+		country.Daily[i].Successful = login.Successful
+		country.Daily[i].Failed = login.Failed
+		country.Successful += login.Successful
+		country.Failed += login.Failed
+	}
+
+	for _, country := range countries {
+		if attempts := country.Successful + country.Failed; attempts > 0 {
+			country.FailureRate = float64(country.Failed) / float64(attempts)
+		}
+		stats.Countries = append(stats.Countries, *country)
+	}
+	sort.Slice(stats.Countries, func(i, j int) bool {
+		a, b := stats.Countries[i], stats.Countries[j]
+		if a.Successful+a.Failed != b.Successful+b.Failed {
+			return a.Successful+a.Failed > b.Successful+b.Failed
+		}
+		return a.Country < b.Country
+	})
+	return stats, nil
+}
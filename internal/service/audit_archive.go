@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Audit Log Archival
+// ============================================================================
+
+const (
+	// auditArchiveFileRows is how many entries an archive file holds at most
+	auditArchiveFileRows = 50000
+
+	// auditArchiveTimeout bounds an on-demand export
+	auditArchiveTimeout = time.Hour
+)
+
+// Archive run statuses
+const (
+	archiveRunning  = "running"
+	archiveComplete = "complete"
+	archiveFailed   = "failed"
+)
+
+// ArchiveAuditLogs starts exporting the audit log entries recorded in
+// [req.From, req.To) to the archive bucket, keeping them in the database,
+// and returns the running archive. Its manifest is written to the bucket
+// when it completes.
+func (s *AuthService) ArchiveAuditLogs(ctx context.Context, req models.AuditArchiveRequest, actorID int64) (*models.AuditArchive, error) {
+	// Audit logs are shared by every tenant
+	if tenant := requestctx.TenantFrom(ctx); tenant != 0 && tenant != constants.DefaultTenantID {
+		return nil, ErrAuditArchiveForbidden
+	}
+	if s.auditArchive == nil {
+		return nil, ErrAuditArchiveDisabled
+	}
+	if !req.From.Before(req.To) {
+		return nil, ErrInvalidArchiveRange
+	}
+
+	from := req.From.UTC()
+	archive := s.newAuditArchive("export", "all", &from, req.To.UTC())
+	archive.RequestedBy = &actorID
+	started := *archive
+
+	s.recordAudit(ctx, &actorID, constants.AuditLogsExported, map[string]interface{}{
+		"archive_id": archive.ID,
+		"from":       archive.From,
+		"to":         archive.To,
+	})
+
+	// The export outlives the request
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditArchiveTimeout)
+		defer cancel()
+		s.runAuditArchive(ctx, archive, models.AuditLogFilter{From: from, Before: archive.To}, false)
+	}()
+
+	return &started, nil
+}
+
+// archiveAndPurge archives the entries of data matching filter, deleting
+// each file's entries once it is uploaded. Entries are only deleted once
+// archived, so a failed upload keeps them for the next run.
+func (s *AuthService) archiveAndPurge(ctx context.Context, data string, filter models.AuditLogFilter) {
+	archive := s.newAuditArchive("retention", data, nil, filter.Before)
+	s.runAuditArchive(ctx, archive, filter, true)
+}
+
+// newAuditArchive returns a running archive, named after its start time.
+func (s *AuthService) newAuditArchive(reason, data string, from *time.Time, to time.Time) *models.AuditArchive {
+	now := time.Now().UTC()
+	archive := &models.AuditArchive{
+		ID:        now.Format("20060102T150405Z") + "-" + generateSecureToken()[:8],
+		Reason:    reason,
+		Data:      data,
+		Status:    archiveRunning,
+		From:      from,
+		To:        to,
+		Files:     []models.AuditArchiveFile{},
+		CreatedAt: now,
+	}
+	archive.Manifest = s.auditArchivePrefix(archive) + "manifest.json"
+	return archive
+}
+
+// auditArchivePrefix returns the key prefix of the files of archive.
+func (s *AuthService) auditArchivePrefix(archive *models.AuditArchive) string {
+	return s.cfg.AuditArchivePrefix + archive.ID + "/"
+}
+
+// runAuditArchive writes the entries matching filter to archive files,
+// deleting them once uploaded when purge is set, then the manifest. Runs of
+// the retention job that found nothing to archive write no manifest.
+func (s *AuthService) runAuditArchive(ctx context.Context, archive *models.AuditArchive, filter models.AuditLogFilter, purge bool) {
+	err := s.writeAuditArchive(ctx, archive, filter, purge)
+
+	completedAt := time.Now().UTC()
+	archive.CompletedAt = &completedAt
+	archive.Status = archiveComplete
+	if err != nil {
+		archive.Status = archiveFailed
+		archive.Error = err.Error()
+		logger.Error("audit log archive failed", "error", err, "archive", archive.ID, "rows", archive.Rows)
+	}
+	if archive.Rows == 0 && err == nil && archive.Reason == "retention" {
+		return
+	}
+
+	manifest, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		logger.Error("failed to encode audit archive manifest", "error", err, "archive", archive.ID)
+		return
+	}
+	if _, err := s.auditArchive.Put(ctx, archive.Manifest, bytes.NewReader(manifest), int64(len(manifest)), "application/json"); err != nil {
+		logger.Error("failed to write audit archive manifest", "error", err, "archive", archive.ID)
+		return
+	}
+	logger.Info("audit log entries archived", "archive", archive.ID, "data", archive.Data, "rows", archive.Rows, "files", len(archive.Files))
+}
+
+// writeAuditArchive writes the entries matching filter, in ID order, to
+// gzipped NDJSON files of up to auditArchiveFileRows entries.
+func (s *AuthService) writeAuditArchive(ctx context.Context, archive *models.AuditArchive, filter models.AuditLogFilter, purge bool) error {
+	var afterID int64
+	for {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		encoder := json.NewEncoder(gz)
+
+		var file models.AuditArchiveFile
+		for file.Rows < auditArchiveFileRows {
+			limit := min(s.cfg.RetentionBatchSize, auditArchiveFileRows-file.Rows)
+			entries, err := s.auditRepo.ListForArchive(ctx, filter, afterID, limit)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if err := encoder.Encode(entry); err != nil {
+					return err
+				}
+			}
+			if len(entries) > 0 {
+				if file.FirstID == 0 {
+					file.FirstID = entries[0].ID
+				}
+				afterID = entries[len(entries)-1].ID
+				file.LastID = afterID
+				file.Rows += len(entries)
+			}
+			if len(entries) < limit {
+				break
+			}
+		}
+		if file.Rows == 0 {
+			return nil
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(buf.Bytes())
+		file.Key = fmt.Sprintf("%s%06d.ndjson.gz", s.auditArchivePrefix(archive), len(archive.Files)+1)
+		file.Bytes = buf.Len()
+		file.SHA256 = hex.EncodeToString(sum[:])
+		if _, err := s.auditArchive.Put(ctx, file.Key, &buf, int64(file.Bytes), "application/gzip"); err != nil {
+			return err
+		}
+		archive.Files = append(archive.Files, file)
+		archive.Rows += file.Rows
+
+		if purge {
+			archived := filter
+			archived.ThroughID = file.LastID
+			s.purge(ctx, archive.Data, func(ctx context.Context, limit int) (int64, error) {
+				return s.auditRepo.Purge(ctx, archived, limit)
+			})
+		}
+		if file.Rows < auditArchiveFileRows {
+			return nil
+		}
+	}
+}
@@ -3,23 +3,47 @@ package service
 import (
 	"context"
 	"crypto/rand"
-	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"authentio/internal/config"
 	"authentio/internal/constants"
 	"authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/internal/requestctx"
+	"authentio/pkg/breaker"
+	"authentio/pkg/challengestore"
+	"authentio/pkg/disposable"
 	"authentio/pkg/email"
+	"authentio/pkg/export"
+	"authentio/pkg/i18n"
+	"authentio/pkg/jwks"
 	"authentio/pkg/jwt"
+	"authentio/pkg/lock"
 	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+	"authentio/pkg/otplimit"
+	"authentio/pkg/pairing"
 	"authentio/pkg/password"
+	"authentio/pkg/push"
+	"authentio/pkg/quota"
+	"authentio/pkg/refreshgrace"
 	"authentio/pkg/response"
+	"authentio/pkg/revocation"
+	"authentio/pkg/signuplimit"
+	"authentio/pkg/sms"
+	"authentio/pkg/storage"
+	"authentio/pkg/tasks"
+	"authentio/pkg/webhook"
 
-	"google.golang.org/api/idtoken"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/idtoken"
 )
 
 // ============================================================================
@@ -29,13 +53,60 @@ import (
 // AuthService handles all authentication-related business logic including
 // registration, login, password reset, 2FA, and OAuth flows.
 type AuthService struct {
-	userRepo     repository.UserRepository
-	twoFARepo    repository.TwoFARepository
-	otpRepo      repository.OTPRepository
-	tokenRepo    repository.TokenRepository
-	jwtManager   *jwt.Manager
-	emailClient  *email.Client
-	googleClient *oauth2.Config
+	cfg             *config.Config
+	userRepo        repository.UserRepository
+	twoFARepo       repository.TwoFARepository
+	otpRepo         repository.OTPRepository
+	tokenRepo       repository.TokenRepository
+	auditRepo       repository.AuditLogRepository
+	consentRepo     repository.ConsentRepository
+	domainRules     repository.EmailDomainRuleRepository
+	inviteRepo      repository.InvitationRepository
+	orgRepo         repository.OrganizationRepository
+	orgInvites      repository.OrganizationInvitationRepository
+	oauthRepo       repository.OAuthRepository
+	profileRepo     repository.ProfileRepository
+	preferencesRepo repository.PreferencesRepository
+	pushRepo        repository.PushRepository
+	recoveryRepo    repository.RecoveryRepository
+	deviceRepo      repository.DeviceRepository
+	tenantRepo      repository.TenantRepository
+	ipAllowlistRepo repository.IPAllowlistRepository
+	ipDenylistRepo  repository.IPDenylistRepository
+	emailLogRepo    repository.EmailLogRepository
+	emailTemplates  repository.EmailTemplateRepository
+	duplicates      repository.DuplicateAccountRepository
+	jwtManager      *jwt.Manager
+	keys            *KeyManager
+	emailClient     email.EmailSender
+	emailRender     *email.EmailRenderer
+	emailWebhook    *email.SendGridWebhook
+	disposable      *disposable.Checker
+	quotas          *quota.Limiter
+	otpLimits       *otplimit.Limiter
+	signupLimits    *signuplimit.Limiter
+	challengeTokens *challengestore.Store
+	refreshGrace    *refreshgrace.Store
+	revoked         *revocation.List // revoked access tokens; nil when they aren't checked
+	locks           *lock.Locker     // keeps background jobs from running on several servers at once
+	scheduler       *lock.Election   // elects the server running the scheduled jobs
+	tasks           *tasks.Queue     // runs registration side effects in the background
+	webhook         *webhook.Sender
+	exports         *export.Store
+	pairings        *pairing.Store
+	federation      *jwks.Verifier
+	storage         storage.Storage
+	auditArchive    storage.Storage // private bucket audit logs are archived to
+	push            *push.Dispatcher
+	sms             sms.Sender
+	googleClient    *oauth2.Config
+	googleBreaker   *breaker.Breaker
+
+	allowlists    *userCache[allowlistState]
+	denylist      *ipDenylistCache
+	geoPolicies   *geoPolicyCache
+	tokenVersions *userCache[int]
+	scheduledJobs *jobRegistry
 }
 
 // ============================================================================
@@ -44,22 +115,108 @@ type AuthService struct {
 
 // NewAuthService constructs the AuthService with its dependencies.
 func NewAuthService(
+	cfg *config.Config,
 	userRepo repository.UserRepository,
 	twoFARepo repository.TwoFARepository,
 	otpRepo repository.OTPRepository,
 	tokenRepo repository.TokenRepository,
+	auditRepo repository.AuditLogRepository,
+	consentRepo repository.ConsentRepository,
+	domainRules repository.EmailDomainRuleRepository,
+	inviteRepo repository.InvitationRepository,
+	orgRepo repository.OrganizationRepository,
+	orgInvites repository.OrganizationInvitationRepository,
+	oauthRepo repository.OAuthRepository,
+	profileRepo repository.ProfileRepository,
+	preferencesRepo repository.PreferencesRepository,
+	pushRepo repository.PushRepository,
+	recoveryRepo repository.RecoveryRepository,
+	deviceRepo repository.DeviceRepository,
+	tenantRepo repository.TenantRepository,
+	ipAllowlistRepo repository.IPAllowlistRepository,
+	ipDenylistRepo repository.IPDenylistRepository,
+	emailLogRepo repository.EmailLogRepository,
+	emailTemplates repository.EmailTemplateRepository,
+	duplicates repository.DuplicateAccountRepository,
 	jwtManager *jwt.Manager,
-	emailClient *email.Client,
+	keys *KeyManager,
+	emailClient email.EmailSender,
+	emailRender *email.EmailRenderer,
+	emailWebhook *email.SendGridWebhook,
+	disposableChecker *disposable.Checker,
+	quotas *quota.Limiter,
+	otpLimits *otplimit.Limiter,
+	signupLimits *signuplimit.Limiter,
+	challengeTokens *challengestore.Store,
+	refreshGrace *refreshgrace.Store,
+	revokedTokens *revocation.List,
+	locks *lock.Locker,
+	scheduler *lock.Election,
+	taskQueue *tasks.Queue,
+	registrationWebhook *webhook.Sender,
+	exports *export.Store,
+	pairings *pairing.Store,
+	federation *jwks.Verifier,
+	fileStorage storage.Storage,
+	auditArchive storage.Storage,
+	pushDispatcher *push.Dispatcher,
+	smsSender sms.Sender,
 	googleClient *oauth2.Config,
 ) *AuthService {
 	return &AuthService{
-		userRepo:     userRepo,
-		twoFARepo:    twoFARepo,
-		otpRepo:      otpRepo,
-		tokenRepo:    tokenRepo,
-		jwtManager:   jwtManager,
-		emailClient:  emailClient,
-		googleClient: googleClient,
+		cfg:             cfg,
+		userRepo:        userRepo,
+		twoFARepo:       twoFARepo,
+		otpRepo:         otpRepo,
+		tokenRepo:       tokenRepo,
+		auditRepo:       auditRepo,
+		consentRepo:     consentRepo,
+		domainRules:     domainRules,
+		inviteRepo:      inviteRepo,
+		orgRepo:         orgRepo,
+		orgInvites:      orgInvites,
+		oauthRepo:       oauthRepo,
+		profileRepo:     profileRepo,
+		preferencesRepo: preferencesRepo,
+		pushRepo:        pushRepo,
+		recoveryRepo:    recoveryRepo,
+		deviceRepo:      deviceRepo,
+		tenantRepo:      tenantRepo,
+		ipAllowlistRepo: ipAllowlistRepo,
+		ipDenylistRepo:  ipDenylistRepo,
+		emailLogRepo:    emailLogRepo,
+		emailTemplates:  emailTemplates,
+		duplicates:      duplicates,
+		jwtManager:      jwtManager,
+		keys:            keys,
+		emailClient:     emailClient,
+		emailRender:     emailRender,
+		emailWebhook:    emailWebhook,
+		disposable:      disposableChecker,
+		quotas:          quotas,
+		otpLimits:       otpLimits,
+		signupLimits:    signupLimits,
+		challengeTokens: challengeTokens,
+		refreshGrace:    refreshGrace,
+		revoked:         revokedTokens,
+		locks:           locks,
+		scheduler:       scheduler,
+		tasks:           taskQueue,
+		webhook:         registrationWebhook,
+		exports:         exports,
+		pairings:        pairings,
+		federation:      federation,
+		storage:         fileStorage,
+		auditArchive:    auditArchive,
+		push:            pushDispatcher,
+		sms:             smsSender,
+		googleClient:    googleClient,
+		googleBreaker:   breaker.New("google", cfg.CircuitBreakerFailures, cfg.CircuitBreakerOpenTimeout),
+		allowlists:      newUserCache[allowlistState](maxCachedUsers),
+		denylist:        &ipDenylistCache{},
+		geoPolicies:     newGeoPolicyCache(),
+		tokenVersions:   newUserCache[int](maxCachedUsers),
+		scheduledJobs:   &jobRegistry{},
 	}
 }
 
@@ -68,12 +225,59 @@ func NewAuthService(
 // ============================================================================
 
 // Register handles user registration flow including validation, user creation,
-// and sending welcome email.
+// and queueing its side effects (onboarding emails, webhook).
 func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest) (*response.RegisterResponse, error) {
+	// Registration requires accepting the current legal documents
+	if !req.AcceptTerms {
+		return nil, ErrTermsNotAccepted
+	}
+
+	// Throttle signup floods from one address or of one mailbox
+	if err := s.throttleRegistration(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	// Enforce the registration domain allowlist / blocklist
+	if err := s.checkEmailDomain(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	// Reject (or flag) throwaway email domains
+	disposableEmail, err := s.checkDisposableEmail(req.Email)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if email already exists
 	existingUser, _ := s.userRepo.FindByEmail(ctx, req.Email)
 	if existingUser != nil {
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailExists
+	}
+
+	username := NormalizeUsername(req.Username)
+	if username != "" {
+		if err := s.claimableUsername(ctx, username, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// Validate the invitations; an organization invitation also satisfies
+	// invite-only mode
+	orgInvitation, err := s.resolveOrgInvitation(ctx, req.OrgInviteToken, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	var invitation *models.Invitation
+	if orgInvitation == nil || req.InviteToken != "" {
+		invitation, err = s.resolveInvitation(ctx, req.InviteToken, req.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Count the signup against the tenant's daily registration quota
+	if err := s.consumeQuota(ctx, quota.Registrations); err != nil {
+		return nil, err
 	}
 
 	// Hash password before storage
@@ -87,6 +291,7 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Email:     req.Email,
+		Username:  username,
 		Password:  hashed,
 		IsActive:  true,
 		BaseModel: models.BaseModel{
@@ -95,46 +300,91 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 		},
 	}
 
+	if invitation != nil {
+		user.InvitedBy = invitation.InvitedBy
+	} else if orgInvitation != nil {
+		user.InvitedBy = orgInvitation.InvitedBy
+	}
+	user.ApprovalStatus = s.initialApprovalStatus(invitation != nil || orgInvitation != nil)
+
 	// Persist user to database
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		return nil, err
 	}
-
-	// Send welcome email (non-blocking, log errors but don't fail registration)
-	go s.sendWelcomeEmail(user.Email, user.FirstName)
+	s.acceptInvitation(ctx, invitation, user)
+	s.joinInvitedOrganization(ctx, orgInvitation, user)
+
+	// Queue the first onboarding emails, or the admins' review request for
+	// pending users, and the registration webhook
+	s.queueRegistrationTasks(ctx, user, "password")
+	message := i18n.T(requestctx.LocaleFrom(ctx), "message.registration_successful")
+	if user.ApprovalStatus == constants.ApprovalPending {
+		message = i18n.T(requestctx.LocaleFrom(ctx), "message.registration_pending")
+	}
 
 	// Convert to response DTO
-	userResponse := response.UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		IsActive:  user.IsActive,
+	userResponse := newUserResponse(user)
+
+	var auditMeta map[string]interface{}
+	if disposableEmail {
+		auditMeta = map[string]interface{}{"disposable_email": true}
+	}
+	s.recordAudit(ctx, &user.ID, constants.AuditUserRegistered, auditMeta)
+
+	// Record acceptance of the ToS/privacy versions in force at registration
+	if err := s.recordConsent(ctx, user.ID, s.cfg.TOSVersion, s.cfg.PrivacyPolicyVersion); err != nil {
+		logger.Error("failed to record registration consent", "error", err, "userID", user.ID)
 	}
 
 	logger.Info("user registered successfully", "email", req.Email)
 
 	return &response.RegisterResponse{
 		User:    userResponse,
-		Message: "Registration successful",
+		Message: message,
 	}, nil
 }
 
 // Login validates user credentials and returns JWT tokens upon successful authentication.
 func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*response.LoginResponse, error) {
-	// Find user by email
-	user, err := s.userRepo.FindByEmail(ctx, req.Email)
+	// Find user by email, or by username when no email is given
+	var user *models.User
+	var err error
+	if req.Email != "" {
+		user, err = s.userRepo.FindByEmail(ctx, req.Email)
+	} else {
+		user, err = s.userRepo.FindByUsername(ctx, NormalizeUsername(req.Username))
+	}
 	if err != nil || user == nil {
-		return nil, errors.New("invalid email or password")
+		if req.Email != "" {
+			s.recordAudit(ctx, nil, constants.AuditLoginFailed, map[string]interface{}{"email": req.Email, "reason": "unknown_email"})
+		} else {
+			s.recordAudit(ctx, nil, constants.AuditLoginFailed, map[string]interface{}{"username": req.Username, "reason": "unknown_username"})
+		}
+		return nil, ErrInvalidCredentials
 	}
 
 	// Verify password
 	if !password.Check(req.Password, user.Password) {
-		return nil, errors.New("invalid credentials")
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"reason": "invalid_password"})
+		return nil, ErrInvalidCredentials
+	}
+
+	// Unapproved registrations can't log in
+	if err := checkApproval(user); err != nil {
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"reason": "approval_" + user.ApprovalStatus})
+		return nil, err
 	}
 
+	// Users with push approval, and risky logins, get a challenge instead of tokens
+	metadata := map[string]interface{}{"method": "password"}
+	if challenge, err := s.checkLoginRisk(ctx, user, "password", metadata); challenge != nil || err != nil {
+		return challenge, err
+	}
+
+	s.loginSucceeded(ctx, user, "password", metadata)
+
 	// Generate authentication response with tokens
-	return s.generateAuthResponse(user)
+	return s.generateAuthResponse(ctx, user, nil)
 }
 
 // ============================================================================
@@ -143,11 +393,23 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*resp
 
 // GoogleAuth handles Google OAuth authentication by validating ID tokens
 // and creating new users or logging in existing ones.
-func (s *AuthService) GoogleAuth(ctx context.Context, idTokenStr string, audience string) (*response.LoginResponse, error) {
+// inviteToken is only needed for new users when INVITE_ONLY_REGISTRATION is enabled;
+// orgInviteToken joins a new user to the organization that invited them.
+func (s *AuthService) GoogleAuth(ctx context.Context, idTokenStr string, audience string, inviteToken, orgInviteToken string) (*response.LoginResponse, error) {
 	// Validate the Google ID token
-	payload, err := idtoken.Validate(ctx, idTokenStr, audience)
+	var payload *idtoken.Payload
+	err := s.googleBreaker.Do(func() error {
+		var err error
+		payload, err = idtoken.Validate(ctx, idTokenStr, audience)
+		return err
+	}, googleUnavailable)
 	if err != nil {
-		return nil, fmt.Errorf("invalid Google token: %w", err)
+		if errors.Is(err, breaker.ErrOpen) || googleUnavailable(err) {
+			logger.Warn("google id token validation unavailable", "error", err)
+			return nil, ErrGoogleUnavailable
+		}
+		logger.Debug("google id token validation failed", "error", err)
+		return nil, ErrInvalidGoogleToken
 	}
 
 	// Extract user information from token claims
@@ -156,56 +418,133 @@ func (s *AuthService) GoogleAuth(ctx context.Context, idTokenStr string, audienc
 	lastName, _ := payload.Claims["family_name"].(string)
 
 	if email == "" {
-		return nil, errors.New("invalid token payload: missing email")
+		logger.Debug("google id token missing email claim")
+		return nil, ErrInvalidGoogleToken
 	}
 
-	// Check if user exists, create if new
+	// Check if user exists, create if new (FindByEmail returns nil, nil when not found)
 	user, err := s.userRepo.FindByEmail(ctx, email)
-	if err == sql.ErrNoRows {
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		// New signups are subject to the registration domain policy
+		if err := s.checkEmailDomain(ctx, email); err != nil {
+			return nil, err
+		}
+		orgInvitation, err := s.resolveOrgInvitation(ctx, orgInviteToken, email)
+		if err != nil {
+			return nil, err
+		}
+		var invitation *models.Invitation
+		if orgInvitation == nil || inviteToken != "" {
+			invitation, err = s.resolveInvitation(ctx, inviteToken, email)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := s.consumeQuota(ctx, quota.Registrations); err != nil {
+			return nil, err
+		}
+
 		// Create new user for Google OAuth
 		user = &models.User{
-			Email:     email,
-			FirstName: firstName,
-			LastName:  lastName,
-			IsActive:  true,
-			Provider:  "google",
+			Email:      email,
+			FirstName:  firstName,
+			LastName:   lastName,
+			IsActive:   true,
+			Provider:   "google",
+			ProviderID: payload.Subject,
 			BaseModel: models.BaseModel{
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			},
 		}
+		if invitation != nil {
+			user.InvitedBy = invitation.InvitedBy
+		} else if orgInvitation != nil {
+			user.InvitedBy = orgInvitation.InvitedBy
+		}
+		user.ApprovalStatus = s.initialApprovalStatus(invitation != nil || orgInvitation != nil)
 
 		if err := s.userRepo.Create(ctx, user); err != nil {
 			return nil, err
 		}
+		s.acceptInvitation(ctx, invitation, user)
+		s.joinInvitedOrganization(ctx, orgInvitation, user)
+
+		s.recordAudit(ctx, &user.ID, constants.AuditUserRegistered, map[string]interface{}{"provider": "google"})
 
-		// Send welcome email for new Google OAuth users
-		go s.sendWelcomeEmail(user.Email, user.FirstName)
-	} else if err != nil {
+		// Queue the first onboarding emails to new Google OAuth users; pending
+		// users get theirs once approved
+		s.queueRegistrationTasks(ctx, user, "google")
+	}
+
+	// Unapproved registrations can't log in
+	if err := checkApproval(user); err != nil {
 		return nil, err
 	}
 
+	metadata := map[string]interface{}{"method": "google"}
+	if challenge, err := s.checkLoginRisk(ctx, user, "google", metadata); challenge != nil || err != nil {
+		return challenge, err
+	}
+
+	s.loginSucceeded(ctx, user, "google", metadata)
+
 	// Generate authentication response
-	return s.generateAuthResponse(user)
+	return s.generateAuthResponse(ctx, user, nil)
 }
 
 // GoogleCallback handles the OAuth callback flow by exchanging authorization code
 // for tokens and processing the authentication.
 func (s *AuthService) GoogleCallback(ctx context.Context, code string, oauthConfig *oauth2.Config) (*response.LoginResponse, error) {
 	// Exchange authorization code for tokens
-	token, err := s.googleClient.Exchange(ctx, code)
+	var token *oauth2.Token
+	err := s.googleBreaker.Do(func() error {
+		var err error
+		token, err = s.googleClient.Exchange(ctx, code)
+		return err
+	}, googleUnavailable)
+	if errors.Is(err, breaker.ErrOpen) {
+		return nil, ErrGoogleUnavailable
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange code: %w", err)
+		logger.Warn("google oauth code exchange failed", "error", err)
+		if googleUnavailable(err) {
+			return nil, ErrGoogleUnavailable
+		}
+		return nil, ErrOAuthExchangeFailed
 	}
 
 	// Extract ID token from response
 	rawIDToken, ok := token.Extra("id_token").(string)
 	if !ok || rawIDToken == "" {
-		return nil, errors.New("no id_token in response")
+		logger.Warn("google oauth response has no id_token")
+		return nil, ErrOAuthExchangeFailed
 	}
 
 	// Reuse GoogleAuth to validate ID token and login/create user
-	return s.GoogleAuth(ctx, rawIDToken, oauthConfig.ClientID)
+	// The redirect flow carries no invite token, so invite-only deployments
+	// must onboard new users through the ID token flow
+	return s.GoogleAuth(ctx, rawIDToken, oauthConfig.ClientID, "", "")
+}
+
+// googleUnavailable reports whether a Google call failed because Google
+// couldn't be reached or answered with a server error, rather than because
+// the token or code was refused.
+func googleUnavailable(err error) bool {
+	var transport *url.Error
+	if errors.As(err, &transport) {
+		return true
+	}
+	var refused *oauth2.RetrieveError
+	if errors.As(err, &refused) && refused.Response != nil {
+		return refused.Response.StatusCode >= http.StatusInternalServerError || refused.Response.StatusCode == http.StatusTooManyRequests
+	}
+	// idtoken doesn't wrap the status of its certificate download
+	return strings.Contains(err.Error(), "unable to retrieve cert")
 }
 
 // ============================================================================
@@ -215,6 +554,14 @@ func (s *AuthService) GoogleCallback(ctx context.Context, code string, oauthConf
 // RequestPasswordReset initiates the password reset flow by generating a reset code
 // and sending it to the user's email.
 func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	// Checked before the user lookup so the limits don't reveal which emails exist
+	if err := s.throttleOTP(ctx, constants.TypePasswordReset, email); err != nil {
+		return err
+	}
+	if err := s.consumeQuota(ctx, quota.OTPEmails); err != nil {
+		return err
+	}
+
 	// Check if user exists (but don't reveal if they don't to prevent email enumeration)
 	user, _ := s.userRepo.FindByEmail(ctx, email)
 	if user == nil {
@@ -223,24 +570,34 @@ func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) er
 	}
 
 	// Generate reset code
-	code := generateRandomCode(6)
+	policy := s.cfg.OTPPolicy(constants.TypePasswordReset)
+	code, err := policy.Generate()
+	if err != nil {
+		return err
+	}
+	expiresAt := policy.ExpiresAt(time.Now())
 
 	// Store OTP with password_reset type
-	otp := &models.OTP{
+	issued := &models.OTP{
 		UserID: &user.ID,
 		Email:  email,
 		Code:   code,
 		Type:   string(constants.TypePasswordReset),
 	}
+	issued.ExpiredAt = &expiresAt
 
-	if err := s.otpRepo.CreateOTP(ctx, otp); err != nil {
+	if err := s.otpRepo.CreateOTP(ctx, issued); err != nil {
 		return err
 	}
 
 	// Send password reset email
-	if err := s.emailClient.SendPasswordReset(email, code); err != nil {
+	msg, err := s.emailRender.PasswordReset(ctx, code, policy.TTL)
+	if err != nil {
+		return err
+	}
+	if err := s.sendEmail(ctx, constants.EmailPasswordReset, []string{email}, msg); err != nil {
 		logger.Error("failed to send password reset email", "error", err, "email", email)
-		return fmt.Errorf("failed to send reset email")
+		return ErrEmailSendFailed
 	}
 
 	logger.Info("password reset code sent", "email", email)
@@ -250,15 +607,17 @@ func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) er
 // ResetPassword verifies the reset code and updates the user's password.
 func (s *AuthService) ResetPassword(ctx context.Context, email, code, newPassword string) error {
 	// Verify the reset code
+	code = s.cfg.OTPPolicy(constants.TypePasswordReset).Normalize(code)
 	valid, err := s.otpRepo.VerifyOTP(ctx, email, code, string(constants.TypePasswordReset))
 	if err != nil || !valid {
-		return errors.New("invalid or expired reset code")
+		metrics.OTPFailures.Inc()
+		return ErrInvalidResetCode
 	}
 
 	// Find the user
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil || user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	// Hash new password
@@ -268,20 +627,15 @@ func (s *AuthService) ResetPassword(ctx context.Context, email, code, newPasswor
 	}
 
 	// Update user's password
-	user.Password = hashedPassword
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	if err := s.userRepo.SetPassword(ctx, user.ID, hashedPassword); err != nil {
 		return err
 	}
 
-	// Send password change confirmation email
-	if err := s.emailClient.Send(
-		[]string{email},
-		"Password Changed Successfully",
-		"<p>Your password has been successfully changed.</p><p>If you didn't make this change, please contact support immediately.</p>",
-	); err != nil {
-		logger.Warn("failed to send password change confirmation email", "error", err, "email", email)
-		// Don't return error - password was already changed successfully
-	}
+	// Confirm the change on the user's security alert channels
+	// Don't return errors - password was already changed successfully
+	s.Notify(ctx, user, Notification{Event: constants.NotificationPasswordChanged})
+
+	s.recordAudit(ctx, &user.ID, constants.AuditPasswordReset, nil)
 
 	logger.Info("password reset successful", "email", email)
 	return nil
@@ -296,28 +650,53 @@ func (s *AuthService) Send2FAOTP(ctx context.Context, email string) error {
 	// Check if user exists
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil || user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
+	}
+
+	if err := s.throttleOTP(ctx, constants.Type2FA, email); err != nil {
+		return err
+	}
+	if err := s.consumeQuota(ctx, quota.OTPEmails); err != nil {
+		return err
 	}
 
+	return s.send2FACode(ctx, user)
+}
+
+// send2FACode issues a 2FA code for user and emails it to them. Callers apply
+// the cooldown and quota first.
+func (s *AuthService) send2FACode(ctx context.Context, user *models.User) error {
+	email := user.Email
+
 	// Generate OTP code
-	code := generateRandomCode(6)
+	policy := s.cfg.OTPPolicy(constants.Type2FA)
+	code, err := policy.Generate()
+	if err != nil {
+		return err
+	}
+	expiresAt := policy.ExpiresAt(time.Now())
 
 	// Store OTP with 2FA type
-	otp := &models.OTP{
+	issued := &models.OTP{
 		UserID: &user.ID,
 		Email:  email,
 		Code:   code,
 		Type:   string(constants.Type2FA),
 	}
+	issued.ExpiredAt = &expiresAt
 
-	if err := s.otpRepo.CreateOTP(ctx, otp); err != nil {
+	if err := s.otpRepo.CreateOTP(ctx, issued); err != nil {
 		return err
 	}
 
 	// Send OTP via email
-	if err := s.emailClient.SendOTP(email, code); err != nil {
+	msg, err := s.emailRender.OTP(ctx, code, policy.TTL)
+	if err != nil {
+		return err
+	}
+	if err := s.sendEmail(ctx, constants.EmailTwoFactorCode, []string{email}, msg); err != nil {
 		logger.Error("failed to send 2FA email", "error", err, "email", email)
-		return fmt.Errorf("failed to send verification email")
+		return ErrEmailSendFailed
 	}
 
 	logger.Info("2FA code sent via email", "email", email)
@@ -326,21 +705,40 @@ func (s *AuthService) Send2FAOTP(ctx context.Context, email string) error {
 
 // Verify2FA checks OTP validity for 2FA verification.
 func (s *AuthService) Verify2FA(ctx context.Context, email, code string) error {
+	code = s.cfg.OTPPolicy(constants.Type2FA).Normalize(code)
 	valid, err := s.otpRepo.VerifyOTP(ctx, email, code, string(constants.Type2FA))
 	if err != nil || !valid {
-		return errors.New("invalid or expired code")
+		metrics.OTPFailures.Inc()
+		return ErrInvalidOTP
 	}
 	return nil
 }
 
-// EnableEmail2FA enables email-based 2FA for a user.
+// EnableEmail2FA enables email-based 2FA for a user. Their access tokens
+// must be refreshed.
 func (s *AuthService) EnableEmail2FA(ctx context.Context, userID int64) error {
-	return s.twoFARepo.EnableEmail2FA(ctx, userID)
+	if err := s.twoFARepo.EnableEmail2FA(ctx, userID); err != nil {
+		return err
+	}
+	s.revokeAccessTokens(ctx, userID, "two_factor_enabled")
+	return nil
 }
 
-// Disable2FA disables 2FA for a user.
+// Disable2FA disables 2FA for a user and alerts them. Their access tokens
+// must be refreshed.
 func (s *AuthService) Disable2FA(ctx context.Context, userID int64) error {
-	return s.twoFARepo.Disable2FA(ctx, userID)
+	if err := s.twoFARepo.Disable2FA(ctx, userID); err != nil {
+		return err
+	}
+	s.revokeAccessTokens(ctx, userID, "two_factor_disabled")
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		logger.Warn("failed to load user for two-factor alert", "error", err, "userID", userID)
+		return nil
+	}
+	s.Notify(ctx, user, Notification{Event: constants.NotificationTwoFactorDisabled})
+	return nil
 }
 
 // Is2FAEnabled checks if 2FA is enabled for a user.
@@ -357,24 +755,56 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenStr string)
 	// Get the refresh token from database
 	token, err := s.tokenRepo.GetRefreshToken(ctx, refreshTokenStr)
 	if err != nil {
-		return nil, errors.New("invalid refresh token")
+		// A concurrent request may have just rotated it
+		if resp := s.rotatedRefresh(ctx, refreshTokenStr); resp != nil {
+			return resp, nil
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	// Tokens issued to OAuth clients can only be refreshed at the token endpoint
+	if token.OAuthClientID != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	// Sessions started before auth_time was recorded count from their last
+	// refresh
+	authTime := token.AuthTime
+	if authTime == nil {
+		authTime = &token.CreatedAt
+	}
+	if err := s.checkSessionLimits(token, *authTime); err != nil {
+		if err := s.tokenRepo.DeleteRefreshToken(ctx, refreshTokenStr); err != nil {
+			logger.Error("failed to delete ended session", "error", err)
+		}
+		logger.Info("session ended", "userID", token.UserID, "reason", err)
+		return nil, err
 	}
 
 	// Get the user associated with the refresh token
 	user, err := s.userRepo.FindByID(ctx, token.UserID)
-	if err != nil {
-		return nil, errors.New("user not found")
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := checkApproval(user); err != nil {
+		return nil, err
+	}
+	if !s.AllowsIP(ctx, user.ID, requestctx.ClientInfoFrom(ctx).IP) {
+		return nil, ErrIPNotAllowed
 	}
 
-	// Generate new access token
-	accessToken, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.FirstName, user.LastName)
+	// Keep the organization scope while the user is still a member
+	membership, err := s.tokenMembership(ctx, token)
 	if err != nil {
 		return nil, err
 	}
 
-	// Token rotation: delete old refresh token for security
-	if err := s.tokenRepo.DeleteRefreshToken(ctx, refreshTokenStr); err != nil {
-		logger.Error("failed to delete old refresh token", "error", err)
+	// Generate new access token, carrying the user's current privileges and
+	// token version; the session keeps its authentication time
+	key := s.jwtManager.Key(user.TenantID)
+	claims := s.tokenClaims(user, membership)
+	claims.AuthTime = *authTime
+	accessToken, err := s.jwtManager.GenerateToken(claims)
+	if err != nil {
+		return nil, err
 	}
 
 	// Generate new refresh token
@@ -384,8 +814,18 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenStr string)
 		BaseModel: models.BaseModel{
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
-			ExpiredAt: timePtr(time.Now().Add(30 * 24 * time.Hour)),
+			ExpiredAt: timePtr(time.Now().Add(key.RefreshTTL)),
 		},
+		AuthTime: authTime,
+	}
+	recordAccessToken(newRefreshToken, accessToken, key.AccessTTL)
+	if membership != nil {
+		newRefreshToken.OrganizationID = &membership.OrganizationID
+	}
+	// The session stays on its device when the client sends no fingerprint
+	newRefreshToken.DeviceID = s.touchDevice(ctx, user.ID)
+	if newRefreshToken.DeviceID == nil {
+		newRefreshToken.DeviceID = token.DeviceID
 	}
 
 	// Save new refresh token
@@ -393,25 +833,127 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenStr string)
 		return nil, err
 	}
 
-	userResponse := response.UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		IsActive:  user.IsActive,
-	}
+	userResponse := newUserResponse(user)
+	userResponse.ConsentRequired = s.consentRequired(ctx, user.ID)
 
-	return &response.LoginResponse{
+	resp := &response.LoginResponse{
 		User:         userResponse,
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken.Token,
-		ExpiresIn:    3600, // 1 hour in seconds
-	}, nil
+		ExpiresIn:    int(key.AccessTTL.Seconds()),
+	}
+	setOrgScope(resp, membership)
+	s.setLoginHints(resp, user)
+
+	// Of concurrent refreshes with the same token, the first to record its
+	// result wins; the others return it and discard their own token
+	if winner := s.claimRotation(ctx, refreshTokenStr, resp); winner != nil {
+		if err := s.tokenRepo.DeleteRefreshToken(ctx, newRefreshToken.Token); err != nil {
+			logger.Error("failed to delete concurrent refresh token", "error", err)
+		}
+		return winner, nil
+	}
+
+	// Token rotation: delete old refresh token for security
+	if err := s.tokenRepo.DeleteRefreshToken(ctx, refreshTokenStr); err != nil {
+		logger.Error("failed to delete old refresh token", "error", err)
+	}
+	return resp, nil
+}
+
+// claimRotation records resp as the result of rotating refreshToken for the
+// grace period. It returns the result recorded by a concurrent request that
+// rotated the same token first, or nil when resp was recorded (or there is
+// no grace period).
+func (s *AuthService) claimRotation(ctx context.Context, refreshToken string, resp *response.LoginResponse) *response.LoginResponse {
+	if s.refreshGrace == nil {
+		return nil
+	}
+	result, err := json.Marshal(resp)
+	if err != nil {
+		logger.Error("failed to encode refresh result", "error", err)
+		return nil
+	}
+	claimed, err := s.refreshGrace.Claim(ctx, currentTenant(ctx), refreshToken, result)
+	if err != nil {
+		logger.Error("failed to record refresh token rotation", "error", err)
+		return nil
+	}
+	if claimed {
+		return nil
+	}
+	return s.rotatedRefresh(ctx, refreshToken)
+}
+
+// rotatedRefresh returns, once, the token pair refreshToken was rotated into
+// if that happened within the grace period, so a client refreshing twice at
+// once isn't signed out. It returns nil otherwise.
+func (s *AuthService) rotatedRefresh(ctx context.Context, refreshToken string) *response.LoginResponse {
+	if s.refreshGrace == nil {
+		return nil
+	}
+	result, err := s.refreshGrace.Consume(ctx, currentTenant(ctx), refreshToken)
+	if err != nil {
+		logger.Error("failed to read refresh token rotation", "error", err)
+		return nil
+	}
+	if result == nil {
+		return nil
+	}
+	var resp response.LoginResponse
+	if err := json.Unmarshal(result, &resp); err != nil {
+		logger.Error("failed to decode refresh result", "error", err)
+		return nil
+	}
+	logger.Info("rotated refresh token reused within the grace period", "userID", resp.User.ID)
+	return &resp
+}
+
+// checkSessionLimits returns ErrSessionMaxAge when the session of a refresh
+// token started more than REFRESH_TOKEN_MAX_AGE ago (at authTime), or
+// ErrSessionIdle when it was last refreshed (the token issued) more than
+// REFRESH_TOKEN_IDLE_TIMEOUT ago. Either way the user must sign in again.
+func (s *AuthService) checkSessionLimits(token *models.RefreshToken, authTime time.Time) error {
+	if s.cfg.RefreshTokenMaxAge > 0 && time.Since(authTime) > s.cfg.RefreshTokenMaxAge {
+		return ErrSessionMaxAge
+	}
+	if s.cfg.RefreshTokenIdleTimeout > 0 && time.Since(token.CreatedAt) > s.cfg.RefreshTokenIdleTimeout {
+		return ErrSessionIdle
+	}
+	return nil
 }
 
-// Logout invalidates a specific refresh token.
+// Logout ends the session of a refresh token: the token is deleted and the
+// last access token issued with it revoked.
 func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
-	return s.tokenRepo.DeleteRefreshToken(ctx, refreshToken)
+	token, _ := s.tokenRepo.GetRefreshToken(ctx, refreshToken)
+	if err := s.tokenRepo.DeleteRefreshToken(ctx, refreshToken); err != nil {
+		return err
+	}
+	if token != nil {
+		s.revokeAccessToken(ctx, token)
+	}
+	return nil
+}
+
+// recordAccessToken records accessToken, valid for ttl, as the last access
+// token of the session of refresh token token.
+func recordAccessToken(token *models.RefreshToken, accessToken string, ttl time.Duration) {
+	token.AccessTokenID, _ = jwt.UnverifiedTokenID(accessToken)
+	token.AccessExpiresAt = timePtr(time.Now().Add(ttl))
+}
+
+// revokeAccessToken revokes the last access token of the session of refresh
+// token token for the time it has left, so an ended session can't be used
+// until it expires. Failures are logged: the session is ended anyway, and
+// its access token runs out within ACCESS_TOKEN_TTL.
+func (s *AuthService) revokeAccessToken(ctx context.Context, token *models.RefreshToken) {
+	if s.revoked == nil || token.AccessTokenID == "" || token.AccessExpiresAt == nil {
+		return
+	}
+	if err := s.revoked.Revoke(ctx, token.AccessTokenID, time.Until(*token.AccessExpiresAt)); err != nil {
+		logger.Error("failed to revoke the access token of an ended session", "userID", token.UserID, "error", err)
+	}
 }
 
 // LogoutAll invalidates all refresh tokens for a user.
@@ -427,32 +969,30 @@ func (s *AuthService) LogoutAll(ctx context.Context, userID int64) error {
 func (s *AuthService) GetUserProfile(ctx context.Context, userID int64) (*response.UserResponse, error) {
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil || user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
-	userResponse := &response.UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		IsActive:  user.IsActive,
-	}
+	userResponse := newUserResponse(user)
+	userResponse.ConsentRequired = s.consentRequired(ctx, user.ID)
 
-	return userResponse, nil
+	return &userResponse, nil
 }
 
 // UpdateProfile updates user profile information.
 func (s *AuthService) UpdateProfile(ctx context.Context, userID int64, firstName, lastName, email string) error {
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil || user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	// If email is being changed, check it's not already taken
 	if email != "" && email != user.Email {
+		if _, err := s.checkDisposableEmail(email); err != nil {
+			return err
+		}
 		existingUser, _ := s.userRepo.FindByEmail(ctx, email)
 		if existingUser != nil {
-			return errors.New("email already exists")
+			return ErrEmailExists
 		}
 		user.Email = email
 	}
@@ -475,52 +1015,161 @@ func (s *AuthService) UpdateProfile(ctx context.Context, userID int64, firstName
 	return nil
 }
 
+// UpdateLocale saves the user's preferred language for localized messages.
+// The preference is carried in access tokens, so it applies from the next
+// login or token refresh.
+func (s *AuthService) UpdateLocale(ctx context.Context, userID int64, locale string) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	user.Locale = locale
+	user.UpdatedAt = time.Now()
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	logger.Info("locale preference updated", "userID", userID, "locale", locale)
+	return nil
+}
+
 // ============================================================================
-// Email Methods
+// Legal Consent (Terms of Service / Privacy Policy)
 // ============================================================================
 
-// sendWelcomeEmail sends a welcome email to new users after successful registration.
-// This method runs asynchronously and logs errors without failing the main operation.
-func (s *AuthService) sendWelcomeEmail(email, firstName string) {
-	subject := "Welcome to Authentio! 🎉"
-	body := fmt.Sprintf(`
-		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
-			<h1 style="color: #2563eb;">Welcome to Authentio, %s!</h1>
-			<p>Thank you for joining our secure authentication service. We're excited to have you on board!</p>
-			
-			<div style="background-color: #f3f4f6; padding: 20px; border-radius: 8px; margin: 20px 0;">
-				<h3 style="color: #2563eb; margin-top: 0;">Getting Started:</h3>
-				<ul>
-					<li>Explore your user dashboard</li>
-					<li>Set up two-factor authentication for enhanced security</li>
-					<li>Update your profile information</li>
-				</ul>
-			</div>
-			
-			<p>If you have any questions or need assistance, please don't hesitate to contact our support team.</p>
-			
-			<p style="color: #6b7280; font-size: 14px; margin-top: 30px;">
-				Best regards,<br>
-				<strong>The Authentio Team</strong>
-			</p>
-		</div>
-	`, firstName)
-
-	if err := s.emailClient.Send([]string{email}, subject, body); err != nil {
-		logger.Error("failed to send welcome email", "error", err, "email", email)
-	} else {
-		logger.Info("welcome email sent successfully", "email", email)
+// GetConsentStatus returns the ToS/privacy versions the user accepted alongside
+// the versions currently in force.
+func (s *AuthService) GetConsentStatus(ctx context.Context, userID int64) (*response.ConsentStatus, error) {
+	consents, err := s.consentRepo.LatestByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &response.ConsentStatus{
+		TOSVersion:           s.cfg.TOSVersion,
+		PrivacyPolicyVersion: s.cfg.PrivacyPolicyVersion,
+	}
+	if tos := consents[constants.ConsentTermsOfService]; tos != nil {
+		status.AcceptedTOSVersion = tos.Version
+		status.TOSAcceptedAt = timePtr(tos.AcceptedAt)
+	}
+	if privacy := consents[constants.ConsentPrivacyPolicy]; privacy != nil {
+		status.AcceptedPrivacyPolicyVersion = privacy.Version
+		status.PrivacyPolicyAcceptedAt = timePtr(privacy.AcceptedAt)
+	}
+	status.ConsentRequired = status.AcceptedTOSVersion != status.TOSVersion ||
+		status.AcceptedPrivacyPolicyVersion != status.PrivacyPolicyVersion
+
+	return status, nil
+}
+
+// AcceptConsent records the user's acceptance of the current legal documents.
+// The submitted versions must match the versions in force so a client showing
+// stale text can't record acceptance of a document the user never saw.
+func (s *AuthService) AcceptConsent(ctx context.Context, userID int64, req models.ConsentRequest) (*response.ConsentStatus, error) {
+	if req.TOSVersion != s.cfg.TOSVersion || req.PrivacyPolicyVersion != s.cfg.PrivacyPolicyVersion {
+		return nil, ErrConsentOutdated
+	}
+
+	if err := s.recordConsent(ctx, userID, req.TOSVersion, req.PrivacyPolicyVersion); err != nil {
+		return nil, err
+	}
+
+	logger.Info("legal consent recorded", "userID", userID, "tos", req.TOSVersion, "privacy", req.PrivacyPolicyVersion)
+	return s.GetConsentStatus(ctx, userID)
+}
+
+// recordConsent stores acceptance of both legal documents for a user.
+func (s *AuthService) recordConsent(ctx context.Context, userID int64, tosVersion, privacyVersion string) error {
+	ip := requestctx.ClientInfoFrom(ctx).IP
+	for document, version := range map[string]string{
+		constants.ConsentTermsOfService: tosVersion,
+		constants.ConsentPrivacyPolicy:  privacyVersion,
+	} {
+		consent := &models.UserConsent{
+			UserID:    userID,
+			Document:  document,
+			Version:   version,
+			IPAddress: ip,
+		}
+		if err := s.consentRepo.Record(ctx, consent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consentRequired reports whether the user must (re-)accept the legal documents.
+// Lookup errors are logged and treated as "not required" so they never block login.
+func (s *AuthService) consentRequired(ctx context.Context, userID int64) bool {
+	status, err := s.GetConsentStatus(ctx, userID)
+	if err != nil {
+		logger.Error("failed to load consent status", "error", err, "userID", userID)
+		return false
+	}
+	return status.ConsentRequired
+}
+
+// ============================================================================
+// Data Privacy
+// ============================================================================
+
+// AnonymizeUser honors a right-to-be-forgotten request by irreversibly scrubbing
+// the user's PII (name, email, credentials, IPs in audit logs) while keeping the
+// user row and audit events so foreign keys and aggregate statistics stay intact.
+// actorID is the user performing the erasure: the subject themself or an admin.
+func (s *AuthService) AnonymizeUser(ctx context.Context, userID, actorID int64) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.userRepo.Anonymize(ctx, userID); err != nil {
+		return err
+	}
+	s.deleteStoredAvatar(ctx, user.AvatarURL)
+
+	// Record the erasure itself. When the subject triggered it, the request IP
+	// and user agent are theirs, so they must not be written back.
+	entry := &models.AuditLog{
+		UserID: &userID,
+		Event:  string(constants.AuditUserAnonymized),
+	}
+	if actorID != userID {
+		client := requestctx.ClientInfoFrom(ctx)
+		entry.ActorID = &actorID
+		entry.IPAddress = client.IP
+		entry.UserAgent = client.UserAgent
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		logger.Error("failed to record audit event", "error", err, "event", entry.Event)
 	}
+
+	logger.Info("user anonymized", "userID", userID, "actorID", actorID)
+	return nil
 }
 
 // ============================================================================
 // Internal Helper Methods
 // ============================================================================
 
-// generateAuthResponse creates authentication tokens and returns a unified login response.
-func (s *AuthService) generateAuthResponse(user *models.User) (*response.LoginResponse, error) {
-	// Generate access token
-	accessToken, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.FirstName, user.LastName)
+// generateAuthResponse creates authentication tokens for a user who just
+// authenticated and returns a unified login response. A non-nil membership
+// scopes both tokens to that organization.
+func (s *AuthService) generateAuthResponse(ctx context.Context, user *models.User, membership *models.OrganizationMember) (*response.LoginResponse, error) {
+	return s.issueTokens(ctx, user, membership, time.Now())
+}
+
+// issueTokens creates a new session's tokens for a user who authenticated at
+// authTime.
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User, membership *models.OrganizationMember, authTime time.Time) (*response.LoginResponse, error) {
+	// Generate access token; lifetimes follow the user's tenant
+	key := s.jwtManager.Key(user.TenantID)
+	claims := s.tokenClaims(user, membership)
+	claims.AuthTime = authTime
+	accessToken, err := s.jwtManager.GenerateToken(claims)
 	if err != nil {
 		return nil, err
 	}
@@ -532,46 +1181,221 @@ func (s *AuthService) generateAuthResponse(user *models.User) (*response.LoginRe
 		BaseModel: models.BaseModel{
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
-			ExpiredAt: timePtr(time.Now().Add(30 * 24 * time.Hour)), // 30 days
+			ExpiredAt: timePtr(time.Now().Add(key.RefreshTTL)),
 		},
 	}
+	if !authTime.IsZero() {
+		refreshToken.AuthTime = &authTime
+	}
+	recordAccessToken(refreshToken, accessToken, key.AccessTTL)
+	if membership != nil {
+		refreshToken.OrganizationID = &membership.OrganizationID
+	}
+	refreshToken.DeviceID = s.touchDevice(ctx, user.ID)
 
 	// Save refresh token to database
-	if err := s.tokenRepo.SaveRefreshToken(context.Background(), refreshToken); err != nil {
+	if err := s.tokenRepo.SaveRefreshToken(ctx, refreshToken); err != nil {
 		return nil, err
 	}
 
 	// Create user response DTO
-	userResponse := response.UserResponse{
-		ID:        user.ID,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
-		IsActive:  user.IsActive,
-	}
+	userResponse := newUserResponse(user)
+	userResponse.ConsentRequired = s.consentRequired(ctx, user.ID)
 
 	logger.Info("authentication tokens generated", "email", user.Email)
 
-	return &response.LoginResponse{
+	resp := &response.LoginResponse{
 		User:         userResponse,
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken.Token,
-		ExpiresIn:    3600, // 1 hour in seconds
-	}, nil
+		ExpiresIn:    int(key.AccessTTL.Seconds()),
+	}
+	setOrgScope(resp, membership)
+	s.setLoginHints(resp, user)
+	return resp, nil
+}
+
+// checkEmailDomain enforces the registration domain policy: block rules always
+// reject, and when any allow rule exists the domain must match one of them.
+// Rules come from the environment and the email_domain_rules table. A rule for
+// example.com also covers its subdomains.
+func (s *AuthService) checkEmailDomain(ctx context.Context, email string) error {
+	rules, err := s.ListEmailDomainRules(ctx)
+	if err != nil {
+		// Fail closed: an allowlist must not be bypassed by a database error
+		return err
+	}
+
+	domain := emailDomain(email)
+	hasAllowRules, allowed := false, false
+	for _, rule := range rules {
+		matches := domain == rule.Domain || strings.HasSuffix(domain, "."+rule.Domain)
+		switch rule.Rule {
+		case constants.DomainRuleBlock:
+			if matches {
+				logger.Info("registration rejected by domain blocklist", "domain", domain)
+				return ErrEmailDomainBlocked
+			}
+		case constants.DomainRuleAllow:
+			hasAllowRules = true
+			allowed = allowed || matches
+		}
+	}
+
+	if hasAllowRules && !allowed {
+		logger.Info("registration rejected by domain allowlist", "domain", domain)
+		return ErrEmailDomainBlocked
+	}
+	return nil
+}
+
+// checkDisposableEmail applies DISPOSABLE_EMAIL_MODE to email. It returns
+// ErrDisposableEmail in block mode and reports flagged=true in flag mode.
+func (s *AuthService) checkDisposableEmail(email string) (flagged bool, err error) {
+	if s.cfg.DisposableEmailMode == constants.DisposableEmailOff || !s.disposable.IsDisposable(email) {
+		return false, nil
+	}
+	if s.cfg.DisposableEmailMode == constants.DisposableEmailFlag {
+		logger.Warn("disposable email address flagged", "email", email)
+		return true, nil
+	}
+	return false, ErrDisposableEmail
+}
+
+// recordAudit writes a security event for userID (nil when the user is unknown)
+// using the client details carried in ctx. Failures are logged, never returned,
+// so auditing can't break the flow being audited.
+func (s *AuthService) recordAudit(ctx context.Context, userID *int64, event constants.AuditEvent, metadata map[string]interface{}) {
+	client := requestctx.ClientInfoFrom(ctx)
+	if client.ClientCertificate != "" {
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		metadata["client_certificate"] = client.ClientCertificate
+	}
+	// Where sign-ins came from, for impossible travel checks; rounded, since
+	// GeoIP is no more precise
+	if event == constants.AuditLoginSuccess && client.Located {
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		metadata["location"] = map[string]interface{}{
+			"lat": math.Round(client.Latitude*100) / 100,
+			"lon": math.Round(client.Longitude*100) / 100,
+		}
+	}
+	entry := &models.AuditLog{
+		UserID:    userID,
+		Event:     string(event),
+		IPAddress: client.IP,
+		UserAgent: client.UserAgent,
+		Metadata:  metadata,
+	}
+	if client.DeviceFingerprint != "" {
+		entry.DeviceFingerprintHash = hashFingerprint(client.DeviceFingerprint)
+		// A successful login registers its device before the entry refers to it
+		if event == constants.AuditLoginSuccess && userID != nil {
+			s.touchDevice(ctx, *userID)
+		}
+	}
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		logger.Error("failed to record audit event", "error", err, "event", event)
+	}
+	s.countLoginCountry(ctx, event, client.Country)
+	recordSecurityMetrics(event, metadata)
+}
+
+// recordSecurityMetrics counts the failed and refused sign-ins among audit
+// events for the security metrics and alerts.
+func recordSecurityMetrics(event constants.AuditEvent, metadata map[string]interface{}) {
+	switch event {
+	case constants.AuditLoginBlocked:
+		metrics.Lockouts.Inc()
+	case constants.AuditLoginFailed:
+		metrics.FailedLogins.Inc()
+		// Refused whatever the credentials: the account is locked out
+		switch reason, _ := metadata["reason"].(string); reason {
+		case "approval_" + constants.ApprovalPending, "approval_" + constants.ApprovalRejected, "ip_not_allowed":
+			metrics.Lockouts.Inc()
+		}
+	}
 }
 
 // ============================================================================
 // Utility Functions
 // ============================================================================
 
-// generateRandomCode generates a random numeric code of specified length.
-func generateRandomCode(length int) string {
-	const digits = "0123456789"
-	bytes := make([]byte, length)
-	for i := range bytes {
-		bytes[i] = digits[time.Now().UnixNano()%int64(len(digits))]
+// newUserResponse converts a user entity into the public response DTO.
+func newUserResponse(user *models.User) response.UserResponse {
+	return response.UserResponse{
+		ID:        user.ID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Username:  user.Username,
+		IsActive:  user.IsActive,
+		CreatedAt: user.CreatedAt,
+		Locale:    user.Locale,
+		AvatarURL: user.AvatarURL,
+
+		UserMetadata: user.UserMetadata,
+		AppMetadata:  user.AppMetadata,
+
+		ApprovalStatus: approvalStatusForResponse(user.ApprovalStatus),
 	}
-	return string(bytes)
+}
+
+// tokenClaims maps a user to the claims embedded in its access token. A
+// non-nil membership adds the organization scope; metadata is included when
+// enabled in the configuration.
+func (s *AuthService) tokenClaims(user *models.User, membership *models.OrganizationMember) jwt.UserClaims {
+	claims := jwt.UserClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      user.Role,
+		Locale:    user.Locale,
+		TenantID:  user.TenantID,
+		Version:   user.TokenVersion,
+	}
+	if membership != nil {
+		claims.OrgID = membership.OrganizationID
+		claims.OrgRole = membership.Role
+	}
+	if s.cfg.TokenIncludeUserMetadata {
+		claims.UserMetadata = user.UserMetadata
+	}
+	if s.cfg.TokenIncludeAppMetadata {
+		claims.AppMetadata = user.AppMetadata
+	}
+	return claims
+}
+
+// setOrgScope reports the organization a login response's tokens are scoped to.
+func setOrgScope(resp *response.LoginResponse, membership *models.OrganizationMember) {
+	if membership != nil {
+		resp.OrgID = membership.OrganizationID
+		resp.OrgRole = membership.Role
+	}
+}
+
+// setLoginHints fills in the next steps of a login response carrying tokens
+// for user.
+func (s *AuthService) setLoginHints(resp *response.LoginResponse, user *models.User) {
+	resp.RequiresTwoFactor = resp.TwoFactor != nil
+	resp.RequiresTOSAcceptance = resp.User.ConsentRequired
+	resp.PasswordExpired = s.passwordExpired(user)
+}
+
+// passwordExpired reports whether user's password is older than
+// PASSWORD_MAX_AGE. Accounts without a password never expire.
+func (s *AuthService) passwordExpired(user *models.User) bool {
+	if s.cfg.PasswordMaxAge <= 0 || user.Password == "" || user.PasswordChangedAt == nil {
+		return false
+	}
+	return time.Since(*user.PasswordChangedAt) > s.cfg.PasswordMaxAge
 }
 
 // generateSecureToken generates a cryptographically secure random token.
@@ -583,7 +1407,17 @@ func generateSecureToken() string {
 	return hex.EncodeToString(bytes)
 }
 
+// emailDomain returns the lowercased domain part of an email address.
+func emailDomain(email string) string {
+	return strings.ToLower(strings.TrimSpace(email[strings.LastIndex(email, "@")+1:]))
+}
+
+// normalizeDomain lowercases a configured domain and strips a leading "@".
+func normalizeDomain(domain string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@")
+}
+
 // timePtr returns a pointer to a time.Time value.
 func timePtr(t time.Time) *time.Time {
 	return &t
-}
\ No newline at end of file
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"authentio/pkg/avatar"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Avatar Uploads
+// ============================================================================
+
+// UploadAvatar validates an uploaded image, normalizes it to a square JPEG,
+// stores it and saves its public URL on the user's profile. The previous
+// avatar, if it was uploaded here, is deleted.
+func (s *AuthService) UploadAvatar(ctx context.Context, userID int64, file io.Reader) (*response.UserResponse, error) {
+	if s.storage == nil {
+		return nil, ErrAvatarsDisabled
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	image, err := avatar.Process(file, s.cfg.AvatarMaxBytes, s.cfg.AvatarSize)
+	switch {
+	case errors.Is(err, avatar.ErrTooLarge):
+		return nil, ErrImageTooLarge
+	case errors.Is(err, avatar.ErrInvalidImage):
+		return nil, ErrInvalidImage
+	case err != nil:
+		return nil, err
+	}
+
+	// A new key per upload lets the object be cached forever by CDNs and browsers
+	key := fmt.Sprintf("avatars/%d/%d/%s%s", user.TenantID, user.ID, generateSecureToken()[:32], avatar.Extension)
+	avatarURL, err := s.storage.Put(ctx, key, bytes.NewReader(image), int64(len(image)), avatar.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetAvatarURL(ctx, user.ID, &avatarURL); err != nil {
+		s.deleteStoredAvatar(ctx, &avatarURL)
+		return nil, err
+	}
+	s.deleteStoredAvatar(ctx, user.AvatarURL)
+
+	user.AvatarURL = &avatarURL
+	userResponse := newUserResponse(user)
+	userResponse.ConsentRequired = s.consentRequired(ctx, user.ID)
+
+	logger.Info("avatar uploaded", "userID", user.ID, "bytes", len(image))
+	return &userResponse, nil
+}
+
+// RemoveAvatar clears the user's profile picture and deletes the stored image.
+func (s *AuthService) RemoveAvatar(ctx context.Context, userID int64) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+	if user.AvatarURL == nil {
+		return nil
+	}
+
+	if err := s.userRepo.SetAvatarURL(ctx, user.ID, nil); err != nil {
+		return err
+	}
+	s.deleteStoredAvatar(ctx, user.AvatarURL)
+
+	logger.Info("avatar removed", "userID", user.ID)
+	return nil
+}
+
+// AvatarUploadLimit is the largest avatar upload request body accepted: the
+// image size limit plus room for the multipart envelope.
+func (s *AuthService) AvatarUploadLimit() int64 {
+	return s.cfg.AvatarMaxBytes + 64<<10
+}
+
+// deleteStoredAvatar deletes an avatar image from storage. URLs not served by
+// our storage (such as a Google profile picture) are left alone, and failures
+// are only logged: an orphaned object is harmless.
+func (s *AuthService) deleteStoredAvatar(ctx context.Context, avatarURL *string) {
+	if s.storage == nil || avatarURL == nil {
+		return
+	}
+	key, ok := s.storage.KeyForURL(*avatarURL)
+	if !ok {
+		return
+	}
+	if err := s.storage.Delete(ctx, key); err != nil {
+		logger.Error("failed to delete avatar image", "error", err, "key", key)
+	}
+}
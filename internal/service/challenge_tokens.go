@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// MFA Challenge Tokens
+// ============================================================================
+
+// challengeTokenTTL is how long a client has to complete a login with the
+// challenge token it received; a challenge expiring sooner shortens it.
+const challengeTokenTTL = 5 * time.Minute
+
+// issueChallengeToken mints the token the client completes challenge with.
+// Push approval and email code logins can only be completed, and their code
+// resent, with it; the challenge ID alone is not enough.
+func (s *AuthService) issueChallengeToken(ctx context.Context, user *models.User, challenge *models.PushChallenge) (string, error) {
+	expiresAt := time.Now().Add(challengeTokenTTL)
+	if challenge.ExpiresAt.Before(expiresAt) {
+		expiresAt = challenge.ExpiresAt
+	}
+
+	claims := jwt.ChallengeClaims{
+		ID:          generateSecureToken(),
+		UserID:      user.ID,
+		TenantID:    user.TenantID,
+		ChallengeID: challenge.ChallengeID,
+		Method:      challenge.Method,
+		ExpiresAt:   expiresAt,
+	}
+	if s.challengeTokens != nil {
+		if err := s.challengeTokens.Save(ctx, user.TenantID, claims.ID, claims.ChallengeID, time.Until(expiresAt)); err != nil {
+			return "", err
+		}
+	}
+	return s.jwtManager.GenerateChallengeToken(claims)
+}
+
+// checkChallengeToken returns the claims of a challenge token issued for a
+// login waiting for method and not used yet, or ErrInvalidChallengeToken.
+func (s *AuthService) checkChallengeToken(ctx context.Context, token, method string) (*jwt.ChallengeClaims, error) {
+	claims, err := s.jwtManager.VerifyChallengeToken(token, currentTenant(ctx))
+	if err != nil {
+		logger.Debug("challenge token rejected", "error", err)
+		return nil, ErrInvalidChallengeToken
+	}
+	if claims.Method != method {
+		return nil, ErrInvalidChallengeToken
+	}
+
+	if s.challengeTokens != nil {
+		active, err := s.challengeTokens.Active(ctx, claims.TenantID, claims.ID, claims.ChallengeID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrInvalidChallengeToken
+		}
+	}
+	return claims, nil
+}
+
+// consumeChallengeToken uses up a challenge token once its login completes,
+// returning ErrInvalidChallengeToken when another request used it first.
+// Without Redis, the challenge itself can still only be completed once.
+func (s *AuthService) consumeChallengeToken(ctx context.Context, claims *jwt.ChallengeClaims) error {
+	if s.challengeTokens == nil {
+		return nil
+	}
+	consumed, err := s.challengeTokens.Consume(ctx, claims.TenantID, claims.ID, claims.ChallengeID)
+	if err != nil {
+		return err
+	}
+	if !consumed {
+		return ErrInvalidChallengeToken
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+)
+
+// ============================================================================
+// Data Retention
+// ============================================================================
+
+// loginEvents are the audit log entries kept for LOGIN_EVENT_RETENTION; the
+// others are kept for AUDIT_LOG_RETENTION.
+var loginEvents = []string{
+	string(constants.AuditLoginSuccess),
+	string(constants.AuditLoginFailed),
+	string(constants.AuditLoginBlocked),
+}
+
+// StartCleanup deletes the one-time codes, refresh tokens and audit log
+// entries kept past their retention now and then every interval until ctx
+// is cancelled, from the server running the scheduler.
+func (s *AuthService) StartCleanup(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.startScheduled(ctx, "cleanup", interval, s.cleanup)
+}
+
+// cleanup enforces the retention of each kind of data, for every tenant.
+func (s *AuthService) cleanup(ctx context.Context) {
+	now := time.Now()
+	s.purge(ctx, "otps", func(ctx context.Context, limit int) (int64, error) {
+		return s.otpRepo.PurgeOTPs(ctx, now.Add(-s.cfg.OTPRetention), limit)
+	})
+	s.purge(ctx, "refresh_tokens", func(ctx context.Context, limit int) (int64, error) {
+		return s.tokenRepo.PurgeRefreshTokens(ctx, now.Add(-s.cfg.RefreshTokenRetention), limit)
+	})
+	if s.cfg.LoginEventRetention > 0 {
+		s.purgeAuditLogs(ctx, "login_events", models.AuditLogFilter{
+			Events: loginEvents,
+			Before: now.Add(-s.cfg.LoginEventRetention),
+		})
+	}
+	if s.cfg.AuditLogRetention > 0 {
+		s.purgeAuditLogs(ctx, "audit_logs", models.AuditLogFilter{
+			Events:      loginEvents,
+			OtherEvents: true,
+			Before:      now.Add(-s.cfg.AuditLogRetention),
+		})
+	}
+}
+
+// purgeAuditLogs deletes the audit log entries of data matching filter,
+// archiving them first when AUDIT_ARCHIVE_BUCKET is set.
+func (s *AuthService) purgeAuditLogs(ctx context.Context, data string, filter models.AuditLogFilter) {
+	if s.auditArchive != nil {
+		s.archiveAndPurge(ctx, data, filter)
+		return
+	}
+	s.purge(ctx, data, func(ctx context.Context, limit int) (int64, error) {
+		return s.auditRepo.Purge(ctx, filter, limit)
+	})
+}
+
+// purge runs batch, deleting up to RETENTION_BATCH_SIZE rows of data at a
+// time, RETENTION_BATCH_PAUSE apart, until a batch comes up short. Small
+// batches keep each delete's locks short, so sign-ins aren't held up.
+func (s *AuthService) purge(ctx context.Context, data string, batch func(ctx context.Context, limit int) (int64, error)) {
+	var total int64
+	for ctx.Err() == nil {
+		n, err := batch(ctx, s.cfg.RetentionBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Warn("data retention purge failed", "data", data, "error", err)
+			}
+			break
+		}
+		total += n
+		metrics.RowsPurged(data, n)
+		if n < int64(s.cfg.RetentionBatchSize) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.cfg.RetentionBatchPause):
+		}
+	}
+	if total > 0 {
+		logger.Info("purged data past its retention", "data", data, "rows", total)
+	}
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Device Registry
+// ============================================================================
+
+// ListDevices returns the devices the user signed in from, most recently seen
+// first, marking the one making the request.
+func (s *AuthService) ListDevices(ctx context.Context, userID int64) ([]models.Device, error) {
+	devices, err := s.deviceRepo.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		devices = []models.Device{}
+	}
+
+	if fingerprint := requestctx.ClientInfoFrom(ctx).DeviceFingerprint; fingerprint != "" {
+		current := hashFingerprint(fingerprint)
+		for i := range devices {
+			devices[i].Current = devices[i].FingerprintHash == current
+		}
+	}
+	return devices, nil
+}
+
+// RenameDevice names one of the user's devices; an empty name clears it.
+func (s *AuthService) RenameDevice(ctx context.Context, userID, deviceID int64, name string) error {
+	if err := s.deviceRepo.RenameDevice(ctx, userID, deviceID, strings.TrimSpace(name)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDeviceNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// RevokeDevice forgets one of the user's devices and signs out its sessions.
+// The device is registered again if it signs in later.
+func (s *AuthService) RevokeDevice(ctx context.Context, userID, deviceID int64) error {
+	// The access tokens of the device's sessions are revoked with them
+	sessions, err := s.tokenRepo.ListDeviceAccessTokens(ctx, userID, deviceID)
+	if err != nil {
+		return err
+	}
+	if err := s.deviceRepo.DeleteDevice(ctx, userID, deviceID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDeviceNotFound
+		}
+		return err
+	}
+	for _, session := range sessions {
+		s.revokeAccessToken(ctx, session)
+	}
+
+	s.recordAudit(ctx, &userID, constants.AuditDeviceRevoked, map[string]interface{}{"device_id": deviceID})
+	logger.Info("device revoked", "userID", userID, "deviceID", deviceID)
+	return nil
+}
+
+// touchDevice registers the device of the current request for userID, or
+// updates when it was last seen, and returns its ID. It returns nil when the
+// client sent no fingerprint; failures are logged, since the registry must
+// not block sign-ins.
+func (s *AuthService) touchDevice(ctx context.Context, userID int64) *int64 {
+	client := requestctx.ClientInfoFrom(ctx)
+	if client.DeviceFingerprint == "" {
+		return nil
+	}
+
+	device := &models.Device{
+		UserID:          userID,
+		FingerprintHash: hashFingerprint(client.DeviceFingerprint),
+		Platform:        devicePlatform(client.UserAgent),
+		IPAddress:       client.IP,
+		UserAgent:       client.UserAgent,
+	}
+	if err := s.deviceRepo.TouchDevice(ctx, device); err != nil {
+		logger.Warn("failed to record device", "error", err, "userID", userID)
+		return nil
+	}
+	return &device.ID
+}
+
+// devicePlatform guesses the operating system from a user agent.
+func devicePlatform(userAgent string) string {
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"), strings.Contains(userAgent, "iOS"):
+		return "ios"
+	case strings.Contains(userAgent, "Android"):
+		return "android"
+	case strings.Contains(userAgent, "Windows"):
+		return "windows"
+	case strings.Contains(userAgent, "CrOS"):
+		return "chromeos"
+	case strings.Contains(userAgent, "Macintosh"), strings.Contains(userAgent, "Mac OS X"):
+		return "macos"
+	case strings.Contains(userAgent, "Linux"):
+		return "linux"
+	}
+	return "unknown"
+}
+
+// hashFingerprint returns the SHA-256 hex digest stored for a device
+// fingerprint.
+func hashFingerprint(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return hex.EncodeToString(sum[:])
+}
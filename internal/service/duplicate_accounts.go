@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+	"authentio/pkg/pagination"
+)
+
+// ============================================================================
+// Duplicate Accounts
+// ============================================================================
+
+// ScanDuplicateAccounts looks for the tenant's likely duplicate accounts and
+// updates the report. Dismissed groups stay dismissed until another account
+// joins them.
+func (s *AuthService) ScanDuplicateAccounts(ctx context.Context) (*models.DuplicateScanResult, error) {
+	candidates, err := s.duplicates.Candidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.duplicates.Sync(ctx, candidates); err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[int64]bool)
+	for _, candidate := range candidates {
+		for _, userID := range candidate.UserIDs {
+			accounts[userID] = true
+		}
+	}
+	result := &models.DuplicateScanResult{Groups: len(candidates), Accounts: len(accounts)}
+	logger.Info("duplicate account scan finished", "tenantID", currentTenant(ctx), "groups", result.Groups, "accounts", result.Accounts)
+	return result, nil
+}
+
+// StartDuplicateAccountScan scans every tenant for duplicate accounts now and
+// then every interval until ctx is cancelled, on the server running the
+// scheduler.
+func (s *AuthService) StartDuplicateAccountScan(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	s.startScheduled(ctx, "duplicate-account-scan", interval, s.scanAllTenants)
+}
+
+// scanAllTenants runs ScanDuplicateAccounts for each tenant.
+func (s *AuthService) scanAllTenants(ctx context.Context) {
+	tenants, err := s.tenantRepo.List(ctx)
+	if err != nil {
+		logger.Warn("failed to list tenants for the duplicate account scan", "error", err)
+		return
+	}
+	for _, tenant := range tenants {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.ScanDuplicateAccounts(requestctx.WithTenant(ctx, tenant.ID)); err != nil {
+			logger.Warn("duplicate account scan failed", "error", err, "tenantID", tenant.ID)
+		}
+	}
+}
+
+// DuplicateAccounts returns a page of the tenant's groups of likely duplicate
+// accounts with the given status (any when empty), newest first.
+func (s *AuthService) DuplicateAccounts(ctx context.Context, status string, page pagination.Request) (pagination.Page[*models.DuplicateAccountGroup], error) {
+	switch status {
+	case "", constants.DuplicatesOpen, constants.DuplicatesDismissed:
+	default:
+		return pagination.Page[*models.DuplicateAccountGroup]{}, ErrInvalidDuplicateState
+	}
+
+	groups, err := s.duplicates.List(ctx, status, page.After, page.Fetch())
+	if err != nil {
+		return pagination.Page[*models.DuplicateAccountGroup]{}, err
+	}
+
+	return pagination.NewPage(groups, page, duplicateGroupCursor, func(group *models.DuplicateAccountGroup) *models.DuplicateAccountGroup {
+		return group
+	}), nil
+}
+
+// DismissDuplicateAccounts marks a group as not duplicates, hiding it from
+// the open report.
+func (s *AuthService) DismissDuplicateAccounts(ctx context.Context, id, actorID int64) error {
+	found, err := s.duplicates.Dismiss(ctx, id, actorID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrDuplicatesNotFound
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditDuplicatesDismissed, map[string]interface{}{"group_id": id})
+	logger.Info("duplicate accounts dismissed", "groupID", id, "actorID", actorID)
+	return nil
+}
+
+// duplicateGroupCursor positions a page after group.
+func duplicateGroupCursor(group *models.DuplicateAccountGroup) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: group.CreatedAt, ID: group.ID}
+}
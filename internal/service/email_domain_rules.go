@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Registration Email Domain Rules
+// ============================================================================
+
+// Sources of an email domain rule.
+const (
+	domainRuleSourceEnv   = "env"
+	domainRuleSourceAdmin = "admin"
+)
+
+// ListEmailDomainRules returns the registration domain rules in effect: those
+// from REGISTRATION_ALLOWED_DOMAINS / REGISTRATION_BLOCKED_DOMAINS followed by
+// those added through the admin API.
+func (s *AuthService) ListEmailDomainRules(ctx context.Context) ([]*models.EmailDomainRule, error) {
+	var rules []*models.EmailDomainRule
+	for _, entry := range []struct {
+		rule    string
+		domains []string
+	}{
+		{constants.DomainRuleAllow, s.cfg.RegistrationAllowedDomains},
+		{constants.DomainRuleBlock, s.cfg.RegistrationBlockedDomains},
+	} {
+		for _, domain := range entry.domains {
+			if domain = normalizeDomain(domain); domain != "" {
+				rules = append(rules, &models.EmailDomainRule{
+					Domain: domain,
+					Rule:   entry.rule,
+					Source: domainRuleSourceEnv,
+				})
+			}
+		}
+	}
+
+	stored, err := s.domainRules.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range stored {
+		rule.Source = domainRuleSourceAdmin
+	}
+
+	return append(rules, stored...), nil
+}
+
+// AddEmailDomainRule allows or blocks registrations from a domain. Adding a
+// rule for a domain that already has one replaces its type.
+func (s *AuthService) AddEmailDomainRule(ctx context.Context, req models.EmailDomainRuleRequest, actorID int64) (*models.EmailDomainRule, error) {
+	rule := &models.EmailDomainRule{
+		Domain:    normalizeDomain(req.Domain),
+		Rule:      req.Rule,
+		Source:    domainRuleSourceAdmin,
+		CreatedBy: &actorID,
+	}
+
+	if err := s.domainRules.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	logger.Info("email domain rule added", "domain", rule.Domain, "rule", rule.Rule, "actorID", actorID)
+	return rule, nil
+}
+
+// DeleteEmailDomainRule removes an admin-managed domain rule.
+func (s *AuthService) DeleteEmailDomainRule(ctx context.Context, id int64) error {
+	if err := s.domainRules.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDomainRuleNotFound
+		}
+		return err
+	}
+
+	logger.Info("email domain rule deleted", "ruleID", id)
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/email"
+	"authentio/pkg/logger"
+	"authentio/pkg/pagination"
+)
+
+// ============================================================================
+// Email Delivery Log
+// ============================================================================
+
+// emailStatuses lists the delivery states the log can be filtered by.
+var emailStatuses = map[string]bool{
+	constants.EmailQueued:    true,
+	constants.EmailSent:      true,
+	constants.EmailDelivered: true,
+	constants.EmailFailed:    true,
+	constants.EmailBounced:   true,
+	constants.EmailDropped:   true,
+}
+
+// sendEmail sends msg to recipients and records it in the delivery log as
+// triggered by event. The email matters more than its log entry, so logging
+// failures don't stop it.
+func (s *AuthService) sendEmail(ctx context.Context, event constants.EmailEvent, to []string, msg *email.Message) error {
+	_, err := s.deliverEmail(ctx, event, to, msg)
+	return err
+}
+
+// deliverEmail is sendEmail returning the ID of the email in the delivery
+// log. It is detached from ctx's cancellation: a client disconnecting once
+// its code was generated mustn't abort the email half-way, nor leave its log
+// entry queued.
+func (s *AuthService) deliverEmail(ctx context.Context, event constants.EmailEvent, to []string, msg *email.Message) (string, error) {
+	ctx = context.WithoutCancel(ctx)
+	messageID := email.NewMessageID()
+	if err := s.emailLogRepo.Create(ctx, messageID, string(event), msg.Subject, to); err != nil {
+		logger.Error("failed to log email", "error", err, "event", event)
+	}
+
+	if _, err := s.emailClient.Send(ctx, email.Mail{ID: messageID, To: to, Subject: msg.Subject, Body: msg.HTML, FromName: msg.FromName, FromEmail: msg.FromEmail}); err != nil {
+		if logErr := s.emailLogRepo.MarkFailed(ctx, messageID, err.Error()); logErr != nil {
+			logger.Error("failed to log email failure", "error", logErr, "messageID", messageID)
+		}
+		return messageID, err
+	}
+	return messageID, nil
+}
+
+// EmailLog returns a page of the tenant's email delivery records matching
+// filter, newest first.
+func (s *AuthService) EmailLog(ctx context.Context, filter models.EmailLogFilter, page pagination.Request) (pagination.Page[*models.EmailLog], error) {
+	if filter.Status != "" && !emailStatuses[filter.Status] {
+		return pagination.Page[*models.EmailLog]{}, ErrInvalidEmailStatus
+	}
+
+	entries, err := s.emailLogRepo.List(ctx, filter, page.After, page.Fetch())
+	if err != nil {
+		return pagination.Page[*models.EmailLog]{}, err
+	}
+
+	return pagination.NewPage(entries, page, emailLogCursor, func(entry *models.EmailLog) *models.EmailLog {
+		return entry
+	}), nil
+}
+
+// RecordSendGridEvents verifies a SendGrid event webhook payload and applies
+// its delivery and engagement events to the log. It returns how many events
+// were applied.
+func (s *AuthService) RecordSendGridEvents(ctx context.Context, payload []byte, signature, timestamp string) (int, error) {
+	if s.emailWebhook == nil {
+		return 0, ErrEmailWebhookDisabled
+	}
+
+	events, err := s.emailWebhook.Events(payload, signature, timestamp)
+	if errors.Is(err, email.ErrInvalidWebhookSignature) {
+		return 0, ErrInvalidEmailWebhook
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if err := s.emailLogRepo.RecordEvent(ctx, event); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+// SendTestEmail sends a test email to req.To through the configured provider,
+// so admins can check delivery. The provider's error is returned in
+// ErrEmailTestFailed's detail. With the delivery queue the email is only
+// queued: its outcome shows in the delivery log.
+func (s *AuthService) SendTestEmail(ctx context.Context, actorID int64, req models.EmailTestRequest) (*models.EmailTestResult, error) {
+	actor, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := s.emailRender.EmailTest(ctx, actor.Email, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	messageID, err := s.deliverEmail(ctx, constants.EmailTest, []string{req.To}, msg)
+	s.recordAudit(ctx, &actorID, constants.AuditEmailTestSent, map[string]interface{}{"to": req.To, "sent": err == nil})
+	if err != nil {
+		logger.Warn("test email failed", "error", err, "to", req.To, "actorID", actorID)
+		return nil, &DetailError{ServiceError: ErrEmailTestFailed, Detail: err.Error()}
+	}
+
+	queued := false
+	switch s.emailClient.(type) {
+	case *email.Queue, *email.AsyncSender:
+		queued = true
+	}
+	return &models.EmailTestResult{MessageID: messageID, Queued: queued}, nil
+}
+
+// emailLogCursor is the position of a record in email log listings.
+func emailLogCursor(entry *models.EmailLog) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: entry.CreatedAt, ID: entry.ID}
+}
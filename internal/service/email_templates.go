@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/email"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Email Templates
+// ============================================================================
+
+// EmailTemplates lists every email template with the saved version in use,
+// if any.
+func (s *AuthService) EmailTemplates(ctx context.Context) ([]models.EmailTemplateSummary, error) {
+	active, err := s.emailTemplates.ListActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.EmailTemplate, len(active))
+	for _, tmpl := range active {
+		byName[tmpl.Name] = tmpl
+	}
+
+	names := email.TemplateNames()
+	summaries := make([]models.EmailTemplateSummary, 0, len(names))
+	for _, name := range names {
+		summary := models.EmailTemplateSummary{Name: name}
+		if tmpl, ok := byName[name]; ok {
+			summary.Version = tmpl.Version
+			summary.UpdatedAt = &tmpl.CreatedAt
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// EmailTemplate returns a template's embedded default and saved versions.
+func (s *AuthService) EmailTemplate(ctx context.Context, name string) (*models.EmailTemplateDetails, error) {
+	source, err := s.emailRender.DefaultTemplate(name)
+	if err != nil {
+		return nil, ErrEmailTemplateNotFound
+	}
+
+	versions, err := s.emailTemplates.Versions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []*models.EmailTemplate{}
+	}
+
+	return &models.EmailTemplateDetails{
+		Name:           name,
+		DefaultSubject: source.Subject,
+		DefaultContent: source.Content,
+		Versions:       versions,
+	}, nil
+}
+
+// SaveEmailTemplate saves a new version of a template and puts it in use.
+// The version must render with sample data.
+func (s *AuthService) SaveEmailTemplate(ctx context.Context, name string, req models.EmailTemplateRequest, actorID int64) (*models.EmailTemplate, error) {
+	source := email.TemplateSource{Subject: req.Subject, Content: req.Content}
+	if _, err := s.emailRender.Preview(ctx, name, &source); err != nil {
+		return nil, emailTemplateError(err)
+	}
+
+	tmpl := &models.EmailTemplate{
+		Name:      name,
+		Subject:   req.Subject,
+		Content:   req.Content,
+		CreatedBy: &actorID,
+	}
+	if err := s.emailTemplates.Create(ctx, tmpl); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditEmailTemplateChanged, map[string]interface{}{"template": name, "action": "saved", "version": tmpl.Version})
+	logger.Info("email template saved", "template", name, "version", tmpl.Version, "actorID", actorID)
+	return tmpl, nil
+}
+
+// ActivateEmailTemplate puts a saved version of a template back in use, e.g.
+// to roll back an edit.
+func (s *AuthService) ActivateEmailTemplate(ctx context.Context, name string, version int, actorID int64) (*models.EmailTemplate, error) {
+	if _, err := s.emailRender.DefaultTemplate(name); err != nil {
+		return nil, ErrEmailTemplateNotFound
+	}
+
+	tmpl, err := s.emailTemplates.Activate(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		return nil, ErrNoSuchTemplateVersion
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditEmailTemplateChanged, map[string]interface{}{"template": name, "action": "activated", "version": version})
+	logger.Info("email template version activated", "template", name, "version", version, "actorID", actorID)
+	return tmpl, nil
+}
+
+// ResetEmailTemplate puts a template's embedded default back in use. Saved
+// versions are kept.
+func (s *AuthService) ResetEmailTemplate(ctx context.Context, name string, actorID int64) error {
+	if _, err := s.emailRender.DefaultTemplate(name); err != nil {
+		return ErrEmailTemplateNotFound
+	}
+
+	if err := s.emailTemplates.Deactivate(ctx, name); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditEmailTemplateChanged, map[string]interface{}{"template": name, "action": "reset"})
+	logger.Info("email template reset to default", "template", name, "actorID", actorID)
+	return nil
+}
+
+// PreviewEmailTemplate renders a template with sample data. Fields of req
+// that are set replace those of the version in use.
+func (s *AuthService) PreviewEmailTemplate(ctx context.Context, name string, req models.EmailTemplatePreviewRequest) (*email.Message, error) {
+	if req.Subject == "" && req.Content == "" {
+		msg, err := s.emailRender.Preview(ctx, name, nil)
+		if err != nil {
+			return nil, emailTemplateError(err)
+		}
+		return msg, nil
+	}
+
+	draft, err := s.emailRender.DefaultTemplate(name)
+	if err != nil {
+		return nil, ErrEmailTemplateNotFound
+	}
+	stored, err := s.emailTemplates.ActiveTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		draft = stored.TemplateSource
+	}
+	if req.Subject != "" {
+		draft.Subject = req.Subject
+	}
+	if req.Content != "" {
+		draft.Content = req.Content
+	}
+
+	msg, err := s.emailRender.Preview(ctx, name, &draft)
+	if err != nil {
+		return nil, emailTemplateError(err)
+	}
+	return msg, nil
+}
+
+// emailTemplateError converts renderer errors to service errors.
+func emailTemplateError(err error) error {
+	switch {
+	case errors.Is(err, email.ErrUnknownTemplate):
+		return ErrEmailTemplateNotFound
+	case errors.Is(err, email.ErrInvalidTemplate):
+		detail := strings.TrimPrefix(err.Error(), email.ErrInvalidTemplate.Error()+": ")
+		return &DetailError{ServiceError: ErrInvalidEmailTemplate, Detail: detail}
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+package service
+
+import "time"
+
+// ServiceError is a user-facing service error with a stable, machine-readable
+// code. Handlers return the code to clients and use it to look up a
+// translated message ("error.<code>" in the i18n catalogs); Message is the
+// English fallback and what Error() returns.
+type ServiceError struct {
+	Code    string
+	Message string
+}
+
+// Error implements the error interface.
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+// newError creates a new ServiceError sentinel.
+func newError(code, message string) *ServiceError {
+	return &ServiceError{Code: code, Message: message}
+}
+
+// OAuthError is an OAuth 2.0 protocol error returned by the OpenID Connect
+// provider. Code is one of the error codes defined by RFC 6749 (e.g.
+// "invalid_grant") and is sent to clients as-is with Description.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+// Error implements the error interface.
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// RetryAfterError is a ServiceError for a request that will succeed if
+// retried after RetryAfter. It unwraps to the ServiceError sentinel.
+type RetryAfterError struct {
+	*ServiceError
+	RetryAfter time.Duration
+}
+
+// Unwrap returns the ServiceError sentinel.
+func (e *RetryAfterError) Unwrap() error {
+	return e.ServiceError
+}
+
+// DetailError is a ServiceError with details about this occurrence, e.g. the
+// parse error of an invalid template. It unwraps to the ServiceError sentinel.
+type DetailError struct {
+	*ServiceError
+	Detail string
+}
+
+// Unwrap returns the ServiceError sentinel.
+func (e *DetailError) Unwrap() error {
+	return e.ServiceError
+}
+
+// Error returns the message followed by the details.
+func (e *DetailError) Error() string {
+	return e.Message + ": " + e.Detail
+}
+
+// oauthError creates an OAuthError.
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// Sentinel errors returned by the service layer. Compare with errors.Is.
+var (
+	ErrTermsNotAccepted      = newError("terms_not_accepted", "terms of service must be accepted")
+	ErrEmailExists           = newError("email_exists", "email already exists")
+	ErrDisposableEmail       = newError("disposable_email", "disposable email addresses are not allowed")
+	ErrEmailDomainBlocked    = newError("email_domain_not_allowed", "registrations from this email domain are not allowed")
+	ErrDomainRuleNotFound    = newError("domain_rule_not_found", "email domain rule not found")
+	ErrInviteRequired        = newError("invite_required", "registration requires an invitation")
+	ErrInvalidInvite         = newError("invalid_invite", "invalid, expired or already used invitation")
+	ErrInviteNotPermitted    = newError("invite_not_permitted", "you are not allowed to send invitations")
+	ErrInvitationNotFound    = newError("invitation_not_found", "invitation not found")
+	ErrAccountPending        = newError("account_pending_approval", "your account is awaiting administrator approval")
+	ErrAccountRejected       = newError("account_rejected", "your registration was not approved")
+	ErrUserNotPending        = newError("user_not_pending", "user is not awaiting approval")
+	ErrOrgNotFound           = newError("organization_not_found", "organization not found")
+	ErrOrgSlugTaken          = newError("organization_slug_taken", "organization slug is already taken")
+	ErrOrgPermission         = newError("organization_permission_denied", "you don't have permission to manage this organization")
+	ErrOrgMemberNotFound     = newError("organization_member_not_found", "organization member not found")
+	ErrAlreadyOrgMember      = newError("already_organization_member", "user is already a member of this organization")
+	ErrLastOrgOwner          = newError("last_organization_owner", "the last owner can't leave the organization")
+	ErrInvalidCredentials    = newError("invalid_credentials", "invalid email or password")
+	ErrInvalidGoogleToken    = newError("invalid_google_token", "invalid Google token")
+	ErrGoogleUnavailable     = newError("google_unavailable", "Google sign-in is temporarily unavailable")
+	ErrFederationDisabled    = newError("federation_disabled", "sign-in with an external identity provider is not enabled")
+	ErrInvalidFederatedToken = newError("invalid_federated_token", "invalid or expired identity provider token")
+	ErrNoFederatedAccount    = newError("federated_account_not_found", "no account matches this identity")
+	ErrOAuthExchangeFailed   = newError("oauth_exchange_failed", "failed to exchange code")
+	ErrEmailSendFailed       = newError("email_send_failed", "failed to send email")
+	ErrInvalidResetCode      = newError("invalid_reset_code", "invalid or expired reset code")
+	ErrInvalidOTP            = newError("invalid_otp", "invalid or expired code")
+	ErrInvalidRefreshToken   = newError("invalid_refresh_token", "invalid refresh token")
+	ErrSessionMaxAge         = newError("session_max_age", "the session has reached its maximum lifetime; sign in again")
+	ErrSessionIdle           = newError("session_idle_timeout", "the session ended after a period of inactivity; sign in again")
+	ErrUserNotFound          = newError("user_not_found", "user not found")
+	ErrConsentOutdated       = newError("consent_outdated", "outdated document versions")
+	ErrQuotaExceeded         = newError("tenant_quota_exceeded", "daily quota exceeded, try again tomorrow")
+	ErrQuotasUnavailable     = newError("quotas_unavailable", "quota tracking is unavailable")
+	ErrOTPCooldown           = newError("otp_cooldown", "a code was sent recently, please wait before requesting another")
+	ErrOTPDailyLimit         = newError("otp_daily_limit", "too many codes requested today, try again tomorrow")
+	ErrRegistrationIPLimit   = newError("registration_ip_limit", "too many accounts registered from this network, try again later")
+	ErrRegistrationFlood     = newError("registration_email_flood", "too many accounts registered for this email address, try again later")
+	ErrRegistrationBlocked   = newError("registration_blocked", "registration is temporarily blocked, try again later")
+	ErrOIDCDisabled          = newError("oidc_disabled", "OpenID Connect provider mode is disabled")
+	ErrOAuthClientNotFound   = newError("oauth_client_not_found", "OAuth client not found")
+	ErrInvalidRedirectURI    = newError("invalid_redirect_uri", "redirect URI is not registered for this client")
+	ErrOAuthGrantNotFound    = newError("oauth_grant_not_found", "no authorization found for this client")
+	ErrInvalidAccessToken    = newError("invalid_access_token", "invalid or expired access token")
+	ErrTokenOutdated         = newError("token_outdated", "the account's privileges changed since this token was issued; refresh it")
+	ErrAvatarsDisabled       = newError("avatar_uploads_disabled", "avatar uploads are not enabled")
+	ErrInvalidImage          = newError("invalid_image", "the file must be a JPEG, PNG, GIF or WebP image")
+	ErrImageTooLarge         = newError("image_too_large", "the image is too large")
+	ErrMetadataTooLarge      = newError("metadata_too_large", "metadata must not exceed 8 KB")
+	ErrUsernameTaken         = newError("username_taken", "username is not available")
+	ErrInvalidUsername       = newError("invalid_username", "usernames are 3-30 letters, digits, underscores or dots and start with a letter")
+	ErrPushUnavailable       = newError("push_unavailable", "push notifications are not configured for this platform")
+	ErrNoPushDevice          = newError("no_push_device", "register a device before enabling push approval")
+	ErrLastPushDevice        = newError("last_push_device", "disable push approval before removing your last device")
+	ErrPushDeviceNotFound    = newError("push_device_not_found", "device not found")
+	ErrChallengeNotFound     = newError("push_challenge_not_found", "login approval request not found")
+	ErrChallengePending      = newError("push_approval_pending", "waiting for approval on your device")
+	ErrChallengeExpired      = newError("push_challenge_expired", "the login approval request expired, please log in again")
+	ErrLoginDenied           = newError("push_login_denied", "the login was denied on your device")
+	ErrNoPassword            = newError("no_password", "set a password first to confirm your identity")
+	ErrTwoFactorCodeRequired = newError("two_factor_code_required", "enter the code sent to your email to confirm your identity")
+	ErrInvalidChallengeToken = newError("invalid_challenge_token", "invalid or expired challenge token, please log in again")
+	ErrInvalidRecoveryCode   = newError("invalid_recovery_code", "invalid or already used recovery code")
+	ErrRecoveryNotFound      = newError("recovery_not_found", "recovery request not found or no longer pending")
+	ErrRecoveryPending       = newError("recovery_pending", "two-factor recovery is not available yet")
+	ErrDeviceNotFound        = newError("device_not_found", "device not found")
+	ErrLoginQuarantined      = newError("login_quarantined", "this login is on hold until you approve it with the link sent to your email")
+	ErrIPNotAllowed          = newError("ip_not_allowed", "this account can't be used from your IP address")
+	ErrIPAllowlistLockout    = newError("ip_allowlist_lockout", "this change would lock you out: your current IP address must stay allowed")
+	ErrIPEntryNotFound       = newError("ip_allowlist_entry_not_found", "allowlist entry not found")
+	ErrInvalidCIDR           = newError("invalid_cidr", "invalid IP address or CIDR block")
+	ErrDenylistEntryNotFound = newError("ip_denylist_entry_not_found", "denylist entry not found")
+	ErrIPDenylistLockout     = newError("ip_denylist_lockout", "this entry would deny your current IP address")
+	ErrDenylistForbidden     = newError("ip_denylist_forbidden", "the IP denylist can only be managed from the default tenant")
+	ErrLoginBlocked          = newError("login_blocked", "this login was blocked as too risky, please try again from a device you have used before")
+	ErrExportsDisabled       = newError("exports_disabled", "exports are not available")
+	ErrExportNotFound        = newError("export_not_found", "export not found or expired")
+	ErrExportNotReady        = newError("export_not_ready", "the export is not ready yet")
+	ErrInvalidExportFilter   = newError("invalid_export_filter", "created_from must be before created_to")
+	ErrInvalidDownloadLink   = newError("invalid_download_link", "invalid or expired download link")
+	ErrEmailWebhookDisabled  = newError("email_webhook_disabled", "email event webhooks are not configured")
+	ErrInvalidEmailWebhook   = newError("invalid_email_webhook", "invalid email event webhook signature")
+	ErrInvalidEmailStatus    = newError("invalid_email_status", "status must be queued, sent, delivered, failed, bounced or dropped")
+	ErrInvalidPhoneNumber    = newError("invalid_phone_number", "phone number must be in international format, e.g. +14155552671")
+	ErrPhoneNumberRequired   = newError("phone_number_required", "a phone number is required for text message notifications")
+	ErrEmailTemplateNotFound = newError("email_template_not_found", "email template not found")
+	ErrNoSuchTemplateVersion = newError("email_template_version_not_found", "email template version not found")
+	ErrInvalidEmailTemplate  = newError("invalid_email_template", "the email template can't be rendered")
+	ErrInvalidEmailBranding  = newError("invalid_email_branding", "the sender name must fit on one line")
+	ErrEmailTestFailed       = newError("email_test_failed", "the email provider didn't accept the test email")
+	ErrDuplicatesNotFound    = newError("duplicate_accounts_not_found", "duplicate account group not found")
+	ErrInvalidDuplicateState = newError("invalid_duplicate_status", "status must be open or dismissed")
+	ErrKeyRotationDisabled   = newError("key_rotation_unavailable", "rotating this key requires a master key (SECRETS_ENCRYPTION_KEY or SECRETS_USE_KMS) and the feature it signs for")
+	ErrInvalidKeyPurpose     = newError("invalid_key_purpose", "key must be jwt, oidc or secrets")
+	ErrKeyRotationRunning    = newError("key_rotation_running", "a rotation of this key is already running")
+	ErrKeysForbidden         = newError("keys_forbidden", "signing and encryption keys can only be managed from the default tenant")
+	ErrGeoBlocked            = newError("geo_blocked", "signing in from your location is not allowed")
+	ErrGeoPolicyLockout      = newError("geo_policy_lockout", "this policy would refuse the country you are connected from")
+	ErrRequestTimeout        = newError("request_timeout", "the request took too long, please try again")
+	ErrSchedulerForbidden    = newError("scheduler_forbidden", "the scheduler can only be inspected from the default tenant")
+	ErrAuditArchiveDisabled  = newError("audit_archive_disabled", "audit log archival is not configured")
+	ErrAuditArchiveForbidden = newError("audit_archive_forbidden", "audit logs can only be archived from the default tenant")
+	ErrInvalidArchiveRange   = newError("invalid_archive_range", "from must be before to")
+	ErrPairingUnavailable    = newError("pairing_unavailable", "QR code pairing is not available")
+	ErrPairingNotFound       = newError("pairing_not_found", "pairing code not found or expired")
+	ErrPairingExpired        = newError("pairing_expired", "the pairing code has expired, show a new one")
+	ErrPairingPending        = newError("pairing_pending", "waiting for the pairing to be approved in the app")
+	ErrPairingDenied         = newError("pairing_denied", "the pairing was denied in the app")
+	ErrInvalidPollToken      = newError("invalid_poll_token", "invalid poll token")
+	ErrSessionCookieMissing  = newError("session_cookie_missing", "no session cookie, sign in again")
+	ErrOriginNotAllowed      = newError("origin_not_allowed", "this origin may not use the session")
+)
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Federated Sign-In
+// ============================================================================
+
+// FederatedLogin signs in an existing user with a token issued by the
+// external identity provider (FEDERATED_ISSUER), verified against the keys
+// it publishes. The user is matched by the token's email, which the provider
+// must not report as unverified; accounts are never created this way.
+func (s *AuthService) FederatedLogin(ctx context.Context, token string) (*response.LoginResponse, error) {
+	if s.federation == nil {
+		return nil, ErrFederationDisabled
+	}
+
+	claims, err := s.federation.Verify(ctx, token)
+	if err != nil {
+		logger.Debug("federated token rejected", "error", err)
+		return nil, ErrInvalidFederatedToken
+	}
+
+	email, _ := claims["email"].(string)
+	if email == "" || !emailVerified(claims["email_verified"]) {
+		logger.Debug("federated token has no verified email")
+		return nil, ErrInvalidFederatedToken
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNoFederatedAccount
+	}
+	if err := checkApproval(user); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims.GetSubject()
+	metadata := map[string]interface{}{"method": "federated", "issuer": s.federation.Issuer(), "subject": subject}
+	if challenge, err := s.checkLoginRisk(ctx, user, "federated", metadata); challenge != nil || err != nil {
+		return challenge, err
+	}
+
+	s.loginSucceeded(ctx, user, "federated", metadata)
+
+	return s.generateAuthResponse(ctx, user, nil)
+}
+
+// emailVerified reports whether an "email_verified" claim doesn't deny the
+// email was verified. Some providers send it as a string.
+func emailVerified(claim interface{}) bool {
+	switch v := claim.(type) {
+	case bool:
+		return v
+	case string:
+		return !strings.EqualFold(v, "false")
+	}
+	return true
+}
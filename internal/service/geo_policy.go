@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Geo Policies
+// ============================================================================
+
+// geoPolicyRefresh is how long a tenant's geo policy is cached for the check
+// made on every authenticated request. Changes made through this instance
+// apply at once; other instances pick them up within this interval.
+const geoPolicyRefresh = 30 * time.Second
+
+// geoMinTravelDistance (km) is the distance from the previous sign-in below
+// which travel speed isn't checked: GeoIP only places addresses roughly, so
+// nearby sign-ins a few minutes apart would look impossibly fast.
+const geoMinTravelDistance = 300
+
+// earthRadius is the mean radius of the Earth in km.
+const earthRadius = 6371.0
+
+// cachedGeoPolicy is a tenant's geo policy as the checks need it.
+type cachedGeoPolicy struct {
+	policy  *models.GeoPolicy
+	expires time.Time
+}
+
+// geoPolicyCache caches geo policies by tenant. It is safe for concurrent
+// use.
+type geoPolicyCache struct {
+	mu      sync.Mutex
+	tenants map[int64]cachedGeoPolicy
+}
+
+func newGeoPolicyCache() *geoPolicyCache {
+	return &geoPolicyCache{tenants: make(map[int64]cachedGeoPolicy)}
+}
+
+func (c *geoPolicyCache) get(tenantID int64) (cachedGeoPolicy, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.tenants[tenantID]
+	return cached, ok
+}
+
+func (c *geoPolicyCache) set(tenantID int64, policy *models.GeoPolicy) {
+	c.mu.Lock()
+	c.tenants[tenantID] = cachedGeoPolicy{policy: policy, expires: time.Now().Add(geoPolicyRefresh)}
+	c.mu.Unlock()
+}
+
+// GeoPolicy returns the geographic sign-in rules of the tenant: its own, or
+// the deployment's defaults.
+func (s *AuthService) GeoPolicy(ctx context.Context) (*models.GeoPolicy, error) {
+	policy, err := s.tenantRepo.GeoPolicy(ctx, currentTenant(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		policy = s.defaultGeoPolicy()
+	}
+	return policy, nil
+}
+
+// UpdateGeoPolicy replaces the geographic sign-in rules of the tenant. An
+// admin can't refuse the country they are connected from.
+func (s *AuthService) UpdateGeoPolicy(ctx context.Context, actorID int64, req models.UpdateGeoPolicyRequest) (*models.GeoPolicy, error) {
+	policy := &models.GeoPolicy{
+		BlockedCountries:    normalizeCountries(req.BlockedCountries),
+		AllowedCountries:    normalizeCountries(req.AllowedCountries),
+		SuspiciousCountries: normalizeCountries(req.SuspiciousCountries),
+		SuspiciousAction:    req.SuspiciousAction,
+		MaxTravelSpeed:      req.MaxTravelSpeed,
+		VelocityAction:      req.VelocityAction,
+		Source:              "tenant",
+		UpdatedBy:           &actorID,
+	}
+	if policy.SuspiciousAction == "" {
+		policy.SuspiciousAction = constants.GeoActionLog
+	}
+	if policy.VelocityAction == "" {
+		policy.VelocityAction = constants.GeoActionStepUp
+	}
+	if countryRefused(policy, requestctx.ClientInfoFrom(ctx).Country) {
+		return nil, ErrGeoPolicyLockout
+	}
+
+	tenantID := currentTenant(ctx)
+	if err := s.tenantRepo.SaveGeoPolicy(ctx, tenantID, policy); err != nil {
+		return nil, err
+	}
+	s.geoPolicies.set(tenantID, policy)
+
+	s.recordAudit(ctx, &actorID, constants.AuditGeoPolicyChanged, map[string]interface{}{
+		"action":               "updated",
+		"blocked_countries":    policy.BlockedCountries,
+		"allowed_countries":    policy.AllowedCountries,
+		"suspicious_countries": policy.SuspiciousCountries,
+		"suspicious_action":    policy.SuspiciousAction,
+		"max_travel_speed_kmh": policy.MaxTravelSpeed,
+		"velocity_action":      policy.VelocityAction,
+	})
+	logger.Info("geo policy saved", "tenantID", tenantID, "actorID", actorID)
+	return policy, nil
+}
+
+// ResetGeoPolicy removes the tenant's geographic sign-in rules, so the
+// deployment's defaults apply. It returns them.
+func (s *AuthService) ResetGeoPolicy(ctx context.Context, actorID int64) (*models.GeoPolicy, error) {
+	policy := s.defaultGeoPolicy()
+	if countryRefused(policy, requestctx.ClientInfoFrom(ctx).Country) {
+		return nil, ErrGeoPolicyLockout
+	}
+
+	tenantID := currentTenant(ctx)
+	if err := s.tenantRepo.DeleteGeoPolicy(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	s.geoPolicies.set(tenantID, policy)
+
+	s.recordAudit(ctx, &actorID, constants.AuditGeoPolicyChanged, map[string]interface{}{"action": "reset"})
+	logger.Info("geo policy reset", "tenantID", tenantID, "actorID", actorID)
+	return policy, nil
+}
+
+// CountryAction applies the tenant's geo policy to an authenticated request
+// from country: constants.GeoActionBlock when the country is refused,
+// constants.GeoActionLog when it is suspicious, "" otherwise. Step-up and
+// impossible travel only apply when signing in.
+func (s *AuthService) CountryAction(ctx context.Context, country string) string {
+	policy := s.currentGeoPolicy(ctx)
+	switch {
+	case countryRefused(policy, country):
+		return constants.GeoActionBlock
+	case hasCountry(policy.SuspiciousCountries, country):
+		return constants.GeoActionLog
+	}
+	return ""
+}
+
+// checkGeoPolicy applies the tenant's geo policy to a sign-in by user that
+// passed the first factor with loginMethod. It returns true when the sign-in
+// needs a second factor, and ErrGeoBlocked when it is refused. Flags are
+// added to metadata for the sign-in's audit entry.
+func (s *AuthService) checkGeoPolicy(ctx context.Context, user *models.User, loginMethod string, metadata map[string]interface{}) (bool, error) {
+	client := requestctx.ClientInfoFrom(ctx)
+	policy := s.currentGeoPolicy(ctx)
+
+	action, reason := "", ""
+	switch {
+	case countryRefused(policy, client.Country):
+		action, reason = constants.GeoActionBlock, "country_refused"
+	case hasCountry(policy.SuspiciousCountries, client.Country):
+		action, reason = policy.SuspiciousAction, "suspicious_country"
+	}
+	if speed, ok := s.travelSpeed(ctx, user.ID, policy); ok {
+		metadata["travel_speed_kmh"] = speed
+		if geoActionRank(policy.VelocityAction) > geoActionRank(action) {
+			action, reason = policy.VelocityAction, "impossible_travel"
+		}
+	}
+	if action == "" {
+		return false, nil
+	}
+
+	logger.Warn("sign-in flagged by geo policy", "userID", user.ID, "country", client.Country, "reason", reason, "action", action)
+	if action == constants.GeoActionBlock {
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginBlocked, map[string]interface{}{"method": loginMethod, "reason": "geo_" + reason, "country": client.Country})
+		return false, ErrGeoBlocked
+	}
+	metadata["geo_flag"] = reason
+	return action == constants.GeoActionStepUp, nil
+}
+
+// travelSpeed returns the speed (km/h) the user would have travelled at
+// since their previous located sign-in, when it exceeds the policy's
+// maximum.
+func (s *AuthService) travelSpeed(ctx context.Context, userID int64, policy *models.GeoPolicy) (int, bool) {
+	client := requestctx.ClientInfoFrom(ctx)
+	if policy.MaxTravelSpeed <= 0 || !client.Located {
+		return 0, false
+	}
+
+	entries, err := s.auditRepo.ListByUser(ctx, userID, []string{string(constants.AuditLoginSuccess)}, nil, riskHistorySize)
+	if err != nil {
+		logger.Error("failed to load login history", "error", err, "userID", userID)
+		return 0, false
+	}
+	for _, entry := range entries {
+		location, _ := entry.Metadata["location"].(map[string]interface{})
+		lat, latOK := location["lat"].(float64)
+		lon, lonOK := location["lon"].(float64)
+		if !latOK || !lonOK {
+			continue
+		}
+
+		distance := distanceKm(lat, lon, client.Latitude, client.Longitude)
+		if distance < geoMinTravelDistance {
+			return 0, false
+		}
+		hours := math.Max(time.Since(entry.CreatedAt).Hours(), 1.0/60)
+		speed := int(distance / hours)
+		return speed, speed > policy.MaxTravelSpeed
+	}
+	return 0, false
+}
+
+// currentGeoPolicy returns the tenant's geo policy, cached for
+// geoPolicyRefresh. If it can't be loaded the last known one applies, and
+// without one the deployment's defaults.
+func (s *AuthService) currentGeoPolicy(ctx context.Context) *models.GeoPolicy {
+	tenantID := currentTenant(ctx)
+	cached, ok := s.geoPolicies.get(tenantID)
+	if ok && time.Now().Before(cached.expires) {
+		return cached.policy
+	}
+
+	policy, err := s.tenantRepo.GeoPolicy(ctx, tenantID)
+	if err != nil {
+		logger.Error("failed to load geo policy", "error", err, "tenantID", tenantID)
+		if ok {
+			return cached.policy
+		}
+		return s.defaultGeoPolicy()
+	}
+	if policy == nil {
+		policy = s.defaultGeoPolicy()
+	}
+	s.geoPolicies.set(tenantID, policy)
+	return policy
+}
+
+// defaultGeoPolicy returns the deployment's geo policy, from the
+// configuration.
+func (s *AuthService) defaultGeoPolicy() *models.GeoPolicy {
+	return &models.GeoPolicy{
+		BlockedCountries:    normalizeCountries(s.cfg.BlockedCountries),
+		AllowedCountries:    normalizeCountries(s.cfg.AllowedCountries),
+		SuspiciousCountries: normalizeCountries(s.cfg.SuspiciousCountries),
+		SuspiciousAction:    s.cfg.GeoSuspiciousAction,
+		MaxTravelSpeed:      s.cfg.GeoMaxTravelSpeed,
+		VelocityAction:      s.cfg.GeoVelocityAction,
+		Source:              "config",
+	}
+}
+
+// countryRefused reports whether policy refuses country: it is blocked, or
+// not among the allowed countries when there are some. Clients GeoIP
+// couldn't place are never refused.
+func countryRefused(policy *models.GeoPolicy, country string) bool {
+	switch country {
+	case "", constants.CountryLocal, constants.CountryUnknown:
+		return false
+	}
+	return hasCountry(policy.BlockedCountries, country) ||
+		(len(policy.AllowedCountries) > 0 && !hasCountry(policy.AllowedCountries, country))
+}
+
+func hasCountry(countries []string, country string) bool {
+	for _, c := range countries {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeCountries upper-cases country codes, dropping blanks and
+// duplicates, sorted.
+func normalizeCountries(countries []string) []string {
+	seen := make(map[string]bool, len(countries))
+	normalized := []string{}
+	for _, country := range countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if country != "" && !seen[country] {
+			seen[country] = true
+			normalized = append(normalized, country)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// geoActionRank orders actions by severity, so the strictest applies when a
+// sign-in breaks several rules.
+func geoActionRank(action string) int {
+	switch action {
+	case constants.GeoActionLog:
+		return 1
+	case constants.GeoActionStepUp:
+		return 2
+	case constants.GeoActionBlock:
+		return 3
+	}
+	return 0
+}
+
+// distanceKm returns the great-circle distance between two points.
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLon := toRad(lat2-lat1), toRad(lon2-lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
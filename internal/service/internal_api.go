@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/jwt"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Internal Service API
+// ============================================================================
+
+// These methods back the gRPC API used by internal services. Like the HTTP
+// handlers they act on the tenant stored in ctx.
+
+// VerifyAccessToken validates an access token issued to a user of the current
+// tenant and returns its claims.
+func (s *AuthService) VerifyAccessToken(ctx context.Context, token string) (*response.TokenInfo, error) {
+	tenantID := currentTenant(ctx)
+
+	claims, err := s.jwtManager.VerifyTenantToken(token, tenantID)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrInvalidAccessToken
+	}
+
+	// Tokens without the claim predate tenancy and belong to the default tenant
+	tokenTenant := constants.DefaultTenantID
+	if id, ok := claims["tenant_id"].(float64); ok {
+		tokenTenant = int64(id)
+	}
+	if tokenTenant != tenantID {
+		return nil, ErrInvalidAccessToken
+	}
+	// Tokens issued before the user's privileges changed must be refreshed
+	if !s.TokenVersionCurrent(ctx, int64(userID), jwt.ClaimedVersion(claims)) {
+		return nil, ErrTokenOutdated
+	}
+
+	info := &response.TokenInfo{
+		UserID:   int64(userID),
+		TenantID: tokenTenant,
+	}
+	info.Email, _ = claims["email"].(string)
+	info.Role, _ = claims["role"].(string)
+	info.OrgRole, _ = claims["org_role"].(string)
+	if orgID, ok := claims["org_id"].(float64); ok {
+		info.OrgID = int64(orgID)
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		info.ExpiresAt = exp.Time
+	}
+
+	return info, nil
+}
+
+// LookupUser finds a user of the current tenant by ID or, when userID is 0,
+// by email.
+func (s *AuthService) LookupUser(ctx context.Context, userID int64, email string) (*response.UserResponse, error) {
+	var (
+		user *models.User
+		err  error
+	)
+	switch {
+	case userID != 0:
+		user, err = s.userRepo.FindByID(ctx, userID)
+	case email != "":
+		user, err = s.userRepo.FindByEmail(ctx, email)
+	}
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	userResponse := newUserResponse(user)
+	return &userResponse, nil
+}
+
+// TwoFactorStatus reports whether a user of the current tenant has 2FA enabled.
+func (s *AuthService) TwoFactorStatus(ctx context.Context, userID int64) (bool, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return false, ErrUserNotFound
+	}
+
+	return s.twoFARepo.Is2FAEnabled(ctx, user.ID)
+}
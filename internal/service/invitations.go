@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Registration Invitations
+// ============================================================================
+
+// CreateInvitation invites email to register and sends the invite link. Admins
+// can always invite; other users only when USERS_CAN_INVITE is enabled.
+func (s *AuthService) CreateInvitation(ctx context.Context, actorID int64, actorRole string, req models.InvitationRequest) (*models.Invitation, error) {
+	if actorRole != constants.RoleAdmin && !s.cfg.UsersCanInvite {
+		return nil, ErrInviteNotPermitted
+	}
+
+	inviter, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil || inviter == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if existing, _ := s.userRepo.FindByEmail(ctx, req.Email); existing != nil {
+		return nil, ErrEmailExists
+	}
+
+	token := generateSecureToken()
+	invitation := &models.Invitation{
+		Email:     strings.TrimSpace(req.Email),
+		TokenHash: hashInviteToken(token),
+		InvitedBy: &actorID,
+		ExpiresAt: time.Now().Add(s.cfg.InvitationTTL),
+	}
+	if err := s.inviteRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+	invitation.Status = constants.InvitationPending
+
+	link := strings.TrimRight(s.cfg.FrontendURL, "/") + "/register?invite=" + url.QueryEscape(token)
+	inviterName := strings.TrimSpace(inviter.FirstName + " " + inviter.LastName)
+	msg, err := s.emailRender.Invitation(ctx, inviterName, link, invitation.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sendEmail(ctx, constants.EmailInvitation, []string{invitation.Email}, msg); err != nil {
+		logger.Error("failed to send invitation email", "error", err, "invitationID", invitation.ID)
+		return nil, ErrEmailSendFailed
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditInviteCreated, map[string]interface{}{
+		"invitation_id": invitation.ID,
+		"email":         invitation.Email,
+	})
+
+	logger.Info("invitation sent", "invitationID", invitation.ID, "invitedBy", actorID)
+	return invitation, nil
+}
+
+// ListInvitations returns every invitation for admins and the caller's own
+// invitations for other users, newest first.
+func (s *AuthService) ListInvitations(ctx context.Context, actorID int64, actorRole string) ([]*models.Invitation, error) {
+	var invitedBy *int64
+	if actorRole != constants.RoleAdmin {
+		invitedBy = &actorID
+	}
+
+	invitations, err := s.inviteRepo.List(ctx, invitedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, invitation := range invitations {
+		invitation.Status = invitation.CurrentStatus(now)
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation cancels a pending invitation. Users can revoke their own
+// invitations; admins can revoke any.
+func (s *AuthService) RevokeInvitation(ctx context.Context, id, actorID int64, actorRole string) error {
+	invitation, err := s.inviteRepo.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Don't reveal other users' invitations
+	if invitation == nil || (actorRole != constants.RoleAdmin && (invitation.InvitedBy == nil || *invitation.InvitedBy != actorID)) {
+		return ErrInvitationNotFound
+	}
+	if invitation.CurrentStatus(time.Now()) != constants.InvitationPending {
+		return ErrInvalidInvite
+	}
+
+	if err := s.inviteRepo.Revoke(ctx, id); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditInviteRevoked, map[string]interface{}{"invitation_id": id})
+
+	logger.Info("invitation revoked", "invitationID", id, "actorID", actorID)
+	return nil
+}
+
+// resolveInvitation validates an invite token for a registration with email.
+// It returns nil, nil when no token is given and invitations are optional.
+func (s *AuthService) resolveInvitation(ctx context.Context, token, email string) (*models.Invitation, error) {
+	if token == "" {
+		if s.cfg.InviteOnlyRegistration {
+			return nil, ErrInviteRequired
+		}
+		return nil, nil
+	}
+
+	invitation, err := s.inviteRepo.FindByTokenHash(ctx, hashInviteToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil ||
+		invitation.CurrentStatus(time.Now()) != constants.InvitationPending ||
+		!strings.EqualFold(invitation.Email, strings.TrimSpace(email)) {
+		return nil, ErrInvalidInvite
+	}
+
+	return invitation, nil
+}
+
+// acceptInvitation marks invitation as used by user. Failures are logged: the
+// account already exists at this point.
+func (s *AuthService) acceptInvitation(ctx context.Context, invitation *models.Invitation, user *models.User) {
+	if invitation == nil {
+		return
+	}
+	if err := s.inviteRepo.MarkAccepted(ctx, invitation.ID, user.ID); err != nil {
+		logger.Error("failed to mark invitation accepted", "error", err, "invitationID", invitation.ID, "userID", user.ID)
+	}
+}
+
+// hashInviteToken returns the SHA-256 hex digest stored for an invite token.
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/netip"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// IP Allowlist
+// ============================================================================
+
+// ipAllowlistCacheTTL is how long an account's allowlist is cached for the
+// check made on every authenticated request. Changes made through this
+// instance apply at once; other instances pick them up within the TTL.
+const ipAllowlistCacheTTL = time.Minute
+
+// allowlistState is what the access check needs of an account's allowlist.
+type allowlistState struct {
+	restricted bool           // the account has a permanent entry
+	prefixes   []netip.Prefix // every unexpired entry
+	expires    time.Time
+}
+
+// allows reports whether the account may be used from ip.
+func (a allowlistState) allows(ip string) bool {
+	if !a.restricted {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range a.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIPAllowlist returns the user's allowlist mode and unexpired entries.
+func (s *AuthService) GetIPAllowlist(ctx context.Context, userID int64) (*models.IPAllowlist, error) {
+	mode, err := s.ipAllowlistRepo.GetMode(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.ipAllowlistRepo.ListEntries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.IPAllowlistEntry{}
+	}
+	return &models.IPAllowlist{Mode: mode, Entries: entries}, nil
+}
+
+// AddIPAllowlistEntry allows an address or CIDR block for userID. actorID is
+// the user or an admin; users can't make a change that would refuse their
+// current address.
+func (s *AuthService) AddIPAllowlistEntry(ctx context.Context, userID, actorID int64, req models.AddIPAllowlistEntryRequest) (*models.IPAllowlistEntry, error) {
+	prefix, err := parseIPBlock(req.CIDR)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.GetIPAllowlist(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.IPAllowlistEntry{
+		UserID:    userID,
+		CIDR:      prefix.String(),
+		Label:     req.Label,
+		CreatedBy: &actorID,
+	}
+	if actorID == userID {
+		if err := checkAllowlistLockout(ctx, list.Mode, append(list.Entries, *entry)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.ipAllowlistRepo.SaveEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	s.allowlists.forget(userID)
+
+	s.recordAudit(ctx, &actorID, constants.AuditIPAllowlistChanged, map[string]interface{}{"user_id": userID, "action": "added", "cidr": entry.CIDR})
+	logger.Info("IP allowlist entry added", "userID", userID, "cidr", entry.CIDR)
+	return entry, nil
+}
+
+// RemoveIPAllowlistEntry removes one of the user's entries. Removing the last
+// permanent entry lifts the restriction.
+func (s *AuthService) RemoveIPAllowlistEntry(ctx context.Context, userID, actorID, entryID int64) error {
+	list, err := s.GetIPAllowlist(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var removed *models.IPAllowlistEntry
+	remaining := make([]models.IPAllowlistEntry, 0, len(list.Entries))
+	for i := range list.Entries {
+		if list.Entries[i].ID == entryID {
+			removed = &list.Entries[i]
+			continue
+		}
+		remaining = append(remaining, list.Entries[i])
+	}
+	if removed == nil {
+		return ErrIPEntryNotFound
+	}
+	if actorID == userID {
+		if err := checkAllowlistLockout(ctx, list.Mode, remaining); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ipAllowlistRepo.DeleteEntry(ctx, userID, entryID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrIPEntryNotFound
+		}
+		return err
+	}
+	s.allowlists.forget(userID)
+
+	s.recordAudit(ctx, &actorID, constants.AuditIPAllowlistChanged, map[string]interface{}{"user_id": userID, "action": "removed", "cidr": removed.CIDR})
+	return nil
+}
+
+// SetIPAllowlistMode sets whether logins from addresses outside the user's
+// allowlist are refused or need a second factor (see constants.IPAllowlist*).
+func (s *AuthService) SetIPAllowlistMode(ctx context.Context, userID, actorID int64, mode string) error {
+	list, err := s.GetIPAllowlist(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if actorID == userID {
+		if err := checkAllowlistLockout(ctx, mode, list.Entries); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ipAllowlistRepo.SetMode(ctx, userID, mode); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	s.allowlists.forget(userID)
+
+	s.recordAudit(ctx, &actorID, constants.AuditIPAllowlistChanged, map[string]interface{}{"user_id": userID, "action": "mode", "mode": mode})
+	return nil
+}
+
+// AllowsIP reports whether userID's account may be used from ip: accounts
+// without a permanent allowlist entry may be used from anywhere. It is
+// called on every authenticated request, so allowlists are cached. If one
+// can't be loaded the last known one applies, and without one access is
+// refused.
+func (s *AuthService) AllowsIP(ctx context.Context, userID int64, ip string) bool {
+	state, fresh, ok := s.allowlists.get(userID)
+	if !fresh {
+		loaded, err := s.loadAllowlist(ctx, userID)
+		if err != nil {
+			logger.Error("failed to load IP allowlist", "error", err, "userID", userID)
+			if ok {
+				// The last known allowlist applies until the next try
+				s.allowlists.set(userID, state, time.Now().Add(ipAllowlistCacheTTL))
+			}
+			return ok && state.allows(ip)
+		}
+		state = loaded
+		s.allowlists.set(userID, state, state.expires)
+	}
+	return state.allows(ip)
+}
+
+// loadAllowlist reads userID's allowlist. The state expires with the cache or
+// its first step-up entry, whichever comes first.
+func (s *AuthService) loadAllowlist(ctx context.Context, userID int64) (allowlistState, error) {
+	entries, err := s.ipAllowlistRepo.ListEntries(ctx, userID)
+	if err != nil {
+		return allowlistState{}, err
+	}
+
+	state := allowlistState{expires: time.Now().Add(ipAllowlistCacheTTL)}
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.CIDR)
+		if err != nil {
+			logger.Warn("invalid IP allowlist entry", "error", err, "entryID", entry.ID)
+			continue
+		}
+		state.prefixes = append(state.prefixes, prefix)
+		if entry.ExpiresAt == nil {
+			state.restricted = true
+		} else if entry.ExpiresAt.Before(state.expires) {
+			state.expires = *entry.ExpiresAt
+		}
+	}
+	return state, nil
+}
+
+// checkIPAllowlist applies user's allowlist to a login that passed the first
+// factor. It returns ErrIPNotAllowed when the address is refused, and true
+// when the login needs step-up verification.
+func (s *AuthService) checkIPAllowlist(ctx context.Context, user *models.User, loginMethod string) (bool, error) {
+	if s.AllowsIP(ctx, user.ID, requestctx.ClientInfoFrom(ctx).IP) {
+		return false, nil
+	}
+
+	mode, err := s.ipAllowlistRepo.GetMode(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	if mode == constants.IPAllowlistVerify {
+		return true, nil
+	}
+
+	s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"method": loginMethod, "reason": "ip_not_allowed"})
+	return false, ErrIPNotAllowed
+}
+
+// allowVerifiedIP allows the client's address for IP_ALLOWLIST_STEP_UP_TTL
+// after userID passed a second factor from outside their allowlist in verify
+// mode. Failures are logged.
+func (s *AuthService) allowVerifiedIP(ctx context.Context, userID int64) {
+	ip := requestctx.ClientInfoFrom(ctx).IP
+	if s.AllowsIP(ctx, userID, ip) {
+		return
+	}
+	mode, err := s.ipAllowlistRepo.GetMode(ctx, userID)
+	if err != nil || mode != constants.IPAllowlistVerify {
+		return
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return
+	}
+	addr = addr.Unmap()
+
+	expiresAt := time.Now().Add(s.cfg.IPAllowlistStepUpTTL)
+	entry := &models.IPAllowlistEntry{
+		UserID:    userID,
+		CIDR:      netip.PrefixFrom(addr, addr.BitLen()).String(),
+		Label:     "Verified login",
+		CreatedBy: &userID,
+		ExpiresAt: &expiresAt,
+	}
+	if err := s.ipAllowlistRepo.SaveEntry(ctx, entry); err != nil {
+		logger.Error("failed to allow verified IP address", "error", err, "userID", userID)
+		return
+	}
+	s.allowlists.forget(userID)
+}
+
+// checkAllowlistLockout returns ErrIPAllowlistLockout if, with mode and
+// entries, the caller's current address would be refused.
+func checkAllowlistLockout(ctx context.Context, mode string, entries []models.IPAllowlistEntry) error {
+	if mode != constants.IPAllowlistBlock {
+		return nil
+	}
+
+	var state allowlistState
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.CIDR)
+		if err != nil {
+			continue
+		}
+		state.prefixes = append(state.prefixes, prefix)
+		if entry.ExpiresAt == nil {
+			state.restricted = true
+		}
+	}
+	if !state.allows(requestctx.ClientInfoFrom(ctx).IP) {
+		return ErrIPAllowlistLockout
+	}
+	return nil
+}
+
+// parseIPBlock parses an address or CIDR block; addresses become a
+// single-address block and host bits are cleared.
+func parseIPBlock(value string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(value); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, ErrInvalidCIDR
+	}
+	return prefix.Masked(), nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Global IP Denylist
+// ============================================================================
+
+// ipDenylistRefresh is how often the denylist checked on every request is
+// reloaded. Changes made through this instance apply at once; other
+// instances pick them up within this interval.
+const ipDenylistRefresh = 30 * time.Second
+
+// deniedPrefix is a denylist entry as the request check needs it.
+type deniedPrefix struct {
+	prefix    netip.Prefix
+	expiresAt *time.Time
+}
+
+// ipDenylistCache holds the loaded denylist. It is safe for concurrent use.
+type ipDenylistCache struct {
+	mu       sync.RWMutex
+	prefixes []deniedPrefix
+	loadedAt time.Time
+
+	reload sync.Mutex // held while reloading, so requests reload once
+}
+
+// stale reports whether the denylist must be reloaded.
+func (c *ipDenylistCache) stale() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return time.Since(c.loadedAt) > ipDenylistRefresh
+}
+
+// set replaces the denylist. A nil slice keeps the current one, only
+// postponing the next reload.
+func (c *ipDenylistCache) set(prefixes []deniedPrefix) {
+	c.mu.Lock()
+	if prefixes != nil {
+		c.prefixes = prefixes
+	}
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+}
+
+// forget makes the next check reload the denylist.
+func (c *ipDenylistCache) forget() {
+	c.mu.Lock()
+	c.loadedAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// denies reports whether addr is in an unexpired entry.
+func (c *ipDenylistCache) denies(addr netip.Addr) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	now := time.Now()
+	for _, denied := range c.prefixes {
+		if denied.expiresAt != nil && !now.Before(*denied.expiresAt) {
+			continue
+		}
+		if denied.prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListIPDenylist returns the unexpired denylist entries.
+func (s *AuthService) ListIPDenylist(ctx context.Context) ([]models.IPDenylistEntry, error) {
+	if err := checkDenylistTenant(ctx); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.ipDenylistRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []models.IPDenylistEntry{}
+	}
+	return entries, nil
+}
+
+// AddIPDenylistEntry refuses every request from an address or CIDR block,
+// for req.TTLSeconds or until removed. Denying the block again replaces its
+// reason and expiry. An admin can't deny their own current address.
+func (s *AuthService) AddIPDenylistEntry(ctx context.Context, actorID int64, req models.AddIPDenylistEntryRequest) (*models.IPDenylistEntry, error) {
+	if err := checkDenylistTenant(ctx); err != nil {
+		return nil, err
+	}
+	prefix, err := parseIPBlock(req.CIDR)
+	if err != nil {
+		return nil, err
+	}
+	if addr, err := netip.ParseAddr(requestctx.ClientInfoFrom(ctx).IP); err == nil && prefix.Contains(addr.Unmap()) {
+		return nil, ErrIPDenylistLockout
+	}
+
+	entry := &models.IPDenylistEntry{
+		CIDR:      prefix.String(),
+		Reason:    req.Reason,
+		CreatedBy: &actorID,
+	}
+	if req.TTLSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		entry.ExpiresAt = &expiresAt
+	}
+
+	if err := s.ipDenylistRepo.Save(ctx, entry); err != nil {
+		return nil, err
+	}
+	s.denylist.forget()
+
+	s.recordAudit(ctx, &actorID, constants.AuditIPDenylistChanged, map[string]interface{}{"action": "added", "cidr": entry.CIDR, "reason": entry.Reason})
+	logger.Info("IP denylist entry added", "cidr", entry.CIDR, "actorID", actorID)
+	return entry, nil
+}
+
+// RemoveIPDenylistEntry lifts a denylist entry.
+func (s *AuthService) RemoveIPDenylistEntry(ctx context.Context, actorID, entryID int64) error {
+	if err := checkDenylistTenant(ctx); err != nil {
+		return err
+	}
+
+	if err := s.ipDenylistRepo.Delete(ctx, entryID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDenylistEntryNotFound
+		}
+		return err
+	}
+	s.denylist.forget()
+
+	s.recordAudit(ctx, &actorID, constants.AuditIPDenylistChanged, map[string]interface{}{"action": "removed", "entry_id": entryID})
+	logger.Info("IP denylist entry removed", "entryID", entryID, "actorID", actorID)
+	return nil
+}
+
+// DeniesIP reports whether requests from ip are refused. It is called on
+// every request, so the denylist is kept in memory and reloaded every
+// ipDenylistRefresh. If it can't be reloaded the last loaded one applies.
+func (s *AuthService) DeniesIP(ctx context.Context, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	if s.denylist.stale() {
+		s.denylist.reload.Lock()
+		if s.denylist.stale() {
+			s.loadDenylist(ctx)
+		}
+		s.denylist.reload.Unlock()
+	}
+	return s.denylist.denies(addr.Unmap())
+}
+
+// loadDenylist reloads the denylist. Failures are logged and retried after
+// ipDenylistRefresh.
+func (s *AuthService) loadDenylist(ctx context.Context) {
+	entries, err := s.ipDenylistRepo.List(ctx)
+	if err != nil {
+		logger.Error("failed to load IP denylist", "error", err)
+		s.denylist.set(nil)
+		return
+	}
+
+	prefixes := make([]deniedPrefix, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry.CIDR)
+		if err != nil {
+			logger.Warn("invalid IP denylist entry", "error", err, "entryID", entry.ID)
+			continue
+		}
+		prefixes = append(prefixes, deniedPrefix{prefix: prefix, expiresAt: entry.ExpiresAt})
+	}
+	s.denylist.set(prefixes)
+}
+
+// checkDenylistTenant returns ErrDenylistForbidden unless the request belongs
+// to the default tenant: the denylist applies to every tenant, so only the
+// deployment's own admins manage it.
+func checkDenylistTenant(ctx context.Context) error {
+	if tenant := requestctx.TenantFrom(ctx); tenant != 0 && tenant != constants.DefaultTenantID {
+		return ErrDenylistForbidden
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/lock"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Background Jobs
+// ============================================================================
+
+// jobLockTTL is how long a job lock outlives a server that died while
+// holding it; live holders keep extending it.
+const jobLockTTL = time.Minute
+
+// runExclusive runs job holding the lock name, so that maintenance work
+// started on several servers at once only runs on one of them; the others
+// skip this run.
+func runExclusive(ctx context.Context, locks *lock.Locker, name string, job func(ctx context.Context)) {
+	ran, err := locks.Do(ctx, name, jobLockTTL, job)
+	if err != nil {
+		logger.Warn("failed to acquire job lock", "lock", name, "error", err)
+		return
+	}
+	if !ran {
+		logger.Debug("job running on another server, skipped", "lock", name)
+	}
+}
+
+// jobRegistry lists the scheduled jobs for the status endpoint. It is safe
+// for concurrent use.
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs []models.ScheduledJob
+}
+
+// add registers a scheduled job.
+func (r *jobRegistry) add(job models.ScheduledJob) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, job)
+}
+
+// list returns the scheduled jobs, in the order they were started.
+func (r *jobRegistry) list() []models.ScheduledJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.ScheduledJob{}, r.jobs...)
+}
+
+// startScheduled runs job now and then every interval until ctx is
+// cancelled, on the server elected to run the scheduler. The job's lock
+// also covers a change of leader in the middle of a run.
+func (s *AuthService) startScheduled(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context)) {
+	s.scheduledJobs.add(models.ScheduledJob{Name: name, Interval: interval.String()})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if s.scheduler.IsLeader() {
+				runExclusive(ctx, s.locks, name, job)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SchedulerStatus tells which server leads the scheduler and lists the
+// scheduled jobs.
+func (s *AuthService) SchedulerStatus(ctx context.Context) (*models.SchedulerStatus, error) {
+	// Servers are shared by every tenant
+	if tenant := requestctx.TenantFrom(ctx); tenant != 0 && tenant != constants.DefaultTenantID {
+		return nil, ErrSchedulerForbidden
+	}
+
+	status := &models.SchedulerStatus{IsLeader: s.scheduler.IsLeader(), Jobs: s.scheduledJobs.list()}
+	if s.scheduler == nil {
+		return status, nil
+	}
+
+	leader, expiresAt, err := s.scheduler.Leader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Coordinated = true
+	status.Server = s.scheduler.Instance()
+	status.Leader = leader
+	if leader != "" {
+		status.LeaseExpiresAt = &expiresAt
+	}
+	return status, nil
+}
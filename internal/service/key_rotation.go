@@ -0,0 +1,558 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/requestctx"
+	"authentio/pkg/jwt"
+	"authentio/pkg/lock"
+	"authentio/pkg/logger"
+	"authentio/pkg/secretbox"
+)
+
+// ============================================================================
+// Key Rotation
+// ============================================================================
+
+// recentRotations is how many rotations the status reports.
+const recentRotations = 20
+
+// KeyManager rotates the JWT signing secret, the OpenID Connect provider key
+// and the master key of encrypted columns on demand. Generated keys are
+// stored in the managed_keys table, sealed with the configured master key,
+// and installed in the JWT manager and keyring; every server reloads them
+// periodically. A rotated key stays verify-only (decrypt-only for the master
+// key): signing keys until every token they signed has expired, the master
+// key for good, since values sealed by servers that hadn't reloaded yet may
+// still need it.
+type KeyManager struct {
+	repo     repository.ManagedKeyRepository
+	root     *secretbox.Keyring // seals generated key material; nil disables rotations
+	secrets  *secretbox.Keyring // keyring of encrypted columns; nil when encryption is off
+	jwt      *jwt.Manager
+	tenants  *TenantKeyStore
+	baseJWT  jwt.Key          // from JWT_SECRET and JWT_RETIRED_KEYS
+	baseOIDC *jwt.ProviderKey // nil when provider mode is off
+	phaseOut time.Duration    // how long rotated signing keys keep verifying
+	locks    *lock.Locker     // keeps a re-encryption job on one server
+	passes   []ReencryptionPass
+
+	mu   sync.Mutex      // serializes Load
+	jobs context.Context // context of re-encryption jobs, set by Start
+}
+
+// NewKeyManager creates the key manager. root seals generated keys and must
+// be a keyring of its own: secrets, the keyring of encrypted columns, gets
+// the generated master keys. Tokens signed by a rotated key are accepted for
+// phaseOut (the access token lifetime); after a master key rotation, passes
+// re-encrypt the values it protects, on one server at a time thanks to locks.
+func NewKeyManager(
+	repo repository.ManagedKeyRepository,
+	root, secrets *secretbox.Keyring,
+	jwtManager *jwt.Manager,
+	tenants *TenantKeyStore,
+	baseJWT jwt.Key,
+	baseOIDC *jwt.ProviderKey,
+	phaseOut time.Duration,
+	locks *lock.Locker,
+	passes ...ReencryptionPass,
+) *KeyManager {
+	return &KeyManager{
+		repo:     repo,
+		root:     root,
+		secrets:  secrets,
+		jwt:      jwtManager,
+		tenants:  tenants,
+		baseJWT:  baseJWT,
+		baseOIDC: baseOIDC,
+		phaseOut: phaseOut,
+		locks:    locks,
+		passes:   passes,
+		jobs:     context.Background(),
+	}
+}
+
+// Load reads the generated keys and installs them, retiring the signing keys
+// whose tokens have all expired.
+func (m *KeyManager) Load(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, purpose := range []string{constants.KeyPurposeJWT, constants.KeyPurposeOIDC} {
+		if _, err := m.repo.RetireKeys(ctx, purpose, time.Now().Add(-m.phaseOut)); err != nil {
+			return fmt.Errorf("failed to retire phased out keys: %w", err)
+		}
+	}
+	keys, err := m.repo.ListKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load managed keys: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if m.root == nil {
+		return errors.New("keys generated by rotations can't be opened without SECRETS_ENCRYPTION_KEY or SECRETS_USE_KMS")
+	}
+
+	byPurpose := make(map[string][]*models.ManagedKey)
+	for _, key := range keys {
+		byPurpose[key.Purpose] = append(byPurpose[key.Purpose], key)
+	}
+	if err := m.installJWT(ctx, byPurpose[constants.KeyPurposeJWT]); err != nil {
+		return err
+	}
+	if err := m.installOIDC(byPurpose[constants.KeyPurposeOIDC]); err != nil {
+		return err
+	}
+	return m.installSecrets(byPurpose[constants.KeyPurposeSecrets])
+}
+
+// Start resumes the re-encryption of rotations interrupted by a restart and
+// reloads the keys every interval until ctx is cancelled, so rotations
+// started on another server are picked up. Each reload also resumes the
+// rotations left running by a server that died.
+func (m *KeyManager) Start(ctx context.Context, interval time.Duration) {
+	m.jobs = ctx
+	m.resume(ctx)
+
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := m.Load(ctx); err != nil {
+				logger.Warn("failed to refresh managed keys", "error", err)
+			}
+			m.resume(ctx)
+		}
+	}()
+}
+
+// resume finishes the running rotations that no server is working on.
+func (m *KeyManager) resume(ctx context.Context) {
+	rotations, err := m.repo.RunningRotations(ctx)
+	if err != nil {
+		logger.Warn("failed to resume key rotations", "error", err)
+	}
+	for _, rotation := range rotations {
+		go m.finishExclusive(ctx, rotation)
+	}
+}
+
+// Rotate generates a new key for purpose and makes it the active one; the
+// previous key becomes verify-only. After a master key rotation the values it
+// protects are re-encrypted by a background job, whose progress is reported
+// by Status.
+func (m *KeyManager) Rotate(ctx context.Context, purpose string, actorID int64) (*models.KeyRotation, error) {
+	if err := m.rotatable(purpose); err != nil {
+		return nil, err
+	}
+
+	keyID := time.Now().UTC().Format("20060102T150405Z")
+	material, err := generateKeyMaterial(purpose)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := m.root.SealString(material, managedKeyContext(purpose, keyID))
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.ManagedKey{Purpose: purpose, KeyID: keyID, Material: sealed, CreatedBy: &actorID}
+	rotation := &models.KeyRotation{
+		Purpose:       purpose,
+		KeyID:         keyID,
+		PreviousKeyID: m.currentKeyID(purpose),
+		Status:        constants.RotationRunning,
+		RequestedBy:   &actorID,
+	}
+	started, err := m.repo.Rotate(ctx, key, rotation)
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, ErrKeyRotationRunning
+	}
+
+	if err := m.Load(ctx); err != nil {
+		logger.Error("failed to install rotated key", "purpose", purpose, "keyID", keyID, "error", err)
+	}
+	logger.Info("key rotated", "purpose", purpose, "keyID", keyID, "previousKeyID", rotation.PreviousKeyID, "actorID", actorID)
+
+	if purpose == constants.KeyPurposeSecrets {
+		go m.finishExclusive(m.jobs, rotation)
+		return rotation, nil
+	}
+	m.finish(ctx, rotation)
+	return rotation, nil
+}
+
+// rotatable checks that the key of purpose can be rotated.
+func (m *KeyManager) rotatable(purpose string) error {
+	switch purpose {
+	case constants.KeyPurposeJWT, constants.KeyPurposeOIDC, constants.KeyPurposeSecrets:
+	default:
+		return ErrInvalidKeyPurpose
+	}
+	if m == nil || m.root == nil ||
+		(purpose == constants.KeyPurposeOIDC && m.baseOIDC == nil) ||
+		(purpose == constants.KeyPurposeSecrets && m.secrets == nil) {
+		return ErrKeyRotationDisabled
+	}
+	return nil
+}
+
+// finishExclusive runs finish holding the rotation's lock, unless another
+// server already works on it.
+func (m *KeyManager) finishExclusive(ctx context.Context, rotation *models.KeyRotation) {
+	runExclusive(ctx, m.locks, fmt.Sprintf("key-rotation:%d", rotation.ID), func(ctx context.Context) {
+		// The previous holder of the lock may have finished it since
+		running, err := m.repo.RunningRotations(ctx)
+		if err != nil {
+			logger.Warn("failed to check key rotation", "rotationID", rotation.ID, "error", err)
+			return
+		}
+		for _, r := range running {
+			if r.ID == rotation.ID {
+				m.finish(ctx, r)
+				return
+			}
+		}
+	})
+}
+
+// finish completes a rotation: signing keys need nothing more, while the
+// values protected by a rotated master key are re-encrypted with the new one.
+// An interrupted job is left running, to be resumed by Start.
+func (m *KeyManager) finish(ctx context.Context, rotation *models.KeyRotation) {
+	if rotation.Purpose == constants.KeyPurposeSecrets {
+		for _, pass := range m.passes {
+			reencrypted, err := pass.Run(ctx)
+			rotation.Reencrypted += reencrypted
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				rotation.Status = constants.RotationFailed
+				rotation.Error = pass.Name + ": " + err.Error()
+				break
+			}
+			// Progress, reported by Status
+			if err := m.repo.UpdateRotation(ctx, rotation); err != nil {
+				logger.Warn("failed to save key rotation progress", "rotationID", rotation.ID, "error", err)
+			}
+		}
+	}
+	if rotation.Status == constants.RotationRunning {
+		rotation.Status = constants.RotationCompleted
+	}
+
+	if err := m.repo.UpdateRotation(ctx, rotation); err != nil {
+		logger.Error("failed to save key rotation", "rotationID", rotation.ID, "error", err)
+		return
+	}
+	if rotation.Status == constants.RotationFailed {
+		logger.Warn("key rotation failed", "purpose", rotation.Purpose, "keyID", rotation.KeyID, "reencrypted", rotation.Reencrypted, "error", rotation.Error)
+		return
+	}
+	logger.Info("key rotation completed", "purpose", rotation.Purpose, "keyID", rotation.KeyID, "reencrypted", rotation.Reencrypted)
+}
+
+// Status lists the keys in use and the recent rotations.
+func (m *KeyManager) Status(ctx context.Context) (*models.KeyRotationStatus, error) {
+	keys, err := m.repo.ListKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rotations, err := m.repo.ListRotations(ctx, recentRotations)
+	if err != nil {
+		return nil, err
+	}
+	if rotations == nil {
+		rotations = []*models.KeyRotation{}
+	}
+
+	status := &models.KeyRotationStatus{Keys: []models.KeyStatus{}, Rotations: rotations}
+	for _, purpose := range []string{constants.KeyPurposeJWT, constants.KeyPurposeOIDC, constants.KeyPurposeSecrets} {
+		var generated []*models.ManagedKey
+		for _, key := range keys {
+			if key.Purpose == purpose {
+				generated = append(generated, key)
+			}
+		}
+		status.Keys = append(status.Keys, m.configKeys(purpose, generated)...)
+		for _, key := range generated {
+			if key.Status != constants.KeyRetired {
+				createdAt := key.CreatedAt
+				status.Keys = append(status.Keys, models.KeyStatus{
+					Purpose: purpose, KeyID: key.KeyID, Status: key.Status, Source: "rotation",
+					CreatedAt: &createdAt, RetiredAt: key.RetiredAt,
+				})
+			}
+		}
+	}
+	return status, nil
+}
+
+// configKeys returns the status of the configured key of purpose, given the
+// keys generated for it. It is verify-only once a key was generated, and
+// left out once phased out.
+func (m *KeyManager) configKeys(purpose string, generated []*models.ManagedKey) []models.KeyStatus {
+	var keyID string
+	switch {
+	case purpose == constants.KeyPurposeJWT:
+		keyID = m.baseJWT.KeyID
+	case purpose == constants.KeyPurposeOIDC && m.baseOIDC != nil:
+		keyID = m.baseOIDC.ID
+	case purpose == constants.KeyPurposeSecrets && m.root != nil:
+		keyID = m.root.CurrentKeyID()
+	default:
+		return nil
+	}
+
+	key := models.KeyStatus{Purpose: purpose, KeyID: keyID, Status: constants.KeyActive, Source: "config"}
+	if len(generated) > 0 {
+		retiredAt := generated[0].CreatedAt
+		if purpose != constants.KeyPurposeSecrets && time.Since(retiredAt) > m.phaseOut {
+			return nil
+		}
+		key.Status, key.RetiredAt = constants.KeyVerifyOnly, &retiredAt
+	}
+	return []models.KeyStatus{key}
+}
+
+// RewrapKeys re-wraps the generated keys sealed under a retired master key
+// with the current one, like the other encrypted values.
+func (m *KeyManager) RewrapKeys(ctx context.Context) (int, error) {
+	if m.root == nil {
+		return 0, nil
+	}
+	keys, err := m.repo.ListKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	prefix := secretbox.FieldPrefix(m.root.CurrentKeyID())
+	updated := 0
+	for _, key := range keys {
+		if key.Status == constants.KeyRetired || strings.HasPrefix(key.Material, prefix) {
+			continue
+		}
+		material, err := m.root.RewrapString(key.Material)
+		if err != nil {
+			return updated, fmt.Errorf("key %s %s: %w", key.Purpose, key.KeyID, err)
+		}
+		if err := m.repo.UpdateMaterial(ctx, key.ID, key.Material, material); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// ============================================================================
+// Installing Keys
+// ============================================================================
+
+// installJWT makes the active generated JWT secret the default signing key.
+// The configured secret and the rotated ones keep verifying the tokens they
+// signed.
+func (m *KeyManager) installJWT(ctx context.Context, keys []*models.ManagedKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	key := m.baseJWT
+	// The configured secret stopped signing when the first key was generated
+	key.Retired = append(append([]jwt.RetiredKey{}, m.baseJWT.Retired...),
+		jwt.RetiredKey{ID: m.baseJWT.KeyID, Secret: m.baseJWT.Secret, RetiredAt: keys[0].CreatedAt})
+	for _, generated := range keys {
+		if generated.Status == constants.KeyRetired {
+			continue
+		}
+		secret, err := m.open(generated)
+		if err != nil {
+			return err
+		}
+		if generated.Status == constants.KeyActive {
+			key.Secret, key.KeyID = secret, generated.KeyID
+		} else if generated.RetiredAt != nil {
+			key.Retired = append(key.Retired, jwt.RetiredKey{ID: generated.KeyID, Secret: secret, RetiredAt: *generated.RetiredAt})
+		}
+	}
+
+	m.jwt.SetDefaultKey(key)
+	return m.tenants.SetDefaults(ctx, key)
+}
+
+// installOIDC makes the active generated RSA key the provider key; the
+// configured key and the rotated ones stay published until phased out.
+func (m *KeyManager) installOIDC(keys []*models.ManagedKey) error {
+	if len(keys) == 0 || m.baseOIDC == nil {
+		return nil
+	}
+
+	var current *jwt.ProviderKey
+	var previous []*jwt.ProviderKey
+	if time.Since(keys[0].CreatedAt) <= m.phaseOut {
+		previous = append(previous, m.baseOIDC)
+	}
+	for _, generated := range keys {
+		if generated.Status == constants.KeyRetired {
+			continue
+		}
+		material, err := m.open(generated)
+		if err != nil {
+			return err
+		}
+		providerKey, err := jwt.ParseProviderKey([]byte(material))
+		if err != nil {
+			return fmt.Errorf("key %s %s: %w", generated.Purpose, generated.KeyID, err)
+		}
+		// The "kid" of provider tokens is the thumbprint, not the key ID
+		if generated.Status == constants.KeyActive {
+			current = providerKey
+		} else {
+			previous = append(previous, providerKey)
+		}
+	}
+	if current == nil {
+		return nil
+	}
+
+	m.jwt.SetProviderKey(current, previous...)
+	return nil
+}
+
+// installSecrets adds the generated master keys to the keyring of encrypted
+// columns, sealing new values with the active one.
+func (m *KeyManager) installSecrets(keys []*models.ManagedKey) error {
+	if len(keys) == 0 || m.secrets == nil {
+		return nil
+	}
+
+	var current string
+	masterKeys := make([]secretbox.Key, 0, len(keys))
+	for _, generated := range keys {
+		material, err := m.open(generated)
+		if err != nil {
+			return err
+		}
+		key, err := secretbox.ParseKey(generated.KeyID, material)
+		if err != nil {
+			return err
+		}
+		masterKeys = append(masterKeys, key)
+		if generated.Status == constants.KeyActive {
+			current = generated.KeyID
+		}
+	}
+	return m.secrets.SetManaged(masterKeys, current)
+}
+
+// open returns the material of a generated key.
+func (m *KeyManager) open(key *models.ManagedKey) (string, error) {
+	material, err := m.root.OpenString(key.Material, managedKeyContext(key.Purpose, key.KeyID))
+	if err != nil {
+		return "", fmt.Errorf("key %s %s: %w", key.Purpose, key.KeyID, err)
+	}
+	return material, nil
+}
+
+// currentKeyID returns the ID of the key of purpose in use.
+func (m *KeyManager) currentKeyID(purpose string) string {
+	switch purpose {
+	case constants.KeyPurposeJWT:
+		return m.jwt.Key(constants.DefaultTenantID).KeyID
+	case constants.KeyPurposeOIDC:
+		if jwks := m.jwt.JWKS(); len(jwks) > 0 {
+			return jwks[0].KeyID
+		}
+	case constants.KeyPurposeSecrets:
+		return m.secrets.CurrentKeyID()
+	}
+	return ""
+}
+
+// generateKeyMaterial returns a new key for purpose: a 256-bit secret
+// (base64), or a PEM-encoded RSA key for the provider.
+func generateKeyMaterial(purpose string) (string, error) {
+	if purpose == constants.KeyPurposeOIDC {
+		key, err := jwt.GenerateProviderKey()
+		if err != nil {
+			return "", err
+		}
+		return string(jwt.EncodeProviderKey(key)), nil
+	}
+
+	secret := make([]byte, secretbox.KeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(secret), nil
+}
+
+// managedKeyContext binds sealed key material to its row.
+func managedKeyContext(purpose, keyID string) []byte {
+	return []byte("managed_keys:" + purpose + ":" + keyID)
+}
+
+// ============================================================================
+// Admin API
+// ============================================================================
+
+// KeyRotationStatus lists the keys in use and the recent rotations.
+func (s *AuthService) KeyRotationStatus(ctx context.Context) (*models.KeyRotationStatus, error) {
+	if err := checkKeysTenant(ctx); err != nil {
+		return nil, err
+	}
+	if s.keys == nil {
+		return nil, ErrKeyRotationDisabled
+	}
+	return s.keys.Status(ctx)
+}
+
+// RotateKey rotates the key of purpose (jwt, oidc or secrets).
+func (s *AuthService) RotateKey(ctx context.Context, purpose string, actorID int64) (*models.KeyRotation, error) {
+	if err := checkKeysTenant(ctx); err != nil {
+		return nil, err
+	}
+	rotation, err := s.keys.Rotate(ctx, purpose, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditKeyRotated, map[string]interface{}{
+		"purpose": purpose, "key_id": rotation.KeyID, "previous_key_id": rotation.PreviousKeyID,
+	})
+	return rotation, nil
+}
+
+// checkKeysTenant returns ErrKeysForbidden unless the request belongs to the
+// default tenant: keys are shared by every tenant, so only the deployment's
+// own admins manage them.
+func checkKeysTenant(ctx context.Context) error {
+	if tenant := requestctx.TenantFrom(ctx); tenant != 0 && tenant != constants.DefaultTenantID {
+		return ErrKeysForbidden
+	}
+	return nil
+}
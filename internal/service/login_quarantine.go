@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Login Quarantine
+// ============================================================================
+
+// startQuarantine holds a risky login by user, who passed the first factor
+// with loginMethod, and emails them a link to approve it. The login client
+// polls the returned challenge with CompleteQuarantinedLogin.
+func (s *AuthService) startQuarantine(ctx context.Context, user *models.User, loginMethod string, riskScore int) (*response.LoginResponse, error) {
+	token := generateSecureToken()
+	client := requestctx.ClientInfoFrom(ctx)
+	challenge := &models.PushChallenge{
+		ChallengeID:       generateSecureToken(),
+		UserID:            user.ID,
+		Method:            constants.ChallengeQuarantine,
+		LoginMethod:       loginMethod,
+		Status:            constants.PushChallengePending,
+		IPAddress:         client.IP,
+		UserAgent:         client.UserAgent,
+		ExpiresAt:         time.Now().Add(s.cfg.LoginQuarantineTTL),
+		ApprovalTokenHash: hashApprovalToken(token),
+	}
+	if err := s.pushRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &user.ID, constants.AuditLoginQuarantined, map[string]interface{}{"method": loginMethod, "risk_score": riskScore})
+	logger.Warn("risky login quarantined", "userID", user.ID, "riskScore", riskScore)
+
+	// Sent whatever the user's notification preferences: without it the
+	// login can't be approved
+	link := strings.TrimRight(s.cfg.FrontendURL, "/") + "/login/approve?token=" + url.QueryEscape(token)
+	msg, err := s.emailRender.LoginApproval(ctx, user.FirstName, loginMethod, client.IP, client.UserAgent, challenge.CreatedAt, link, challenge.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sendEmail(ctx, constants.EmailLoginApproval, []string{user.Email}, msg); err != nil {
+		logger.Error("failed to send login approval email", "error", err, "email", user.Email)
+		return nil, ErrEmailSendFailed
+	}
+
+	return &response.LoginResponse{
+		User:              newUserResponse(user),
+		RequiresTwoFactor: true,
+		TwoFactor: &response.TwoFactorChallenge{
+			Method:      constants.ChallengeQuarantine,
+			ChallengeID: challenge.ChallengeID,
+			ExpiresAt:   challenge.ExpiresAt,
+		},
+	}, nil
+}
+
+// ApproveQuarantinedLogin approves the quarantined login whose emailed link
+// carries token, so that its client receives tokens. An expired link returns
+// ErrChallengeExpired.
+func (s *AuthService) ApproveQuarantinedLogin(ctx context.Context, token string) error {
+	challenge, err := s.pushRepo.FindChallengeByApprovalToken(ctx, hashApprovalToken(token))
+	if err != nil {
+		return err
+	}
+	if challenge == nil || challenge.Status != constants.PushChallengePending {
+		return ErrChallengeNotFound
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		s.recordAudit(ctx, &challenge.UserID, constants.AuditQuarantineExpired, map[string]interface{}{"method": challenge.LoginMethod})
+		return ErrChallengeExpired
+	}
+
+	if err := s.pushRepo.DecideChallenge(ctx, challenge.UserID, challenge.ChallengeID, constants.PushChallengeApproved); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrChallengeNotFound
+		}
+		return err
+	}
+
+	s.recordAudit(ctx, &challenge.UserID, constants.AuditQuarantineApproved, map[string]interface{}{"method": challenge.LoginMethod})
+	logger.Info("quarantined login approved", "userID", challenge.UserID)
+	return nil
+}
+
+// CompleteQuarantinedLogin exchanges an approved quarantined login for
+// tokens. It returns ErrLoginQuarantined until the user approves it,
+// ErrChallengeExpired once the link expired, and the tokens exactly once
+// after approval.
+func (s *AuthService) CompleteQuarantinedLogin(ctx context.Context, challengeID string) (*response.LoginResponse, error) {
+	return s.completeChallenge(ctx, challengeID, constants.ChallengeQuarantine, ErrLoginQuarantined, nil)
+}
+
+// hashApprovalToken returns the hex-encoded SHA-256 hash under which a login
+// approval token is stored.
+func hashApprovalToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
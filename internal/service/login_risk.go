@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+	"authentio/pkg/quota"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Adaptive MFA
+// ============================================================================
+
+// Login risk signals. A login's score is the sum of the signals it shows,
+// capped at 100.
+const (
+	riskUnknownDevice = 35 // no device fingerprint, or one the user never signed in with
+	riskNewLocation   = 30 // IP address none of the user's recent logins came from
+	riskFailedLogin   = 10 // per failed login in the last riskFailureWindow, up to riskMaxFailures
+	riskAnonymousIP   = 25 // proxy, VPN or Tor exit node (anonymizer detection)
+	riskHostingIP     = 15 // hosting provider or datacenter (anonymizer detection)
+
+	riskMaxFailures   = 3
+	riskFailureWindow = time.Hour
+
+	// riskHistorySize is how many recent logins (and failures) are considered
+	riskHistorySize = 20
+)
+
+// loginRisk is the assessment of a login that passed its first factor.
+type loginRisk struct {
+	Score       int
+	KnownDevice bool
+}
+
+// checkLoginRisk applies the user's IP allowlist, the tenant's geo policy and
+// the risk policy to a login by user that passed the first factor with
+// loginMethod. It returns a challenge when the login needs a second factor,
+// ErrIPNotAllowed, ErrGeoBlocked or ErrLoginBlocked when it is refused, and
+// nothing when the login can
+// proceed. The risk score is added to metadata for the login's audit entry.
+//
+// Without a policy push 2FA users are always challenged. With one, push
+// approval is skipped for low-risk logins from a known device, users
+// without push 2FA must enter a code sent by email when the risk is high,
+// and riskier logins are quarantined until approved by email link. Logins
+// from outside an allowlist in verify mode, and those the geo policy steps
+// up, always need a second factor.
+func (s *AuthService) checkLoginRisk(ctx context.Context, user *models.User, loginMethod string, metadata map[string]interface{}) (*response.LoginResponse, error) {
+	stepUp, err := s.checkIPAllowlist(ctx, user, loginMethod)
+	if err != nil {
+		return nil, err
+	}
+	geoStepUp, err := s.checkGeoPolicy(ctx, user, loginMethod, metadata)
+	if err != nil {
+		return nil, err
+	}
+	stepUp = stepUp || geoStepUp
+
+	policy := s.riskPolicy(ctx)
+	pushEnabled := s.pushApprovalEnabled(ctx, user.ID)
+	if policy == (models.RiskPolicy{}) {
+		switch {
+		case pushEnabled:
+			return s.startPushChallenge(ctx, user, loginMethod)
+		case stepUp:
+			return s.startEmailChallenge(ctx, user, loginMethod)
+		}
+		return nil, nil
+	}
+
+	risk := s.assessLoginRisk(ctx, user.ID)
+	metadata["risk_score"] = risk.Score
+	if client := requestctx.ClientInfoFrom(ctx); client.IsProxy || client.IsHosting {
+		metadata["is_proxy"] = client.IsProxy
+		metadata["is_hosting"] = client.IsHosting
+	}
+
+	switch {
+	case policy.BlockAt > 0 && risk.Score >= policy.BlockAt:
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginBlocked, map[string]interface{}{"method": loginMethod, "risk_score": risk.Score})
+		logger.Warn("risky login blocked", "userID", user.ID, "riskScore", risk.Score)
+		return nil, ErrLoginBlocked
+	case policy.QuarantineAt > 0 && risk.Score >= policy.QuarantineAt:
+		return s.startQuarantine(ctx, user, loginMethod, risk.Score)
+	case pushEnabled && !stepUp && policy.SkipBelow > 0 && risk.Score < policy.SkipBelow && risk.KnownDevice:
+		metadata["second_factor"] = "skipped"
+		return nil, nil
+	case pushEnabled:
+		return s.startPushChallenge(ctx, user, loginMethod)
+	case stepUp || (policy.RequireAt > 0 && risk.Score >= policy.RequireAt):
+		return s.startEmailChallenge(ctx, user, loginMethod)
+	}
+	return nil, nil
+}
+
+// riskPolicy returns the risk thresholds of the current tenant. If its
+// overrides can't be loaded the deployment's policy applies.
+func (s *AuthService) riskPolicy(ctx context.Context) models.RiskPolicy {
+	defaults := models.RiskPolicy{
+		SkipBelow: s.cfg.MFARiskSkipBelow,
+		RequireAt: s.cfg.MFARiskRequireAt,
+		BlockAt:   s.cfg.MFARiskBlockAt,
+	}
+
+	overrides, err := s.tenantRepo.RiskPolicy(ctx, currentTenant(ctx))
+	if err != nil {
+		logger.Error("failed to load tenant risk policy", "error", err)
+		return defaults
+	}
+	return overrides.Apply(defaults)
+}
+
+// assessLoginRisk scores the login of userID from the client in ctx against
+// the user's registered devices and recent login history, and the network it
+// comes from. Signals that can't be checked count as risky.
+func (s *AuthService) assessLoginRisk(ctx context.Context, userID int64) loginRisk {
+	client := requestctx.ClientInfoFrom(ctx)
+	var risk loginRisk
+
+	if client.DeviceFingerprint != "" {
+		hash := hashFingerprint(client.DeviceFingerprint)
+		devices, err := s.deviceRepo.ListDevices(ctx, userID)
+		if err != nil {
+			logger.Error("failed to list devices", "error", err, "userID", userID)
+		}
+		for _, device := range devices {
+			if device.FingerprintHash == hash {
+				risk.KnownDevice = true
+				break
+			}
+		}
+	}
+	if !risk.KnownDevice {
+		risk.Score += riskUnknownDevice
+	}
+
+	entries, err := s.auditRepo.ListByUser(ctx, userID,
+		[]string{string(constants.AuditLoginSuccess), string(constants.AuditLoginFailed)}, nil, riskHistorySize)
+	if err != nil {
+		logger.Error("failed to load login history", "error", err, "userID", userID)
+	}
+	knownLocation, failures := false, 0
+	for _, entry := range entries {
+		switch constants.AuditEvent(entry.Event) {
+		case constants.AuditLoginSuccess:
+			if client.IP != "" && entry.IPAddress == client.IP {
+				knownLocation = true
+			}
+		case constants.AuditLoginFailed:
+			if time.Since(entry.CreatedAt) < riskFailureWindow && failures < riskMaxFailures {
+				failures++
+			}
+		}
+	}
+	if !knownLocation {
+		risk.Score += riskNewLocation
+	}
+	risk.Score += failures * riskFailedLogin
+
+	if client.IsProxy {
+		risk.Score += riskAnonymousIP
+	}
+	if client.IsHosting {
+		risk.Score += riskHostingIP
+	}
+
+	if risk.Score > 100 {
+		risk.Score = 100
+	}
+	return risk
+}
+
+// startEmailChallenge emails user a 2FA code and creates a pending login,
+// completed by CompleteEmailLogin, for user who passed the first factor with
+// loginMethod.
+func (s *AuthService) startEmailChallenge(ctx context.Context, user *models.User, loginMethod string) (*response.LoginResponse, error) {
+	if err := s.throttleOTP(ctx, constants.Type2FA, user.Email); err != nil {
+		return nil, err
+	}
+	if err := s.consumeQuota(ctx, quota.OTPEmails); err != nil {
+		return nil, err
+	}
+
+	client := requestctx.ClientInfoFrom(ctx)
+	challenge := &models.PushChallenge{
+		ChallengeID: generateSecureToken(),
+		UserID:      user.ID,
+		Method:      constants.TwoFAMethodEmail,
+		LoginMethod: loginMethod,
+		Status:      constants.PushChallengePending,
+		IPAddress:   client.IP,
+		UserAgent:   client.UserAgent,
+		ExpiresAt:   s.cfg.OTPPolicy(constants.Type2FA).ExpiresAt(time.Now()),
+	}
+	if err := s.pushRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	if err := s.send2FACode(ctx, user); err != nil {
+		return nil, err
+	}
+	token, err := s.issueChallengeToken(ctx, user, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.LoginResponse{
+		User:              newUserResponse(user),
+		RequiresTwoFactor: true,
+		TwoFactor: &response.TwoFactorChallenge{
+			Method:         constants.TwoFAMethodEmail,
+			ChallengeID:    challenge.ChallengeID,
+			ChallengeToken: token,
+			ExpiresAt:      challenge.ExpiresAt,
+		},
+	}, nil
+}
+
+// CompleteEmailLogin exchanges the challenge token of an email challenge and
+// the code sent for it for tokens. The challenge can be completed once.
+func (s *AuthService) CompleteEmailLogin(ctx context.Context, req models.EmailLoginRequest) (*response.LoginResponse, error) {
+	claims, challenge, user, err := s.pendingEmailChallenge(ctx, req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Verify2FA(ctx, user.Email, req.Code); err != nil {
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"method": challenge.LoginMethod, "reason": "invalid_2fa_code"})
+		return nil, err
+	}
+	if err := checkApproval(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.consumeChallengeToken(ctx, claims); err != nil {
+		return nil, err
+	}
+	if err := s.pushRepo.DecideChallenge(ctx, user.ID, challenge.ChallengeID, constants.PushChallengeApproved); err != nil {
+		return nil, ErrChallengeNotFound
+	}
+	completed, err := s.pushRepo.CompleteChallenge(ctx, challenge.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrChallengeNotFound
+	}
+
+	s.allowVerifiedIP(ctx, user.ID)
+	s.loginSucceeded(ctx, user, challenge.LoginMethod, map[string]interface{}{"method": challenge.LoginMethod, "second_factor": constants.TwoFAMethodEmail})
+
+	return s.generateAuthResponse(ctx, user, nil)
+}
+
+// ResendLoginCode emails a new code for the email challenge of a challenge
+// token, subject to the code email cooldown and quota. Earlier codes stop
+// working; the challenge token stays valid.
+func (s *AuthService) ResendLoginCode(ctx context.Context, challengeToken string) error {
+	_, _, user, err := s.pendingEmailChallenge(ctx, challengeToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.throttleOTP(ctx, constants.Type2FA, user.Email); err != nil {
+		return err
+	}
+	if err := s.consumeQuota(ctx, quota.OTPEmails); err != nil {
+		return err
+	}
+	return s.send2FACode(ctx, user)
+}
+
+// pendingEmailChallenge returns the email challenge of a challenge token,
+// while it waits for its code, with its user.
+func (s *AuthService) pendingEmailChallenge(ctx context.Context, challengeToken string) (*jwt.ChallengeClaims, *models.PushChallenge, *models.User, error) {
+	claims, err := s.checkChallengeToken(ctx, challengeToken, constants.TwoFAMethodEmail)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	challenge, err := s.pushRepo.FindChallenge(ctx, claims.ChallengeID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if challenge == nil || challenge.Method != constants.TwoFAMethodEmail || challenge.Status != constants.PushChallengePending {
+		return nil, nil, nil, ErrChallengeNotFound
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, nil, nil, ErrChallengeExpired
+	}
+
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil || user == nil {
+		return nil, nil, nil, ErrUserNotFound
+	}
+	return claims, challenge, user, nil
+}
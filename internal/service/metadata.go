@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Custom User Metadata
+// ============================================================================
+
+// UpdateUserMetadata merges update into the user's own user_metadata and
+// returns the result.
+func (s *AuthService) UpdateUserMetadata(ctx context.Context, userID int64, update models.MetadataUpdate) (map[string]interface{}, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	metadata, err := s.mergeMetadata(ctx, user.ID, constants.MetadataUser, user.UserMetadata, update)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("user metadata updated", "userID", userID)
+	return metadata, nil
+}
+
+// AdminUpdateMetadata merges the given updates into a user's user_metadata
+// and app_metadata on behalf of an admin. When app_metadata is embedded in
+// access tokens, changing it revokes them, since it often holds permissions.
+func (s *AuthService) AdminUpdateMetadata(ctx context.Context, userID, actorID int64, req models.AdminMetadataRequest) (*response.UserResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if req.UserMetadata != nil {
+		if user.UserMetadata, err = s.mergeMetadata(ctx, user.ID, constants.MetadataUser, user.UserMetadata, req.UserMetadata); err != nil {
+			return nil, err
+		}
+	}
+	if req.AppMetadata != nil {
+		if user.AppMetadata, err = s.mergeMetadata(ctx, user.ID, constants.MetadataApp, user.AppMetadata, req.AppMetadata); err != nil {
+			return nil, err
+		}
+		if s.cfg.TokenIncludeAppMetadata {
+			s.revokeAccessTokens(ctx, user.ID, "app_metadata_changed")
+		}
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditMetadataEdited, map[string]interface{}{
+		"user_id":       userID,
+		"user_metadata": req.UserMetadata != nil,
+		"app_metadata":  req.AppMetadata != nil,
+	})
+
+	userResponse := newUserResponse(user)
+	return &userResponse, nil
+}
+
+// mergeMetadata applies update to current (null values remove keys), checks
+// the size limit and saves the result.
+func (s *AuthService) mergeMetadata(ctx context.Context, userID int64, kind string, current map[string]interface{}, update models.MetadataUpdate) (map[string]interface{}, error) {
+	merged := make(map[string]interface{}, len(current)+len(update))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range update {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		merged[key] = value
+	}
+
+	raw, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > constants.MaxMetadataBytes {
+		return nil, ErrMetadataTooLarge
+	}
+
+	if err := s.userRepo.SetMetadata(ctx, userID, kind, merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
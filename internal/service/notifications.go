@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/email"
+	"authentio/pkg/logger"
+	"authentio/pkg/push"
+)
+
+// ============================================================================
+// Notification Dispatcher
+// ============================================================================
+
+// Notification is an event to tell a user about, with the details its
+// messages need. Client details come from the request context.
+type Notification struct {
+	Event constants.NotificationEvent
+
+	LoginMethod         string    // NotificationLoginAlert, NotificationNewLocation: how the user signed in
+	RecoveryCodesLeft   int       // NotificationRecoveryCodeUsed
+	RecoveryAvailableAt time.Time // NotificationRecoveryStarted: when 2FA turns off
+	CancelLink          string    // NotificationRecoveryStarted
+	PasswordExpiresAt   time.Time // NotificationPasswordExpiring
+	PasswordChangedAt   time.Time // NotificationPasswordRotation
+	PasswordUpgrade     bool      // NotificationPasswordRotation: the password predates PASSWORD_HASH_UPGRADED_AT
+}
+
+// notificationTopic groups events whose channels are chosen the same way.
+type notificationTopic int
+
+const (
+	topicAccount       notificationTopic = iota // email only, always sent
+	topicCritical                               // email always sent, other channels per security_alert_* preferences
+	topicSecurityAlert                          // security_alert_* preferences
+	topicLogin                                  // login_notification* preferences
+	topicRecovery                               // every channel, whatever the preferences
+	topicReminder                               // email only
+)
+
+// notificationPolicy describes how an event is delivered: its topic, the
+// delivery log event of its email, the short text of its text message and
+// push notification, and for optional classes of notification the
+// preference that turns them off.
+type notificationPolicy struct {
+	topic      notificationTopic
+	emailEvent constants.EmailEvent
+	text       string
+	enabled    func(*models.NotificationPreferences) bool // nil: can't be turned off
+}
+
+var notificationPolicies = map[constants.NotificationEvent]notificationPolicy{
+	constants.NotificationWelcome:              {topic: topicAccount, emailEvent: constants.EmailWelcome},
+	constants.NotificationOnboardingTips:       {topic: topicAccount, emailEvent: constants.EmailOnboardingTips},
+	constants.NotificationOnboardingSecurity:   {topic: topicAccount, emailEvent: constants.EmailOnboardingSecurity},
+	constants.NotificationRegistrationApproved: {topic: topicAccount, emailEvent: constants.EmailRegistrationApproved},
+	constants.NotificationRegistrationRejected: {topic: topicAccount, emailEvent: constants.EmailRegistrationRejected},
+	constants.NotificationPasswordChanged: {
+		topic: topicCritical, emailEvent: constants.EmailPasswordChanged,
+		text: "Your password was changed. If this wasn't you, reset it now.",
+	},
+	constants.NotificationTwoFactorDisabled: {
+		topic: topicCritical, emailEvent: constants.EmailTwoFactorDisabled,
+		text: "Two-factor authentication was turned off on your account. If this wasn't you, secure your account now.",
+	},
+	constants.NotificationNewLocation: {
+		topic: topicSecurityAlert, emailEvent: constants.EmailNewLocationLogin,
+		text:    "New sign-in to your account from a new location. If this wasn't you, change your password.",
+		enabled: func(prefs *models.NotificationPreferences) bool { return prefs.NewLocationAlerts },
+	},
+	constants.NotificationPasswordExpiring: {
+		topic: topicReminder, emailEvent: constants.EmailPasswordExpiring,
+		enabled: func(prefs *models.NotificationPreferences) bool { return prefs.PasswordExpiryReminders },
+	},
+	constants.NotificationPasswordRotation: {
+		topic: topicReminder, emailEvent: constants.EmailPasswordRotation,
+		enabled: func(prefs *models.NotificationPreferences) bool { return prefs.PasswordExpiryReminders },
+	},
+	constants.NotificationLoginAlert: {
+		topic: topicLogin, emailEvent: constants.EmailLoginAlert,
+		text: "New sign-in to your account. If this wasn't you, change your password.",
+	},
+	constants.NotificationRecoveryCodeUsed: {
+		topic: topicRecovery, emailEvent: constants.EmailTwoFactorRecovery,
+		text: "A recovery code was used to log in to your account.",
+	},
+	constants.NotificationRecoveryStarted: {
+		topic: topicRecovery, emailEvent: constants.EmailTwoFactorRecovery,
+		text: "Someone asked to turn off two-factor authentication on your account. Check your email to cancel.",
+	},
+	constants.NotificationRecoveryCompleted: {
+		topic: topicRecovery, emailEvent: constants.EmailTwoFactorRecovery,
+		text: "Two-factor authentication was turned off on your account after a recovery request.",
+	},
+}
+
+// Notify tells user about n on the channels its event and the user's
+// preferences select: email, a text message to their phone number, and
+// their push devices. Optional classes of notification the user turned off
+// aren't sent at all, while critical ones are always emailed. Channels the
+// server has no provider for are skipped. Delivery runs in the background of
+// the request, so failures are logged rather than returned.
+func (s *AuthService) Notify(ctx context.Context, user *models.User, n Notification) {
+	policy, ok := notificationPolicies[n.Event]
+	if !ok {
+		logger.Error("unknown notification event", "event", n.Event)
+		return
+	}
+
+	byEmail, bySMS, byPush := true, false, false
+	phoneNumber := ""
+	if policy.topic != topicAccount {
+		prefs := s.notificationPreferences(ctx, user.ID)
+		if policy.enabled != nil && !policy.enabled(prefs) {
+			return
+		}
+		phoneNumber = prefs.PhoneNumber
+		switch policy.topic {
+		case topicCritical:
+			bySMS, byPush = prefs.SecurityAlertSMS, prefs.SecurityAlertPush
+		case topicSecurityAlert:
+			byEmail, bySMS, byPush = prefs.SecurityAlertEmails, prefs.SecurityAlertSMS, prefs.SecurityAlertPush
+		case topicLogin:
+			byEmail, bySMS, byPush = prefs.LoginNotifications, prefs.LoginNotificationSMS, prefs.LoginNotificationPush
+		case topicRecovery:
+			bySMS, byPush = true, true
+		}
+	}
+
+	if byEmail {
+		s.notifyByEmail(ctx, user, n, policy)
+	}
+	if bySMS && s.sms != nil && phoneNumber != "" {
+		if err := s.sms.Send(ctx, phoneNumber, "Authentio: "+policy.text); err != nil {
+			logger.Warn("failed to send notification text message", "error", err, "event", n.Event, "userID", user.ID)
+		}
+	}
+	if byPush && s.push != nil {
+		s.notifyByPush(ctx, user, n, policy)
+	}
+}
+
+// notifyByEmail renders and sends the email of n.
+func (s *AuthService) notifyByEmail(ctx context.Context, user *models.User, n Notification, policy notificationPolicy) {
+	client := requestctx.ClientInfoFrom(ctx)
+	now := time.Now()
+
+	var msg *email.Message
+	var err error
+	switch n.Event {
+	case constants.NotificationWelcome:
+		msg, err = s.emailRender.Welcome(ctx, user.FirstName)
+	case constants.NotificationOnboardingTips:
+		msg, err = s.emailRender.OnboardingTips(ctx, user.FirstName)
+	case constants.NotificationOnboardingSecurity:
+		msg, err = s.emailRender.OnboardingSecurity(ctx, user.FirstName)
+	case constants.NotificationRegistrationApproved:
+		msg, err = s.emailRender.RegistrationApproved(ctx, user.FirstName, strings.TrimRight(s.cfg.FrontendURL, "/")+"/login")
+	case constants.NotificationRegistrationRejected:
+		msg, err = s.emailRender.RegistrationRejected(ctx, user.FirstName)
+	case constants.NotificationPasswordChanged:
+		msg, err = s.emailRender.PasswordChanged(ctx)
+	case constants.NotificationTwoFactorDisabled:
+		msg, err = s.emailRender.TwoFactorDisabled(ctx, user.FirstName, client.IP, client.UserAgent, now)
+	case constants.NotificationNewLocation:
+		msg, err = s.emailRender.NewLocationLogin(ctx, user.FirstName, n.LoginMethod, client.IP, client.UserAgent, now)
+	case constants.NotificationPasswordExpiring:
+		msg, err = s.emailRender.PasswordExpiry(ctx, user.FirstName, n.PasswordExpiresAt)
+	case constants.NotificationPasswordRotation:
+		msg, err = s.emailRender.PasswordRotation(ctx, user.FirstName, n.PasswordChangedAt, n.PasswordUpgrade)
+	case constants.NotificationLoginAlert:
+		msg, err = s.emailRender.LoginAlert(ctx, user.FirstName, n.LoginMethod, client.IP, client.UserAgent, now)
+	case constants.NotificationRecoveryCodeUsed:
+		msg, err = s.emailRender.RecoveryCodeUsed(ctx, user.FirstName, client.IP, client.UserAgent, now, n.RecoveryCodesLeft)
+	case constants.NotificationRecoveryStarted:
+		msg, err = s.emailRender.RecoveryStarted(ctx, user.FirstName, client.IP, client.UserAgent, now, n.RecoveryAvailableAt, n.CancelLink)
+	case constants.NotificationRecoveryCompleted:
+		msg, err = s.emailRender.RecoveryCompleted(ctx, user.FirstName, client.IP, client.UserAgent, now)
+	default:
+		err = fmt.Errorf("no email for notification event %q", n.Event)
+	}
+	if err != nil {
+		logger.Warn("failed to render notification email", "error", err, "event", n.Event)
+		return
+	}
+
+	if err := s.sendEmail(ctx, policy.emailEvent, []string{user.Email}, msg); err != nil {
+		logger.Warn("failed to send notification email", "error", err, "event", n.Event, "email", user.Email)
+	}
+}
+
+// notifyByPush sends the text of n to each of the user's push devices.
+func (s *AuthService) notifyByPush(ctx context.Context, user *models.User, n Notification, policy notificationPolicy) {
+	devices, err := s.pushRepo.ListDevices(ctx, user.ID)
+	if err != nil {
+		logger.Warn("failed to list push devices", "error", err, "userID", user.ID)
+		return
+	}
+
+	notification := push.Notification{
+		Title: "Security alert",
+		Body:  policy.text,
+		Data:  map[string]string{"type": "security_alert", "event": string(n.Event)},
+	}
+	for _, device := range devices {
+		if err := s.push.Send(ctx, device.Platform, device.Token, notification); err != nil {
+			logger.Warn("failed to send push notification", "error", err, "event", n.Event, "deviceID", device.ID, "platform", device.Platform)
+		}
+	}
+}
+
+// ============================================================================
+// Login Notices
+// ============================================================================
+
+// loginSucceeded records a successful login by user with loginMethod and
+// sends the notices it triggers: the login notification, an alert when it
+// comes from an IP address none of the user's recent logins came from, and a
+// reminder when their password is about to expire.
+func (s *AuthService) loginSucceeded(ctx context.Context, user *models.User, loginMethod string, metadata map[string]interface{}) {
+	// Checked before this login joins the history
+	newLocation := s.isNewLocation(ctx, user.ID)
+	s.recordAudit(ctx, &user.ID, constants.AuditLoginSuccess, metadata)
+
+	s.Notify(ctx, user, Notification{Event: constants.NotificationLoginAlert, LoginMethod: loginMethod})
+	if newLocation {
+		s.Notify(ctx, user, Notification{Event: constants.NotificationNewLocation, LoginMethod: loginMethod})
+	}
+	s.remindPasswordExpiry(ctx, user)
+}
+
+// isNewLocation reports whether the client in ctx has an IP address none of
+// the recent logins of userID came from. A first login isn't new: there is
+// nothing to compare it with.
+func (s *AuthService) isNewLocation(ctx context.Context, userID int64) bool {
+	client := requestctx.ClientInfoFrom(ctx)
+	if client.IP == "" {
+		return false
+	}
+
+	entries, err := s.auditRepo.ListByUser(ctx, userID, []string{string(constants.AuditLoginSuccess)}, nil, riskHistorySize)
+	if err != nil {
+		logger.Warn("failed to load login history", "error", err, "userID", userID)
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IPAddress == client.IP {
+			return false
+		}
+	}
+	return len(entries) > 0
+}
+
+// remindPasswordExpiry emails user a reminder once their password is less
+// than PASSWORD_EXPIRY_REMINDER from PASSWORD_MAX_AGE. Each password gets
+// one reminder: none is sent when the delivery log has one since the
+// password was changed.
+func (s *AuthService) remindPasswordExpiry(ctx context.Context, user *models.User) {
+	if s.cfg.PasswordMaxAge <= 0 || s.cfg.PasswordExpiryReminder <= 0 || user.Password == "" || user.PasswordChangedAt == nil {
+		return
+	}
+	expiresAt := user.PasswordChangedAt.Add(s.cfg.PasswordMaxAge)
+	if time.Until(expiresAt) > s.cfg.PasswordExpiryReminder {
+		return
+	}
+
+	sent, err := s.emailLogRepo.List(ctx, models.EmailLogFilter{UserID: user.ID, Event: string(constants.EmailPasswordExpiring)}, nil, 1)
+	if err != nil {
+		logger.Warn("failed to check password expiry reminders", "error", err, "userID", user.ID)
+		return
+	}
+	if len(sent) > 0 && sent[0].CreatedAt.After(*user.PasswordChangedAt) {
+		return
+	}
+
+	s.Notify(ctx, user, Notification{Event: constants.NotificationPasswordExpiring, PasswordExpiresAt: expiresAt})
+}
@@ -0,0 +1,651 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/i18n"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+)
+
+// ============================================================================
+// OpenID Connect Provider
+// ============================================================================
+
+// Paths of the provider endpoints, relative to OIDC_ISSUER.
+const (
+	oidcAuthorizePath = "/api/v1/oauth/authorize"
+	oidcTokenPath     = "/api/v1/oauth/token"
+	oidcUserInfoPath  = "/api/v1/oauth/userinfo"
+	oidcJWKSPath      = "/.well-known/jwks.json"
+)
+
+// OIDCDiscovery returns the provider's discovery document.
+func (s *AuthService) OIDCDiscovery() (*response.OIDCDiscovery, error) {
+	if !s.oidcEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	issuer := s.oidcIssuer()
+	return &response.OIDCDiscovery{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + oidcAuthorizePath,
+		TokenEndpoint:                     issuer + oidcTokenPath,
+		UserInfoEndpoint:                  issuer + oidcUserInfoPath,
+		JWKSURI:                           issuer + oidcJWKSPath,
+		ScopesSupported:                   constants.OIDCScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{constants.GrantAuthorizationCode, constants.GrantRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "email", "name", "given_name", "family_name", "preferred_username", "locale", "picture"},
+	}, nil
+}
+
+// JWKS returns the public keys clients verify ID and access tokens with.
+func (s *AuthService) JWKS() ([]jwt.JWK, error) {
+	if !s.oidcEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+	return s.jwtManager.JWKS(), nil
+}
+
+// StartAuthorization validates an authorization request and returns where
+// to send the browser: the consent page with the request parameters, or the
+// client's redirect URI with an error. An error is returned only when the
+// client or redirect URI is invalid, since the user must not be redirected
+// then.
+func (s *AuthService) StartAuthorization(ctx context.Context, req models.AuthorizeRequest) (string, error) {
+	if _, _, err := s.validateAuthorizeRequest(ctx, req); err != nil {
+		var oauthErr *OAuthError
+		if errors.As(err, &oauthErr) {
+			return authorizeErrorRedirect(req, oauthErr), nil
+		}
+		return "", err
+	}
+
+	consentURL := s.cfg.OIDCConsentURL
+	if consentURL == "" {
+		consentURL = strings.TrimRight(s.cfg.FrontendURL, "/") + "/oauth/consent"
+	}
+	return appendQuery(consentURL, authorizeParams(req)), nil
+}
+
+// AuthorizationConsent describes an authorization request for the consent
+// screen: the client, the requested scopes in the user's language and whether
+// the user still has to approve them. Invalid requests return the same errors
+// as Authorize would redirect with, as *OAuthError.
+func (s *AuthService) AuthorizationConsent(ctx context.Context, userID int64, req models.AuthorizeRequest) (*models.OAuthConsentPrompt, error) {
+	client, scopes, err := s.validateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	grant, err := s.oauthRepo.FindGrant(ctx, userID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	locale := requestctx.LocaleFrom(ctx)
+	prompt := &models.OAuthConsentPrompt{
+		ClientID:   client.ClientID,
+		ClientName: client.Name,
+	}
+	for _, scope := range scopes {
+		granted := grant != nil && containsString(grant.Scopes, scope)
+		if !granted {
+			prompt.ConsentRequired = true
+		}
+		prompt.Scopes = append(prompt.Scopes, models.OAuthScopeDetail{
+			Scope:       scope,
+			Description: i18n.T(locale, "oauth_scope."+scope),
+			Granted:     granted,
+		})
+	}
+
+	return prompt, nil
+}
+
+// Authorize records the signed-in user's decision on an authorization request
+// and returns the client redirect URI carrying an authorization code, or an
+// access_denied error when the user declined. As with StartAuthorization, an
+// error is only returned when the user must not be redirected.
+func (s *AuthService) Authorize(ctx context.Context, userID int64, decision models.AuthorizeDecision) (string, error) {
+	req := decision.AuthorizeRequest
+	client, scopes, err := s.validateAuthorizeRequest(ctx, req)
+	if err != nil {
+		var oauthErr *OAuthError
+		if errors.As(err, &oauthErr) {
+			return authorizeErrorRedirect(req, oauthErr), nil
+		}
+		return "", err
+	}
+
+	if !decision.Approve {
+		logger.Info("authorization denied", "clientID", client.ClientID, "userID", userID)
+		return authorizeErrorRedirect(req, oauthError("access_denied", "the user denied the request")), nil
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return "", ErrUserNotFound
+	}
+	if err := checkApproval(user); err != nil {
+		return "", err
+	}
+
+	code := generateSecureToken()
+	authCode := &models.OAuthAuthorizationCode{
+		CodeHash:      hashOAuthToken(code),
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(scopes, " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(s.cfg.OIDCCodeTTL),
+	}
+	if err := s.oauthRepo.CreateCode(ctx, authCode); err != nil {
+		return "", err
+	}
+
+	// Remember the approval so later requests for the same scopes skip the
+	// consent screen
+	grant, err := s.oauthRepo.FindGrant(ctx, user.ID, client.ID)
+	if err != nil {
+		return "", err
+	}
+	granted := scopes
+	if grant != nil {
+		granted = grant.Scopes
+		for _, scope := range scopes {
+			if !containsString(granted, scope) {
+				granted = append(granted, scope)
+			}
+		}
+	}
+	if err := s.oauthRepo.SaveGrant(ctx, &models.OAuthGrant{
+		UserID:        user.ID,
+		OAuthClientID: client.ID,
+		Scopes:        granted,
+	}); err != nil {
+		return "", err
+	}
+
+	s.recordAudit(ctx, &user.ID, constants.AuditOAuthAuthorized, map[string]interface{}{
+		"client_id": client.ClientID,
+		"scope":     authCode.Scope,
+	})
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return appendQuery(req.RedirectURI, params), nil
+}
+
+// ExchangeToken handles a token endpoint request: it authenticates the
+// client and redeems an authorization code or refresh token for new tokens.
+// Failures are returned as *OAuthError.
+func (s *AuthService) ExchangeToken(ctx context.Context, req models.TokenRequest) (*response.OAuthTokenResponse, error) {
+	if !s.oidcEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case constants.GrantAuthorizationCode:
+		return s.exchangeAuthorizationCode(ctx, client, req)
+	case constants.GrantRefreshToken:
+		return s.exchangeRefreshToken(ctx, client, req.RefreshToken)
+	default:
+		return nil, oauthError("unsupported_grant_type", "grant_type must be authorization_code or refresh_token")
+	}
+}
+
+// UserInfo returns the claims about the user an access token was issued for,
+// limited to the scopes it was granted.
+func (s *AuthService) UserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	if !s.oidcEnabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	claims, err := s.jwtManager.VerifyProviderToken(accessToken, s.oidcIssuer())
+	if err != nil {
+		return nil, oauthError("invalid_token", "invalid or expired access token")
+	}
+	// ID tokens are signed with the same key but are not access tokens
+	if _, ok := claims["scope"].(string); !ok {
+		return nil, oauthError("invalid_token", "invalid or expired access token")
+	}
+	if tenant, _ := claims["tenant_id"].(float64); int64(tenant) != currentTenant(ctx) {
+		return nil, oauthError("invalid_token", "invalid or expired access token")
+	}
+
+	subject, _ := claims["sub"].(string)
+	userID, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		return nil, oauthError("invalid_token", "invalid or expired access token")
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, oauthError("invalid_token", "invalid or expired access token")
+	}
+
+	info := map[string]interface{}{"sub": subject}
+	addScopedClaims(info, user, strings.Fields(claims["scope"].(string)))
+	return info, nil
+}
+
+// ============================================================================
+// OAuth Client Administration
+// ============================================================================
+
+// CreateOAuthClient registers an application with the provider. The returned
+// client carries its secret, which is not stored and can't be retrieved later.
+func (s *AuthService) CreateOAuthClient(ctx context.Context, actorID int64, req models.OAuthClientRequest) (*models.OAuthClient, error) {
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = constants.OIDCScopes
+	}
+	if !containsString(scopes, constants.ScopeOpenID) {
+		scopes = append([]string{constants.ScopeOpenID}, scopes...)
+	}
+
+	client := &models.OAuthClient{
+		ClientID:     generateSecureToken()[:32],
+		Name:         strings.TrimSpace(req.Name),
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       scopes,
+		CreatedBy:    &actorID,
+	}
+	if !req.Public {
+		client.ClientSecret = generateSecureToken()
+		client.SecretHash = hashOAuthToken(client.ClientSecret)
+	}
+
+	if err := s.oauthRepo.CreateClient(ctx, client); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditOAuthClientCreated, map[string]interface{}{
+		"client_id": client.ClientID,
+		"name":      client.Name,
+	})
+
+	logger.Info("oauth client registered", "clientID", client.ClientID, "public", req.Public)
+	return client, nil
+}
+
+// ListOAuthClients returns every registered client, newest first.
+func (s *AuthService) ListOAuthClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	return s.oauthRepo.ListClients(ctx)
+}
+
+// DeleteOAuthClient removes a client. Its pending codes and refresh tokens
+// stop working immediately; access tokens expire on their own.
+func (s *AuthService) DeleteOAuthClient(ctx context.Context, id, actorID int64) error {
+	if err := s.oauthRepo.DeleteClient(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOAuthClientNotFound
+		}
+		return err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditOAuthClientDeleted, map[string]interface{}{"id": id})
+
+	logger.Info("oauth client deleted", "id", id, "actorID", actorID)
+	return nil
+}
+
+// ============================================================================
+// OAuth Grant Management
+// ============================================================================
+
+// ListOAuthGrants returns the clients userID has authorized and the scopes
+// approved for each.
+func (s *AuthService) ListOAuthGrants(ctx context.Context, userID int64) ([]*models.OAuthGrant, error) {
+	return s.oauthRepo.ListGrants(ctx, userID)
+}
+
+// RevokeOAuthGrant withdraws userID's approval for a client. The client's
+// refresh tokens for the user stop working immediately and the next
+// authorization request asks for consent again.
+func (s *AuthService) RevokeOAuthGrant(ctx context.Context, userID int64, clientID string) error {
+	client, err := s.oauthRepo.FindClientByClientID(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return ErrOAuthGrantNotFound
+	}
+
+	if err := s.oauthRepo.RevokeGrant(ctx, userID, client.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOAuthGrantNotFound
+		}
+		return err
+	}
+
+	s.recordAudit(ctx, &userID, constants.AuditOAuthGrantRevoked, map[string]interface{}{"client_id": client.ClientID})
+
+	logger.Info("oauth grant revoked", "clientID", client.ClientID, "userID", userID)
+	return nil
+}
+
+// ============================================================================
+// OpenID Connect Helpers
+// ============================================================================
+
+// validateAuthorizeRequest checks an authorization request against the
+// registered client and returns the client and the requested scopes. Invalid
+// clients and redirect URIs are reported as ServiceErrors; everything else as
+// *OAuthError, to be sent to the redirect URI.
+func (s *AuthService) validateAuthorizeRequest(ctx context.Context, req models.AuthorizeRequest) (*models.OAuthClient, []string, error) {
+	if !s.oidcEnabled() {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	client, err := s.oauthRepo.FindClientByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil {
+		return nil, nil, ErrOAuthClientNotFound
+	}
+	if !client.AllowsRedirect(req.RedirectURI) {
+		return nil, nil, ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != "code" {
+		return nil, nil, oauthError("unsupported_response_type", "only the code response type is supported")
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if !containsString(scopes, constants.ScopeOpenID) {
+		return nil, nil, oauthError("invalid_scope", "the openid scope is required")
+	}
+	for _, scope := range scopes {
+		if !containsString(client.Scopes, scope) {
+			return nil, nil, oauthError("invalid_scope", "scope "+scope+" is not allowed for this client")
+		}
+	}
+
+	// PKCE is required for public clients and optional for confidential ones
+	if req.CodeChallenge == "" {
+		if client.Public() {
+			return nil, nil, oauthError("invalid_request", "code_challenge is required for public clients")
+		}
+	} else if req.CodeChallengeMethod != "S256" {
+		return nil, nil, oauthError("invalid_request", "code_challenge_method must be S256")
+	}
+
+	return client, scopes, nil
+}
+
+// authenticateClient identifies the client of a token request. Confidential
+// clients must present their secret; public clients must not have one.
+func (s *AuthService) authenticateClient(ctx context.Context, clientID, secret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+
+	client, err := s.oauthRepo.FindClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+
+	if client.Public() {
+		if secret != "" {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashOAuthToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	return client, nil
+}
+
+// exchangeAuthorizationCode redeems a single-use authorization code.
+func (s *AuthService) exchangeAuthorizationCode(ctx context.Context, client *models.OAuthClient, req models.TokenRequest) (*response.OAuthTokenResponse, error) {
+	if req.Code == "" {
+		return nil, oauthError("invalid_request", "code is required")
+	}
+
+	code, err := s.oauthRepo.ConsumeCode(ctx, hashOAuthToken(req.Code))
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.ClientID != client.ID || code.RedirectURI != req.RedirectURI {
+		return nil, oauthError("invalid_grant", "invalid, expired or already used authorization code")
+	}
+	if code.CodeChallenge != "" && !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError("invalid_grant", "code_verifier does not match the code challenge")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, code.UserID)
+	if err != nil || user == nil || checkApproval(user) != nil {
+		return nil, oauthError("invalid_grant", "the user can no longer sign in")
+	}
+
+	logger.Info("authorization code redeemed", "clientID", client.ClientID, "userID", user.ID)
+	return s.issueClientTokens(ctx, client, user, strings.Fields(code.Scope), code.Nonce)
+}
+
+// exchangeRefreshToken rotates a refresh token issued to client.
+func (s *AuthService) exchangeRefreshToken(ctx context.Context, client *models.OAuthClient, refreshToken string) (*response.OAuthTokenResponse, error) {
+	token, err := s.tokenRepo.GetRefreshToken(ctx, refreshToken)
+	if err != nil || token.OAuthClientID == nil || *token.OAuthClientID != client.ID {
+		return nil, oauthError("invalid_grant", "invalid or expired refresh token")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, token.UserID)
+	if err != nil || user == nil || checkApproval(user) != nil {
+		return nil, oauthError("invalid_grant", "the user can no longer sign in")
+	}
+
+	// Token rotation: the old refresh token can't be used again
+	if err := s.tokenRepo.DeleteRefreshToken(ctx, refreshToken); err != nil {
+		return nil, oauthError("invalid_grant", "invalid or expired refresh token")
+	}
+
+	return s.issueClientTokens(ctx, client, user, strings.Fields(token.Scope), "")
+}
+
+// issueClientTokens creates the access token, ID token and, with the
+// offline_access scope, a refresh token for client acting on behalf of user.
+func (s *AuthService) issueClientTokens(ctx context.Context, client *models.OAuthClient, user *models.User, scopes []string, nonce string) (*response.OAuthTokenResponse, error) {
+	key := s.jwtManager.Key(user.TenantID)
+	now := time.Now()
+	subject := strconv.FormatInt(user.ID, 10)
+	scope := strings.Join(scopes, " ")
+
+	accessToken, err := s.jwtManager.SignProviderToken(jwtlib.MapClaims{
+		"iss":       s.oidcIssuer(),
+		"sub":       subject,
+		"aud":       client.ClientID,
+		"client_id": client.ClientID,
+		"scope":     scope,
+		"tenant_id": user.TenantID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(key.AccessTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	idClaims := jwtlib.MapClaims{
+		"iss": s.oidcIssuer(),
+		"sub": subject,
+		"aud": client.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(key.AccessTTL).Unix(),
+	}
+	if nonce != "" {
+		idClaims["nonce"] = nonce
+	}
+	addScopedClaims(idClaims, user, scopes)
+	idToken, err := s.jwtManager.SignProviderToken(idClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &response.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(key.AccessTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       scope,
+	}
+
+	if containsString(scopes, constants.ScopeOfflineAccess) {
+		refreshToken := &models.RefreshToken{
+			UserID:        user.ID,
+			Token:         generateSecureToken(),
+			OAuthClientID: &client.ID,
+			Scope:         scope,
+			BaseModel: models.BaseModel{
+				CreatedAt: now,
+				UpdatedAt: now,
+				ExpiredAt: timePtr(now.Add(key.RefreshTTL)),
+			},
+		}
+		if err := s.tokenRepo.SaveRefreshToken(ctx, refreshToken); err != nil {
+			return nil, err
+		}
+		resp.RefreshToken = refreshToken.Token
+	}
+
+	return resp, nil
+}
+
+// oidcEnabled reports whether provider mode is configured.
+func (s *AuthService) oidcEnabled() bool {
+	return s.cfg.OIDCIssuer != ""
+}
+
+// oidcIssuer returns the issuer identifier without a trailing slash.
+func (s *AuthService) oidcIssuer() string {
+	return strings.TrimRight(s.cfg.OIDCIssuer, "/")
+}
+
+// addScopedClaims adds the user claims covered by scopes to claims.
+func addScopedClaims(claims map[string]interface{}, user *models.User, scopes []string) {
+	if containsString(scopes, constants.ScopeEmail) {
+		claims["email"] = user.Email
+	}
+	if containsString(scopes, constants.ScopeProfile) {
+		claims["name"] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		if user.Username != "" {
+			claims["preferred_username"] = user.Username
+		}
+		if user.Locale != "" {
+			claims["locale"] = user.Locale
+		}
+		if user.AvatarURL != nil {
+			claims["picture"] = *user.AvatarURL
+		}
+	}
+}
+
+// authorizeParams returns the parameters of an authorization request, for
+// passing it on to the consent page.
+func authorizeParams(req models.AuthorizeRequest) url.Values {
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	return params
+}
+
+// authorizeErrorRedirect returns the client redirect URI reporting err.
+func authorizeErrorRedirect(req models.AuthorizeRequest, err *OAuthError) string {
+	params := url.Values{
+		"error":             {err.Code},
+		"error_description": {err.Description},
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return appendQuery(req.RedirectURI, params)
+}
+
+// appendQuery adds params to the query string of rawURL.
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against an S256 challenge.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// hashOAuthToken returns the SHA-256 hex digest stored for a client secret or
+// authorization code.
+func hashOAuthToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// containsString reports whether values contains value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
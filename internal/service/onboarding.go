@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Onboarding Emails
+// ============================================================================
+
+// onboardingCatchUp is how late a scheduled onboarding email may still be
+// sent, e.g. after downtime. Users registered earlier than that don't get
+// it, so enabling a step doesn't email every existing user.
+const onboardingCatchUp = 7 * 24 * time.Hour
+
+// onboardingBatchSize is how many users an onboarding run loads at a time.
+const onboardingBatchSize = 500
+
+// onboardingEvents maps the steps of ONBOARDING_EMAILS to their
+// notification.
+var onboardingEvents = map[string]constants.NotificationEvent{
+	constants.OnboardingWelcome:  constants.NotificationWelcome,
+	constants.OnboardingTips:     constants.NotificationOnboardingTips,
+	constants.OnboardingSecurity: constants.NotificationOnboardingSecurity,
+}
+
+// startOnboarding sends a new user the onboarding emails due at
+// registration, those of ONBOARDING_EMAILS without a delay.
+func (s *AuthService) startOnboarding(ctx context.Context, user *models.User) {
+	steps, _ := s.cfg.OnboardingSequence() // checked by cfg.Validate
+	for _, step := range steps {
+		if step.Delay == 0 {
+			s.sendOnboardingEmail(ctx, user, step.Name)
+		}
+	}
+}
+
+// StartOnboardingEmails sends every tenant's new users the onboarding emails
+// that became due, now and then every interval until ctx is cancelled, from
+// the server running the scheduler.
+func (s *AuthService) StartOnboardingEmails(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	steps, _ := s.cfg.OnboardingSequence() // checked by cfg.Validate
+	scheduled := false
+	for _, step := range steps {
+		scheduled = scheduled || step.Delay > 0
+	}
+	if !scheduled {
+		return
+	}
+
+	s.startScheduled(ctx, "onboarding-emails", interval, s.onboardAllTenants)
+}
+
+// onboardAllTenants runs SendOnboardingEmails for each tenant.
+func (s *AuthService) onboardAllTenants(ctx context.Context) {
+	tenants, err := s.tenantRepo.List(ctx)
+	if err != nil {
+		logger.Warn("failed to list tenants for onboarding emails", "error", err)
+		return
+	}
+	for _, tenant := range tenants {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.SendOnboardingEmails(requestctx.WithTenant(ctx, tenant.ID)); err != nil {
+			logger.Warn("onboarding emails failed", "error", err, "tenantID", tenant.ID)
+		}
+	}
+}
+
+// SendOnboardingEmails sends the tenant's users the scheduled onboarding
+// emails that became due and weren't sent yet, according to the delivery
+// log. It returns how many users were due one.
+func (s *AuthService) SendOnboardingEmails(ctx context.Context) (int, error) {
+	steps, _ := s.cfg.OnboardingSequence() // checked by cfg.Validate
+	now := time.Now()
+
+	due := 0
+	for _, step := range steps {
+		if step.Delay == 0 {
+			continue
+		}
+		event := notificationPolicies[onboardingEvents[step.Name]].emailEvent
+		registeredBefore := now.Add(-step.Delay)
+
+		var after int64
+		for {
+			if err := ctx.Err(); err != nil {
+				return due, err
+			}
+			users, err := s.userRepo.FindNotEmailed(ctx, registeredBefore.Add(-onboardingCatchUp), registeredBefore, string(event), after, onboardingBatchSize)
+			if err != nil {
+				return due, err
+			}
+			for _, user := range users {
+				s.sendOnboardingEmail(ctx, user, step.Name)
+				after = user.ID
+			}
+			due += len(users)
+			if len(users) < onboardingBatchSize {
+				break
+			}
+		}
+	}
+
+	if due > 0 {
+		logger.Info("onboarding emails sent", "tenantID", currentTenant(ctx), "users", due)
+	}
+	return due, nil
+}
+
+// sendOnboardingEmail sends user the email of an onboarding step. The
+// security step is skipped for users who already turned on two-factor
+// authentication.
+func (s *AuthService) sendOnboardingEmail(ctx context.Context, user *models.User, step string) {
+	if step == constants.OnboardingSecurity {
+		enabled, err := s.twoFARepo.Is2FAEnabled(ctx, user.ID)
+		if err != nil {
+			logger.Warn("failed to check 2FA for onboarding", "error", err, "userID", user.ID)
+			return
+		}
+		if enabled {
+			return
+		}
+	}
+	s.Notify(ctx, user, Notification{Event: onboardingEvents[step]})
+}
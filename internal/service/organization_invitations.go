@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Organization Invitations
+// ============================================================================
+
+// CreateOrganizationInvitation emails an invite link for joining an
+// organization. Owners and admins can invite, granting at most their own role.
+func (s *AuthService) CreateOrganizationInvitation(ctx context.Context, orgID, actorID int64, req models.OrganizationInvitationRequest) (*models.OrganizationInvitation, error) {
+	actor, err := s.requireOrgManager(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	role := req.Role
+	if role == "" {
+		role = constants.OrgRoleMember
+	}
+	if !constants.OrgRoleAtLeast(actor.Role, role) {
+		return nil, ErrOrgPermission
+	}
+
+	org, err := s.orgRepo.FindByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrgNotFound
+	}
+
+	inviter, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil || inviter == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Existing members don't need an invitation
+	if existing, _ := s.userRepo.FindByEmail(ctx, req.Email); existing != nil {
+		if member, err := s.orgRepo.FindMember(ctx, orgID, existing.ID); err != nil {
+			return nil, err
+		} else if member != nil {
+			return nil, ErrAlreadyOrgMember
+		}
+	}
+
+	token := generateSecureToken()
+	invitation := &models.OrganizationInvitation{
+		OrganizationID: orgID,
+		Email:          strings.TrimSpace(req.Email),
+		Role:           role,
+		TokenHash:      hashInviteToken(token),
+		InvitedBy:      &actorID,
+		ExpiresAt:      time.Now().Add(s.cfg.InvitationTTL),
+	}
+	if err := s.orgInvites.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+	invitation.Status = constants.InvitationPending
+
+	link := strings.TrimRight(s.cfg.FrontendURL, "/") + "/orgs/join?invite=" + url.QueryEscape(token)
+	inviterName := strings.TrimSpace(inviter.FirstName + " " + inviter.LastName)
+	msg, err := s.emailRender.OrganizationInvitation(ctx, inviterName, org.Name, link, invitation.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sendEmail(ctx, constants.EmailOrganizationInvitation, []string{invitation.Email}, msg); err != nil {
+		logger.Error("failed to send organization invitation email", "error", err, "invitationID", invitation.ID)
+		return nil, ErrEmailSendFailed
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditOrgInviteCreated, map[string]interface{}{
+		"organization_id": orgID,
+		"invitation_id":   invitation.ID,
+		"email":           invitation.Email,
+		"role":            role,
+	})
+
+	logger.Info("organization invitation sent", "orgID", orgID, "invitationID", invitation.ID, "invitedBy", actorID)
+	return invitation, nil
+}
+
+// ListOrganizationInvitations returns an organization's invitations, newest
+// first. Only owners and admins can list them.
+func (s *AuthService) ListOrganizationInvitations(ctx context.Context, orgID, actorID int64) ([]*models.OrganizationInvitation, error) {
+	if _, err := s.requireOrgManager(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.orgInvites.ListByOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, invitation := range invitations {
+		invitation.Status = invitation.CurrentStatus(now)
+	}
+	return invitations, nil
+}
+
+// RevokeOrganizationInvitation cancels a pending organization invitation.
+func (s *AuthService) RevokeOrganizationInvitation(ctx context.Context, orgID, invitationID, actorID int64) error {
+	if _, err := s.requireOrgManager(ctx, orgID, actorID); err != nil {
+		return err
+	}
+
+	invitation, err := s.orgInvites.FindByID(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation == nil || invitation.OrganizationID != orgID {
+		return ErrInvitationNotFound
+	}
+	if invitation.CurrentStatus(time.Now()) != constants.InvitationPending {
+		return ErrInvalidInvite
+	}
+
+	if err := s.orgInvites.Revoke(ctx, invitationID); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditOrgInviteRevoked, map[string]interface{}{
+		"organization_id": orgID,
+		"invitation_id":   invitationID,
+	})
+
+	logger.Info("organization invitation revoked", "orgID", orgID, "invitationID", invitationID, "actorID", actorID)
+	return nil
+}
+
+// AcceptOrganizationInvitation joins an existing user to the organization
+// that invited them. The invitation must be addressed to the user's email.
+func (s *AuthService) AcceptOrganizationInvitation(ctx context.Context, userID int64, token string) (*models.Organization, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	invitation, err := s.resolveOrgInvitation(ctx, token, user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil {
+		return nil, ErrInvalidInvite
+	}
+
+	member, err := s.orgRepo.FindMember(ctx, invitation.OrganizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		return nil, ErrAlreadyOrgMember
+	}
+
+	if err := s.joinOrganization(ctx, invitation, user); err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgRepo.FindByID(ctx, invitation.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrgNotFound
+	}
+	org.Role = invitation.Role
+	return org, nil
+}
+
+// resolveOrgInvitation validates an organization invite token for email. It
+// returns nil, nil when no token is given.
+func (s *AuthService) resolveOrgInvitation(ctx context.Context, token, email string) (*models.OrganizationInvitation, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	invitation, err := s.orgInvites.FindByTokenHash(ctx, hashInviteToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil ||
+		invitation.CurrentStatus(time.Now()) != constants.InvitationPending ||
+		!strings.EqualFold(invitation.Email, strings.TrimSpace(email)) {
+		return nil, ErrInvalidInvite
+	}
+
+	return invitation, nil
+}
+
+// joinOrganization claims invitation for user and adds them to its
+// organization with the invited role.
+func (s *AuthService) joinOrganization(ctx context.Context, invitation *models.OrganizationInvitation, user *models.User) error {
+	// Claim the invitation first so it can only be used once
+	if err := s.orgInvites.MarkAccepted(ctx, invitation.ID, user.ID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidInvite
+		}
+		return err
+	}
+
+	if err := s.orgRepo.AddMember(ctx, invitation.OrganizationID, user.ID, invitation.Role); err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, &user.ID, constants.AuditOrgJoined, map[string]interface{}{
+		"organization_id": invitation.OrganizationID,
+		"invitation_id":   invitation.ID,
+		"role":            invitation.Role,
+	})
+
+	logger.Info("user joined organization", "orgID", invitation.OrganizationID, "userID", user.ID)
+	return nil
+}
+
+// joinInvitedOrganization adds a newly registered user to the organization
+// that invited them. Failures are logged: the account already exists.
+func (s *AuthService) joinInvitedOrganization(ctx context.Context, invitation *models.OrganizationInvitation, user *models.User) {
+	if invitation == nil {
+		return
+	}
+	if err := s.joinOrganization(ctx, invitation, user); err != nil {
+		logger.Error("failed to join invited organization", "error", err, "orgID", invitation.OrganizationID, "userID", user.ID)
+	}
+}
+
+// requireOrgManager returns actorID's membership in orgID if their role lets
+// them manage the organization (owner or admin).
+func (s *AuthService) requireOrgManager(ctx context.Context, orgID, actorID int64) (*models.OrganizationMember, error) {
+	membership, err := s.requireMembership(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !constants.OrgRoleAtLeast(membership.Role, constants.OrgRoleAdmin) {
+		return nil, ErrOrgPermission
+	}
+	return membership, nil
+}
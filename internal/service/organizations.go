@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Organizations
+// ============================================================================
+
+// CreateOrganization creates an organization owned by userID. The slug is
+// derived from the name when not given.
+func (s *AuthService) CreateOrganization(ctx context.Context, userID int64, req models.OrganizationRequest) (*models.Organization, error) {
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if slug == "" {
+		// Names without any ASCII letters or digits
+		slug = "org-" + generateSecureToken()[:8]
+	}
+
+	existing, err := s.orgRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrOrgSlugTaken
+	}
+
+	org := &models.Organization{
+		Name:      strings.TrimSpace(req.Name),
+		Slug:      slug,
+		CreatedBy: &userID,
+	}
+	if err := s.orgRepo.Create(ctx, org, userID); err != nil {
+		return nil, err
+	}
+	org.Role = constants.OrgRoleOwner
+
+	s.recordAudit(ctx, &userID, constants.AuditOrgCreated, map[string]interface{}{
+		"organization_id": org.ID,
+		"slug":            org.Slug,
+	})
+
+	logger.Info("organization created", "orgID", org.ID, "slug", org.Slug, "userID", userID)
+	return org, nil
+}
+
+// ListUserOrganizations returns the organizations userID belongs to, with the
+// user's role in each.
+func (s *AuthService) ListUserOrganizations(ctx context.Context, userID int64) ([]*models.Organization, error) {
+	return s.orgRepo.ListByUser(ctx, userID)
+}
+
+// GetOrganization returns an organization userID belongs to.
+func (s *AuthService) GetOrganization(ctx context.Context, orgID, userID int64) (*models.Organization, error) {
+	membership, err := s.requireMembership(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgRepo.FindByID(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrgNotFound
+	}
+	org.Role = membership.Role
+	return org, nil
+}
+
+// ListOrganizationMembers returns the members of an organization userID
+// belongs to.
+func (s *AuthService) ListOrganizationMembers(ctx context.Context, orgID, userID int64) ([]*models.OrganizationMember, error) {
+	if _, err := s.requireMembership(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListMembers(ctx, orgID)
+}
+
+// LeaveOrganization removes userID from an organization. The last owner can't
+// leave, so every organization keeps someone able to manage it.
+func (s *AuthService) LeaveOrganization(ctx context.Context, orgID, userID int64) error {
+	membership, err := s.requireMembership(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureOtherOwner(ctx, orgID, membership); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.RemoveMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+	s.revokeAccessTokens(ctx, userID, "organization_left")
+
+	s.recordAudit(ctx, &userID, constants.AuditOrgLeft, map[string]interface{}{"organization_id": orgID})
+
+	logger.Info("user left organization", "orgID", orgID, "userID", userID)
+	return nil
+}
+
+// SwitchOrganization issues a new token pair scoped to an organization userID
+// belongs to. The access token carries org_id and org_role claims, and the
+// scope is kept when the refresh token is used. authTime is when the user
+// authenticated in the session making the switch (zero when unknown).
+func (s *AuthService) SwitchOrganization(ctx context.Context, orgID, userID int64, authTime time.Time) (*response.LoginResponse, error) {
+	membership, err := s.requireMembership(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return s.issueTokens(ctx, user, membership, authTime)
+}
+
+// ============================================================================
+// Member Management
+// ============================================================================
+
+// UpdateOrganizationMemberRole promotes or demotes a member. Owners can change
+// any role; admins can only change members and grant at most admin. The last
+// owner can't be demoted.
+func (s *AuthService) UpdateOrganizationMemberRole(ctx context.Context, orgID, targetID, actorID int64, role string) (*models.OrganizationMember, error) {
+	target, err := s.manageableMember(ctx, orgID, targetID, actorID, role)
+	if err != nil {
+		return nil, err
+	}
+	if target.Role == role {
+		return target, nil
+	}
+
+	if err := s.ensureOtherOwner(ctx, orgID, target); err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.UpdateMemberRole(ctx, orgID, targetID, role); err != nil {
+		return nil, err
+	}
+	s.revokeAccessTokens(ctx, targetID, "organization_role_changed")
+
+	s.recordAudit(ctx, &actorID, constants.AuditOrgRoleChanged, map[string]interface{}{
+		"organization_id": orgID,
+		"user_id":         targetID,
+		"from":            target.Role,
+		"to":              role,
+	})
+
+	logger.Info("organization role changed", "orgID", orgID, "userID", targetID, "role", role, "actorID", actorID)
+	target.Role = role
+	return target, nil
+}
+
+// RemoveOrganizationMember removes a member from an organization. Owners can
+// remove anyone; admins can only remove members. The last owner can't be
+// removed.
+func (s *AuthService) RemoveOrganizationMember(ctx context.Context, orgID, targetID, actorID int64) error {
+	if targetID == actorID {
+		return s.LeaveOrganization(ctx, orgID, actorID)
+	}
+
+	target, err := s.manageableMember(ctx, orgID, targetID, actorID, "")
+	if err != nil {
+		return err
+	}
+	if err := s.ensureOtherOwner(ctx, orgID, target); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.RemoveMember(ctx, orgID, targetID); err != nil {
+		return err
+	}
+	s.revokeAccessTokens(ctx, targetID, "organization_member_removed")
+
+	s.recordAudit(ctx, &actorID, constants.AuditOrgMemberRemoved, map[string]interface{}{
+		"organization_id": orgID,
+		"user_id":         targetID,
+	})
+
+	logger.Info("organization member removed", "orgID", orgID, "userID", targetID, "actorID", actorID)
+	return nil
+}
+
+// manageableMember returns targetID's membership if actorID may change it and,
+// when newRole is set, grant newRole. Owners may act on anyone; other managers
+// only on members below their own role and only up to their own role.
+func (s *AuthService) manageableMember(ctx context.Context, orgID, targetID, actorID int64, newRole string) (*models.OrganizationMember, error) {
+	actor, err := s.requireOrgManager(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.orgRepo.FindMember(ctx, orgID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrOrgMemberNotFound
+	}
+
+	if actor.Role != constants.OrgRoleOwner {
+		if constants.OrgRoleAtLeast(target.Role, actor.Role) {
+			return nil, ErrOrgPermission
+		}
+		if newRole != "" && !constants.OrgRoleAtLeast(actor.Role, newRole) {
+			return nil, ErrOrgPermission
+		}
+	}
+	return target, nil
+}
+
+// ensureOtherOwner returns ErrLastOrgOwner if target is the organization's
+// only owner, so every organization keeps someone able to manage it.
+func (s *AuthService) ensureOtherOwner(ctx context.Context, orgID int64, target *models.OrganizationMember) error {
+	if target.Role != constants.OrgRoleOwner {
+		return nil
+	}
+	owners, err := s.orgRepo.CountMembersWithRole(ctx, orgID, constants.OrgRoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOrgOwner
+	}
+	return nil
+}
+
+// requireMembership returns userID's membership in orgID. Non-members get
+// ErrOrgNotFound so organizations they don't belong to aren't revealed.
+func (s *AuthService) requireMembership(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	membership, err := s.orgRepo.FindMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return nil, ErrOrgNotFound
+	}
+	return membership, nil
+}
+
+// tokenMembership returns the membership a refresh token is scoped to, or nil
+// for personal tokens and users who have since left the organization.
+func (s *AuthService) tokenMembership(ctx context.Context, token *models.RefreshToken) (*models.OrganizationMember, error) {
+	if token.OrganizationID == nil {
+		return nil, nil
+	}
+	return s.orgRepo.FindMember(ctx, *token.OrganizationID, token.UserID)
+}
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slugify turns an organization name into a URL-safe slug.
+func slugify(name string) string {
+	slug := strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 63 {
+		slug = strings.TrimRight(slug[:63], "-")
+	}
+	return slug
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+	"authentio/pkg/pairing"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// QR Code Pairing
+// ============================================================================
+
+// pairingPollMargin is kept between the end of a poll's wait and the
+// request deadline, to answer before it passes.
+const pairingPollMargin = time.Second
+
+// StartPairing starts a QR code pairing for the desktop client making the
+// request. The desktop shows the code as a QR code and polls PollPairing
+// with the poll token until the app that scanned it answers.
+func (s *AuthService) StartPairing(ctx context.Context) (*response.PairingSession, error) {
+	if s.pairings == nil {
+		return nil, ErrPairingUnavailable
+	}
+
+	client := requestctx.ClientInfoFrom(ctx)
+	pollToken := generateSecureToken()
+	p := &pairing.Pairing{
+		Code:          generateSecureToken()[:32],
+		PollTokenHash: hashPollToken(pollToken),
+		IPAddress:     client.IP,
+		UserAgent:     client.UserAgent,
+		Country:       client.Country,
+	}
+	if err := s.pairings.Create(ctx, currentTenant(ctx), p); err != nil {
+		return nil, err
+	}
+
+	return &response.PairingSession{Code: p.Code, PollToken: pollToken, ExpiresAt: p.ExpiresAt}, nil
+}
+
+// GetPairing describes the desktop client behind a pending pairing code, for
+// the app to show before the user answers.
+func (s *AuthService) GetPairing(ctx context.Context, code string) (*response.PairingDetails, error) {
+	if s.pairings == nil {
+		return nil, ErrPairingUnavailable
+	}
+	p, err := s.pairings.Get(ctx, currentTenant(ctx), code)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.Status != pairing.StatusPending {
+		return nil, ErrPairingNotFound
+	}
+
+	return &response.PairingDetails{
+		Code:      p.Code,
+		IPAddress: p.IPAddress,
+		UserAgent: p.UserAgent,
+		Country:   p.Country,
+		ExpiresAt: p.ExpiresAt,
+	}, nil
+}
+
+// DecidePairing approves or denies a pending pairing scanned by the app of
+// userID. An approved desktop is logged in as userID.
+func (s *AuthService) DecidePairing(ctx context.Context, userID int64, code string, approve bool) error {
+	if s.pairings == nil {
+		return ErrPairingUnavailable
+	}
+	tenantID := currentTenant(ctx)
+	p, err := s.pairings.Get(ctx, tenantID, code)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return ErrPairingNotFound
+	}
+
+	decided, err := s.pairings.Decide(ctx, tenantID, code, userID, approve)
+	if err != nil {
+		return err
+	}
+	if !decided {
+		return ErrPairingNotFound
+	}
+
+	event := constants.AuditPairingDenied
+	if approve {
+		event = constants.AuditPairingApproved
+	}
+	s.recordAudit(ctx, &userID, event, map[string]interface{}{
+		"desktop_ip":         p.IPAddress,
+		"desktop_user_agent": p.UserAgent,
+	})
+	logger.Info("pairing decided", "userID", userID, "approved", approve)
+	return nil
+}
+
+// PollPairing waits up to PAIRING_POLL_WAIT for the app to answer the
+// pairing, returning ErrPairingPending while it hasn't, ErrPairingDenied or
+// ErrPairingExpired when the desktop can't be logged in, and the tokens
+// exactly once after approval, unless the approver's account can't sign in
+// from the desktop's address.
+func (s *AuthService) PollPairing(ctx context.Context, code, pollToken string) (*response.LoginResponse, error) {
+	if s.pairings == nil {
+		return nil, ErrPairingUnavailable
+	}
+	tenantID := currentTenant(ctx)
+	p, err := s.pairings.Get(ctx, tenantID, code)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPairingExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(hashPollToken(pollToken)), []byte(p.PollTokenHash)) != 1 {
+		return nil, ErrInvalidPollToken
+	}
+
+	// Answer before the request deadline passes
+	wait := s.cfg.PairingPollWait
+	if deadline, ok := ctx.Deadline(); ok {
+		wait = min(wait, time.Until(deadline)-pairingPollMargin)
+	}
+	p, err = s.pairings.Await(ctx, tenantID, code, wait)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPairingExpired
+	}
+
+	switch p.Status {
+	case pairing.StatusPending:
+		return nil, ErrPairingPending
+	case pairing.StatusDenied:
+		return nil, ErrPairingDenied
+	}
+
+	completed, err := s.pairings.Complete(ctx, tenantID, code)
+	if err != nil {
+		return nil, err
+	}
+	if completed == nil {
+		return nil, ErrPairingExpired
+	}
+
+	// The desktop signs in as the approver, so it gets the checks of their
+	// password logins, the IP allowlist and geo policy applying to the
+	// desktop's address. Approving in the app stands for the second factor
+	// these may ask for.
+	user, err := s.userRepo.FindByID(ctx, completed.UserID)
+	if err != nil || user == nil || !user.IsActive {
+		return nil, ErrUserNotFound
+	}
+	if err := checkApproval(user); err != nil {
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"method": "pairing", "reason": "approval_" + user.ApprovalStatus})
+		return nil, err
+	}
+	metadata := map[string]interface{}{"method": "pairing"}
+	if _, err := s.checkIPAllowlist(ctx, user, "pairing"); err != nil {
+		return nil, err
+	}
+	if _, err := s.checkGeoPolicy(ctx, user, "pairing", metadata); err != nil {
+		return nil, err
+	}
+
+	s.loginSucceeded(ctx, user, "pairing", metadata)
+
+	return s.generateAuthResponse(ctx, user, nil)
+}
+
+// hashPollToken returns the hex-encoded SHA-256 hash under which a pairing's
+// poll token is stored.
+func hashPollToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Password Rotation Reminders
+// ============================================================================
+
+// passwordRotationBatchSize is how many users a reminder run loads at a time.
+const passwordRotationBatchSize = 500
+
+// StartPasswordRotationReminders emails every tenant's users with stale
+// passwords now and then every interval until ctx is cancelled, from the
+// server running the scheduler. It does nothing unless PASSWORD_ROTATION_REMINDER_AGE or
+// PASSWORD_HASH_UPGRADED_AT is set.
+func (s *AuthService) StartPasswordRotationReminders(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if _, ok := s.passwordRotationCutoff(time.Now()); !ok {
+		return
+	}
+
+	s.startScheduled(ctx, "password-rotation-reminders", interval, s.remindAllTenants)
+}
+
+// remindAllTenants runs SendPasswordRotationReminders for each tenant.
+func (s *AuthService) remindAllTenants(ctx context.Context) {
+	tenants, err := s.tenantRepo.List(ctx)
+	if err != nil {
+		logger.Warn("failed to list tenants for password rotation reminders", "error", err)
+		return
+	}
+	for _, tenant := range tenants {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := s.SendPasswordRotationReminders(requestctx.WithTenant(ctx, tenant.ID)); err != nil {
+			logger.Warn("password rotation reminders failed", "error", err, "tenantID", tenant.ID)
+		}
+	}
+}
+
+// SendPasswordRotationReminders emails the tenant's users whose password is
+// older than PASSWORD_ROTATION_REMINDER_AGE, or was set before
+// PASSWORD_HASH_UPGRADED_AT, a reminder to change it. Users reminded within
+// PASSWORD_ROTATION_REMINDER_SUPPRESSION are skipped, and users who turned
+// password reminders off aren't emailed. It returns how many users were due.
+func (s *AuthService) SendPasswordRotationReminders(ctx context.Context) (int, error) {
+	now := time.Now()
+	cutoff, ok := s.passwordRotationCutoff(now)
+	if !ok {
+		return 0, nil
+	}
+	remindedSince := now.Add(-s.cfg.PasswordRotationReminderSuppression)
+
+	due := 0
+	var after int64
+	for ctx.Err() == nil {
+		users, err := s.userRepo.FindStalePasswords(ctx, cutoff, string(constants.EmailPasswordRotation), remindedSince, after, passwordRotationBatchSize)
+		if err != nil {
+			return due, err
+		}
+		for _, user := range users {
+			changedAt := user.CreatedAt
+			if user.PasswordChangedAt != nil {
+				changedAt = *user.PasswordChangedAt
+			}
+			s.Notify(ctx, user, Notification{
+				Event:             constants.NotificationPasswordRotation,
+				PasswordChangedAt: changedAt,
+				PasswordUpgrade:   changedAt.Before(s.cfg.PasswordHashUpgradedAt),
+			})
+			after = user.ID
+		}
+		due += len(users)
+		if len(users) < passwordRotationBatchSize {
+			break
+		}
+	}
+
+	if due > 0 {
+		logger.Info("password rotation reminders sent", "tenantID", currentTenant(ctx), "users", due)
+	}
+	return due, ctx.Err()
+}
+
+// passwordRotationCutoff returns the time before which passwords are stale
+// at now: the later of PASSWORD_ROTATION_REMINDER_AGE ago and
+// PASSWORD_HASH_UPGRADED_AT. It returns false when neither is set.
+func (s *AuthService) passwordRotationCutoff(now time.Time) (time.Time, bool) {
+	var cutoff time.Time
+	if s.cfg.PasswordRotationReminderAge > 0 {
+		cutoff = now.Add(-s.cfg.PasswordRotationReminderAge)
+	}
+	if s.cfg.PasswordHashUpgradedAt.After(cutoff) {
+		cutoff = s.cfg.PasswordHashUpgradedAt
+	}
+	return cutoff, !cutoff.IsZero()
+}
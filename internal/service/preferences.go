@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+	"authentio/pkg/sms"
+)
+
+// ============================================================================
+// Notification Preferences
+// ============================================================================
+
+// GetPreferences returns the user's notification preferences, or the defaults
+// when they never saved any.
+func (s *AuthService) GetPreferences(ctx context.Context, userID int64) (*models.NotificationPreferences, error) {
+	prefs, err := s.preferencesRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs != nil {
+		return prefs, nil
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	prefs = models.DefaultNotificationPreferences(userID)
+	prefs.UpdatedAt = user.CreatedAt
+	return prefs, nil
+}
+
+// UpdatePreferences applies the fields set in req to the user's preferences.
+func (s *AuthService) UpdatePreferences(ctx context.Context, userID int64, req models.UpdatePreferencesRequest) (*models.NotificationPreferences, error) {
+	prefs, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SecurityAlertEmails != nil {
+		prefs.SecurityAlertEmails = *req.SecurityAlertEmails
+	}
+	if req.MarketingEmails != nil {
+		prefs.MarketingEmails = *req.MarketingEmails
+	}
+	if req.LoginNotifications != nil {
+		prefs.LoginNotifications = *req.LoginNotifications
+	}
+	if req.PhoneNumber != nil {
+		prefs.PhoneNumber = strings.ReplaceAll(strings.TrimSpace(*req.PhoneNumber), " ", "")
+		if prefs.PhoneNumber != "" && !sms.ValidNumber(prefs.PhoneNumber) {
+			return nil, ErrInvalidPhoneNumber
+		}
+	}
+	if req.SecurityAlertSMS != nil {
+		prefs.SecurityAlertSMS = *req.SecurityAlertSMS
+	}
+	if req.SecurityAlertPush != nil {
+		prefs.SecurityAlertPush = *req.SecurityAlertPush
+	}
+	if req.LoginNotificationSMS != nil {
+		prefs.LoginNotificationSMS = *req.LoginNotificationSMS
+	}
+	if req.LoginNotificationPush != nil {
+		prefs.LoginNotificationPush = *req.LoginNotificationPush
+	}
+	if req.NewLocationAlerts != nil {
+		prefs.NewLocationAlerts = *req.NewLocationAlerts
+	}
+	if req.PasswordExpiryReminders != nil {
+		prefs.PasswordExpiryReminders = *req.PasswordExpiryReminders
+	}
+	// Removing the phone number turns text messages off
+	if prefs.PhoneNumber == "" {
+		if (req.SecurityAlertSMS != nil && *req.SecurityAlertSMS) || (req.LoginNotificationSMS != nil && *req.LoginNotificationSMS) {
+			return nil, ErrPhoneNumberRequired
+		}
+		prefs.SecurityAlertSMS, prefs.LoginNotificationSMS = false, false
+	}
+
+	if err := s.preferencesRepo.Save(ctx, prefs); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	logger.Info("notification preferences saved", "userID", userID)
+	return prefs, nil
+}
+
+// notificationPreferences returns the preferences used to decide whether to
+// send optional mail. Lookup failures fall back to the defaults so a database
+// hiccup never suppresses a security alert.
+func (s *AuthService) notificationPreferences(ctx context.Context, userID int64) *models.NotificationPreferences {
+	prefs, err := s.preferencesRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		logger.Warn("failed to load notification preferences", "error", err, "userID", userID)
+	}
+	if prefs == nil {
+		prefs = models.DefaultNotificationPreferences(userID)
+	}
+	return prefs
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// User Profiles
+// ============================================================================
+
+// GetProfile returns the user's profile: names and avatar from the account
+// plus display name and bio.
+func (s *AuthService) GetProfile(ctx context.Context, userID int64) (*models.UserProfile, error) {
+	profile, err := s.profileRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrUserNotFound
+	}
+	return profile, nil
+}
+
+// SaveProfile applies req to the user's profile. Empty fields are left
+// unchanged; an empty avatar URL removes the picture. A replaced avatar that
+// was uploaded to our storage is deleted.
+func (s *AuthService) SaveProfile(ctx context.Context, userID int64, req models.UpdateProfileRequest) (*models.UserProfile, error) {
+	profile, err := s.GetProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.FirstName != "" {
+		profile.FirstName = req.FirstName
+	}
+	if req.LastName != "" {
+		profile.LastName = req.LastName
+	}
+	if req.DisplayName != "" {
+		profile.DisplayName = req.DisplayName
+	}
+	if req.Bio != "" {
+		profile.Bio = &req.Bio
+	}
+
+	previousAvatar := profile.AvatarURL
+	avatarChanged := false
+	if req.AvatarURL != nil && (previousAvatar == nil || *req.AvatarURL != *previousAvatar) {
+		avatarChanged = true
+		profile.AvatarURL = nil
+		if *req.AvatarURL != "" {
+			profile.AvatarURL = req.AvatarURL
+		}
+	}
+
+	if err := s.profileRepo.Save(ctx, profile); err != nil {
+		return nil, err
+	}
+	if avatarChanged {
+		s.deleteStoredAvatar(ctx, previousAvatar)
+	}
+
+	logger.Info("profile saved", "userID", userID)
+	return profile, nil
+}
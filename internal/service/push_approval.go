@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+	"authentio/pkg/push"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Push Approval 2FA
+// ============================================================================
+
+// RegisterPushDevice registers the device the mobile app runs on, so it
+// receives login approval requests. Registering a known token renames it.
+func (s *AuthService) RegisterPushDevice(ctx context.Context, userID int64, req models.RegisterDeviceRequest) (*models.PushDevice, error) {
+	if s.push == nil || !s.push.Supports(req.Platform) {
+		return nil, ErrPushUnavailable
+	}
+
+	device := &models.PushDevice{
+		UserID:   userID,
+		Platform: req.Platform,
+		Token:    req.Token,
+		Name:     req.Name,
+	}
+	if err := s.pushRepo.SaveDevice(ctx, device); err != nil {
+		return nil, err
+	}
+
+	logger.Info("push device registered", "userID", userID, "deviceID", device.ID, "platform", device.Platform)
+	return device, nil
+}
+
+// ListPushDevices returns the user's registered devices.
+func (s *AuthService) ListPushDevices(ctx context.Context, userID int64) ([]models.PushDevice, error) {
+	devices, err := s.pushRepo.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if devices == nil {
+		devices = []models.PushDevice{}
+	}
+	return devices, nil
+}
+
+// RemovePushDevice unregisters a device. The last device can't be removed
+// while push approval is enabled, or the user could no longer log in.
+func (s *AuthService) RemovePushDevice(ctx context.Context, userID, deviceID int64) error {
+	if s.pushApprovalEnabled(ctx, userID) {
+		devices, err := s.pushRepo.ListDevices(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(devices) == 1 && devices[0].ID == deviceID {
+			return ErrLastPushDevice
+		}
+	}
+
+	if err := s.pushRepo.DeleteDevice(ctx, userID, deviceID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPushDeviceNotFound
+		}
+		return err
+	}
+
+	logger.Info("push device removed", "userID", userID, "deviceID", deviceID)
+	return nil
+}
+
+// EnablePush2FA makes logins wait for approval on one of the user's devices.
+func (s *AuthService) EnablePush2FA(ctx context.Context, userID int64) error {
+	if s.push == nil {
+		return ErrPushUnavailable
+	}
+	devices, err := s.pushRepo.ListDevices(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return ErrNoPushDevice
+	}
+	if err := s.twoFARepo.EnablePush2FA(ctx, userID); err != nil {
+		return err
+	}
+	s.revokeAccessTokens(ctx, userID, "two_factor_enabled")
+	return nil
+}
+
+// ListPushChallenges returns the logins waiting for the user's approval, so
+// the app can show them even if a notification was missed.
+func (s *AuthService) ListPushChallenges(ctx context.Context, userID int64) ([]models.PushChallenge, error) {
+	challenges, err := s.pushRepo.ListPendingChallenges(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if challenges == nil {
+		challenges = []models.PushChallenge{}
+	}
+	return challenges, nil
+}
+
+// DecidePushChallenge approves or denies one of the user's pending logins.
+func (s *AuthService) DecidePushChallenge(ctx context.Context, userID int64, challengeID string, approve bool) error {
+	status, event := constants.PushChallengeDenied, constants.AuditPushDenied
+	if approve {
+		status, event = constants.PushChallengeApproved, constants.AuditPushApproved
+	}
+
+	// Email challenges can only be answered with the code that was sent
+	challenge, err := s.pushRepo.FindChallenge(ctx, challengeID)
+	if err != nil {
+		return err
+	}
+	if challenge == nil || challenge.Method != constants.TwoFAMethodPush {
+		return ErrChallengeNotFound
+	}
+
+	if err := s.pushRepo.DecideChallenge(ctx, userID, challengeID, status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrChallengeNotFound
+		}
+		return err
+	}
+
+	s.recordAudit(ctx, &userID, event, nil)
+	logger.Info("push login decided", "userID", userID, "status", status)
+	return nil
+}
+
+// CompletePushLogin exchanges an approved challenge for tokens. It returns
+// ErrChallengePending while the user hasn't answered, ErrLoginDenied or
+// ErrChallengeExpired when the login can't proceed, and the tokens exactly
+// once after approval.
+func (s *AuthService) CompletePushLogin(ctx context.Context, challengeToken string) (*response.LoginResponse, error) {
+	claims, err := s.checkChallengeToken(ctx, challengeToken, constants.TwoFAMethodPush)
+	if err != nil {
+		return nil, err
+	}
+	return s.completeChallenge(ctx, claims.ChallengeID, constants.TwoFAMethodPush, ErrChallengePending, claims)
+}
+
+// completeChallenge exchanges an approved challenge of the given method for
+// tokens, returning errPending while it waits for approval. The challenge
+// token it was presented with, if any, is used up on approval.
+func (s *AuthService) completeChallenge(ctx context.Context, challengeID, method string, errPending error, token *jwt.ChallengeClaims) (*response.LoginResponse, error) {
+	challenge, err := s.pushRepo.FindChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.Method != method {
+		return nil, ErrChallengeNotFound
+	}
+
+	switch challenge.Status {
+	case constants.PushChallengePending:
+		if time.Now().After(challenge.ExpiresAt) {
+			return nil, ErrChallengeExpired
+		}
+		return nil, errPending
+	case constants.PushChallengeDenied:
+		return nil, ErrLoginDenied
+	case constants.PushChallengeApproved:
+	default:
+		return nil, ErrChallengeNotFound
+	}
+
+	if token != nil {
+		if err := s.consumeChallengeToken(ctx, token); err != nil {
+			return nil, err
+		}
+	}
+	completed, err := s.pushRepo.CompleteChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrChallengeNotFound
+	}
+
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := checkApproval(user); err != nil {
+		return nil, err
+	}
+
+	s.allowVerifiedIP(ctx, user.ID)
+	s.loginSucceeded(ctx, user, challenge.LoginMethod, map[string]interface{}{"method": challenge.LoginMethod, "second_factor": method})
+
+	return s.generateAuthResponse(ctx, user, nil)
+}
+
+// pushApprovalEnabled reports whether the user's logins need push approval.
+func (s *AuthService) pushApprovalEnabled(ctx context.Context, userID int64) bool {
+	enabled, err := s.twoFARepo.Is2FAEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return false
+	}
+	method, err := s.twoFARepo.Get2FAMethod(ctx, userID)
+	return err == nil && method == constants.TwoFAMethodPush
+}
+
+// startPushChallenge creates a pending login for user, who passed the first
+// factor with loginMethod, and notifies their devices. Delivery failures are
+// logged: the app can still list pending challenges.
+func (s *AuthService) startPushChallenge(ctx context.Context, user *models.User, loginMethod string) (*response.LoginResponse, error) {
+	client := requestctx.ClientInfoFrom(ctx)
+	challenge := &models.PushChallenge{
+		ChallengeID: generateSecureToken(),
+		UserID:      user.ID,
+		Method:      constants.TwoFAMethodPush,
+		LoginMethod: loginMethod,
+		Status:      constants.PushChallengePending,
+		IPAddress:   client.IP,
+		UserAgent:   client.UserAgent,
+		ExpiresAt:   time.Now().Add(s.cfg.PushChallengeTTL),
+	}
+	if err := s.pushRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	token, err := s.issueChallengeToken(ctx, user, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyPushDevices(ctx, user.ID, challenge)
+
+	return &response.LoginResponse{
+		User:              newUserResponse(user),
+		RequiresTwoFactor: true,
+		TwoFactor: &response.TwoFactorChallenge{
+			Method:         constants.TwoFAMethodPush,
+			ChallengeID:    challenge.ChallengeID,
+			ChallengeToken: token,
+			ExpiresAt:      challenge.ExpiresAt,
+		},
+	}, nil
+}
+
+// notifyPushDevices sends the approval request to every device of the user.
+// Devices whose token the platform rejects are forgotten, except the last
+// one so the user isn't locked out by a transient platform error.
+func (s *AuthService) notifyPushDevices(ctx context.Context, userID int64, challenge *models.PushChallenge) {
+	if s.push == nil {
+		logger.Warn("push approval requested but push is not configured", "userID", userID)
+		return
+	}
+	devices, err := s.pushRepo.ListDevices(ctx, userID)
+	if err != nil {
+		logger.Error("failed to list push devices", "error", err, "userID", userID)
+		return
+	}
+
+	body := "Someone is trying to log in to your account. Tap to approve or deny."
+	if challenge.IPAddress != "" {
+		body = "Login attempt from " + challenge.IPAddress + ". Tap to approve or deny."
+	}
+	notification := push.Notification{
+		Title: "Approve login?",
+		Body:  body,
+		Data: map[string]string{
+			"type":         "login_approval",
+			"challenge_id": challenge.ChallengeID,
+		},
+		TTL: time.Until(challenge.ExpiresAt),
+	}
+
+	remaining := len(devices)
+	for _, device := range devices {
+		err := s.push.Send(ctx, device.Platform, device.Token, notification)
+		switch {
+		case err == nil:
+		case errors.Is(err, push.ErrInvalidToken) && remaining > 1:
+			remaining--
+			if err := s.pushRepo.DeleteDeviceToken(ctx, userID, device.Token); err != nil {
+				logger.Warn("failed to remove invalid push device", "error", err, "deviceID", device.ID)
+			}
+		default:
+			logger.Warn("failed to send push approval request", "error", err, "deviceID", device.ID, "platform", device.Platform)
+		}
+	}
+}
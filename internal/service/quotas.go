@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/requestctx"
+	"authentio/pkg/otplimit"
+	"authentio/pkg/quota"
+	"authentio/pkg/signuplimit"
+)
+
+// ============================================================================
+// Tenant Quotas
+// ============================================================================
+
+// QuotaUsage returns the current tenant's quota usage on the UTC day
+// containing day.
+func (s *AuthService) QuotaUsage(ctx context.Context, day time.Time) ([]quota.Usage, error) {
+	if s.quotas == nil {
+		return nil, ErrQuotasUnavailable
+	}
+	return s.quotas.Usage(ctx, currentTenant(ctx), day)
+}
+
+// consumeQuota records one use of metric by the current tenant, returning
+// ErrQuotaExceeded once today's quota is used up. It does nothing when quotas
+// are disabled.
+func (s *AuthService) consumeQuota(ctx context.Context, metric string) error {
+	if s.quotas == nil {
+		return nil
+	}
+	if err := s.quotas.Consume(ctx, currentTenant(ctx), metric); err != nil {
+		if errors.Is(err, quota.ErrExceeded) {
+			return ErrQuotaExceeded
+		}
+		return err
+	}
+	return nil
+}
+
+// throttleOTP applies the per-recipient cooldown and daily cap to a code email
+// of kind sent to email, returning a *RetryAfterError wrapping ErrOTPCooldown
+// or ErrOTPDailyLimit when it must not be sent. It does nothing without Redis.
+func (s *AuthService) throttleOTP(ctx context.Context, kind constants.Type, email string) error {
+	if s.otpLimits == nil {
+		return nil
+	}
+
+	err := s.otpLimits.Allow(ctx, currentTenant(ctx), string(kind), email)
+	var limitErr *otplimit.LimitError
+	if !errors.As(err, &limitErr) {
+		return err
+	}
+
+	sentinel := ErrOTPCooldown
+	if errors.Is(limitErr, otplimit.ErrDailyLimit) {
+		sentinel = ErrOTPDailyLimit
+	}
+	return &RetryAfterError{ServiceError: sentinel, RetryAfter: limitErr.RetryAfter}
+}
+
+// throttleRegistration applies the anti-abuse limits on signups from the
+// client's IP address and for email without its +tag, returning a
+// *RetryAfterError wrapping ErrRegistrationIPLimit, ErrRegistrationFlood or
+// ErrRegistrationBlocked when the registration must be refused. It does
+// nothing without Redis.
+func (s *AuthService) throttleRegistration(ctx context.Context, email string) error {
+	if s.signupLimits == nil {
+		return nil
+	}
+
+	err := s.signupLimits.Allow(ctx, currentTenant(ctx), requestctx.ClientInfoFrom(ctx).IP, email)
+	var limitErr *signuplimit.LimitError
+	if !errors.As(err, &limitErr) {
+		return err
+	}
+
+	sentinel := ErrRegistrationBlocked
+	switch {
+	case errors.Is(limitErr, signuplimit.ErrIPLimit):
+		sentinel = ErrRegistrationIPLimit
+	case errors.Is(limitErr, signuplimit.ErrEmailFlood):
+		sentinel = ErrRegistrationFlood
+	}
+	return &RetryAfterError{ServiceError: sentinel, RetryAfter: limitErr.RetryAfter}
+}
+
+// currentTenant returns the tenant the request in ctx was resolved to.
+func currentTenant(ctx context.Context) int64 {
+	if id := requestctx.TenantFrom(ctx); id != 0 {
+		return id
+	}
+	return constants.DefaultTenantID
+}
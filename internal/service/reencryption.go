@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/pkg/lock"
+	"authentio/pkg/logger"
+	"authentio/pkg/secretbox"
+)
+
+// ReencryptionPass encrypts the values of one kind stored unencrypted and
+// re-wraps those wrapped by a retired master key with the current one.
+type ReencryptionPass struct {
+	Name string // e.g. "2FA secrets", for logs
+	// Run returns how many values were updated.
+	Run func(ctx context.Context) (int, error)
+}
+
+// StartReencryption runs passes now and then every interval (0 = only now)
+// until ctx is cancelled. Values wrapped by a master key that was rotated,
+// locally or at the key management service, are thus moved to the new key
+// without a restart. keys is the keyring the passes seal with, for logs; it
+// is nil when encryption is off. locks keeps the passes from running on
+// several servers at once.
+func StartReencryption(ctx context.Context, keys *secretbox.Keyring, locks *lock.Locker, interval time.Duration, passes ...ReencryptionPass) {
+	if keys == nil {
+		return
+	}
+
+	go func() {
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			runExclusive(ctx, locks, "reencryption", func(ctx context.Context) {
+				for _, pass := range passes {
+					if ctx.Err() != nil {
+						return
+					}
+					reencrypted, err := pass.Run(ctx)
+					if err != nil {
+						logger.Warn("failed to re-encrypt "+pass.Name, "error", err, "reencrypted", reencrypted)
+					} else if reencrypted > 0 {
+						logger.Info(pass.Name+" re-encrypted", "count", reencrypted, "keyID", keys.CurrentKeyID())
+					}
+				}
+			})
+			if ctx.Err() != nil {
+				return
+			}
+
+			if tick == nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick:
+			}
+		}
+	}()
+}
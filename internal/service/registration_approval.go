@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Registration Approval
+// ============================================================================
+
+// ListPendingUsers returns registrations awaiting admin approval, oldest first.
+func (s *AuthService) ListPendingUsers(ctx context.Context) ([]response.UserResponse, error) {
+	users, err := s.userRepo.FindByApprovalStatus(ctx, constants.ApprovalPending)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]response.UserResponse, 0, len(users))
+	for _, user := range users {
+		pending = append(pending, newUserResponse(user))
+	}
+	return pending, nil
+}
+
+// ApproveUser lets a pending user log in and sends them the approval and
+// welcome emails.
+func (s *AuthService) ApproveUser(ctx context.Context, userID, actorID int64) error {
+	user, err := s.reviewPendingUser(ctx, userID, actorID, constants.ApprovalApproved)
+	if err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditUserApproved, map[string]interface{}{"user_id": userID})
+
+	s.Notify(ctx, user, Notification{Event: constants.NotificationRegistrationApproved})
+	s.startOnboarding(ctx, user)
+
+	logger.Info("registration approved", "userID", userID, "actorID", actorID)
+	return nil
+}
+
+// RejectUser refuses a pending registration and notifies the user. The
+// account is kept (and blocked from logging in) so the email can't simply
+// register again.
+func (s *AuthService) RejectUser(ctx context.Context, userID, actorID int64) error {
+	user, err := s.reviewPendingUser(ctx, userID, actorID, constants.ApprovalRejected)
+	if err != nil {
+		return err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditUserRejected, map[string]interface{}{"user_id": userID})
+
+	s.Notify(ctx, user, Notification{Event: constants.NotificationRegistrationRejected})
+
+	logger.Info("registration rejected", "userID", userID, "actorID", actorID)
+	return nil
+}
+
+// reviewPendingUser moves a pending user to status and returns the user.
+func (s *AuthService) reviewPendingUser(ctx context.Context, userID, actorID int64, status string) (*models.User, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.userRepo.SetApprovalStatus(ctx, userID, status, actorID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotPending
+		}
+		return nil, err
+	}
+	user.ApprovalStatus = status
+	return user, nil
+}
+
+// initialApprovalStatus returns the approval state for a new account. Invited
+// users were vouched for by the inviter and skip the queue.
+func (s *AuthService) initialApprovalStatus(invited bool) string {
+	if s.cfg.RequireRegistrationApproval && !invited {
+		return constants.ApprovalPending
+	}
+	return constants.ApprovalApproved
+}
+
+// notifyAdminsOfPendingUser emails every admin about a registration that needs
+// review. It runs as a registration task, retried on error.
+func (s *AuthService) notifyAdminsOfPendingUser(ctx context.Context, user *models.User) error {
+	admins, err := s.userRepo.FindEmailsByRole(ctx, constants.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("load admin emails: %w", err)
+	}
+	if len(admins) == 0 {
+		logger.Warn("registration awaiting approval but no admins to notify", "userID", user.ID)
+		return nil
+	}
+
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	msg, err := s.emailRender.RegistrationPending(ctx, name, user.Email)
+	if err != nil {
+		return fmt.Errorf("render pending registration email: %w", err)
+	}
+	return s.sendEmail(ctx, constants.EmailRegistrationPending, admins, msg)
+}
+
+// checkApproval returns an error for users whose registration has not been
+// approved.
+func checkApproval(user *models.User) error {
+	switch user.ApprovalStatus {
+	case constants.ApprovalPending:
+		return ErrAccountPending
+	case constants.ApprovalRejected:
+		return ErrAccountRejected
+	}
+	return nil
+}
+
+// approvalStatusForResponse hides the approval status of approved accounts,
+// which is the normal case.
+func approvalStatusForResponse(status string) string {
+	if status == constants.ApprovalApproved {
+		return ""
+	}
+	return status
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+	"authentio/pkg/tasks"
+	"authentio/pkg/webhook"
+)
+
+// Kinds of the tasks run after a registration.
+const (
+	taskOnboarding          = "registration.onboarding"    // first onboarding emails
+	taskNotifyAdmins        = "registration.notify_admins" // review request for a pending registration
+	taskRegistrationWebhook = "registration.webhook"       // user.registered event
+)
+
+// registrationTask is the payload of the registration tasks: the new user,
+// and the tenant and locale of the request that registered them.
+type registrationTask struct {
+	TenantID int64  `json:"tenant_id"`
+	Locale   string `json:"locale,omitempty"`
+	UserID   int64  `json:"user_id"`
+	Provider string `json:"provider"` // password or google
+}
+
+// queueRegistrationTasks queues the side effects of registering user: the
+// first onboarding emails, or the admins' review request while the
+// registration is pending, and the registration webhook.
+func (s *AuthService) queueRegistrationTasks(ctx context.Context, user *models.User, provider string) {
+	payload := registrationTask{
+		TenantID: requestctx.TenantFrom(ctx),
+		Locale:   requestctx.LocaleFrom(ctx),
+		UserID:   user.ID,
+		Provider: provider,
+	}
+	if user.ApprovalStatus == constants.ApprovalPending {
+		s.queueTask(ctx, taskNotifyAdmins, payload)
+	} else {
+		s.queueTask(ctx, taskOnboarding, payload)
+	}
+	if s.webhook != nil {
+		s.queueTask(ctx, taskRegistrationWebhook, payload)
+	}
+}
+
+// queueTask queues a registration task, once per kind, tenant and user.
+// Without the task queue (no Redis), or when it fails, the task runs at once
+// and its errors are logged without failing the registration.
+func (s *AuthService) queueTask(ctx context.Context, kind string, payload registrationTask) {
+	id := fmt.Sprintf("%s:%d:%d", kind, payload.TenantID, payload.UserID)
+	if s.tasks != nil {
+		enqueued, err := s.tasks.Enqueue(ctx, kind, id, payload)
+		if err == nil {
+			if !enqueued {
+				logger.Debug("duplicate task dropped", "taskID", id)
+			}
+			return
+		}
+		logger.Error("failed to queue task, running it now", "error", err, "taskID", id)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		logger.Error("failed to encode task", "error", err, "taskID", id)
+		return
+	}
+	if err := s.RunTask(ctx, tasks.Task{ID: id, Kind: kind, Payload: data}); err != nil {
+		logger.Error("task failed", "error", err, "taskID", id)
+	}
+}
+
+// RunTask runs a queued registration task in the tenant and locale of the
+// registration. It is the handler of the task queue workers; an error has
+// the task retried.
+func (s *AuthService) RunTask(ctx context.Context, task tasks.Task) error {
+	var payload registrationTask
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		logger.Error("dropping malformed task", "error", err, "taskID", task.ID)
+		return nil
+	}
+	ctx = requestctx.WithTenant(ctx, payload.TenantID)
+	if payload.Locale != "" {
+		ctx = requestctx.WithLocale(ctx, payload.Locale)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, payload.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		// Deleted since; nobody left to onboard or announce
+		logger.Info("dropping task of a deleted user", "taskID", task.ID)
+		return nil
+	}
+
+	switch task.Kind {
+	case taskOnboarding:
+		s.startOnboarding(ctx, user)
+		return nil
+	case taskNotifyAdmins:
+		return s.notifyAdminsOfPendingUser(ctx, user)
+	case taskRegistrationWebhook:
+		if s.webhook == nil {
+			return nil
+		}
+		return s.webhook.Send(ctx, webhook.Event{
+			ID:         task.ID,
+			Type:       "user.registered",
+			OccurredAt: user.CreatedAt,
+			Data: map[string]interface{}{
+				"user_id":         user.ID,
+				"email":           user.Email,
+				"username":        user.Username,
+				"tenant_id":       payload.TenantID,
+				"provider":        payload.Provider,
+				"approval_status": user.ApprovalStatus,
+				"invited":         user.InvitedBy != nil,
+			},
+		})
+	}
+	logger.Error("dropping task of unknown kind", "kind", task.Kind, "taskID", task.ID)
+	return nil
+}
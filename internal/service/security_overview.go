@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Security Overview
+// ============================================================================
+
+// SecurityOverview gathers the user's 2FA setup, sessions, last login,
+// password age and sign-in methods for a security settings page.
+func (s *AuthService) SecurityOverview(ctx context.Context, userID int64) (*response.SecurityOverview, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	overview := &response.SecurityOverview{
+		TwoFactor:       response.TwoFactorStatus{Methods: []string{}},
+		LinkedProviders: []string{},
+	}
+
+	enabled, err := s.twoFARepo.Is2FAEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		method, err := s.twoFARepo.Get2FAMethod(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		overview.TwoFactor.Enabled = true
+		overview.TwoFactor.Methods = append(overview.TwoFactor.Methods, method)
+	}
+	if overview.TwoFactor.RecoveryCodesRemaining, err = s.recoveryRepo.CountUnusedCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	devices, err := s.pushRepo.ListDevices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	overview.TwoFactor.PushDevices = len(devices)
+
+	overview.ActiveSessions, err = s.tokenRepo.CountUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	logins, err := s.auditRepo.ListByUser(ctx, userID, []string{string(constants.AuditLoginSuccess)}, nil, 1)
+	if err != nil {
+		// The audit log is best-effort; the rest of the overview is still useful
+		logger.Warn("failed to load last login", "error", err, "userID", userID)
+	} else if len(logins) > 0 {
+		last := newLoginEvent(logins[0])
+		overview.LastLogin = &last
+	}
+
+	if user.Password != "" {
+		overview.LinkedProviders = append(overview.LinkedProviders, "password")
+		overview.Password = &response.PasswordStatus{ChangedAt: user.PasswordChangedAt}
+		if user.PasswordChangedAt != nil {
+			days := int(time.Since(*user.PasswordChangedAt).Hours() / 24)
+			overview.Password.AgeDays = &days
+		}
+	}
+	if user.Provider != "" && user.Provider != "email" {
+		overview.LinkedProviders = append(overview.LinkedProviders, user.Provider)
+	}
+
+	return overview, nil
+}
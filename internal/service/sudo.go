@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/password"
+	"authentio/pkg/quota"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Sudo Mode
+// ============================================================================
+
+// Reauthenticate checks the password of the signed-in user and issues an
+// access token carrying the sudo scope for SUDO_TTL, which admin operations
+// require. Accounts without a password get ErrNoPassword. Users with 2FA also
+// need a 2FA code: without one, a code is emailed to them and
+// ErrTwoFactorCodeRequired returned, so they call again with it.
+func (s *AuthService) Reauthenticate(ctx context.Context, userID int64, plainPassword, code string) (*response.SudoToken, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Password == "" {
+		return nil, ErrNoPassword
+	}
+	if !password.Check(plainPassword, user.Password) {
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"reason": "reauth_invalid_password"})
+		return nil, ErrInvalidCredentials
+	}
+	if err := checkApproval(user); err != nil {
+		return nil, err
+	}
+	if err := s.checkReauthCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	// The scope can't outlive the token carrying it
+	key := s.jwtManager.Key(user.TenantID)
+	now := time.Now()
+	sudoUntil := now.Add(s.cfg.SudoTTL)
+	if tokenExpiry := now.Add(key.AccessTTL); tokenExpiry.Before(sudoUntil) {
+		sudoUntil = tokenExpiry
+	}
+
+	claims := s.tokenClaims(user, nil)
+	claims.SudoUntil = sudoUntil
+	claims.AuthTime = now
+	accessToken, err := s.jwtManager.GenerateToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &user.ID, constants.AuditSudoGranted, map[string]interface{}{"expires_at": sudoUntil.UTC()})
+	return &response.SudoToken{
+		AccessToken:   accessToken,
+		ExpiresIn:     int(key.AccessTTL.Seconds()),
+		SudoExpiresAt: sudoUntil.Truncate(time.Second),
+	}, nil
+}
+
+// checkReauthCode checks the second factor of a re-authentication by user,
+// for users with 2FA. Push approval users confirm with a code sent by email
+// too, as sudo mode has no push challenge.
+func (s *AuthService) checkReauthCode(ctx context.Context, user *models.User, code string) error {
+	enabled, err := s.twoFARepo.Is2FAEnabled(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+
+	if code == "" {
+		if err := s.throttleOTP(ctx, constants.Type2FA, user.Email); err != nil {
+			return err
+		}
+		if err := s.consumeQuota(ctx, quota.OTPEmails); err != nil {
+			return err
+		}
+		if err := s.send2FACode(ctx, user); err != nil {
+			return err
+		}
+		return ErrTwoFactorCodeRequired
+	}
+	if err := s.Verify2FA(ctx, user.Email, code); err != nil {
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"reason": "reauth_invalid_otp"})
+		return err
+	}
+	return nil
+}
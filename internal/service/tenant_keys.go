@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/jwt"
+	"authentio/pkg/kms"
+	"authentio/pkg/logger"
+)
+
+// minTenantSecretLength is the shortest accepted per-tenant HMAC key.
+const minTenantSecretLength = 32
+
+// TenantKeyStore supplies the JWT signing key, issuer and token TTLs of each
+// tenant (jwt.KeyStore). Settings come from the tenants table; tenants without
+// their own secret sign with a key derived from the default secret, so a token
+// issued for one tenant never validates for another. The default tenant keeps
+// the default key. It is safe for concurrent use.
+type TenantKeyStore struct {
+	tenants  repository.TenantRepository
+	defaults jwt.Key
+	kms      *kms.Keys // resolves "kms:" secrets; nil when not configured
+
+	mu        sync.RWMutex
+	overrides map[int64]*jwt.Key // tenants with custom settings
+	loadMu    sync.Mutex         // serializes Load, so older defaults never win
+}
+
+// NewTenantKeyStore creates a store that falls back to defaults for settings
+// a tenant doesn't override. keys unwraps tenant secrets wrapped by the key
+// management service, if any. Call Load before use.
+func NewTenantKeyStore(tenants repository.TenantRepository, defaults jwt.Key, keys *kms.Keys) *TenantKeyStore {
+	return &TenantKeyStore{
+		tenants:   tenants,
+		defaults:  defaults,
+		kms:       keys,
+		overrides: make(map[int64]*jwt.Key),
+	}
+}
+
+// TenantKey returns the signing key for tenantID, or nil for the default
+// tenant, which uses the default key.
+func (s *TenantKeyStore) TenantKey(tenantID int64) *jwt.Key {
+	if tenantID <= constants.DefaultTenantID {
+		return nil
+	}
+
+	s.mu.RLock()
+	key, ok := s.overrides[tenantID]
+	defaults := s.defaults
+	s.mu.RUnlock()
+	if ok {
+		return key
+	}
+
+	derived := deriveTenantKey(defaults, tenantID)
+	return &derived
+}
+
+// SetDefaults replaces the default key, e.g. after a rotation, and builds
+// the tenants' keys from it again.
+func (s *TenantKeyStore) SetDefaults(ctx context.Context, defaults jwt.Key) error {
+	s.mu.Lock()
+	s.defaults = defaults
+	s.mu.Unlock()
+	return s.Load(ctx)
+}
+
+// Load reads every tenant's signing settings. On failure the current
+// settings are kept.
+func (s *TenantKeyStore) Load(ctx context.Context) error {
+	s.loadMu.Lock()
+	defer s.loadMu.Unlock()
+
+	tenants, err := s.tenants.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant signing keys: %w", err)
+	}
+
+	s.mu.RLock()
+	defaults := s.defaults
+	s.mu.RUnlock()
+
+	overrides := make(map[int64]*jwt.Key)
+	for _, tenant := range tenants {
+		key, err := s.tenantKey(tenant, defaults)
+		if err != nil {
+			// Keep signing with the derived key rather than failing every request
+			logger.Error("invalid tenant signing key - using derived key", "tenant", tenant.Slug, "error", err)
+			continue
+		}
+		if key != nil {
+			overrides[tenant.ID] = key
+		}
+	}
+
+	s.mu.Lock()
+	s.overrides = overrides
+	s.mu.Unlock()
+
+	logger.Info("tenant signing keys loaded", "tenants", len(tenants), "custom", len(overrides))
+	return nil
+}
+
+// StartRefresh reloads the settings every interval until ctx is cancelled, so
+// rotated keys and changed TTLs are picked up without a restart.
+func (s *TenantKeyStore) StartRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := s.Load(ctx); err != nil {
+				logger.Warn("failed to refresh tenant signing keys", "error", err)
+			}
+		}
+	}()
+}
+
+// tenantKey builds tenant's key from its settings, or returns nil when it
+// only uses defaults. The default tenant's secret can't be overridden: it is
+// JWT_SECRET.
+func (s *TenantKeyStore) tenantKey(tenant *models.Tenant, defaults jwt.Key) (*jwt.Key, error) {
+	if tenant.ID <= constants.DefaultTenantID {
+		return nil, nil
+	}
+	if tenant.JWTSecret == "" && tenant.JWTIssuer == "" && tenant.AccessTokenTTL == 0 && tenant.RefreshTokenTTL == 0 {
+		return nil, nil
+	}
+
+	key := deriveTenantKey(defaults, tenant.ID)
+	if tenant.JWTSecret != "" {
+		secret, err := s.resolveSecret(tenant.JWTSecret)
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) < minTenantSecretLength {
+			return nil, fmt.Errorf("secret must be at least %d characters", minTenantSecretLength)
+		}
+		// The default key ring doesn't apply to a tenant's own secret
+		key.Secret = secret
+		key.KeyID = ""
+		key.Retired = nil
+	}
+	if tenant.JWTIssuer != "" {
+		key.Issuer = tenant.JWTIssuer
+	}
+	if tenant.AccessTokenTTL > 0 {
+		key.AccessTTL = tenant.AccessTokenTTL
+	}
+	if tenant.RefreshTokenTTL > 0 {
+		key.RefreshTTL = tenant.RefreshTokenTTL
+	}
+	return &key, nil
+}
+
+// resolveSecret returns the key material for a stored secret: the value of
+// an environment variable for "env:NAME", a file's contents for "file:/path"
+// (e.g. a secret mounted from a KMS), the key wrapped by the key management
+// service for "kms:<ciphertext>", or the value itself.
+func (s *TenantKeyStore) resolveSecret(value string) (string, error) {
+	switch {
+	case kms.IsReference(value):
+		if s.kms == nil {
+			return "", fmt.Errorf("secret is wrapped by a key management service but KMS_PROVIDER is not set")
+		}
+		return s.kms.Resolve(value)
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return value, nil
+}
+
+// deriveTenantKey derives tenantID's key from the defaults. Retired default
+// secrets are derived too, so rotating JWT_SECRET doesn't invalidate the
+// tenant's tokens before they expire.
+func deriveTenantKey(defaults jwt.Key, tenantID int64) jwt.Key {
+	key := defaults
+	key.Secret = deriveTenantSecret(defaults.Secret, tenantID)
+	key.Retired = make([]jwt.RetiredKey, len(defaults.Retired))
+	for i, retired := range defaults.Retired {
+		retired.Secret = deriveTenantSecret(retired.Secret, tenantID)
+		key.Retired[i] = retired
+	}
+	return key
+}
+
+// deriveTenantSecret derives a tenant-specific signing key from the default
+// secret.
+func deriveTenantSecret(secret string, tenantID int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("authentio-tenant:" + strconv.FormatInt(tenantID, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -33,8 +33,3 @@ func (s *TokenService) DeleteRefreshToken(ctx context.Context, token string) err
 func (s *TokenService) DeleteUserRefreshTokens(ctx context.Context, userID int64) error {
 	return s.tokenRepo.DeleteUserRefreshTokens(ctx, userID)
 }
-
-// CleanupExpiredTokens removes all expired refresh tokens
-func (s *TokenService) CleanupExpiredTokens(ctx context.Context) error {
-	return s.tokenRepo.CleanupExpiredTokens(ctx)
-}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Token Versions
+// ============================================================================
+
+// tokenVersionCacheTTL is how long a user's token version is cached for the
+// check made on every authenticated request. Privilege changes made through
+// this instance apply at once; other instances pick them up within the TTL.
+const tokenVersionCacheTTL = 30 * time.Second
+
+// TokenVersionCurrent reports whether an access token of userID carrying
+// version was issued after the user's privileges last changed. It is called
+// on every authenticated request, so versions are cached. If one can't be
+// loaded the last known one applies, and without one the token is accepted,
+// like tokens are when the revocation list is unreachable. Unknown or
+// deleted users are cached with version -1, refusing all their tokens.
+func (s *AuthService) TokenVersionCurrent(ctx context.Context, userID int64, version int) bool {
+	cached, fresh, ok := s.tokenVersions.get(userID)
+	if !fresh {
+		current, err := s.userRepo.TokenVersion(ctx, userID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			current = -1
+		case err != nil:
+			logger.Error("failed to load token version", "error", err, "userID", userID)
+			if ok {
+				// The last known version applies until the next try
+				s.tokenVersions.set(userID, cached, time.Now().Add(tokenVersionCacheTTL))
+			}
+			return !ok || version >= cached
+		}
+		s.tokenVersions.set(userID, current, time.Now().Add(tokenVersionCacheTTL))
+		cached = current
+	}
+	return cached >= 0 && version >= cached
+}
+
+// revokeAccessTokens increments userID's token version after their
+// privileges changed, so the access tokens issued before are refused and
+// clients refresh them (rotating the refresh token) to get the new ones. It
+// returns the new version, to embed in tokens issued in the same request;
+// failures are logged, since the change itself has been made.
+func (s *AuthService) revokeAccessTokens(ctx context.Context, userID int64, reason string) (int, bool) {
+	version, err := s.userRepo.BumpTokenVersion(ctx, userID)
+	if err != nil {
+		logger.Error("failed to revoke access tokens", "error", err, "userID", userID, "reason", reason)
+		return 0, false
+	}
+	s.tokenVersions.set(userID, version, time.Now().Add(tokenVersionCacheTTL))
+	logger.Info("access tokens revoked", "userID", userID, "reason", reason, "version", version)
+	return version, true
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/requestctx"
+	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+	"authentio/pkg/otp"
+	"authentio/pkg/quota"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Two-Factor Recovery
+// ============================================================================
+
+// recoveryCodePolicy describes recovery codes: ten characters of the
+// unambiguous alphanumeric alphabet, shown as two groups of five. TTL is
+// unused; codes are valid until used or regenerated.
+var recoveryCodePolicy = otp.Policy{Length: 10, Charset: otp.CharsetAlphanumeric, TTL: otp.MaxTTL}
+
+// GenerateRecoveryCodes issues a new set of recovery codes, replacing the
+// previous set. Only hashes are stored, so the codes are returned this once.
+func (s *AuthService) GenerateRecoveryCodes(ctx context.Context, userID int64) (*models.RecoveryCodes, error) {
+	codes := make([]string, constants.RecoveryCodeCount)
+	hashes := make([]string, constants.RecoveryCodeCount)
+	for i := range codes {
+		code, err := recoveryCodePolicy.Generate()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryToken(code)
+	}
+
+	if err := s.recoveryRepo.ReplaceCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &userID, constants.AuditRecoveryCodesGenerated, nil)
+	logger.Info("recovery codes generated", "userID", userID)
+	return &models.RecoveryCodes{Codes: codes, Remaining: len(codes)}, nil
+}
+
+// RecoveryCodesRemaining returns how many unused recovery codes the user has.
+func (s *AuthService) RecoveryCodesRemaining(ctx context.Context, userID int64) (*models.RecoveryCodes, error) {
+	remaining, err := s.recoveryRepo.CountUnusedCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RecoveryCodes{Remaining: remaining}, nil
+}
+
+// LoginWithRecoveryCode completes a login waiting for push approval, given
+// its challenge token, with one of the user's recovery codes instead. The code
+// is used up, and the user is alerted on every address we know.
+func (s *AuthService) LoginWithRecoveryCode(ctx context.Context, req models.RecoveryCodeLoginRequest) (*response.LoginResponse, error) {
+	claims, err := s.checkChallengeToken(ctx, req.ChallengeToken, constants.TwoFAMethodPush)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.pendingChallenge(ctx, claims.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := s.recoveryRepo.UseCode(ctx, challenge.UserID, hashRecoveryToken(normalizeRecoveryCode(req.Code)))
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		metrics.OTPFailures.Inc()
+		s.recordAudit(ctx, &challenge.UserID, constants.AuditLoginFailed, map[string]interface{}{"reason": "invalid_recovery_code"})
+		return nil, ErrInvalidRecoveryCode
+	}
+	if err := s.consumeChallengeToken(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	if err := s.pushRepo.DecideChallenge(ctx, challenge.UserID, challenge.ChallengeID, constants.PushChallengeCompleted); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChallengeNotFound
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := checkApproval(user); err != nil {
+		return nil, err
+	}
+
+	remaining, err := s.recoveryRepo.CountUnusedCodes(ctx, user.ID)
+	if err != nil {
+		logger.Warn("failed to count recovery codes", "error", err, "userID", user.ID)
+	}
+
+	s.allowVerifiedIP(ctx, user.ID)
+	s.recordAudit(ctx, &user.ID, constants.AuditRecoveryCodeUsed, map[string]interface{}{"remaining_codes": remaining})
+	s.recordAudit(ctx, &user.ID, constants.AuditLoginSuccess, map[string]interface{}{"method": challenge.LoginMethod, "second_factor": "recovery_code"})
+
+	s.Notify(ctx, user, Notification{Event: constants.NotificationRecoveryCodeUsed, RecoveryCodesLeft: remaining})
+
+	return s.generateAuthResponse(ctx, user, nil)
+}
+
+// StartEmailRecovery emails a 2FA code to the user of a login waiting for push
+// approval, for when they can't approve it. The challenge stops accepting
+// approvals; calling it again resends the code.
+func (s *AuthService) StartEmailRecovery(ctx context.Context, challengeID string) error {
+	challenge, err := s.pushRepo.FindChallenge(ctx, challengeID)
+	if err != nil {
+		return err
+	}
+	if challenge == nil || challenge.Method != constants.TwoFAMethodPush {
+		return ErrChallengeNotFound
+	}
+
+	switch challenge.Status {
+	case constants.PushChallengePending:
+		if time.Now().After(challenge.ExpiresAt) {
+			return ErrChallengeExpired
+		}
+		if err := s.pushRepo.DecideChallenge(ctx, challenge.UserID, challengeID, constants.PushChallengeRecovering); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrChallengeNotFound
+			}
+			return err
+		}
+	case constants.PushChallengeRecovering:
+	default:
+		return ErrChallengeNotFound
+	}
+
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.throttleOTP(ctx, constants.Type2FA, user.Email); err != nil {
+		return err
+	}
+	if err := s.consumeQuota(ctx, quota.OTPEmails); err != nil {
+		return err
+	}
+
+	return s.send2FACode(ctx, user)
+}
+
+// VerifyEmailRecovery checks the code sent by StartEmailRecovery and opens a
+// recovery request. It can be completed after TWO_FA_RECOVERY_DELAY; until
+// then the owner is alerted on every address we know and can cancel it.
+func (s *AuthService) VerifyEmailRecovery(ctx context.Context, req models.RecoveryEmailVerifyRequest) (*models.RecoveryStarted, error) {
+	challenge, err := s.pushRepo.FindChallenge(ctx, req.ChallengeID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.Status != constants.PushChallengeRecovering {
+		return nil, ErrChallengeNotFound
+	}
+
+	user, err := s.userRepo.FindByID(ctx, challenge.UserID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := s.Verify2FA(ctx, user.Email, req.Code); err != nil {
+		s.recordAudit(ctx, &user.ID, constants.AuditLoginFailed, map[string]interface{}{"reason": "invalid_recovery_email_code"})
+		return nil, err
+	}
+
+	token, cancelToken := generateSecureToken(), generateSecureToken()
+	client := requestctx.ClientInfoFrom(ctx)
+	recovery := &models.TwoFactorRecovery{
+		UserID:          user.ID,
+		TokenHash:       hashRecoveryToken(token),
+		CancelTokenHash: hashRecoveryToken(cancelToken),
+		Status:          constants.RecoveryPending,
+		IPAddress:       client.IP,
+		UserAgent:       client.UserAgent,
+		AvailableAt:     time.Now().Add(s.cfg.TwoFARecoveryDelay),
+	}
+	if err := s.recoveryRepo.CreateRecovery(ctx, recovery); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &user.ID, constants.AuditRecoveryStarted, map[string]interface{}{"available_at": recovery.AvailableAt})
+	logger.Info("two-factor recovery started", "userID", user.ID, "availableAt", recovery.AvailableAt)
+
+	cancelLink := strings.TrimRight(s.cfg.FrontendURL, "/") + "/2fa/recovery/cancel?token=" + url.QueryEscape(cancelToken)
+	s.Notify(ctx, user, Notification{Event: constants.NotificationRecoveryStarted, RecoveryAvailableAt: recovery.AvailableAt, CancelLink: cancelLink})
+
+	return &models.RecoveryStarted{Token: token, AvailableAt: recovery.AvailableAt}, nil
+}
+
+// CompleteRecovery turns 2FA off for the user of a recovery request whose
+// delay has passed and logs them in. Before that it returns a
+// *RetryAfterError wrapping ErrRecoveryPending.
+func (s *AuthService) CompleteRecovery(ctx context.Context, token string) (*response.LoginResponse, error) {
+	recovery, err := s.recoveryRepo.FindRecoveryByToken(ctx, hashRecoveryToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if recovery == nil || recovery.Status != constants.RecoveryPending {
+		return nil, ErrRecoveryNotFound
+	}
+	if wait := time.Until(recovery.AvailableAt); wait > 0 {
+		return nil, &RetryAfterError{ServiceError: ErrRecoveryPending, RetryAfter: wait}
+	}
+
+	user, err := s.userRepo.FindByID(ctx, recovery.UserID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := checkApproval(user); err != nil {
+		return nil, err
+	}
+
+	completed, err := s.recoveryRepo.CompleteRecovery(ctx, recovery.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrRecoveryNotFound
+	}
+	if err := s.twoFARepo.Disable2FA(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	if version, ok := s.revokeAccessTokens(ctx, user.ID, "two_factor_recovered"); ok {
+		user.TokenVersion = version
+	}
+
+	s.recordAudit(ctx, &user.ID, constants.AuditRecoveryCompleted, nil)
+	s.recordAudit(ctx, &user.ID, constants.AuditLoginSuccess, map[string]interface{}{"method": "two_factor_recovery"})
+	logger.Info("two-factor recovery completed", "userID", user.ID)
+
+	s.Notify(ctx, user, Notification{Event: constants.NotificationRecoveryCompleted})
+
+	return s.generateAuthResponse(ctx, user, nil)
+}
+
+// CancelRecovery stops a pending recovery request with the cancel token from
+// the alert email.
+func (s *AuthService) CancelRecovery(ctx context.Context, cancelToken string) error {
+	recovery, err := s.recoveryRepo.CancelRecovery(ctx, hashRecoveryToken(cancelToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecoveryNotFound
+		}
+		return err
+	}
+
+	s.recordAudit(ctx, &recovery.UserID, constants.AuditRecoveryCancelled, nil)
+	logger.Info("two-factor recovery cancelled", "userID", recovery.UserID)
+	return nil
+}
+
+// pendingChallenge returns the unexpired push challenge waiting for approval
+// with the given identifier.
+func (s *AuthService) pendingChallenge(ctx context.Context, challengeID string) (*models.PushChallenge, error) {
+	challenge, err := s.pushRepo.FindChallenge(ctx, challengeID)
+	if err != nil {
+		return nil, err
+	}
+	if challenge == nil || challenge.Method != constants.TwoFAMethodPush || challenge.Status != constants.PushChallengePending {
+		return nil, ErrChallengeNotFound
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, ErrChallengeExpired
+	}
+	return challenge, nil
+}
+
+// normalizeRecoveryCode drops the separator and spaces from a submitted
+// recovery code and uppercases it.
+func normalizeRecoveryCode(code string) string {
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return recoveryCodePolicy.Normalize(code)
+}
+
+// hashRecoveryToken returns the SHA-256 hex digest stored for a recovery
+// code or recovery token.
+func hashRecoveryToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// maxCachedUsers bounds each per-user cache of the checks made on every
+// authenticated request: the least recently used users are evicted beyond
+// it.
+const maxCachedUsers = 100000
+
+// userCache caches a value per user until it expires, keeping at most size
+// users. It is safe for concurrent use.
+type userCache[V any] struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[int64]*list.Element
+}
+
+// userCacheEntry is one cached value.
+type userCacheEntry[V any] struct {
+	userID  int64
+	value   V
+	expires time.Time
+}
+
+func newUserCache[V any](size int) *userCache[V] {
+	return &userCache[V]{size: size, order: list.New(), entries: make(map[int64]*list.Element)}
+}
+
+// get returns userID's cached value. An expired value is removed and
+// returned with fresh false: it is the last known value, for when a new one
+// can't be loaded.
+func (c *userCache[V]) get(userID int64) (value V, fresh, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[userID]
+	if !ok {
+		return value, false, false
+	}
+	entry := e.Value.(*userCacheEntry[V])
+	if time.Now().After(entry.expires) {
+		c.order.Remove(e)
+		delete(c.entries, userID)
+		return entry.value, false, true
+	}
+	c.order.MoveToFront(e)
+	return entry.value, true, true
+}
+
+// set caches value for userID until expires, evicting the least recently
+// used users when full.
+func (c *userCache[V]) set(userID int64, value V, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[userID]; ok {
+		entry := e.Value.(*userCacheEntry[V])
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[userID] = c.order.PushFront(&userCacheEntry[V]{userID: userID, value: value, expires: expires})
+	if c.order.Len() > c.size {
+		c.evict()
+	}
+}
+
+// forget removes userID's value.
+func (c *userCache[V]) forget(userID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[userID]; ok {
+		c.order.Remove(e)
+		delete(c.entries, userID)
+	}
+}
+
+// evict removes the least recently used value, and the expired values
+// next to it. The caller holds mu.
+func (c *userCache[V]) evict() {
+	now := time.Now()
+	for oldest := c.order.Back(); oldest != nil; oldest = c.order.Back() {
+		entry := oldest.Value.(*userCacheEntry[V])
+		if c.order.Len() <= c.size && !now.After(entry.expires) {
+			return
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, entry.userID)
+	}
+}
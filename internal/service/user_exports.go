@@ -0,0 +1,245 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/export"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+	"authentio/pkg/pagination"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// User Exports (admin)
+// ============================================================================
+
+const (
+	// userExportDownloadPath is the signed download link of a ready export
+	userExportDownloadPath = "/api/v1/exports/%s/download?token=%s"
+
+	// userExportBatchSize is how many users are read per query
+	userExportBatchSize = 500
+
+	// userExportTimeout bounds the background generation of an export
+	userExportTimeout = 10 * time.Minute
+)
+
+// userExportColumns is the header row of CSV exports.
+var userExportColumns = []string{
+	"id", "email", "username", "first_name", "last_name", "provider", "role",
+	"is_active", "approval_status", "locale", "created_at",
+}
+
+// StartUserExport queues an export of the tenant's users matching req's
+// filter and returns the pending job. The file is generated in the
+// background; poll UserExport for its download link.
+func (s *AuthService) StartUserExport(ctx context.Context, req models.UserExportRequest, actorID int64) (*response.UserExport, error) {
+	if s.exports == nil {
+		return nil, ErrExportsDisabled
+	}
+	if req.CreatedFrom != nil && req.CreatedTo != nil && !req.CreatedFrom.Before(*req.CreatedTo) {
+		return nil, ErrInvalidExportFilter
+	}
+
+	tenantID := currentTenant(ctx)
+	job := &export.Job{
+		ID:          generateSecureToken()[:32],
+		Format:      req.Format,
+		RequestedBy: actorID,
+	}
+	if err := s.exports.Create(ctx, tenantID, job); err != nil {
+		return nil, err
+	}
+
+	s.recordAudit(ctx, &actorID, constants.AuditUsersExported, map[string]interface{}{
+		"export_id": job.ID,
+		"format":    req.Format,
+		"filter":    req.UserFilter,
+	})
+
+	// The export outlives the request but keeps its tenant and client details
+	go s.runUserExport(context.WithoutCancel(ctx), tenantID, job, req.UserFilter)
+
+	return &response.UserExport{Job: *job}, nil
+}
+
+// UserExport returns one of the tenant's export jobs. A ready export carries
+// a signed link to download its file, valid for EXPORT_LINK_TTL.
+func (s *AuthService) UserExport(ctx context.Context, id string) (*response.UserExport, error) {
+	if s.exports == nil {
+		return nil, ErrExportsDisabled
+	}
+
+	tenantID := currentTenant(ctx)
+	job, err := s.exports.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrExportNotFound
+	}
+
+	result := &response.UserExport{Job: *job}
+	if job.Status != export.StatusReady {
+		return result, nil
+	}
+
+	expiresAt := time.Now().Add(s.cfg.ExportLinkTTL)
+	if job.ExpiresAt.Before(expiresAt) {
+		expiresAt = job.ExpiresAt
+	}
+	token, err := s.jwtManager.GenerateDownloadToken(jwt.DownloadClaims{
+		FileID:    job.ID,
+		TenantID:  tenantID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.DownloadURL = fmt.Sprintf(userExportDownloadPath, job.ID, token)
+	result.DownloadExpiresAt = &expiresAt
+	return result, nil
+}
+
+// DownloadUserExport returns the file of export id and its job, given the
+// token of its signed download link.
+func (s *AuthService) DownloadUserExport(ctx context.Context, id, token string) (*export.Job, []byte, error) {
+	if s.exports == nil {
+		return nil, nil, ErrExportsDisabled
+	}
+
+	tenantID := currentTenant(ctx)
+	claims, err := s.jwtManager.VerifyDownloadToken(token, tenantID)
+	if err != nil || claims.FileID != id {
+		logger.Debug("download token rejected", "error", err)
+		return nil, nil, ErrInvalidDownloadLink
+	}
+
+	job, err := s.exports.Get(ctx, tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job == nil {
+		return nil, nil, ErrExportNotFound
+	}
+	if job.Status != export.StatusReady {
+		return nil, nil, ErrExportNotReady
+	}
+
+	file, err := s.exports.File(ctx, tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file == nil {
+		return nil, nil, ErrExportNotFound
+	}
+	return job, file, nil
+}
+
+// runUserExport generates the file of job and stores it, or marks the job
+// failed.
+func (s *AuthService) runUserExport(ctx context.Context, tenantID int64, job *export.Job, filter models.UserFilter) {
+	ctx, cancel := context.WithTimeout(ctx, userExportTimeout)
+	defer cancel()
+
+	records, err := s.userExportRecords(ctx, filter)
+	var file []byte
+	if err == nil {
+		file, err = encodeUserExport(job.Format, records)
+	}
+	if err == nil {
+		err = s.exports.Complete(ctx, tenantID, job, file, len(records))
+	}
+	if err != nil {
+		logger.Error("user export failed", "error", err, "exportID", job.ID)
+		// Recorded even when the export ran out of time
+		if err := s.exports.Fail(context.WithoutCancel(ctx), tenantID, job, "the export could not be generated"); err != nil {
+			logger.Error("failed to record user export failure", "error", err, "exportID", job.ID)
+		}
+		return
+	}
+
+	logger.Info("user export ready", "exportID", job.ID, "rows", len(records), "bytes", len(file))
+}
+
+// userExportRecords reads every user matching filter, newest first.
+func (s *AuthService) userExportRecords(ctx context.Context, filter models.UserFilter) ([]models.UserExportRecord, error) {
+	var records []models.UserExportRecord
+	var after *pagination.Cursor
+	for {
+		users, err := s.userRepo.List(ctx, filter, after, userExportBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			records = append(records, models.UserExportRecord{
+				ID:             user.ID,
+				Email:          user.Email,
+				Username:       user.Username,
+				FirstName:      user.FirstName,
+				LastName:       user.LastName,
+				Provider:       user.Provider,
+				Role:           user.Role,
+				IsActive:       user.IsActive,
+				ApprovalStatus: user.ApprovalStatus,
+				Locale:         user.Locale,
+				CreatedAt:      user.CreatedAt,
+			})
+		}
+		if len(users) < userExportBatchSize {
+			return records, nil
+		}
+		last := users[len(users)-1]
+		after = &pagination.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+}
+
+// encodeUserExport writes records as a CSV file with a header row, or as a
+// JSON array.
+func encodeUserExport(format string, records []models.UserExportRecord) ([]byte, error) {
+	if format == export.FormatJSON {
+		if records == nil {
+			records = []models.UserExportRecord{}
+		}
+		return json.Marshal(records)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(userExportColumns); err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		row := []string{
+			strconv.FormatInt(r.ID, 10), r.Email, r.Username, r.FirstName, r.LastName, r.Provider, r.Role,
+			strconv.FormatBool(r.IsActive), r.ApprovalStatus, r.Locale, r.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		for i, value := range row {
+			row[i] = csvSafe(value)
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvSafe defuses values a spreadsheet would run as a formula (names are
+// chosen by users) by prefixing them with a quote.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
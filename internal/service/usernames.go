@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+)
+
+// ============================================================================
+// Usernames
+// ============================================================================
+
+// usernamePattern allows 3-30 lowercase letters, digits, underscores and
+// dots, starting with a letter.
+var usernamePattern = regexp.MustCompile(`^[a-z][a-z0-9_.]{2,29}$`)
+
+// reservedUsernames can't be claimed, so they can't be used to impersonate
+// the service or its staff.
+var reservedUsernames = map[string]struct{}{
+	"admin": {}, "administrator": {}, "root": {}, "system": {}, "support": {},
+	"security": {}, "help": {}, "authentio": {}, "api": {}, "www": {},
+	"me": {}, "null": {}, "undefined": {},
+}
+
+// Reasons a username is unavailable, reported by CheckUsername.
+const (
+	usernameInvalid  = "invalid"
+	usernameReserved = "reserved"
+	usernameTaken    = "taken"
+)
+
+// NormalizeUsername lowercases a username and trims surrounding whitespace.
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// IsValidUsername reports whether username (after normalization) matches the
+// allowed format. Reserved names are valid but unavailable.
+func IsValidUsername(username string) bool {
+	return usernamePattern.MatchString(NormalizeUsername(username))
+}
+
+// CheckUsername reports whether a username can be claimed in the current tenant.
+func (s *AuthService) CheckUsername(ctx context.Context, username string) (*response.UsernameAvailability, error) {
+	username = NormalizeUsername(username)
+	result := &response.UsernameAvailability{Username: username}
+
+	reason, err := s.usernameUnavailable(ctx, username, 0)
+	if err != nil {
+		return nil, err
+	}
+	result.Available = reason == ""
+	result.Reason = reason
+	return result, nil
+}
+
+// SetUsername gives the user a new username.
+func (s *AuthService) SetUsername(ctx context.Context, userID int64, username string) (*response.UserResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	username = NormalizeUsername(username)
+	if err := s.claimableUsername(ctx, username, user.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.SetUsername(ctx, user.ID, username); err != nil {
+		return nil, err
+	}
+	user.Username = username
+
+	logger.Info("username changed", "userID", user.ID)
+	userResponse := newUserResponse(user)
+	return &userResponse, nil
+}
+
+// claimableUsername returns the error for a (normalized) username that the
+// given user can't take, or nil.
+func (s *AuthService) claimableUsername(ctx context.Context, username string, userID int64) error {
+	reason, err := s.usernameUnavailable(ctx, username, userID)
+	if err != nil {
+		return err
+	}
+	switch reason {
+	case "":
+		return nil
+	case usernameInvalid:
+		return ErrInvalidUsername
+	default:
+		return ErrUsernameTaken
+	}
+}
+
+// usernameUnavailable returns why a normalized username can't be claimed by
+// userID (0 for a new account), or "" when it can.
+func (s *AuthService) usernameUnavailable(ctx context.Context, username string, userID int64) (string, error) {
+	if !usernamePattern.MatchString(username) {
+		return usernameInvalid, nil
+	}
+	if _, reserved := reservedUsernames[username]; reserved {
+		return usernameReserved, nil
+	}
+
+	existing, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil && existing.ID != userID {
+		return usernameTaken, nil
+	}
+	return "", nil
+}
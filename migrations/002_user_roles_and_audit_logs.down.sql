@@ -0,0 +1,9 @@
+-- Rollback user roles, audit logs and anonymization support
+
+DROP INDEX IF EXISTS idx_audit_logs_event_created_at;
+DROP INDEX IF EXISTS idx_audit_logs_user_id;
+DROP TABLE IF EXISTS audit_logs;
+
+DROP INDEX IF EXISTS idx_users_role;
+ALTER TABLE users DROP COLUMN IF EXISTS anonymized_at;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,34 @@
+-- =============================================================================
+-- USER ROLES, AUDIT LOGS AND ANONYMIZATION SUPPORT
+-- Adds a role column used by the admin API, an audit log of security events,
+-- and an anonymized_at marker for right-to-be-forgotten erasure requests
+-- =============================================================================
+
+-- =============================================================================
+-- USERS TABLE ADDITIONS
+-- =============================================================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(50) NOT NULL DEFAULT 'user';  -- 'user' or 'admin'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP WITH TIME ZONE NULL; -- Set when PII was scrubbed
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+
+-- =============================================================================
+-- AUDIT LOGS TABLE
+-- =============================================================================
+-- Append-only record of security-relevant events (logins, password resets, ...)
+-- ip_address and user_agent are PII and are cleared when a user is anonymized;
+-- the event itself is kept so aggregate statistics remain accurate
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,                                   -- Auto-incrementing primary key
+    user_id BIGINT NULL REFERENCES users(id) ON DELETE SET NULL, -- Subject of the event (nullable for unknown users)
+    actor_id BIGINT NULL REFERENCES users(id) ON DELETE SET NULL, -- Who triggered the event when not the subject (e.g. an admin)
+    event VARCHAR(100) NOT NULL,                                -- Event type: 'login_success', 'user_anonymized', ...
+    ip_address VARCHAR(45) NULL,                                -- Client IP address (IPv4 or IPv6)
+    user_agent TEXT NULL,                                       -- Client user agent
+    metadata JSONB NULL,                                        -- Event specific details
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_event_created_at ON audit_logs(event, created_at);
@@ -0,0 +1,4 @@
+-- Rollback user consents
+
+DROP INDEX IF EXISTS idx_user_consents_user_document;
+DROP TABLE IF EXISTS user_consents;
@@ -0,0 +1,17 @@
+-- =============================================================================
+-- USER CONSENTS TABLE
+-- =============================================================================
+-- Records which Terms of Service / Privacy Policy version each user accepted
+-- and when. Rows are append-only: the latest row per (user_id, document) is
+-- the user's current acceptance, older rows are kept as history
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS user_consents (
+    id BIGSERIAL PRIMARY KEY,                                      -- Auto-incrementing primary key
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- Foreign key to users
+    document VARCHAR(50) NOT NULL,                                 -- Document type: 'tos', 'privacy'
+    version VARCHAR(50) NOT NULL,                                  -- Accepted document version
+    ip_address VARCHAR(45) NULL,                                   -- IP the acceptance came from (scrubbed on anonymization)
+    accepted_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_consents_user_document ON user_consents(user_id, document, accepted_at DESC);
@@ -0,0 +1,3 @@
+-- Rollback user locale preference
+
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
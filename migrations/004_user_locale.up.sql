@@ -0,0 +1,7 @@
+-- =============================================================================
+-- USER LOCALE PREFERENCE
+-- =============================================================================
+-- Preferred language for validation and error messages. NULL means the locale
+-- is negotiated from the Accept-Language header on every request.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(10);
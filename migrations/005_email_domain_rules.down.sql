@@ -0,0 +1,3 @@
+-- Rollback email domain rules
+
+DROP TABLE IF EXISTS email_domain_rules;
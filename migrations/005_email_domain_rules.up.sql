@@ -0,0 +1,14 @@
+-- =============================================================================
+-- EMAIL DOMAIN RULES TABLE
+-- =============================================================================
+-- Registration allowlist / blocklist entries managed through the admin API.
+-- They are combined with REGISTRATION_ALLOWED_DOMAINS / REGISTRATION_BLOCKED_DOMAINS.
+-- When any allow rule exists, only matching domains (and their subdomains) may sign up.
+
+CREATE TABLE IF NOT EXISTS email_domain_rules (
+    id BIGSERIAL PRIMARY KEY,
+    domain VARCHAR(255) NOT NULL UNIQUE,                              -- Lowercase domain, e.g. acme.com
+    rule VARCHAR(10) NOT NULL CHECK (rule IN ('allow', 'block')),     -- allow | block
+    created_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,   -- Admin who added the rule
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
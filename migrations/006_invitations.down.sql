@@ -0,0 +1,6 @@
+-- Rollback invitations
+
+ALTER TABLE users DROP COLUMN IF EXISTS invited_by;
+DROP INDEX IF EXISTS idx_invitations_email;
+DROP INDEX IF EXISTS idx_invitations_invited_by;
+DROP TABLE IF EXISTS invitations;
@@ -0,0 +1,24 @@
+-- =============================================================================
+-- INVITATIONS TABLE
+-- =============================================================================
+-- Invite tokens emailed to prospective users. Only a SHA-256 hash of the token
+-- is stored. When INVITE_ONLY_REGISTRATION is enabled, registering requires a
+-- pending invitation addressed to the registering email.
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,                                      -- Invitee email address
+    token_hash CHAR(64) NOT NULL UNIQUE,                              -- SHA-256 hex of the invite token
+    invited_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,   -- User who sent the invite
+    accepted_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,  -- User created from the invite
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_invited_by ON invitations(invited_by);
+CREATE INDEX IF NOT EXISTS idx_invitations_email ON invitations(LOWER(email));
+
+-- Who invited whom
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invited_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL;
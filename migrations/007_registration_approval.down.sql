@@ -0,0 +1,6 @@
+-- Rollback registration approval
+
+DROP INDEX IF EXISTS idx_users_approval_pending;
+ALTER TABLE users DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS reviewed_by;
+ALTER TABLE users DROP COLUMN IF EXISTS approval_status;
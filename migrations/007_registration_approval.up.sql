@@ -0,0 +1,12 @@
+-- =============================================================================
+-- REGISTRATION APPROVAL
+-- =============================================================================
+-- With REQUIRE_REGISTRATION_APPROVAL enabled, new users start as 'pending' and
+-- cannot log in until an admin approves them. Existing users are 'approved'.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS approval_status VARCHAR(20) NOT NULL DEFAULT 'approved'
+    CHECK (approval_status IN ('pending', 'approved', 'rejected'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reviewed_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP WITH TIME ZONE NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_approval_pending ON users(created_at) WHERE approval_status = 'pending';
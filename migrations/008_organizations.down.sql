@@ -0,0 +1,5 @@
+-- Rollback organizations
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS organization_id;
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,28 @@
+-- =============================================================================
+-- ORGANIZATIONS
+-- =============================================================================
+-- Organizations group users for B2B products where access is granted per
+-- organization. Access tokens can be scoped to one organization (org_id claim);
+-- refresh tokens remember that scope so it survives token rotation.
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,                                       -- Display name
+    slug VARCHAR(63) NOT NULL UNIQUE,                                 -- URL-safe unique identifier
+    created_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,   -- User who created the organization
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS organization_members (
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member',                       -- Role within the organization
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),                -- When the user joined
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_members_user_id ON organization_members(user_id);
+
+-- Organization a refresh token is scoped to (NULL for personal tokens)
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS organization_id BIGINT NULL REFERENCES organizations(id) ON DELETE SET NULL;
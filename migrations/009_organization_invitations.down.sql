@@ -0,0 +1,3 @@
+-- Rollback organization invitations
+
+DROP TABLE IF EXISTS organization_invitations;
@@ -0,0 +1,22 @@
+-- =============================================================================
+-- ORGANIZATION INVITATIONS TABLE
+-- =============================================================================
+-- Invite tokens emailed by organization owners. Only a SHA-256 hash of the
+-- token is stored. Existing users accept with POST /orgs/invitations/accept;
+-- new users pass the token as org_invite_token when registering.
+
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,                                      -- Invitee email address
+    role VARCHAR(20) NOT NULL DEFAULT 'member',                       -- Role granted on acceptance
+    token_hash CHAR(64) NOT NULL UNIQUE,                              -- SHA-256 hex of the invite token
+    invited_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,   -- User who sent the invite
+    accepted_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,  -- User who joined with the invite
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_org_id ON organization_invitations(organization_id);
@@ -0,0 +1,4 @@
+-- Rollback organization roles
+
+ALTER TABLE organization_invitations DROP CONSTRAINT IF EXISTS organization_invitations_role_check;
+ALTER TABLE organization_members DROP CONSTRAINT IF EXISTS organization_members_role_check;
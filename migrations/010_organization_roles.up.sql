@@ -0,0 +1,13 @@
+-- =============================================================================
+-- ORGANIZATION ROLES
+-- =============================================================================
+-- Members are owner, admin or member. Owners and admins manage members and
+-- invitations; only owners can grant or remove the owner role.
+
+ALTER TABLE organization_members DROP CONSTRAINT IF EXISTS organization_members_role_check;
+ALTER TABLE organization_members ADD CONSTRAINT organization_members_role_check
+    CHECK (role IN ('owner', 'admin', 'member'));
+
+ALTER TABLE organization_invitations DROP CONSTRAINT IF EXISTS organization_invitations_role_check;
+ALTER TABLE organization_invitations ADD CONSTRAINT organization_invitations_role_check
+    CHECK (role IN ('owner', 'admin', 'member'));
@@ -0,0 +1,21 @@
+-- Rollback tenants (fails if the same email or slug exists in several tenants)
+
+DROP INDEX IF EXISTS idx_invitations_tenant_id;
+DROP INDEX IF EXISTS idx_otps_tenant_email;
+
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_tenant_slug_key;
+ALTER TABLE organizations ADD CONSTRAINT organizations_slug_key UNIQUE (slug);
+ALTER TABLE organizations DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE email_domain_rules DROP CONSTRAINT IF EXISTS email_domain_rules_tenant_domain_key;
+ALTER TABLE email_domain_rules ADD CONSTRAINT email_domain_rules_domain_key UNIQUE (domain);
+ALTER TABLE email_domain_rules DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE invitations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE otps DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_tenant_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,39 @@
+-- =============================================================================
+-- TENANTS TABLE
+-- =============================================================================
+-- A tenant is an isolated application served by the same deployment. Users,
+-- OTPs, invitations, email domain rules and organizations belong to exactly
+-- one tenant; the same email can register separately in every tenant.
+-- Existing data is assigned to the built-in "default" tenant (id 1).
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,                                 -- Resolved from header, subdomain or path
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO tenants (id, slug, name) VALUES (1, 'default', 'Default')
+ON CONFLICT (id) DO NOTHING;
+SELECT setval(pg_get_serial_sequence('tenants', 'id'), GREATEST((SELECT MAX(id) FROM tenants), 1));
+
+-- Users: emails are unique per tenant instead of globally
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+
+-- Email domain rules: one rule per domain and tenant
+ALTER TABLE email_domain_rules ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE email_domain_rules DROP CONSTRAINT IF EXISTS email_domain_rules_domain_key;
+ALTER TABLE email_domain_rules ADD CONSTRAINT email_domain_rules_tenant_domain_key UNIQUE (tenant_id, domain);
+
+-- Organizations: slugs are unique per tenant
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id);
+ALTER TABLE organizations DROP CONSTRAINT IF EXISTS organizations_slug_key;
+ALTER TABLE organizations ADD CONSTRAINT organizations_tenant_slug_key UNIQUE (tenant_id, slug);
+
+CREATE INDEX IF NOT EXISTS idx_otps_tenant_email ON otps(tenant_id, email);
+CREATE INDEX IF NOT EXISTS idx_invitations_tenant_id ON invitations(tenant_id);
@@ -0,0 +1,6 @@
+-- Rollback tenant signing keys
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS refresh_token_ttl_seconds;
+ALTER TABLE tenants DROP COLUMN IF EXISTS access_token_ttl_seconds;
+ALTER TABLE tenants DROP COLUMN IF EXISTS jwt_issuer;
+ALTER TABLE tenants DROP COLUMN IF EXISTS jwt_secret;
@@ -0,0 +1,12 @@
+-- =============================================================================
+-- TENANT SIGNING KEYS
+-- =============================================================================
+-- Optional per-tenant JWT settings. jwt_secret holds the HMAC key itself or a
+-- reference to it ("env:NAME" or "file:/path", e.g. a secret mounted from a
+-- KMS). Tenants without a secret sign with a key derived from JWT_SECRET; NULL
+-- issuer and TTLs use JWT_ISSUER, ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL.
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS jwt_secret TEXT NULL;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS jwt_issuer VARCHAR(255) NULL;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS access_token_ttl_seconds INTEGER NULL CHECK (access_token_ttl_seconds > 0);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS refresh_token_ttl_seconds INTEGER NULL CHECK (refresh_token_ttl_seconds > 0);
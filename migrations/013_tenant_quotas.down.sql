@@ -0,0 +1,3 @@
+-- Rollback tenant quotas
+
+DROP TABLE IF EXISTS tenant_quotas;
@@ -0,0 +1,13 @@
+-- =============================================================================
+-- TENANT QUOTAS TABLE
+-- =============================================================================
+-- Per-tenant daily quota overrides. Metrics without a row use the
+-- TENANT_QUOTA_*_PER_DAY defaults; a daily_limit of 0 means unlimited.
+-- Usage counters themselves live in Redis.
+
+CREATE TABLE IF NOT EXISTS tenant_quotas (
+    tenant_id BIGINT NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    metric VARCHAR(50) NOT NULL CHECK (metric IN ('requests', 'registrations', 'otp_emails')),
+    daily_limit BIGINT NOT NULL CHECK (daily_limit >= 0),
+    PRIMARY KEY (tenant_id, metric)
+);
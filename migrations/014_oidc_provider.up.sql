@@ -0,0 +1,45 @@
+-- =============================================================================
+-- OAUTH CLIENTS TABLE
+-- =============================================================================
+-- Applications registered with the OpenID Connect provider. Confidential
+-- clients authenticate with a secret (only its SHA-256 hash is stored);
+-- public clients (SPAs, mobile apps) have none and must use PKCE.
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id),
+    client_id VARCHAR(64) NOT NULL UNIQUE,                            -- Public identifier sent by the client
+    secret_hash CHAR(64) NULL,                                        -- SHA-256 hex of the secret; NULL for public clients
+    name VARCHAR(255) NOT NULL,                                       -- Shown to users on the consent screen
+    redirect_uris JSONB NOT NULL,                                     -- Exact redirect URIs the client may use
+    scopes VARCHAR(255) NOT NULL,                                     -- Space-separated scopes the client may request
+    created_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_clients_tenant_id ON oauth_clients(tenant_id);
+
+-- =============================================================================
+-- OAUTH AUTHORIZATION CODES TABLE
+-- =============================================================================
+-- Single-use codes issued by the authorization endpoint and exchanged at the
+-- token endpoint. Only a SHA-256 hash of the code is stored.
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    id BIGSERIAL PRIMARY KEY,
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    client_id BIGINT NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    nonce VARCHAR(255) NOT NULL DEFAULT '',
+    code_challenge VARCHAR(128) NOT NULL DEFAULT '',                  -- PKCE S256 challenge; empty when not used
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Refresh tokens issued to OAuth clients (offline_access) carry the client and
+-- granted scope; first-party refresh tokens leave both NULL
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS oauth_client_id BIGINT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS scope VARCHAR(255) NULL;
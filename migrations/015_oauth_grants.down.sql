@@ -0,0 +1,3 @@
+-- Rollback OAuth grants
+
+DROP TABLE IF EXISTS oauth_grants;
@@ -0,0 +1,16 @@
+-- =============================================================================
+-- OAUTH GRANTS TABLE
+-- =============================================================================
+-- Scopes each user has approved for an OAuth client on the consent screen.
+-- Authorization requests covered by an existing grant don't need to ask
+-- again; revoking a grant also invalidates the client's refresh tokens.
+
+CREATE TABLE IF NOT EXISTS oauth_grants (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id BIGINT NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    scope VARCHAR(255) NOT NULL,                                      -- Space-separated approved scopes
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, client_id)
+);
@@ -0,0 +1,3 @@
+-- Rollback user profiles
+
+DROP TABLE IF EXISTS user_profiles;
@@ -0,0 +1,15 @@
+-- =============================================================================
+-- USER PROFILES TABLE
+-- =============================================================================
+-- Optional, non-critical profile details kept apart from the authentication
+-- fields in users. Names and the avatar URL stay on users; a user without a
+-- row here simply has an empty profile.
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    display_name VARCHAR(100) NOT NULL DEFAULT '',                    -- Public name chosen by the user
+    bio TEXT NULL,                                                    -- Short self-description (max 500 chars)
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Rollback user metadata
+
+ALTER TABLE users DROP COLUMN IF EXISTS app_metadata;
+ALTER TABLE users DROP COLUMN IF EXISTS user_metadata;
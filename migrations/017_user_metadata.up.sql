@@ -0,0 +1,9 @@
+-- =============================================================================
+-- USER METADATA
+-- =============================================================================
+-- Free-form JSON attributes attached by consuming applications. Users edit
+-- user_metadata themselves; app_metadata (plan, permissions, external IDs...)
+-- can only be changed through the admin API.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS user_metadata JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS app_metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+-- Rollback usernames
+
+DROP INDEX IF EXISTS idx_users_tenant_username;
+ALTER TABLE users DROP COLUMN IF EXISTS username;
@@ -0,0 +1,9 @@
+-- =============================================================================
+-- USERNAMES
+-- =============================================================================
+-- Optional login name, unique per tenant. Stored lowercase; the index is on
+-- LOWER(username) so rows written by other tools can't introduce case variants.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(30) NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_username ON users(tenant_id, LOWER(username)) WHERE username IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Rollback notification preferences
+
+DROP TABLE IF EXISTS user_preferences;
@@ -0,0 +1,14 @@
+-- =============================================================================
+-- NOTIFICATION PREFERENCES TABLE
+-- =============================================================================
+-- Which optional emails a user wants to receive. Users without a row get the
+-- defaults below. Critical mail (verification codes, password resets,
+-- invitations, approval decisions) is always sent.
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    security_alert_emails BOOLEAN NOT NULL DEFAULT TRUE,               -- e.g. "your password was changed"
+    marketing_emails BOOLEAN NOT NULL DEFAULT FALSE,                   -- Product news; opt-in only
+    login_notifications BOOLEAN NOT NULL DEFAULT FALSE,                -- An email on every successful login
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,4 @@
+-- Rollback push approval 2FA
+
+DROP TABLE IF EXISTS push_challenges;
+DROP TABLE IF EXISTS push_devices;
@@ -0,0 +1,32 @@
+-- =============================================================================
+-- PUSH APPROVAL 2FA
+-- =============================================================================
+-- Mobile devices that receive login approval requests, and the requests
+-- themselves. A login by a user with push 2FA creates a pending challenge
+-- that the user approves or denies on a device; the login client polls it
+-- and receives tokens once it is approved.
+
+CREATE TABLE IF NOT EXISTS push_devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL,                                    -- 'fcm' or 'apns'
+    token TEXT NOT NULL,                                              -- Registration token from the platform
+    name VARCHAR(100) NOT NULL DEFAULT '',                            -- e.g. "John's iPhone"
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, token)
+);
+
+CREATE TABLE IF NOT EXISTS push_challenges (
+    id BIGSERIAL PRIMARY KEY,
+    challenge_id VARCHAR(64) NOT NULL UNIQUE,                         -- Random identifier given to the login client
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    login_method VARCHAR(20) NOT NULL,                                -- First factor: 'password' or 'google'
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',                    -- pending, approved, denied, completed
+    ip_address VARCHAR(45) NULL,                                      -- Client that started the login
+    user_agent TEXT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_challenges_user_pending ON push_challenges(user_id) WHERE status = 'pending';
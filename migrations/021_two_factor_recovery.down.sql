@@ -0,0 +1,4 @@
+-- Rollback two-factor recovery
+
+DROP TABLE IF EXISTS two_factor_recoveries;
+DROP TABLE IF EXISTS recovery_codes;
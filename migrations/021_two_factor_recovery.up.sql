@@ -0,0 +1,33 @@
+-- =============================================================================
+-- TWO-FACTOR RECOVERY
+-- =============================================================================
+-- Ways back into an account whose second factor is lost. Recovery codes are
+-- single-use codes the user saved in advance; each completes one login. A
+-- recovery request is the fallback: the user proves access to their email,
+-- waits out a mandatory delay during which the account owner is alerted and
+-- can cancel, and 2FA is then turned off.
+-- A push challenge moves to 'recovering' once email recovery starts from it.
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,                                   -- SHA-256 of the normalized code
+    used_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS two_factor_recoveries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,                           -- Completes the recovery once available
+    cancel_token_hash VARCHAR(64) NOT NULL UNIQUE,                    -- Sent to the owner to stop it
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',                    -- pending, cancelled, completed
+    ip_address VARCHAR(45) NULL,                                      -- Client that started the recovery
+    user_agent TEXT NULL,
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL,                   -- End of the cool-down period
+    completed_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_recoveries_user_pending ON two_factor_recoveries(user_id) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+-- Rollback password age
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
@@ -0,0 +1,9 @@
+-- =============================================================================
+-- PASSWORD AGE
+-- =============================================================================
+-- When the user's password was last set, for the security overview. Existing
+-- passwords are assumed to date from registration.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP WITH TIME ZONE NULL;
+
+UPDATE users SET password_changed_at = created_at WHERE password IS NOT NULL AND password_changed_at IS NULL;
@@ -0,0 +1,5 @@
+-- Rollback device registry
+
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS device_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS device_id;
+DROP TABLE IF EXISTS user_devices;
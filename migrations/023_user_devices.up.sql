@@ -0,0 +1,24 @@
+-- =============================================================================
+-- DEVICE REGISTRY
+-- =============================================================================
+-- Devices a user signs in from, identified by the fingerprint the client sends
+-- in the X-Device-Fingerprint header. Only a hash of the fingerprint is kept.
+-- Sessions (refresh tokens) and audit events record the device they came from.
+
+CREATE TABLE IF NOT EXISTS user_devices (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fingerprint_hash VARCHAR(64) NOT NULL,                            -- SHA-256 of the client fingerprint
+    name VARCHAR(100) NOT NULL DEFAULT '',                            -- Set by the user, e.g. "Work laptop"
+    platform VARCHAR(20) NOT NULL DEFAULT 'unknown',                  -- ios, android, windows, macos, linux, chromeos, unknown
+    ip_address VARCHAR(45) NULL,                                      -- Last seen from
+    user_agent TEXT NULL,
+    first_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, fingerprint_hash)
+);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS device_id BIGINT NULL REFERENCES user_devices(id) ON DELETE SET NULL;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS device_id BIGINT NULL REFERENCES user_devices(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_device_id ON refresh_tokens(device_id);
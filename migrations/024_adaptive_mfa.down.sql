@@ -0,0 +1,7 @@
+-- Rollback adaptive MFA
+
+ALTER TABLE push_challenges DROP COLUMN IF EXISTS method;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS mfa_risk_block_at;
+ALTER TABLE tenants DROP COLUMN IF EXISTS mfa_risk_require_at;
+ALTER TABLE tenants DROP COLUMN IF EXISTS mfa_risk_skip_below;
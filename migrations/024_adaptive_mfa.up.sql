@@ -0,0 +1,13 @@
+-- =============================================================================
+-- ADAPTIVE MFA
+-- =============================================================================
+-- Per-tenant login risk thresholds (0-100); NULL uses MFA_RISK_SKIP_BELOW,
+-- MFA_RISK_REQUIRE_AT and MFA_RISK_BLOCK_AT, and 0 turns a threshold off.
+-- Login challenges are either push approvals or, for risky logins by users
+-- without push 2FA, a code sent by email.
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS mfa_risk_skip_below SMALLINT NULL CHECK (mfa_risk_skip_below BETWEEN 0 AND 100);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS mfa_risk_require_at SMALLINT NULL CHECK (mfa_risk_require_at BETWEEN 0 AND 100);
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS mfa_risk_block_at SMALLINT NULL CHECK (mfa_risk_block_at BETWEEN 0 AND 100);
+
+ALTER TABLE push_challenges ADD COLUMN IF NOT EXISTS method VARCHAR(20) NOT NULL DEFAULT 'push'; -- 'push' or 'email'
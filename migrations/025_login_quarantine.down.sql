@@ -0,0 +1,5 @@
+-- Rollback login quarantine
+
+ALTER TABLE push_challenges DROP COLUMN IF EXISTS approval_token_hash;
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS mfa_risk_quarantine_at;
@@ -0,0 +1,10 @@
+-- =============================================================================
+-- LOGIN QUARANTINE
+-- =============================================================================
+-- Logins whose risk score reaches the quarantine threshold are held as a
+-- 'quarantine' challenge until the user approves them with the one-click link
+-- emailed to them. Only a hash of the link's token is kept.
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS mfa_risk_quarantine_at SMALLINT NULL CHECK (mfa_risk_quarantine_at BETWEEN 0 AND 100);
+
+ALTER TABLE push_challenges ADD COLUMN IF NOT EXISTS approval_token_hash VARCHAR(64) NULL UNIQUE; -- SHA-256 of the emailed approval token
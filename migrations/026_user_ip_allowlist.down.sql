@@ -0,0 +1,5 @@
+-- Rollback per-account IP allowlist
+
+ALTER TABLE users DROP COLUMN IF EXISTS ip_allowlist_mode;
+
+DROP TABLE IF EXISTS user_ip_allowlist;
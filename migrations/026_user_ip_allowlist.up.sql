@@ -0,0 +1,21 @@
+-- =============================================================================
+-- PER-ACCOUNT IP ALLOWLIST
+-- =============================================================================
+-- Addresses (CIDR blocks) an account may be used from. An account with at
+-- least one permanent entry is restricted: in 'block' mode logins and API
+-- requests from other addresses are refused; in 'verify' mode a login from
+-- another address needs a second factor, after which that address is allowed
+-- temporarily (expires_at).
+
+CREATE TABLE IF NOT EXISTS user_ip_allowlist (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cidr CIDR NOT NULL,                                               -- e.g. 203.0.113.0/24; single addresses are /32 or /128
+    label VARCHAR(100) NOT NULL DEFAULT '',                           -- e.g. "Office VPN"
+    created_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,   -- The user or an admin
+    expires_at TIMESTAMP WITH TIME ZONE NULL,                         -- Set for addresses allowed after step-up verification
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, cidr)
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ip_allowlist_mode VARCHAR(10) NOT NULL DEFAULT 'block'; -- 'block' or 'verify'
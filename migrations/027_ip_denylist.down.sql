@@ -0,0 +1,3 @@
+-- Rollback global IP denylist
+
+DROP TABLE IF EXISTS ip_denylist;
@@ -0,0 +1,15 @@
+-- =============================================================================
+-- GLOBAL IP DENYLIST
+-- =============================================================================
+-- Addresses (CIDR blocks) refused on every route before any other processing,
+-- managed by admins of the default tenant. Entries with expires_at stop
+-- applying once it has passed.
+
+CREATE TABLE IF NOT EXISTS ip_denylist (
+    id BIGSERIAL PRIMARY KEY,
+    cidr CIDR NOT NULL UNIQUE,                                        -- e.g. 198.51.100.0/24; single addresses are /32 or /128
+    reason VARCHAR(255) NOT NULL DEFAULT '',                          -- e.g. "credential stuffing"
+    created_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NULL,                         -- NULL blocks until removed
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
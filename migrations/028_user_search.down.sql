@@ -0,0 +1,5 @@
+-- Rollback user search
+
+DROP INDEX IF EXISTS idx_users_search_fts;
+DROP INDEX IF EXISTS idx_users_search_trgm;
+ALTER TABLE users DROP COLUMN IF EXISTS search_text;
@@ -0,0 +1,15 @@
+-- =============================================================================
+-- USER SEARCH
+-- =============================================================================
+-- Admin search across email, name and username. search_text holds the
+-- lowercased fields; a trigram index serves substring and typo-tolerant
+-- (word similarity) matches, and a full-text index ranks whole-word matches.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+    LOWER(email || ' ' || first_name || ' ' || last_name || ' ' || COALESCE(username, ''))
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_trgm ON users USING GIN (search_text gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_search_fts ON users USING GIN (to_tsvector('simple', search_text));
@@ -0,0 +1,3 @@
+-- Rollback email delivery log
+
+DROP TABLE IF EXISTS email_log;
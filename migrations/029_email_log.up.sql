@@ -0,0 +1,33 @@
+-- =============================================================================
+-- EMAIL DELIVERY LOG
+-- =============================================================================
+-- One row per recipient of every email sent, linked to the user and the event
+-- that triggered it (e.g. password_reset), for support investigations. The
+-- status follows each delivery attempt and the provider's event webhooks
+-- (SendGrid): queued -> sent -> delivered, or failed, bounced, dropped.
+-- Opens are tracked apart from the status. Bodies are never stored since
+-- they can contain one-time codes.
+
+CREATE TABLE IF NOT EXISTS email_log (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE,
+    message_id VARCHAR(64) NOT NULL,                                  -- shared by the recipients of one email; sent to the provider
+    user_id BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,      -- NULL for invitations and admin notifications
+    event VARCHAR(64) NOT NULL,                                       -- what triggered the email, e.g. password_reset
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',                     -- queued, sent, delivered, failed, bounced, dropped
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT NULL,                                                  -- last send error or bounce reason
+    provider_message_id VARCHAR(255) NULL,                            -- e.g. SendGrid X-Message-Id
+    sent_at TIMESTAMP WITH TIME ZONE NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE NULL,
+    opened_at TIMESTAMP WITH TIME ZONE NULL,                          -- first open
+    open_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT email_log_message_recipient_key UNIQUE (message_id, recipient)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_log_tenant_created_at ON email_log(tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_email_log_user_id ON email_log(user_id, created_at DESC);
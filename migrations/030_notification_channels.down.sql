@@ -0,0 +1,8 @@
+-- Rollback notification channels
+
+ALTER TABLE user_preferences
+    DROP COLUMN IF EXISTS login_notification_push,
+    DROP COLUMN IF EXISTS login_notification_sms,
+    DROP COLUMN IF EXISTS security_alert_push,
+    DROP COLUMN IF EXISTS security_alert_sms,
+    DROP COLUMN IF EXISTS phone_number;
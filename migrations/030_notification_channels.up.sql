@@ -0,0 +1,13 @@
+-- =============================================================================
+-- NOTIFICATION CHANNELS
+-- =============================================================================
+-- Security alerts and login notifications can also be sent by text message
+-- and to the user's push devices. Email stays controlled by the existing
+-- columns; the new channels are opt-in.
+
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS phone_number VARCHAR(16),                        -- E.164; where text messages go
+    ADD COLUMN IF NOT EXISTS security_alert_sms BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS security_alert_push BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS login_notification_sms BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS login_notification_push BOOLEAN NOT NULL DEFAULT FALSE;
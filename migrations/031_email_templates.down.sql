@@ -0,0 +1,3 @@
+-- Rollback email templates
+
+DROP TABLE IF EXISTS email_templates;
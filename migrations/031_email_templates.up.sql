@@ -0,0 +1,23 @@
+-- =============================================================================
+-- EMAIL TEMPLATES
+-- =============================================================================
+-- Versions of the email templates edited through the admin API. Saving a
+-- template adds a version and makes it active; older versions are kept so an
+-- edit can be rolled back. Templates without an active version use the
+-- defaults embedded in the binary.
+
+CREATE TABLE IF NOT EXISTS email_templates (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,                                        -- e.g. welcome, password_reset
+    version INT NOT NULL,                                             -- 1, 2, ... per tenant and name
+    subject TEXT NOT NULL,                                            -- html/template source
+    content TEXT NOT NULL,                                            -- html/template source placed in the layout
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT email_templates_version_key UNIQUE (tenant_id, name, version)
+);
+
+-- At most one active version per template
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_templates_active ON email_templates(tenant_id, name) WHERE active;
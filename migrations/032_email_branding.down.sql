@@ -0,0 +1,8 @@
+-- Rollback email branding
+
+ALTER TABLE tenants DROP COLUMN IF EXISTS email_footer_text;
+ALTER TABLE tenants DROP COLUMN IF EXISTS email_background_color;
+ALTER TABLE tenants DROP COLUMN IF EXISTS email_primary_color;
+ALTER TABLE tenants DROP COLUMN IF EXISTS email_logo_url;
+ALTER TABLE tenants DROP COLUMN IF EXISTS email_from_address;
+ALTER TABLE tenants DROP COLUMN IF EXISTS email_from_name;
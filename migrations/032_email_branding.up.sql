@@ -0,0 +1,12 @@
+-- =============================================================================
+-- EMAIL BRANDING
+-- =============================================================================
+-- Per-tenant sender, logo, colors and footer of the emails. NULL uses the
+-- deployment defaults (SMTP_FROM, EMAIL_FROM_NAME and the built-in look).
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS email_from_name VARCHAR(100) NULL;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS email_from_address VARCHAR(255) NULL;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS email_logo_url VARCHAR(2048) NULL;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS email_primary_color VARCHAR(9) NULL;    -- CSS hex color, e.g. #2563eb
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS email_background_color VARCHAR(9) NULL;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS email_footer_text VARCHAR(500) NULL;
@@ -0,0 +1,5 @@
+-- Rollback notification opt-outs
+
+ALTER TABLE user_preferences
+    DROP COLUMN IF EXISTS password_expiry_reminders,
+    DROP COLUMN IF EXISTS new_location_alerts;
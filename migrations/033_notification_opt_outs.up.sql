@@ -0,0 +1,10 @@
+-- =============================================================================
+-- NOTIFICATION OPT-OUTS
+-- =============================================================================
+-- Optional notification classes users can turn off one by one. Critical
+-- security alerts (password changed, two-factor authentication turned off)
+-- have no switch and are always emailed.
+
+ALTER TABLE user_preferences
+    ADD COLUMN IF NOT EXISTS new_location_alerts BOOLEAN NOT NULL DEFAULT TRUE,       -- login from an unfamiliar IP address
+    ADD COLUMN IF NOT EXISTS password_expiry_reminders BOOLEAN NOT NULL DEFAULT TRUE; -- password about to exceed PASSWORD_MAX_AGE
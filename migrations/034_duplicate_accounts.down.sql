@@ -0,0 +1,4 @@
+-- Rollback duplicate accounts
+
+DROP TABLE IF EXISTS duplicate_account_members;
+DROP TABLE IF EXISTS duplicate_account_groups;
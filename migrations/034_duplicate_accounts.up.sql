@@ -0,0 +1,30 @@
+-- =============================================================================
+-- DUPLICATE ACCOUNTS
+-- =============================================================================
+-- Accounts that likely belong to the same person, found by a periodic scan:
+-- the same email once plus tags are removed, the same phone number, or the
+-- same identity provider subject. Each scan replaces the groups; admins can
+-- dismiss a group, which stays dismissed until another account joins it.
+
+CREATE TABLE IF NOT EXISTS duplicate_account_groups (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE,
+    reason VARCHAR(20) NOT NULL,                                      -- email, phone or provider
+    match_key VARCHAR(320) NOT NULL,                                  -- normalized email, phone number or provider:subject
+    status VARCHAR(20) NOT NULL DEFAULT 'open',                       -- open or dismissed
+    dismissed_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    dismissed_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),                -- first detected
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),                -- members last changed
+    CONSTRAINT duplicate_account_groups_key UNIQUE (tenant_id, reason, match_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_duplicate_account_groups_status ON duplicate_account_groups(tenant_id, status, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS duplicate_account_members (
+    group_id BIGINT NOT NULL REFERENCES duplicate_account_groups(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_duplicate_account_members_user ON duplicate_account_members(user_id);
@@ -0,0 +1,7 @@
+-- Rollback encrypted 2FA secrets (encrypted values become unreadable)
+
+DROP INDEX IF EXISTS idx_two_fa_configs_secret_key_id;
+
+ALTER TABLE two_fa_configs
+    DROP COLUMN IF EXISTS secret_data_key,
+    DROP COLUMN IF EXISTS secret_key_id;
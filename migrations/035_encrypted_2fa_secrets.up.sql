@@ -0,0 +1,14 @@
+-- =============================================================================
+-- ENCRYPTED 2FA SECRETS
+-- =============================================================================
+-- 2FA secrets are stored with envelope encryption: secret holds the value
+-- encrypted with its own data key (base64), secret_data_key that data key
+-- wrapped by the master key named in secret_key_id. Rows without a key id
+-- are unencrypted and get encrypted at startup once a master key is set.
+
+ALTER TABLE two_fa_configs
+    ADD COLUMN IF NOT EXISTS secret_key_id VARCHAR(64),  -- master key that wrapped secret_data_key
+    ADD COLUMN IF NOT EXISTS secret_data_key TEXT;       -- wrapped data key (base64)
+
+-- Finds the secrets still wrapped by a retired master key
+CREATE INDEX IF NOT EXISTS idx_two_fa_configs_secret_key_id ON two_fa_configs(secret_key_id);
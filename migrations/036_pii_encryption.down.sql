@@ -0,0 +1,11 @@
+-- Rollback PII encryption (encrypted values must be decrypted first)
+
+DROP INDEX IF EXISTS idx_push_devices_user_token_hash;
+DROP INDEX IF EXISTS idx_user_preferences_phone_number_hash;
+
+ALTER TABLE push_devices
+    DROP COLUMN IF EXISTS token_hash;
+
+ALTER TABLE user_preferences
+    DROP COLUMN IF EXISTS phone_number_hash,
+    ALTER COLUMN phone_number TYPE VARCHAR(16);
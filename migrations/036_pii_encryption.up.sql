@@ -0,0 +1,19 @@
+-- =============================================================================
+-- PII ENCRYPTION
+-- =============================================================================
+-- With PII_ENCRYPTION, phone numbers and push device tokens are stored
+-- encrypted ("enc:v1:..." values, longer than the plain ones). Each is
+-- paired with a blind index (HMAC of the plain value) used to find rows by
+-- value and to detect duplicates without decrypting them.
+
+ALTER TABLE user_preferences
+    ALTER COLUMN phone_number TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS phone_number_hash VARCHAR(64);  -- blind index of phone_number
+
+ALTER TABLE push_devices
+    ADD COLUMN IF NOT EXISTS token_hash VARCHAR(64);         -- blind index of token
+
+CREATE INDEX IF NOT EXISTS idx_user_preferences_phone_number_hash ON user_preferences(phone_number_hash);
+
+-- Encrypted tokens never repeat, so devices are deduplicated by hash
+CREATE UNIQUE INDEX IF NOT EXISTS idx_push_devices_user_token_hash ON push_devices(user_id, token_hash);
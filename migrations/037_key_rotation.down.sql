@@ -0,0 +1,4 @@
+-- Rollback key rotation
+
+DROP TABLE IF EXISTS key_rotations;
+DROP TABLE IF EXISTS managed_keys;
@@ -0,0 +1,40 @@
+-- =============================================================================
+-- KEY ROTATION
+-- =============================================================================
+-- Keys generated by rotations started through the admin API: the JWT signing
+-- secret, the OpenID Connect provider's RSA key and the master key of
+-- encrypted columns. Their material is sealed with the configured master key
+-- (SECRETS_ENCRYPTION_KEY or the KMS key). One key per purpose is active;
+-- the keys it replaced stay verify-only (or decrypt-only) until phased out.
+
+CREATE TABLE IF NOT EXISTS managed_keys (
+    id BIGSERIAL PRIMARY KEY,
+    purpose VARCHAR(20) NOT NULL,                        -- jwt, oidc or secrets
+    key_id VARCHAR(64) NOT NULL,                         -- "kid" header or secretbox key ID
+    material TEXT NOT NULL,                              -- sealed key material
+    status VARCHAR(20) NOT NULL DEFAULT 'active',        -- active, verify_only or retired
+    created_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    retired_at TIMESTAMP WITH TIME ZONE NULL,            -- when it stopped signing or sealing
+    CONSTRAINT managed_keys_key UNIQUE (purpose, key_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_managed_keys_active ON managed_keys(purpose) WHERE status = 'active';
+
+-- Rotations and the progress of their re-encryption job
+CREATE TABLE IF NOT EXISTS key_rotations (
+    id BIGSERIAL PRIMARY KEY,
+    purpose VARCHAR(20) NOT NULL,
+    key_id VARCHAR(64) NOT NULL,                         -- new key
+    previous_key_id VARCHAR(64) NOT NULL,                -- key made verify-only
+    status VARCHAR(20) NOT NULL DEFAULT 'running',       -- running, completed or failed
+    reencrypted INTEGER NOT NULL DEFAULT 0,              -- values moved to the new key
+    error TEXT NULL,
+    requested_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+-- One rotation of each purpose at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_key_rotations_running ON key_rotations(purpose) WHERE status = 'running';
+CREATE INDEX IF NOT EXISTS idx_key_rotations_started ON key_rotations(started_at DESC);
@@ -0,0 +1,5 @@
+-- Rollback token versions
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS auth_time;
+
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
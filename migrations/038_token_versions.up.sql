@@ -0,0 +1,12 @@
+-- =============================================================================
+-- TOKEN VERSIONS
+-- =============================================================================
+-- Access tokens carry the token_version of their user ("ver" claim). It is
+-- incremented when the user's privileges change (2FA state, organization
+-- role, app metadata), so tokens issued before are refused and clients must
+-- refresh them. auth_time is when the session's user last authenticated,
+-- kept across refreshes ("auth_time" claim).
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP WITH TIME ZONE NULL;
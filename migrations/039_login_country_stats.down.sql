@@ -0,0 +1,3 @@
+-- Rollback login country statistics
+
+DROP TABLE IF EXISTS login_country_stats;
@@ -0,0 +1,18 @@
+-- =============================================================================
+-- LOGIN COUNTRY STATISTICS
+-- =============================================================================
+-- Daily rollups of the sign-ins of each tenant per country of the client
+-- (resolved by GeoIP: an ISO 3166-1 alpha-2 code, LOCAL or UNKNOWN), so
+-- geographic spikes (e.g. credential stuffing campaigns) show up in the
+-- admin statistics. Unlike audit_logs, failed sign-ins with an unknown email
+-- are counted. Counters are incremented as sign-ins happen; the rows hold no
+-- personal data.
+
+CREATE TABLE IF NOT EXISTS login_country_stats (
+    tenant_id BIGINT NOT NULL DEFAULT 1 REFERENCES tenants(id) ON DELETE CASCADE,
+    day DATE NOT NULL,                                                -- UTC
+    country VARCHAR(16) NOT NULL,
+    successful BIGINT NOT NULL DEFAULT 0,
+    failed BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (tenant_id, day, country)
+);
@@ -0,0 +1,3 @@
+-- Rollback geo policies
+
+DROP TABLE IF EXISTS geo_policies;
@@ -0,0 +1,21 @@
+-- =============================================================================
+-- GEO POLICIES
+-- =============================================================================
+-- Geographic sign-in rules of a tenant, managed by its admins: blocked,
+-- allowed and suspicious countries (ISO 3166-1 alpha-2 codes, JSON arrays)
+-- and the maximum travel speed between two sign-ins of a user, with what
+-- happens when a sign-in breaks them (log, step_up or block). Tenants
+-- without a row use the deployment's BLOCKED_COUNTRIES, ALLOWED_COUNTRIES,
+-- SUSPICIOUS_COUNTRIES and GEO_* settings.
+
+CREATE TABLE IF NOT EXISTS geo_policies (
+    tenant_id BIGINT PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    blocked_countries JSONB NOT NULL DEFAULT '[]',
+    allowed_countries JSONB NOT NULL DEFAULT '[]',                    -- empty allows every country not blocked
+    suspicious_countries JSONB NOT NULL DEFAULT '[]',
+    suspicious_action VARCHAR(20) NOT NULL DEFAULT 'log',             -- log, step_up, block
+    max_travel_speed_kmh INT NOT NULL DEFAULT 0,                      -- 0 disables impossible travel checks
+    velocity_action VARCHAR(20) NOT NULL DEFAULT 'step_up',           -- log, step_up, block
+    updated_by BIGINT NULL REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
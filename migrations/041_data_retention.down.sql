@@ -0,0 +1,5 @@
+-- Rollback data retention indexes
+
+DROP INDEX IF EXISTS idx_audit_logs_created_at;
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
+DROP INDEX IF EXISTS idx_otps_expires_at;
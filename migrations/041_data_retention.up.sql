@@ -0,0 +1,12 @@
+-- =============================================================================
+-- DATA RETENTION
+-- =============================================================================
+-- The data retention job deletes one-time codes and refresh tokens some time
+-- after they expire, and audit log entries some time after they were
+-- recorded (OTP_RETENTION, REFRESH_TOKEN_RETENTION, LOGIN_EVENT_RETENTION,
+-- AUDIT_LOG_RETENTION), a batch of rows at a time. These indexes let each
+-- batch find its rows without scanning the table.
+
+CREATE INDEX IF NOT EXISTS idx_otps_expires_at ON otps(expires_at);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
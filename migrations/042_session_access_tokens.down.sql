@@ -0,0 +1,4 @@
+-- Rollback session access tokens
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS access_expires_at;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS access_token_id;
@@ -0,0 +1,10 @@
+-- =============================================================================
+-- SESSION ACCESS TOKENS
+-- =============================================================================
+-- Each session (first-party refresh token) records the ID ("jti" claim) and
+-- expiry of the last access token issued with it, so that signing out or
+-- revoking its device revokes that access token too (REVOCATION_STRATEGY=
+-- stateful). Sessions started before have none.
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS access_token_id VARCHAR(64) NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS access_expires_at TIMESTAMP WITH TIME ZONE NULL;
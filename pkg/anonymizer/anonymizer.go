@@ -0,0 +1,157 @@
+// Package anonymizer detects requests coming through anonymizing networks
+// (proxies, VPNs, Tor) and from hosting providers (datacenters, cloud
+// servers), through ip-api.com, IPQualityScore or a local list of CIDR
+// blocks. Lookups are cached per address.
+package anonymizer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Supported values for the ANONYMIZER_PROVIDER setting.
+const (
+	ProviderOff   = "off"
+	ProviderIPAPI = "ipapi"
+	ProviderIPQS  = "ipqs"
+	ProviderList  = "list"
+)
+
+// maxCacheEntries bounds the lookup cache; expired entries are dropped when
+// it is full, and the whole cache if that is not enough.
+const maxCacheEntries = 50000
+
+// Signals describes the network a request comes from.
+type Signals struct {
+	Proxy   bool `json:"is_proxy"`   // proxy, VPN or Tor exit node
+	Hosting bool `json:"is_hosting"` // hosting provider or datacenter
+}
+
+// Provider looks up the signals of a public IP address.
+type Provider interface {
+	Lookup(ctx context.Context, addr netip.Addr) (Signals, error)
+}
+
+// Config holds the settings needed to build any provider.
+type Config struct {
+	Provider string
+
+	// ip-api.com endpoint the address is appended to (IPAPI_URL)
+	IPAPIURL string
+
+	// IPQualityScore API key
+	IPQSKey string
+
+	// Local list: one "<address or CIDR block> <proxy|vpn|tor|hosting>" per line
+	ListFile string
+
+	// How long a lookup is reused
+	CacheTTL time.Duration
+}
+
+// Detector looks up signals with a provider and caches them. It is safe for
+// concurrent use.
+type Detector struct {
+	provider Provider
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[netip.Addr]cachedSignals
+}
+
+type cachedSignals struct {
+	signals Signals
+	expires time.Time
+}
+
+// New returns the Detector for cfg.Provider, or nil when detection is off.
+func New(cfg Config) (*Detector, error) {
+	httpClient := &http.Client{Timeout: 3 * time.Second}
+
+	var provider Provider
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderOff, "":
+		return nil, nil
+	case ProviderIPAPI:
+		provider = &ipAPIProvider{baseURL: cfg.IPAPIURL, httpClient: httpClient}
+	case ProviderIPQS:
+		if cfg.IPQSKey == "" {
+			return nil, fmt.Errorf("ipqs provider requires ANONYMIZER_IPQS_KEY")
+		}
+		provider = &ipqsProvider{apiKey: cfg.IPQSKey, httpClient: httpClient}
+	case ProviderList:
+		list, err := LoadList(cfg.ListFile)
+		if err != nil {
+			return nil, err
+		}
+		provider = list
+	default:
+		return nil, fmt.Errorf("unknown anonymizer provider %q", cfg.Provider)
+	}
+
+	return NewDetector(provider, cfg.CacheTTL), nil
+}
+
+// NewDetector creates a detector that caches provider's lookups for cacheTTL.
+func NewDetector(provider Provider, cacheTTL time.Duration) *Detector {
+	return &Detector{
+		provider: provider,
+		cacheTTL: cacheTTL,
+		cache:    make(map[netip.Addr]cachedSignals),
+	}
+}
+
+// Lookup returns the signals of ip. Addresses that aren't public (loopback,
+// private networks) and failed lookups have no signals; failures are logged
+// and not cached.
+func (d *Detector) Lookup(ctx context.Context, ip string) Signals {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Signals{}
+	}
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return Signals{}
+	}
+
+	d.mu.Lock()
+	cached, ok := d.cache[addr]
+	d.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.signals
+	}
+
+	signals, err := d.provider.Lookup(ctx, addr)
+	if err != nil {
+		logger.Warn("anonymizer lookup failed", "ip", ip, "error", err)
+		return Signals{}
+	}
+
+	d.mu.Lock()
+	d.store(addr, signals)
+	d.mu.Unlock()
+	return signals
+}
+
+// store caches signals for addr. d.mu must be held.
+func (d *Detector) store(addr netip.Addr, signals Signals) {
+	now := time.Now()
+	if len(d.cache) >= maxCacheEntries {
+		for cachedAddr, entry := range d.cache {
+			if now.After(entry.expires) {
+				delete(d.cache, cachedAddr)
+			}
+		}
+		if len(d.cache) >= maxCacheEntries {
+			d.cache = make(map[netip.Addr]cachedSignals)
+		}
+	}
+	d.cache[addr] = cachedSignals{signals: signals, expires: now.Add(d.cacheTTL)}
+}
@@ -0,0 +1,176 @@
+package anonymizer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// =============================================================================
+// ip-api.com
+// =============================================================================
+
+// ipAPIProvider reads the proxy and hosting flags of ip-api.com.
+type ipAPIProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func (p *ipAPIProvider) Lookup(ctx context.Context, addr netip.Addr) (Signals, error) {
+	var result struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Proxy   bool   `json:"proxy"`
+		Hosting bool   `json:"hosting"`
+	}
+	if err := getJSON(ctx, p.httpClient, p.baseURL+addr.String()+"?fields=status,message,proxy,hosting", &result); err != nil {
+		return Signals{}, fmt.Errorf("ip-api: %w", err)
+	}
+	if result.Status != "success" {
+		return Signals{}, fmt.Errorf("ip-api: %s", result.Message)
+	}
+	return Signals{Proxy: result.Proxy, Hosting: result.Hosting}, nil
+}
+
+// =============================================================================
+// IPQualityScore
+// =============================================================================
+
+// ipqsBaseURL is the IPQualityScore proxy detection endpoint; the API key and
+// address are appended.
+const ipqsBaseURL = "https://ipqualityscore.com/api/json/ip/"
+
+// ipqsProvider reads the proxy, VPN, Tor and connection type of
+// IPQualityScore.
+type ipqsProvider struct {
+	apiKey     string
+	httpClient *http.Client
+}
+
+func (p *ipqsProvider) Lookup(ctx context.Context, addr netip.Addr) (Signals, error) {
+	var result struct {
+		Success        bool   `json:"success"`
+		Message        string `json:"message"`
+		Proxy          bool   `json:"proxy"`
+		VPN            bool   `json:"vpn"`
+		Tor            bool   `json:"tor"`
+		ConnectionType string `json:"connection_type"`
+	}
+	if err := getJSON(ctx, p.httpClient, ipqsBaseURL+url.PathEscape(p.apiKey)+"/"+addr.String(), &result); err != nil {
+		return Signals{}, fmt.Errorf("ipqs: %w", err)
+	}
+	if !result.Success {
+		return Signals{}, fmt.Errorf("ipqs: %s", result.Message)
+	}
+	return Signals{
+		Proxy:   result.Proxy || result.VPN || result.Tor,
+		Hosting: result.ConnectionType == "Data Center",
+	}, nil
+}
+
+// getJSON fetches target and decodes its JSON body into v.
+func getJSON(ctx context.Context, client *http.Client, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// =============================================================================
+// Local List
+// =============================================================================
+
+// List is a provider backed by a fixed list of CIDR blocks, e.g. exported
+// Tor exit nodes and cloud provider ranges.
+type List struct {
+	proxy   []netip.Prefix
+	hosting []netip.Prefix
+}
+
+// LoadList reads a list file with one "<address or CIDR block> <category>"
+// per line, where category is proxy, vpn, tor or hosting. Blank lines and #
+// comments are skipped.
+func LoadList(path string) (*List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open anonymizer list: %w", err)
+	}
+	defer file.Close()
+
+	list := &List{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("anonymizer list line %d: expected \"<cidr> <category>\"", line)
+		}
+
+		prefix, err := parsePrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("anonymizer list line %d: %w", line, err)
+		}
+		switch strings.ToLower(fields[1]) {
+		case "proxy", "vpn", "tor":
+			list.proxy = append(list.proxy, prefix)
+		case "hosting":
+			list.hosting = append(list.hosting, prefix)
+		default:
+			return nil, fmt.Errorf("anonymizer list line %d: unknown category %q", line, fields[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read anonymizer list: %w", err)
+	}
+	return list, nil
+}
+
+// Lookup reports which of the list's categories contain addr.
+func (l *List) Lookup(_ context.Context, addr netip.Addr) (Signals, error) {
+	return Signals{
+		Proxy:   containsAddr(l.proxy, addr),
+		Hosting: containsAddr(l.hosting, addr),
+	}, nil
+}
+
+// parsePrefix parses an address or CIDR block; addresses become a
+// single-address block.
+func parsePrefix(value string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(value); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
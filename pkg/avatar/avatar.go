@@ -0,0 +1,86 @@
+// Package avatar validates uploaded profile pictures and normalizes them to a
+// square JPEG of a fixed size, so clients can upload photos straight from a
+// camera without resizing them first.
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// Registered decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ContentType is the MIME type of processed avatars.
+const ContentType = "image/jpeg"
+
+// Extension is the file extension of processed avatars.
+const Extension = ".jpg"
+
+const (
+	// maxPixels caps the decoded image size so a small, highly compressed
+	// upload cannot exhaust memory (a "decompression bomb")
+	maxPixels = 40_000_000
+
+	jpegQuality = 90
+)
+
+var (
+	// ErrTooLarge is returned when the upload exceeds the byte limit.
+	ErrTooLarge = errors.New("avatar image too large")
+
+	// ErrInvalidImage is returned when the upload is not a JPEG, PNG, GIF or
+	// WebP image, or its dimensions are out of bounds.
+	ErrInvalidImage = errors.New("invalid avatar image")
+)
+
+// Process reads an image of at most maxBytes from r, crops it to a centered
+// square, scales it to size x size pixels and encodes it as JPEG. Transparent
+// areas are filled with white.
+func Process(r io.Reader, maxBytes int64, size int) ([]byte, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(raw)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+
+	// Check the dimensions before decoding the pixel data
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrInvalidImage
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, squareCrop(src.Bounds()), draw.Over, nil)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// squareCrop returns the largest square centered in b.
+func squareCrop(b image.Rectangle) image.Rectangle {
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
@@ -0,0 +1,121 @@
+// Package breaker implements circuit breakers for calls to third parties
+// (GeoIP, email providers, Google), so an outage or a slow dependency fails
+// fast instead of adding its timeout to every request. After a number of
+// consecutive failures the breaker opens and calls are refused; once the
+// open timeout elapses a single probe call is let through (half-open), which
+// closes the breaker when it succeeds and opens it again when it fails.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// ErrOpen is returned instead of calling a dependency whose breaker is open.
+var ErrOpen = errors.New("circuit breaker open")
+
+// States of a breaker.
+const (
+	StateClosed   = "closed"    // calls go through
+	StateOpen     = "open"      // calls are refused
+	StateHalfOpen = "half_open" // a probe call is in flight
+)
+
+// Breaker guards the calls to one dependency. A nil *Breaker lets every call
+// through, so disabled breakers need no checks. It is safe for concurrent
+// use.
+type Breaker struct {
+	name        string
+	threshold   int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int       // consecutive, while closed
+	openedAt time.Time // while open; when the probe started while half-open
+}
+
+// New creates a breaker for the dependency name, opening after threshold
+// consecutive failures and probing again after openTimeout. It returns nil
+// (no breaker) when threshold is 0.
+func New(name string, threshold int, openTimeout time.Duration) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &Breaker{name: name, threshold: threshold, openTimeout: openTimeout, state: StateClosed}
+}
+
+// Allow reports whether a call may be made: nil, after which its outcome must
+// be passed to Record, or ErrOpen. When the open timeout has elapsed the
+// caller becomes the half-open probe.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateClosed {
+		return nil
+	}
+	// a probe whose outcome is never recorded is replaced after the timeout
+	if time.Since(b.openedAt) < b.openTimeout {
+		return ErrOpen
+	}
+	b.state = StateHalfOpen
+	b.openedAt = time.Now()
+	return nil
+}
+
+// Record reports the outcome of an allowed call: failed when the dependency
+// didn't answer properly (not when it rejected the request, e.g. an invalid
+// token).
+func (b *Breaker) Record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		if b.state != StateClosed {
+			logger.Info("circuit breaker closed", "dependency", b.name)
+		}
+		b.state = StateClosed
+		b.failures = 0
+		return
+	}
+
+	switch b.state {
+	case StateHalfOpen:
+		b.state = StateOpen
+		b.openedAt = time.Now()
+		logger.Warn("circuit breaker probe failed, still open", "dependency", b.name, "retryIn", b.openTimeout)
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.state = StateOpen
+			b.openedAt = time.Now()
+			logger.Warn("circuit breaker opened", "dependency", b.name, "failures", b.failures, "retryIn", b.openTimeout)
+		}
+	}
+}
+
+// Do runs call when the breaker allows it, recording whether it failed
+// according to isFailure (any error when nil). It returns ErrOpen without
+// calling when the breaker is open.
+func (b *Breaker) Do(call func() error, isFailure func(error) bool) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := call()
+	if isFailure == nil {
+		b.Record(err != nil)
+	} else {
+		b.Record(err != nil && isFailure(err))
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+// Package captcha verifies CAPTCHA tokens solved in the browser with Google
+// reCAPTCHA, hCaptcha or Cloudflare Turnstile. All three follow the same
+// "siteverify" protocol: the server posts its secret and the client's token
+// and gets back whether the challenge was solved.
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Supported values for the CAPTCHA_PROVIDER setting.
+const (
+	ProviderOff       = "off"
+	ProviderReCAPTCHA = "recaptcha"
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderTurnstile = "turnstile"
+)
+
+// Verification endpoints of each provider.
+const (
+	reCAPTCHAVerifyURL = "https://www.google.com/recaptcha/api/siteverify"
+	hCaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+	turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+var (
+	// ErrMissingToken is returned when the client sent no token.
+	ErrMissingToken = errors.New("captcha token required")
+
+	// ErrFailed is returned when the provider rejected the token, or its
+	// score is below the minimum.
+	ErrFailed = errors.New("captcha verification failed")
+)
+
+// CaptchaVerifier checks a token solved by the client at remoteIP. It
+// returns ErrMissingToken or ErrFailed when the client must solve a
+// CAPTCHA again, and other errors when the provider can't be reached.
+type CaptchaVerifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// Config holds the settings needed to build any provider.
+type Config struct {
+	Provider string
+
+	// Secret key issued by the provider for this site
+	Secret string
+
+	// Lowest reCAPTCHA v3 score accepted (0.0-1.0); ignored for tokens
+	// without a score (reCAPTCHA v2, hCaptcha, Turnstile)
+	MinScore float64
+}
+
+// NewVerifier returns the CaptchaVerifier for cfg.Provider, or nil when
+// CAPTCHAs are off.
+func NewVerifier(cfg Config) (CaptchaVerifier, error) {
+	var endpoint string
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderOff, "":
+		return nil, nil
+	case ProviderReCAPTCHA:
+		endpoint = reCAPTCHAVerifyURL
+	case ProviderHCaptcha:
+		endpoint = hCaptchaVerifyURL
+	case ProviderTurnstile:
+		endpoint = turnstileVerifyURL
+	default:
+		return nil, fmt.Errorf("unknown captcha provider %q", cfg.Provider)
+	}
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("%s captcha provider requires CAPTCHA_SECRET", cfg.Provider)
+	}
+
+	return &siteVerifier{
+		endpoint:   endpoint,
+		secret:     cfg.Secret,
+		minScore:   cfg.MinScore,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// siteVerifier implements the siteverify protocol shared by every provider.
+type siteVerifier struct {
+	endpoint   string
+	secret     string
+	minScore   float64
+	httpClient *http.Client
+}
+
+// siteVerifyResponse is the part of the siteverify response the providers
+// have in common. Score is only set by reCAPTCHA v3 (and hCaptcha
+// Enterprise).
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify posts the token to the provider.
+func (v *siteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrMissingToken
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha verification request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha verification request: unexpected status %d", resp.StatusCode)
+	}
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode captcha verification: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	if result.Score != nil && *result.Score < v.minScore {
+		return fmt.Errorf("%w: score %.2f below %.2f", ErrFailed, *result.Score, v.minScore)
+	}
+	return nil
+}
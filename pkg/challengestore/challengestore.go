@@ -0,0 +1,64 @@
+// Package challengestore tracks in Redis the challenge tokens handed out for
+// logins waiting for a second factor. A token is only accepted while its
+// entry exists, and the entry is removed when the login completes, so each
+// token can be exchanged for access tokens once.
+package challengestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "mfa_challenge:"
+
+// Store records issued challenge tokens. It is safe for concurrent use.
+// Unlike the rate limiters it fails closed: Redis errors are returned and the
+// token must be refused.
+type Store struct {
+	redis *redis.Client
+}
+
+// NewStore creates a store.
+func NewStore(redisClient *redis.Client) *Store {
+	return &Store{redis: redisClient}
+}
+
+// Save records the token id issued for challengeID in tenantID until ttl
+// elapses.
+func (s *Store) Save(ctx context.Context, tenantID int64, id, challengeID string, ttl time.Duration) error {
+	return s.redis.Set(ctx, key(tenantID, id), challengeID, ttl).Err()
+}
+
+// Active reports whether the token id was issued for challengeID and has
+// neither expired nor been used.
+func (s *Store) Active(ctx context.Context, tenantID int64, id, challengeID string) (bool, error) {
+	stored, err := s.redis.Get(ctx, key(tenantID, id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return stored == challengeID, nil
+}
+
+// Consume uses up the token id issued for challengeID. It returns false when
+// the token isn't active, e.g. because a concurrent request used it first.
+func (s *Store) Consume(ctx context.Context, tenantID int64, id, challengeID string) (bool, error) {
+	stored, err := s.redis.GetDel(ctx, key(tenantID, id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return stored == challengeID, nil
+}
+
+func key(tenantID int64, id string) string {
+	return fmt.Sprintf("%s%d:%s", keyPrefix, tenantID, id)
+}
@@ -0,0 +1,147 @@
+// Package disposable detects email addresses on throwaway ("disposable")
+// domains. A bundled list is always loaded; an optional remote list in the
+// same one-domain-per-line format can be merged in and refreshed periodically.
+package disposable
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+//go:embed domains.txt
+var bundledList string
+
+// Checker reports whether an email address uses a disposable domain. It is
+// safe for concurrent use.
+type Checker struct {
+	mu      sync.RWMutex
+	domains map[string]struct{}
+	bundled map[string]struct{}
+
+	remoteURL  string
+	httpClient *http.Client
+}
+
+// NewChecker creates a checker loaded with the bundled domain list. remoteURL
+// may be empty to disable remote refreshes.
+func NewChecker(remoteURL string) *Checker {
+	bundled := parseList(strings.NewReader(bundledList))
+	return &Checker{
+		domains:    bundled,
+		bundled:    bundled,
+		remoteURL:  remoteURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IsDisposable reports whether email's domain, or any parent domain of it,
+// is on the list.
+func (c *Checker) IsDisposable(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Check the domain and each parent (a.b.example.com, b.example.com, example.com)
+	for domain != "" {
+		if _, ok := c.domains[domain]; ok {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+// Refresh downloads the remote list and merges it with the bundled one. On
+// failure the current list is kept.
+func (c *Checker) Refresh(ctx context.Context) error {
+	if c.remoteURL == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.remoteURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch disposable domain list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch disposable domain list: unexpected status %d", resp.StatusCode)
+	}
+
+	remote := parseList(resp.Body)
+	merged := make(map[string]struct{}, len(c.bundled)+len(remote))
+	for domain := range c.bundled {
+		merged[domain] = struct{}{}
+	}
+	for domain := range remote {
+		merged[domain] = struct{}{}
+	}
+
+	c.mu.Lock()
+	c.domains = merged
+	c.mu.Unlock()
+
+	logger.Info("disposable email domain list refreshed", "domains", len(merged))
+	return nil
+}
+
+// StartRefresh refreshes the remote list immediately and then every interval
+// until ctx is cancelled. It does nothing when no remote URL is configured.
+func (c *Checker) StartRefresh(ctx context.Context, interval time.Duration) {
+	if c.remoteURL == "" || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := c.Refresh(ctx); err != nil {
+				logger.Warn("failed to refresh disposable email domains", "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// parseList reads one domain per line, skipping blanks and # comments.
+func parseList(r io.Reader) map[string]struct{} {
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[line] = struct{}{}
+	}
+	return domains
+}
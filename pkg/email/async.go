@@ -0,0 +1,90 @@
+package email
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"authentio/pkg/logger"
+)
+
+// AsyncSender delivers emails from a fixed number of background workers, so
+// request handlers don't wait on the provider. It is the in-process
+// counterpart of Queue for deployments without Redis: failed emails are
+// logged (and recorded by a TrackingSender) but not retried, and emails
+// still waiting when the process crashes are lost.
+type AsyncSender struct {
+	sender EmailSender
+	jobs   chan asyncJob
+	wg     sync.WaitGroup
+
+	mu      sync.RWMutex
+	stopped bool
+}
+
+// asyncJob is an email waiting for a worker, with the context of the caller
+// detached from its cancellation: the request usually ends first.
+type asyncJob struct {
+	ctx  context.Context
+	mail Mail
+}
+
+// NewAsyncSender starts workers goroutines delivering through sender, with
+// up to backlog emails waiting for them. Zero values fall back to 2 workers
+// and a backlog of 100.
+func NewAsyncSender(sender EmailSender, workers, backlog int) *AsyncSender {
+	if workers <= 0 {
+		workers = 2
+	}
+	if backlog <= 0 {
+		backlog = 100
+	}
+	a := &AsyncSender{sender: sender, jobs: make(chan asyncJob, backlog)}
+	for i := 0; i < workers; i++ {
+		a.wg.Add(1)
+		go a.work()
+	}
+	return a
+}
+
+// Send hands the email to a worker; there is no provider message id yet.
+// When the backlog is full, or after Stop, the email is sent synchronously
+// rather than dropped.
+func (a *AsyncSender) Send(ctx context.Context, mail Mail) (string, error) {
+	a.mu.RLock()
+	stopped := a.stopped
+	if !stopped {
+		select {
+		case a.jobs <- asyncJob{ctx: context.WithoutCancel(ctx), mail: mail}:
+			a.mu.RUnlock()
+			return "", nil
+		default:
+		}
+	}
+	a.mu.RUnlock()
+
+	if !stopped {
+		logger.Warn("email backlog full, sending synchronously", "jobID", mail.ID)
+	}
+	return a.sender.Send(ctx, mail)
+}
+
+// Stop waits for the workers to send the emails waiting in the backlog.
+func (a *AsyncSender) Stop() {
+	a.mu.Lock()
+	if !a.stopped {
+		a.stopped = true
+		close(a.jobs)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+}
+
+func (a *AsyncSender) work() {
+	defer a.wg.Done()
+	for job := range a.jobs {
+		if _, err := a.sender.Send(job.ctx, job.mail); err != nil {
+			logger.Error("email delivery failed", "error", err, "jobID", job.mail.ID, "to", strings.Join(job.mail.To, ","))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package email
+
+import (
+	"context"
+
+	"authentio/pkg/logger"
+)
+
+// Colors used when a branding doesn't set its own.
+const (
+	defaultPrimaryColor    = "#2563eb"
+	defaultBackgroundColor = "#ffffff"
+)
+
+// Branding customizes the emails of a tenant. Empty fields keep the
+// defaults. Templates read it as {{brand}}, with the default colors filled
+// in, e.g. {{(brand).PrimaryColor}}.
+type Branding struct {
+	FromName        string // sender name, also shown as {{appName}}
+	FromEmail       string // sender address
+	LogoURL         string // image shown in the header instead of the name
+	PrimaryColor    string // CSS color of the header
+	BackgroundColor string // CSS color behind the email
+	FooterText      string // line added to the footer
+}
+
+// BrandingStore supplies the branding of each tenant.
+type BrandingStore interface {
+	// EmailBranding returns the branding of the tenant in ctx, or nil to
+	// use the defaults.
+	EmailBranding(ctx context.Context) (*Branding, error)
+}
+
+// SetBrandingStore makes the renderer brand emails with the settings in
+// store. Call it once, before rendering.
+func (r *EmailRenderer) SetBrandingStore(store BrandingStore) {
+	r.brands = store
+}
+
+// templateSet returns the templates for the branding of the tenant in ctx,
+// parsing them on first use, along with that branding. Lookup or parse
+// failures fall back to the unbranded templates, so branding never stops an
+// email.
+func (r *EmailRenderer) templateSet(ctx context.Context) (*templateSet, Branding) {
+	if r.brands == nil {
+		return r.standard, Branding{}
+	}
+	stored, err := r.brands.EmailBranding(ctx)
+	if err != nil {
+		logger.Warn("failed to load email branding, using the default", "error", err)
+		return r.standard, Branding{}
+	}
+	if stored == nil || *stored == (Branding{}) {
+		return r.standard, Branding{}
+	}
+	b := *stored
+
+	// The sender address isn't shown in templates, so tenants that only
+	// change it share the parsed set
+	key := b
+	key.FromEmail = ""
+	if key == (Branding{}) {
+		return r.standard, b
+	}
+	if cached, ok := r.branded.Load(key); ok {
+		return cached.(*templateSet), b
+	}
+	set, err := r.parseSet(key)
+	if err != nil {
+		logger.Warn("failed to parse branded email templates, using the default", "error", err)
+		return r.standard, Branding{}
+	}
+	cached, _ := r.branded.LoadOrStore(key, set)
+	return cached.(*templateSet), b
+}
+
+// withDefaults returns b with the default colors filled in.
+func (b Branding) withDefaults() Branding {
+	if b.PrimaryColor == "" {
+		b.PrimaryColor = defaultPrimaryColor
+	}
+	if b.BackgroundColor == "" {
+		b.BackgroundColor = defaultBackgroundColor
+	}
+	return b
+}
+
+// sign sets the sender of msg to the branding's and returns msg.
+func (b Branding) sign(msg *Message) *Message {
+	msg.FromName, msg.FromEmail = b.FromName, b.FromEmail
+	return msg
+}
@@ -0,0 +1,61 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+
+	"authentio/pkg/breaker"
+)
+
+// CircuitSender sends emails through a provider guarded by a circuit
+// breaker: while the provider is failing, emails are refused at once
+// (and retried by the queue) instead of each waiting for its timeout.
+type CircuitSender struct {
+	sender  EmailSender
+	breaker *breaker.Breaker
+}
+
+// NewCircuitSender wraps sender with b (a nil b never refuses).
+func NewCircuitSender(sender EmailSender, b *breaker.Breaker) *CircuitSender {
+	return &CircuitSender{sender: sender, breaker: b}
+}
+
+// Send sends mail unless the breaker is open.
+func (c *CircuitSender) Send(ctx context.Context, mail Mail) (string, error) {
+	var messageID string
+	err := c.breaker.Do(func() error {
+		var err error
+		messageID, err = c.sender.Send(ctx, mail)
+		return err
+	}, providerFailure)
+	if errors.Is(err, breaker.ErrOpen) {
+		return "", fmt.Errorf("email provider unavailable: %w", err)
+	}
+	return messageID, err
+}
+
+// providerFailure reports whether a send error means the provider is
+// unavailable, rather than that it refused this email (e.g. an unknown
+// recipient), which says nothing about the next one.
+func providerFailure(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		// temporary failures, and authentication failing for every email
+		return reply.Code < 500 || reply.Code == 530 || reply.Code == 535
+	}
+	var refused *SendGridError
+	if errors.As(err, &refused) {
+		return refused.StatusCode >= http.StatusInternalServerError ||
+			refused.StatusCode == http.StatusTooManyRequests ||
+			refused.StatusCode == http.StatusUnauthorized ||
+			refused.StatusCode == http.StatusForbidden
+	}
+	// the caller giving up says nothing about the provider
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	return true
+}
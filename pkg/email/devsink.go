@@ -0,0 +1,96 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Development sinks deliver emails locally, so the OTP and password reset
+// flows can be followed end to end offline: to a mail catcher such as
+// MailHog or Mailpit, whose web UI shows what was sent, or to a maildir that
+// mail clients read.
+
+// SMTPSink delivers emails over plain SMTP, without TLS or authentication, to
+// a local mail catcher.
+type SMTPSink struct {
+	addr string
+	from string
+}
+
+// NewSMTPSink creates a sink delivering to the catcher listening on addr
+// (host:port), emails from the from address.
+func NewSMTPSink(addr, from string) *SMTPSink {
+	return &SMTPSink{addr: addr, from: from}
+}
+
+// Send delivers the email to the catcher.
+func (s *SMTPSink) Send(ctx context.Context, mail Mail) (string, error) {
+	if len(mail.To) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := smtp.SendMail(s.addr, nil, s.from, mail.To, formatEML(mail, s.from, time.Now())); err != nil {
+		return "", fmt.Errorf("deliver to %s: %w", s.addr, err)
+	}
+	return "", nil
+}
+
+// maildirDeliveries numbers the emails delivered by this process, for unique
+// maildir file names.
+var maildirDeliveries atomic.Uint64
+
+// MaildirSender delivers emails to a maildir: each one is written to tmp/
+// and then moved to new/, so readers never see partial files.
+type MaildirSender struct {
+	dir      string
+	from     string
+	hostname string
+}
+
+// NewMaildirSender creates a sender delivering to the maildir at dir,
+// creating it if needed, emails from the from address.
+func NewMaildirSender(dir, from string) (*MaildirSender, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, fmt.Errorf("create maildir: %w", err)
+		}
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	// "/" and ":" have a meaning in maildir file names
+	hostname = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(hostname)
+	return &MaildirSender{dir: dir, from: from, hostname: hostname}, nil
+}
+
+// Send delivers the email to new/.
+func (s *MaildirSender) Send(_ context.Context, mail Mail) (string, error) {
+	if len(mail.To) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), maildirDeliveries.Add(1), s.hostname)
+	tmp := filepath.Join(s.dir, "tmp", name)
+	if err := os.WriteFile(tmp, formatEML(mail, s.from, now), 0o640); err != nil {
+		return "", fmt.Errorf("write email: %w", err)
+	}
+	path := filepath.Join(s.dir, "new", name)
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("deliver email: %w", err)
+	}
+	logger.Info("email (maildir provider)", "id", mail.ID, "to", strings.Join(mail.To, ","), "subject", mail.Subject, "path", path)
+	return "", nil
+}
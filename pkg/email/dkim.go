@@ -0,0 +1,191 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dkimHeaders are the headers signed when the message has them.
+var dkimHeaders = []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type"}
+
+// DKIMSigner adds a DKIM signature (RFC 6376: rsa-sha256, relaxed/relaxed
+// canonicalization) to messages, so receivers can check they come from the
+// domain, whose DNS publishes the public key under the selector.
+type DKIMSigner struct {
+	Domain   string
+	Selector string
+	key      *rsa.PrivateKey
+}
+
+// LoadDKIMSigner reads the PEM-encoded RSA private key (PKCS#1 or PKCS#8)
+// at path, to sign for domain with selector.
+func LoadDKIMSigner(domain, selector, path string) (*DKIMSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		var ok bool
+		if key, ok = parsed.(*rsa.PrivateKey); !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+	}
+	if key.N.BitLen() < 1024 {
+		return nil, fmt.Errorf("DKIM key is %d bits, at least 1024 are needed", key.N.BitLen())
+	}
+	return &DKIMSigner{Domain: domain, Selector: selector, key: key}, nil
+}
+
+// DNSRecord returns the name and value of the TXT record publishing the
+// public key.
+func (s *DKIMSigner) DNSRecord() (name, value string) {
+	der, _ := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	return s.Selector + "._domainkey." + s.Domain, "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)
+}
+
+// Sign returns msg, a CRLF-separated RFC 5322 message, with a
+// DKIM-Signature header prepended.
+func (s *DKIMSigner) Sign(msg []byte) ([]byte, error) {
+	end := bytes.Index(msg, []byte("\r\n\r\n"))
+	if end < 0 {
+		return nil, errors.New("message has no body")
+	}
+	fields := splitHeaderFields(string(msg[:end+2]))
+	body := msg[end+4:]
+
+	bodyHash := sha256.Sum256(relaxedBody(body))
+
+	var signed []string
+	var h bytes.Buffer
+	for _, name := range dkimHeaders {
+		field, ok := lastHeaderField(fields, name)
+		if !ok {
+			continue
+		}
+		signed = append(signed, strings.ToLower(name))
+		h.WriteString(relaxedHeader(field))
+		h.WriteString("\r\n")
+	}
+	if len(signed) == 0 || signed[0] != "from" {
+		return nil, errors.New("message has no From header")
+	}
+
+	header := "DKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=" + s.Domain +
+		"; s=" + s.Selector +
+		"; t=" + strconv.FormatInt(time.Now().Unix(), 10) +
+		"; h=" + strings.Join(signed, ":") +
+		"; bh=" + base64.StdEncoding.EncodeToString(bodyHash[:]) +
+		";\r\n b="
+	h.WriteString(relaxedHeader(header))
+
+	digest := sha256.Sum256(h.Bytes())
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("dkim sign: %w", err)
+	}
+
+	out := make([]byte, 0, len(header)+344+2+len(msg))
+	out = append(out, header...)
+	out = append(out, base64.StdEncoding.EncodeToString(signature)...)
+	out = append(out, "\r\n"...)
+	return append(out, msg...), nil
+}
+
+// splitHeaderFields returns the header fields of a header section, with
+// their folded lines.
+func splitHeaderFields(section string) []string {
+	var fields []string
+	for _, line := range strings.SplitAfter(section, "\r\n") {
+		if line == "" {
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1] += line
+			continue
+		}
+		fields = append(fields, line)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSuffix(fields[i], "\r\n")
+	}
+	return fields
+}
+
+// lastHeaderField returns the last field named name (case-insensitively),
+// the one receivers check first.
+func lastHeaderField(fields []string, name string) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if colon := strings.IndexByte(fields[i], ':'); colon > 0 && strings.EqualFold(strings.TrimRight(fields[i][:colon], " \t"), name) {
+			return fields[i], true
+		}
+	}
+	return "", false
+}
+
+// relaxedHeader canonicalizes a header field (RFC 6376 3.4.2), without the
+// trailing CRLF.
+func relaxedHeader(field string) string {
+	colon := strings.IndexByte(field, ':')
+	name := strings.ToLower(strings.TrimRight(field[:colon], " \t"))
+	value := strings.NewReplacer("\r\n", "").Replace(field[colon+1:])
+	return name + ":" + strings.Join(strings.Fields(value), " ")
+}
+
+// relaxedBody canonicalizes a body (RFC 6376 3.4.4). Lines may end with LF
+// alone, which SMTP sends as CRLF.
+func relaxedBody(body []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(collapseWSP(line), " ")
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+// collapseWSP replaces each run of spaces and tabs with a single space.
+func collapseWSP(line string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range line {
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
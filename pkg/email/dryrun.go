@@ -0,0 +1,153 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Dry-run providers render emails but never send them, so staging and test
+// environments run without SMTP or SendGrid credentials.
+
+// FileSender writes each email to a file of a directory, in the .eml format
+// mail clients open.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+// NewFileSender creates a sender writing to dir, creating it if needed,
+// emails from the from address.
+func NewFileSender(dir, from string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create email outbox: %w", err)
+	}
+	return &FileSender{dir: dir, from: from}, nil
+}
+
+// Send writes the email to a file named after the time and its ID.
+func (s *FileSender) Send(_ context.Context, mail Mail) (string, error) {
+	if len(mail.To) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+
+	now := time.Now().UTC()
+	name := now.Format("20060102T150405.000000000")
+	if mail.ID != "" {
+		name += "-" + mail.ID
+	}
+	path := filepath.Join(s.dir, name+".eml")
+
+	if err := os.WriteFile(path, formatEML(mail, s.from, now), 0o640); err != nil {
+		return "", fmt.Errorf("write email: %w", err)
+	}
+	logger.Info("email (file provider)", "id", mail.ID, "to", strings.Join(mail.To, ","), "subject", mail.Subject, "path", path)
+	return "", nil
+}
+
+// formatEML returns mail from the from address as an RFC 5322 message, for
+// mail clients to open.
+func formatEML(mail Mail, from string, date time.Time) []byte {
+	var b strings.Builder
+	if from != "" || mail.FromEmail != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", fromHeader(mail, from))
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(mail.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mail.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	if mail.ID != "" {
+		fmt.Fprintf(&b, "X-Authentio-Message-Id: %s\r\n", mail.ID)
+	}
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\n\r\n")
+	b.WriteString(mail.Body)
+	return []byte(b.String())
+}
+
+// InboxMessage is an email kept by an Inbox.
+type InboxMessage struct {
+	ID        string    `json:"id"`
+	To        []string  `json:"to"`
+	Subject   string    `json:"subject"`
+	HTML      string    `json:"html"`
+	FromName  string    `json:"from_name,omitempty"`
+	FromEmail string    `json:"from_email,omitempty"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+// Inbox keeps the last emails in memory instead of sending them, for
+// development and tests to read back. It is safe for concurrent use.
+type Inbox struct {
+	mu       sync.Mutex
+	messages []InboxMessage // oldest first
+	size     int
+}
+
+// NewInbox creates an inbox keeping the last size emails.
+func NewInbox(size int) *Inbox {
+	if size <= 0 {
+		size = 1
+	}
+	return &Inbox{size: size}
+}
+
+// Send keeps the email, dropping the oldest one when the inbox is full.
+func (in *Inbox) Send(_ context.Context, mail Mail) (string, error) {
+	if len(mail.To) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.messages = append(in.messages, InboxMessage{
+		ID:        mail.ID,
+		To:        append([]string(nil), mail.To...),
+		Subject:   mail.Subject,
+		HTML:      mail.Body,
+		FromName:  mail.FromName,
+		FromEmail: mail.FromEmail,
+		SentAt:    time.Now(),
+	})
+	if len(in.messages) > in.size {
+		in.messages = append([]InboxMessage(nil), in.messages[len(in.messages)-in.size:]...)
+	}
+	return "", nil
+}
+
+// Messages returns up to limit of the kept emails sent to recipient (any
+// when empty, case-insensitively), newest first.
+func (in *Inbox) Messages(recipient string, limit int) []InboxMessage {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	messages := []InboxMessage{}
+	for i := len(in.messages) - 1; i >= 0 && len(messages) < limit; i-- {
+		if recipient == "" || hasRecipient(in.messages[i].To, recipient) {
+			messages = append(messages, in.messages[i])
+		}
+	}
+	return messages
+}
+
+// Clear forgets every kept email and returns how many there were.
+func (in *Inbox) Clear() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	n := len(in.messages)
+	in.messages = nil
+	return n
+}
+
+func hasRecipient(to []string, recipient string) bool {
+	for _, address := range to {
+		if strings.EqualFold(address, recipient) {
+			return true
+		}
+	}
+	return false
+}
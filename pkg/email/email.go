@@ -1,12 +1,15 @@
 package email
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net"
+	netmail "net/mail"
 	"net/smtp"
 	"strconv"
 	"strings"
+	"time"
 
 	"authentio/pkg/logger"
 )
@@ -17,7 +20,10 @@ type Client struct {
 	Port     int
 	Username string
 	Password string
-	From     string // optional From address; if empty Username will be used
+	From     string      // optional From address; if empty Username will be used
+	DKIM     *DKIMSigner // optional; signs every message when set
+
+	pool *smtpPool // keep-alive sessions, when enabled with EnablePool
 }
 
 // NewClient constructs a new email client.
@@ -32,9 +38,16 @@ func NewClient(host string, port int, username, password, from string) *Client {
 }
 
 // Send sends an email to one or more recipients. The body may contain HTML.
-func (c *Client) Send(to []string, subject, body string) error {
+// The email's ID becomes its Message-ID header, returned as the provider's
+// message id. Pooled sessions are bounded by ctx; a new connection only
+// checks it before dialing.
+func (c *Client) Send(ctx context.Context, mail Mail) (string, error) {
+	to, subject, body := mail.To, mail.Subject, mail.Body
 	if len(to) == 0 {
-		return fmt.Errorf("no recipients specified")
+		return "", fmt.Errorf("no recipients specified")
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
 	}
 
 	from := c.From
@@ -42,13 +55,22 @@ func (c *Client) Send(to []string, subject, body string) error {
 		from = c.Username
 	}
 
+	var messageID string
+	if mail.ID != "" {
+		messageID = "<" + mail.ID + "@" + messageIDDomain(from, c.Host) + ">"
+	}
+
 	// Build message with basic MIME headers (HTML)
 	headers := make(map[string]string)
-	headers["From"] = from
+	headers["From"] = fromHeader(mail, from)
 	headers["To"] = strings.Join(to, ",")
 	headers["Subject"] = subject
+	headers["Date"] = time.Now().Format(time.RFC1123Z)
 	headers["MIME-Version"] = "1.0"
 	headers["Content-Type"] = "text/html; charset=\"utf-8\""
+	if messageID != "" {
+		headers["Message-ID"] = messageID
+	}
 
 	var msg strings.Builder
 	for k, v := range headers {
@@ -57,21 +79,64 @@ func (c *Client) Send(to []string, subject, body string) error {
 	msg.WriteString("\r\n")
 	msg.WriteString(body)
 
+	raw := []byte(msg.String())
+	if c.DKIM != nil {
+		signed, err := c.DKIM.Sign(raw)
+		if err != nil {
+			return "", err
+		}
+		raw = signed
+	}
+
+	if c.pool != nil {
+		if err := c.pool.send(ctx, from, to, raw); err != nil {
+			return "", err
+		}
+		return messageID, nil
+	}
+
 	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
 
 	auth := smtp.PlainAuth("", c.Username, c.Password, c.Host)
 
 	// Use direct TLS for port 465, otherwise try SendMail which will typically use STARTTLS on 587
 	if c.Port == 465 {
-		return c.sendUsingTLS(addr, auth, from, to, []byte(msg.String()))
+		if err := c.sendUsingTLS(addr, auth, from, to, raw); err != nil {
+			return "", err
+		}
+		return messageID, nil
 	}
 
 	// Try standard SendMail (works for servers advertising STARTTLS)
-	if err := smtp.SendMail(addr, auth, from, to, []byte(msg.String())); err != nil {
+	if err := smtp.SendMail(addr, auth, from, to, raw); err != nil {
 		logger.Warn("smtp.SendMail failed, falling back to direct TLS", "error", err)
-		return c.sendUsingTLS(addr, auth, from, to, []byte(msg.String()))
+		if err := c.sendUsingTLS(addr, auth, from, to, raw); err != nil {
+			return "", err
+		}
 	}
-	return nil
+	return messageID, nil
+}
+
+// fromHeader returns the From header of mail: its own sender when it has
+// one, otherwise the client's address. The envelope sender stays the
+// client's, the account the server authenticates.
+func fromHeader(mail Mail, from string) string {
+	if mail.FromEmail != "" {
+		from = mail.FromEmail
+	}
+	if mail.FromName == "" {
+		return from
+	}
+	return (&netmail.Address{Name: mail.FromName, Address: from}).String()
+}
+
+// messageIDDomain returns the domain of the From address, or the SMTP host
+// when it has none, for the right-hand side of Message-ID headers.
+func messageIDDomain(from, host string) string {
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		return strings.Trim(from[at+1:], "> ")
+	}
+	return host
 }
 
 // sendUsingTLS connects to the SMTP server over TLS and sends the message.
@@ -130,17 +195,3 @@ func (c *Client) sendUsingTLS(addr string, auth smtp.Auth, from string, to []str
 	}
 	return nil
 }
-
-// SendOTP is a convenience helper that formats and sends an OTP email.
-func (c *Client) SendOTP(to string, code string) error {
-	subject := "Your verification code"
-	body := fmt.Sprintf(`<p>Your verification code is <strong>%s</strong>. It will expire in 10 minutes.</p>`, code)
-	return c.Send([]string{to}, subject, body)
-}
-
-// SendPasswordReset sends a password reset email with a provided code or link.
-func (c *Client) SendPasswordReset(to string, codeOrLink string) error {
-	subject := "Password reset request"
-	body := fmt.Sprintf(`<p>We received a request to reset your password. Use the code below or click the link:</p><p><strong>%s</strong></p>`, codeOrLink)
-	return c.Send([]string{to}, subject, body)
-}
@@ -0,0 +1,122 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// probeTimeout bounds a connectivity probe when the context has no deadline.
+const probeTimeout = 10 * time.Second
+
+// Prober is implemented by senders that can check they reach their provider
+// without sending an email, for readiness checks.
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+// Probe connects to the SMTP server as Send does (TLS on port 465, STARTTLS
+// when offered), authenticates when credentials are set, and checks the
+// session with NOOP.
+func (c *Client) Probe(ctx context.Context) error {
+	client, _, err := c.session(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return closeProbe(client)
+}
+
+// session opens an SMTP session ready to send: over TLS on port 465,
+// upgraded with STARTTLS when the server offers it otherwise, and
+// authenticated when credentials are set. It also returns the connection,
+// for deadlines.
+func (c *Client) session(ctx context.Context) (*smtp.Client, net.Conn, error) {
+	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	var tlsConfig *tls.Config
+	if c.Port == 465 {
+		tlsConfig = &tls.Config{ServerName: c.Host}
+	}
+	client, conn, err := dialSMTP(ctx, addr, c.Host, tlsConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+				client.Close()
+				return nil, nil, fmt.Errorf("starttls: %w", err)
+			}
+		}
+	}
+	if c.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+				client.Close()
+				return nil, nil, fmt.Errorf("auth failed: %w", err)
+			}
+		}
+	}
+	return client, conn, nil
+}
+
+// Probe checks the mail catcher accepts SMTP sessions.
+func (s *SMTPSink) Probe(ctx context.Context) error {
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return err
+	}
+	client, _, err := dialSMTP(ctx, s.addr, host, nil)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return closeProbe(client)
+}
+
+// dialSMTP opens an SMTP session with the server at addr, over TLS when
+// tlsConfig is set, bounded by ctx's deadline or probeTimeout. It also
+// returns the TCP connection, for deadlines.
+func dialSMTP(ctx context.Context, addr, host string, tlsConfig *tls.Config) (*smtp.Client, net.Conn, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(probeTimeout)
+	}
+
+	dialer := &net.Dialer{Deadline: deadline}
+	raw, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial: %w", err)
+	}
+	if err := raw.SetDeadline(deadline); err != nil {
+		raw.Close()
+		return nil, nil, err
+	}
+	conn := raw
+	if tlsConfig != nil {
+		conn = tls.Client(raw, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("new smtp client: %w", err)
+	}
+	return client, raw, nil
+}
+
+// closeProbe checks the session with NOOP and ends it.
+func closeProbe(client *smtp.Client) error {
+	if err := client.Noop(); err != nil {
+		return fmt.Errorf("noop failed: %w", err)
+	}
+	if err := client.Quit(); err != nil {
+		return fmt.Errorf("quit failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"authentio/pkg/lock"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys used by the queue.
+const (
+	queueKeyPending    = "email:queue"      // list of jobs waiting for a worker
+	queueKeyProcessing = "email:processing" // list of jobs a worker is sending
+	queueKeyRetry      = "email:retry"      // sorted set of jobs scored by next attempt (unix ms)
+	queueKeyDead       = "email:dead"       // hash of job id -> job that exhausted its attempts
+)
+
+// retryLockTTL bounds how long the retry scheduler of a server that died
+// keeps the others from taking over.
+const retryLockTTL = 15 * time.Second
+
+// ErrJobNotFound is returned when a dead-letter job id does not exist.
+var ErrJobNotFound = errors.New("email job not found")
+
+// QueueConfig controls worker concurrency and the retry policy.
+type QueueConfig struct {
+	Workers     int           // concurrent sending goroutines
+	MaxAttempts int           // attempts before a job is moved to the dead-letter list
+	BaseBackoff time.Duration // delay before the first retry; doubled on each attempt
+	MaxBackoff  time.Duration // upper bound for the retry delay
+}
+
+// Job is a queued email.
+type Job struct {
+	ID        string     `json:"id"`
+	To        []string   `json:"to"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body,omitempty"`
+	FromName  string     `json:"from_name,omitempty"`
+	FromEmail string     `json:"from_email,omitempty"`
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	FailedAt  *time.Time `json:"failed_at,omitempty"`
+}
+
+// QueueStats reports the number of jobs in each state.
+type QueueStats struct {
+	Pending    int64 `json:"pending"`
+	Processing int64 `json:"processing"`
+	Retrying   int64 `json:"retrying"`
+	Dead       int64 `json:"dead"`
+}
+
+// Queue is a Redis-backed EmailSender. Send only enqueues the email; worker
+// goroutines deliver it through the wrapped sender, retrying failures with
+// exponential backoff and moving jobs that exhaust MaxAttempts to a
+// dead-letter hash where an admin can inspect, retry or delete them.
+type Queue struct {
+	redis  *redis.Client
+	sender EmailSender
+	cfg    QueueConfig
+	locks  *lock.Locker // one server at a time scans the retry set
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a queue delivering through sender. Zero config values
+// fall back to 2 workers, 5 attempts and a 30s..1h backoff.
+func NewQueue(redisClient *redis.Client, sender EmailSender, cfg QueueConfig) *Queue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 30 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	return &Queue{redis: redisClient, sender: sender, cfg: cfg, locks: lock.NewLocker(redisClient)}
+}
+
+// Send enqueues an email for asynchronous delivery. The job takes the
+// email's ID, so dead letters match the delivery log; there is no provider
+// message id yet.
+func (q *Queue) Send(ctx context.Context, mail Mail) (string, error) {
+	if len(mail.To) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+
+	job := Job{
+		ID:        mail.ID,
+		To:        mail.To,
+		Subject:   mail.Subject,
+		Body:      mail.Body,
+		FromName:  mail.FromName,
+		FromEmail: mail.FromEmail,
+		CreatedAt: time.Now(),
+	}
+	if job.ID == "" {
+		job.ID = NewMessageID()
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+
+	if err := q.redis.LPush(ctx, queueKeyPending, payload).Err(); err != nil {
+		return "", fmt.Errorf("enqueue email: %w", err)
+	}
+	return "", nil
+}
+
+// ============================================================================
+// Workers
+// ============================================================================
+
+// Start launches the workers and the retry scheduler; they stop when ctx is
+// cancelled. Jobs left in the processing list by a previous run that stopped
+// mid-send are requeued first, so they are delivered at least once.
+func (q *Queue) Start(ctx context.Context) {
+	for {
+		err := q.redis.LMove(ctx, queueKeyProcessing, queueKeyPending, "RIGHT", "RIGHT").Err()
+		if err != nil {
+			if err != redis.Nil {
+				logger.Error("failed to requeue in-flight emails", "error", err)
+			}
+			break
+		}
+	}
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+
+	q.wg.Add(1)
+	go q.scheduleRetries(ctx)
+
+	logger.Info("email queue started", "workers", q.cfg.Workers, "maxAttempts", q.cfg.MaxAttempts)
+}
+
+// Wait blocks until all goroutines started by Start have returned.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// work takes jobs off the pending list and delivers them until ctx is done.
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+
+	for ctx.Err() == nil {
+		raw, err := q.redis.BLMove(ctx, queueKeyPending, queueKeyProcessing, "RIGHT", "LEFT", 5*time.Second).Result()
+		if err == redis.Nil || ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			logger.Error("email queue read failed", "error", err)
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		q.process(raw)
+	}
+}
+
+// process delivers one job and moves it to its next state. It deliberately
+// uses a fresh context so bookkeeping completes even during shutdown.
+func (q *Queue) process(raw string) {
+	ctx := context.Background()
+	defer q.redis.LRem(ctx, queueKeyProcessing, 1, raw)
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		logger.Error("dropping malformed email job", "error", err)
+		return
+	}
+
+	job.Attempts++
+	_, err := q.sender.Send(ctx, Mail{ID: job.ID, To: job.To, Subject: job.Subject, Body: job.Body, FromName: job.FromName, FromEmail: job.FromEmail})
+	if err == nil {
+		logger.Debug("email delivered", "jobID", job.ID, "attempts", job.Attempts)
+		return
+	}
+	job.LastError = err.Error()
+
+	if job.Attempts >= q.cfg.MaxAttempts {
+		now := time.Now()
+		job.FailedAt = &now
+		payload, _ := json.Marshal(job)
+		if err := q.redis.HSet(ctx, queueKeyDead, job.ID, payload).Err(); err != nil {
+			logger.Error("failed to dead-letter email", "error", err, "jobID", job.ID)
+			return
+		}
+		logger.Error("email delivery failed permanently", "error", job.LastError, "jobID", job.ID, "attempts", job.Attempts)
+		return
+	}
+
+	delay := q.backoff(job.Attempts)
+	payload, _ := json.Marshal(job)
+	if err := q.redis.ZAdd(ctx, queueKeyRetry, redis.Z{
+		Score:  float64(time.Now().Add(delay).UnixMilli()),
+		Member: payload,
+	}).Err(); err != nil {
+		logger.Error("failed to schedule email retry", "error", err, "jobID", job.ID)
+		return
+	}
+	logger.Warn("email delivery failed, will retry", "error", job.LastError, "jobID", job.ID, "attempts", job.Attempts, "retryIn", delay)
+}
+
+// scheduleRetries moves retry jobs whose backoff has elapsed back to the
+// pending list every second, on the server holding the retry lock.
+func (q *Queue) scheduleRetries(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := q.locks.Do(ctx, "email-retries", retryLockTTL, q.requeueDue); err != nil && ctx.Err() == nil {
+			logger.Error("failed to acquire the email retry lock", "error", err)
+		}
+	}
+}
+
+// requeueDue moves the retry jobs whose backoff has elapsed to the pending
+// list. ZRem acts as a claim, so a job is requeued once even if the lock was
+// lost meanwhile.
+func (q *Queue) requeueDue(ctx context.Context) {
+	due, err := q.redis.ZRangeByScore(ctx, queueKeyRetry, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprint(time.Now().UnixMilli()),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("email retry scan failed", "error", err)
+		}
+		return
+	}
+
+	for _, raw := range due {
+		if removed, err := q.redis.ZRem(ctx, queueKeyRetry, raw).Result(); err != nil || removed == 0 {
+			continue
+		}
+		if err := q.redis.LPush(ctx, queueKeyPending, raw).Err(); err != nil {
+			logger.Error("failed to requeue email retry", "error", err)
+		}
+	}
+}
+
+// backoff returns the delay before retrying a job that has failed attempts times.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < q.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.cfg.MaxBackoff {
+		delay = q.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// ============================================================================
+// Inspection and Dead-Letter Management
+// ============================================================================
+
+// Stats returns the number of jobs in each state.
+func (q *Queue) Stats(ctx context.Context) (*QueueStats, error) {
+	pipe := q.redis.Pipeline()
+	pending := pipe.LLen(ctx, queueKeyPending)
+	processing := pipe.LLen(ctx, queueKeyProcessing)
+	retrying := pipe.ZCard(ctx, queueKeyRetry)
+	dead := pipe.HLen(ctx, queueKeyDead)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return &QueueStats{
+		Pending:    pending.Val(),
+		Processing: processing.Val(),
+		Retrying:   retrying.Val(),
+		Dead:       dead.Val(),
+	}, nil
+}
+
+// DeadLetters returns up to limit dead-lettered jobs, most recent failure
+// first. Bodies are omitted since they can contain one-time codes.
+func (q *Queue) DeadLetters(ctx context.Context, limit int) ([]Job, error) {
+	entries, err := q.redis.HGetAll(ctx, queueKeyDead).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0, len(entries))
+	for _, raw := range entries {
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			continue
+		}
+		job.Body = ""
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return failedAt(jobs[i]).After(failedAt(jobs[j]))
+	})
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs, nil
+}
+
+// RetryDeadLetter moves a dead-lettered job back to the pending list with a
+// fresh attempt budget.
+func (q *Queue) RetryDeadLetter(ctx context.Context, id string) error {
+	raw, err := q.redis.HGet(ctx, queueKeyDead, id).Result()
+	if err == redis.Nil {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return err
+	}
+	job.Attempts = 0
+	job.FailedAt = nil
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HDel(ctx, queueKeyDead, id)
+		pipe.LPush(ctx, queueKeyPending, payload)
+		return nil
+	})
+	return err
+}
+
+// DeleteDeadLetter discards a dead-lettered job.
+func (q *Queue) DeleteDeadLetter(ctx context.Context, id string) error {
+	removed, err := q.redis.HDel(ctx, queueKeyDead, id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+// NewMessageID returns a random 16-byte hex email id, also used as the id of
+// its queue job.
+func NewMessageID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // Should never happen with proper system entropy
+	}
+	return hex.EncodeToString(b)
+}
+
+// failedAt returns the job's failure time, or the zero time when unset.
+func failedAt(job Job) time.Time {
+	if job.FailedAt == nil {
+		return time.Time{}
+	}
+	return *job.FailedAt
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
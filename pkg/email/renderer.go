@@ -0,0 +1,473 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Templates live in templates/: layout.html wraps every email, partials/*.html
+// holds shared blocks (header, footer, code box) and each <name>.html page
+// defines a "subject" and a "content" template.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// Template names, one per page file in templates/.
+const (
+	templateWelcome         = "welcome"
+	templateOnboardingTips  = "onboarding_tips"
+	templateOnboardingSec   = "onboarding_security"
+	templateOTP             = "otp"
+	templatePasswordReset   = "password_reset"
+	templatePasswordChanged = "password_changed"
+	templateInvitation      = "invitation"
+	templateLoginAlert      = "login_alert"
+	templateLoginApproval   = "login_quarantine"
+	templateRecovery        = "two_factor_recovery"
+	templateNewLocation     = "new_location_login"
+	templateTwoFADisabled   = "two_factor_disabled"
+	templatePasswordExpiry  = "password_expiry_reminder"
+	templatePasswordRotate  = "password_rotation_reminder"
+
+	templateRegistrationPending  = "registration_pending"
+	templateRegistrationApproved = "registration_approved"
+	templateRegistrationRejected = "registration_rejected"
+
+	templateOrganizationInvitation = "organization_invitation"
+
+	templateEmailTest = "email_test"
+)
+
+// templateNames lists every page template.
+var templateNames = []string{templateWelcome, templateOnboardingTips, templateOnboardingSec, templateOTP, templatePasswordReset, templatePasswordChanged, templateInvitation, templateLoginAlert, templateLoginApproval, templateRecovery,
+	templateNewLocation, templateTwoFADisabled, templatePasswordExpiry, templatePasswordRotate,
+	templateRegistrationPending, templateRegistrationApproved, templateRegistrationRejected,
+	templateOrganizationInvitation, templateEmailTest}
+
+// Message is a rendered email ready to be sent.
+type Message struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+
+	// Sender of the tenant's branding; empty to use the configured one
+	FromName  string `json:"from_name,omitempty"`
+	FromEmail string `json:"from_email,omitempty"`
+}
+
+// welcomeData is the data for welcome.html.
+type welcomeData struct {
+	FirstName string
+}
+
+// codeData is the data for templates that deliver a one-time code
+// (otp.html, password_reset.html).
+type codeData struct {
+	Code             string
+	ExpiresInMinutes int
+}
+
+// invitationData is the data for invitation.html.
+type invitationData struct {
+	InviterName string
+	Link        string
+	ExpiresAt   time.Time
+}
+
+// organizationInvitationData is the data for organization_invitation.html.
+type organizationInvitationData struct {
+	invitationData
+	OrganizationName string
+}
+
+// loginAlertData is the data for login_alert.html and new_location_login.html.
+type loginAlertData struct {
+	FirstName string
+	Method    string
+	IPAddress string
+	UserAgent string
+	At        time.Time
+}
+
+// loginApprovalData is the data for login_quarantine.html.
+type loginApprovalData struct {
+	loginAlertData
+	Link      string
+	ExpiresAt time.Time
+}
+
+// recoveryData is the data for two_factor_recovery.html. Kind is one of the
+// recovery* kinds below and selects the wording.
+type recoveryData struct {
+	Kind           string
+	FirstName      string
+	IPAddress      string
+	UserAgent      string
+	At             time.Time
+	AvailableAt    time.Time
+	CancelLink     string
+	RemainingCodes int
+}
+
+// twoFactorDisabledData is the data for two_factor_disabled.html.
+type twoFactorDisabledData struct {
+	FirstName string
+	IPAddress string
+	UserAgent string
+	At        time.Time
+}
+
+// emailTestData is the data for email_test.html.
+type emailTestData struct {
+	RequestedBy string
+	At          time.Time
+}
+
+// passwordExpiryData is the data for password_expiry_reminder.html. Expired
+// is set once ExpiresAt has passed.
+type passwordExpiryData struct {
+	FirstName string
+	ExpiresAt time.Time
+	Expired   bool
+}
+
+// passwordRotationData is the data for password_rotation_reminder.html.
+// Upgrade is set when the password predates a change of hashing algorithm
+// rather than being old.
+type passwordRotationData struct {
+	FirstName string
+	ChangedAt time.Time
+	Upgrade   bool
+}
+
+// Kinds of two-factor recovery alert.
+const (
+	recoveryCodeUsed  = "code_used"
+	recoveryStarted   = "started"
+	recoveryCompleted = "completed"
+)
+
+// registrationData is the data for the registration approval templates.
+type registrationData struct {
+	Name      string
+	FirstName string
+	Email     string
+	Link      string
+}
+
+// EmailRenderer renders the transactional email templates. Each email has its
+// own method taking exactly the values its template needs. Versions of the
+// page templates saved in a TemplateStore replace the embedded ones, and a
+// BrandingStore gives each tenant its own sender, logo, colors and footer.
+type EmailRenderer struct {
+	appName  string
+	defaults map[string]TemplateSource
+	standard *templateSet // templates without branding
+
+	store   TemplateStore
+	brands  BrandingStore
+	branded sync.Map // Branding (without FromEmail) -> *templateSet
+}
+
+// templateSet holds the layout and page templates parsed for one branding.
+type templateSet struct {
+	base      *template.Template // layout and partials, never executed
+	templates map[string]*template.Template
+	overrides sync.Map // stored template ID -> parsed *template.Template
+}
+
+// NewEmailRenderer parses the embedded templates. appName is exposed to
+// templates as {{appName}}. Parsing errors are returned here rather than at
+// send time.
+func NewEmailRenderer(appName string) (*EmailRenderer, error) {
+	r := &EmailRenderer{
+		appName:  appName,
+		defaults: make(map[string]TemplateSource),
+	}
+
+	standard, err := r.parseSet(Branding{})
+	if err != nil {
+		return nil, err
+	}
+	r.standard = standard
+	for _, name := range templateNames {
+		tmpl := standard.templates[name]
+		// Keep the page's source, read back before execution escapes it
+		r.defaults[name] = TemplateSource{
+			Subject: tmpl.Lookup("subject").Tree.Root.String(),
+			Content: strings.TrimSpace(tmpl.Lookup("content").Tree.Root.String()),
+		}
+	}
+
+	return r, nil
+}
+
+// parseSet parses the embedded templates with branding b.
+func (r *EmailRenderer) parseSet(b Branding) (*templateSet, error) {
+	name := r.appName
+	if b.FromName != "" {
+		name = b.FromName
+	}
+	theme := b.withDefaults()
+	funcs := template.FuncMap{
+		"appName": func() string { return name },
+		"year":    func() int { return time.Now().Year() },
+		"brand":   func() Branding { return theme },
+	}
+
+	base, err := template.New("layout.html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/partials/*.html")
+	if err != nil {
+		return nil, fmt.Errorf("parse email layout: %w", err)
+	}
+
+	set := &templateSet{base: base, templates: make(map[string]*template.Template)}
+	for _, page := range templateNames {
+		tmpl, err := template.Must(base.Clone()).ParseFS(templateFS, "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parse email template %s: %w", page, err)
+		}
+		set.templates[page] = tmpl
+	}
+	return set, nil
+}
+
+// Welcome renders the welcome email sent after registration.
+func (r *EmailRenderer) Welcome(ctx context.Context, firstName string) (*Message, error) {
+	return r.render(ctx, templateWelcome, welcomeData{FirstName: firstName})
+}
+
+// EmailTest renders the test email an admin (requestedBy) sent at at.
+func (r *EmailRenderer) EmailTest(ctx context.Context, requestedBy string, at time.Time) (*Message, error) {
+	return r.render(ctx, templateEmailTest, emailTestData{RequestedBy: requestedBy, At: at})
+}
+
+// OnboardingTips renders the onboarding email introducing account features.
+func (r *EmailRenderer) OnboardingTips(ctx context.Context, firstName string) (*Message, error) {
+	return r.render(ctx, templateOnboardingTips, welcomeData{FirstName: firstName})
+}
+
+// OnboardingSecurity renders the onboarding email suggesting two-factor
+// authentication.
+func (r *EmailRenderer) OnboardingSecurity(ctx context.Context, firstName string) (*Message, error) {
+	return r.render(ctx, templateOnboardingSec, welcomeData{FirstName: firstName})
+}
+
+// OTP renders the two-factor verification code email.
+func (r *EmailRenderer) OTP(ctx context.Context, code string, expiresIn time.Duration) (*Message, error) {
+	return r.render(ctx, templateOTP, codeData{Code: code, ExpiresInMinutes: int(expiresIn.Minutes())})
+}
+
+// PasswordReset renders the password reset code email.
+func (r *EmailRenderer) PasswordReset(ctx context.Context, code string, expiresIn time.Duration) (*Message, error) {
+	return r.render(ctx, templatePasswordReset, codeData{Code: code, ExpiresInMinutes: int(expiresIn.Minutes())})
+}
+
+// PasswordChanged renders the password change confirmation email.
+func (r *EmailRenderer) PasswordChanged(ctx context.Context) (*Message, error) {
+	return r.render(ctx, templatePasswordChanged, nil)
+}
+
+// LoginAlert renders the notice sent after a successful login. ipAddress and
+// userAgent may be empty.
+func (r *EmailRenderer) LoginAlert(ctx context.Context, firstName, method, ipAddress, userAgent string, at time.Time) (*Message, error) {
+	return r.render(ctx, templateLoginAlert, loginAlertData{
+		FirstName: firstName,
+		Method:    method,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		At:        at,
+	})
+}
+
+// NewLocationLogin renders the alert sent after a login from an IP address
+// none of the user's recent logins came from. userAgent may be empty.
+func (r *EmailRenderer) NewLocationLogin(ctx context.Context, firstName, method, ipAddress, userAgent string, at time.Time) (*Message, error) {
+	return r.render(ctx, templateNewLocation, loginAlertData{
+		FirstName: firstName,
+		Method:    method,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		At:        at,
+	})
+}
+
+// TwoFactorDisabled alerts the user that two-factor authentication was
+// turned off. ipAddress and userAgent may be empty.
+func (r *EmailRenderer) TwoFactorDisabled(ctx context.Context, firstName, ipAddress, userAgent string, at time.Time) (*Message, error) {
+	return r.render(ctx, templateTwoFADisabled, twoFactorDisabledData{
+		FirstName: firstName,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		At:        at,
+	})
+}
+
+// PasswordExpiry reminds the user that their password expires, or expired,
+// at expiresAt.
+func (r *EmailRenderer) PasswordExpiry(ctx context.Context, firstName string, expiresAt time.Time) (*Message, error) {
+	return r.render(ctx, templatePasswordExpiry, passwordExpiryData{
+		FirstName: firstName,
+		ExpiresAt: expiresAt,
+		Expired:   !expiresAt.After(time.Now()),
+	})
+}
+
+// PasswordRotation asks the user to choose a new password, last set at
+// changedAt; upgrade tells them it is to store it with a stronger algorithm.
+func (r *EmailRenderer) PasswordRotation(ctx context.Context, firstName string, changedAt time.Time, upgrade bool) (*Message, error) {
+	return r.render(ctx, templatePasswordRotate, passwordRotationData{
+		FirstName: firstName,
+		ChangedAt: changedAt,
+		Upgrade:   upgrade,
+	})
+}
+
+// LoginApproval renders the email asking the user to approve a quarantined
+// login with link before expiresAt.
+func (r *EmailRenderer) LoginApproval(ctx context.Context, firstName, method, ipAddress, userAgent string, at time.Time, link string, expiresAt time.Time) (*Message, error) {
+	return r.render(ctx, templateLoginApproval, loginApprovalData{
+		loginAlertData: loginAlertData{
+			FirstName: firstName,
+			Method:    method,
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+			At:        at,
+		},
+		Link:      link,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// RecoveryCodeUsed alerts the user that a recovery code was used to log in.
+// ipAddress and userAgent may be empty.
+func (r *EmailRenderer) RecoveryCodeUsed(ctx context.Context, firstName, ipAddress, userAgent string, at time.Time, remainingCodes int) (*Message, error) {
+	return r.render(ctx, templateRecovery, recoveryData{
+		Kind:           recoveryCodeUsed,
+		FirstName:      firstName,
+		IPAddress:      ipAddress,
+		UserAgent:      userAgent,
+		At:             at,
+		RemainingCodes: remainingCodes,
+	})
+}
+
+// RecoveryStarted alerts the user that two-factor recovery was requested and
+// will be possible from availableAt; cancelLink stops it.
+func (r *EmailRenderer) RecoveryStarted(ctx context.Context, firstName, ipAddress, userAgent string, at, availableAt time.Time, cancelLink string) (*Message, error) {
+	return r.render(ctx, templateRecovery, recoveryData{
+		Kind:        recoveryStarted,
+		FirstName:   firstName,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		At:          at,
+		AvailableAt: availableAt,
+		CancelLink:  cancelLink,
+	})
+}
+
+// RecoveryCompleted alerts the user that 2FA was turned off by a recovery.
+func (r *EmailRenderer) RecoveryCompleted(ctx context.Context, firstName, ipAddress, userAgent string, at time.Time) (*Message, error) {
+	return r.render(ctx, templateRecovery, recoveryData{
+		Kind:      recoveryCompleted,
+		FirstName: firstName,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		At:        at,
+	})
+}
+
+// Invitation renders the registration invitation email. inviterName may be
+// empty; link is where the recipient accepts the invitation.
+func (r *EmailRenderer) Invitation(ctx context.Context, inviterName, link string, expiresAt time.Time) (*Message, error) {
+	return r.render(ctx, templateInvitation, invitationData{
+		InviterName: inviterName,
+		Link:        link,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// OrganizationInvitation renders the invitation to join an organization.
+// inviterName may be empty; link is where the recipient accepts the invitation.
+func (r *EmailRenderer) OrganizationInvitation(ctx context.Context, inviterName, organizationName, link string, expiresAt time.Time) (*Message, error) {
+	return r.render(ctx, templateOrganizationInvitation, organizationInvitationData{
+		invitationData: invitationData{
+			InviterName: inviterName,
+			Link:        link,
+			ExpiresAt:   expiresAt,
+		},
+		OrganizationName: organizationName,
+	})
+}
+
+// RegistrationPending renders the notice sent to admins when a registration
+// needs approval.
+func (r *EmailRenderer) RegistrationPending(ctx context.Context, name, email string) (*Message, error) {
+	return r.render(ctx, templateRegistrationPending, registrationData{Name: name, Email: email})
+}
+
+// RegistrationApproved tells a user their registration was approved. loginLink
+// may be empty.
+func (r *EmailRenderer) RegistrationApproved(ctx context.Context, firstName, loginLink string) (*Message, error) {
+	return r.render(ctx, templateRegistrationApproved, registrationData{FirstName: firstName, Link: loginLink})
+}
+
+// RegistrationRejected tells a user their registration was rejected.
+func (r *EmailRenderer) RegistrationRejected(ctx context.Context, firstName string) (*Message, error) {
+	return r.render(ctx, templateRegistrationRejected, registrationData{FirstName: firstName})
+}
+
+// render executes the named page's subject and layout templates with the
+// branding of the tenant in ctx, using the version in the template store when
+// there is one. A stored version that can't be loaded or rendered falls back
+// to the embedded template, so a bad edit never stops an email.
+func (r *EmailRenderer) render(ctx context.Context, name string, data interface{}) (*Message, error) {
+	set, branding := r.templateSet(ctx)
+	tmpl, ok := set.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	if r.store != nil {
+		stored, err := r.store.ActiveTemplate(ctx, name)
+		if err != nil {
+			logger.Warn("failed to load stored email template, using the default", "error", err, "template", name)
+		} else if stored != nil {
+			msg, err := set.renderStored(stored, data)
+			if err == nil {
+				return branding.sign(msg), nil
+			}
+			logger.Warn("failed to render stored email template, using the default", "error", err, "template", name, "version", stored.Version)
+		}
+	}
+
+	msg, err := execute(name, tmpl, data)
+	if err != nil {
+		return nil, err
+	}
+	return branding.sign(msg), nil
+}
+
+// execute renders tmpl, the page template called name, with data.
+func execute(name string, tmpl *template.Template, data interface{}) (*Message, error) {
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "layout", data); err != nil {
+		return nil, fmt.Errorf("render %s body: %w", name, err)
+	}
+
+	return &Message{
+		// The subject is a header, not HTML, so undo html/template's escaping
+		Subject: strings.TrimSpace(html.UnescapeString(subject.String())),
+		HTML:    body.String(),
+	}, nil
+}
@@ -0,0 +1,49 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/textproto"
+
+	"authentio/pkg/retry"
+)
+
+// RetrySender sends emails through a provider, retrying at once (with a
+// short backoff) the sends failing for transient reasons, before the
+// queue's slower retries.
+type RetrySender struct {
+	sender  EmailSender
+	retries *retry.Retrier
+}
+
+// NewRetrySender wraps sender with retries (nil = no retries).
+func NewRetrySender(sender EmailSender, retries *retry.Retrier) *RetrySender {
+	return &RetrySender{sender: sender, retries: retries}
+}
+
+// Send sends mail, retrying transient failures until ctx is done.
+func (r *RetrySender) Send(ctx context.Context, mail Mail) (string, error) {
+	var messageID string
+	err := r.retries.Do(ctx, func(ctx context.Context) error {
+		var err error
+		messageID, err = r.sender.Send(ctx, mail)
+		return err
+	}, transientFailure)
+	return messageID, err
+}
+
+// transientFailure reports whether a send error may not happen again: the
+// provider was unreachable, busy or failing, rather than refusing the
+// email or the credentials.
+func transientFailure(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 400 && reply.Code < 500
+	}
+	var refused *SendGridError
+	if errors.As(err, &refused) {
+		return refused.StatusCode >= http.StatusInternalServerError || refused.StatusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,149 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Mail is an HTML email to one or more recipients. ID names it in the
+// delivery log; providers reporting delivery events (SendGrid) tag the
+// message with it so their webhooks can be matched to the log.
+type Mail struct {
+	ID      string
+	To      []string
+	Subject string
+	Body    string
+
+	// Sender replacing the provider's configured one, e.g. a tenant's
+	// branding; empty fields keep it
+	FromName  string
+	FromEmail string
+}
+
+// EmailSender delivers emails and returns the id the provider gave the
+// message (empty when it gives none). Every provider implements it so
+// services don't depend on a specific one. ctx bounds the delivery; callers
+// that must not abort it when their request ends detach it first
+// (context.WithoutCancel).
+type EmailSender interface {
+	Send(ctx context.Context, mail Mail) (string, error)
+}
+
+// Supported values for the EMAIL_PROVIDER setting.
+const (
+	ProviderSMTP     = "smtp"
+	ProviderSendGrid = "sendgrid"
+	ProviderLog      = "log"
+	ProviderFile     = "file"    // dry run: emails written to files
+	ProviderMemory   = "memory"  // dry run: emails kept in an Inbox
+	ProviderMailHog  = "mailhog" // development: delivered to MailHog or Mailpit
+	ProviderMaildir  = "maildir" // development: delivered to a local maildir
+)
+
+// SenderConfig holds the settings needed to build any provider.
+type SenderConfig struct {
+	Provider string
+
+	// Sender identity, shared by all providers
+	FromEmail string
+	FromName  string
+
+	// SMTP
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// Keep-alive sessions (0 = a new connection per email), see
+	// Client.EnablePool
+	SMTPPoolSize        int
+	SMTPPoolIdleTimeout time.Duration
+	// DKIM signing (no key file = unsigned); the domain defaults to
+	// FromEmail's
+	DKIMDomain     string
+	DKIMSelector   string
+	DKIMPrivateKey string // PEM file
+
+	// SendGrid
+	SendGridAPIKey string
+
+	// Dry-run providers: directory of the file provider, number of emails
+	// the memory provider keeps
+	OutboxDir string
+	InboxSize int
+
+	// Development sinks: SMTP address (host:port) of the mail catcher,
+	// directory of the maildir
+	SinkAddr string
+	Maildir  string
+}
+
+// NewSender returns the EmailSender for cfg.Provider. The memory provider
+// returns an *Inbox, to read its emails back.
+func NewSender(cfg SenderConfig) (EmailSender, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderSMTP, "":
+		client := NewClient(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.FromEmail)
+		client.EnablePool(cfg.SMTPPoolSize, cfg.SMTPPoolIdleTimeout)
+		if cfg.DKIMPrivateKey != "" {
+			signer, err := LoadDKIMSigner(cfg.dkimDomain(), cfg.DKIMSelector, cfg.DKIMPrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("load DKIM key: %w", err)
+			}
+			client.DKIM = signer
+		}
+		return client, nil
+	case ProviderSendGrid:
+		if cfg.SendGridAPIKey == "" {
+			return nil, fmt.Errorf("sendgrid provider requires SENDGRID_API_KEY")
+		}
+		return NewSendGridClient(cfg.SendGridAPIKey, cfg.FromEmail, cfg.FromName), nil
+	case ProviderLog:
+		return LogSender{}, nil
+	case ProviderFile:
+		if cfg.OutboxDir == "" {
+			return nil, fmt.Errorf("file provider requires EMAIL_OUTBOX_DIR")
+		}
+		return NewFileSender(cfg.OutboxDir, cfg.FromEmail)
+	case ProviderMemory:
+		return NewInbox(cfg.InboxSize), nil
+	case ProviderMailHog:
+		if cfg.SinkAddr == "" {
+			return nil, fmt.Errorf("mailhog provider requires EMAIL_SINK_ADDR")
+		}
+		return NewSMTPSink(cfg.SinkAddr, cfg.FromEmail), nil
+	case ProviderMaildir:
+		if cfg.Maildir == "" {
+			return nil, fmt.Errorf("maildir provider requires EMAIL_MAILDIR")
+		}
+		return NewMaildirSender(cfg.Maildir, cfg.FromEmail)
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.Provider)
+	}
+}
+
+// dkimDomain returns the domain emails are signed for: DKIMDomain, or the
+// domain of FromEmail.
+func (cfg SenderConfig) dkimDomain() string {
+	if cfg.DKIMDomain != "" {
+		return cfg.DKIMDomain
+	}
+	return cfg.FromEmail[strings.LastIndex(cfg.FromEmail, "@")+1:]
+}
+
+// LogSender writes emails to the application log instead of sending them.
+// Intended for local development, where OTP and reset codes can be read from
+// the console.
+type LogSender struct{}
+
+// Send logs the email.
+func (LogSender) Send(_ context.Context, mail Mail) (string, error) {
+	if len(mail.To) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+	logger.Info("email (log provider)", "id", mail.ID, "to", strings.Join(mail.To, ","), "subject", mail.Subject, "body", mail.Body)
+	return "", nil
+}
@@ -1,17 +1,20 @@
 package email
 
 import (
+	"context"
 	"fmt"
 	
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
 )
 
+// SendGridClient sends emails through the SendGrid v3 API.
 type SendGridClient struct {
 	APIKey string
 	From   *mail.Email
 }
 
+// NewSendGridClient constructs a SendGrid client sending as fromName <fromEmail>.
 func NewSendGridClient(apiKey, fromEmail, fromName string) *SendGridClient {
 	return &SendGridClient{
 		APIKey: apiKey,
@@ -19,31 +22,64 @@ func NewSendGridClient(apiKey, fromEmail, fromName string) *SendGridClient {
 	}
 }
 
-func (c *SendGridClient) Send(toEmail, toName, subject, htmlContent string) error {
-	to := mail.NewEmail(toName, toEmail)
-	message := mail.NewSingleEmail(c.From, subject, to, "", htmlContent)
+// Send sends an HTML email to one or more recipients and returns SendGrid's
+// X-Message-Id. The email's ID is sent as the sendGridMessageIDArg custom
+// argument, which SendGrid echoes in event webhooks. The API call is bounded
+// by ctx.
+func (c *SendGridClient) Send(ctx context.Context, email Mail) (string, error) {
+	if len(email.To) == 0 {
+		return "", fmt.Errorf("no recipients specified")
+	}
+
+	personalization := mail.NewPersonalization()
+	for _, addr := range email.To {
+		personalization.AddTos(mail.NewEmail("", addr))
+	}
+
+	from := c.From
+	if email.FromName != "" || email.FromEmail != "" {
+		from = mail.NewEmail(c.From.Name, c.From.Address)
+		if email.FromName != "" {
+			from.Name = email.FromName
+		}
+		if email.FromEmail != "" {
+			from.Address = email.FromEmail
+		}
+	}
+
+	message := mail.NewV3Mail()
+	message.SetFrom(from)
+	message.Subject = email.Subject
+	message.AddPersonalizations(personalization)
+	message.AddContent(mail.NewContent("text/html", email.Body))
+	if email.ID != "" {
+		message.SetCustomArg(sendGridMessageIDArg, email.ID)
+	}
+
 	client := sendgrid.NewSendClient(c.APIKey)
 	
-	response, err := client.Send(message)
+	response, err := client.SendWithContext(ctx, message)
 	if err != nil {
-		return fmt.Errorf("sendgrid send failed: %w", err)
+		return "", fmt.Errorf("sendgrid send failed: %w", err)
 	}
 	
 	if response.StatusCode >= 400 {
-		return fmt.Errorf("sendgrid error: %d - %s", response.StatusCode, response.Body)
+		return "", &SendGridError{StatusCode: response.StatusCode, Body: response.Body}
 	}
 	
-	return nil
+	var messageID string
+	if ids := response.Headers["X-Message-Id"]; len(ids) > 0 {
+		messageID = ids[0]
+	}
+	return messageID, nil
 }
 
-func (c *SendGridClient) SendOTP(toEmail, code string) error {
-	subject := "Your verification code"
-	htmlContent := fmt.Sprintf(`<p>Your verification code is <strong>%s</strong>. It will expire in 10 minutes.</p>`, code)
-	return c.Send(toEmail, "", subject, htmlContent)
+// SendGridError is an email refused by the SendGrid API.
+type SendGridError struct {
+	StatusCode int
+	Body       string
 }
 
-func (c *SendGridClient) SendPasswordReset(toEmail, code string) error {
-	subject := "Password reset request"
-	htmlContent := fmt.Sprintf(`<p>We received a request to reset your password. Use the code below:</p><p><strong>%s</strong></p>`, code)
-	return c.Send(toEmail, "", subject, htmlContent)
-}
\ No newline at end of file
+func (e *SendGridError) Error() string {
+	return fmt.Sprintf("sendgrid error: %d - %s", e.StatusCode, e.Body)
+}
@@ -0,0 +1,115 @@
+package email
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sendGridMessageIDArg is the custom argument carrying a Mail's ID through
+// SendGrid, echoed as a field of its events.
+const sendGridMessageIDArg = "authentio_message_id"
+
+// Headers of SendGrid's signed event webhook
+const (
+	SendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	SendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// ErrInvalidWebhookSignature is returned for webhook payloads not signed by
+// the provider.
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// Delivery event types
+const (
+	EventDelivered = "delivered" // accepted by the recipient's server
+	EventBounced   = "bounced"   // rejected by the recipient's server
+	EventDropped   = "dropped"   // not sent by the provider (e.g. suppressed address)
+	EventOpened    = "opened"    // opened by the recipient (tracking pixel)
+)
+
+// sendGridEventTypes maps the SendGrid events tracked to delivery event
+// types; processed, deferred, click and the rest are ignored.
+var sendGridEventTypes = map[string]string{
+	"delivered": EventDelivered,
+	"bounce":    EventBounced,
+	"dropped":   EventDropped,
+	"open":      EventOpened,
+}
+
+// DeliveryEvent is a delivery or engagement event a provider reported for
+// one recipient of an email.
+type DeliveryEvent struct {
+	MessageID string // the Mail's ID
+	Recipient string
+	Type      string // EventDelivered, EventBounced, EventDropped or EventOpened
+	Reason    string // why a message bounced or was dropped
+	At        time.Time
+}
+
+// SendGridWebhook verifies and parses SendGrid event webhook payloads.
+type SendGridWebhook struct {
+	publicKey *ecdsa.PublicKey
+}
+
+// NewSendGridWebhook creates a webhook verifier from the verification key
+// shown in SendGrid's signed event webhook settings (base64 DER).
+func NewSendGridWebhook(publicKey string) (*SendGridWebhook, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil {
+		return nil, fmt.Errorf("decode sendgrid webhook key: %w", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("parse sendgrid webhook key: %w", err)
+	}
+	ecdsaKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("sendgrid webhook key is not an ECDSA key")
+	}
+	return &SendGridWebhook{publicKey: ecdsaKey}, nil
+}
+
+// Events verifies payload's signature and timestamp headers and returns
+// the tracked events it reports. Events of emails without an ID, e.g. sent
+// by other applications on the same account, are skipped.
+func (w *SendGridWebhook) Events(payload []byte, signature, timestamp string) ([]DeliveryEvent, error) {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || timestamp == "" {
+		return nil, ErrInvalidWebhookSignature
+	}
+	digest := sha256.Sum256(append([]byte(timestamp), payload...))
+	if !ecdsa.VerifyASN1(w.publicKey, digest[:], sig) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var raw []map[string]interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return nil, fmt.Errorf("parse sendgrid events: %w", err)
+	}
+
+	events := make([]DeliveryEvent, 0, len(raw))
+	for _, fields := range raw {
+		name, _ := fields["event"].(string)
+		eventType, tracked := sendGridEventTypes[name]
+		messageID, _ := fields[sendGridMessageIDArg].(string)
+		recipient, _ := fields["email"].(string)
+		if !tracked || messageID == "" || recipient == "" {
+			continue
+		}
+
+		event := DeliveryEvent{MessageID: messageID, Recipient: recipient, Type: eventType, At: time.Now().UTC()}
+		if ts, ok := fields["timestamp"].(float64); ok {
+			event.At = time.Unix(int64(ts), 0).UTC()
+		}
+		event.Reason, _ = fields["reason"].(string)
+		events = append(events, event)
+	}
+	return events, nil
+}
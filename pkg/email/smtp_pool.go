@@ -0,0 +1,194 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// smtpSendTimeout bounds the delivery of one email over a pooled session.
+const smtpSendTimeout = 30 * time.Second
+
+// EnablePool makes the client keep authenticated sessions open between
+// emails instead of dialing, negotiating TLS and authenticating for each
+// one, which is what limits throughput during bursts of OTP codes. At most
+// size emails are delivered at once (further senders wait) and at most size
+// sessions are kept idle, each for up to idleTimeout. Call it before the
+// first Send.
+func (c *Client) EnablePool(size int, idleTimeout time.Duration) {
+	if size <= 0 {
+		return
+	}
+	c.pool = &smtpPool{
+		dial: func(ctx context.Context) (*smtp.Client, net.Conn, error) {
+			ctx, cancel := context.WithTimeout(ctx, smtpSendTimeout)
+			defer cancel()
+			return c.session(ctx)
+		},
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, size),
+	}
+}
+
+// Close ends the idle pooled sessions. Emails being sent finish normally.
+func (c *Client) Close() error {
+	if c.pool != nil {
+		c.pool.close()
+	}
+	return nil
+}
+
+// smtpPool is a set of keep-alive SMTP sessions. It is safe for concurrent
+// use.
+type smtpPool struct {
+	dial        func(ctx context.Context) (*smtp.Client, net.Conn, error)
+	idleTimeout time.Duration
+	slots       chan struct{} // one per email being delivered
+
+	mu   sync.Mutex
+	idle []*pooledSession // most recently used last
+}
+
+type pooledSession struct {
+	client *smtp.Client
+	conn   net.Conn
+	since  time.Time // returned to the pool
+}
+
+// send delivers msg over an idle session, or a new one when there is none.
+// A reused session the server closed in the meantime fails without a reply;
+// the email is then sent again over a new session. Waiting for a free slot,
+// dialing and delivering are bounded by ctx.
+func (p *smtpPool) send(ctx context.Context, from string, to []string, msg []byte) error {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.slots }()
+
+	session := p.get()
+	reused := session != nil
+	if !reused {
+		var err error
+		if session, err = p.newSession(ctx); err != nil {
+			return err
+		}
+	}
+
+	err := session.deliver(ctx, from, to, msg)
+	var reply *textproto.Error
+	if err != nil && reused && !errors.As(err, &reply) {
+		session.client.Close()
+		if session, err = p.newSession(ctx); err != nil {
+			return err
+		}
+		err = session.deliver(ctx, from, to, msg)
+	}
+	if err != nil {
+		// a rejected recipient leaves the transaction open; a session that
+		// can't be reset isn't reused
+		if errors.As(err, &reply) && session.client.Reset() == nil {
+			p.put(session)
+		} else {
+			session.client.Close()
+		}
+		return err
+	}
+	p.put(session)
+	return nil
+}
+
+func (p *smtpPool) newSession(ctx context.Context) (*pooledSession, error) {
+	client, conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledSession{client: client, conn: conn}, nil
+}
+
+// get takes the most recently used idle session, closing those idle for
+// longer than idleTimeout.
+func (p *smtpPool) get() *pooledSession {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.idle) > 0 {
+		session := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if time.Since(session.since) < p.idleTimeout {
+			return session
+		}
+		session.client.Close()
+	}
+	return nil
+}
+
+// put returns a session to the pool, or ends it when the pool is full.
+func (p *smtpPool) put(session *pooledSession) {
+	session.since = time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) >= cap(p.slots) {
+		go session.quit()
+		return
+	}
+	p.idle = append(p.idle, session)
+}
+
+// close ends the idle sessions.
+func (p *smtpPool) close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	for _, session := range idle {
+		session.quit()
+	}
+}
+
+// quit ends the session politely, without waiting long for the server.
+func (s *pooledSession) quit() {
+	s.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := s.client.Quit(); err != nil {
+		s.client.Close()
+	}
+}
+
+// deliver sends one email over the session, leaving it ready for the next.
+// It must finish by ctx's deadline, and within smtpSendTimeout.
+func (s *pooledSession) deliver(ctx context.Context, from string, to []string, msg []byte) error {
+	deadline := time.Now().Add(smtpSendTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	if err := s.client.Mail(from); err != nil {
+		return fmt.Errorf("mail from failed: %w", err)
+	}
+	for _, addr := range to {
+		if err := s.client.Rcpt(addr); err != nil {
+			return fmt.Errorf("rcpt to %s failed: %w", addr, err)
+		}
+	}
+	wc, err := s.client.Data()
+	if err != nil {
+		return fmt.Errorf("data command failed: %w", err)
+	}
+	if _, err := wc.Write(msg); err != nil {
+		return fmt.Errorf("write message failed: %w", err)
+	}
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("close writer failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"time"
+)
+
+var (
+	// ErrUnknownTemplate is returned for a template name that is not one of
+	// TemplateNames.
+	ErrUnknownTemplate = errors.New("unknown email template")
+
+	// ErrInvalidTemplate wraps the parse or execution error of a template
+	// source that can't be rendered.
+	ErrInvalidTemplate = errors.New("invalid email template")
+)
+
+// TemplateSource is the source of a page template: its subject line and the
+// content placed in the shared layout. Both use html/template syntax with the
+// fields of the page's data, {{appName}}, {{year}}, the tenant's {{brand}}
+// (see Branding) and the partials (e.g. {{template "code" .Code}}).
+type TemplateSource struct {
+	Subject string `json:"subject"`
+	Content string `json:"content"`
+}
+
+// StoredTemplate is a saved version of a page template. Versions never
+// change once saved, so ID identifies the content.
+type StoredTemplate struct {
+	ID      int64
+	Version int
+	TemplateSource
+}
+
+// TemplateStore supplies the saved versions of templates that replace the
+// embedded ones.
+type TemplateStore interface {
+	// ActiveTemplate returns the version of the named template in use for
+	// the tenant in ctx, or nil to use the embedded one.
+	ActiveTemplate(ctx context.Context, name string) (*StoredTemplate, error)
+}
+
+// SetTemplateStore makes the renderer use the template versions in store.
+// Call it once, before rendering.
+func (r *EmailRenderer) SetTemplateStore(store TemplateStore) {
+	r.store = store
+}
+
+// TemplateNames returns the name of every page template.
+func TemplateNames() []string {
+	return append([]string(nil), templateNames...)
+}
+
+// DefaultTemplate returns the source of the embedded version of the named
+// template.
+func (r *EmailRenderer) DefaultTemplate(name string) (TemplateSource, error) {
+	source, ok := r.defaults[name]
+	if !ok {
+		return TemplateSource{}, ErrUnknownTemplate
+	}
+	return source, nil
+}
+
+// Preview renders the named template with sample data: draft when set,
+// otherwise the version in use. Errors of a draft wrap ErrInvalidTemplate.
+func (r *EmailRenderer) Preview(ctx context.Context, name string, draft *TemplateSource) (*Message, error) {
+	data, ok := sampleData(name)
+	if !ok {
+		return nil, ErrUnknownTemplate
+	}
+	if draft == nil {
+		return r.render(ctx, name, data)
+	}
+
+	set, branding := r.templateSet(ctx)
+	tmpl, err := set.parse(*draft)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	msg, err := execute(name, tmpl, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return branding.sign(msg), nil
+}
+
+// renderStored renders a stored template version with data, parsing it on
+// first use.
+func (set *templateSet) renderStored(stored *StoredTemplate, data interface{}) (*Message, error) {
+	var tmpl *template.Template
+	if cached, ok := set.overrides.Load(stored.ID); ok {
+		tmpl = cached.(*template.Template)
+	} else {
+		parsed, err := set.parse(stored.TemplateSource)
+		if err != nil {
+			return nil, err
+		}
+		set.overrides.Store(stored.ID, parsed)
+		tmpl = parsed
+	}
+	return execute(fmt.Sprintf("stored template %d", stored.ID), tmpl, data)
+}
+
+// parse builds a page template from source on a copy of the layout.
+func (set *templateSet) parse(source TemplateSource) (*template.Template, error) {
+	tmpl, err := set.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.New("subject").Parse(source.Subject); err != nil {
+		return nil, fmt.Errorf("subject: %w", err)
+	}
+	if _, err := tmpl.New("content").Parse(source.Content); err != nil {
+		return nil, fmt.Errorf("content: %w", err)
+	}
+	return tmpl, nil
+}
+
+// sampleData returns example data for previewing the named template.
+func sampleData(name string) (interface{}, bool) {
+	now := time.Now()
+	login := loginAlertData{
+		FirstName: "Jane",
+		Method:    "password",
+		IPAddress: "203.0.113.7",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) Safari/605.1.15",
+		At:        now,
+	}
+	invitation := invitationData{InviterName: "John Doe", Link: "https://example.com/invite?token=sample", ExpiresAt: now.Add(7 * 24 * time.Hour)}
+
+	switch name {
+	case templateWelcome, templateOnboardingTips, templateOnboardingSec:
+		return welcomeData{FirstName: "Jane"}, true
+	case templateOTP, templatePasswordReset:
+		return codeData{Code: "123456", ExpiresInMinutes: 10}, true
+	case templatePasswordChanged:
+		return nil, true
+	case templateLoginAlert, templateNewLocation:
+		return login, true
+	case templateTwoFADisabled:
+		return twoFactorDisabledData{FirstName: login.FirstName, IPAddress: login.IPAddress, UserAgent: login.UserAgent, At: now}, true
+	case templatePasswordExpiry:
+		return passwordExpiryData{FirstName: "Jane", ExpiresAt: now.Add(5 * 24 * time.Hour)}, true
+	case templatePasswordRotate:
+		return passwordRotationData{FirstName: "Jane", ChangedAt: now.Add(-400 * 24 * time.Hour)}, true
+	case templateLoginApproval:
+		return loginApprovalData{loginAlertData: login, Link: "https://example.com/login/approve?token=sample", ExpiresAt: now.Add(30 * time.Minute)}, true
+	case templateRecovery:
+		return recoveryData{
+			Kind:        recoveryStarted,
+			FirstName:   "Jane",
+			IPAddress:   login.IPAddress,
+			UserAgent:   login.UserAgent,
+			At:          now,
+			AvailableAt: now.Add(24 * time.Hour),
+			CancelLink:  "https://example.com/2fa/recovery/cancel?token=sample",
+		}, true
+	case templateInvitation:
+		return invitation, true
+	case templateOrganizationInvitation:
+		return organizationInvitationData{invitationData: invitation, OrganizationName: "Acme"}, true
+	case templateRegistrationPending, templateRegistrationApproved, templateRegistrationRejected:
+		return registrationData{Name: "Jane Smith", FirstName: "Jane", Email: "jane@example.com", Link: "https://example.com/login"}, true
+	case templateEmailTest:
+		return emailTestData{RequestedBy: "admin@example.com", At: now}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,47 @@
+package email
+
+import (
+	"context"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// trackTimeout bounds the recording of one delivery attempt
+const trackTimeout = 5 * time.Second
+
+// DeliveryTracker records the outcome of each attempt to send an email:
+// sendErr is nil when the provider accepted it as providerMessageID.
+type DeliveryTracker interface {
+	RecordAttempt(ctx context.Context, messageID, providerMessageID string, sendErr error) error
+}
+
+// TrackingSender sends emails through a provider and reports every attempt
+// to a DeliveryTracker. Wrap the provider itself, not the queue, so each
+// retry made by queue workers is recorded.
+type TrackingSender struct {
+	sender  EmailSender
+	tracker DeliveryTracker
+}
+
+// NewTrackingSender wraps sender, reporting attempts to tracker.
+func NewTrackingSender(sender EmailSender, tracker DeliveryTracker) *TrackingSender {
+	return &TrackingSender{sender: sender, tracker: tracker}
+}
+
+// Send sends mail and records the attempt when mail has an ID. Tracking
+// failures are logged; they don't fail the send. The attempt is recorded
+// even when ctx was cancelled during the send.
+func (t *TrackingSender) Send(ctx context.Context, mail Mail) (string, error) {
+	providerMessageID, sendErr := t.sender.Send(ctx, mail)
+	if mail.ID == "" {
+		return providerMessageID, sendErr
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), trackTimeout)
+	defer cancel()
+	if err := t.tracker.RecordAttempt(ctx, mail.ID, providerMessageID, sendErr); err != nil {
+		logger.Error("failed to record email delivery attempt", "error", err, "messageID", mail.ID)
+	}
+	return providerMessageID, sendErr
+}
@@ -0,0 +1,146 @@
+// Package export keeps in Redis the bulk exports admins request, such as the
+// user export: the state of each export job while it is generated in the
+// background, then the file it produced. Both expire together, so exports of
+// personal data don't outlive their purpose.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "export:"
+
+// File formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Job statuses
+const (
+	StatusPending = "pending"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+// ContentType returns the MIME type of files in format.
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// Job is an export being generated or ready to download.
+type Job struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"` // pending, ready or failed
+	Format      string     `json:"format"` // csv or json
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"` // why a failed export failed
+	RequestedBy int64      `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"` // when the job and its file are deleted
+}
+
+// Store records export jobs and their files by tenant. It is safe for
+// concurrent use.
+type Store struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewStore creates a store keeping exports for ttl after they were requested.
+func NewStore(redisClient *redis.Client, ttl time.Duration) *Store {
+	return &Store{redis: redisClient, ttl: ttl}
+}
+
+// Create records a new pending job, setting its creation and expiry times.
+func (s *Store) Create(ctx context.Context, tenantID int64, job *Job) error {
+	job.Status = StatusPending
+	job.CreatedAt = time.Now().UTC()
+	job.ExpiresAt = job.CreatedAt.Add(s.ttl)
+	return s.save(ctx, tenantID, job)
+}
+
+// Get returns the job id of tenantID, or nil when it doesn't exist or has
+// expired.
+func (s *Store) Get(ctx context.Context, tenantID int64, id string) (*Job, error) {
+	stored, err := s.redis.Get(ctx, jobKey(tenantID, id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job Job
+	if err := json.Unmarshal(stored, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Complete stores the file of a job with its number of rows and marks the
+// job ready.
+func (s *Store) Complete(ctx context.Context, tenantID int64, job *Job, file []byte, rows int) error {
+	ttl := time.Until(job.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("export %s expired before it completed", job.ID)
+	}
+	if err := s.redis.Set(ctx, fileKey(tenantID, job.ID), file, ttl).Err(); err != nil {
+		return err
+	}
+
+	completedAt := time.Now().UTC()
+	job.Status = StatusReady
+	job.Rows = rows
+	job.CompletedAt = &completedAt
+	return s.save(ctx, tenantID, job)
+}
+
+// Fail marks a job failed with the reason shown to admins.
+func (s *Store) Fail(ctx context.Context, tenantID int64, job *Job, reason string) error {
+	completedAt := time.Now().UTC()
+	job.Status = StatusFailed
+	job.Error = reason
+	job.CompletedAt = &completedAt
+	return s.save(ctx, tenantID, job)
+}
+
+// File returns the file of a ready job, or nil when it has expired.
+func (s *Store) File(ctx context.Context, tenantID int64, id string) ([]byte, error) {
+	file, err := s.redis.Get(ctx, fileKey(tenantID, id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return file, err
+}
+
+// save writes job until it expires.
+func (s *Store) save(ctx context.Context, tenantID int64, job *Job) error {
+	ttl := time.Until(job.ExpiresAt)
+	if ttl <= 0 {
+		return fmt.Errorf("export %s expired", job.ID)
+	}
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, jobKey(tenantID, job.ID), raw, ttl).Err()
+}
+
+func jobKey(tenantID int64, id string) string {
+	return fmt.Sprintf("%s%d:%s", keyPrefix, tenantID, id)
+}
+
+func fileKey(tenantID int64, id string) string {
+	return jobKey(tenantID, id) + ":file"
+}
@@ -0,0 +1,175 @@
+// Package health serves the readiness endpoint: it runs named checks of the
+// service's dependencies (database, Redis, email provider, GeoIP and
+// identity providers) and reports whether the instance can take traffic,
+// with the latency of each. Only critical checks make it unavailable; the
+// others, and slow checks, mark it degraded.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Statuses of the instance and of each check.
+const (
+	StatusReady       = "ready"       // every check passed
+	StatusDegraded    = "degraded"    // a non-critical check failed
+	StatusUnavailable = "unavailable" // a critical check failed
+	StatusOK          = "ok"
+	StatusSlow        = "slow" // passed, but slower than SlowAfter
+	StatusFailed      = "failed"
+)
+
+// Check is a dependency check.
+type Check struct {
+	Name string
+	// Critical checks make the instance unavailable when they fail
+	Critical bool
+	// Interval caches the result, for checks too costly to run on every
+	// request (0 = run every time)
+	Interval time.Duration
+	// SlowAfter marks the check slow when it passes in more time (0 = never)
+	SlowAfter time.Duration
+	Run       func(ctx context.Context) error
+}
+
+// CheckResult is the outcome of a check. Errors are logged rather than
+// returned, so the unauthenticated endpoint doesn't reveal internal
+// addresses.
+type CheckResult struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	LatencyMS int64     `json:"latency_ms"`
+	At        time.Time `json:"checked_at"`
+}
+
+// Report is the readiness of the instance.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type check struct {
+	Check
+
+	mu   sync.Mutex
+	last *CheckResult
+}
+
+// Checker runs the checks. It is safe for concurrent use.
+type Checker struct {
+	checks  []*check
+	timeout time.Duration
+}
+
+// NewChecker creates a checker giving each run of a check timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check. Call it before serving.
+func (c *Checker) Add(ch Check) {
+	c.checks = append(c.checks, &check{Check: ch})
+}
+
+// Run runs the checks concurrently and returns the report.
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func(i int, ch *check) {
+			defer wg.Done()
+			results[i] = ch.result(ctx, c.timeout)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]CheckResult, len(c.checks))}
+	for i, ch := range c.checks {
+		report.Checks[ch.Name] = results[i]
+		if results[i].Status == StatusOK {
+			continue
+		}
+		if ch.Critical && results[i].Status == StatusFailed {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// ServeHTTP answers with the report: 200 when ready or degraded, 503 when
+// unavailable.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	status := http.StatusOK
+	if report.Status == StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// result returns the check's cached result, running it when it is stale.
+// Changes of status are logged.
+func (ch *check) result(ctx context.Context, timeout time.Duration) CheckResult {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if ch.last != nil && time.Since(ch.last.At) < ch.Interval {
+		return *ch.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := ch.Run(ctx)
+	latency := time.Since(start)
+
+	result := CheckResult{Status: StatusOK, Critical: ch.Critical, LatencyMS: latency.Milliseconds(), At: start}
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		if ch.last == nil || ch.last.Status != StatusFailed {
+			logger.Warn("readiness check failed", "check", ch.Name, "critical", ch.Critical, "error", err)
+		}
+	case ch.SlowAfter > 0 && latency > ch.SlowAfter:
+		result.Status = StatusSlow
+		if ch.last == nil || ch.last.Status != StatusSlow {
+			logger.Warn("readiness check slow", "check", ch.Name, "latency", latency)
+		}
+	case ch.last != nil && ch.last.Status != StatusOK:
+		logger.Info("readiness check recovered", "check", ch.Name)
+	}
+	ch.last = &result
+	return result
+}
+
+// HTTPCheck returns a check that a server is reachable: a GET of url
+// answered without a server error.
+func HTTPCheck(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
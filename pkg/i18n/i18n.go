@@ -0,0 +1,111 @@
+// Package i18n provides message catalogs for user-facing strings (validation
+// messages, service errors) and Accept-Language negotiation. Catalogs are flat
+// JSON files embedded from locales/<lang>.json; English is the fallback.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is used when no supported locale can be negotiated and as the
+// fallback catalog for keys missing from other locales.
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var localeFS embed.FS
+
+var (
+	// catalogs maps a locale (e.g. "fr") to its key -> message table
+	catalogs = map[string]map[string]string{}
+
+	// supported lists loaded locales with DefaultLocale first, as required by language.NewMatcher
+	supported []language.Tag
+	matcher   language.Matcher
+)
+
+func init() {
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: read embedded locales: %v", err))
+	}
+
+	supported = []language.Tag{language.Make(DefaultLocale)}
+	for _, entry := range entries {
+		locale := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+
+		raw, err := localeFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: read %s: %v", entry.Name(), err))
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: parse %s: %v", entry.Name(), err))
+		}
+		catalogs[locale] = messages
+
+		if locale != DefaultLocale {
+			supported = append(supported, language.Make(locale))
+		}
+	}
+	if _, ok := catalogs[DefaultLocale]; !ok {
+		panic("i18n: missing default locale catalog " + DefaultLocale)
+	}
+
+	matcher = language.NewMatcher(supported)
+}
+
+// T returns the message for key in the given locale, falling back to the
+// default locale and finally to the key itself. When args are given the
+// message is used as a fmt format string.
+func T(locale, key string, args ...interface{}) string {
+	msg, ok := catalogs[locale][key]
+	if !ok {
+		if msg, ok = catalogs[DefaultLocale][key]; !ok {
+			return key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Lookup is like T but reports whether the key exists in any catalog instead
+// of falling back to the key, so callers can supply their own default.
+func Lookup(locale, key string) (string, bool) {
+	if msg, ok := catalogs[locale][key]; ok {
+		return msg, true
+	}
+	msg, ok := catalogs[DefaultLocale][key]
+	return msg, ok
+}
+
+// Match negotiates the best supported locale for an Accept-Language header
+// value (e.g. "fr-CA,fr;q=0.9,en;q=0.8"), returning DefaultLocale when nothing matches.
+func Match(acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return DefaultLocale
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return DefaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return DefaultLocale
+	}
+	base, _ := supported[index].Base()
+	return base.String()
+}
+
+// IsSupported reports whether a catalog exists for locale.
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
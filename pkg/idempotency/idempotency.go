@@ -0,0 +1,93 @@
+// Package idempotency stores in Redis the responses of requests sent with
+// an Idempotency-Key, so a client retrying the request gets the first
+// response again instead of running it twice.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "idempotency:"
+
+// lockTTL bounds how long a request holds its key before it completes; a
+// crashed request releases it after this.
+const lockTTL = time.Minute
+
+// pending marks a key whose first request hasn't completed yet.
+const pending = "pending"
+
+// ErrInProgress is returned by Begin while the first request with the key is
+// still running.
+var ErrInProgress = errors.New("a request with this idempotency key is in progress")
+
+// Response is a stored response, with the fingerprint of the request that
+// produced it.
+type Response struct {
+	RequestHash string            `json:"request_hash"`
+	Status      int               `json:"status"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body"`
+}
+
+// Store records responses by key. It is safe for concurrent use.
+type Store struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewStore creates a store keeping responses for ttl.
+func NewStore(redisClient *redis.Client, ttl time.Duration) *Store {
+	return &Store{redis: redisClient, ttl: ttl}
+}
+
+// Begin claims key for a new request. It returns the stored response when a
+// request with key already completed, nil when the caller must run the
+// request and then Complete or Release key, and ErrInProgress while another
+// request holds it.
+func (s *Store) Begin(ctx context.Context, key string) (*Response, error) {
+	claimed, err := s.redis.SetNX(ctx, keyPrefix+key, pending, lockTTL).Result()
+	if err != nil {
+		return nil, err
+	}
+	if claimed {
+		return nil, nil
+	}
+
+	stored, err := s.redis.Get(ctx, keyPrefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		// Released or expired in between: let the client retry
+		return nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored == pending {
+		return nil, ErrInProgress
+	}
+
+	var resp Response
+	if err := json.Unmarshal([]byte(stored), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Complete stores the response of the request holding key.
+func (s *Store) Complete(ctx context.Context, key string, resp Response) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, keyPrefix+key, data, s.ttl).Err()
+}
+
+// Release frees key without storing a response, so a retry runs the request
+// again.
+func (s *Store) Release(ctx context.Context, key string) error {
+	return s.redis.Del(ctx, keyPrefix+key).Err()
+}
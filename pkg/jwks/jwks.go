@@ -0,0 +1,271 @@
+// Package jwks verifies tokens issued by an external identity provider
+// against the keys it publishes as a JSON Web Key Set (RFC 7517). Keys are
+// cached and refreshed periodically, and fetched again when a token names a
+// key the cache doesn't hold, so the provider can rotate keys at any time.
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRefetchInterval throttles fetches triggered by unknown key IDs, so
+// tokens naming made-up keys can't flood the provider.
+const minRefetchInterval = time.Minute
+
+// maxKeySetSize bounds the key set response read from the provider.
+const maxKeySetSize = 1 << 20
+
+// minRSABits is the smallest RSA modulus accepted.
+const minRSABits = 2048
+
+// supportedAlgorithms are the asymmetric signing algorithms accepted. HMAC
+// is never accepted: the key set is public.
+var supportedAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// ErrUnknownKey is returned for tokens signed with a key the provider
+// doesn't publish.
+var ErrUnknownKey = errors.New("signing key not found in the provider's key set")
+
+// Config describes an identity provider.
+type Config struct {
+	Issuer   string        // required "iss" claim
+	URL      string        // JWKS endpoint
+	Audience string        // required "aud" claim
+	Refresh  time.Duration // how long fetched keys are used before fetching them again
+	Leeway   time.Duration // clock skew tolerated on exp, nbf and iat
+}
+
+// Verifier validates tokens of one identity provider. It is safe for
+// concurrent use.
+type Verifier struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]publicKey // by "kid"
+	fetchedAt   time.Time            // last successful fetch
+	attemptedAt time.Time            // last fetch, successful or not
+}
+
+type publicKey struct {
+	key       interface{} // *rsa.PublicKey or *ecdsa.PublicKey
+	algorithm string      // "alg" of the JWK; empty when the provider doesn't restrict it
+}
+
+// NewVerifier creates a verifier. Keys are fetched on first use.
+func NewVerifier(cfg Config) *Verifier {
+	return &Verifier{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       make(map[string]publicKey),
+	}
+}
+
+// Issuer returns the issuer whose tokens are verified.
+func (v *Verifier) Issuer() string {
+	return v.cfg.Issuer
+}
+
+// Verify parses and validates a token: its signature against the provider's
+// keys, its issuer and audience, and its lifetime. It returns the claims.
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.algorithm != "" && key.algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("key %q is not used with %s", kid, token.Method.Alg())
+		}
+		return key.key, nil
+	},
+		jwt.WithValidMethods(supportedAlgorithms),
+		jwt.WithIssuer(v.cfg.Issuer),
+		jwt.WithAudience(v.cfg.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(v.cfg.Leeway),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key returns the key named kid, fetching the key set when the cache is stale
+// or doesn't hold it. A token without kid is accepted when the provider
+// publishes a single key.
+func (v *Verifier) key(ctx context.Context, kid string) (publicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	stale := now.Sub(v.fetchedAt) > v.cfg.Refresh
+	_, known := v.lookup(kid)
+	if (stale || !known) && now.Sub(v.attemptedAt) >= minRefetchInterval {
+		v.attemptedAt = now
+		keys, err := v.fetch(ctx)
+		if err != nil {
+			// Keep verifying with the keys we have until the provider is back
+			logger.Warn("failed to fetch identity provider keys", "url", v.cfg.URL, "error", err)
+		} else {
+			v.keys = keys
+			v.fetchedAt = now
+		}
+	}
+
+	key, ok := v.lookup(kid)
+	if !ok {
+		return publicKey{}, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// lookup finds kid in the cache. Callers hold mu.
+func (v *Verifier) lookup(kid string) (publicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey holds the members of the RSA and EC keys supported.
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// fetch downloads and parses the key set. Keys that aren't signing keys or
+// can't be parsed are skipped.
+func (v *Verifier) fetch(ctx context.Context) (map[string]publicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxKeySetSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseKey(jwk)
+		if err != nil {
+			logger.Debug("skipping identity provider key", "kid", jwk.KeyID, "error", err)
+			continue
+		}
+		keys[jwk.KeyID] = publicKey{key: key, algorithm: jwk.Algorithm}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("key set has no usable signing keys")
+	}
+	return keys, nil
+}
+
+// parseKey converts a JWK into an RSA or ECDSA public key.
+func parseKey(jwk jsonWebKey) (interface{}, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(jwk.Modulus)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.Exponent)
+		if err != nil {
+			return nil, err
+		}
+		if n.BitLen() < minRSABits {
+			return nil, fmt.Errorf("RSA key shorter than %d bits", minRSABits)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		// Uncompressed point encoding: 0x04 || X || Y, the coordinates
+		// being fixed-length as RFC 7518 requires
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC coordinates")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+}
+
+// decodeBigInt decodes an unpadded base64url big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,99 @@
+package jwt
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ChallengeAudience is the "aud" claim of challenge tokens. Access token
+// verification rejects tokens carrying it, so a challenge token can only be
+// used to complete its login.
+const ChallengeAudience = "authentio:mfa-challenge"
+
+// ChallengeClaims identify a login waiting for a second factor.
+type ChallengeClaims struct {
+	ID          string // "jti"; tracked by the caller to make the token single use
+	UserID      int64
+	TenantID    int64
+	ChallengeID string
+	Method      string // second factor the login waits for (push or email)
+	ExpiresAt   time.Time
+}
+
+// GenerateChallengeToken signs a challenge token with the key of the user's
+// tenant.
+func (m *Manager) GenerateChallengeToken(challenge ChallengeClaims) (string, error) {
+	key := m.Key(challenge.TenantID)
+
+	claims := jwt.MapClaims{
+		"jti":          challenge.ID,
+		"sub":          strconv.FormatInt(challenge.UserID, 10),
+		"aud":          ChallengeAudience,
+		"challenge_id": challenge.ChallengeID,
+		"method":       challenge.Method,
+		"iat":          time.Now().Unix(),
+		"exp":          challenge.ExpiresAt.Unix(),
+	}
+	if key.Issuer != "" {
+		claims["iss"] = key.Issuer
+	}
+	if challenge.TenantID != 0 {
+		claims["tenant_id"] = challenge.TenantID
+	}
+
+	return m.sign(key, claims)
+}
+
+// VerifyChallengeToken parses and validates a challenge token signed with
+// the given tenant's key.
+func (m *Manager) VerifyChallengeToken(tokenString string, tenantID int64) (*ChallengeClaims, error) {
+	key := m.Key(tenantID)
+
+	opts := m.parserOptions(jwt.WithAudience(ChallengeAudience), jwt.WithExpirationRequired())
+	if key.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(key.Issuer))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc(key), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims.GetSubject()
+	userID, err := strconv.ParseInt(subject, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid challenge token subject")
+	}
+	id, _ := claims["jti"].(string)
+	challengeID, _ := claims["challenge_id"].(string)
+	if id == "" || challengeID == "" {
+		return nil, errors.New("incomplete challenge token")
+	}
+	method, _ := claims["method"].(string)
+	expiresAt, _ := claims.GetExpirationTime()
+
+	return &ChallengeClaims{
+		ID:          id,
+		UserID:      userID,
+		TenantID:    tenantID,
+		ChallengeID: challengeID,
+		Method:      method,
+		ExpiresAt:   expiresAt.Time,
+	}, nil
+}
+
+// hasAudience reports whether claims carry the "aud" value audience, which
+// marks challenge and download tokens.
+func hasAudience(claims jwt.MapClaims, audience string) bool {
+	values, _ := claims.GetAudience()
+	for _, aud := range values {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DownloadAudience is the "aud" claim of download tokens. Access token
+// verification rejects tokens carrying it, so a download link can't be used
+// to call the API.
+const DownloadAudience = "authentio:download"
+
+// DownloadClaims sign a link to download a file, such as a user export,
+// without an access token.
+type DownloadClaims struct {
+	FileID    string // "sub"
+	TenantID  int64
+	ExpiresAt time.Time
+}
+
+// GenerateDownloadToken signs a download token with the key of the file's
+// tenant.
+func (m *Manager) GenerateDownloadToken(download DownloadClaims) (string, error) {
+	key := m.Key(download.TenantID)
+
+	claims := jwt.MapClaims{
+		"sub": download.FileID,
+		"aud": DownloadAudience,
+		"iat": time.Now().Unix(),
+		"exp": download.ExpiresAt.Unix(),
+	}
+	if key.Issuer != "" {
+		claims["iss"] = key.Issuer
+	}
+	if download.TenantID != 0 {
+		claims["tenant_id"] = download.TenantID
+	}
+
+	return m.sign(key, claims)
+}
+
+// VerifyDownloadToken parses and validates a download token signed with the
+// given tenant's key.
+func (m *Manager) VerifyDownloadToken(tokenString string, tenantID int64) (*DownloadClaims, error) {
+	key := m.Key(tenantID)
+
+	opts := m.parserOptions(jwt.WithAudience(DownloadAudience), jwt.WithExpirationRequired())
+	if key.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(key.Issuer))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc(key), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID, _ := claims.GetSubject()
+	if fileID == "" {
+		return nil, errors.New("incomplete download token")
+	}
+	expiresAt, _ := claims.GetExpirationTime()
+
+	return &DownloadClaims{
+		FileID:    fileID,
+		TenantID:  tenantID,
+		ExpiresAt: expiresAt.Time,
+	}, nil
+}
@@ -3,7 +3,10 @@ package jwt
 
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,46 +15,193 @@ import (
 // Manager is responsible for handling all JWT-related operations:
 // generation, signing, and verification.
 type Manager struct {
-	secretKey string
+	mu                   sync.RWMutex // guards the keys replaced by rotations
+	defaultKey           Key
+	keys                 KeyStore
+	providerKey          *ProviderKey   // OpenID Connect provider signing key; nil when provider mode is off
+	previousProviderKeys []*ProviderKey // provider keys replaced by rotations, still verifying
+	leeway               time.Duration  // clock skew tolerated on exp, nbf and iat
 }
 
-// NewManager constructs the Manager with its required dependency, the secret key.
-func NewManager(secretKey string) *Manager {
-	return &Manager{secretKey: secretKey}
+// Key is the signing configuration for the tokens of one tenant. Secret
+// signs new tokens; after a rotation, Retired keeps the previous secrets
+// verifying the tokens they signed until those expire.
+type Key struct {
+	Secret     string
+	KeyID      string        // "kid" header of tokens signed with Secret; omitted when empty
+	Retired    []RetiredKey  // previous secrets, oldest first
+	Issuer     string        // "iss" claim; neither set nor checked when empty
+	Audience   string        // access token "aud" claim; neither set nor checked when empty
+	AccessTTL  time.Duration // access token lifetime
+	RefreshTTL time.Duration // refresh token lifetime (enforced by the caller)
 }
 
-// GenerateToken creates a new JWT access token with the specified user claims.
-func (m *Manager) GenerateToken(userID int64, email string, firstName, lastName string) (string, error) {
+// ScopeSudo is the "scope" claim of access tokens issued after the user
+// re-authenticated, which admin operations require.
+const ScopeSudo = "sudo"
+
+// KeyStore supplies per-tenant signing keys. TenantKey returns nil for tenants
+// that use the manager's default key.
+type KeyStore interface {
+	TenantKey(tenantID int64) *Key
+}
+
+// NewManager constructs the Manager with the default signing key and an
+// optional store of per-tenant keys (nil signs every token with defaultKey).
+func NewManager(defaultKey Key, keys KeyStore) *Manager {
+	return &Manager{defaultKey: defaultKey, keys: keys}
+}
+
+// SetLeeway sets the clock skew tolerated when validating the exp, nbf and
+// iat claims, so servers whose clocks drift slightly apart accept each
+// other's fresh tokens. Call it before the server starts handling requests.
+func (m *Manager) SetLeeway(leeway time.Duration) {
+	m.leeway = leeway
+}
+
+// parserOptions returns the time validation options shared by every token
+// type, followed by opts.
+func (m *Manager) parserOptions(opts ...jwt.ParserOption) []jwt.ParserOption {
+	return append([]jwt.ParserOption{jwt.WithLeeway(m.leeway), jwt.WithIssuedAt()}, opts...)
+}
+
+// SetDefaultKey replaces the default signing key, e.g. after a rotation. It
+// is safe to call while the server handles requests.
+func (m *Manager) SetDefaultKey(key Key) {
+	m.mu.Lock()
+	m.defaultKey = key
+	m.mu.Unlock()
+}
+
+// Key returns the signing key for tokens of the given tenant.
+func (m *Manager) Key(tenantID int64) Key {
+	if m.keys != nil {
+		if key := m.keys.TenantKey(tenantID); key != nil {
+			return *key
+		}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.defaultKey
+}
+
+// UserClaims holds the user details embedded in an access token.
+type UserClaims struct {
+	UserID    int64
+	Email     string
+	Username  string // empty when the user has none
+	FirstName string
+	LastName  string
+	Role      string
+	Locale    string // preferred language; empty when the user has none saved
+
+	// OrgID scopes the token to one organization; zero for personal tokens.
+	// OrgRole is the user's role in that organization.
+	OrgID   int64
+	OrgRole string
+
+	// TenantID is the tenant whose user pool the user belongs to. Tokens are
+	// only accepted for requests resolved to the same tenant.
+	TenantID int64
+
+	// UserMetadata and AppMetadata are added as claims when non-empty.
+	UserMetadata map[string]interface{}
+	AppMetadata  map[string]interface{}
+
+	// SudoUntil grants the "sudo" scope, required by admin operations, until
+	// that time ("sudo_exp" claim). Zero for regular tokens; only set right
+	// after the user re-authenticated.
+	SudoUntil time.Time
+
+	// Version is the user's token version ("ver" claim): tokens carrying an
+	// older one were issued before the user's privileges changed.
+	Version int
+	// AuthTime is when the user authenticated ("auth_time" claim); it
+	// survives refreshes. Omitted when zero.
+	AuthTime time.Time
+}
+
+// GenerateToken creates a new JWT access token with the specified user claims,
+// signed with the key of the user's tenant.
+func (m *Manager) GenerateToken(user UserClaims) (string, error) {
+	key := m.Key(user.TenantID)
+
 	// Define the token's payload (claims). 'exp' is the standard expiration time claim.
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"first_name": firstName,  // Change from "name" to "first_name"
-            "last_name":  lastName, 
-		"name":    firstName + " " + lastName,
-		// Token expires 24 hours from creation, represented as a Unix timestamp
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"user_id":    user.UserID,
+		"email":      user.Email,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"name":       user.FirstName + " " + user.LastName,
+		"role":       user.Role,
+		// Token expires after the tenant's access token TTL, as a Unix timestamp
+		"exp": time.Now().Add(key.AccessTTL).Unix(),
+		"iat": time.Now().Unix(),
+		"ver": user.Version,
+		// Token ID, under which the token can be revoked
+		"jti": newTokenID(),
+	}
+	if !user.AuthTime.IsZero() {
+		claims["auth_time"] = user.AuthTime.Unix()
+	}
+	if key.Issuer != "" {
+		claims["iss"] = key.Issuer
+	}
+	if key.Audience != "" {
+		claims["aud"] = key.Audience
+	}
+	if user.Username != "" {
+		claims["username"] = user.Username
+	}
+	if user.Locale != "" {
+		claims["locale"] = user.Locale
+	}
+	if user.OrgID != 0 {
+		claims["org_id"] = user.OrgID
+		claims["org_role"] = user.OrgRole
+	}
+	if user.TenantID != 0 {
+		claims["tenant_id"] = user.TenantID
+	}
+	if len(user.UserMetadata) > 0 {
+		claims["user_metadata"] = user.UserMetadata
+	}
+	if len(user.AppMetadata) > 0 {
+		claims["app_metadata"] = user.AppMetadata
+	}
+	if !user.SudoUntil.IsZero() {
+		claims["scope"] = ScopeSudo
+		claims["sudo_exp"] = user.SudoUntil.Unix()
 	}
 
-	// Create the token object, specifying the signing method (HS256) and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign the token using the secret key
-	return token.SignedString([]byte(m.secretKey))
+	// Sign the token (HS256) using the tenant's current secret key
+	return m.sign(key, claims)
 }
 
-// VerifyToken parses, validates, and returns the claims from a given token string.
+// VerifyToken parses, validates, and returns the claims from a given token
+// string signed with the default key.
 func (m *Manager) VerifyToken(tokenString string) (jwt.MapClaims, error) {
+	return m.VerifyTenantToken(tokenString, 0)
+}
+
+// VerifyTenantToken parses, validates, and returns the claims from a token
+// string signed with the given tenant's key. Tokens issued for any other
+// tenant fail signature (and, when set, issuer) verification; tokens minted
+// for another environment fail issuer or audience verification.
+func (m *Manager) VerifyTenantToken(tokenString string, tenantID int64) (jwt.MapClaims, error) {
+	key := m.Key(tenantID)
+
+	opts := m.parserOptions()
+	if key.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(key.Issuer))
+	}
+	if key.Audience != "" {
+		opts = append(opts, jwt.WithAudience(key.Audience))
+	}
+
 	// Parse the token. The keyFunc is called during parsing to get the secret key
-	// needed to verify the token's signature.
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// SECURITY CHECK: Ensure the token's signing method is what we expect (HS256)
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		// Return the secret key used for verification
-		return []byte(m.secretKey), nil
-	})
+	// named by the token's "kid" header, needed to verify its signature.
+	token, err := jwt.Parse(tokenString, m.keyFunc(key), opts...)
 
 	if err != nil {
 		// Handles errors like 'token is expired' or 'invalid signature'
@@ -68,5 +218,53 @@ func (m *Manager) VerifyToken(tokenString string) (jwt.MapClaims, error) {
 		return nil, errors.New("invalid token claims format")
 	}
 
+	// Challenge and download tokens are signed with the same key but only
+	// complete a login or download a file
+	if hasAudience(claims, ChallengeAudience) || hasAudience(claims, DownloadAudience) {
+		return nil, errors.New("challenge and download tokens are not access tokens")
+	}
+
 	return claims, nil
 }
+
+// ClaimedVersion returns the token version of an access token's claims, 0
+// for tokens issued before versions were added.
+func ClaimedVersion(claims jwt.MapClaims) int {
+	version, _ := claims["ver"].(float64)
+	return int(version)
+}
+
+// TokenID returns the ID ("jti") of an access token's claims, empty for
+// tokens issued before IDs were added.
+func TokenID(claims jwt.MapClaims) string {
+	id, _ := claims["jti"].(string)
+	return id
+}
+
+// UnverifiedTokenID returns the ID ("jti") of tokenString without verifying
+// it, to look it up in the revocation list before the token is verified.
+// The ID is "" for tokens issued before IDs were added; ok is false for
+// malformed tokens.
+func UnverifiedTokenID(tokenString string) (id string, ok bool) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return "", false
+	}
+	return TokenID(claims), true
+}
+
+// newTokenID returns a random access token ID.
+func newTokenID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // Should never happen with proper system entropy
+	}
+	return hex.EncodeToString(b)
+}
+
+// IsForeignToken reports whether err rejected a token whose issuer or
+// audience belongs to another deployment, e.g. a staging token sent to
+// production.
+func IsForeignToken(err error) bool {
+	return errors.Is(err, jwt.ErrTokenInvalidIssuer) || errors.Is(err, jwt.ErrTokenInvalidAudience)
+}
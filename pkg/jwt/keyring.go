@@ -0,0 +1,68 @@
+package jwt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RetiredKey is a secret that signed tokens before the current one replaced
+// it. Tokens carrying its "kid" keep verifying until they have expired.
+type RetiredKey struct {
+	ID        string // "kid" header; empty for the key of tokens issued without one
+	Secret    string
+	RetiredAt time.Time // when it stopped signing; zero keeps it until removed
+}
+
+// sign signs claims with the key's current secret, naming it in the "kid"
+// header when it has an ID.
+func (m *Manager) sign(key Key, claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if key.KeyID != "" {
+		token.Header["kid"] = key.KeyID
+	}
+	return token.SignedString([]byte(key.Secret))
+}
+
+// keyFunc returns the jwt.Keyfunc verifying HS256 tokens signed with the
+// key's current secret or one of its retired secrets still in its phase-out
+// window. Retired secrets sharing a "kid" (such as previous secrets of
+// deployments that don't name their keys) are tried in turn.
+func (m *Manager) keyFunc(key Key) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		// SECURITY CHECK: Ensure the token's signing method is what we expect (HS256)
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		var secrets []jwt.VerificationKey
+		if kid == key.KeyID {
+			secrets = append(secrets, []byte(key.Secret))
+		}
+		phasedOut := false
+		for _, retired := range key.Retired {
+			if retired.ID != kid {
+				continue
+			}
+			// Every token it signed has expired once an access token lifetime
+			// has passed since the rotation
+			if !retired.RetiredAt.IsZero() && time.Since(retired.RetiredAt) > key.AccessTTL+m.leeway {
+				phasedOut = true
+				continue
+			}
+			secrets = append(secrets, []byte(retired.Secret))
+		}
+
+		switch {
+		case len(secrets) == 1:
+			return secrets[0], nil
+		case len(secrets) > 1:
+			return jwt.VerificationKeySet{Keys: secrets}, nil
+		case phasedOut:
+			return nil, errors.New("signing key has been phased out")
+		}
+		return nil, errors.New("unknown signing key")
+	}
+}
@@ -0,0 +1,190 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ProviderKey is the RSA key used when Authentio acts as an OpenID Connect
+// provider. ID tokens and access tokens issued to registered clients are
+// signed with it (RS256) so clients can verify them with the public key
+// published by the JWKS endpoint. Tokens for the first-party API keep using
+// the HMAC tenant keys.
+type ProviderKey struct {
+	ID         string // "kid" header; the RFC 7638 thumbprint of the public key
+	PrivateKey *rsa.PrivateKey
+}
+
+// NewProviderKey wraps an RSA private key, deriving its key ID.
+func NewProviderKey(privateKey *rsa.PrivateKey) *ProviderKey {
+	return &ProviderKey{ID: thumbprint(&privateKey.PublicKey), PrivateKey: privateKey}
+}
+
+// LoadProviderKey reads a PEM-encoded RSA private key (PKCS#1 or PKCS#8).
+func LoadProviderKey(path string) (*ProviderKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return NewProviderKey(key), nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return NewProviderKey(key), nil
+}
+
+// GenerateProviderKey creates a random 2048-bit key. Tokens signed with it
+// can't be verified after a restart, so it is only meant for development.
+func GenerateProviderKey() (*ProviderKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return NewProviderKey(key), nil
+}
+
+// SetProviderKey enables signing of OpenID Connect tokens. After a rotation,
+// previous keys keep verifying the tokens they signed and stay published in
+// the JWKS, so clients holding such tokens can still verify them. It is safe
+// to call while the server handles requests.
+func (m *Manager) SetProviderKey(key *ProviderKey, previous ...*ProviderKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providerKey = key
+	m.previousProviderKeys = previous
+}
+
+// providerKeys returns the provider key, followed by the previous ones.
+func (m *Manager) providerKeys() []*ProviderKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.providerKey == nil {
+		return nil
+	}
+	return append([]*ProviderKey{m.providerKey}, m.previousProviderKeys...)
+}
+
+// SignProviderToken signs claims with the provider key (RS256).
+func (m *Manager) SignProviderToken(claims jwt.MapClaims) (string, error) {
+	keys := m.providerKeys()
+	if len(keys) == 0 {
+		return "", errors.New("no provider key configured")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keys[0].ID
+	return token.SignedString(keys[0].PrivateKey)
+}
+
+// VerifyProviderToken parses and validates a token signed with the provider
+// key and issued by issuer.
+func (m *Manager) VerifyProviderToken(tokenString, issuer string) (jwt.MapClaims, error) {
+	keys := m.providerKeys()
+	if len(keys) == 0 {
+		return nil, errors.New("no provider key configured")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		// Only RS256 tokens from this provider; never the HMAC API tokens
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		kid, _ := token.Header["kid"].(string)
+		for _, key := range keys {
+			if key.ID == kid {
+				return &key.PrivateKey.PublicKey, nil
+			}
+		}
+		return nil, errors.New("unknown signing key")
+	}, m.parserOptions(jwt.WithIssuer(issuer), jwt.WithExpirationRequired())...)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// JWKS returns the public keys clients verify provider tokens with, current
+// first. It is empty when no provider key is configured.
+func (m *Manager) JWKS() []JWK {
+	keys := m.providerKeys()
+	jwks := make([]JWK, 0, len(keys))
+	for _, key := range keys {
+		public := &key.PrivateKey.PublicKey
+		jwks = append(jwks, JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: "RS256",
+			KeyID:     key.ID,
+			Modulus:   encodeBigInt(public.N),
+			Exponent:  encodeBigInt(big.NewInt(int64(public.E))),
+		})
+	}
+	return jwks
+}
+
+// EncodeProviderKey encodes key's private key as PEM (PKCS#1), the format
+// read by LoadProviderKey.
+func EncodeProviderKey(key *ProviderKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key.PrivateKey)})
+}
+
+// ParseProviderKey decodes a key encoded by EncodeProviderKey.
+func ParseProviderKey(data []byte) (*ProviderKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return NewProviderKey(key), nil
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint of an RSA public key.
+func thumbprint(key *rsa.PublicKey) string {
+	// Required members in lexicographic order, no whitespace
+	canonical := `{"e":"` + encodeBigInt(big.NewInt(int64(key.E))) + `","kty":"RSA","n":"` + encodeBigInt(key.N) + `"}`
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// encodeBigInt encodes an integer as unpadded base64url big-endian bytes.
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
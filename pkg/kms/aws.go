@@ -0,0 +1,171 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// awsKMS calls the AWS KMS JSON API, signing requests with Signature V4.
+// Credentials come from the environment (AWS_ACCESS_KEY_ID...), the shared
+// credentials file, or the instance, task or pod role.
+type awsKMS struct {
+	keyID    string
+	region   string
+	endpoint string
+	creds    *credentials.Credentials
+	client   *http.Client
+}
+
+// newAWS creates the AWS KMS client of cfg.
+func newAWS(cfg Config) (*awsKMS, error) {
+	region := cfg.AWSRegion
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, errors.New("kms: the AWS region is required")
+	}
+
+	return &awsKMS{
+		keyID:    cfg.KeyID,
+		region:   region,
+		endpoint: "https://kms." + region + ".amazonaws.com/",
+		creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.FileAWSCredentials{},
+			&credentials.IAM{},
+		}),
+		client: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func (a *awsKMS) encrypt(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	var resp struct {
+		CiphertextBlob []byte
+		KeyId          string
+	}
+	if err := a.call(ctx, "Encrypt", map[string]interface{}{"KeyId": a.keyID, "Plaintext": plaintext}, &resp); err != nil {
+		return nil, "", err
+	}
+	// AWS rotates key material transparently; the version is the key itself
+	// (arn:aws:kms:REGION:ACCOUNT:key/ID), which changes when KMS_KEY_ID
+	// points to another key
+	version := resp.KeyId[strings.LastIndex(resp.KeyId, "/")+1:]
+	return resp.CiphertextBlob, version, nil
+}
+
+func (a *awsKMS) decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte
+	}
+	if err := a.call(ctx, "Decrypt", map[string]interface{}{"CiphertextBlob": ciphertext}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintext, nil
+}
+
+// call sends a signed request for action and decodes the response into out.
+// []byte fields are base64 in JSON, as the API expects.
+func (a *awsKMS) call(ctx context.Context, action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+
+	creds, err := a.creds.Get()
+	if err != nil {
+		return fmt.Errorf("no AWS credentials: %w", err)
+	}
+	signAWSRequest(req, body, creds, a.region, time.Now().UTC())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("%s (HTTP %d): %s", apiErr.Type, resp.StatusCode, apiErr.Message)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// signAWSRequest adds the Signature V4 headers of the kms service to req.
+func signAWSRequest(req *http.Request, body []byte, creds credentials.Value, region string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Canonical request over every header set above
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method, "/", "", canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/kms/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
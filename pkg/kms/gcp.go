@@ -0,0 +1,52 @@
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	cloudkms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// gcpKMS calls Google Cloud KMS with Application Default Credentials
+// (GOOGLE_APPLICATION_CREDENTIALS, or the workload's service account).
+type gcpKMS struct {
+	keyName string
+	keys    *cloudkms.ProjectsLocationsKeyRingsCryptoKeysService
+}
+
+// newGCP creates the Cloud KMS client of cfg.
+func newGCP(ctx context.Context, cfg Config) (*gcpKMS, error) {
+	svc, err := cloudkms.NewService(ctx, option.WithScopes(cloudkms.CloudkmsScope))
+	if err != nil {
+		return nil, err
+	}
+	return &gcpKMS{keyName: cfg.KeyID, keys: svc.Projects.Locations.KeyRings.CryptoKeys}, nil
+}
+
+func (g *gcpKMS) encrypt(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	resp, err := g.keys.Encrypt(g.keyName, &cloudkms.EncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(plaintext),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(resp.Ciphertext)
+	if err != nil {
+		return nil, "", err
+	}
+	// resp.Name is the key version used: .../cryptoKeyVersions/N
+	return ciphertext, "v" + resp.Name[strings.LastIndex(resp.Name, "/")+1:], nil
+}
+
+func (g *gcpKMS) decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	// The ciphertext names its key version, so older versions still decrypt
+	resp, err := g.keys.Decrypt(g.keyName, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
@@ -0,0 +1,227 @@
+// Package kms protects key material with a key management service: AWS KMS,
+// Google Cloud KMS or the transit secrets engine of HashiCorp Vault. The
+// service's key never leaves it; it wraps (encrypts) the data keys used
+// locally and unwraps them on demand. Unwrapped keys are cached for a while
+// so that each use doesn't cost a round trip.
+package kms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported services (KMS_PROVIDER).
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderVault = "vault"
+)
+
+// referencePrefix marks settings holding key material wrapped by the service.
+const referencePrefix = "kms:"
+
+const (
+	// dataKeySize is the length of the data keys generated by DataKey (AES-256)
+	dataKeySize = 32
+	// maxCachedKeys bounds the cache of unwrapped keys
+	maxCachedKeys = 1024
+	// requestTimeout bounds each call to the service
+	requestTimeout = 10 * time.Second
+)
+
+// Config selects the service and its key.
+type Config struct {
+	Provider string // aws, gcp or vault
+	// KeyID is the key at the service: an AWS key ID, ARN or alias, a GCP
+	// CryptoKey resource name (projects/.../cryptoKeys/NAME), or the name of
+	// a Vault transit key.
+	KeyID string
+
+	AWSRegion string // AWS region of the key
+
+	VaultAddr  string // e.g. https://vault.example.com:8200
+	VaultToken string
+	VaultMount string // mount path of the transit engine
+
+	// CacheTTL is how long unwrapped keys are kept in memory, and how long a
+	// data key is reused for new values before another is generated.
+	CacheTTL time.Duration
+}
+
+// service is the encrypt / decrypt API of a key management service.
+type service interface {
+	// encrypt encrypts plaintext with the current version of the key and
+	// returns that version.
+	encrypt(ctx context.Context, plaintext []byte) (ciphertext []byte, version string, err error)
+	// decrypt decrypts a ciphertext made with any version of the key.
+	decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// Keys wraps and unwraps keys with a key management service. It is safe for
+// concurrent use.
+type Keys struct {
+	name    string
+	service service
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	current *dataKey             // data key handed out by DataKey
+	cache   map[string]cachedKey // unwrapped keys by wrapped key
+}
+
+// dataKey is a data key with its wrapped form.
+type dataKey struct {
+	plain     []byte
+	wrapped   []byte
+	version   string
+	expiresAt time.Time
+}
+
+// cachedKey is an unwrapped key.
+type cachedKey struct {
+	plain     []byte
+	expiresAt time.Time
+}
+
+// New connects to the service selected by cfg.
+func New(ctx context.Context, cfg Config) (*Keys, error) {
+	if cfg.KeyID == "" {
+		return nil, errors.New("kms: key id is required")
+	}
+
+	var svc service
+	var err error
+	switch cfg.Provider {
+	case ProviderAWS:
+		svc, err = newAWS(cfg)
+	case ProviderGCP:
+		svc, err = newGCP(ctx, cfg)
+	case ProviderVault:
+		svc, err = newVault(cfg)
+	default:
+		return nil, fmt.Errorf("kms: unknown provider %q", cfg.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keys{name: cfg.Provider, service: svc, ttl: cfg.CacheTTL, cache: make(map[string]cachedKey)}, nil
+}
+
+// Name returns the provider, e.g. "aws".
+func (k *Keys) Name() string {
+	return k.name
+}
+
+// DataKey returns a random data key, wrapped by the service, and the version
+// of the service key that wrapped it. The same data key is returned until
+// the cache TTL elapses, so the service is called at most once per TTL.
+func (k *Keys) DataKey() (plain, wrapped []byte, version string, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.current != nil && time.Now().Before(k.current.expiresAt) {
+		return k.current.plain, k.current.wrapped, k.current.version, nil
+	}
+
+	plain = make([]byte, dataKeySize)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, nil, "", err
+	}
+	wrapped, version, err = k.Wrap(plain)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	k.current = &dataKey{plain: plain, wrapped: wrapped, version: version, expiresAt: time.Now().Add(k.ttl)}
+	return plain, wrapped, version, nil
+}
+
+// Wrap encrypts key with the current version of the service key, which is
+// returned.
+func (k *Keys) Wrap(key []byte) ([]byte, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	wrapped, version, err := k.service.encrypt(ctx, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("kms: %s encrypt: %w", k.name, err)
+	}
+	return wrapped, version, nil
+}
+
+// Unwrap decrypts a key wrapped by any version of the service key. Results
+// are cached.
+func (k *Keys) Unwrap(wrapped []byte) ([]byte, error) {
+	cacheKey := string(wrapped)
+	k.mu.Lock()
+	cached, ok := k.cache[cacheKey]
+	k.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.plain, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	plain, err := k.service.decrypt(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("kms: %s decrypt: %w", k.name, err)
+	}
+
+	k.mu.Lock()
+	if len(k.cache) >= maxCachedKeys {
+		k.evictLocked()
+	}
+	k.cache[cacheKey] = cachedKey{plain: plain, expiresAt: time.Now().Add(k.ttl)}
+	k.mu.Unlock()
+	return plain, nil
+}
+
+// evictLocked removes expired keys, then arbitrary ones until the cache is
+// at most half full.
+func (k *Keys) evictLocked() {
+	now := time.Now()
+	for key, cached := range k.cache {
+		if now.After(cached.expiresAt) {
+			delete(k.cache, key)
+		}
+	}
+	for key := range k.cache {
+		if len(k.cache) < maxCachedKeys/2 {
+			break
+		}
+		delete(k.cache, key)
+	}
+}
+
+// IsReference reports whether a setting holds key material wrapped by the
+// service: "kms:<base64 ciphertext>", or "kms:vault:v1:..." with Vault.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, referencePrefix)
+}
+
+// Resolve returns the key material of a setting wrapped by the service (see
+// IsReference), or value itself for other settings.
+func (k *Keys) Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	// Vault ciphertexts are text already
+	wrapped := []byte(strings.TrimPrefix(value, referencePrefix))
+	if !strings.HasPrefix(string(wrapped), vaultCiphertextPrefix) {
+		var err error
+		if wrapped, err = base64.StdEncoding.DecodeString(string(wrapped)); err != nil {
+			return "", errors.New("kms: reference is not valid base64")
+		}
+	}
+	plain, err := k.Unwrap(wrapped)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,105 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// vaultCiphertextPrefix starts the ciphertexts of the transit engine, which
+// are followed by the key version: "vault:v3:...".
+const vaultCiphertextPrefix = "vault:"
+
+// vaultTransit calls the transit secrets engine of HashiCorp Vault.
+type vaultTransit struct {
+	addr   string
+	token  string
+	mount  string
+	key    string
+	client *http.Client
+}
+
+// newVault creates the Vault transit client of cfg.
+func newVault(cfg Config) (*vaultTransit, error) {
+	if cfg.VaultAddr == "" || cfg.VaultToken == "" {
+		return nil, errors.New("kms: the Vault address and token are required")
+	}
+	mount := strings.Trim(cfg.VaultMount, "/")
+	if mount == "" {
+		mount = "transit"
+	}
+	return &vaultTransit{
+		addr:   strings.TrimRight(cfg.VaultAddr, "/"),
+		token:  cfg.VaultToken,
+		mount:  mount,
+		key:    cfg.KeyID,
+		client: &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func (v *vaultTransit) encrypt(ctx context.Context, plaintext []byte) ([]byte, string, error) {
+	var resp struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := v.call(ctx, "encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, &resp); err != nil {
+		return nil, "", err
+	}
+	parts := strings.SplitN(resp.Ciphertext, ":", 3)
+	if len(parts) != 3 || parts[0]+":" != vaultCiphertextPrefix {
+		return nil, "", errors.New("unexpected ciphertext format")
+	}
+	return []byte(resp.Ciphertext), parts[1], nil
+}
+
+func (v *vaultTransit) decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := v.call(ctx, "decrypt", map[string]string{"ciphertext": string(ciphertext)}, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
+
+// call posts in to the operation endpoint of the key and decodes the data of
+// the response into out.
+func (v *vaultTransit) call(ctx context.Context, operation string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/v1/%s/%s/%s", v.addr, v.mount, operation, url.PathEscape(v.key))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	var envelope struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	_ = json.Unmarshal(data, &envelope)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.Join(envelope.Errors, "; "))
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
@@ -0,0 +1,141 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Election elects, among the instances campaigning for it, the one leader
+// that holds the lock name. The leader keeps extending its lock; when it
+// dies, the lock expires and another instance takes over within the TTL.
+// A nil *Election (no Redis, a single server in development) always leads.
+// It is safe for concurrent use.
+type Election struct {
+	locker *Locker
+	name   string
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	held  *Lock     // nil while another instance leads
+	until time.Time // leadership is assumed lost past this without a refresh
+}
+
+// Election creates the election of the leader holding the lock name for
+// ttl at a time. A nil Locker returns a nil Election.
+func (l *Locker) Election(name string, ttl time.Duration) *Election {
+	if l == nil {
+		return nil
+	}
+	return &Election{locker: l, name: name, ttl: ttl}
+}
+
+// Campaign runs for leader now, so a leader is elected by the time it
+// returns, then every third of the TTL until ctx is cancelled: the leader
+// extends its lock, the others try to take it over. On cancellation the
+// leader steps down, handing over at once.
+func (e *Election) Campaign(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	e.campaign(ctx)
+
+	go func() {
+		ticker := time.NewTicker(e.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				e.stepDown(context.WithoutCancel(ctx))
+				return
+			case <-ticker.C:
+			}
+			e.campaign(ctx)
+		}
+	}()
+}
+
+// IsLeader reports whether this instance leads.
+func (e *Election) IsLeader() bool {
+	if e == nil {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.held != nil && time.Now().Before(e.until)
+}
+
+// Leader returns the instance leading and when its lease expires unless it
+// is extended, or "" while there is no leader.
+func (e *Election) Leader(ctx context.Context) (string, time.Time, error) {
+	return e.locker.Holder(ctx, e.name)
+}
+
+// Instance names this instance, as Leader would.
+func (e *Election) Instance() string {
+	return e.locker.Instance()
+}
+
+// campaign extends the lock of the leader, or tries to acquire it.
+func (e *Election) campaign(ctx context.Context) {
+	e.mu.Lock()
+	held := e.held
+	e.mu.Unlock()
+
+	start := time.Now()
+	if held != nil {
+		err := held.Refresh(ctx)
+		switch {
+		case err == nil:
+			e.mu.Lock()
+			e.until = start.Add(e.ttl)
+			e.mu.Unlock()
+		case errors.Is(err, ErrNotHeld):
+			logger.Warn("lost leadership", "election", e.name, "instance", e.Instance())
+			e.mu.Lock()
+			e.held = nil
+			e.mu.Unlock()
+		case ctx.Err() == nil:
+			// Still leading until the lease runs out, unless Redis is back
+			// before then
+			logger.Warn("failed to extend leadership", "election", e.name, "error", err)
+		}
+		return
+	}
+
+	acquired, err := e.locker.Acquire(ctx, e.name, e.ttl)
+	if errors.Is(err, ErrLocked) {
+		return
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("failed to run for leader", "election", e.name, "error", err)
+		}
+		return
+	}
+	e.mu.Lock()
+	e.held, e.until = acquired, start.Add(e.ttl)
+	e.mu.Unlock()
+	logger.Info("elected leader", "election", e.name, "instance", e.Instance())
+}
+
+// stepDown releases the lock of the leader.
+func (e *Election) stepDown(ctx context.Context) {
+	e.mu.Lock()
+	held := e.held
+	e.held = nil
+	e.mu.Unlock()
+	if held == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := held.Release(ctx); err != nil && !errors.Is(err, ErrNotHeld) {
+		logger.Warn("failed to step down", "election", e.name, "error", err)
+	}
+}
@@ -0,0 +1,203 @@
+// Package lock provides Redis locks coordinating maintenance work (scans,
+// reminders, re-encryption, key rotations, email retries) across server
+// instances, and the election of the instance running the scheduler. A lock
+// is a key set with SET NX PX to a token naming the instance plus a random
+// part; only the holder of the token can extend or release it, so a holder
+// that overran its TTL can't release a lock another instance has since
+// acquired.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "lock:"
+
+// ErrLocked is returned by Acquire while another holder has the lock.
+var ErrLocked = errors.New("lock is held by another holder")
+
+// ErrNotHeld is returned by Refresh and Release once the lock expired and
+// may have been acquired by another holder.
+var ErrNotHeld = errors.New("lock is no longer held")
+
+// releaseScript deletes the lock only if it still holds the caller's token.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// refreshScript resets the TTL (ms) of the lock only if it still holds the
+// caller's token.
+var refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// Locker acquires locks. A nil *Locker (no Redis, a single server in
+// development) runs everything as if it held every lock. It is safe for
+// concurrent use.
+type Locker struct {
+	redis    *redis.Client
+	instance string
+}
+
+// NewLocker creates a locker keeping its locks in Redis. Its locks are held
+// in the name of this instance: host name and process ID.
+func NewLocker(redisClient *redis.Client) *Locker {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "authentio"
+	}
+	return &Locker{redis: redisClient, instance: fmt.Sprintf("%s-%d", host, os.Getpid())}
+}
+
+// Instance names this instance in the locks it holds.
+func (l *Locker) Instance() string {
+	return l.instance
+}
+
+// Holder returns the instance holding the lock name and when the lock
+// expires, or "" while nobody holds it.
+func (l *Locker) Holder(ctx context.Context, name string) (string, time.Time, error) {
+	token, err := l.redis.Get(ctx, keyPrefix+name).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	ttl, err := l.redis.PTTL(ctx, keyPrefix+name).Result()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	instance, _, _ := strings.Cut(token, "/")
+	return instance, time.Now().Add(ttl), nil
+}
+
+// Lock is a held lock, until it expires or is released.
+type Lock struct {
+	redis *redis.Client
+	key   string
+	token string
+	ttl   time.Duration
+}
+
+// Acquire takes the lock name for ttl, or returns ErrLocked while another
+// holder has it.
+func (l *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	token, err := l.newToken()
+	if err != nil {
+		return nil, err
+	}
+	acquired, err := l.redis.SetNX(ctx, keyPrefix+name, token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLocked
+	}
+	return &Lock{redis: l.redis, key: keyPrefix + name, token: token, ttl: ttl}, nil
+}
+
+// Refresh extends the lock for another TTL.
+func (k *Lock) Refresh(ctx context.Context) error {
+	extended, err := refreshScript.Run(ctx, k.redis, []string{k.key}, k.token, k.ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if extended == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Release frees the lock for other holders.
+func (k *Lock) Release(ctx context.Context) error {
+	released, err := releaseScript.Run(ctx, k.redis, []string{k.key}, k.token).Int()
+	if err != nil {
+		return err
+	}
+	if released == 0 {
+		return ErrNotHeld
+	}
+	return nil
+}
+
+// Do runs fn holding the lock name and reports whether it ran: it returns
+// false, without running fn, while another holder has the lock. The lock is
+// extended every third of ttl while fn runs; the context passed to fn is
+// cancelled if the lock is lost, so fn should stop at its next check. A nil
+// Locker runs fn unconditionally.
+func (l *Locker) Do(ctx context.Context, name string, ttl time.Duration, fn func(ctx context.Context)) (bool, error) {
+	if l == nil {
+		fn(ctx)
+		return true, nil
+	}
+
+	held, err := l.Acquire(ctx, name, ttl)
+	if errors.Is(err, ErrLocked) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer cancel()
+
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := held.Refresh(jobCtx); err != nil && jobCtx.Err() == nil {
+				logger.Warn("lost lock, stopping its job", "lock", name, "error", err)
+				return
+			}
+		}
+	}()
+
+	fn(jobCtx)
+	close(done)
+	cancel()
+
+	// Released even if ctx was cancelled (shutdown), so other instances
+	// don't wait for the TTL
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancelRelease()
+	if err := held.Release(releaseCtx); err != nil && !errors.Is(err, ErrNotHeld) {
+		logger.Warn("failed to release lock", "lock", name, "error", err)
+	}
+	return true, nil
+}
+
+// newToken returns a token identifying one acquisition of a lock by this
+// instance: "<instance>/<random>".
+func (l *Locker) newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return l.instance + "/" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"authentio/pkg/retry"
+)
+
+// Webhook formats
+const (
+	FormatSlack     = "slack"     // Slack incoming webhook
+	FormatPagerDuty = "pagerduty" // PagerDuty Events API v2
+)
+
+// Alert reports a security event that happened Count times within Window,
+// reaching its Threshold.
+type Alert struct {
+	Metric      string // e.g. authentio_failed_logins_total
+	Description string // e.g. "failed logins"
+	Count       int64
+	Threshold   int64
+	Window      time.Duration
+	At          time.Time
+}
+
+// Summary describes the alert in one line.
+func (a Alert) Summary() string {
+	return fmt.Sprintf("Authentio security alert: %d %s in the last %s (threshold %d)", a.Count, a.Description, a.Window, a.Threshold)
+}
+
+// Notifier delivers alerts, e.g. to a chat channel or an on-call service.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Webhook posts alerts to a Slack incoming webhook or the PagerDuty Events
+// API.
+type Webhook struct {
+	client     *http.Client
+	retries    *retry.Retrier
+	url        string
+	format     string
+	routingKey string // PagerDuty integration key
+	source     string // instance named in PagerDuty events
+}
+
+// NewWebhook creates a notifier posting to url in format (FormatSlack or
+// FormatPagerDuty). PagerDuty events are routed with routingKey. Posts
+// failing for transient reasons are retried by retries (nil = never).
+func NewWebhook(url, format, routingKey string, retries *retry.Retrier) *Webhook {
+	source, err := os.Hostname()
+	if err != nil {
+		source = "authentio"
+	}
+	return &Webhook{
+		client:     &http.Client{Timeout: notifyTimeout},
+		retries:    retries,
+		url:        url,
+		format:     format,
+		routingKey: routingKey,
+		source:     source,
+	}
+}
+
+// Notify posts alert to the webhook.
+func (w *Webhook) Notify(ctx context.Context, alert Alert) error {
+	var payload interface{}
+	if w.format == FormatPagerDuty {
+		payload = map[string]interface{}{
+			"routing_key":  w.routingKey,
+			"event_action": "trigger",
+			// Alerts of the same metric are grouped into one incident
+			"dedup_key": "authentio:" + w.source + ":" + alert.Metric,
+			"payload": map[string]interface{}{
+				"summary":   alert.Summary(),
+				"source":    w.source,
+				"severity":  "warning",
+				"timestamp": alert.At.Format(time.RFC3339),
+				"component": "authentio",
+				"class":     alert.Metric,
+				"custom_details": map[string]interface{}{
+					"count":     alert.Count,
+					"threshold": alert.Threshold,
+					"window":    alert.Window.String(),
+				},
+			},
+		}
+	} else {
+		payload = map[string]string{"text": alert.Summary() + " on " + w.source}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return w.retries.Do(ctx, func(ctx context.Context) error {
+		return w.post(ctx, body)
+	}, retryableWebhookError)
+}
+
+// webhookStatusError is a post the webhook answered with an error status.
+type webhookStatusError struct {
+	status string
+	code   int
+}
+
+func (e *webhookStatusError) Error() string {
+	return "alert webhook returned " + e.status
+}
+
+// post sends body to the webhook once.
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &webhookStatusError{status: resp.Status, code: resp.StatusCode}
+	}
+	return nil
+}
+
+// retryableWebhookError reports whether a post may succeed when sent again:
+// the webhook was unreachable, overloaded or failing.
+func retryableWebhookError(err error) bool {
+	var status *webhookStatusError
+	if errors.As(err, &status) {
+		return status.code >= http.StatusInternalServerError || status.code == http.StatusTooManyRequests
+	}
+	return true
+}
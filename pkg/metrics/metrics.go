@@ -0,0 +1,218 @@
+// Package metrics counts security events (failed logins, lockouts, revoked
+// token use, blocked-country requests, one-time code failures) in memory and
+// serves them in the Prometheus text format. Each counter can raise an alert
+// through a Notifier when its event happens a threshold number of times
+// within a sliding window. It also serves the durations of database queries,
+// the stats of the database and Redis connection pools, the rows deleted by
+// data retention and how access token revocation checks were answered.
+//
+// Counts are kept per instance: Prometheus sums them across instances, but
+// thresholds apply to each instance's own events.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"authentio/pkg/logger"
+	"authentio/pkg/retry"
+)
+
+const (
+	// windowBuckets is how many slices the alert window is counted in; the
+	// window slides by one slice at a time
+	windowBuckets = 10
+
+	// defaultWindow is the alert window until Configure sets one
+	defaultWindow = 5 * time.Minute
+
+	// notifyTimeout bounds the delivery of one alert
+	notifyTimeout = 10 * time.Second
+)
+
+// Security event counters.
+var (
+	FailedLogins     = newCounter("authentio_failed_logins_total", "failed logins", "Failed sign-in attempts.")
+	Lockouts         = newCounter("authentio_lockouts_total", "lockouts", "Sign-ins refused to locked accounts (registration pending or rejected, too risky, outside the IP allowlist).")
+	RevokedTokens    = newCounter("authentio_revoked_token_uses_total", "revoked token uses", "Requests made with a revoked access token.")
+	BlockedCountries = newCounter("authentio_blocked_country_requests_total", "blocked-country requests", "Authenticated requests refused from a blocked country.")
+	OTPFailures      = newCounter("authentio_otp_failures_total", "one-time code failures", "Invalid or expired one-time codes, including 2FA recovery codes.")
+)
+
+// counters lists every counter in exposition order.
+var counters = []*Counter{FailedLogins, Lockouts, RevokedTokens, BlockedCountries, OTPFailures}
+
+// alerts holds the window and notifier set by Configure.
+var alerts struct {
+	mu       sync.RWMutex
+	window   time.Duration
+	notifier Notifier
+}
+
+func init() {
+	alerts.window = defaultWindow
+}
+
+// Configure sets the sliding window thresholds are counted over and where
+// alerts are sent (nil disables alerts). Call it, and SetThreshold, before
+// the server starts handling requests.
+func Configure(window time.Duration, notifier Notifier) {
+	alerts.mu.Lock()
+	defer alerts.mu.Unlock()
+	alerts.window = window
+	alerts.notifier = notifier
+}
+
+// Counter counts one kind of security event since the process started, and
+// within the alert window.
+type Counter struct {
+	name        string // Prometheus metric name
+	description string // plural noun used in alerts
+	help        string
+
+	total atomic.Int64
+
+	mu        sync.Mutex
+	buckets   [windowBuckets]bucket
+	threshold int64
+	alertedAt time.Time
+}
+
+// bucket counts the events of one slice of the alert window.
+type bucket struct {
+	start time.Time
+	count int64
+}
+
+func newCounter(name, description, help string) *Counter {
+	return &Counter{name: name, description: description, help: help}
+}
+
+// SetThreshold raises an alert whenever the event happens threshold times
+// within the window (0 disables the alert). An alert fires at most once per
+// window.
+func (c *Counter) SetThreshold(threshold int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.threshold = threshold
+}
+
+// Inc records one event.
+func (c *Counter) Inc() {
+	c.total.Add(1)
+
+	alerts.mu.RLock()
+	window, notifier := alerts.window, alerts.notifier
+	alerts.mu.RUnlock()
+
+	now := time.Now()
+	c.mu.Lock()
+	slice := window / windowBuckets
+	start := now.Truncate(slice)
+	b := &c.buckets[(start.UnixNano()/int64(slice))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	b.count++
+
+	var alert *Alert
+	if c.threshold > 0 && notifier != nil && now.Sub(c.alertedAt) >= window {
+		if count := c.countSince(now.Add(-window)); count >= c.threshold {
+			c.alertedAt = now
+			alert = &Alert{
+				Metric:      c.name,
+				Description: c.description,
+				Count:       count,
+				Threshold:   c.threshold,
+				Window:      window,
+				At:          now.UTC(),
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if alert != nil {
+		go notify(notifier, *alert)
+	}
+}
+
+// Total returns the number of events since the process started.
+func (c *Counter) Total() int64 {
+	return c.total.Load()
+}
+
+// Recent returns the number of events within the current alert window.
+func (c *Counter) Recent() int64 {
+	alerts.mu.RLock()
+	window := alerts.window
+	alerts.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.countSince(time.Now().Add(-window))
+}
+
+// countSince sums the buckets started after since. The caller holds c.mu.
+func (c *Counter) countSince(since time.Time) int64 {
+	var count int64
+	for _, b := range c.buckets {
+		if b.start.After(since) {
+			count += b.count
+		}
+	}
+	return count
+}
+
+// notify delivers alert, logging failures.
+func notify(notifier Notifier, alert Alert) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	logger.Warn("security alert", "metric", alert.Metric, "count", alert.Count, "threshold", alert.Threshold, "window", alert.Window.String())
+	if err := notifier.Notify(ctx, alert); err != nil {
+		logger.Error("failed to send security alert", "error", err, "metric", alert.Metric)
+	}
+}
+
+// =============================================================================
+// Prometheus Exposition
+// =============================================================================
+
+// Handler serves every counter, with a gauge of its events within the alert
+// window, in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w)
+	})
+}
+
+// Write writes every counter and window gauge, the attempts and give-ups of
+// calls to third parties, the database query durations, the connection pool
+// stats, the rows purged by data retention and the revocation checks, to w
+// in the Prometheus text format.
+func Write(w io.Writer) {
+	for _, c := range counters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.Total())
+	}
+	for _, c := range counters {
+		gauge := windowGaugeName(c.name)
+		fmt.Fprintf(w, "# HELP %s Number of %s within the alert window.\n# TYPE %s gauge\n%s %d\n", gauge, c.description, gauge, gauge, c.Recent())
+	}
+	retry.WriteMetrics(w)
+	writeQueryMetrics(w)
+	writePoolMetrics(w)
+	writeRetentionMetrics(w)
+	writeRevocationMetrics(w)
+}
+
+// windowGaugeName names the window gauge of a counter: its name with
+// "_window" instead of "_total".
+func windowGaugeName(counter string) string {
+	return counter[:len(counter)-len("_total")] + "_window"
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// pools holds the last sample of the connection pool stats, taken by
+// WatchPools.
+var pools struct {
+	sync.Mutex
+	db    *sql.DBStats
+	redis *redis.PoolStats
+}
+
+// WatchPools samples the connection pool stats of db and redisClient (either
+// may be nil) now and then every interval until ctx is done, for the metrics
+// endpoint. Pools running out of connections are the usual cause of latency
+// spikes at sign-in peaks.
+func WatchPools(ctx context.Context, interval time.Duration, db *sql.DB, redisClient *redis.Client) {
+	samplePools(db, redisClient)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				samplePools(db, redisClient)
+			}
+		}
+	}()
+}
+
+func samplePools(db *sql.DB, redisClient *redis.Client) {
+	pools.Lock()
+	defer pools.Unlock()
+	if db != nil {
+		stats := db.Stats()
+		pools.db = &stats
+	}
+	if redisClient != nil {
+		pools.redis = redisClient.PoolStats()
+	}
+}
+
+// writePoolMetrics writes the last pool stats sampled to w in the Prometheus
+// text format.
+func writePoolMetrics(w io.Writer) {
+	pools.Lock()
+	defer pools.Unlock()
+
+	if db := pools.db; db != nil {
+		writeMetric(w, "authentio_db_connections_open", "gauge", "Open database connections, in use or idle.", db.OpenConnections)
+		writeMetric(w, "authentio_db_connections_in_use", "gauge", "Database connections running a query or transaction.", db.InUse)
+		writeMetric(w, "authentio_db_connections_idle", "gauge", "Idle database connections.", db.Idle)
+		writeMetric(w, "authentio_db_connections_max_open", "gauge", "Maximum open database connections (0 = unlimited).", db.MaxOpenConnections)
+		writeMetric(w, "authentio_db_connection_waits_total", "counter", "Queries that waited for a free database connection.", db.WaitCount)
+		writeMetric(w, "authentio_db_connection_wait_seconds_total", "counter", "Time queries waited for a free database connection.", db.WaitDuration.Seconds())
+	}
+	if rs := pools.redis; rs != nil {
+		writeMetric(w, "authentio_redis_connections_open", "gauge", "Open Redis connections, in use or idle.", rs.TotalConns)
+		writeMetric(w, "authentio_redis_connections_idle", "gauge", "Idle Redis connections.", rs.IdleConns)
+		writeMetric(w, "authentio_redis_pool_hits_total", "counter", "Redis commands that found an idle connection.", rs.Hits)
+		writeMetric(w, "authentio_redis_pool_misses_total", "counter", "Redis commands that had to open a connection.", rs.Misses)
+		writeMetric(w, "authentio_redis_pool_waits_total", "counter", "Redis commands that waited for a free connection.", rs.WaitCount)
+		writeMetric(w, "authentio_redis_pool_wait_seconds_total", "counter", "Time Redis commands waited for a free connection.", time.Duration(rs.WaitDurationNs).Seconds())
+		writeMetric(w, "authentio_redis_pool_timeouts_total", "counter", "Redis commands that gave up waiting for a free connection.", rs.Timeouts)
+	}
+}
+
+// writeMetric writes one unlabelled metric.
+func writeMetric(w io.Writer, name, kind, help string, value any) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// queryBuckets are the upper bounds, in seconds, of the query duration
+// histogram buckets.
+var queryBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// histogram counts the durations of one query.
+type histogram struct {
+	counts []int64 // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+	count  int64
+}
+
+// slowQuery names the slow queries of a tenant.
+type slowQuery struct {
+	query  string
+	tenant int64
+}
+
+var queries = struct {
+	sync.Mutex
+	durations map[string]*histogram
+	slow      map[slowQuery]int64
+}{durations: make(map[string]*histogram), slow: make(map[slowQuery]int64)}
+
+// ObserveQuery records that the database query named query (the repository
+// method making it, e.g. "userRepository.FindByEmail") took d.
+func ObserveQuery(query string, d time.Duration) {
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(queryBuckets, seconds)
+
+	queries.Lock()
+	defer queries.Unlock()
+	h, ok := queries.durations[query]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(queryBuckets)+1)}
+		queries.durations[query] = h
+	}
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+}
+
+// SlowQuery counts a query of tenant slower than the slow-query threshold.
+func SlowQuery(query string, tenant int64) {
+	queries.Lock()
+	defer queries.Unlock()
+	queries.slow[slowQuery{query: query, tenant: tenant}]++
+}
+
+// writeQueryMetrics writes the query duration histograms and the slow-query
+// counts to w in the Prometheus text format.
+func writeQueryMetrics(w io.Writer) {
+	queries.Lock()
+	defer queries.Unlock()
+
+	names := make([]string, 0, len(queries.durations))
+	for name := range queries.durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprint(w, "# HELP authentio_db_query_duration_seconds Duration of database queries, by repository method.\n# TYPE authentio_db_query_duration_seconds histogram\n")
+	for _, name := range names {
+		h := queries.durations[name]
+		var cumulative int64
+		for i, bound := range queryBuckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "authentio_db_query_duration_seconds_bucket{query=%q,le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+		}
+		fmt.Fprintf(w, "authentio_db_query_duration_seconds_bucket{query=%q,le=\"+Inf\"} %d\n", name, h.count)
+		fmt.Fprintf(w, "authentio_db_query_duration_seconds_sum{query=%q} %g\n", name, h.sum)
+		fmt.Fprintf(w, "authentio_db_query_duration_seconds_count{query=%q} %d\n", name, h.count)
+	}
+
+	slow := make([]slowQuery, 0, len(queries.slow))
+	for key := range queries.slow {
+		slow = append(slow, key)
+	}
+	sort.Slice(slow, func(i, j int) bool {
+		if slow[i].query != slow[j].query {
+			return slow[i].query < slow[j].query
+		}
+		return slow[i].tenant < slow[j].tenant
+	})
+
+	fmt.Fprint(w, "# HELP authentio_db_slow_queries_total Database queries slower than DB_SLOW_QUERY_THRESHOLD, by repository method and tenant.\n# TYPE authentio_db_slow_queries_total counter\n")
+	for _, key := range slow {
+		fmt.Fprintf(w, "authentio_db_slow_queries_total{query=%q,tenant=\"%d\"} %d\n", key.query, key.tenant, queries.slow[key])
+	}
+}
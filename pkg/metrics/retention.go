@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// purged counts the rows deleted by the data retention job, per table (and
+// audit log class), since the process started.
+var purged = struct {
+	sync.Mutex
+	rows map[string]int64
+}{rows: make(map[string]int64)}
+
+// RowsPurged records that the data retention job deleted n rows of data
+// (e.g. "otps", "login_events").
+func RowsPurged(data string, n int64) {
+	purged.Lock()
+	defer purged.Unlock()
+	purged.rows[data] += n
+}
+
+// writeRetentionMetrics writes the purged row counts to w in the Prometheus
+// text format.
+func writeRetentionMetrics(w io.Writer) {
+	purged.Lock()
+	defer purged.Unlock()
+
+	names := make([]string, 0, len(purged.rows))
+	for name := range purged.rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprint(w, "# HELP authentio_retention_purged_rows_total Rows deleted by the data retention job, by kind of data.\n# TYPE authentio_retention_purged_rows_total counter\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "authentio_retention_purged_rows_total{data=%q} %d\n", name, purged.rows[name])
+	}
+}
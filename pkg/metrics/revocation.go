@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Sources answering a revocation check.
+const (
+	RevocationFilter = "filter" // the bloom filter ruled the token out
+	RevocationCache  = "cache"  // a possible hit answered by the local cache
+	RevocationRedis  = "redis"  // a possible hit, or a check while the filter loads, answered by Redis
+)
+
+// revocationChecks counts the revocation checks of access tokens by the
+// source that answered them.
+var revocationChecks = map[string]*atomic.Int64{
+	RevocationFilter: {},
+	RevocationCache:  {},
+	RevocationRedis:  {},
+}
+
+// RevocationChecked records a revocation check answered by source
+// (RevocationFilter, RevocationCache or RevocationRedis).
+func RevocationChecked(source string) {
+	if n, ok := revocationChecks[source]; ok {
+		n.Add(1)
+	}
+}
+
+// writeRevocationMetrics writes the revocation check counts to w in the
+// Prometheus text format.
+func writeRevocationMetrics(w io.Writer) {
+	fmt.Fprint(w, "# HELP authentio_revocation_checks_total Access token revocation checks, by what answered them.\n# TYPE authentio_revocation_checks_total counter\n")
+	for _, source := range []string{RevocationFilter, RevocationCache, RevocationRedis} {
+		fmt.Fprintf(w, "authentio_revocation_checks_total{source=%q} %d\n", source, revocationChecks[source].Load())
+	}
+}
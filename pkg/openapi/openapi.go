@@ -0,0 +1,361 @@
+// Package openapi validates requests against the service's Swagger 2.0
+// document (docs/swagger.json), so the API can't accept anything other than
+// what it documents: unknown body fields, wrong types, values outside an
+// enum or a length/range bound, and missing required fields and parameters
+// are reported before a handler binds the request.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-openapi/spec"
+)
+
+// Violation is one way a request departs from its documented contract.
+type Violation struct {
+	Location string `json:"location"`        // body, query or path
+	Field    string `json:"field,omitempty"` // e.g. "email" or "scopes[1]"
+	Message  string `json:"message"`
+}
+
+// Validator checks requests against the operations of a Swagger document.
+type Validator struct {
+	operations  map[string]*Operation
+	definitions spec.Definitions
+}
+
+// Operation is a documented method and path.
+type Operation struct {
+	validator  *Validator
+	parameters []spec.Parameter
+	body       *spec.Parameter
+}
+
+// pathParam matches the {name} path parameters of Swagger paths.
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// New parses a Swagger 2.0 JSON document.
+func New(doc string) (*Validator, error) {
+	var swagger spec.Swagger
+	if err := json.Unmarshal([]byte(doc), &swagger); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+	if swagger.Paths == nil {
+		return nil, errors.New("OpenAPI document has no paths")
+	}
+
+	v := &Validator{
+		operations:  make(map[string]*Operation),
+		definitions: swagger.Definitions,
+	}
+	for path, item := range swagger.Paths.Paths {
+		// Routes are looked up as the router names them: /api/v1/user/devices/:id
+		route := strings.TrimSuffix(swagger.BasePath, "/") + pathParam.ReplaceAllString(path, ":$1")
+		for method, op := range map[string]*spec.Operation{
+			"GET": item.Get, "POST": item.Post, "PUT": item.Put, "PATCH": item.Patch, "DELETE": item.Delete,
+		} {
+			if op == nil {
+				continue
+			}
+			operation := &Operation{validator: v}
+			for i, param := range op.Parameters {
+				switch param.In {
+				case "body":
+					operation.body = &op.Parameters[i]
+				case "query", "path":
+					operation.parameters = append(operation.parameters, param)
+				}
+			}
+			v.operations[method+" "+route] = operation
+		}
+	}
+	return v, nil
+}
+
+// Operation returns the documented operation of a request to route (in the
+// router's ":param" form), or nil when it is undocumented.
+func (v *Validator) Operation(method, route string) *Operation {
+	return v.operations[method+" "+route]
+}
+
+// HasBody reports whether the operation documents a JSON request body.
+func (op *Operation) HasBody() bool {
+	return op.body != nil
+}
+
+// ValidateParameters checks the query parameters and the path parameters,
+// looked up with param. Undocumented query parameters are ignored.
+func (op *Operation) ValidateParameters(query url.Values, param func(string) string) []Violation {
+	var violations []Violation
+	for _, p := range op.parameters {
+		values := query[p.Name]
+		if p.In == "path" {
+			values = []string{param(p.Name)}
+		}
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			if p.Required {
+				violations = append(violations, Violation{Location: p.In, Field: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		if p.Type != "array" {
+			values = values[:1]
+		}
+		for _, value := range values {
+			if message := checkParameter(p, value); message != "" {
+				violations = append(violations, Violation{Location: p.In, Field: p.Name, Message: message})
+				break
+			}
+		}
+	}
+	return violations
+}
+
+// ValidateBody checks a JSON request body against the documented schema.
+func (op *Operation) ValidateBody(body []byte) []Violation {
+	if op.body == nil {
+		return nil
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.body.Required {
+			return []Violation{{Location: "body", Message: "request body is required"}}
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []Violation{{Location: "body", Message: "request body is not valid JSON"}}
+	}
+	if op.body.Schema == nil {
+		return nil
+	}
+
+	var violations []Violation
+	op.validator.checkValue(*op.body.Schema, value, "", &violations)
+	return violations
+}
+
+// =============================================================================
+// Schema Checks
+// =============================================================================
+
+// checkValue appends to violations the ways value departs from schema.
+// field is the position of value in the body.
+func (v *Validator) checkValue(schema spec.Schema, value interface{}, field string, violations *[]Violation) {
+	if ref := schema.Ref.String(); ref != "" {
+		definition, ok := v.definitions[strings.TrimPrefix(ref, "#/definitions/")]
+		if !ok {
+			return
+		}
+		schema = definition
+	}
+	for _, part := range schema.AllOf {
+		v.checkValue(part, value, field, violations)
+	}
+
+	// null leaves the field unset, as when binding to a pointer
+	if value == nil {
+		return
+	}
+	fail := func(message string) {
+		*violations = append(*violations, Violation{Location: "body", Field: field, Message: message})
+	}
+
+	typ := ""
+	if len(schema.Type) > 0 {
+		typ = schema.Type[0]
+	}
+	switch typ {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		v.checkObject(schema, object, field, violations)
+		return
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if schema.MinItems != nil && int64(len(items)) < *schema.MinItems {
+			fail(fmt.Sprintf("must have at least %d items", *schema.MinItems))
+		}
+		if schema.MaxItems != nil && int64(len(items)) > *schema.MaxItems {
+			fail(fmt.Sprintf("must have at most %d items", *schema.MaxItems))
+		}
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, item := range items {
+				v.checkValue(*schema.Items.Schema, item, fmt.Sprintf("%s[%d]", field, i), violations)
+			}
+		}
+		return
+
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if message := checkLength(s, schema.MinLength, schema.MaxLength); message != "" {
+			fail(message)
+			return
+		}
+
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			fail("must be a number")
+			return
+		}
+		if message := checkNumber(n.String(), typ, schema.Minimum, schema.Maximum); message != "" {
+			fail(message)
+			return
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+			return
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		fail("must be one of " + formatEnum(schema.Enum))
+	}
+}
+
+// checkObject checks the fields of a JSON object.
+func (v *Validator) checkObject(schema spec.Schema, object map[string]interface{}, field string, violations *[]Violation) {
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			*violations = append(*violations, Violation{Location: "body", Field: join(field, name), Message: "is required"})
+		}
+	}
+
+	// An object without documented properties (a map) accepts any field
+	freeForm := len(schema.Properties) == 0 ||
+		(schema.AdditionalProperties != nil && schema.AdditionalProperties.Allows)
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := object[name]
+		if property, ok := schema.Properties[name]; ok {
+			v.checkValue(property, value, join(field, name), violations)
+			continue
+		}
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+			v.checkValue(*schema.AdditionalProperties.Schema, value, join(field, name), violations)
+			continue
+		}
+		if !freeForm {
+			*violations = append(*violations, Violation{Location: "body", Field: join(field, name), Message: "unknown field"})
+		}
+	}
+}
+
+// checkParameter checks the value of a query or path parameter.
+func checkParameter(p spec.Parameter, value string) string {
+	typ := p.Type
+	if typ == "array" && p.Items != nil {
+		typ = p.Items.Type
+	}
+	switch typ {
+	case "integer", "number":
+		if message := checkNumber(value, typ, p.Minimum, p.Maximum); message != "" {
+			return message
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "must be a boolean"
+		}
+	case "string":
+		if message := checkLength(value, p.MinLength, p.MaxLength); message != "" {
+			return message
+		}
+	}
+	if len(p.Enum) > 0 && !inEnum(p.Enum, value) {
+		return "must be one of " + formatEnum(p.Enum)
+	}
+	return ""
+}
+
+// checkLength checks the length of a string, in characters.
+func checkLength(s string, minLength, maxLength *int64) string {
+	length := int64(utf8.RuneCountInString(s))
+	if minLength != nil && length < *minLength {
+		return fmt.Sprintf("must be at least %d characters long", *minLength)
+	}
+	if maxLength != nil && length > *maxLength {
+		return fmt.Sprintf("must be at most %d characters long", *maxLength)
+	}
+	return ""
+}
+
+// checkNumber checks that s is a number of type typ ("integer" or
+// "number") within bounds.
+func checkNumber(s, typ string, minimum, maximum *float64) string {
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "must be a number"
+	}
+	if typ == "integer" {
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	}
+	if minimum != nil && n < *minimum {
+		return fmt.Sprintf("must be at least %v", *minimum)
+	}
+	if maximum != nil && n > *maximum {
+		return fmt.Sprintf("must be at most %v", *maximum)
+	}
+	return ""
+}
+
+// inEnum reports whether value is one of enum. Values are compared in their
+// text form, so numbers match however they were written.
+func inEnum(enum []interface{}, value interface{}) bool {
+	text := fmt.Sprint(value)
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == text {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEnum lists the allowed values for a violation message.
+func formatEnum(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, allowed := range enum {
+		values[i] = fmt.Sprint(allowed)
+	}
+	return strings.Join(values, ", ")
+}
+
+// join appends a property name to a field path.
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
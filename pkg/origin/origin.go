@@ -0,0 +1,109 @@
+// Package origin matches browser origins (scheme://host[:port]) against
+// allowed origin patterns: exact origins, or wildcard patterns such as
+// https://*.example.com covering every subdomain of a domain.
+package origin
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Any is the pattern matching every origin.
+const Any = "*"
+
+// Pattern is a parsed origin pattern.
+type Pattern struct {
+	any      bool
+	scheme   string
+	host     string // lowercase; the domain below the wildcard for wildcard patterns
+	port     string
+	wildcard bool
+}
+
+// Parse parses an origin pattern: Any, an origin, or an origin whose host
+// starts with a "*." label, matching subdomains of what follows at any depth
+// but not the domain itself. The wildcard must cover subdomains of a
+// registrable domain: public suffixes, where anyone can register a site, are
+// refused, so https://*.com, https://*.co.uk and https://*.github.io are.
+func Parse(pattern string) (Pattern, error) {
+	if pattern == Any {
+		return Pattern{any: true}, nil
+	}
+
+	u, err := url.Parse(pattern)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Pattern{}, fmt.Errorf("%q is not an http(s) origin", pattern)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return Pattern{}, fmt.Errorf("%q must be an origin (scheme://host[:port]), without path or query", pattern)
+	}
+
+	p := Pattern{scheme: u.Scheme, host: strings.ToLower(u.Hostname()), port: u.Port()}
+	if domain, ok := strings.CutPrefix(p.host, "*."); ok {
+		if !validHost(domain) || !registrable(domain) {
+			return Pattern{}, fmt.Errorf("%q must cover subdomains of a registrable domain, not of a public suffix, e.g. https://*.example.com", pattern)
+		}
+		p.host, p.wildcard = domain, true
+	} else if strings.Contains(p.host, "*") {
+		return Pattern{}, fmt.Errorf("%q may only use a wildcard as its first host label", pattern)
+	}
+	return p, nil
+}
+
+// Matches reports whether origin, as sent by a browser in the Origin
+// header, matches the pattern. Scheme and port must be the same; hosts are
+// compared case-insensitively, and a wildcard only matches whole labels, so
+// https://*.example.com doesn't match https://evilexample.com.
+func (p Pattern) Matches(origin string) bool {
+	if p.any {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Path != "" || u.User != nil || !strings.EqualFold(u.Scheme, p.scheme) || u.Port() != p.port {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if !p.wildcard {
+		return host == p.host
+	}
+
+	sub, ok := strings.CutSuffix(host, "."+p.host)
+	return ok && validHost(sub)
+}
+
+// MatchesAny reports whether origin matches one of patterns.
+func MatchesAny(patterns []Pattern, origin string) bool {
+	for _, p := range patterns {
+		if p.Matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// registrable reports whether domain is, or is under, a registrable domain
+// (a public suffix plus one label), according to the Public Suffix List.
+func registrable(domain string) bool {
+	_, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	return err == nil
+}
+
+// validHost reports whether host is made of non-empty DNS labels (letters,
+// digits and hyphens).
+func validHost(host string) bool {
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
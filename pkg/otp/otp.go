@@ -0,0 +1,109 @@
+// Package otp generates and checks the one-time codes emailed for two-factor
+// authentication and password resets. A Policy sets each code type's length,
+// alphabet and lifetime.
+package otp
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Supported code alphabets.
+const (
+	CharsetNumeric      = "numeric"
+	CharsetAlphanumeric = "alphanumeric"
+)
+
+// Characters codes are drawn from. The alphanumeric alphabet is uppercase
+// and leaves out 0/O and 1/I, which are easily confused when retyped.
+const (
+	numericAlphabet      = "0123456789"
+	alphanumericAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// Bounds on Policy values.
+const (
+	MinLength = 4
+	MaxLength = 12
+	MinTTL    = time.Minute
+	MaxTTL    = 24 * time.Hour
+)
+
+// Policy describes the codes issued for one purpose.
+type Policy struct {
+	Length  int           // number of characters
+	Charset string        // CharsetNumeric or CharsetAlphanumeric
+	TTL     time.Duration // how long a code stays valid
+}
+
+// Validate reports why p can't be used, or nil.
+func (p Policy) Validate() error {
+	if p.Length < MinLength || p.Length > MaxLength {
+		return fmt.Errorf("length must be between %d and %d, got %d", MinLength, MaxLength, p.Length)
+	}
+	if p.alphabet() == "" {
+		return fmt.Errorf("charset must be %s or %s, got %q", CharsetNumeric, CharsetAlphanumeric, p.Charset)
+	}
+	if p.TTL < MinTTL || p.TTL > MaxTTL {
+		return fmt.Errorf("ttl must be between %s and %s, got %s", MinTTL, MaxTTL, p.TTL)
+	}
+	return nil
+}
+
+// Generate returns a new code. Each character is drawn uniformly with
+// crypto/rand, so codes can't be predicted from earlier ones or from the time
+// they were issued.
+func (p Policy) Generate() (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", fmt.Errorf("invalid otp policy: %w", err)
+	}
+
+	alphabet := p.alphabet()
+	size := big.NewInt(int64(len(alphabet)))
+	code := make([]byte, p.Length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", fmt.Errorf("generate otp: %w", err)
+		}
+		code[i] = alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// Normalize prepares a submitted code for comparison: surrounding spaces are
+// dropped and alphanumeric codes are matched case-insensitively.
+func (p Policy) Normalize(code string) string {
+	code = strings.TrimSpace(code)
+	if p.Charset == CharsetAlphanumeric {
+		code = strings.ToUpper(code)
+	}
+	return code
+}
+
+// ExpiresAt returns when a code issued at issuedAt expires.
+func (p Policy) ExpiresAt(issuedAt time.Time) time.Time {
+	return issuedAt.Add(p.TTL)
+}
+
+// alphabet returns the characters for p.Charset, or "" when it is unknown.
+func (p Policy) alphabet() string {
+	switch p.Charset {
+	case CharsetNumeric:
+		return numericAlphabet
+	case CharsetAlphanumeric:
+		return alphanumericAlphabet
+	}
+	return ""
+}
+
+// Equal reports whether a submitted code matches the issued one. The
+// comparison takes the same time wherever the codes differ, so response
+// timing doesn't reveal how many leading characters were right.
+func Equal(issued, submitted string) bool {
+	return subtle.ConstantTimeCompare([]byte(issued), []byte(submitted)) == 1
+}
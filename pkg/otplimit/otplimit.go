@@ -0,0 +1,115 @@
+// Package otplimit throttles one-time code emails per recipient in Redis, so
+// the endpoints that send them can't be used to flood someone's inbox. Each
+// recipient must wait a cooldown between two codes of the same purpose and
+// gets at most a fixed number of them per UTC day.
+package otplimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrCooldown is returned when a code was sent to the recipient too recently.
+	ErrCooldown = errors.New("code requested too soon")
+
+	// ErrDailyLimit is returned when the recipient has received today's
+	// maximum number of codes.
+	ErrDailyLimit = errors.New("daily code limit reached")
+)
+
+const (
+	keyPrefix = "otplimit:"
+
+	// dailyRetention is how long daily counters are kept after the day starts
+	dailyRetention = 25 * time.Hour
+)
+
+// LimitError reports a rejected send and when the next one will be allowed.
+// It wraps ErrCooldown or ErrDailyLimit.
+type LimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Err, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap returns ErrCooldown or ErrDailyLimit.
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// Limiter enforces the cooldown and daily cap. It is safe for concurrent use.
+type Limiter struct {
+	redis    *redis.Client
+	cooldown time.Duration
+	dailyCap int64
+}
+
+// NewLimiter creates a limiter. A zero cooldown or daily cap disables that
+// check.
+func NewLimiter(redisClient *redis.Client, cooldown time.Duration, dailyCap int64) *Limiter {
+	return &Limiter{
+		redis:    redisClient,
+		cooldown: cooldown,
+		dailyCap: dailyCap,
+	}
+}
+
+// Allow records a code sent to email for purpose (e.g. "2fa") in tenantID and
+// returns a *LimitError if it must not be sent. Rejected sends don't count
+// towards the daily cap. Redis errors are logged and the send is allowed
+// (fail-open), like the rate limiter.
+func (l *Limiter) Allow(ctx context.Context, tenantID int64, purpose, email string) error {
+	recipient := fmt.Sprintf("%s%d:%s:%s", keyPrefix, tenantID, purpose, strings.ToLower(strings.TrimSpace(email)))
+
+	if l.cooldown > 0 {
+		cooldownKey := recipient + ":cooldown"
+		set, err := l.redis.SetNX(ctx, cooldownKey, 1, l.cooldown).Result()
+		if err != nil {
+			logger.Error("otp cooldown check failed", "error", err, "purpose", purpose)
+			return nil
+		}
+		if !set {
+			retryAfter, err := l.redis.PTTL(ctx, cooldownKey).Result()
+			if err != nil || retryAfter <= 0 {
+				retryAfter = l.cooldown
+			}
+			return &LimitError{Err: ErrCooldown, RetryAfter: retryAfter}
+		}
+	}
+
+	if l.dailyCap > 0 {
+		now := time.Now().UTC()
+		dailyKey := recipient + ":" + now.Format("2006-01-02")
+
+		pipe := l.redis.TxPipeline()
+		incr := pipe.Incr(ctx, dailyKey)
+		pipe.Expire(ctx, dailyKey, dailyRetention)
+		if _, err := pipe.Exec(ctx); err != nil {
+			logger.Error("otp daily counter update failed", "error", err, "purpose", purpose)
+			return nil
+		}
+
+		if incr.Val() > l.dailyCap {
+			if err := l.redis.Decr(ctx, dailyKey).Err(); err != nil {
+				logger.Warn("failed to roll back rejected otp send", "error", err, "key", dailyKey)
+			}
+			logger.Warn("otp daily limit reached", "tenantID", tenantID, "purpose", purpose, "limit", l.dailyCap)
+			tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			return &LimitError{Err: ErrDailyLimit, RetryAfter: tomorrow.Sub(now)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package pagination implements cursor-based pagination of list endpoints.
+// Lists are ordered newest first by (created_at, id); a page ends with an
+// opaque cursor naming its last item, and the next page starts right after it.
+// Unlike offsets, cursors stay valid while new rows are inserted.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Page size bounds
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Errors returned when parsing a request's page parameters
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+)
+
+// Cursor is the position of the last item of a page.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+}
+
+// Encode returns the opaque form of c handed to clients as next_cursor.
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode parses a cursor produced by Encode.
+func Decode(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID <= 0 || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Request asks for the page of at most Limit items following After (the
+// first page when nil).
+type Request struct {
+	Limit int
+	After *Cursor
+}
+
+// NewRequest parses the "limit" and "cursor" query parameters of a list
+// endpoint. An empty limit means DefaultLimit, an empty cursor the first page.
+func NewRequest(limit, cursor string) (Request, error) {
+	req := Request{Limit: DefaultLimit}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Request{}, ErrInvalidLimit
+		}
+		req.Limit = n
+	}
+	if cursor != "" {
+		after, err := Decode(cursor)
+		if err != nil {
+			return Request{}, err
+		}
+		req.After = after
+	}
+	return req, nil
+}
+
+// Size returns the page size: Limit, or DefaultLimit when unset, capped at
+// MaxLimit.
+func (r Request) Size() int {
+	switch {
+	case r.Limit <= 0:
+		return DefaultLimit
+	case r.Limit > MaxLimit:
+		return MaxLimit
+	}
+	return r.Limit
+}
+
+// Fetch returns how many rows to query for the page: one more than its size,
+// to tell whether another page follows.
+func (r Request) Fetch() int {
+	return r.Size() + 1
+}
+
+// Page is the response envelope of list endpoints. NextCursor is omitted on
+// the last page.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NewPage builds the page answering req from rows queried with req.Fetch(),
+// converting each item with convert. cursorOf gives the position of a row.
+func NewPage[R, T any](rows []R, req Request, cursorOf func(R) Cursor, convert func(R) T) Page[T] {
+	page := Page[T]{Data: make([]T, 0, min(len(rows), req.Size()))}
+	if len(rows) > req.Size() {
+		rows = rows[:req.Size()]
+		page.NextCursor = cursorOf(rows[len(rows)-1]).Encode()
+	}
+	for _, row := range rows {
+		page.Data = append(page.Data, convert(row))
+	}
+	return page
+}
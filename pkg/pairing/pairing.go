@@ -0,0 +1,187 @@
+// Package pairing keeps in Redis the QR code pairings through which a mobile
+// app that is signed in logs a desktop client in: the desktop shows a code as
+// a QR code, the app scans it and approves or denies the pairing, and the
+// desktop, which alone holds the pairing's poll token, exchanges the approved
+// pairing for tokens once. Decisions are published, so a desktop waiting on
+// its pairing learns of them at once, on any server.
+package pairing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "pairing:"
+
+// Pairing statuses
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusDenied   = "denied"
+)
+
+// Pairing is a desktop's request to be logged in by the app that scans its
+// code.
+type Pairing struct {
+	Code          string     `json:"code"`
+	PollTokenHash string     `json:"poll_token_hash"`
+	Status        string     `json:"status"`            // pending, approved or denied
+	UserID        int64      `json:"user_id,omitempty"` // who answered
+	IPAddress     string     `json:"ip_address,omitempty"`
+	UserAgent     string     `json:"user_agent,omitempty"`
+	Country       string     `json:"country,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+}
+
+// Store records pairings by tenant until they expire. It is safe for
+// concurrent use.
+type Store struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewStore creates a store keeping pairings for ttl.
+func NewStore(redisClient *redis.Client, ttl time.Duration) *Store {
+	return &Store{redis: redisClient, ttl: ttl}
+}
+
+// Create records a new pending pairing, setting its creation and expiry
+// times.
+func (s *Store) Create(ctx context.Context, tenantID int64, p *Pairing) error {
+	p.Status = StatusPending
+	p.CreatedAt = time.Now().UTC()
+	p.ExpiresAt = p.CreatedAt.Add(s.ttl)
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.redis.Set(ctx, key(tenantID, p.Code), data, s.ttl).Err()
+}
+
+// Get returns the pairing code of tenantID, or nil when it doesn't exist,
+// has expired or was completed.
+func (s *Store) Get(ctx context.Context, tenantID int64, code string) (*Pairing, error) {
+	return get(ctx, s.redis, key(tenantID, code))
+}
+
+// Decide records the answer of userID to a pending pairing and wakes up the
+// desktop waiting on it. It returns false when the pairing isn't pending
+// anymore, e.g. because another answer came first.
+func (s *Store) Decide(ctx context.Context, tenantID int64, code string, userID int64, approve bool) (bool, error) {
+	k := key(tenantID, code)
+	decided := false
+	err := s.redis.Watch(ctx, func(tx *redis.Tx) error {
+		p, err := get(ctx, tx, k)
+		if err != nil || p == nil || p.Status != StatusPending {
+			return err
+		}
+
+		now := time.Now().UTC()
+		p.Status = StatusDenied
+		if approve {
+			p.Status = StatusApproved
+		}
+		p.UserID = userID
+		p.DecidedAt = &now
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, k, data, redis.KeepTTL)
+			pipe.Publish(ctx, k, p.Status)
+			return nil
+		})
+		decided = err == nil
+		return err
+	}, k)
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil
+	}
+	return decided, err
+}
+
+// Complete removes an approved pairing and returns it, so it is exchanged for
+// tokens once. It returns nil when the pairing isn't approved, e.g. because
+// a concurrent poll completed it first.
+func (s *Store) Complete(ctx context.Context, tenantID int64, code string) (*Pairing, error) {
+	k := key(tenantID, code)
+	var completed *Pairing
+	err := s.redis.Watch(ctx, func(tx *redis.Tx) error {
+		p, err := get(ctx, tx, k)
+		if err != nil || p == nil || p.Status != StatusApproved {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, k)
+			return nil
+		})
+		if err == nil {
+			completed = p
+		}
+		return err
+	}, k)
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil, nil
+	}
+	return completed, err
+}
+
+// Await returns the pairing code of tenantID once it is no longer pending,
+// or as it is when wait passes or ctx is done first. It returns nil when the
+// pairing doesn't exist or has expired.
+func (s *Store) Await(ctx context.Context, tenantID int64, code string, wait time.Duration) (*Pairing, error) {
+	k := key(tenantID, code)
+
+	// Subscribed before reading, so a decision in between isn't missed
+	sub := s.redis.Subscribe(ctx, k)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return nil, err
+	}
+
+	p, err := s.Get(ctx, tenantID, code)
+	if err != nil || p == nil || p.Status != StatusPending || wait <= 0 {
+		return p, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-sub.Channel():
+	case <-timer.C:
+	case <-ctx.Done():
+		return p, nil
+	}
+	return s.Get(ctx, tenantID, code)
+}
+
+// get reads and decodes the pairing stored at k, or returns nil.
+func get(ctx context.Context, client redis.StringCmdable, k string) (*Pairing, error) {
+	stored, err := client.Get(ctx, k).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var p Pairing
+	if err := json.Unmarshal(stored, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func key(tenantID int64, code string) string {
+	return fmt.Sprintf("%s%d:%s", keyPrefix, tenantID, code)
+}
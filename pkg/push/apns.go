@@ -0,0 +1,149 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionURL  = "https://api.push.apple.com/3/device/"
+	apnsDevelopmentURL = "https://api.sandbox.push.apple.com/3/device/"
+
+	// apnsTokenLifetime is how long a provider token is reused. Apple rejects
+	// tokens older than an hour and throttles ones refreshed more often than
+	// every 20 minutes.
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// APNsSender sends notifications with APNs token-based authentication.
+type APNsSender struct {
+	client  *http.Client
+	baseURL string
+	key     *ecdsa.PrivateKey
+	keyID   string
+	teamID  string
+	topic   string
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsSender loads the .p8 signing key in keyFile. production selects the
+// production gateway instead of the sandbox used by development builds.
+func NewAPNsSender(keyFile, keyID, teamID, topic string, production bool) (*APNsSender, error) {
+	if keyID == "" || teamID == "" || topic == "" {
+		return nil, fmt.Errorf("apns requires a key id, team id and topic")
+	}
+	raw, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read apns key: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse apns key: %w", err)
+	}
+
+	baseURL := apnsDevelopmentURL
+	if production {
+		baseURL = apnsProductionURL
+	}
+
+	return &APNsSender{
+		// APNs only speaks HTTP/2, which net/http negotiates over TLS
+		client:  &http.Client{Timeout: 10 * time.Second},
+		baseURL: baseURL,
+		key:     key,
+		keyID:   keyID,
+		teamID:  teamID,
+		topic:   topic,
+	}, nil
+}
+
+// Send delivers n to an APNs device token.
+func (s *APNsSender) Send(ctx context.Context, token string, n Notification) error {
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": n.Title, "body": n.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range n.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	authToken, err := s.providerToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+authToken)
+	req.Header.Set("apns-topic", s.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	if n.TTL > 0 {
+		req.Header.Set("apns-expiration", strconv.FormatInt(time.Now().Add(n.TTL).Unix(), 10))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns send: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var reply struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&reply)
+	if resp.StatusCode == http.StatusGone || reply.Reason == "BadDeviceToken" || reply.Reason == "Unregistered" {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("apns send: status %d: %s", resp.StatusCode, reply.Reason)
+}
+
+// providerToken returns the signed authentication token, renewing it once
+// it is apnsTokenLifetime old.
+func (s *APNsSender) providerToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Since(s.issuedAt) < apnsTokenLifetime {
+		return s.token, nil
+	}
+
+	now := time.Now()
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": s.teamID,
+		"iat": now.Unix(),
+	})
+	tok.Header["kid"] = s.keyID
+	signed, err := tok.SignedString(s.key)
+	if err != nil {
+		return "", fmt.Errorf("sign apns token: %w", err)
+	}
+
+	s.token = signed
+	s.issuedAt = now
+	return signed, nil
+}
@@ -0,0 +1,133 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmTokenURL = "https://oauth2.googleapis.com/token"
+	fcmSendURL  = "https://fcm.googleapis.com/v1/projects/%s/messages:send"
+)
+
+// FCMSender sends notifications with the FCM HTTP v1 API, authenticating as
+// a Firebase service account.
+type FCMSender struct {
+	client  *http.Client
+	sendURL string
+}
+
+// serviceAccount holds the fields of a service account key file used here.
+type serviceAccount struct {
+	ProjectID    string `json:"project_id"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewFCMSender reads the service account key in credentialsFile. Access
+// tokens are fetched and refreshed on demand.
+func NewFCMSender(credentialsFile string) (*FCMSender, error) {
+	raw, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("read fcm credentials: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("parse fcm credentials: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("fcm credentials must contain project_id, client_email and private_key")
+	}
+
+	tokenURL := account.TokenURI
+	if tokenURL == "" {
+		tokenURL = fcmTokenURL
+	}
+	conf := &jwt.Config{
+		Email:        account.ClientEmail,
+		PrivateKey:   []byte(account.PrivateKey),
+		PrivateKeyID: account.PrivateKeyID,
+		Scopes:       []string{fcmScope},
+		TokenURL:     tokenURL,
+	}
+
+	base := &http.Client{Timeout: 10 * time.Second}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, base)
+	client := oauth2.NewClient(ctx, conf.TokenSource(ctx))
+	client.Timeout = 10 * time.Second
+
+	return &FCMSender{
+		client:  client,
+		sendURL: fmt.Sprintf(fcmSendURL, account.ProjectID),
+	}, nil
+}
+
+// fcmMessage is the body of a messages:send request.
+type fcmMessage struct {
+	Message struct {
+		Token        string            `json:"token"`
+		Notification fcmNotification   `json:"notification"`
+		Data         map[string]string `json:"data,omitempty"`
+		Android      fcmAndroid        `json:"android"`
+	} `json:"message"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	Priority string `json:"priority"`
+	TTL      string `json:"ttl,omitempty"`
+}
+
+// Send delivers n to an FCM registration token.
+func (s *FCMSender) Send(ctx context.Context, token string, n Notification) error {
+	var msg fcmMessage
+	msg.Message.Token = token
+	msg.Message.Notification = fcmNotification{Title: n.Title, Body: n.Body}
+	msg.Message.Data = n.Data
+	msg.Message.Android.Priority = "high"
+	if n.TTL > 0 {
+		msg.Message.Android.TTL = fmt.Sprintf("%ds", int(n.TTL.Seconds()))
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.sendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm send: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	// NOT_FOUND (UNREGISTERED) means the app was uninstalled or the token rotated
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrInvalidToken
+	}
+	return fmt.Errorf("fcm send: status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+}
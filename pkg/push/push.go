@@ -0,0 +1,106 @@
+// Package push sends notifications to mobile devices through Firebase Cloud
+// Messaging (FCM) and the Apple Push Notification service (APNs). Devices are
+// addressed by the registration token their app obtained from the platform.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Supported device platforms.
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+)
+
+var (
+	// ErrInvalidToken is returned when the platform reports that a device
+	// token is no longer registered; the device should be forgotten.
+	ErrInvalidToken = errors.New("device token is no longer valid")
+
+	// ErrUnsupportedPlatform is returned for platforms without configured
+	// credentials.
+	ErrUnsupportedPlatform = errors.New("push platform not configured")
+)
+
+// Notification is a visible alert with optional data for the app.
+type Notification struct {
+	Title string
+	Body  string
+
+	// Data is delivered to the app alongside the alert.
+	Data map[string]string
+
+	// TTL is how long the platform keeps trying to deliver the notification.
+	TTL time.Duration
+}
+
+// Sender delivers notifications for one platform.
+type Sender interface {
+	Send(ctx context.Context, token string, n Notification) error
+}
+
+// Config holds the credentials of every platform. A platform is enabled when
+// its credentials are set.
+type Config struct {
+	// FCMCredentialsFile is a Firebase service account key (JSON).
+	FCMCredentialsFile string
+
+	// APNs token-based authentication: the .p8 signing key, its key ID, the
+	// Apple developer team ID and the app's bundle ID (the topic).
+	APNsKeyFile    string
+	APNsKeyID      string
+	APNsTeamID     string
+	APNsTopic      string
+	APNsProduction bool
+}
+
+// Dispatcher routes notifications to the sender of each device's platform.
+type Dispatcher struct {
+	senders map[string]Sender
+}
+
+// New returns a Dispatcher for the platforms configured in cfg, or nil when
+// none is.
+func New(cfg Config) (*Dispatcher, error) {
+	senders := make(map[string]Sender)
+
+	if cfg.FCMCredentialsFile != "" {
+		fcm, err := NewFCMSender(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		senders[PlatformFCM] = fcm
+	}
+
+	if cfg.APNsKeyFile != "" {
+		apns, err := NewAPNsSender(cfg.APNsKeyFile, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsProduction)
+		if err != nil {
+			return nil, err
+		}
+		senders[PlatformAPNs] = apns
+	}
+
+	if len(senders) == 0 {
+		return nil, nil
+	}
+	return &Dispatcher{senders: senders}, nil
+}
+
+// Supports reports whether notifications can be sent to platform.
+func (d *Dispatcher) Supports(platform string) bool {
+	_, ok := d.senders[platform]
+	return ok
+}
+
+// Send delivers n to the device with token on platform.
+func (d *Dispatcher) Send(ctx context.Context, platform, token string, n Notification) error {
+	sender, ok := d.senders[platform]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedPlatform, platform)
+	}
+	return sender.Send(ctx, token, n)
+}
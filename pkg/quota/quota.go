@@ -0,0 +1,184 @@
+// Package quota enforces daily usage quotas per tenant in Redis. Each tenant,
+// metric and UTC day has its own counter, kept for a week so recent usage can
+// be reported.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Metered metrics.
+const (
+	Requests      = "requests"      // API requests
+	Registrations = "registrations" // new accounts (email and Google signups)
+	OTPEmails     = "otp_emails"    // 2FA and password reset code emails requested
+)
+
+// Metrics lists every metered metric in reporting order.
+var Metrics = []string{Requests, Registrations, OTPEmails}
+
+// ErrExceeded is returned when a tenant has used up a daily quota.
+var ErrExceeded = errors.New("quota exceeded")
+
+const (
+	keyPrefix = "quota:"
+
+	// retention is how long daily counters are kept after the day starts
+	retention = 8 * 24 * time.Hour
+
+	// limitsTTL is how long per-tenant limits are cached
+	limitsTTL = time.Minute
+)
+
+// LimitStore supplies per-tenant limit overrides by metric. Metrics missing
+// from the result use the limiter's defaults.
+type LimitStore interface {
+	QuotaLimits(ctx context.Context, tenantID int64) (map[string]int64, error)
+}
+
+// Usage reports one metric's consumption for a day. A zero Limit means
+// unlimited.
+type Usage struct {
+	Metric   string    `json:"metric"`
+	Used     int64     `json:"used"`
+	Limit    int64     `json:"limit"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+type cachedLimits struct {
+	limits  map[string]int64
+	expires time.Time
+}
+
+// Limiter counts and enforces daily quotas. It is safe for concurrent use.
+type Limiter struct {
+	redis    *redis.Client
+	defaults map[string]int64
+	store    LimitStore
+
+	mu    sync.Mutex
+	cache map[int64]cachedLimits
+}
+
+// NewLimiter creates a limiter with default daily limits by metric (zero or
+// missing means unlimited). store may be nil when tenants can't override
+// them.
+func NewLimiter(redisClient *redis.Client, defaults map[string]int64, store LimitStore) *Limiter {
+	return &Limiter{
+		redis:    redisClient,
+		defaults: defaults,
+		store:    store,
+		cache:    make(map[int64]cachedLimits),
+	}
+}
+
+// Consume records one use of metric by tenantID and returns ErrExceeded if
+// that goes over today's limit; rejected uses aren't counted. Redis errors
+// are logged and the use is allowed (fail-open), like the rate limiter.
+func (l *Limiter) Consume(ctx context.Context, tenantID int64, metric string) error {
+	limit := l.limit(ctx, tenantID, metric)
+	if limit <= 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	key := counterKey(tenantID, metric, now)
+
+	pipe := l.redis.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, retention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("quota counter update failed", "error", err, "tenantID", tenantID, "metric", metric)
+		return nil
+	}
+
+	if incr.Val() > limit {
+		if err := l.redis.Decr(ctx, key).Err(); err != nil {
+			logger.Warn("failed to roll back rejected quota use", "error", err, "key", key)
+		}
+		logger.Warn("tenant quota exceeded", "tenantID", tenantID, "metric", metric, "limit", limit)
+		return ErrExceeded
+	}
+	return nil
+}
+
+// Usage returns tenantID's consumption of every metric on the UTC day
+// containing day. Counters older than a week have expired and read as zero.
+func (l *Limiter) Usage(ctx context.Context, tenantID int64, day time.Time) ([]Usage, error) {
+	day = day.UTC()
+
+	pipe := l.redis.Pipeline()
+	cmds := make([]*redis.StringCmd, len(Metrics))
+	for i, metric := range Metrics {
+		cmds[i] = pipe.Get(ctx, counterKey(tenantID, metric, day))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read quota usage: %w", err)
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	usage := make([]Usage, len(Metrics))
+	for i, metric := range Metrics {
+		used, err := cmds[i].Int64()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		usage[i] = Usage{
+			Metric:   metric,
+			Used:     used,
+			Limit:    l.limit(ctx, tenantID, metric),
+			ResetsAt: start.Add(24 * time.Hour),
+		}
+	}
+	return usage, nil
+}
+
+// limit returns tenantID's daily limit for metric. If the overrides can't be
+// loaded the defaults apply.
+func (l *Limiter) limit(ctx context.Context, tenantID int64, metric string) int64 {
+	if l.store != nil {
+		if limits := l.overrides(ctx, tenantID); limits != nil {
+			if limit, ok := limits[metric]; ok {
+				return limit
+			}
+		}
+	}
+	return l.defaults[metric]
+}
+
+// overrides returns tenantID's cached limit overrides, reloading them once
+// they are older than limitsTTL.
+func (l *Limiter) overrides(ctx context.Context, tenantID int64) map[string]int64 {
+	l.mu.Lock()
+	cached, ok := l.cache[tenantID]
+	l.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.limits
+	}
+
+	limits, err := l.store.QuotaLimits(ctx, tenantID)
+	if err != nil {
+		logger.Error("failed to load tenant quota limits", "error", err, "tenantID", tenantID)
+		return cached.limits
+	}
+
+	l.mu.Lock()
+	l.cache[tenantID] = cachedLimits{limits: limits, expires: time.Now().Add(limitsTTL)}
+	l.mu.Unlock()
+	return limits
+}
+
+// counterKey returns the Redis key counting metric for tenantID on day's UTC date.
+//
+// Format: "quota:{tenantID}:{metric}:{YYYY-MM-DD}"
+func counterKey(tenantID int64, metric string, day time.Time) string {
+	return fmt.Sprintf("%s%d:%s:%s", keyPrefix, tenantID, metric, day.UTC().Format("2006-01-02"))
+}
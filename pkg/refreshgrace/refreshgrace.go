@@ -0,0 +1,54 @@
+// Package refreshgrace keeps in Redis, for a short grace period, the token
+// pair a refresh token was rotated into. Clients that refresh twice at once
+// (e.g. a mobile app resuming with several requests in flight) then get the
+// same pair for the second request instead of a failure that signs them
+// out. Each rotated token is accepted once more at most.
+package refreshgrace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "refresh_grace:"
+
+// Store records rotated refresh tokens. It is safe for concurrent use.
+type Store struct {
+	redis  *redis.Client
+	period time.Duration
+}
+
+// NewStore creates a store accepting rotated tokens for period.
+func NewStore(redisClient *redis.Client, period time.Duration) *Store {
+	return &Store{redis: redisClient, period: period}
+}
+
+// Claim records the result of rotating token in tenantID. It returns false
+// when another request rotated the same token first: its result should then
+// be used (see Consume).
+func (s *Store) Claim(ctx context.Context, tenantID int64, token string, result []byte) (bool, error) {
+	return s.redis.SetNX(ctx, key(tenantID, token), result, s.period).Result()
+}
+
+// Consume returns the result of rotating token within the grace period and
+// forgets it, so it is returned once. It returns nil when there is none.
+func (s *Store) Consume(ctx context.Context, tenantID int64, token string) ([]byte, error) {
+	result, err := s.redis.GetDel(ctx, key(tenantID, token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return result, err
+}
+
+// key identifies token by its hash, so refresh tokens don't appear in key
+// names.
+func key(tenantID int64, token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%s%d:%s", keyPrefix, tenantID, hex.EncodeToString(sum[:]))
+}
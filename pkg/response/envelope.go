@@ -0,0 +1,68 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"authentio/pkg/pagination"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDKey is the gin context key holding the ID of the request, set by
+// the RequestID middleware and echoed in every envelope.
+const RequestIDKey = "requestID"
+
+// Envelope wraps every successful REST response, so clients can parse them
+// generically: the payload in Data, list details such as the next page in
+// Meta, and the request ID to quote when reporting a problem.
+type Envelope struct {
+	Data      interface{} `json:"data"`
+	Meta      interface{} `json:"meta,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// PageMeta is the Meta of paginated lists. NextCursor is omitted on the last
+// page.
+type PageMeta struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// JSON writes data in an envelope with the given status.
+func JSON(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, Envelope{Data: data, RequestID: c.GetString(RequestIDKey)})
+}
+
+// Message writes a confirmation message, {"message": message}, in an
+// envelope with the given status.
+func Message(c *gin.Context, status int, message string) {
+	JSON(c, status, gin.H{"message": message})
+}
+
+// Page writes a page of a list in an envelope: its items in Data and the
+// cursor of the next page in Meta.
+func Page[T any](c *gin.Context, page pagination.Page[T]) {
+	c.JSON(http.StatusOK, Envelope{
+		Data:      page.Data,
+		Meta:      PageMeta{NextCursor: page.NextCursor},
+		RequestID: c.GetString(RequestIDKey),
+	})
+}
+
+// Payload returns the data and meta of an enveloped response body, leaving
+// out the request ID that differs on every response, or false when body is
+// not an envelope.
+func Payload(body []byte) ([]byte, bool) {
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+		Meta json.RawMessage `json:"meta,omitempty"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Data == nil {
+		return nil, false
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, false
+	}
+	return payload, true
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"time"
+
+	"authentio/pkg/export"
+)
+
+// UserExport is a user export job. Once it is ready, DownloadURL is a signed
+// link to its file, valid until DownloadExpiresAt.
+type UserExport struct {
+	export.Job
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty"`
+}
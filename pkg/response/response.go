@@ -14,8 +14,23 @@ type UserResponse struct {
     FirstName string `json:"first_name"`
     LastName  string `json:"last_name"`
     Email     string    `json:"email"`
+    Username  string    `json:"username,omitempty"`
     IsActive  bool      `json:"is_active"`
     CreatedAt time.Time `json:"created_at,omitempty"`
+    Locale    string    `json:"locale,omitempty"`
+    AvatarURL *string   `json:"avatar_url,omitempty"`
+
+    // UserMetadata is editable by the user; AppMetadata is read-only for them.
+    UserMetadata map[string]interface{} `json:"user_metadata,omitempty"`
+    AppMetadata  map[string]interface{} `json:"app_metadata,omitempty"`
+
+    // ApprovalStatus is "pending" or "rejected" for registrations awaiting or
+    // refused admin approval; omitted for approved accounts.
+    ApprovalStatus string `json:"approval_status,omitempty"`
+
+    // ConsentRequired is true when the user has not accepted the current
+    // Terms of Service / Privacy Policy versions and must call POST /user/consent.
+    ConsentRequired bool `json:"consent_required"`
 }
 
 
@@ -29,9 +44,158 @@ type LoginResponse struct {
 	AccessToken  string       `json:"access_token"`
 	RefreshToken string       `json:"refresh_token"`
 	ExpiresIn    int          `json:"expires_in"`
+
+	// OrgID and OrgRole are set when the tokens are scoped to an organization
+	OrgID   int64  `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+
+	// TwoFactor is set instead of the tokens when the login must be approved
+	// on one of the user's devices first
+	TwoFactor *TwoFactorChallenge `json:"two_factor,omitempty"`
+
+	// Next steps for the client, so it can branch without parsing messages:
+	// complete the TwoFactor challenge, verify the email address (never set
+	// yet, addresses are not verified by this service), change the password
+	// (older than PASSWORD_MAX_AGE) or accept the current legal documents
+	// (POST /user/consent).
+	RequiresTwoFactor         bool `json:"requires_2fa"`
+	RequiresEmailVerification bool `json:"requires_email_verification"`
+	PasswordExpired           bool `json:"password_expired"`
+	RequiresTOSAcceptance     bool `json:"requires_tos_acceptance"`
+}
+
+// SudoToken is an access token carrying the sudo scope, issued after the user
+// re-authenticated. The scope lapses at SudoExpiresAt, which may be before the
+// token itself expires.
+type SudoToken struct {
+	AccessToken   string    `json:"access_token"`
+	ExpiresIn     int       `json:"expires_in"`
+	SudoExpiresAt time.Time `json:"sudo_expires_at"`
+}
+
+// TwoFactorChallenge tells the client to complete a login with a second
+// factor. For push approval, the client polls POST /auth/2fa/push with
+// ChallengeToken until the user approves or denies the login.
+type TwoFactorChallenge struct {
+	Method      string    `json:"method"`
+	ChallengeID string    `json:"challenge_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+
+	// ChallengeToken completes push and email challenges: a single-use token
+	// valid for at most 5 minutes, only accepted by the second-factor
+	// endpoints. Quarantined logins are polled with ChallengeID.
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+// PairingSession is a QR code pairing started by a desktop client. The
+// desktop shows Code as a QR code for the signed-in mobile app to scan, and
+// polls POST /auth/pairing/poll with Code and PollToken, which only it knows,
+// until the app approves or denies the pairing.
+type PairingSession struct {
+	Code      string    `json:"code"`
+	PollToken string    `json:"poll_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PairingDetails describes the desktop client behind a pairing code, for the
+// app to show before the user approves it.
+type PairingDetails struct {
+	Code      string    `json:"code"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionToken is an access token issued to a single-page app from its
+// session cookie, which keeps the refresh token out of reach of scripts.
+type SessionToken struct {
+	User        UserResponse `json:"user"`
+	AccessToken string       `json:"access_token"`
+	ExpiresIn   int          `json:"expires_in"`
+}
+
+// Session is a signed-in session of a user (a first-party refresh token).
+type Session struct {
+	ID             int64      `json:"id"`
+	OrganizationID *int64     `json:"organization_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	DeviceID       *int64     `json:"device_id,omitempty"` // registered device the session runs on
+}
+
+// LoginEvent is a successful or failed sign-in attempt on a user's account.
+type LoginEvent struct {
+	Success   bool      `json:"success"`
+	Method    string    `json:"method,omitempty"` // password or google, for successful sign-ins
+	Reason    string    `json:"reason,omitempty"` // why a sign-in failed
+	IPAddress string    `json:"ip_address,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	DeviceID  *int64    `json:"device_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecurityOverview summarizes the security of a user's account for a
+// security settings page.
+type SecurityOverview struct {
+	TwoFactor      TwoFactorStatus `json:"two_factor"`
+	ActiveSessions int             `json:"active_sessions"`
+	LastLogin      *LoginEvent     `json:"last_login,omitempty"` // most recent successful sign-in
+
+	// Password is nil for accounts without one (Google sign-in only).
+	Password *PasswordStatus `json:"password,omitempty"`
+
+	// LinkedProviders lists the ways the user can sign in: "password" and/or
+	// the social provider the account was created with.
+	LinkedProviders []string `json:"linked_providers"`
+}
+
+// TwoFactorStatus describes a user's second factor.
+type TwoFactorStatus struct {
+	Enabled                bool     `json:"enabled"`
+	Methods                []string `json:"methods"` // email or push; empty when disabled
+	RecoveryCodesRemaining int      `json:"recovery_codes_remaining"`
+	PushDevices            int      `json:"push_devices"`
+}
+
+// PasswordStatus tells when a user's password was last set.
+type PasswordStatus struct {
+	ChangedAt *time.Time `json:"changed_at,omitempty"` // nil if unknown
+	AgeDays   *int       `json:"age_days,omitempty"`
+}
+
+// TokenInfo holds the claims of a verified access token.
+type TokenInfo struct {
+	UserID    int64     `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	OrgID     int64     `json:"org_id,omitempty"`
+	OrgRole   string    `json:"org_role,omitempty"`
+	TenantID  int64     `json:"tenant_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ConsentStatus describes the legal document versions a user accepted
+// compared with the versions currently in force.
+type ConsentStatus struct {
+	TOSVersion                   string     `json:"tos_version"`
+	AcceptedTOSVersion           string     `json:"accepted_tos_version,omitempty"`
+	TOSAcceptedAt                *time.Time `json:"tos_accepted_at,omitempty"`
+	PrivacyPolicyVersion         string     `json:"privacy_policy_version"`
+	AcceptedPrivacyPolicyVersion string     `json:"accepted_privacy_policy_version,omitempty"`
+	PrivacyPolicyAcceptedAt      *time.Time `json:"privacy_policy_accepted_at,omitempty"`
+	ConsentRequired              bool       `json:"consent_required"`
 }
 
 // I Added a helper method to get full name
 func (u *UserResponse) GetFullName() string {
     return u.FirstName + " " + u.LastName
-}
\ No newline at end of file
+}
+
+// UsernameAvailability answers GET /auth/username-available. Reason is
+// "invalid", "reserved" or "taken" when the username is unavailable.
+type UsernameAvailability struct {
+	Username  string `json:"username"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
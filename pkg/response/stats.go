@@ -0,0 +1,68 @@
+package response
+
+import "time"
+
+// AdminStats summarizes a tenant's users and their sign-ins over the last
+// Days UTC days, from From (inclusive) to To (exclusive).
+type AdminStats struct {
+	From   time.Time   `json:"from"`
+	To     time.Time   `json:"to"`
+	Days   int         `json:"days"`
+	Users  UserStats   `json:"users"`
+	Logins LoginStats  `json:"logins"`
+	Daily  []DailyStat `json:"daily"` // oldest first, one entry per day
+}
+
+// UserStats counts a tenant's live users. TwoFactorAdoption is the share of
+// them with 2FA enabled, from 0 to 1.
+type UserStats struct {
+	Total             int64            `json:"total"`
+	Locked            int64            `json:"locked"` // registration pending or rejected
+	TwoFactorEnabled  int64            `json:"two_factor_enabled"`
+	TwoFactorAdoption float64          `json:"two_factor_adoption"`
+	Providers         map[string]int64 `json:"providers"` // users per sign-up provider
+}
+
+// LoginStats counts the sign-ins of the period. FailureRate is the share of
+// failed attempts, from 0 to 1.
+type LoginStats struct {
+	Successful  int64   `json:"successful"`
+	Failed      int64   `json:"failed"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// DailyStat are the sign-ups and sign-ins of one UTC day (YYYY-MM-DD).
+type DailyStat struct {
+	Date             string `json:"date"`
+	Signups          int64  `json:"signups"`
+	ActiveUsers      int64  `json:"active_users"` // distinct users who signed in
+	SuccessfulLogins int64  `json:"successful_logins"`
+	FailedLogins     int64  `json:"failed_logins"`
+}
+
+// CountryStats are a tenant's sign-ins per country of the client over the
+// last Days UTC days, from From (inclusive) to To (exclusive).
+type CountryStats struct {
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Days      int           `json:"days"`
+	Countries []CountryStat `json:"countries"` // most attempts first
+}
+
+// CountryStat counts the sign-ins from one country: an ISO 3166-1 alpha-2
+// code, LOCAL for loopback addresses or UNKNOWN when GeoIP gave no answer.
+type CountryStat struct {
+	Country     string             `json:"country"`
+	Successful  int64              `json:"successful"`
+	Failed      int64              `json:"failed"`
+	FailureRate float64            `json:"failure_rate"`
+	Daily       []CountryDailyStat `json:"daily"` // oldest first, one entry per day
+}
+
+// CountryDailyStat are the sign-ins from a country on one UTC day
+// (YYYY-MM-DD).
+type CountryDailyStat struct {
+	Date       string `json:"date"`
+	Successful int64  `json:"successful"`
+	Failed     int64  `json:"failed"`
+}
@@ -0,0 +1,173 @@
+// Package retry retries calls to third parties (email providers, alert
+// webhooks, GeoIP) that fail for transient reasons, waiting an exponential
+// backoff with full jitter between attempts. A retry budget per target caps
+// retries at a fraction of the calls, so an outage doesn't multiply the
+// traffic sent to the failing service. Attempts and give-ups are counted per
+// target for the metrics endpoint.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// budgetReserve is how many retries a target may make regardless of its
+// call rate, e.g. right after startup.
+const budgetReserve = 10
+
+// Policy controls how calls are retried.
+type Policy struct {
+	Attempts  int           // attempts in all, the first included (1 = no retries)
+	BaseDelay time.Duration // upper bound of the first wait; doubled on each retry
+	MaxDelay  time.Duration // upper bound of any wait
+	// Budget is the fraction of calls that may be retried, beyond a small
+	// reserve (0 = no limit)
+	Budget float64
+}
+
+// Retrier retries the calls to one target. A nil *Retrier calls once. It is
+// safe for concurrent use.
+type Retrier struct {
+	policy  Policy
+	metrics *targetMetrics
+
+	mu     sync.Mutex
+	tokens float64 // retries the budget allows
+}
+
+// New creates the retrier of target (e.g. "email"), which names it in the
+// metrics.
+func New(target string, policy Policy) *Retrier {
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	if policy.MaxDelay < policy.BaseDelay {
+		policy.MaxDelay = policy.BaseDelay
+	}
+	return &Retrier{policy: policy, metrics: metricsOf(target), tokens: budgetReserve}
+}
+
+// Do calls call until it succeeds, fails with an error retryable doesn't
+// accept (any error is retried when retryable is nil), the attempts or the
+// budget run out, or ctx is done. It returns the last error of call.
+func (r *Retrier) Do(ctx context.Context, call func(ctx context.Context) error, retryable func(error) bool) error {
+	if r == nil {
+		return call(ctx)
+	}
+	r.deposit()
+
+	for attempt := 1; ; attempt++ {
+		r.metrics.attempts.Add(1)
+		err := call(ctx)
+		if err == nil || (retryable != nil && !retryable(err)) {
+			return err
+		}
+		if attempt >= r.policy.Attempts || !r.withdraw() {
+			r.metrics.giveUps.Add(1)
+			return err
+		}
+
+		timer := time.NewTimer(r.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			r.metrics.giveUps.Add(1)
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns a random wait before the retry following attempt.
+func (r *Retrier) backoff(attempt int) time.Duration {
+	limit := r.policy.MaxDelay
+	if shift := attempt - 1; shift < 32 {
+		if d := r.policy.BaseDelay << shift; d > 0 && d < limit {
+			limit = d
+		}
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(limit) + 1))
+}
+
+// deposit credits the budget for a call.
+func (r *Retrier) deposit() {
+	if r.policy.Budget <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens += r.policy.Budget
+	if r.tokens > budgetReserve {
+		r.tokens = budgetReserve
+	}
+}
+
+// withdraw reports whether the budget allows a retry, debiting it.
+func (r *Retrier) withdraw() bool {
+	if r.policy.Budget <= 0 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// =============================================================================
+// Metrics
+// =============================================================================
+
+type targetMetrics struct {
+	attempts atomic.Int64
+	giveUps  atomic.Int64
+}
+
+var registry = struct {
+	sync.Mutex
+	targets map[string]*targetMetrics
+}{targets: make(map[string]*targetMetrics)}
+
+// metricsOf returns the counters of target, shared by its retriers.
+func metricsOf(target string) *targetMetrics {
+	registry.Lock()
+	defer registry.Unlock()
+	m, ok := registry.targets[target]
+	if !ok {
+		m = &targetMetrics{}
+		registry.targets[target] = m
+	}
+	return m
+}
+
+// WriteMetrics writes the attempts and give-ups of every target to w in the
+// Prometheus text format.
+func WriteMetrics(w io.Writer) {
+	registry.Lock()
+	targets := make([]string, 0, len(registry.targets))
+	for target := range registry.targets {
+		targets = append(targets, target)
+	}
+	registry.Unlock()
+	sort.Strings(targets)
+
+	fmt.Fprint(w, "# HELP authentio_external_attempts_total Calls to third parties, retries included.\n# TYPE authentio_external_attempts_total counter\n")
+	for _, target := range targets {
+		fmt.Fprintf(w, "authentio_external_attempts_total{target=%q} %d\n", target, metricsOf(target).attempts.Load())
+	}
+	fmt.Fprint(w, "# HELP authentio_external_give_ups_total Calls to third parties that still failed after the retries allowed.\n# TYPE authentio_external_give_ups_total counter\n")
+	for _, target := range targets {
+		fmt.Fprintf(w, "authentio_external_give_ups_total{target=%q} %d\n", target, metricsOf(target).giveUps.Load())
+	}
+}
@@ -0,0 +1,54 @@
+package revocation
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloom is a bloom filter of token IDs: it may report an ID that was never
+// added (a false positive), never the opposite. It is not safe for
+// concurrent use.
+type bloom struct {
+	bits   []uint64
+	m      uint64 // number of bits
+	hashes uint64 // bits set per ID
+}
+
+// newBloom sizes a filter for capacity IDs with a false positive rate of
+// fpRate.
+func newBloom(capacity int, fpRate float64) *bloom {
+	n := float64(max(capacity, 1))
+	m := uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	hashes := uint64(max(1, math.Round(float64(m)/n*math.Ln2)))
+	return &bloom{bits: make([]uint64, (m+63)/64), m: m, hashes: hashes}
+}
+
+// add records id.
+func (b *bloom) add(id string) {
+	h1, h2 := hashID(id)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain reports whether id may have been added.
+func (b *bloom) mayContain(id string) bool {
+	h1, h2 := hashID(id)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashID returns the two hashes the bit positions of id are derived from
+// (double hashing).
+func hashID(id string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	sum := h.Sum64()
+	return sum, sum>>32 | 1
+}
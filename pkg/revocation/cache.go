@@ -0,0 +1,81 @@
+package revocation
+
+import (
+	"container/list"
+	"sync"
+)
+
+// cache remembers, for the most recently checked token IDs the bloom filter
+// couldn't rule out, whether Redis has them revoked. It is safe for
+// concurrent use.
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // most recently used first
+	entries map[string]*list.Element
+}
+
+// cacheEntry is one cached answer.
+type cacheEntry struct {
+	id      string
+	revoked bool
+}
+
+func newCache(size int) *cache {
+	return &cache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the cached answer for id, if any.
+func (c *cache) get(id string) (revoked, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[id]
+	if !ok {
+		return false, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).revoked, true
+}
+
+// put caches the answer for id, evicting the least recently used answer
+// when full. Unless force is set, it doesn't overwrite a cached revocation:
+// a revocation announced while Redis was being asked wins over its answer.
+func (c *cache) put(id string, revoked, force bool) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[id]; ok {
+		entry := e.Value.(*cacheEntry)
+		if force || !entry.revoked {
+			entry.revoked = revoked
+		}
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[id] = c.order.PushFront(&cacheEntry{id: id, revoked: revoked})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).id)
+	}
+}
+
+// remove forgets the answer for id.
+func (c *cache) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[id]; ok {
+		c.order.Remove(e)
+		delete(c.entries, id)
+	}
+}
+
+// clear forgets every answer.
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
@@ -0,0 +1,314 @@
+// Package revocation keeps the list of revoked access tokens in Redis, by
+// token ID ("jti" claim), and answers the revocation check made on every
+// authenticated request mostly in process. Each instance keeps a bloom
+// filter of the revoked IDs, loaded from Redis and kept current through a
+// Redis pub/sub channel: tokens the filter rules out, nearly all of them,
+// are accepted without a Redis round trip. Possible hits are checked in
+// Redis and the answer is cached, since a token is presented on every
+// request of its session. Until the filter is loaded, and while it is
+// reloaded after the subscription dropped (revocations published meanwhile
+// were missed), every check goes to Redis.
+package revocation
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+	"authentio/pkg/metrics"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys and channel used by the list.
+const (
+	keyPrefix = "revoked_jti:" // + token ID, set until the token expires
+	channel   = "revoked_jti"  // "+<id>" when a token is revoked, "-<id>" when restored
+
+	// legacyKeyPrefix + a whole token: how tokens were revoked before they
+	// had IDs. Tokens issued then are still revoked and checked there, in
+	// Redis, until they have expired.
+	legacyKeyPrefix = "blacklist:"
+)
+
+// falsePositiveRate is the share of tokens that aren't revoked which the
+// filter can't rule out, at capacity.
+const falsePositiveRate = 0.01
+
+// Config sizes the filter and the cache of possible hits.
+type Config struct {
+	Capacity  int           // revoked tokens the filter is sized for
+	CacheSize int           // possible hits whose Redis answer is cached
+	Refresh   time.Duration // how often the filter is rebuilt, dropping expired tokens
+}
+
+// List is the revocation list. A nil *List (no Redis) has no revoked
+// tokens. It is safe for concurrent use.
+type List struct {
+	redis *redis.Client
+	cfg   Config
+	cache *cache
+
+	mu         sync.RWMutex
+	filter     *bloom
+	ready      bool     // the filter holds every revoked token
+	pending    []string // tokens revoked while the filter is rebuilt; nil otherwise
+	generation int      // bumped when revocations may have been missed
+
+	rebuilds chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewList creates a revocation list. A zero Capacity or Refresh falls back
+// to a filter for 100000 tokens or a 10m refresh; a zero CacheSize caches
+// no answers.
+func NewList(redisClient *redis.Client, cfg Config) *List {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 100000
+	}
+	if cfg.Refresh <= 0 {
+		cfg.Refresh = 10 * time.Minute
+	}
+	return &List{
+		redis:    redisClient,
+		cfg:      cfg,
+		cache:    newCache(cfg.CacheSize),
+		filter:   newBloom(cfg.Capacity, falsePositiveRate),
+		rebuilds: make(chan struct{}, 1),
+	}
+}
+
+// Revoke revokes the token id for ttl, the time it has left before it
+// expires, and announces it to every instance.
+func (l *List) Revoke(ctx context.Context, id string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	_, err := l.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, keyPrefix+id, "1", ttl)
+		pipe.Publish(ctx, channel, "+"+id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	l.apply("+" + id)
+	return nil
+}
+
+// Restore lifts the revocation of the token id. The filter keeps it until
+// its next rebuild, so its checks go to Redis meanwhile.
+func (l *List) Restore(ctx context.Context, id string) error {
+	_, err := l.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keyPrefix+id)
+		pipe.Publish(ctx, channel, "-"+id)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	l.apply("-" + id)
+	return nil
+}
+
+// IsRevoked reports whether the token id was revoked. Tokens without an ID
+// predate the list and are checked with IsLegacyRevoked.
+func (l *List) IsRevoked(ctx context.Context, id string) (bool, error) {
+	if l == nil || id == "" {
+		return false, nil
+	}
+
+	l.mu.RLock()
+	ready := l.ready
+	possible := !ready || l.filter.mayContain(id)
+	l.mu.RUnlock()
+	if !possible {
+		metrics.RevocationChecked(metrics.RevocationFilter)
+		return false, nil
+	}
+	if ready {
+		if revoked, ok := l.cache.get(id); ok {
+			metrics.RevocationChecked(metrics.RevocationCache)
+			return revoked, nil
+		}
+	}
+
+	n, err := l.redis.Exists(ctx, keyPrefix+id).Result()
+	if err != nil {
+		return false, err
+	}
+	metrics.RevocationChecked(metrics.RevocationRedis)
+	if ready {
+		l.cache.put(id, n > 0, false)
+	}
+	return n > 0, nil
+}
+
+// RevokeLegacy revokes token, issued before tokens had IDs, for ttl.
+func (l *List) RevokeLegacy(ctx context.Context, token string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return l.redis.Set(ctx, legacyKeyPrefix+token, "1", ttl).Err()
+}
+
+// RestoreLegacy lifts the revocation of token, issued before tokens had IDs.
+func (l *List) RestoreLegacy(ctx context.Context, token string) error {
+	return l.redis.Del(ctx, legacyKeyPrefix+token).Err()
+}
+
+// IsLegacyRevoked reports whether token, issued before tokens had IDs, was
+// revoked. The filter doesn't hold these tokens, so each check goes to
+// Redis; they only last until ACCESS_TOKEN_TTL after the upgrade.
+func (l *List) IsLegacyRevoked(ctx context.Context, token string) (bool, error) {
+	if l == nil {
+		return false, nil
+	}
+	n, err := l.redis.Exists(ctx, legacyKeyPrefix+token).Result()
+	if err != nil {
+		return false, err
+	}
+	metrics.RevocationChecked(metrics.RevocationRedis)
+	return n > 0, nil
+}
+
+// ============================================================================
+// Filter Maintenance
+// ============================================================================
+
+// Start subscribes to the revocations of every instance and loads the
+// filter, then rebuilds it every Refresh until ctx is cancelled.
+func (l *List) Start(ctx context.Context) {
+	l.wg.Add(2)
+	go l.subscribe(ctx)
+	go l.rebuildLoop(ctx)
+}
+
+// Wait blocks until the goroutines started by Start have returned.
+func (l *List) Wait() {
+	l.wg.Wait()
+}
+
+// subscribe applies the revocations announced on the channel. Each
+// (re)subscription has the filter rebuilt, since revocations announced while
+// disconnected were missed.
+func (l *List) subscribe(ctx context.Context) {
+	defer l.wg.Done()
+
+	sub := l.redis.Subscribe(ctx, channel)
+	defer sub.Close()
+
+	messages := sub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			switch m := msg.(type) {
+			case *redis.Subscription:
+				if m.Kind == "subscribe" {
+					l.invalidate()
+				}
+			case *redis.Message:
+				l.apply(m.Payload)
+			}
+		}
+	}
+}
+
+// rebuildLoop rebuilds the filter when asked to, and every Refresh.
+func (l *List) rebuildLoop(ctx context.Context) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.cfg.Refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.rebuilds:
+		case <-ticker.C:
+		}
+		l.rebuild(ctx)
+	}
+}
+
+// invalidate stops trusting the filter until it is rebuilt.
+func (l *List) invalidate() {
+	l.mu.Lock()
+	l.ready = false
+	l.generation++
+	l.mu.Unlock()
+
+	select {
+	case l.rebuilds <- struct{}{}:
+	default: // a rebuild is already queued
+	}
+}
+
+// rebuild loads the tokens revoked in Redis into a new filter, dropping the
+// expired ones, and swaps it in. Tokens revoked during the scan are added
+// before the swap. The filter is trusted again unless revocations may have
+// been missed since the scan started.
+func (l *List) rebuild(ctx context.Context) {
+	l.mu.Lock()
+	l.pending = []string{}
+	generation := l.generation
+	l.mu.Unlock()
+
+	filter := newBloom(l.cfg.Capacity, falsePositiveRate)
+	count := 0
+	iter := l.redis.Scan(ctx, 0, keyPrefix+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		filter.add(strings.TrimPrefix(iter.Val(), keyPrefix))
+		count++
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pending := l.pending
+	l.pending = nil
+	if err := iter.Err(); err != nil {
+		if ctx.Err() == nil {
+			logger.Error("failed to load revoked tokens, checking them in Redis", "error", err)
+		}
+		return
+	}
+	for _, id := range pending {
+		filter.add(id)
+	}
+	l.filter = filter
+	l.ready = generation == l.generation
+	l.cache.clear()
+
+	if count > l.cfg.Capacity {
+		logger.Warn("more revoked tokens than the filter is sized for, raise REVOCATION_FILTER_CAPACITY", "revoked", count, "capacity", l.cfg.Capacity)
+	}
+	logger.Debug("revoked token filter rebuilt", "revoked", count)
+}
+
+// apply applies an announcement: "+<id>" adds a revoked token to the filter
+// and the cache, "-<id>" drops a restored one from the cache.
+func (l *List) apply(payload string) {
+	if len(payload) < 2 {
+		return
+	}
+	id := payload[1:]
+	switch payload[0] {
+	case '+':
+		l.mu.Lock()
+		l.filter.add(id)
+		if l.pending != nil {
+			l.pending = append(l.pending, id)
+		}
+		l.mu.Unlock()
+		l.cache.put(id, true, true)
+	case '-':
+		l.cache.remove(id)
+	}
+}
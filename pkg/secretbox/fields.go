@@ -0,0 +1,113 @@
+package secretbox
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// fieldPrefix starts the values sealed by SealString.
+const fieldPrefix = "enc:v1:"
+
+// SealString seals value into a single string that fits a text column:
+// "enc:v1:<key id>:<wrapped data key>:<ciphertext>", both base64.
+func (k *Keyring) SealString(value string, context []byte) (string, error) {
+	sealed, err := k.Seal([]byte(value), context)
+	if err != nil {
+		return "", err
+	}
+	return encodeField(sealed), nil
+}
+
+// OpenString decrypts a value sealed by SealString. Other values are
+// returned as is, so columns can hold a mix of both while being encrypted.
+func (k *Keyring) OpenString(stored string, context []byte) (string, error) {
+	if !IsSealedString(stored) {
+		return stored, nil
+	}
+	sealed, err := decodeField(stored)
+	if err != nil {
+		return "", err
+	}
+	value, err := k.Open(sealed, context)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// RewrapString returns a value sealed by SealString with its data key
+// wrapped by the current master key.
+func (k *Keyring) RewrapString(stored string) (string, error) {
+	sealed, err := decodeField(stored)
+	if err != nil {
+		return "", err
+	}
+	if sealed, err = k.Rewrap(sealed); err != nil {
+		return "", err
+	}
+	return encodeField(sealed), nil
+}
+
+// IsSealedString reports whether stored was sealed by SealString.
+func IsSealedString(stored string) bool {
+	return strings.HasPrefix(stored, fieldPrefix)
+}
+
+// FieldPrefix returns the prefix of the values SealString seals under the
+// master key keyID, e.g. to find those sealed under other keys.
+func FieldPrefix(keyID string) string {
+	return fieldPrefix + keyID + ":"
+}
+
+// encodeField formats sealed for SealString.
+func encodeField(sealed *Sealed) string {
+	return FieldPrefix(sealed.KeyID) +
+		base64.RawStdEncoding.EncodeToString(sealed.DataKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed.Ciphertext)
+}
+
+// decodeField parses a value sealed by SealString.
+func decodeField(stored string) (*Sealed, error) {
+	parts := strings.Split(strings.TrimPrefix(stored, fieldPrefix), ":")
+	if len(parts) != 3 {
+		return nil, ErrDecrypt
+	}
+	dataKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return &Sealed{KeyID: parts[0], DataKey: dataKey, Ciphertext: ciphertext}, nil
+}
+
+// Index computes blind indexes: keyed hashes of encrypted values that can be
+// compared for equality, e.g. to look up or deduplicate rows, without
+// decrypting them. Its key is never rotated, or stored hashes stop matching.
+type Index struct {
+	key []byte
+}
+
+// NewIndex creates an index with a key of at least KeySize bytes.
+func NewIndex(key []byte) (*Index, error) {
+	if len(key) < KeySize {
+		return nil, fmt.Errorf("secretbox: index key must be at least %d bytes, got %d", KeySize, len(key))
+	}
+	return &Index{key: key}, nil
+}
+
+// Hash returns the hex HMAC-SHA256 of value. column keeps equal values of
+// different columns from having the same hash.
+func (i *Index) Hash(column, value string) string {
+	mac := hmac.New(sha256.New, i.key)
+	mac.Write([]byte(column))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,351 @@
+// Package secretbox encrypts credentials and personal data stored in the
+// database with envelope encryption. Each value is encrypted with AES-256-GCM under its own
+// random data key, and the data key is encrypted (wrapped) with a master key
+// named by an ID. Rotating the master key only requires re-wrapping data
+// keys: values are never decrypted in bulk. The master key can also be held
+// by a key management service (see Wrapper).
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// KeySize is the length in bytes of master and data keys (AES-256).
+const KeySize = 32
+
+var (
+	// ErrUnknownKey is returned when a value was sealed under a master key
+	// the keyring doesn't hold.
+	ErrUnknownKey = errors.New("secretbox: unknown master key")
+	// ErrDecrypt is returned when a value or its data key fails
+	// authentication, e.g. because it was tampered with or moved.
+	ErrDecrypt = errors.New("secretbox: decryption failed")
+)
+
+// Key is a master key and the ID stored alongside the values it wraps.
+type Key struct {
+	ID     string
+	Secret []byte // KeySize bytes
+}
+
+// ParseKey decodes a base64 (standard or URL encoding) master key.
+func ParseKey(id, encoded string) (Key, error) {
+	if id == "" || strings.Contains(id, ":") {
+		return Key{}, fmt.Errorf("secretbox: key id %q must be set and can't contain ':'", id)
+	}
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		if secret, err = base64.URLEncoding.DecodeString(encoded); err != nil {
+			return Key{}, fmt.Errorf("secretbox: key %q is not valid base64", id)
+		}
+	}
+	if len(secret) != KeySize {
+		return Key{}, fmt.Errorf("secretbox: key %q must be %d bytes, got %d", id, KeySize, len(secret))
+	}
+	return Key{ID: id, Secret: secret}, nil
+}
+
+// Sealed is an encrypted value with what is needed to decrypt it.
+type Sealed struct {
+	KeyID      string // master key that wrapped DataKey
+	DataKey    []byte // wrapped data key
+	Ciphertext []byte // value encrypted with the data key
+}
+
+// Wrapper wraps data keys with a master key held by a key management
+// service, such as *kms.Keys. Data keys it wraps are stored under the key ID
+// "<name>-<version>", e.g. "vault-v3".
+type Wrapper interface {
+	// Name returns the name of the service.
+	Name() string
+	// DataKey returns a data key for new values, wrapped by the current
+	// version of the master key, which is returned. It may be reused for a
+	// while.
+	DataKey() (plain, wrapped []byte, version string, err error)
+	// Wrap wraps key with the current version of the master key.
+	Wrap(key []byte) (wrapped []byte, version string, err error)
+	// Unwrap unwraps a key wrapped by any version of the master key.
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// Keyring seals values under its current master key and opens values sealed
+// under any of its keys. It is safe for concurrent use.
+type Keyring struct {
+	current atomic.Value // string
+	keys    map[string]cipher.AEAD
+	wrapper Wrapper // holds the current master key when set
+
+	// Keys installed at runtime by SetManaged; a managed current key takes
+	// precedence over the others
+	mu             sync.RWMutex
+	managed        map[string]cipher.AEAD
+	managedCurrent string
+}
+
+// NewKeyring creates a keyring sealing with current. Values sealed under the
+// retired keys can still be opened and re-wrapped.
+func NewKeyring(current Key, retired ...Key) (*Keyring, error) {
+	k, err := newKeyring(append([]Key{current}, retired...))
+	if err != nil {
+		return nil, err
+	}
+	k.current.Store(current.ID)
+	return k, nil
+}
+
+// NewWrappedKeyring creates a keyring whose current master key is held by
+// wrapper. Values sealed under the retired keys, e.g. before moving to the
+// key management service, can still be opened and re-wrapped. The service
+// is called once, so that it being unreachable is noticed early.
+func NewWrappedKeyring(wrapper Wrapper, retired ...Key) (*Keyring, error) {
+	k, err := newKeyring(retired)
+	if err != nil {
+		return nil, err
+	}
+	for id := range k.keys {
+		if strings.HasPrefix(id, wrapper.Name()+"-") {
+			return nil, fmt.Errorf("secretbox: key id %q is reserved for %s", id, wrapper.Name())
+		}
+	}
+	k.wrapper = wrapper
+
+	_, _, version, err := wrapper.DataKey()
+	if err != nil {
+		return nil, err
+	}
+	k.current.Store(k.wrapperKeyID(version))
+	return k, nil
+}
+
+// newKeyring creates a keyring holding keys, without a current key.
+func newKeyring(keys []Key) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			return nil, fmt.Errorf("secretbox: key id %q must be set and can't contain ':'", key.ID)
+		}
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("secretbox: key id %q is used more than once", key.ID)
+		}
+		aead, err := newAEAD(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("secretbox: key %q: %w", key.ID, err)
+		}
+		k.keys[key.ID] = aead
+	}
+	return k, nil
+}
+
+// SetManaged replaces the keys added at runtime, e.g. generated by a rotation
+// and stored in the database, and seals new values with current, one of
+// them, or with the keyring's own current key when it is empty.
+func (k *Keyring) SetManaged(keys []Key, current string) error {
+	managed := make(map[string]cipher.AEAD, len(keys))
+	for _, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ":") {
+			return fmt.Errorf("secretbox: key id %q must be set and can't contain ':'", key.ID)
+		}
+		_, configured := k.keys[key.ID]
+		if _, ok := managed[key.ID]; ok || configured || (k.wrapper != nil && strings.HasPrefix(key.ID, k.wrapper.Name()+"-")) {
+			return fmt.Errorf("secretbox: key id %q is used more than once", key.ID)
+		}
+		aead, err := newAEAD(key.Secret)
+		if err != nil {
+			return fmt.Errorf("secretbox: key %q: %w", key.ID, err)
+		}
+		managed[key.ID] = aead
+	}
+	if _, ok := managed[current]; current != "" && !ok {
+		return fmt.Errorf("secretbox: current key %q is not one of the keys", current)
+	}
+
+	k.mu.Lock()
+	k.managed, k.managedCurrent = managed, current
+	k.mu.Unlock()
+	return nil
+}
+
+// managedKey returns the managed key named id, or the managed current key
+// when id is empty.
+func (k *Keyring) managedKey(id string) (string, cipher.AEAD, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if id == "" {
+		id = k.managedCurrent
+	}
+	aead, ok := k.managed[id]
+	return id, aead, ok
+}
+
+// CurrentKeyID returns the ID of the master key new values are sealed under.
+// With a key management service, it follows the rotations of its key.
+func (k *Keyring) CurrentKeyID() string {
+	if id, _, ok := k.managedKey(""); ok {
+		return id
+	}
+	if k.wrapper != nil {
+		// Cached by the wrapper; on error, the last known version is kept
+		if _, _, version, err := k.wrapper.DataKey(); err == nil {
+			k.current.Store(k.wrapperKeyID(version))
+		}
+	}
+	return k.current.Load().(string)
+}
+
+// Seal encrypts plaintext under a new data key. context is authenticated
+// but not stored: the same context must be given to Open, which binds the
+// value to e.g. the row it is stored in.
+func (k *Keyring) Seal(plaintext, context []byte) (*Sealed, error) {
+	keyID, dataKey, wrapped, err := k.newDataKey()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(aead, plaintext, context)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{KeyID: keyID, DataKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+// newDataKey returns a data key for a new value, wrapped by the current
+// master key, and the ID of that key.
+func (k *Keyring) newDataKey() (keyID string, plain, wrapped []byte, err error) {
+	if _, _, managed := k.managedKey(""); k.wrapper != nil && !managed {
+		plain, wrapped, version, err := k.wrapper.DataKey()
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("secretbox: %w", err)
+		}
+		keyID = k.wrapperKeyID(version)
+		k.current.Store(keyID)
+		return keyID, plain, wrapped, nil
+	}
+
+	plain = make([]byte, KeySize)
+	if _, err := rand.Read(plain); err != nil {
+		return "", nil, nil, err
+	}
+	keyID, wrapped, err = k.wrap(plain)
+	return keyID, plain, wrapped, err
+}
+
+// Open decrypts a value sealed with context.
+func (k *Keyring) Open(sealed *Sealed, context []byte) ([]byte, error) {
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, sealed.Ciphertext, context)
+}
+
+// Rewrap returns sealed with its data key wrapped by the current master key.
+// The ciphertext is unchanged.
+func (k *Keyring) Rewrap(sealed *Sealed) (*Sealed, error) {
+	if sealed.KeyID == k.CurrentKeyID() {
+		return sealed, nil
+	}
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := k.wrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{KeyID: keyID, DataKey: wrapped, Ciphertext: sealed.Ciphertext}, nil
+}
+
+// wrap encrypts dataKey with the current master key, whose ID is returned.
+func (k *Keyring) wrap(dataKey []byte) (string, []byte, error) {
+	if keyID, master, ok := k.managedKey(""); ok {
+		wrapped, err := seal(master, dataKey, []byte(keyID))
+		return keyID, wrapped, err
+	}
+	if k.wrapper != nil {
+		wrapped, version, err := k.wrapper.Wrap(dataKey)
+		if err != nil {
+			return "", nil, fmt.Errorf("secretbox: %w", err)
+		}
+		keyID := k.wrapperKeyID(version)
+		k.current.Store(keyID)
+		return keyID, wrapped, nil
+	}
+
+	keyID := k.current.Load().(string)
+	wrapped, err := seal(k.keys[keyID], dataKey, []byte(keyID))
+	return keyID, wrapped, err
+}
+
+// unwrap decrypts the data key of sealed.
+func (k *Keyring) unwrap(sealed *Sealed) ([]byte, error) {
+	if _, master, ok := k.managedKey(sealed.KeyID); ok && sealed.KeyID != "" {
+		return open(master, sealed.DataKey, []byte(sealed.KeyID))
+	}
+	if k.wrapper != nil && strings.HasPrefix(sealed.KeyID, k.wrapper.Name()+"-") {
+		dataKey, err := k.wrapper.Unwrap(sealed.DataKey)
+		if err != nil {
+			return nil, fmt.Errorf("secretbox: %w", err)
+		}
+		return dataKey, nil
+	}
+
+	master, ok := k.keys[sealed.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, sealed.KeyID)
+	}
+	return open(master, sealed.DataKey, []byte(sealed.KeyID))
+}
+
+// wrapperKeyID returns the key ID of a version of the wrapper's master key.
+func (k *Keyring) wrapperKeyID(version string) string {
+	return k.wrapper.Name() + "-" + version
+}
+
+// newAEAD returns AES-GCM with key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which prefixes the result.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open reverses seal.
+func open(aead cipher.AEAD, ciphertext, additional []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,166 @@
+// Package signuplimit throttles registrations in Redis, separately from the
+// generic rate limiter: each IP address gets a fixed number of signups per
+// UTC day, and so does each email address once plus-addressing tags are
+// removed (user+1@example.com, user+2@example.com, ... all count as
+// user@example.com). Going over either limit blocks the IP address, and the
+// email address in the second case, for a while.
+package signuplimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrIPLimit is returned when the IP address used up today's signups.
+	ErrIPLimit = errors.New("too many registrations from this IP address")
+
+	// ErrEmailFlood is returned when too many accounts were registered today
+	// for variants of the same email address.
+	ErrEmailFlood = errors.New("too many registrations for this email address")
+
+	// ErrBlocked is returned while the IP or email address is blocked after
+	// going over a limit.
+	ErrBlocked = errors.New("registration temporarily blocked")
+)
+
+const (
+	keyPrefix = "signuplimit:"
+
+	// dailyRetention is how long daily counters are kept after the day starts
+	dailyRetention = 25 * time.Hour
+)
+
+// LimitError reports a rejected registration and when the next one will be
+// allowed. It wraps ErrIPLimit, ErrEmailFlood or ErrBlocked.
+type LimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s, retry in %s", e.Err, e.RetryAfter.Round(time.Second))
+}
+
+// Unwrap returns ErrIPLimit, ErrEmailFlood or ErrBlocked.
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// Config holds the registration limits. A zero limit disables that check.
+type Config struct {
+	PerIPPerDay    int64         // registrations per IP address per UTC day
+	PerEmailPerDay int64         // registrations per email address without its +tag per UTC day
+	BlockDuration  time.Duration // how long an address is blocked after going over a limit
+}
+
+// Limiter enforces the registration limits. It is safe for concurrent use.
+type Limiter struct {
+	redis *redis.Client
+	cfg   Config
+}
+
+// NewLimiter creates a limiter.
+func NewLimiter(redisClient *redis.Client, cfg Config) *Limiter {
+	return &Limiter{redis: redisClient, cfg: cfg}
+}
+
+// Allow records a registration attempt for email from ip in tenantID and
+// returns a *LimitError if it must be refused. Redis errors are logged and
+// the attempt is allowed (fail-open), like the rate limiter.
+func (l *Limiter) Allow(ctx context.Context, tenantID int64, ip, email string) error {
+	prefix := fmt.Sprintf("%s%d:", keyPrefix, tenantID)
+	base := BaseAddress(email)
+	ipBlockKey := prefix + "block:ip:" + ip
+	emailBlockKey := prefix + "block:email:" + base
+
+	// Addresses blocked after going over a limit
+	for _, key := range []string{ipBlockKey, emailBlockKey} {
+		ttl, err := l.redis.PTTL(ctx, key).Result()
+		if err != nil {
+			logger.Error("registration block check failed", "error", err)
+			return nil
+		}
+		if ttl > 0 {
+			return &LimitError{Err: ErrBlocked, RetryAfter: ttl}
+		}
+	}
+
+	day := time.Now().UTC().Format("2006-01-02")
+	checks := []struct {
+		limit  int64
+		key    string
+		err    error
+		blocks []string
+	}{
+		{l.cfg.PerIPPerDay, prefix + "ip:" + ip + ":" + day, ErrIPLimit, []string{ipBlockKey}},
+		{l.cfg.PerEmailPerDay, prefix + "email:" + base + ":" + day, ErrEmailFlood, []string{ipBlockKey, emailBlockKey}},
+	}
+	for _, check := range checks {
+		if check.limit <= 0 {
+			continue
+		}
+
+		pipe := l.redis.TxPipeline()
+		incr := pipe.Incr(ctx, check.key)
+		pipe.Expire(ctx, check.key, dailyRetention)
+		if _, err := pipe.Exec(ctx); err != nil {
+			logger.Error("registration counter update failed", "error", err)
+			return nil
+		}
+		if incr.Val() <= check.limit {
+			continue
+		}
+
+		if l.cfg.BlockDuration > 0 {
+			for _, key := range check.blocks {
+				if err := l.redis.Set(ctx, key, 1, l.cfg.BlockDuration).Err(); err != nil {
+					logger.Error("failed to block registrations", "error", err, "key", key)
+				}
+			}
+		}
+		logger.Warn("registration limit reached", "tenantID", tenantID, "ip", ip, "reason", check.err)
+		return &LimitError{Err: check.err, RetryAfter: l.retryAfter()}
+	}
+
+	return nil
+}
+
+// retryAfter is when a refused registration can be retried: after the block,
+// or at the start of the next UTC day without one.
+func (l *Limiter) retryAfter() time.Duration {
+	if l.cfg.BlockDuration > 0 {
+		return l.cfg.BlockDuration
+	}
+	now := time.Now().UTC()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return tomorrow.Sub(now)
+}
+
+// BaseAddress returns email lowercased and without its plus-addressing tag
+// ("User+news@Example.com" becomes "user@example.com"). Gmail also ignores
+// dots in the local part, so they are removed for gmail.com addresses.
+func BaseAddress(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if plus := strings.Index(local, "+"); plus >= 0 {
+		local = local[:plus]
+	}
+	if domain == "gmail.com" || domain == "googlemail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+		domain = "gmail.com"
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,69 @@
+// Package sms sends text messages to phone numbers in E.164 format through
+// Twilio, or to the application log during development.
+package sms
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"authentio/pkg/logger"
+)
+
+// Supported values for the SMS_PROVIDER setting. An empty provider disables
+// text messages.
+const (
+	ProviderTwilio = "twilio"
+	ProviderLog    = "log"
+)
+
+// e164 matches phone numbers in E.164 format, e.g. +14155552671.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// ValidNumber reports whether number is in E.164 format.
+func ValidNumber(number string) bool {
+	return e164.MatchString(number)
+}
+
+// Sender delivers a text message to a phone number.
+type Sender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// Config holds the settings needed to build any provider.
+type Config struct {
+	Provider string
+
+	// Twilio
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFromNumber string
+}
+
+// New returns the Sender for cfg.Provider, or nil when no provider is set.
+func New(cfg Config) (Sender, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "":
+		return nil, nil
+	case ProviderTwilio:
+		if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" || cfg.TwilioFromNumber == "" {
+			return nil, fmt.Errorf("twilio provider requires an account SID, auth token and from number")
+		}
+		return NewTwilioSender(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFromNumber), nil
+	case ProviderLog:
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown sms provider %q", cfg.Provider)
+	}
+}
+
+// LogSender writes text messages to the application log instead of sending
+// them. Intended for local development.
+type LogSender struct{}
+
+// Send logs the message.
+func (LogSender) Send(_ context.Context, to, body string) error {
+	logger.Info("sms (log provider)", "to", to, "body", body)
+	return nil
+}
@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const twilioAPIURL = "https://api.twilio.com/2010-04-01/Accounts/"
+
+// TwilioSender sends text messages with the Twilio Programmable Messaging API.
+type TwilioSender struct {
+	client     *http.Client
+	accountSID string
+	authToken  string
+	from       string
+}
+
+// NewTwilioSender creates a sender for a Twilio account, sending from the
+// account's phone number from.
+func NewTwilioSender(accountSID, authToken, from string) *TwilioSender {
+	return &TwilioSender{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+	}
+}
+
+// Send delivers body to the phone number to.
+func (s *TwilioSender) Send(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "From": {s.from}, "Body": {body}}
+	endpoint := twilioAPIURL + url.PathEscape(s.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return nil
+	}
+
+	// Twilio explains rejections in a JSON body, e.g. an unreachable number
+	var problem struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(raw, &problem) == nil && problem.Message != "" {
+		return fmt.Errorf("twilio error %d: %s", problem.Code, problem.Message)
+	}
+	return fmt.Errorf("twilio returned %s", resp.Status)
+}
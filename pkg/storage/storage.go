@@ -0,0 +1,67 @@
+// Package storage stores user-uploaded files such as avatars in an object
+// store and returns the public URL they are served from. The S3 provider works
+// with AWS S3 and S3-compatible servers (MinIO, Cloudflare R2, DigitalOcean
+// Spaces, ...).
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Storage puts and deletes objects. Every provider implements it so services
+// don't depend on a specific one.
+type Storage interface {
+	// Put uploads size bytes from body under key and returns the object's
+	// public URL.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error)
+
+	// Delete removes the object stored under key. Deleting a missing object
+	// is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// KeyForURL returns the key of an object this storage serves at url, and
+	// false for URLs it does not own (for example an avatar from Google).
+	KeyForURL(url string) (string, bool)
+}
+
+// Supported values for the STORAGE_PROVIDER setting.
+const (
+	ProviderNone = "none"
+	ProviderS3   = "s3"
+)
+
+// Config holds the settings needed to build any provider.
+type Config struct {
+	Provider string
+
+	// S3 / S3-compatible object storage
+	Endpoint  string // host[:port], e.g. s3.amazonaws.com or localhost:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+
+	// PublicURL is the base URL objects are served from, typically a CDN in
+	// front of the bucket. Defaults to the bucket's own URL.
+	PublicURL string
+}
+
+// New returns the Storage for cfg.Provider, or nil when storage is disabled.
+func New(cfg Config) (Storage, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case ProviderNone, "":
+		return nil, nil
+	case ProviderS3:
+		s3, err := NewS3Storage(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return s3, nil
+	default:
+		return nil, fmt.Errorf("unknown storage provider %q", cfg.Provider)
+	}
+}
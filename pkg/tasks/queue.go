@@ -0,0 +1,296 @@
+// Package tasks provides a Redis-backed queue of background tasks: side
+// effects (emails, webhooks) run by workers after the request that caused
+// them has returned. Tasks survive restarts, are retried with exponential
+// backoff and are moved to a dead-letter hash once they exhaust their
+// attempts. Each task has an ID; a task enqueued again under the same ID
+// within the dedup window is dropped, so retried requests don't repeat side
+// effects.
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"authentio/pkg/lock"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys used by the queue.
+const (
+	keyPending    = "tasks:queue"      // list of tasks waiting for a worker
+	keyProcessing = "tasks:processing" // list of tasks a worker is running
+	keyRetry      = "tasks:retry"      // sorted set of tasks scored by next attempt (unix ms)
+	keyDead       = "tasks:dead"       // hash of task id -> task that exhausted its attempts
+	keyDedup      = "tasks:dedup:"     // + task id, set while the id was enqueued within the dedup window
+)
+
+// retryLockTTL bounds how long the retry scheduler of a server that died
+// keeps the others from taking over.
+const retryLockTTL = 15 * time.Second
+
+// enqueueScript pushes a task unless its dedup key exists, then sets the key
+// for the dedup window (ms).
+var enqueueScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], "1", "NX", "PX", ARGV[2]) then
+	redis.call("LPUSH", KEYS[2], ARGV[1])
+	return 1
+end
+return 0`)
+
+// QueueConfig controls worker concurrency, the retry policy and
+// deduplication.
+type QueueConfig struct {
+	Workers     int           // concurrent goroutines running tasks
+	MaxAttempts int           // attempts before a task is moved to the dead-letter hash
+	BaseBackoff time.Duration // delay before the first retry; doubled on each attempt
+	MaxBackoff  time.Duration // upper bound for the retry delay
+	Timeout     time.Duration // bounds one attempt
+	DedupWindow time.Duration // how long an enqueued task id keeps its duplicates out
+}
+
+// Task is a queued unit of work. Kind tells the handler what to do with the
+// JSON Payload.
+type Task struct {
+	ID        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	FailedAt  *time.Time      `json:"failed_at,omitempty"`
+}
+
+// Handler runs a task; a returned error has the task retried.
+type Handler func(ctx context.Context, task Task) error
+
+// Queue is the Redis task queue. Enqueue stores a task; the workers started
+// by Start run it through the handler, at least once.
+type Queue struct {
+	redis   *redis.Client
+	cfg     QueueConfig
+	locks   *lock.Locker // one server at a time scans the retry set
+	handler Handler
+	wg      sync.WaitGroup
+}
+
+// NewQueue creates a task queue. Zero config values fall back to 4
+// workers, 8 attempts, a 10s..30m backoff, a 30s timeout and a 24h dedup
+// window.
+func NewQueue(redisClient *redis.Client, cfg QueueConfig) *Queue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 8
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 10 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.DedupWindow <= 0 {
+		cfg.DedupWindow = 24 * time.Hour
+	}
+	return &Queue{redis: redisClient, cfg: cfg, locks: lock.NewLocker(redisClient)}
+}
+
+// Enqueue stores a task of kind with payload marshalled to JSON, and
+// reports whether it was enqueued: false when a task with the same id was
+// enqueued within the dedup window.
+func (q *Queue) Enqueue(ctx context.Context, kind, id string, payload interface{}) (bool, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	task, err := json.Marshal(Task{ID: id, Kind: kind, Payload: data, CreatedAt: time.Now()})
+	if err != nil {
+		return false, err
+	}
+
+	enqueued, err := enqueueScript.Run(ctx, q.redis, []string{keyDedup + id, keyPending}, task, q.cfg.DedupWindow.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("enqueue task: %w", err)
+	}
+	return enqueued == 1, nil
+}
+
+// ============================================================================
+// Workers
+// ============================================================================
+
+// Start launches the workers running tasks through handler, and the retry
+// scheduler; they stop when ctx is cancelled. Tasks left in the processing
+// list by a previous run that stopped mid-task are requeued first.
+func (q *Queue) Start(ctx context.Context, handler Handler) {
+	q.handler = handler
+	for {
+		err := q.redis.LMove(ctx, keyProcessing, keyPending, "RIGHT", "RIGHT").Err()
+		if err != nil {
+			if err != redis.Nil {
+				logger.Error("failed to requeue in-flight tasks", "error", err)
+			}
+			break
+		}
+	}
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+
+	q.wg.Add(1)
+	go q.scheduleRetries(ctx)
+
+	logger.Info("task queue started", "workers", q.cfg.Workers, "maxAttempts", q.cfg.MaxAttempts)
+}
+
+// Wait blocks until all goroutines started by Start have returned.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+// work takes tasks off the pending list and runs them until ctx is done.
+func (q *Queue) work(ctx context.Context) {
+	defer q.wg.Done()
+
+	for ctx.Err() == nil {
+		raw, err := q.redis.BLMove(ctx, keyPending, keyProcessing, "RIGHT", "LEFT", 5*time.Second).Result()
+		if err == redis.Nil || ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			logger.Error("task queue read failed", "error", err)
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		q.process(raw)
+	}
+}
+
+// process runs one task and moves it to its next state. It deliberately
+// uses a fresh context so a task started before shutdown completes.
+func (q *Queue) process(raw string) {
+	ctx := context.Background()
+	defer q.redis.LRem(ctx, keyProcessing, 1, raw)
+
+	var task Task
+	if err := json.Unmarshal([]byte(raw), &task); err != nil {
+		logger.Error("dropping malformed task", "error", err)
+		return
+	}
+
+	task.Attempts++
+	runCtx, cancel := context.WithTimeout(ctx, q.cfg.Timeout)
+	err := q.handler(runCtx, task)
+	cancel()
+	if err == nil {
+		logger.Debug("task done", "taskID", task.ID, "kind", task.Kind, "attempts", task.Attempts)
+		return
+	}
+	task.LastError = err.Error()
+
+	if task.Attempts >= q.cfg.MaxAttempts {
+		now := time.Now()
+		task.FailedAt = &now
+		payload, _ := json.Marshal(task)
+		if err := q.redis.HSet(ctx, keyDead, task.ID, payload).Err(); err != nil {
+			logger.Error("failed to dead-letter task", "error", err, "taskID", task.ID)
+			return
+		}
+		logger.Error("task failed permanently", "error", task.LastError, "taskID", task.ID, "kind", task.Kind, "attempts", task.Attempts)
+		return
+	}
+
+	delay := q.backoff(task.Attempts)
+	payload, _ := json.Marshal(task)
+	if err := q.redis.ZAdd(ctx, keyRetry, redis.Z{
+		Score:  float64(time.Now().Add(delay).UnixMilli()),
+		Member: payload,
+	}).Err(); err != nil {
+		logger.Error("failed to schedule task retry", "error", err, "taskID", task.ID)
+		return
+	}
+	logger.Warn("task failed, will retry", "error", task.LastError, "taskID", task.ID, "kind", task.Kind, "attempts", task.Attempts, "retryIn", delay)
+}
+
+// scheduleRetries moves retry tasks whose backoff has elapsed back to the
+// pending list every second, on the server holding the retry lock.
+func (q *Queue) scheduleRetries(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := q.locks.Do(ctx, "task-retries", retryLockTTL, q.requeueDue); err != nil && ctx.Err() == nil {
+			logger.Error("failed to acquire the task retry lock", "error", err)
+		}
+	}
+}
+
+// requeueDue moves the retry tasks whose backoff has elapsed to the pending
+// list. ZRem acts as a claim, so a task is requeued once even if the lock
+// was lost meanwhile.
+func (q *Queue) requeueDue(ctx context.Context) {
+	due, err := q.redis.ZRangeByScore(ctx, keyRetry, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   fmt.Sprint(time.Now().UnixMilli()),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("task retry scan failed", "error", err)
+		}
+		return
+	}
+
+	for _, raw := range due {
+		if removed, err := q.redis.ZRem(ctx, keyRetry, raw).Result(); err != nil || removed == 0 {
+			continue
+		}
+		if err := q.redis.LPush(ctx, keyPending, raw).Err(); err != nil {
+			logger.Error("failed to requeue task retry", "error", err)
+		}
+	}
+}
+
+// backoff returns the delay before retrying a task that has failed attempts
+// times.
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := q.cfg.BaseBackoff
+	for i := 1; i < attempts && delay < q.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > q.cfg.MaxBackoff {
+		delay = q.cfg.MaxBackoff
+	}
+	return delay
+}
+
+// ============================================================================
+// Helpers
+// ============================================================================
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
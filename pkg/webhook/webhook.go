@@ -0,0 +1,83 @@
+// Package webhook posts account events (e.g. user.registered) to an
+// endpoint of the operator's, such as an analytics pipeline or CRM. Posts
+// are signed with HMAC-SHA256 so the endpoint can tell them from forgeries:
+// the X-Authentio-Signature header is "sha256=" plus the hex HMAC, keyed
+// with the shared secret, of "<X-Authentio-Timestamp>.<body>".
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// postTimeout bounds the delivery of one event.
+const postTimeout = 10 * time.Second
+
+// Event is an account event. ID is stable across redeliveries, so the
+// endpoint can drop duplicates.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Sender posts events to one endpoint.
+type Sender struct {
+	client *http.Client
+	url    string
+	secret []byte
+}
+
+// NewSender creates a sender posting to url, signing with secret (unsigned
+// when empty).
+func NewSender(url, secret string) *Sender {
+	return &Sender{client: &http.Client{Timeout: postTimeout}, url: url, secret: []byte(secret)}
+}
+
+// Send posts event once. Retries are up to the caller.
+func (s *Sender) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Authentio-Event", event.Type)
+	req.Header.Set("X-Authentio-Event-ID", event.ID)
+	if len(s.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Authentio-Timestamp", timestamp)
+		req.Header.Set("X-Authentio-Signature", "sha256="+s.sign(timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sign returns the hex HMAC-SHA256 of "<timestamp>.<body>".
+func (s *Sender) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}